@@ -3,9 +3,11 @@ package api
 import (
 	"context"
 	"errors"
+	"path/filepath"
 
 	"github.com/grafana/grafana/pkg/api/response"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/export"
 )
 
 func (hs *HTTPServer) AdminProvisioningReloadDashboards(c *models.ReqContext) response.Response {
@@ -39,3 +41,59 @@ func (hs *HTTPServer) AdminProvisioningReloadNotifications(c *models.ReqContext)
 	}
 	return response.Success("Notifications config reloaded")
 }
+
+func (hs *HTTPServer) AdminProvisioningReloadTeams(c *models.ReqContext) response.Response {
+	err := hs.ProvisioningService.ProvisionTeams()
+	if err != nil {
+		return response.Error(500, "", err)
+	}
+	return response.Success("Teams config reloaded")
+}
+
+func (hs *HTTPServer) AdminProvisioningReloadFolders(c *models.ReqContext) response.Response {
+	err := hs.ProvisioningService.ProvisionFolders()
+	if err != nil {
+		return response.Error(500, "", err)
+	}
+	return response.Success("Folders config reloaded")
+}
+
+func (hs *HTTPServer) AdminProvisioningReloadPreferences(c *models.ReqContext) response.Response {
+	err := hs.ProvisioningService.ProvisionPreferences()
+	if err != nil {
+		return response.Error(500, "", err)
+	}
+	return response.Success("Preferences config reloaded")
+}
+
+// GET /api/admin/provisioning/plan
+func (hs *HTTPServer) AdminProvisioningPlan(c *models.ReqContext) response.Response {
+	return response.JSON(200, hs.ProvisioningService.Plan())
+}
+
+// GET /api/admin/provisioning/export
+func (hs *HTTPServer) AdminProvisioningExport(c *models.ReqContext) response.Response {
+	secrets, err := export.ParseSecretsMode(c.Query("secrets"))
+	if err != nil {
+		return response.Error(400, err.Error(), nil)
+	}
+
+	dashboardsPath := c.Query("dashboardsPath")
+	if dashboardsPath == "" {
+		dashboardsPath = filepath.Join(hs.Cfg.ProvisioningPath, "dashboards")
+	}
+
+	result, err := export.Export(export.Options{
+		OrgID:          c.QueryInt64("orgId"),
+		Secrets:        secrets,
+		DashboardsPath: dashboardsPath,
+	})
+	if err != nil {
+		if errors.Is(err, models.ErrOrgNotFound) {
+			return response.Error(404, "Organization not found", err)
+		}
+		return response.Error(500, "Failed to export provisioning files", err)
+	}
+
+	return response.JSON(200, result)
+}
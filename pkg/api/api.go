@@ -35,7 +35,15 @@ func (hs *HTTPServer) registerRoutes() {
 
 	// not logged in views
 	r.Get("/logout", hs.Logout)
+	r.Get("/logout/saml", hs.SAMLLogout)
+	r.Get("/saml/metadata", hs.SAMLMetadata)
+	r.Post("/saml/acs", quota("session"), hs.SAMLACS)
+	r.Get("/saml/slo", hs.SAMLSingleLogout)
+	r.Post("/saml/slo", hs.SAMLSingleLogout)
 	r.Post("/login", quota("session"), bind(dtos.LoginCommand{}), routing.Wrap(hs.LoginPost))
+	r.Post("/login/mfa", quota("session"), bind(dtos.LoginMFACommand{}), routing.Wrap(hs.LoginMFA))
+	r.Post("/login/mfa/enroll", bind(dtos.LoginMFAEnrollCommand{}), routing.Wrap(hs.LoginMFAEnroll))
+	r.Get("/login/saml", quota("session"), hs.SAMLLogin)
 	r.Get("/login/:name", quota("session"), hs.OAuthLogin)
 	r.Get("/login", hs.LoginView)
 	r.Get("/invite/:code", hs.Index)
@@ -205,6 +213,12 @@ func (hs *HTTPServer) registerRoutes() {
 			// prefs
 			orgRoute.Get("/preferences", routing.Wrap(GetOrgPreferences))
 			orgRoute.Put("/preferences", bind(dtos.UpdatePrefsCmd{}), routing.Wrap(UpdateOrgPreferences))
+
+			// current org sessions
+			orgRoute.Get("/sessions", routing.Wrap(hs.SearchOrgSessions))
+			orgRoute.Post("/sessions/revoke", bind(dtos.RevokeSessionsCommand{}), routing.Wrap(hs.RevokeOrgSessions))
+			orgRoute.Get("/session-policy", routing.Wrap(hs.GetOrgSessionPolicy))
+			orgRoute.Put("/session-policy", bind(dtos.SessionPolicy{}), routing.Wrap(hs.UpdateOrgSessionPolicy))
 		}, reqOrgAdmin)
 
 		// current org without requirement of user to be org admin
@@ -259,6 +273,7 @@ func (hs *HTTPServer) registerRoutes() {
 			datasourceRoute.Delete("/name/:name", routing.Wrap(DeleteDataSourceByName))
 			datasourceRoute.Get("/:id", routing.Wrap(GetDataSourceById))
 			datasourceRoute.Get("/uid/:uid", routing.Wrap(GetDataSourceByUID))
+			datasourceRoute.Get("/uid/:uid/dependents", routing.Wrap(GetDataSourceDependentsByUID))
 			datasourceRoute.Get("/name/:name", routing.Wrap(GetDataSourceByName))
 		}, reqOrgAdmin)
 
@@ -274,6 +289,7 @@ func (hs *HTTPServer) registerRoutes() {
 
 		apiRoute.Group("/plugins", func(pluginRoute routing.RouteRegister) {
 			pluginRoute.Get("/:pluginId/dashboards/", routing.Wrap(hs.GetPluginDashboards))
+			pluginRoute.Get("/:pluginId/dependents", routing.Wrap(GetAppDependents))
 			pluginRoute.Post("/:pluginId/settings", bind(models.UpdatePluginSettingCmd{}), routing.Wrap(UpdatePluginSetting))
 			pluginRoute.Get("/:pluginId/metrics", routing.Wrap(hs.CollectPluginMetrics))
 		}, reqOrgAdmin)
@@ -300,12 +316,18 @@ func (hs *HTTPServer) registerRoutes() {
 					folderPermissionRoute.Get("/", routing.Wrap(hs.GetFolderPermissionList))
 					folderPermissionRoute.Post("/", bind(dtos.UpdateDashboardAclCommand{}), routing.Wrap(hs.UpdateFolderPermissions))
 				})
+
+				folderUidRoute.Get("/version-retention", routing.Wrap(GetFolderVersionRetention))
+				folderUidRoute.Put("/version-retention", bind(models.SetDashboardVersionRetentionCommand{}), routing.Wrap(SetFolderVersionRetention))
+				folderUidRoute.Delete("/version-retention", routing.Wrap(DeleteFolderVersionRetention))
 			})
 		})
 
 		// Dashboard
 		apiRoute.Group("/dashboards", func(dashboardRoute routing.RouteRegister) {
 			dashboardRoute.Get("/uid/:uid", routing.Wrap(hs.GetDashboard))
+			dashboardRoute.Get("/uid/:uid/dependencies", routing.Wrap(GetDashboardDependencies))
+			dashboardRoute.Get("/dependents", routing.Wrap(GetDependentDashboards))
 			dashboardRoute.Delete("/uid/:uid", routing.Wrap(hs.DeleteDashboardByUID))
 
 			dashboardRoute.Get("/db/:slug", routing.Wrap(hs.GetDashboard))
@@ -313,6 +335,11 @@ func (hs *HTTPServer) registerRoutes() {
 
 			dashboardRoute.Post("/calculate-diff", bind(dtos.CalculateDiffOptions{}), routing.Wrap(CalculateDashboardDiff))
 
+			dashboardRoute.Get("/deleted", reqOrgAdmin, routing.Wrap(GetDeletedDashboards))
+			dashboardRoute.Get("/deleted/:dashboardId/versions", reqOrgAdmin, routing.Wrap(GetDeletedDashboardVersions))
+			dashboardRoute.Post("/deleted/:dashboardId/restore", reqOrgAdmin, bind(dtos.RestoreDeletedDashboardCommand{}), routing.Wrap(hs.RestoreDeletedDashboard))
+			dashboardRoute.Delete("/deleted/:dashboardId", reqOrgAdmin, routing.Wrap(PurgeDeletedDashboard))
+
 			dashboardRoute.Post("/db", bind(models.SaveDashboardCommand{}), routing.Wrap(hs.PostDashboard))
 			dashboardRoute.Get("/home", routing.Wrap(hs.GetHomeDashboard))
 			dashboardRoute.Get("/tags", GetDashboardTags)
@@ -321,6 +348,8 @@ func (hs *HTTPServer) registerRoutes() {
 			dashboardRoute.Group("/id/:dashboardId", func(dashIdRoute routing.RouteRegister) {
 				dashIdRoute.Get("/versions", routing.Wrap(GetDashboardVersions))
 				dashIdRoute.Get("/versions/:id", routing.Wrap(GetDashboardVersion))
+				dashIdRoute.Post("/versions/:id/tags", bind(dtos.AddDashboardVersionTagCommand{}), routing.Wrap(AddDashboardVersionTag))
+				dashIdRoute.Delete("/versions/:id/tags/:tag", routing.Wrap(DeleteDashboardVersionTag))
 				dashIdRoute.Post("/restore", bind(dtos.RestoreDashboardVersionCommand{}), routing.Wrap(hs.RestoreDashboardVersion))
 
 				dashIdRoute.Group("/permissions", func(dashboardPermissionRoute routing.RouteRegister) {
@@ -396,6 +425,15 @@ func (hs *HTTPServer) registerRoutes() {
 			annotationsRoute.Post("/graphite", reqEditorRole, bind(dtos.PostGraphiteAnnotationsCmd{}), routing.Wrap(PostGraphiteAnnotation))
 		})
 
+		// recorded queries
+		apiRoute.Group("/recorded-queries", func(recordedQueriesRoute routing.RouteRegister) {
+			recordedQueriesRoute.Get("/", routing.Wrap(GetRecordedQueries))
+			recordedQueriesRoute.Post("/", bind(models.CreateRecordedQueryCommand{}), routing.Wrap(hs.CreateRecordedQuery))
+			recordedQueriesRoute.Get("/:uid", routing.Wrap(GetRecordedQuery))
+			recordedQueriesRoute.Put("/:uid", bind(models.UpdateRecordedQueryCommand{}), routing.Wrap(hs.UpdateRecordedQuery))
+			recordedQueriesRoute.Delete("/:uid", routing.Wrap(DeleteRecordedQuery))
+		}, reqOrgAdmin)
+
 		// short urls
 		apiRoute.Post("/short-urls", bind(dtos.CreateShortURLCmd{}), routing.Wrap(hs.createShortURL))
 	}, reqSignedIn)
@@ -417,11 +455,18 @@ func (hs *HTTPServer) registerRoutes() {
 		adminRoute.Post("/users/:id/logout", routing.Wrap(hs.AdminLogoutUser))
 		adminRoute.Get("/users/:id/auth-tokens", routing.Wrap(hs.AdminGetUserAuthTokens))
 		adminRoute.Post("/users/:id/revoke-auth-token", bind(models.RevokeAuthTokenCmd{}), routing.Wrap(hs.AdminRevokeUserAuthToken))
+		adminRoute.Get("/sessions", routing.Wrap(hs.AdminSearchSessions))
+		adminRoute.Post("/orgs/:orgId/sessions/revoke", bind(dtos.RevokeSessionsCommand{}), routing.Wrap(hs.AdminRevokeOrgSessions))
 
 		adminRoute.Post("/provisioning/dashboards/reload", routing.Wrap(hs.AdminProvisioningReloadDashboards))
 		adminRoute.Post("/provisioning/plugins/reload", routing.Wrap(hs.AdminProvisioningReloadPlugins))
 		adminRoute.Post("/provisioning/datasources/reload", routing.Wrap(hs.AdminProvisioningReloadDatasources))
 		adminRoute.Post("/provisioning/notifications/reload", routing.Wrap(hs.AdminProvisioningReloadNotifications))
+		adminRoute.Post("/provisioning/teams/reload", routing.Wrap(hs.AdminProvisioningReloadTeams))
+		adminRoute.Post("/provisioning/folders/reload", routing.Wrap(hs.AdminProvisioningReloadFolders))
+		adminRoute.Post("/provisioning/preferences/reload", routing.Wrap(hs.AdminProvisioningReloadPreferences))
+		adminRoute.Get("/provisioning/plan", routing.Wrap(hs.AdminProvisioningPlan))
+		adminRoute.Get("/provisioning/export", routing.Wrap(hs.AdminProvisioningExport))
 		adminRoute.Post("/ldap/reload", routing.Wrap(hs.ReloadLDAPCfg))
 		adminRoute.Post("/ldap/sync/:id", routing.Wrap(hs.PostSyncUserWithLDAP))
 		adminRoute.Get("/ldap/:username", routing.Wrap(hs.GetUserFromLDAP))
@@ -293,6 +293,24 @@ func (hs *HTTPServer) PostDashboard(c *models.ReqContext, cmd models.SaveDashboa
 
 	dashSvc := dashboards.NewService()
 	dashboard, err := dashSvc.SaveDashboard(dashItem, allowUiUpdate)
+
+	// someone else saved the dashboard in between, try to merge their changes with ours
+	merged := false
+	if errors.Is(err, models.ErrDashboardVersionMismatch) && !newDashboard && !cmd.Overwrite {
+		var conflicts []*dashdiffs.MergeConflict
+		conflicts, err = mergeDashboard(dash)
+		if len(conflicts) > 0 {
+			return response.JSON(412, util.DynMap{
+				"status":    models.ErrDashboardVersionMismatch.Status,
+				"message":   models.ErrDashboardVersionMismatch.Reason,
+				"conflicts": conflicts,
+			})
+		}
+		if err == nil {
+			merged = true
+			dashboard, err = dashSvc.SaveDashboard(dashItem, allowUiUpdate)
+		}
+	}
 	if err != nil {
 		return dashboardSaveErrorToApiResponse(err)
 	}
@@ -332,9 +350,44 @@ func (hs *HTTPServer) PostDashboard(c *models.ReqContext, cmd models.SaveDashboa
 		"id":      dashboard.Id,
 		"uid":     dashboard.Uid,
 		"url":     dashboard.GetUrl(),
+		"merged":  merged,
 	})
 }
 
+// mergeDashboard merges the changes to a dashboard with the changes that someone else saved in
+// between, using the version that the dashboard was changed from as the common base. The merged
+// dashboard replaces the data of dash. If the changes conflict, or the base version doesn't exist,
+// dash is left as is and models.ErrDashboardVersionMismatch is returned along with the conflicts.
+func mergeDashboard(dash *models.Dashboard) ([]*dashdiffs.MergeConflict, error) {
+	existingQuery := models.GetDashboardQuery{Id: dash.Id, Uid: dash.Uid, OrgId: dash.OrgId}
+	if err := bus.Dispatch(&existingQuery); err != nil {
+		return nil, err
+	}
+	existing := existingQuery.Result
+
+	baseQuery := models.GetDashboardVersionQuery{DashboardId: existing.Id, Version: dash.Version, OrgId: dash.OrgId}
+	if err := bus.Dispatch(&baseQuery); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionNotFound) {
+			return nil, models.ErrDashboardVersionMismatch
+		}
+		return nil, err
+	}
+
+	data, conflicts, err := dashdiffs.Merge(baseQuery.Result.Data, dash.Data, existing.Data)
+	if err != nil {
+		return nil, err
+	}
+	if len(conflicts) > 0 {
+		return conflicts, models.ErrDashboardVersionMismatch
+	}
+
+	dash.Data = data
+	dash.Title = data.Get("title").MustString()
+	dash.SetVersion(existing.Version)
+	dash.UpdateSlug()
+	return nil, nil
+}
+
 func dashboardSaveErrorToApiResponse(err error) response.Response {
 	var dashboardErr models.DashboardErr
 	if ok := errors.As(err, &dashboardErr); ok {
@@ -519,6 +572,15 @@ func GetDashboardVersion(c *models.ReqContext) response.Response {
 		Created:       query.Result.Created,
 		Message:       query.Result.Message,
 		CreatedBy:     creator,
+		Tags:          []string{},
+	}
+
+	tagsQuery := models.GetDashboardVersionTagsQuery{OrgId: c.OrgId, DashboardId: dashID}
+	if err := bus.Dispatch(&tagsQuery); err != nil {
+		return response.Error(500, "Failed to get dashboard version tags", err)
+	}
+	if tags, ok := tagsQuery.Result[query.Result.Version]; ok {
+		dashVersionMeta.Tags = tags
 	}
 
 	return response.JSON(200, dashVersionMeta)
@@ -561,8 +623,11 @@ func CalculateDashboardDiff(c *models.ReqContext, apiOptions dtos.CalculateDiffO
 		return response.Error(500, "Unable to compute diff", err)
 	}
 
-	if options.DiffType == dashdiffs.DiffDelta {
+	switch options.DiffType {
+	case dashdiffs.DiffDelta, dashdiffs.DiffSemantic:
 		return response.Respond(200, result.Delta).Header("Content-Type", "application/json")
+	case dashdiffs.DiffMarkdown:
+		return response.Respond(200, result.Delta).Header("Content-Type", "text/markdown; charset=utf-8")
 	}
 
 	return response.Respond(200, result.Delta).Header("Content-Type", "text/html")
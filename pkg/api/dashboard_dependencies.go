@@ -0,0 +1,98 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/services/search"
+)
+
+var dependencyKinds = map[string]bool{
+	models.DependencyKindDataSource:   true,
+	models.DependencyKindPlugin:       true,
+	models.DependencyKindLibraryPanel: true,
+	models.DependencyKindDashboard:    true,
+}
+
+// GET /api/dashboards/uid/:uid/dependencies
+func GetDashboardDependencies(c *models.ReqContext) response.Response {
+	dash, rsp := getDashboardHelper(c.OrgId, "", 0, c.Params(":uid"))
+	if rsp != nil {
+		return rsp
+	}
+
+	guardian := guardian.New(dash.Id, c.OrgId, c.SignedInUser)
+	if canView, err := guardian.CanView(); err != nil || !canView {
+		return dashboardGuardianResponse(err)
+	}
+
+	query := models.GetDashboardDependenciesQuery{OrgId: c.OrgId, DashboardId: dash.Id}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Failed to get dashboard dependencies", err)
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// GET /api/dashboards/dependents
+func GetDependentDashboards(c *models.ReqContext) response.Response {
+	kind := c.Query("kind")
+	ref := c.Query("ref")
+	if !dependencyKinds[kind] {
+		return response.Error(400, "Invalid dependency kind", nil)
+	}
+	if ref == "" {
+		return response.Error(400, "Missing dependency ref", nil)
+	}
+
+	query := models.GetDependentDashboardsQuery{OrgId: c.OrgId, Kind: kind, Refs: []string{ref}}
+	if kind == models.DependencyKindDataSource {
+		// dashboards that refer to the data source by name before it existed aren't resolved
+		dsQuery := models.GetDataSourceQuery{Uid: ref, OrgId: c.OrgId}
+		if err := bus.Dispatch(&dsQuery); err != nil && !errors.Is(err, models.ErrDataSourceNotFound) {
+			return response.Error(500, "Failed to get data source", err)
+		} else if err == nil {
+			query.Name = dsQuery.Result.Name
+		}
+	}
+
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Failed to get dependent dashboards", err)
+	}
+	if len(query.Result) == 0 {
+		return response.JSON(200, search.HitList{})
+	}
+
+	ids := make([]int64, 0, len(query.Result))
+	for _, dash := range query.Result {
+		ids = append(ids, dash.Id)
+	}
+
+	// only returns the dashboards the user can view
+	searchQuery := search.FindPersistedDashboardsQuery{
+		SignedInUser: c.SignedInUser,
+		OrgId:        c.OrgId,
+		DashboardIds: ids,
+		Limit:        int64(len(ids)),
+		Permission:   models.PERMISSION_VIEW,
+	}
+	if err := bus.Dispatch(&searchQuery); err != nil {
+		return response.Error(500, "Failed to get dependent dashboards", err)
+	}
+
+	return response.JSON(200, searchQuery.Result)
+}
+
+// getDependentDashboards returns the dashboards that depend on any of the refs. Failing to get them
+// is only logged, since it should never block the deletion of the entity.
+func getDependentDashboards(orgID int64, kind string, refs []string, name string) []*models.DependentDashboard {
+	query := models.GetDependentDashboardsQuery{OrgId: orgID, Kind: kind, Refs: refs, Name: name}
+	if err := bus.Dispatch(&query); err != nil {
+		plog.Error("Failed to get dependent dashboards", "kind", kind, "refs", refs, "error", err)
+		return []*models.DependentDashboard{}
+	}
+	return query.Result
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestDeleteDataSource_DependentDashboards(t *testing.T) {
+	loggedInUserScenarioWithRole(t, "When deleting a data source that dashboards depend on", "DELETE",
+		"/api/datasources/uid/prom", "/api/datasources/uid/:uid", models.ROLE_ADMIN, func(sc *scenarioContext) {
+			bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+				query.Result = &models.DataSource{Id: 1, OrgId: testOrgID, Uid: "prom", Name: "Prometheus"}
+				return nil
+			})
+			bus.AddHandler("test", func(query *models.GetDependentDashboardsQuery) error {
+				assert.Equal(t, models.DependencyKindDataSource, query.Kind)
+				assert.Equal(t, []string{"prom"}, query.Refs)
+				assert.Equal(t, "Prometheus", query.Name)
+				query.Result = []*models.DependentDashboard{{Id: 2, Uid: "api", Title: "API"}}
+				return nil
+			})
+			deleted := false
+			bus.AddHandler("test", func(cmd *models.DeleteDataSourceCommand) error {
+				deleted = true
+				return nil
+			})
+
+			sc.handlerFunc = DeleteDataSourceByUID
+			sc.fakeReqWithParams("DELETE", sc.url, map[string]string{}).exec()
+			require.Equal(t, 200, sc.resp.Code)
+			assert.True(t, deleted)
+
+			var body struct {
+				Message             string                       `json:"message"`
+				DependentDashboards []*models.DependentDashboard `json:"dependentDashboards"`
+			}
+			require.NoError(t, json.NewDecoder(sc.resp.Body).Decode(&body))
+			assert.Equal(t, "Data source deleted", body.Message)
+			assert.Equal(t, []*models.DependentDashboard{{Id: 2, Uid: "api", Title: "API"}}, body.DependentDashboards)
+		})
+}
+
+func TestGetDataSourceDependents(t *testing.T) {
+	loggedInUserScenarioWithRole(t, "When checking the dependents of a data source before deleting it", "GET",
+		"/api/datasources/uid/prom/dependents", "/api/datasources/uid/:uid/dependents", models.ROLE_ADMIN, func(sc *scenarioContext) {
+			bus.AddHandler("test", func(query *models.GetDataSourceQuery) error {
+				query.Result = &models.DataSource{Id: 1, OrgId: testOrgID, Uid: "prom", Name: "Prometheus"}
+				return nil
+			})
+			bus.AddHandler("test", func(query *models.GetDependentDashboardsQuery) error {
+				assert.Equal(t, []string{"prom"}, query.Refs)
+				assert.Equal(t, "Prometheus", query.Name)
+				query.Result = []*models.DependentDashboard{{Id: 2, Uid: "api", Title: "API"}}
+				return nil
+			})
+			bus.AddHandler("test", func(cmd *models.DeleteDataSourceCommand) error {
+				t.Error("the data source must not be deleted")
+				return nil
+			})
+
+			sc.handlerFunc = GetDataSourceDependentsByUID
+			sc.fakeReqWithParams("GET", sc.url, map[string]string{}).exec()
+			require.Equal(t, 200, sc.resp.Code)
+
+			var dependents []*models.DependentDashboard
+			require.NoError(t, json.NewDecoder(sc.resp.Body).Decode(&dependents))
+			assert.Equal(t, []*models.DependentDashboard{{Id: 2, Uid: "api", Title: "API"}}, dependents)
+		})
+}
+
+func TestGetDependentDashboards_InvalidKind(t *testing.T) {
+	loggedInUserScenario(t, "When getting the dependents of an unknown kind", "/api/dashboards/dependents",
+		func(sc *scenarioContext) {
+			sc.handlerFunc = GetDependentDashboards
+			sc.fakeReqWithParams("GET", sc.url, map[string]string{"kind": "alert", "ref": "1"}).exec()
+			assert.Equal(t, 400, sc.resp.Code)
+		})
+}
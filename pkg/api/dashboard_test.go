@@ -848,6 +848,79 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 		})
 	})
 
+	t.Run("Given a dashboard that was saved by someone else in between", func(t *testing.T) {
+		const baseJSON = `{
+			"id": 2, "uid": "uid", "title": "Dash", "version": 1,
+			"panels": [{"id": 1, "title": "CPU"}, {"id": 2, "title": "Memory"}]
+		}`
+
+		parse := func(t *testing.T, data string) *simplejson.Json {
+			json, err := simplejson.NewJson([]byte(data))
+			require.NoError(t, err)
+			return json
+		}
+
+		setUp := func(t *testing.T, theirs *simplejson.Json) {
+			bus.AddHandler("test", func(query *models.GetDashboardQuery) error {
+				query.Result = &models.Dashboard{Id: 2, Uid: "uid", OrgId: 1, Version: 2, Data: theirs}
+				return nil
+			})
+			bus.AddHandler("test", func(query *models.GetDashboardVersionQuery) error {
+				assert.Equal(t, 1, query.Version)
+				query.Result = &models.DashboardVersion{DashboardId: 2, Version: 1, Data: parse(t, baseJSON)}
+				return nil
+			})
+		}
+
+		ours := parse(t, baseJSON)
+		ours.Get("panels").GetIndex(0).Set("title", "CPU usage")
+		cmd := models.SaveDashboardCommand{OrgId: 1, Dashboard: ours}
+
+		postDashboardScenario(t, "When calling POST with changes to another panel", "/api/dashboards", "/api/dashboards",
+			&dashboards.FakeDashboardService{}, cmd, func(sc *scenarioContext) {
+				theirs := parse(t, baseJSON)
+				theirs.Get("panels").GetIndex(1).Set("title", "Memory usage")
+				theirs.Set("version", 2)
+				setUp(t, theirs)
+
+				mock := &versionMismatchDashboardService{}
+				dashboards.NewService = func() dashboards.DashboardService {
+					return mock
+				}
+
+				callPostDashboard(sc)
+				require.Equal(t, 200, sc.resp.Code)
+				assert.True(t, sc.ToJSON().Get("merged").MustBool())
+
+				require.Len(t, mock.saved, 2)
+				saved := mock.saved[1]
+				assert.Equal(t, 2, saved.Version)
+				assert.Equal(t, "CPU usage", saved.Data.Get("panels").GetIndex(0).Get("title").MustString())
+				assert.Equal(t, "Memory usage", saved.Data.Get("panels").GetIndex(1).Get("title").MustString())
+			})
+
+		postDashboardScenario(t, "When calling POST with changes to the same panel", "/api/dashboards", "/api/dashboards",
+			&dashboards.FakeDashboardService{SaveDashboardError: models.ErrDashboardVersionMismatch}, cmd, func(sc *scenarioContext) {
+				theirs := parse(t, baseJSON)
+				theirs.Get("panels").GetIndex(0).Set("title", "CPU load")
+				theirs.Set("version", 2)
+				setUp(t, theirs)
+
+				callPostDashboard(sc)
+				require.Equal(t, 412, sc.resp.Code)
+
+				result := sc.ToJSON()
+				assert.Equal(t, "version-mismatch", result.Get("status").MustString())
+				conflicts := result.Get("conflicts").MustArray()
+				require.Len(t, conflicts, 1)
+				conflict := simplejson.NewFromAny(conflicts[0])
+				assert.Equal(t, "panels[id=1].title", conflict.Get("path").MustString())
+				assert.Equal(t, "CPU", conflict.Get("base").MustString())
+				assert.Equal(t, "CPU usage", conflict.Get("ours").MustString())
+				assert.Equal(t, "CPU load", conflict.Get("theirs").MustString())
+			})
+	})
+
 	t.Run("Given two dashboards being compared", func(t *testing.T) {
 		setUp := func() {
 			mockResult := []*models.DashboardAclInfoDTO{}
@@ -903,6 +976,28 @@ func TestDashboardAPIEndpoint(t *testing.T) {
 				callPostDashboard(sc)
 				assert.Equal(t, 200, sc.resp.Code)
 			})
+
+			semanticCmd := cmd
+			semanticCmd.DiffType = "semantic"
+			postDiffScenario(t, "When calling POST for a semantic diff on", "/api/dashboards/calculate-diff", "/api/dashboards/calculate-diff", semanticCmd, role, func(sc *scenarioContext) {
+				setUp()
+
+				callPostDashboard(sc)
+				assert.Equal(t, 200, sc.resp.Code)
+				assert.Equal(t, "application/json", sc.resp.Header().Get("Content-Type"))
+				assert.Empty(t, sc.ToJSON().Get("panels").MustArray())
+			})
+
+			markdownCmd := cmd
+			markdownCmd.DiffType = "markdown"
+			postDiffScenario(t, "When calling POST for a markdown diff on", "/api/dashboards/calculate-diff", "/api/dashboards/calculate-diff", markdownCmd, role, func(sc *scenarioContext) {
+				setUp()
+
+				callPostDashboard(sc)
+				assert.Equal(t, 200, sc.resp.Code)
+				assert.Equal(t, "text/markdown; charset=utf-8", sc.resp.Header().Get("Content-Type"))
+				assert.Equal(t, "No changes to panels, variables or time settings.\n", sc.resp.Body.String())
+			})
 		})
 	})
 
@@ -1126,6 +1221,10 @@ func callGetDashboardVersion(sc *scenarioContext) {
 		query.Result = &models.DashboardVersion{}
 		return nil
 	})
+	bus.AddHandler("test", func(query *models.GetDashboardVersionTagsQuery) error {
+		query.Result = map[int][]string{}
+		return nil
+	})
 
 	sc.handlerFunc = GetDashboardVersion
 	sc.fakeReqWithParams("GET", sc.url, map[string]string{}).exec()
@@ -1293,3 +1392,21 @@ type mockDashboardProvisioningService struct {
 func (m mockDashboardProvisioningService) GetProvisionedDashboardDataByDashboardID(dashboardId int64) (*models.DashboardProvisioning, error) {
 	return &models.DashboardProvisioning{}, nil
 }
+
+// versionMismatchDashboardService fails to save a dashboard the first time, as if someone else saved
+// it in between.
+type versionMismatchDashboardService struct {
+	dashboards.DashboardService
+
+	saved []*models.Dashboard
+}
+
+func (s *versionMismatchDashboardService) SaveDashboard(dto *dashboards.SaveDashboardDTO, allowUiUpdate bool) (*models.Dashboard, error) {
+	// the dashboard is copied, since it's changed by the merge
+	dash := *dto.Dashboard
+	s.saved = append(s.saved, &dash)
+	if len(s.saved) == 1 {
+		return nil, models.ErrDashboardVersionMismatch
+	}
+	return dto.Dashboard, nil
+}
@@ -0,0 +1,235 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/guardian"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// POST /api/dashboards/id/:dashboardId/versions/:id/tags
+func AddDashboardVersionTag(c *models.ReqContext, cmd dtos.AddDashboardVersionTagCommand) response.Response {
+	dashID := c.ParamsInt64(":dashboardId")
+
+	guardian := guardian.New(dashID, c.OrgId, c.SignedInUser)
+	if canSave, err := guardian.CanSave(); err != nil || !canSave {
+		return dashboardGuardianResponse(err)
+	}
+
+	tagCmd := models.AddDashboardVersionTagCommand{
+		OrgId:       c.OrgId,
+		DashboardId: dashID,
+		Version:     c.ParamsInt(":id"),
+		Tag:         cmd.Tag,
+	}
+	if err := bus.Dispatch(&tagCmd); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionTagInvalid) {
+			return response.Error(400, err.Error(), nil)
+		}
+		if errors.Is(err, models.ErrDashboardVersionNotFound) {
+			return response.Error(404, "Dashboard version not found", nil)
+		}
+		return response.Error(500, "Failed to tag dashboard version", err)
+	}
+
+	return response.Success("Dashboard version tagged")
+}
+
+// DELETE /api/dashboards/id/:dashboardId/versions/:id/tags/:tag
+func DeleteDashboardVersionTag(c *models.ReqContext) response.Response {
+	dashID := c.ParamsInt64(":dashboardId")
+
+	guardian := guardian.New(dashID, c.OrgId, c.SignedInUser)
+	if canSave, err := guardian.CanSave(); err != nil || !canSave {
+		return dashboardGuardianResponse(err)
+	}
+
+	cmd := models.DeleteDashboardVersionTagCommand{
+		OrgId:       c.OrgId,
+		DashboardId: dashID,
+		Version:     c.ParamsInt(":id"),
+		Tag:         c.Params(":tag"),
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionTagNotFound) {
+			return response.Error(404, err.Error(), nil)
+		}
+		return response.Error(500, "Failed to delete dashboard version tag", err)
+	}
+
+	return response.Success("Dashboard version tag deleted")
+}
+
+// GET /api/folders/:uid/version-retention
+func GetFolderVersionRetention(c *models.ReqContext) response.Response {
+	folder, rsp := getFolderForVersionRetention(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	query := models.GetDashboardVersionRetentionQuery{OrgId: c.OrgId, FolderId: folder.Id}
+	if err := bus.Dispatch(&query); err != nil {
+		if !errors.Is(err, models.ErrDashboardVersionRetentionNotFound) {
+			return response.Error(500, "Failed to get version retention policy", err)
+		}
+		// folders without a policy keep the number of versions of the versions_to_keep setting
+		query.Result = &models.DashboardVersionRetention{FolderId: folder.Id, VersionsToKeep: setting.DashboardVersionsToKeep}
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// PUT /api/folders/:uid/version-retention
+func SetFolderVersionRetention(c *models.ReqContext, cmd models.SetDashboardVersionRetentionCommand) response.Response {
+	folder, rsp := getFolderForVersionRetention(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd.OrgId = c.OrgId
+	cmd.FolderId = folder.Id
+	if err := bus.Dispatch(&cmd); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionRetentionInvalid) || errors.Is(err, models.ErrDashboardVersionRetentionBadPeriod) {
+			return response.Error(400, err.Error(), nil)
+		}
+		return response.Error(500, "Failed to set version retention policy", err)
+	}
+
+	return response.JSON(200, cmd.Result)
+}
+
+// DELETE /api/folders/:uid/version-retention
+func DeleteFolderVersionRetention(c *models.ReqContext) response.Response {
+	folder, rsp := getFolderForVersionRetention(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd := models.DeleteDashboardVersionRetentionCommand{OrgId: c.OrgId, FolderId: folder.Id}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return response.Error(500, "Failed to delete version retention policy", err)
+	}
+
+	return response.Success("Version retention policy deleted")
+}
+
+// getFolderForVersionRetention returns the folder of the request, if the user can admin it.
+func getFolderForVersionRetention(c *models.ReqContext) (*models.Folder, response.Response) {
+	s := dashboards.NewFolderService(c.OrgId, c.SignedInUser)
+	folder, err := s.GetFolderByUID(c.Params(":uid"))
+	if err != nil {
+		return nil, toFolderError(err)
+	}
+
+	g := guardian.New(folder.Id, c.OrgId, c.SignedInUser)
+	if canAdmin, err := g.CanAdmin(); err != nil || !canAdmin {
+		return nil, toFolderError(models.ErrFolderAccessDenied)
+	}
+
+	return folder, nil
+}
+
+// GET /api/dashboards/deleted
+func GetDeletedDashboards(c *models.ReqContext) response.Response {
+	query := models.GetDeletedDashboardsQuery{OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Failed to get deleted dashboards", err)
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// GET /api/dashboards/deleted/:dashboardId/versions
+func GetDeletedDashboardVersions(c *models.ReqContext) response.Response {
+	deleted, rsp := getDeletedDashboard(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	query := models.GetDashboardVersionsQuery{
+		OrgId:       c.OrgId,
+		DashboardId: deleted.DashboardId,
+		Limit:       c.QueryInt("limit"),
+		Start:       c.QueryInt("start"),
+	}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(404, "No versions found for the deleted dashboard", err)
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// POST /api/dashboards/deleted/:dashboardId/restore
+func (hs *HTTPServer) RestoreDeletedDashboard(c *models.ReqContext, apiCmd dtos.RestoreDeletedDashboardCommand) response.Response {
+	deleted, rsp := getDeletedDashboard(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd := models.RestoreDeletedDashboardCommand{
+		OrgId:       c.OrgId,
+		DashboardId: deleted.DashboardId,
+		Version:     apiCmd.Version,
+		FolderId:    deleted.FolderId,
+		UserId:      c.UserId,
+	}
+	if apiCmd.FolderId != nil {
+		cmd.FolderId = *apiCmd.FolderId
+	}
+
+	if err := dashboards.NewService().RestoreDeletedDashboard(&cmd, c.SignedInUser); err != nil {
+		if errors.Is(err, models.ErrDashboardVersionNotFound) {
+			return response.Error(404, "Dashboard version not found", nil)
+		}
+		if errors.Is(err, models.ErrFolderSameNameExists) {
+			return toFolderError(err)
+		}
+		return dashboardSaveErrorToApiResponse(err)
+	}
+
+	message := "Dashboard restored"
+	if cmd.Result.IsFolder {
+		message = fmt.Sprintf("Folder restored with %d dashboards", len(cmd.Children))
+	}
+
+	return response.JSON(200, map[string]interface{}{
+		"message": message,
+		"id":      cmd.Result.Id,
+		"uid":     cmd.Result.Uid,
+		"url":     cmd.Result.GetUrl(),
+		"version": cmd.Result.Version,
+	})
+}
+
+// DELETE /api/dashboards/deleted/:dashboardId
+func PurgeDeletedDashboard(c *models.ReqContext) response.Response {
+	deleted, rsp := getDeletedDashboard(c)
+	if rsp != nil {
+		return rsp
+	}
+
+	cmd := models.PurgeDeletedDashboardCommand{OrgId: c.OrgId, DashboardId: deleted.DashboardId}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return response.Error(500, "Failed to purge deleted dashboard", err)
+	}
+
+	return response.Success("Deleted dashboard purged")
+}
+
+func getDeletedDashboard(c *models.ReqContext) (*models.DeletedDashboard, response.Response) {
+	query := models.GetDeletedDashboardQuery{OrgId: c.OrgId, DashboardId: c.ParamsInt64(":dashboardId")}
+	if err := bus.Dispatch(&query); err != nil {
+		if errors.Is(err, models.ErrDeletedDashboardNotFound) {
+			return nil, response.Error(404, err.Error(), nil)
+		}
+		return nil, response.Error(500, "Failed to get deleted dashboard", err)
+	}
+
+	return query.Result, nil
+}
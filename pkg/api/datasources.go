@@ -100,6 +100,7 @@ func DeleteDataSourceById(c *models.ReqContext) response.Response {
 		return response.Error(403, "Cannot delete read-only data source", nil)
 	}
 
+	dependents := getDataSourceDependents(ds)
 	cmd := &models.DeleteDataSourceCommand{ID: id, OrgID: c.OrgId}
 
 	err = bus.Dispatch(cmd)
@@ -107,7 +108,10 @@ func DeleteDataSourceById(c *models.ReqContext) response.Response {
 		return response.Error(500, "Failed to delete datasource", err)
 	}
 
-	return response.Success("Data source deleted")
+	return response.JSON(200, util.DynMap{
+		"message":             "Data source deleted",
+		"dependentDashboards": dependents,
+	})
 }
 
 // GET /api/datasources/uid/:uid
@@ -145,6 +149,7 @@ func DeleteDataSourceByUID(c *models.ReqContext) response.Response {
 		return response.Error(403, "Cannot delete read-only data source", nil)
 	}
 
+	dependents := getDataSourceDependents(ds)
 	cmd := &models.DeleteDataSourceCommand{UID: uid, OrgID: c.OrgId}
 
 	err = bus.Dispatch(cmd)
@@ -152,7 +157,10 @@ func DeleteDataSourceByUID(c *models.ReqContext) response.Response {
 		return response.Error(500, "Failed to delete datasource", err)
 	}
 
-	return response.Success("Data source deleted")
+	return response.JSON(200, util.DynMap{
+		"message":             "Data source deleted",
+		"dependentDashboards": dependents,
+	})
 }
 
 func DeleteDataSourceByName(c *models.ReqContext) response.Response {
@@ -174,6 +182,7 @@ func DeleteDataSourceByName(c *models.ReqContext) response.Response {
 		return response.Error(403, "Cannot delete read-only data source", nil)
 	}
 
+	dependents := getDataSourceDependents(getCmd.Result)
 	cmd := &models.DeleteDataSourceCommand{Name: name, OrgID: c.OrgId}
 	err := bus.Dispatch(cmd)
 	if err != nil {
@@ -181,11 +190,45 @@ func DeleteDataSourceByName(c *models.ReqContext) response.Response {
 	}
 
 	return response.JSON(200, util.DynMap{
-		"message": "Data source deleted",
-		"id":      getCmd.Result.Id,
+		"message":             "Data source deleted",
+		"id":                  getCmd.Result.Id,
+		"dependentDashboards": dependents,
 	})
 }
 
+// GET /api/datasources/uid/:uid/dependents
+func GetDataSourceDependentsByUID(c *models.ReqContext) response.Response {
+	ds, err := getRawDataSourceByUID(c.Params(":uid"), c.OrgId)
+	if err != nil {
+		if errors.Is(err, models.ErrDataSourceNotFound) {
+			return response.Error(404, "Data source not found", nil)
+		}
+		return response.Error(500, "Failed to query datasources", err)
+	}
+
+	return response.JSON(200, findDataSourceDependents(ds))
+}
+
+// findDataSourceDependents returns the dashboards that break when the data source is deleted.
+func findDataSourceDependents(ds *models.DataSource) []*models.DependentDashboard {
+	var refs []string
+	if ds.Uid != "" {
+		refs = append(refs, ds.Uid)
+	}
+	return getDependentDashboards(ds.OrgId, models.DependencyKindDataSource, refs, ds.Name)
+}
+
+// getDataSourceDependents returns the dashboards that break when the data source is deleted, and
+// logs a warning if there are any.
+func getDataSourceDependents(ds *models.DataSource) []*models.DependentDashboard {
+	dependents := findDataSourceDependents(ds)
+	if len(dependents) > 0 {
+		datasourcesLogger.Warn("Deleting data source that dashboards depend on", "name", ds.Name, "uid", ds.Uid,
+			"dashboards", len(dependents))
+	}
+	return dependents
+}
+
 func validateURL(tp string, u string) response.Response {
 	if u != "" {
 		if _, err := datasource.ValidateURL(tp, u); err != nil {
@@ -56,3 +56,15 @@ type CalculateDiffTarget struct {
 type RestoreDashboardVersionCommand struct {
 	Version int `json:"version" binding:"Required"`
 }
+
+type AddDashboardVersionTagCommand struct {
+	Tag string `json:"tag" binding:"Required"`
+}
+
+// RestoreDeletedDashboardCommand restores a deleted dashboard from one of its versions, or from its
+// latest version if Version is not set. The dashboard is restored into the folder it was in if
+// FolderId is not set.
+type RestoreDeletedDashboardCommand struct {
+	Version  int    `json:"version"`
+	FolderId *int64 `json:"folderId"`
+}
@@ -24,6 +24,15 @@ type LoginCommand struct {
 	Remember bool   `json:"remember"`
 }
 
+type LoginMFACommand struct {
+	Token string `json:"mfaToken" binding:"Required"`
+	Code  string `json:"code" binding:"Required"`
+}
+
+type LoginMFAEnrollCommand struct {
+	Token string `json:"mfaToken" binding:"Required"`
+}
+
 type CurrentUser struct {
 	IsSignedIn                 bool              `json:"isSignedIn"`
 	Id                         int64             `json:"id"`
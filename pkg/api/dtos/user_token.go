@@ -1,6 +1,10 @@
 package dtos
 
-import "time"
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+)
 
 type UserToken struct {
 	Id                     int64     `json:"id"`
@@ -14,3 +18,31 @@ type UserToken struct {
 	CreatedAt              time.Time `json:"createdAt"`
 	SeenAt                 time.Time `json:"seenAt"`
 }
+
+type UserSession struct {
+	UserToken
+	UserId int64           `json:"userId"`
+	Login  string          `json:"login"`
+	Email  string          `json:"email"`
+	Name   string          `json:"name"`
+	Role   models.RoleType `json:"role,omitempty"`
+}
+
+type SearchUserSessionsResult struct {
+	TotalCount int64          `json:"totalCount"`
+	Sessions   []*UserSession `json:"sessions"`
+	Page       int            `json:"page"`
+	PerPage    int            `json:"perPage"`
+}
+
+type RevokeSessionsCommand struct {
+	// Role restricts the revocation to the members with the role, all members when empty.
+	Role models.RoleType `json:"role"`
+}
+
+// SessionPolicy holds the session lifetimes as durations like 7d or 12h, empty or 0 to use the server defaults.
+type SessionPolicy struct {
+	MaxInactiveLifetime   string `json:"maxInactiveLifetime"`
+	MaxLifetime           string `json:"maxLifetime"`
+	MaxConcurrentSessions int    `json:"maxConcurrentSessions"`
+}
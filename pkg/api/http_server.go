@@ -39,9 +39,11 @@ import (
 	"github.com/grafana/grafana/pkg/services/hooks"
 	"github.com/grafana/grafana/pkg/services/librarypanels"
 	"github.com/grafana/grafana/pkg/services/login"
+	"github.com/grafana/grafana/pkg/services/mfa"
 	"github.com/grafana/grafana/pkg/services/provisioning"
 	"github.com/grafana/grafana/pkg/services/quota"
 	"github.com/grafana/grafana/pkg/services/rendering"
+	"github.com/grafana/grafana/pkg/services/saml"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
 	"github.com/prometheus/client_golang/prometheus"
@@ -77,6 +79,8 @@ type HTTPServer struct {
 	ProvisioningService    provisioning.ProvisioningService   `inject:""`
 	Login                  *login.LoginService                `inject:""`
 	License                models.Licensing                   `inject:""`
+	SAMLService            *saml.SAMLService                  `inject:""`
+	MFAService             *mfa.MFAService                    `inject:""`
 	BackendPluginManager   backendplugin.Manager              `inject:""`
 	PluginRequestValidator models.PluginRequestValidator      `inject:""`
 	PluginManager          *manager.PluginManager             `inject:""`
@@ -96,7 +96,7 @@ func (hs *HTTPServer) LoginView(c *models.ReqContext) {
 	}
 
 	viewData.Settings["oauth"] = enabledOAuths
-	viewData.Settings["samlEnabled"] = hs.License.HasValidLicense() && hs.Cfg.SAMLEnabled
+	viewData.Settings["samlEnabled"] = hs.SAMLService.IsEnabled()
 
 	if loginError, ok := tryGetEncryptedCookie(c, loginErrorCookieName); ok {
 		// this cookie is only set whenever an OAuth login fails
@@ -173,8 +173,14 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext, cmd dtos.LoginCommand) res
 	authModule := ""
 	var user *models.User
 	var resp *response.NormalResponse
+	mfaPending := false
 
 	defer func() {
+		// The login hook runs once the second factor was verified.
+		if mfaPending {
+			return
+		}
+
 		err := resp.Err()
 		if err == nil && resp.ErrMessage() != "" {
 			err = errors.New(resp.ErrMessage())
@@ -223,21 +229,42 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext, cmd dtos.LoginCommand) res
 
 	user = authQuery.User
 
-	err = hs.loginUserWithUser(user, c)
-	if err != nil {
-		var createTokenErr *models.CreateTokenErr
-		if errors.As(err, &createTokenErr) {
-			resp = response.Error(createTokenErr.StatusCode, createTokenErr.ExternalErr, createTokenErr.InternalErr)
-		} else {
+	// Only passwords stored in Grafana are protected by a second factor, LDAP users use the policies of their directory.
+	if authModule == "grafana" {
+		challenge, err := hs.MFAService.StartLogin(c.Req.Context(), user.Id)
+		if err != nil {
 			resp = response.Error(http.StatusInternalServerError, "Error while signing in user", err)
+			return resp
+		}
+		if challenge != nil {
+			mfaPending = true
+			resp = response.JSON(http.StatusOK, util.DynMap{
+				"message":               "Multi-factor authentication required",
+				"mfaRequired":           true,
+				"mfaEnrollmentRequired": challenge.EnrollmentRequired,
+				"mfaToken":              challenge.Token,
+			})
+			return resp
 		}
-		return resp
 	}
 
-	result := map[string]interface{}{
-		"message": "Logged in",
+	resp = hs.completeLogin(c, user, map[string]interface{}{})
+	return resp
+}
+
+// completeLogin issues a session for a user whose credentials were verified and adds the login message
+// and redirect URL to the result.
+func (hs *HTTPServer) completeLogin(c *models.ReqContext, user *models.User, result map[string]interface{}) *response.NormalResponse {
+	if err := hs.loginUserWithUser(user, c); err != nil {
+		var createTokenErr *models.CreateTokenErr
+		if errors.As(err, &createTokenErr) {
+			return response.Error(createTokenErr.StatusCode, createTokenErr.ExternalErr, createTokenErr.InternalErr)
+		}
+		return response.Error(http.StatusInternalServerError, "Error while signing in user", err)
 	}
 
+	result["message"] = "Logged in"
+
 	if redirectTo := c.GetCookie("redirect_to"); len(redirectTo) > 0 {
 		if err := hs.ValidateRedirectTo(redirectTo); err == nil {
 			result["redirectUrl"] = redirectTo
@@ -248,8 +275,7 @@ func (hs *HTTPServer) LoginPost(c *models.ReqContext, cmd dtos.LoginCommand) res
 	}
 
 	metrics.MApiLoginPost.Inc()
-	resp = response.JSON(http.StatusOK, result)
-	return resp
+	return response.JSON(http.StatusOK, result)
 }
 
 func (hs *HTTPServer) loginUserWithUser(user *models.User, c *models.ReqContext) error {
@@ -278,17 +304,17 @@ func (hs *HTTPServer) loginUserWithUser(user *models.User, c *models.ReqContext)
 }
 
 func (hs *HTTPServer) Logout(c *models.ReqContext) {
-	if hs.Cfg.SAMLEnabled && hs.Cfg.SAMLSingleLogoutEnabled && hs.License.HasValidLicense() {
+	if hs.SAMLService.IsSingleLogoutEnabled() {
 		c.Redirect(hs.Cfg.AppSubURL + "/logout/saml")
 		return
 	}
 
-	err := hs.AuthTokenService.RevokeToken(c.Req.Context(), c.UserToken)
-	if err != nil && !errors.Is(err, models.ErrUserTokenNotFound) {
-		hs.log.Error("failed to revoke auth token", "error", err)
-	}
+	hs.logoutUser(c)
+}
 
-	cookies.WriteSessionCookie(c, hs.Cfg, "", -1)
+// logoutUser ends the Grafana session of the user and redirects to the login page or the signout redirect URL.
+func (hs *HTTPServer) logoutUser(c *models.ReqContext) {
+	hs.revokeLoginSession(c)
 
 	if setting.SignoutRedirectUrl != "" {
 		c.Redirect(setting.SignoutRedirectUrl)
@@ -298,6 +324,18 @@ func (hs *HTTPServer) Logout(c *models.ReqContext) {
 	}
 }
 
+// revokeLoginSession revokes the auth token of the request and removes the session cookie.
+func (hs *HTTPServer) revokeLoginSession(c *models.ReqContext) {
+	if c.UserToken != nil {
+		err := hs.AuthTokenService.RevokeToken(c.Req.Context(), c.UserToken)
+		if err != nil && !errors.Is(err, models.ErrUserTokenNotFound) {
+			hs.log.Error("failed to revoke auth token", "error", err)
+		}
+	}
+
+	cookies.WriteSessionCookie(c, hs.Cfg, "", -1)
+}
+
 func tryGetEncryptedCookie(ctx *models.ReqContext, cookieName string) (string, bool) {
 	cookie := ctx.GetCookie(cookieName)
 	if cookie == "" {
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/login"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/mfa"
+)
+
+// LoginMFA completes a password login with a one-time password or recovery code.
+// If the user had to set up a second factor during the login, the code confirms it
+// and the response contains the recovery codes.
+func (hs *HTTPServer) LoginMFA(c *models.ReqContext, cmd dtos.LoginMFACommand) response.Response {
+	var user *models.User
+	var resp *response.NormalResponse
+
+	defer func() {
+		err := resp.Err()
+		if err == nil && resp.ErrMessage() != "" {
+			err = errors.New(resp.ErrMessage())
+		}
+		hs.HooksService.RunLoginHook(&models.LoginInfo{
+			AuthModule: "grafana",
+			User:       user,
+			HTTPStatus: resp.Status(),
+			Error:      err,
+		}, c)
+	}()
+
+	if !hs.MFAService.IsEnabled() {
+		resp = response.Error(http.StatusNotFound, "Multi-factor authentication not enabled", nil)
+		return resp
+	}
+
+	result, err := hs.MFAService.VerifyLogin(c.Req.Context(), cmd.Token, cmd.Code, c.Req.RemoteAddr)
+	if err != nil {
+		resp = loginMFAError(err)
+		return resp
+	}
+
+	query := models.GetUserByIdQuery{Id: result.UserID}
+	if err := bus.Dispatch(&query); err != nil {
+		resp = response.Error(http.StatusInternalServerError, "Error while signing in user", err)
+		return resp
+	}
+	user = query.Result
+
+	// Do not expose disabled status,
+	// just show incorrect user credentials error (see #17947)
+	if user.IsDisabled {
+		hs.log.Warn("User is disabled", "user", user.Login)
+		resp = response.Error(http.StatusUnauthorized, "Invalid username or password", login.ErrUserDisabled)
+		return resp
+	}
+
+	loginResult := map[string]interface{}{}
+	if len(result.RecoveryCodes) > 0 {
+		loginResult["recoveryCodes"] = result.RecoveryCodes
+	}
+	if result.UsedRecoveryCode {
+		hs.log.Info("User signed in with a recovery code", "user", user.Login)
+	}
+
+	resp = hs.completeLogin(c, user, loginResult)
+	return resp
+}
+
+// LoginMFAEnroll creates the secret for a user that has to set up a second factor before the login can complete.
+func (hs *HTTPServer) LoginMFAEnroll(c *models.ReqContext, cmd dtos.LoginMFAEnrollCommand) response.Response {
+	if !hs.MFAService.IsEnabled() {
+		return response.Error(http.StatusNotFound, "Multi-factor authentication not enabled", nil)
+	}
+
+	enrollment, err := hs.MFAService.EnrollLogin(c.Req.Context(), cmd.Token)
+	if err != nil {
+		return loginMFAError(err)
+	}
+
+	return response.JSON(http.StatusOK, enrollment)
+}
+
+func loginMFAError(err error) *response.NormalResponse {
+	switch {
+	case errors.Is(err, mfa.ErrInvalidCode):
+		return response.Error(http.StatusUnauthorized, "Invalid verification code", err)
+	case errors.Is(err, mfa.ErrInvalidChallenge), errors.Is(err, login.ErrTooManyLoginAttempts):
+		return response.Error(http.StatusUnauthorized, "Multi-factor authentication expired, please sign in again", err)
+	case errors.Is(err, mfa.ErrNotEnrolled):
+		return response.Error(http.StatusBadRequest, "Multi-factor authentication is not set up", err)
+	case errors.Is(err, mfa.ErrAlreadyEnrolled):
+		return response.Error(http.StatusConflict, "Multi-factor authentication is already set up", err)
+	}
+	return response.Error(http.StatusInternalServerError, "Error while signing in user", err)
+}
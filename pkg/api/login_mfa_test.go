@@ -0,0 +1,162 @@
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1" // #nosec G505 - TOTP uses HMAC-SHA1
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/hooks"
+	"github.com/grafana/grafana/pkg/services/licensing"
+	"github.com/grafana/grafana/pkg/services/mfa"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestLoginMFA(t *testing.T) {
+	sc := setupScenarioContext(t, "/login")
+	remoteCache := remotecache.NewFakeStore(t)
+	sqlStore := sqlstore.InitTestDB(t)
+
+	cfg := setting.NewCfg()
+	cfg.MFAEnabled = true
+	cfg.DisableBruteForceLoginProtection = true
+	mfaService := &mfa.MFAService{
+		Cfg:                cfg,
+		SQLStore:           sqlStore,
+		RouteRegister:      routing.NewRouteRegister(),
+		RemoteCacheService: remoteCache,
+	}
+	require.NoError(t, mfaService.Init())
+
+	tokensCreated := 0
+	authTokenService := auth.NewFakeUserAuthTokenService()
+	authTokenService.CreateTokenProvider = func(ctx context.Context, user *models.User, clientIP net.IP, userAgent string) (*models.UserToken, error) {
+		tokensCreated++
+		return &models.UserToken{UserId: user.Id}, nil
+	}
+
+	hookService := &hooks.HooksService{}
+	testHook := loginHookTest{}
+	hookService.AddLoginHook(testHook.LoginHook)
+
+	hs := &HTTPServer{
+		log:              log.New("test"),
+		Cfg:              cfg,
+		License:          &licensing.OSSLicensingService{},
+		AuthTokenService: authTokenService,
+		HooksService:     hookService,
+		MFAService:       mfaService,
+	}
+
+	testUser := &models.User{Id: 42, Login: "admin"}
+	bus.AddHandler("test", func(query *models.GetUserByIdQuery) error {
+		query.Result = testUser
+		return nil
+	})
+
+	var mfaCmd dtos.LoginMFACommand
+	sc.m.Post("/login", routing.Wrap(func(c *models.ReqContext) response.Response {
+		return hs.LoginPost(c, dtos.LoginCommand{User: "admin", Password: "admin"})
+	}))
+	sc.m.Post("/login/mfa", routing.Wrap(func(c *models.ReqContext) response.Response {
+		return hs.LoginMFA(c, mfaCmd)
+	}))
+
+	enrollment, err := mfaService.Enroll(context.Background(), testUser.Id, testUser.Login)
+	require.NoError(t, err)
+	_, err = mfaService.Confirm(context.Background(), testUser.Id, totpCode(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+
+	loginWith := func(authModule string) map[string]interface{} {
+		bus.AddHandler("grafana-auth", func(query *models.LoginUserQuery) error {
+			query.User = testUser
+			query.AuthModule = authModule
+			return nil
+		})
+
+		sc.fakeReqNoAssertions("POST", "/login").exec()
+		require.Equal(t, http.StatusOK, sc.resp.Code)
+
+		var result map[string]interface{}
+		require.NoError(t, json.Unmarshal(sc.resp.Body.Bytes(), &result))
+		return result
+	}
+
+	t.Run("Password login of an enrolled Grafana user requires a second factor", func(t *testing.T) {
+		tokensCreated = 0
+		testHook.info = nil
+
+		result := loginWith("grafana")
+		assert.Equal(t, true, result["mfaRequired"])
+		assert.Equal(t, false, result["mfaEnrollmentRequired"])
+		assert.NotEmpty(t, result["mfaToken"])
+		assert.Equal(t, 0, tokensCreated, "no session before the second factor")
+		assert.Nil(t, testHook.info)
+
+		mfaCmd = dtos.LoginMFACommand{Token: result["mfaToken"].(string), Code: "000000"}
+		sc.fakeReqNoAssertions("POST", "/login/mfa").exec()
+		assert.Equal(t, http.StatusUnauthorized, sc.resp.Code)
+		assert.Equal(t, 0, tokensCreated)
+
+		mfaCmd.Code = totpCode(t, enrollment.Secret, 1)
+		sc.fakeReqNoAssertions("POST", "/login/mfa").exec()
+		assert.Equal(t, http.StatusOK, sc.resp.Code)
+		assert.Equal(t, 1, tokensCreated)
+
+		require.NotNil(t, testHook.info)
+		assert.Equal(t, "grafana", testHook.info.AuthModule)
+		assert.Equal(t, http.StatusOK, testHook.info.HTTPStatus)
+		assert.Equal(t, testUser.Id, testHook.info.User.Id)
+	})
+
+	t.Run("LDAP users are not asked for a second factor", func(t *testing.T) {
+		tokensCreated = 0
+
+		result := loginWith(models.AuthModuleLDAP)
+		assert.Equal(t, "Logged in", result["message"])
+		assert.Nil(t, result["mfaToken"])
+		assert.Equal(t, 1, tokensCreated)
+	})
+
+	t.Run("Unknown tokens are rejected", func(t *testing.T) {
+		mfaCmd = dtos.LoginMFACommand{Token: "unknown", Code: totpCode(t, enrollment.Secret, 0)}
+		sc.fakeReqNoAssertions("POST", "/login/mfa").exec()
+		assert.Equal(t, http.StatusUnauthorized, sc.resp.Code)
+	})
+}
+
+// totpCode returns the RFC 6238 one-time password of the current time step plus offset.
+func totpCode(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(time.Now().Unix()/30+offset))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	o := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[o:o+4])&0x7fffffff)%1000000)
+}
@@ -0,0 +1,208 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/metrics"
+	"github.com/grafana/grafana/pkg/login"
+	"github.com/grafana/grafana/pkg/middleware/cookies"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/saml"
+)
+
+var samlLogger = log.New("saml.login")
+
+// SAMLMetadata serves the metadata of the Grafana service provider.
+func (hs *HTTPServer) SAMLMetadata(c *models.ReqContext) {
+	if !hs.SAMLService.IsEnabled() {
+		c.Handle(hs.Cfg, http.StatusNotFound, "SAML not enabled", nil)
+		return
+	}
+
+	metadata, err := hs.SAMLService.Metadata()
+	if err != nil {
+		c.Handle(hs.Cfg, http.StatusInternalServerError, "Failed to create SAML metadata", err)
+		return
+	}
+
+	c.Resp.Header().Set("Content-Type", "application/samlmetadata+xml")
+	c.Resp.WriteHeader(http.StatusOK)
+	if _, err := c.Resp.Write(metadata); err != nil {
+		samlLogger.Error("Failed to write SAML metadata", "error", err)
+	}
+}
+
+// SAMLLogin starts a login by sending the user to the identity provider with an authentication request.
+func (hs *HTTPServer) SAMLLogin(c *models.ReqContext) {
+	loginInfo := models.LoginInfo{AuthModule: saml.AuthModule}
+	if !hs.SAMLService.IsEnabled() {
+		hs.handleOAuthLoginError(c, loginInfo, LoginError{
+			HttpStatus:    http.StatusNotFound,
+			PublicMessage: "SAML not enabled",
+		})
+		return
+	}
+
+	var redirectTo string
+	if cookie, err := url.QueryUnescape(c.GetCookie("redirect_to")); err == nil && cookie != "" {
+		if err := hs.ValidateRedirectTo(cookie); err == nil {
+			redirectTo = cookie
+		}
+	}
+
+	message, err := hs.SAMLService.NewAuthnRequest(redirectTo)
+	if err != nil {
+		hs.handleOAuthLoginError(c, loginInfo, LoginError{
+			HttpStatus:    http.StatusInternalServerError,
+			PublicMessage: "Failed to create SAML authentication request",
+			Err:           err,
+		})
+		return
+	}
+
+	writeSAMLMessage(c, message)
+}
+
+// SAMLACS is the assertion consumer service that the identity provider posts the authentication response to.
+func (hs *HTTPServer) SAMLACS(c *models.ReqContext) {
+	loginInfo := models.LoginInfo{AuthModule: saml.AuthModule}
+	if !hs.SAMLService.IsEnabled() {
+		hs.handleOAuthLoginError(c, loginInfo, LoginError{
+			HttpStatus:    http.StatusNotFound,
+			PublicMessage: "SAML not enabled",
+		})
+		return
+	}
+
+	result, err := hs.SAMLService.ParseResponse(c.Req.Request)
+	if err != nil {
+		hs.handleOAuthLoginErrorWithRedirect(c, loginInfo, err)
+		return
+	}
+
+	loginInfo.ExternalUser = *result.ExternalUser
+	cmd := &models.UpsertUserCommand{
+		ReqContext:    c,
+		ExternalUser:  result.ExternalUser,
+		SignupAllowed: hs.SAMLService.IsSignupAllowed(),
+	}
+	if err := bus.Dispatch(cmd); err != nil {
+		hs.handleOAuthLoginErrorWithRedirect(c, loginInfo, err)
+		return
+	}
+	loginInfo.User = cmd.Result
+
+	// Do not expose disabled status,
+	// just show incorrect user credentials error (see #17947)
+	if loginInfo.User.IsDisabled {
+		samlLogger.Warn("User is disabled", "user", loginInfo.User.Login)
+		hs.handleOAuthLoginErrorWithRedirect(c, loginInfo, login.ErrInvalidCredentials)
+		return
+	}
+
+	if err := hs.loginUserWithUser(loginInfo.User, c); err != nil {
+		hs.handleOAuthLoginErrorWithRedirect(c, loginInfo, err)
+		return
+	}
+
+	result.Session.UserID = loginInfo.User.Id
+	if err := hs.SAMLService.StoreSession(c.UserToken.Id, result.Session); err != nil {
+		samlLogger.Error("Failed to store SAML session", "error", err)
+	}
+
+	loginInfo.HTTPStatus = http.StatusOK
+	hs.HooksService.RunLoginHook(&loginInfo, c)
+	metrics.MApiLoginSAML.Inc()
+
+	cookies.DeleteCookie(c.Resp, "redirect_to", hs.CookieOptionsFromCfg)
+	if result.RedirectTo != "" {
+		if err := hs.ValidateRedirectTo(result.RedirectTo); err == nil {
+			c.Redirect(result.RedirectTo)
+			return
+		}
+	}
+
+	c.Redirect(hs.Cfg.AppSubURL + "/")
+}
+
+// SAMLLogout logs the user out of Grafana and of the identity provider.
+func (hs *HTTPServer) SAMLLogout(c *models.ReqContext) {
+	if !hs.SAMLService.IsSingleLogoutEnabled() || c.UserToken == nil {
+		hs.logoutUser(c)
+		return
+	}
+
+	message, err := hs.SAMLService.NewLogoutRequest(c.UserToken.Id)
+	if err != nil {
+		if !errors.Is(err, saml.ErrNoSession) {
+			samlLogger.Error("Failed to create SAML logout request", "error", err)
+		}
+		hs.logoutUser(c)
+		return
+	}
+
+	hs.revokeLoginSession(c)
+	writeSAMLMessage(c, message)
+}
+
+// SAMLSingleLogout is the single logout service. It receives the response of the identity provider to a logout
+// request of Grafana, and logout requests the identity provider sends when the user logs out somewhere else.
+func (hs *HTTPServer) SAMLSingleLogout(c *models.ReqContext) {
+	if !hs.SAMLService.IsSingleLogoutEnabled() {
+		c.Handle(hs.Cfg, http.StatusNotFound, "SAML single logout not enabled", nil)
+		return
+	}
+
+	if c.Query("SAMLResponse") != "" || c.Req.PostFormValue("SAMLResponse") != "" {
+		if err := hs.SAMLService.ValidateLogoutResponse(c.Req.Request); err != nil {
+			samlLogger.Warn("Invalid SAML logout response", "error", err)
+		}
+		hs.logoutUser(c)
+		return
+	}
+
+	logoutReq, session, err := hs.SAMLService.ParseLogoutRequest(c.Req.Request)
+	if err != nil {
+		c.Handle(hs.Cfg, http.StatusBadRequest, "Invalid SAML logout request", err)
+		return
+	}
+
+	if session != nil {
+		if err := hs.AuthTokenService.RevokeAllUserTokens(c.Req.Context(), session.UserID); err != nil {
+			c.Handle(hs.Cfg, http.StatusInternalServerError, "Failed to revoke sessions", err)
+			return
+		}
+		samlLogger.Info("Logged out user on request of the identity provider", "userId", session.UserID)
+	}
+	hs.revokeLoginSession(c)
+
+	relayState := c.Query("RelayState")
+	if relayState == "" {
+		relayState = c.Req.PostFormValue("RelayState")
+	}
+	message, err := hs.SAMLService.NewLogoutResponse(logoutReq.ID, relayState)
+	if err != nil {
+		c.Handle(hs.Cfg, http.StatusInternalServerError, "Failed to create SAML logout response", err)
+		return
+	}
+
+	writeSAMLMessage(c, message)
+}
+
+// writeSAMLMessage sends the user to the identity provider with a redirect or an auto-submitting form.
+func writeSAMLMessage(c *models.ReqContext, message *saml.Message) {
+	if message.RedirectURL != "" {
+		c.Redirect(message.RedirectURL)
+		return
+	}
+
+	c.Resp.Header().Set("Content-Type", "text/html; charset=utf-8")
+	c.Resp.WriteHeader(http.StatusOK)
+	if _, err := c.Resp.Write(message.PostForm); err != nil {
+		samlLogger.Error("Failed to write SAML message", "error", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/grafana/grafana/pkg/expr"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/tsdb"
 
 	"github.com/grafana/grafana/pkg/api/dtos"
 	"github.com/grafana/grafana/pkg/api/response"
@@ -73,9 +74,9 @@ func (hs *HTTPServer) QueryMetricsV2(c *models.ReqContext, reqDTO dtos.MetricReq
 		return response.Error(http.StatusForbidden, "Access denied", err)
 	}
 
-	resp, err := hs.DataService.HandleRequest(c.Req.Context(), ds, request)
+	resp, err := hs.DataService.HandleRequest(queryContext(c), ds, request)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Metric request error", err)
+		return metricRequestError(err)
 	}
 
 	statusCode := http.StatusOK
@@ -131,7 +132,7 @@ func (hs *HTTPServer) handleExpressions(c *models.ReqContext, reqDTO dtos.Metric
 		Cfg:         hs.Cfg,
 		DataService: hs.DataService,
 	}
-	resp, err := exprService.WrapTransformData(c.Req.Context(), request)
+	resp, err := exprService.WrapTransformData(queryContext(c), request)
 	if err != nil {
 		return response.Error(500, "expression request error", err)
 	}
@@ -148,6 +149,24 @@ func (hs *HTTPServer) handleExpressions(c *models.ReqContext, reqDTO dtos.Metric
 	return response.JSONStreaming(statusCode, resp)
 }
 
+// queryContext returns the context of a query request, which skips the query cache if the request asks to.
+func queryContext(c *models.ReqContext) context.Context {
+	if c.Req.Header.Get(tsdb.QueryCacheSkipHeader) == "true" {
+		return tsdb.ContextWithoutQueryCache(c.Req.Context())
+	}
+	return c.Req.Context()
+}
+
+func metricRequestError(err error) response.Response {
+	if errors.Is(err, tsdb.ErrQueryQueueTimeout) {
+		return response.Error(http.StatusServiceUnavailable, "Data source is busy, try again later", err)
+	}
+	if errors.Is(err, tsdb.ErrQueryLimitExceeded) {
+		return response.Error(http.StatusBadRequest, err.Error(), err)
+	}
+	return response.Error(http.StatusInternalServerError, "Metric request error", err)
+}
+
 func (hs *HTTPServer) handleGetDataSourceError(err error, datasourceID int64) *response.NormalResponse {
 	hs.log.Debug("Encountered error getting data source", "err", err, "id", datasourceID)
 	if errors.Is(err, models.ErrDataSourceAccessDenied) {
@@ -198,9 +217,9 @@ func (hs *HTTPServer) QueryMetrics(c *models.ReqContext, reqDto dtos.MetricReque
 		})
 	}
 
-	resp, err := hs.DataService.HandleRequest(c.Req.Context(), ds, request)
+	resp, err := hs.DataService.HandleRequest(queryContext(c), ds, request)
 	if err != nil {
-		return response.Error(http.StatusInternalServerError, "Metric request error", err)
+		return metricRequestError(err)
 	}
 
 	statusCode := http.StatusOK
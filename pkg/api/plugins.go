@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins/adapters"
 	"github.com/grafana/grafana/pkg/plugins/backendplugin"
 	"github.com/grafana/grafana/pkg/plugins/manager"
+	"github.com/grafana/grafana/pkg/util"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
@@ -194,15 +195,53 @@ func UpdatePluginSetting(c *models.ReqContext, cmd models.UpdatePluginSettingCmd
 	cmd.OrgId = c.OrgId
 	cmd.PluginId = pluginID
 
-	if _, ok := manager.Apps[cmd.PluginId]; !ok {
+	app, ok := manager.Apps[cmd.PluginId]
+	if !ok {
 		return response.Error(404, "Plugin not installed.", nil)
 	}
 
+	dependents := []*models.DependentDashboard{}
+	if !cmd.Enabled {
+		dependents = getAppDependents(c.OrgId, app)
+	}
+
 	if err := bus.Dispatch(&cmd); err != nil {
 		return response.Error(500, "Failed to update plugin setting", err)
 	}
 
-	return response.Success("Plugin settings updated")
+	return response.JSON(200, util.DynMap{
+		"message":             "Plugin settings updated",
+		"dependentDashboards": dependents,
+	})
+}
+
+// GET /api/plugins/:pluginId/dependents
+func GetAppDependents(c *models.ReqContext) response.Response {
+	app, ok := manager.Apps[c.Params(":pluginId")]
+	if !ok {
+		return response.Error(404, "Plugin not installed.", nil)
+	}
+
+	return response.JSON(200, findAppDependents(c.OrgId, app))
+}
+
+// findAppDependents returns the dashboards that use the app or the panels and data sources it includes.
+func findAppDependents(orgID int64, app *plugins.AppPlugin) []*models.DependentDashboard {
+	refs := []string{app.Id}
+	for _, child := range app.FoundChildPlugins {
+		refs = append(refs, child.Id)
+	}
+	return getDependentDashboards(orgID, models.DependencyKindPlugin, refs, "")
+}
+
+// getAppDependents returns the dashboards that use the app or the panels and data sources it includes,
+// and logs a warning if there are any.
+func getAppDependents(orgID int64, app *plugins.AppPlugin) []*models.DependentDashboard {
+	dependents := findAppDependents(orgID, app)
+	if len(dependents) > 0 {
+		plog.Warn("Disabling app plugin that dashboards depend on", "pluginId", app.Id, "dashboards", len(dependents))
+	}
+	return dependents
 }
 
 func (hs *HTTPServer) GetPluginDashboards(c *models.ReqContext) response.Response {
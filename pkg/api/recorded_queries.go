@@ -0,0 +1,97 @@
+package api
+
+import (
+	"errors"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// GET /api/recorded-queries
+func GetRecordedQueries(c *models.ReqContext) response.Response {
+	query := models.GetRecordedQueriesQuery{OrgId: c.OrgId}
+	if err := bus.Dispatch(&query); err != nil {
+		return response.Error(500, "Failed to get recorded queries", err)
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// GET /api/recorded-queries/:uid
+func GetRecordedQuery(c *models.ReqContext) response.Response {
+	query := models.GetRecordedQueryQuery{OrgId: c.OrgId, Uid: c.Params(":uid")}
+	if err := bus.Dispatch(&query); err != nil {
+		return recordedQueryErrorResponse(err, "Failed to get recorded query")
+	}
+
+	return response.JSON(200, query.Result)
+}
+
+// POST /api/recorded-queries
+func (hs *HTTPServer) CreateRecordedQuery(c *models.ReqContext, cmd models.CreateRecordedQueryCommand) response.Response {
+	if rsp := hs.validateRecordedQuery(c.OrgId, cmd.DatasourceId); rsp != nil {
+		return rsp
+	}
+
+	cmd.OrgId = c.OrgId
+	if err := bus.Dispatch(&cmd); err != nil {
+		return recordedQueryErrorResponse(err, "Failed to create recorded query")
+	}
+
+	return response.JSON(200, cmd.Result)
+}
+
+// PUT /api/recorded-queries/:uid
+func (hs *HTTPServer) UpdateRecordedQuery(c *models.ReqContext, cmd models.UpdateRecordedQueryCommand) response.Response {
+	if rsp := hs.validateRecordedQuery(c.OrgId, cmd.DatasourceId); rsp != nil {
+		return rsp
+	}
+
+	cmd.OrgId = c.OrgId
+	cmd.Uid = c.Params(":uid")
+	if err := bus.Dispatch(&cmd); err != nil {
+		return recordedQueryErrorResponse(err, "Failed to update recorded query")
+	}
+
+	return response.JSON(200, cmd.Result)
+}
+
+// DELETE /api/recorded-queries/:uid
+func DeleteRecordedQuery(c *models.ReqContext) response.Response {
+	cmd := models.DeleteRecordedQueryCommand{OrgId: c.OrgId, Uid: c.Params(":uid")}
+	if err := bus.Dispatch(&cmd); err != nil {
+		return recordedQueryErrorResponse(err, "Failed to delete recorded query")
+	}
+
+	return response.Success("Recorded query deleted")
+}
+
+func (hs *HTTPServer) validateRecordedQuery(orgID int64, datasourceID int64) response.Response {
+	if hs.Cfg.RecordedQueries.RemoteWriteURL == "" {
+		return response.Error(400, "Remote write is not configured", nil)
+	}
+
+	query := models.GetDataSourceQuery{Id: datasourceID, OrgId: orgID}
+	if err := bus.Dispatch(&query); err != nil {
+		if errors.Is(err, models.ErrDataSourceNotFound) {
+			return response.Error(400, "Data source not found", nil)
+		}
+		return response.Error(500, "Failed to get data source", err)
+	}
+
+	return nil
+}
+
+func recordedQueryErrorResponse(err error, message string) response.Response {
+	switch {
+	case errors.Is(err, models.ErrRecordedQueryNotFound):
+		return response.Error(404, err.Error(), nil)
+	case errors.Is(err, models.ErrRecordedQueryNameTaken):
+		return response.Error(409, err.Error(), nil)
+	case errors.Is(err, models.ErrRecordedQueryInvalidName), errors.Is(err, models.ErrRecordedQueryInvalidInterval):
+		return response.Error(400, err.Error(), nil)
+	}
+
+	return response.Error(500, message, err)
+}
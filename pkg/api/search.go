@@ -13,6 +13,15 @@ import (
 	"github.com/grafana/grafana/pkg/services/search"
 )
 
+// searchTermParams maps the search parameters that filter on the search index to the indexed field.
+var searchTermParams = map[string]string{
+	"panel":       models.DashboardSearchFieldPanel,
+	"description": models.DashboardSearchFieldDescription,
+	"target":      models.DashboardSearchFieldTarget,
+	"datasource":  models.DashboardSearchFieldDataSource,
+	"text":        "",
+}
+
 func Search(c *models.ReqContext) response.Response {
 	query := c.Query("query")
 	tags := c.QueryStrings("tag")
@@ -47,8 +56,18 @@ func Search(c *models.ReqContext) response.Response {
 		}
 	}
 
+	var terms []search.TermQuery
+	for param, field := range searchTermParams {
+		for _, term := range c.QueryStrings(param) {
+			if term != "" {
+				terms = append(terms, search.TermQuery{Field: field, Term: term})
+			}
+		}
+	}
+
 	searchQuery := search.Query{
 		Title:        query,
+		Terms:        terms,
 		Tags:         tags,
 		SignedInUser: c.SignedInUser,
 		Limit:        limit,
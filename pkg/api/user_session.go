@@ -0,0 +1,155 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/util"
+	"github.com/ua-parser/uap-go/uaparser"
+)
+
+// GET /api/org/sessions
+func (hs *HTTPServer) SearchOrgSessions(c *models.ReqContext) response.Response {
+	return hs.searchSessionsInternal(c, c.OrgId)
+}
+
+// POST /api/org/sessions/revoke
+func (hs *HTTPServer) RevokeOrgSessions(c *models.ReqContext, cmd dtos.RevokeSessionsCommand) response.Response {
+	return hs.revokeOrgSessionsInternal(c, c.OrgId, cmd)
+}
+
+// GET /api/admin/sessions
+func (hs *HTTPServer) AdminSearchSessions(c *models.ReqContext) response.Response {
+	return hs.searchSessionsInternal(c, c.QueryInt64("orgId"))
+}
+
+// POST /api/admin/orgs/:orgId/sessions/revoke
+func (hs *HTTPServer) AdminRevokeOrgSessions(c *models.ReqContext, cmd dtos.RevokeSessionsCommand) response.Response {
+	return hs.revokeOrgSessionsInternal(c, c.ParamsInt64(":orgId"), cmd)
+}
+
+// GET /api/org/session-policy
+func (hs *HTTPServer) GetOrgSessionPolicy(c *models.ReqContext) response.Response {
+	policy, err := hs.AuthTokenService.GetSessionPolicy(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(500, "Failed to get session policy", err)
+	}
+
+	return response.JSON(200, dtos.SessionPolicy{
+		MaxInactiveLifetime:   formatSessionLifetime(policy.MaxInactiveLifetime),
+		MaxLifetime:           formatSessionLifetime(policy.MaxLifetime),
+		MaxConcurrentSessions: policy.MaxConcurrentSessions,
+	})
+}
+
+// PUT /api/org/session-policy
+func (hs *HTTPServer) UpdateOrgSessionPolicy(c *models.ReqContext, cmd dtos.SessionPolicy) response.Response {
+	policy := &models.SessionPolicy{
+		OrgId:                 c.OrgId,
+		MaxConcurrentSessions: cmd.MaxConcurrentSessions,
+	}
+
+	var err error
+	if policy.MaxInactiveLifetime, err = parseSessionLifetime(cmd.MaxInactiveLifetime); err != nil {
+		return response.Error(400, "Invalid maxInactiveLifetime", err)
+	}
+	if policy.MaxLifetime, err = parseSessionLifetime(cmd.MaxLifetime); err != nil {
+		return response.Error(400, "Invalid maxLifetime", err)
+	}
+
+	if err := hs.AuthTokenService.SetSessionPolicy(c.Req.Context(), policy); err != nil {
+		if errors.Is(err, models.ErrInvalidSessionPolicy) {
+			return response.Error(400, err.Error(), err)
+		}
+		return response.Error(500, "Failed to update session policy", err)
+	}
+
+	return response.Success("Session policy updated")
+}
+
+func (hs *HTTPServer) searchSessionsInternal(c *models.ReqContext, orgID int64) response.Response {
+	perPage := c.QueryInt("perpage")
+	if perPage <= 0 {
+		perPage = 1000
+	}
+	page := c.QueryInt("page")
+	if page < 1 {
+		page = 1
+	}
+
+	role := models.RoleType(c.Query("role"))
+	if role != "" && !role.IsValid() {
+		return response.Error(400, "Invalid role", nil)
+	}
+
+	query := &models.SearchUserSessionsQuery{
+		OrgId: orgID,
+		Query: c.Query("query"),
+		Role:  role,
+		Limit: perPage,
+		Page:  page,
+	}
+	searchResult, err := hs.AuthTokenService.SearchUserSessions(c.Req.Context(), query)
+	if err != nil {
+		return response.Error(500, "Failed to search sessions", err)
+	}
+
+	parser := uaparser.NewFromSaved()
+	result := dtos.SearchUserSessionsResult{
+		TotalCount: searchResult.TotalCount,
+		Sessions:   []*dtos.UserSession{},
+		Page:       page,
+		PerPage:    perPage,
+	}
+	for _, session := range searchResult.Sessions {
+		result.Sessions = append(result.Sessions, &dtos.UserSession{
+			UserToken: *userTokenDTO(c, parser, &session.UserToken),
+			UserId:    session.UserId,
+			Login:     session.Login,
+			Email:     session.Email,
+			Name:      session.Name,
+			Role:      session.Role,
+		})
+	}
+
+	return response.JSON(200, result)
+}
+
+func (hs *HTTPServer) revokeOrgSessionsInternal(c *models.ReqContext, orgID int64, cmd dtos.RevokeSessionsCommand) response.Response {
+	if cmd.Role != "" && !cmd.Role.IsValid() {
+		return response.Error(400, "Invalid role", nil)
+	}
+
+	var activeTokenID int64
+	if c.UserToken != nil {
+		activeTokenID = c.UserToken.Id
+	}
+
+	count, err := hs.AuthTokenService.RevokeOrgUserTokens(c.Req.Context(), orgID, cmd.Role, activeTokenID)
+	if err != nil {
+		return response.Error(500, "Failed to revoke sessions", err)
+	}
+
+	return response.JSON(200, util.DynMap{
+		"message": "Sessions revoked",
+		"count":   count,
+	})
+}
+
+func parseSessionLifetime(value string) (time.Duration, error) {
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	return gtime.ParseDuration(value)
+}
+
+func formatSessionLifetime(d time.Duration) string {
+	if d == 0 {
+		return ""
+	}
+	return d.String()
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/api/dtos"
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/auth"
+)
+
+func TestUserSessionAPIEndpoint(t *testing.T) {
+	t.Run("When searching the sessions of the current org", func(t *testing.T) {
+		fakeAuthTokenService := auth.NewFakeUserAuthTokenService()
+		hs := &HTTPServer{AuthTokenService: fakeAuthTokenService}
+
+		var query *models.SearchUserSessionsQuery
+		fakeAuthTokenService.SearchUserSessionsProvider = func(ctx context.Context, q *models.SearchUserSessionsQuery) (*models.SearchUserSessionsResult, error) {
+			query = q
+			return &models.SearchUserSessionsResult{
+				TotalCount: 1,
+				Sessions: []*models.UserSession{{
+					UserToken: models.UserToken{
+						Id:        2,
+						UserId:    3,
+						ClientIp:  "192.168.1.1",
+						UserAgent: "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_14_0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/72.0.3626.119 Safari/537.36",
+						CreatedAt: time.Now().Unix(),
+					},
+					Login: "editor",
+					Role:  models.ROLE_EDITOR,
+				}},
+			}, nil
+		}
+
+		userSessionScenario(t, "Should return the sessions with device metadata", "/api/org/sessions", sessionToken(1),
+			func(c *models.ReqContext) response.Response { return hs.SearchOrgSessions(c) },
+			func(sc *scenarioContext) {
+				sc.fakeReqWithParams("GET", sc.url, map[string]string{"query": "edit", "role": "Editor"}).exec()
+				require.Equal(t, 200, sc.resp.Code)
+
+				assert.Equal(t, &models.SearchUserSessionsQuery{OrgId: testOrgID, Query: "edit", Role: models.ROLE_EDITOR, Limit: 1000, Page: 1}, query)
+
+				var result dtos.SearchUserSessionsResult
+				require.NoError(t, json.Unmarshal(sc.resp.Body.Bytes(), &result))
+				assert.EqualValues(t, 1, result.TotalCount)
+				require.Len(t, result.Sessions, 1)
+				assert.Equal(t, "editor", result.Sessions[0].Login)
+				assert.Equal(t, int64(3), result.Sessions[0].UserId)
+				assert.Equal(t, "Chrome", result.Sessions[0].Browser)
+				assert.Equal(t, "Mac OS X", result.Sessions[0].OperatingSystem)
+				assert.False(t, result.Sessions[0].IsActive)
+			})
+
+		userSessionScenario(t, "Should reject invalid roles", "/api/org/sessions", sessionToken(1),
+			func(c *models.ReqContext) response.Response { return hs.SearchOrgSessions(c) },
+			func(sc *scenarioContext) {
+				sc.fakeReqWithParams("GET", sc.url, map[string]string{"role": "Owner"}).exec()
+				assert.Equal(t, 400, sc.resp.Code)
+			})
+	})
+
+	t.Run("When revoking the sessions of the current org", func(t *testing.T) {
+		fakeAuthTokenService := auth.NewFakeUserAuthTokenService()
+		hs := &HTTPServer{AuthTokenService: fakeAuthTokenService}
+
+		var orgID, exceptTokenID int64
+		var role models.RoleType
+		fakeAuthTokenService.RevokeOrgUserTokensProvider = func(ctx context.Context, o int64, r models.RoleType, except int64) (int64, error) {
+			orgID, role, exceptTokenID = o, r, except
+			return 5, nil
+		}
+
+		userSessionScenario(t, "Should keep the session of the caller", "/api/org/sessions/revoke", sessionToken(7),
+			func(c *models.ReqContext) response.Response {
+				return hs.RevokeOrgSessions(c, dtos.RevokeSessionsCommand{Role: models.ROLE_VIEWER})
+			},
+			func(sc *scenarioContext) {
+				sc.fakeReqWithParams("POST", sc.url, map[string]string{}).exec()
+				require.Equal(t, 200, sc.resp.Code)
+				assert.Equal(t, testOrgID, orgID)
+				assert.Equal(t, models.ROLE_VIEWER, role)
+				assert.Equal(t, int64(7), exceptTokenID)
+				assert.EqualValues(t, 5, sc.ToJSON().Get("count").MustInt64())
+			})
+	})
+
+	t.Run("When updating the session policy of the current org", func(t *testing.T) {
+		fakeAuthTokenService := auth.NewFakeUserAuthTokenService()
+		hs := &HTTPServer{AuthTokenService: fakeAuthTokenService}
+
+		var policy *models.SessionPolicy
+		fakeAuthTokenService.SetSessionPolicyProvider = func(ctx context.Context, p *models.SessionPolicy) error {
+			policy = p
+			if p.MaxLifetime > 30*24*time.Hour {
+				return models.ErrInvalidSessionPolicy
+			}
+			return nil
+		}
+
+		userSessionScenario(t, "Should parse the durations", "/api/org/session-policy", sessionToken(1),
+			func(c *models.ReqContext) response.Response {
+				return hs.UpdateOrgSessionPolicy(c, dtos.SessionPolicy{MaxInactiveLifetime: "12h", MaxLifetime: "7d", MaxConcurrentSessions: 3})
+			},
+			func(sc *scenarioContext) {
+				sc.fakeReqWithParams("PUT", sc.url, map[string]string{}).exec()
+				require.Equal(t, 200, sc.resp.Code)
+				assert.Equal(t, &models.SessionPolicy{
+					OrgId:                 testOrgID,
+					MaxInactiveLifetime:   12 * time.Hour,
+					MaxLifetime:           7 * 24 * time.Hour,
+					MaxConcurrentSessions: 3,
+				}, policy)
+			})
+
+		userSessionScenario(t, "Should reject lifetimes longer than the server settings", "/api/org/session-policy", sessionToken(1),
+			func(c *models.ReqContext) response.Response {
+				return hs.UpdateOrgSessionPolicy(c, dtos.SessionPolicy{MaxLifetime: "60d"})
+			},
+			func(sc *scenarioContext) {
+				sc.fakeReqWithParams("PUT", sc.url, map[string]string{}).exec()
+				assert.Equal(t, 400, sc.resp.Code)
+			})
+
+		userSessionScenario(t, "Should reject invalid durations", "/api/org/session-policy", sessionToken(1),
+			func(c *models.ReqContext) response.Response {
+				return hs.UpdateOrgSessionPolicy(c, dtos.SessionPolicy{MaxInactiveLifetime: "soon"})
+			},
+			func(sc *scenarioContext) {
+				sc.fakeReqWithParams("PUT", sc.url, map[string]string{}).exec()
+				assert.Equal(t, 400, sc.resp.Code)
+			})
+	})
+}
+
+func sessionToken(id int64) *models.UserToken {
+	return &models.UserToken{Id: id, UserId: testUserID}
+}
+
+func userSessionScenario(t *testing.T, desc string, url string, token *models.UserToken,
+	handler func(c *models.ReqContext) response.Response, fn scenarioFunc) {
+	t.Run(desc, func(t *testing.T) {
+		t.Cleanup(bus.ClearBusHandlers)
+
+		sc := setupScenarioContext(t, url)
+		sc.defaultHandler = routing.Wrap(func(c *models.ReqContext) response.Response {
+			sc.context = c
+			sc.context.UserId = testUserID
+			sc.context.OrgId = testOrgID
+			sc.context.OrgRole = models.ROLE_ADMIN
+			sc.context.UserToken = token
+
+			return handler(c)
+		})
+
+		sc.m.Any(url, sc.defaultHandler)
+
+		fn(sc)
+	})
+}
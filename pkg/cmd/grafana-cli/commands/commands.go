@@ -8,6 +8,7 @@ import (
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/services"
 	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/provisioning/export"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
@@ -130,6 +131,50 @@ var adminCommands = []*cli.Command{
 			},
 		},
 	},
+	{
+		Name:  "secrets",
+		Usage: "Manages the encryption of secrets in your db",
+		Subcommands: []*cli.Command{
+			{
+				Name: "rotate-master-key",
+				Usage: "Re-encrypts the secrets of data sources, alert notification channels and plugins with a new data key " +
+					"and master key, and deletes the previous data keys. Stop grafana before running it.",
+				Action: runDbCommand(rotateMasterKeyCommand),
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "old-secret-key",
+						Usage: "The secret_key that encrypted the secrets, if it was changed in the configuration",
+					},
+					&cli.BoolFlag{
+						Name:  "old-secret-key-from-stdin",
+						Usage: "Read the old secret_key from stdin",
+						Value: false,
+					},
+				},
+			},
+		},
+	},
+	{
+		Name:        "export-provisioning",
+		Usage:       "export-provisioning <output directory>",
+		Description: "Exports the data sources, alert notification channels, app plugin settings, teams, folders, preferences and dashboards to provisioning files",
+		Action:      runDbCommand(exportProvisioningCommand),
+		Flags: []cli.Flag{
+			&cli.StringFlag{
+				Name:  "secrets",
+				Usage: "How secrets are exported, either 'template' to reference environment variables or 'redact' to leave them empty",
+				Value: string(export.SecretsTemplate),
+			},
+			&cli.StringFlag{
+				Name:  "dashboards-path",
+				Usage: "The directory the dashboard providers load the exported dashboards from, defaults to the dashboards directory of the provisioning path",
+			},
+			&cli.IntFlag{
+				Name:  "org-id",
+				Usage: "Only export the organization with this id",
+			},
+		},
+	},
 }
 
 var Commands = []*cli.Command{
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/fatih/color"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/provisioning/export"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+func exportProvisioningCommand(c utils.CommandLine, sqlStore *sqlstore.SQLStore) error {
+	outputDir := c.Args().First()
+	if outputDir == "" {
+		return fmt.Errorf("missing output directory")
+	}
+
+	secrets, err := export.ParseSecretsMode(c.String("secrets"))
+	if err != nil {
+		return err
+	}
+
+	dashboardsPath := c.String("dashboards-path")
+	if dashboardsPath == "" {
+		dashboardsPath = filepath.Join(sqlStore.Cfg.ProvisioningPath, "dashboards")
+	}
+
+	result, err := export.Export(export.Options{
+		OrgID:          int64(c.Int("org-id")),
+		Secrets:        secrets,
+		DashboardsPath: dashboardsPath,
+	})
+	if err != nil {
+		return errutil.Wrapf(err, "failed to export provisioning files")
+	}
+
+	for _, f := range result.Files {
+		path := filepath.Join(outputDir, filepath.FromSlash(f.Path))
+		if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(f.Content), 0640); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("\n")
+	logger.Infof("%s Exported %d files to %s\n", color.GreenString("✔"), len(result.Files), outputDir)
+	if len(result.Variables) > 0 {
+		logger.Info("\nSet the following environment variables to the secrets before provisioning the files:\n")
+		for _, variable := range result.Variables {
+			logger.Infof("  %s\n", variable)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package commands
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/logger"
+	"github.com/grafana/grafana/pkg/cmd/grafana-cli/utils"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+func rotateMasterKeyCommand(c utils.CommandLine, sqlStore *sqlstore.SQLStore) error {
+	oldSecretKey := c.String("old-secret-key")
+
+	if c.Bool("old-secret-key-from-stdin") {
+		logger.Infof("Old secret key: ")
+
+		scanner := bufio.NewScanner(os.Stdin)
+		if ok := scanner.Scan(); !ok {
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("can't read secret key from stdin: %w", err)
+			}
+			return fmt.Errorf("can't read secret key from stdin")
+		}
+		oldSecretKey = scanner.Text()
+	}
+
+	result, err := secrets.RotateMasterKey(context.Background(), sqlStore.Cfg, sqlStore, oldSecretKey)
+	if err != nil {
+		return errutil.Wrapf(err, "failed to re-encrypt secrets")
+	}
+
+	logger.Infof("\n")
+	logger.Infof("%s Re-encrypted the secrets of %d data sources, %d alert notification channels and %d plugins\n",
+		color.GreenString("✔"), result.DataSources, result.AlertNotifications, result.PluginSettings)
+	logger.Infof("%s Re-encrypted the OAuth tokens of %d user logins, %d second factors and %d dashboard snapshots\n",
+		color.GreenString("✔"), result.OAuthTokens, result.MFASecrets, result.DashboardSnapshots)
+	logger.Info("\nRestart grafana to use the new keys. <service grafana-server restart>\n")
+
+	return nil
+}
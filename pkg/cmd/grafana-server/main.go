@@ -63,6 +63,9 @@ func main() {
 		profilePort = flag.Uint64("profile-port", 6060, "Define custom port for profiling")
 		tracing     = flag.Bool("tracing", false, "Turn on tracing")
 		tracingFile = flag.String("tracing-file", "trace.out", "Define tracing output file")
+
+		provisioningDryRun = flag.Bool("provisioning-dry-run", false,
+			"validate the provisioning config files, print the changes that provisioning them would apply and exit")
 	)
 
 	flag.Parse()
@@ -95,7 +98,7 @@ func main() {
 		}()
 	}
 
-	if err := executeServer(*configFile, *homePath, *pidFile, *packaging, traceDiagnostics); err != nil {
+	if err := executeServer(*configFile, *homePath, *pidFile, *packaging, *provisioningDryRun, traceDiagnostics); err != nil {
 		code := 1
 		var ewc exitWithCode
 		if errors.As(err, &ewc) {
@@ -109,7 +112,8 @@ func main() {
 	}
 }
 
-func executeServer(configFile, homePath, pidFile, packaging string, traceDiagnostics *tracingDiagnostics) error {
+func executeServer(configFile, homePath, pidFile, packaging string, provisioningDryRun bool,
+	traceDiagnostics *tracingDiagnostics) error {
 	defer func() {
 		if err := log.Close(); err != nil {
 			fmt.Fprintf(os.Stderr, "Failed to close log: %s\n", err)
@@ -151,6 +155,7 @@ func executeServer(configFile, homePath, pidFile, packaging string, traceDiagnos
 	s, err := server.New(server.Config{
 		ConfigFile: configFile, HomePath: homePath, PidFile: pidFile,
 		Version: version, Commit: commit, BuildBranch: buildBranch,
+		ProvisioningDryRun: provisioningDryRun,
 	})
 	if err != nil {
 		return err
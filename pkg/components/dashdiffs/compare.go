@@ -24,6 +24,8 @@ const (
 	DiffJSON DiffType = iota
 	DiffBasic
 	DiffDelta
+	DiffSemantic
+	DiffMarkdown
 )
 
 type Options struct {
@@ -51,6 +53,10 @@ func ParseDiffType(diff string) DiffType {
 		return DiffBasic
 	case "delta":
 		return DiffDelta
+	case "semantic":
+		return DiffSemantic
+	case "markdown":
+		return DiffMarkdown
 	}
 	return DiffBasic
 }
@@ -81,6 +87,10 @@ func CalculateDiff(options *Options) (*Result, error) {
 	baseData := baseVersionQuery.Result.Data
 	newData := newVersionQuery.Result.Data
 
+	if options.DiffType == DiffSemantic || options.DiffType == DiffMarkdown {
+		return calculateSemanticDiff(baseData, newData, options.DiffType)
+	}
+
 	left, jsonDiff, err := getDiff(baseData, newData)
 	if err != nil {
 		return nil, err
@@ -118,6 +128,25 @@ func CalculateDiff(options *Options) (*Result, error) {
 	return result, nil
 }
 
+// calculateSemanticDiff computes the semantic diff of two dashboard versions, as JSON or markdown.
+// Unlike the other diffs, identical versions have an empty diff.
+func calculateSemanticDiff(baseData, newData *simplejson.Json, diffType DiffType) (*Result, error) {
+	semanticDiff, err := NewSemanticDiff(baseData, newData)
+	if err != nil {
+		return nil, err
+	}
+
+	if diffType == DiffMarkdown {
+		return &Result{Delta: NewMarkdownFormatter().Format(semanticDiff)}, nil
+	}
+
+	jsonOutput, err := json.Marshal(semanticDiff)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Delta: jsonOutput}, nil
+}
+
 // getDiff computes the diff of two dashboard versions.
 func getDiff(baseData, newData *simplejson.Json) (interface{}, diff.Diff, error) {
 	leftBytes, err := baseData.Encode()
@@ -0,0 +1,143 @@
+package dashdiffs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// MarkdownFormatter renders a semantic diff as markdown, such as for comments on pull requests and
+// change notifications.
+type MarkdownFormatter struct{}
+
+// NewMarkdownFormatter returns a new markdown formatter.
+func NewMarkdownFormatter() *MarkdownFormatter {
+	return &MarkdownFormatter{}
+}
+
+// Format renders the semantic diff as markdown.
+func (f *MarkdownFormatter) Format(d *SemanticDiff) []byte {
+	var b bytes.Buffer
+
+	if d.IsEmpty() {
+		b.WriteString("No changes to panels, variables or time settings.\n")
+		return b.Bytes()
+	}
+
+	if len(d.Panels) > 0 {
+		b.WriteString("### Panels\n\n")
+		for _, panel := range d.Panels {
+			fmt.Fprintf(&b, "- %s %s", changeVerb(panel.Change), panelName(panel))
+			if panel.Change == SemanticChanged && panel.Moved {
+				b.WriteString(", moved")
+			}
+			if len(panel.Properties) > 0 {
+				fmt.Fprintf(&b, ": %s", codeList(panel.Properties))
+			}
+			b.WriteString("\n")
+
+			for _, query := range panel.Queries {
+				fmt.Fprintf(&b, "  - %s query %s", changeVerb(query.Change), code(query.RefId))
+				switch query.Change {
+				case SemanticAdded:
+					fmt.Fprintf(&b, ": %s", code(queryExpression(query.After)))
+				case SemanticChanged:
+					fmt.Fprintf(&b, ": %s → %s", code(queryExpression(query.Before)), code(queryExpression(query.After)))
+				}
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString("\n")
+	}
+
+	if len(d.Variables) > 0 {
+		b.WriteString("### Variables\n\n")
+		for _, variable := range d.Variables {
+			fmt.Fprintf(&b, "- %s variable %s", changeVerb(variable.Change), code(variable.Name))
+			if len(variable.Properties) > 0 {
+				fmt.Fprintf(&b, ": %s", codeList(variable.Properties))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+
+	if len(d.Time) > 0 {
+		b.WriteString("### Time settings\n\n")
+		for _, setting := range d.Time {
+			fmt.Fprintf(&b, "- Changed %s: %s → %s\n", code(setting.Setting), code(encodeValue(setting.Before)), code(encodeValue(setting.After)))
+		}
+		b.WriteString("\n")
+	}
+
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n"))
+}
+
+func changeVerb(change SemanticChangeType) string {
+	switch change {
+	case SemanticAdded:
+		return "Added"
+	case SemanticRemoved:
+		return "Removed"
+	case SemanticMoved:
+		return "Moved"
+	default:
+		return "Changed"
+	}
+}
+
+func panelName(panel *PanelChange) string {
+	title := panel.Title
+	if title == "" {
+		title = "Untitled"
+	}
+	return fmt.Sprintf("panel **%s** (id %d)", strings.ReplaceAll(title, "*", "\\*"), panel.Id)
+}
+
+// queryExpression returns the expression of a query, or the query as JSON if its data source doesn't
+// have one.
+func queryExpression(query interface{}) string {
+	target := simplejson.NewFromAny(query)
+	for _, key := range models.TargetExpressionKeys {
+		if expr := target.Get(key).MustString(); expr != "" {
+			return expr
+		}
+	}
+	return encodeValue(query)
+}
+
+func encodeValue(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func codeList(values []string) string {
+	codes := make([]string, 0, len(values))
+	for _, value := range values {
+		codes = append(codes, code(value))
+	}
+	return strings.Join(codes, ", ")
+}
+
+// code renders a value as inline code. Newlines are replaced by spaces, and values that contain
+// backticks are fenced with double backticks.
+func code(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if strings.Contains(value, "`") {
+		return "`` " + value + " ``"
+	}
+	return "`" + value + "`"
+}
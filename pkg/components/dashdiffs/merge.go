@@ -0,0 +1,295 @@
+package dashdiffs
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// MergeConflict is a value of a dashboard that was changed differently by both sides of a merge.
+type MergeConflict struct {
+	// Path is the path of the value, where panels are identified by their id and template
+	// variables and annotations by their name, e.g. `panels[id=2].title`.
+	Path string `json:"path"`
+	// Base is the value in the version both sides started from.
+	Base interface{} `json:"base"`
+	// Ours is the value in the dashboard that is being saved.
+	Ours interface{} `json:"ours"`
+	// Theirs is the value in the dashboard that was saved by someone else.
+	Theirs interface{} `json:"theirs"`
+}
+
+type missingValue struct{}
+
+// missing is the value of keys and array elements that don't exist on one side of a merge.
+var missing = missingValue{}
+
+// keyedArrays are the arrays whose elements are merged one by one, by the key that identifies them.
+// All other arrays are merged as a single value.
+var keyedArrays = map[string]string{
+	"panels":           "id",
+	"templating.list":  "name",
+	"annotations.list": "name",
+}
+
+// Merge merges the changes that were made to base by two sides, and returns the merged dashboard
+// along with the values that both sides changed differently. Changes to different panels, template
+// variables and annotations merge cleanly, and so do changes to different properties of the same
+// panel. Panels that both sides added with the same id are both kept, and ours gets a new id. The
+// version of the merged dashboard is the version of theirs.
+func Merge(base, ours, theirs *simplejson.Json) (*simplejson.Json, []*MergeConflict, error) {
+	// the dashboards are encoded and decoded so numbers are compared as json.Number on all sides
+	values := make([]map[string]interface{}, 0, 3)
+	for _, dash := range []*simplejson.Json{base, ours, theirs} {
+		normalized, err := normalize(dash)
+		if err != nil {
+			return nil, nil, err
+		}
+		values = append(values, normalized)
+	}
+
+	version := values[2]["version"]
+	for _, value := range values {
+		delete(value, "version")
+	}
+
+	m := &merger{conflicts: []*MergeConflict{}, nextPanelID: maxPanelID(values[1], values[2]) + 1}
+	merged := simplejson.NewFromAny(m.merge("", values[0], values[1], values[2]))
+	if version != nil {
+		merged.Set("version", version)
+	}
+
+	return merged, m.conflicts, nil
+}
+
+func normalize(dash *simplejson.Json) (map[string]interface{}, error) {
+	data, err := dash.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	normalized, err := simplejson.NewJson(data)
+	if err != nil {
+		return nil, err
+	}
+	return normalized.MustMap(map[string]interface{}{}), nil
+}
+
+type merger struct {
+	conflicts   []*MergeConflict
+	nextPanelID int64
+}
+
+func (m *merger) merge(path string, base, ours, theirs interface{}) interface{} {
+	switch {
+	case reflect.DeepEqual(ours, theirs):
+		return ours
+	case reflect.DeepEqual(base, ours):
+		return theirs
+	case reflect.DeepEqual(base, theirs):
+		return ours
+	}
+
+	baseObject, baseIsObject := base.(map[string]interface{})
+	oursObject, oursIsObject := ours.(map[string]interface{})
+	theirsObject, theirsIsObject := theirs.(map[string]interface{})
+	// objects that both sides added differently are a conflict rather than merged, since they are
+	// likely unrelated
+	if oursIsObject && theirsIsObject && baseIsObject {
+		return m.mergeObjects(path, baseObject, oursObject, theirsObject)
+	}
+
+	if key, ok := keyedArrays[arrayName(path)]; ok {
+		baseArray, baseOk := keyArray(base, key)
+		oursArray, oursOk := keyArray(ours, key)
+		theirsArray, theirsOk := keyArray(theirs, key)
+		if baseOk && oursOk && theirsOk {
+			return m.mergeArrays(path, key, baseArray, oursArray, theirsArray)
+		}
+	}
+
+	m.conflicts = append(m.conflicts, &MergeConflict{
+		Path:   path,
+		Base:   conflictValue(base),
+		Ours:   conflictValue(ours),
+		Theirs: conflictValue(theirs),
+	})
+	return theirs
+}
+
+func (m *merger) mergeObjects(path string, base, ours, theirs map[string]interface{}) map[string]interface{} {
+	keys := map[string]bool{}
+	for _, object := range []map[string]interface{}{base, ours, theirs} {
+		for key := range object {
+			keys[key] = true
+		}
+	}
+
+	sortedKeys := make([]string, 0, len(keys))
+	for key := range keys {
+		sortedKeys = append(sortedKeys, key)
+	}
+	sort.Strings(sortedKeys)
+
+	merged := map[string]interface{}{}
+	for _, key := range sortedKeys {
+		keyPath := key
+		if path != "" {
+			keyPath = path + "." + key
+		}
+
+		value := m.merge(keyPath, lookup(base, key), lookup(ours, key), lookup(theirs, key))
+		if value != missing {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// mergeArrays merges the elements of arrays by their key. The merged array has the order of theirs,
+// followed by the elements that were only added by ours.
+func (m *merger) mergeArrays(path, key string, base, ours, theirs *keyedArray) []interface{} {
+	keys := append([]string{}, theirs.keys...)
+	var added []interface{}
+	for _, k := range ours.keys {
+		theirsElement, ok := theirs.elements[k]
+		if !ok {
+			keys = append(keys, k)
+			continue
+		}
+
+		// concurrently added panels usually get the same id, since it's the next free one
+		if arrayName(path) == "panels" && base.get(k) == missing && !reflect.DeepEqual(ours.elements[k], theirsElement) {
+			added = append(added, m.newPanelIDs(ours.elements[k]))
+			ours.elements[k] = theirsElement
+		}
+	}
+
+	// elements that were deleted by one side are missing from the merged array, unless the other side changed them
+	merged := make([]interface{}, 0, len(keys))
+	for _, k := range keys {
+		elementPath := fmt.Sprintf("%s[%s=%s]", path, key, k)
+		value := m.merge(elementPath, base.get(k), ours.get(k), theirs.get(k))
+		if value != missing {
+			merged = append(merged, value)
+		}
+	}
+	return append(merged, added...)
+}
+
+// newPanelIDs returns a copy of a panel and of its nested panels with new ids.
+func (m *merger) newPanelIDs(panel interface{}) interface{} {
+	object, ok := panel.(map[string]interface{})
+	if !ok {
+		return panel
+	}
+
+	copied := make(map[string]interface{}, len(object))
+	for key, value := range object {
+		copied[key] = value
+	}
+	copied["id"] = json.Number(strconv.FormatInt(m.nextPanelID, 10))
+	m.nextPanelID++
+
+	if nested, ok := object["panels"].([]interface{}); ok {
+		panels := make([]interface{}, 0, len(nested))
+		for _, p := range nested {
+			panels = append(panels, m.newPanelIDs(p))
+		}
+		copied["panels"] = panels
+	}
+	return copied
+}
+
+// maxPanelID returns the highest id of the panels and nested panels of dashboards.
+func maxPanelID(dashboards ...map[string]interface{}) int64 {
+	var max int64
+	var walk func(panels interface{})
+	walk = func(panels interface{}) {
+		elements, _ := panels.([]interface{})
+		for _, element := range elements {
+			object, ok := element.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if id, err := strconv.ParseInt(fmt.Sprint(object["id"]), 10, 64); err == nil && id > max {
+				max = id
+			}
+			walk(object["panels"])
+		}
+	}
+
+	for _, dash := range dashboards {
+		walk(dash["panels"])
+	}
+	return max
+}
+
+type keyedArray struct {
+	keys     []string
+	elements map[string]interface{}
+}
+
+func (a *keyedArray) get(key string) interface{} {
+	if element, ok := a.elements[key]; ok {
+		return element
+	}
+	return missing
+}
+
+// keyArray indexes the elements of an array by their key. It fails if an element doesn't have a key
+// or if keys aren't unique.
+func keyArray(value interface{}, key string) (*keyedArray, bool) {
+	a := &keyedArray{elements: map[string]interface{}{}}
+	if value == missing || value == nil {
+		return a, true
+	}
+
+	elements, ok := value.([]interface{})
+	if !ok {
+		return nil, false
+	}
+
+	for _, element := range elements {
+		object, ok := element.(map[string]interface{})
+		if !ok || object[key] == nil {
+			return nil, false
+		}
+
+		k := fmt.Sprint(object[key])
+		if _, exists := a.elements[k]; exists {
+			return nil, false
+		}
+		a.keys = append(a.keys, k)
+		a.elements[k] = element
+	}
+	return a, true
+}
+
+// arrayName returns the path of an array without the keys of the elements it's nested in, so nested
+// panels of rows are keyed like top level panels.
+func arrayName(path string) string {
+	if i := strings.LastIndex(path, "]."); i >= 0 {
+		return path[i+2:]
+	}
+	return path
+}
+
+func lookup(object map[string]interface{}, key string) interface{} {
+	if value, ok := object[key]; ok {
+		return value
+	}
+	return missing
+}
+
+func conflictValue(value interface{}) interface{} {
+	if value == missing {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,186 @@
+package dashdiffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestMerge(t *testing.T) {
+	const baseJSON = `{
+		"title": "Dashboard",
+		"version": 1,
+		"tags": ["a"],
+		"panels": [
+			{"id": 1, "type": "graph", "title": "CPU", "targets": [{"refId": "A", "expr": "cpu"}]},
+			{"id": 2, "type": "graph", "title": "Memory"},
+			{"id": 3, "type": "row", "collapsed": true, "panels": [{"id": 4, "type": "stat", "title": "Disk"}]}
+		],
+		"templating": {"list": [{"name": "host", "query": "hosts"}]}
+	}`
+
+	parse := func(t *testing.T, data string) *simplejson.Json {
+		t.Helper()
+		json, err := simplejson.NewJson([]byte(data))
+		require.NoError(t, err)
+		return json
+	}
+
+	// change applies a change to a copy of the base dashboard
+	change := func(t *testing.T, fn func(dash *simplejson.Json)) *simplejson.Json {
+		t.Helper()
+		dash := parse(t, baseJSON)
+		fn(dash)
+		return dash
+	}
+
+	panel := func(dash *simplejson.Json, index int) *simplejson.Json {
+		return dash.Get("panels").GetIndex(index)
+	}
+
+	t.Run("Merges changes to different panels and properties", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			panel(dash, 0).Set("title", "CPU usage")
+			panel(dash, 1).Set("description", "Memory of all hosts")
+			dash.Set("version", 1)
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			panel(dash, 1).Set("title", "Memory usage")
+			dash.Set("tags", []interface{}{"a", "b"})
+			dash.Set("version", 2)
+		})
+
+		merged, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Empty(t, conflicts)
+
+		assert.Equal(t, "CPU usage", panel(merged, 0).Get("title").MustString())
+		assert.Equal(t, "Memory usage", panel(merged, 1).Get("title").MustString())
+		assert.Equal(t, "Memory of all hosts", panel(merged, 1).Get("description").MustString())
+		assert.Equal(t, []string{"a", "b"}, merged.Get("tags").MustStringArray())
+		assert.Equal(t, int64(2), merged.Get("version").MustInt64())
+	})
+
+	t.Run("Merges added and deleted panels", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", append(panels, map[string]interface{}{"id": 5, "type": "text"}))
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", []interface{}{panels[0], panels[2]})
+		})
+
+		merged, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Empty(t, conflicts)
+
+		ids := []int64{}
+		for i := range merged.Get("panels").MustArray() {
+			ids = append(ids, panel(merged, i).Get("id").MustInt64())
+		}
+		assert.Equal(t, []int64{1, 3, 5}, ids)
+	})
+
+	t.Run("Merges changes to panels of collapsed rows and template variables", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			panel(dash, 2).Get("panels").GetIndex(0).Set("title", "Disk usage")
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			panels := panel(dash, 2).Get("panels").MustArray()
+			panel(dash, 2).Set("panels", append(panels, map[string]interface{}{"id": 6, "type": "stat"}))
+			variables := dash.Get("templating").Get("list").MustArray()
+			dash.Get("templating").Set("list", append(variables, map[string]interface{}{"name": "env"}))
+		})
+
+		merged, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Empty(t, conflicts)
+
+		rowPanels := panel(merged, 2).Get("panels")
+		require.Len(t, rowPanels.MustArray(), 2)
+		assert.Equal(t, "Disk usage", rowPanels.GetIndex(0).Get("title").MustString())
+		assert.Len(t, merged.Get("templating").Get("list").MustArray(), 2)
+	})
+
+	t.Run("Returns conflicts for values changed by both sides", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			panel(dash, 0).Set("title", "CPU usage")
+			panel(dash, 1).Set("title", "Memory usage")
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			panel(dash, 0).Set("title", "CPU load")
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", []interface{}{panels[0], panels[2]})
+		})
+
+		_, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 2)
+
+		assert.Equal(t, "panels[id=1].title", conflicts[0].Path)
+		assert.Equal(t, "CPU", conflicts[0].Base)
+		assert.Equal(t, "CPU usage", conflicts[0].Ours)
+		assert.Equal(t, "CPU load", conflicts[0].Theirs)
+
+		assert.Equal(t, "panels[id=2]", conflicts[1].Path)
+		assert.NotNil(t, conflicts[1].Ours)
+		assert.Nil(t, conflicts[1].Theirs)
+	})
+
+	t.Run("Returns a conflict for arrays without keys changed by both sides", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			dash.Set("tags", []interface{}{"a", "b"})
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			dash.Set("tags", []interface{}{"c"})
+		})
+
+		_, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "tags", conflicts[0].Path)
+	})
+
+	t.Run("Keeps panels that both sides added with the same id", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", append(panels, map[string]interface{}{"id": 5, "type": "text", "title": "Notes"}))
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			panels := dash.Get("panels").MustArray()
+			dash.Set("panels", append(panels, map[string]interface{}{"id": 5, "type": "graph", "title": "Network"}))
+		})
+
+		merged, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Empty(t, conflicts)
+
+		require.Len(t, merged.Get("panels").MustArray(), 5)
+		assert.Equal(t, int64(5), panel(merged, 3).Get("id").MustInt64())
+		assert.Equal(t, "Network", panel(merged, 3).Get("title").MustString())
+		assert.Equal(t, int64(6), panel(merged, 4).Get("id").MustInt64())
+		assert.Equal(t, "Notes", panel(merged, 4).Get("title").MustString())
+		assert.Equal(t, "text", panel(merged, 4).Get("type").MustString())
+	})
+
+	t.Run("Returns a conflict for values that both sides added differently", func(t *testing.T) {
+		ours := change(t, func(dash *simplejson.Json) {
+			list := dash.Get("templating").Get("list").MustArray()
+			dash.Get("templating").Set("list", append(list, map[string]interface{}{"name": "env", "query": "envs"}))
+		})
+		theirs := change(t, func(dash *simplejson.Json) {
+			list := dash.Get("templating").Get("list").MustArray()
+			dash.Get("templating").Set("list", append(list, map[string]interface{}{"name": "env", "type": "custom"}))
+		})
+
+		_, conflicts, err := Merge(parse(t, baseJSON), ours, theirs)
+		require.NoError(t, err)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "templating.list[name=env]", conflicts[0].Path)
+		assert.Nil(t, conflicts[0].Base)
+	})
+}
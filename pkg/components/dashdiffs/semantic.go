@@ -0,0 +1,306 @@
+package dashdiffs
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// SemanticChangeType is the kind of change to a panel, query or variable.
+type SemanticChangeType string
+
+const (
+	SemanticAdded   SemanticChangeType = "added"
+	SemanticRemoved SemanticChangeType = "removed"
+	SemanticMoved   SemanticChangeType = "moved"
+	SemanticChanged SemanticChangeType = "changed"
+)
+
+// timeSettings are the paths of the time settings of a dashboard.
+var timeSettings = [][]string{
+	{"time", "from"},
+	{"time", "to"},
+	{"timezone"},
+	{"refresh"},
+	{"timepicker", "hidden"},
+	{"timepicker", "nowDelay"},
+	{"timepicker", "refresh_intervals"},
+}
+
+// SemanticDiff is the diff of two dashboards in terms of their panels, queries, variables and time
+// settings.
+type SemanticDiff struct {
+	Panels    []*PanelChange    `json:"panels"`
+	Variables []*VariableChange `json:"variables"`
+	Time      []*SettingChange  `json:"time"`
+}
+
+// IsEmpty returns true if the dashboards don't differ in their panels, variables or time settings.
+func (d *SemanticDiff) IsEmpty() bool {
+	return len(d.Panels) == 0 && len(d.Variables) == 0 && len(d.Time) == 0
+}
+
+// PanelChange is a panel that was added, removed, moved or changed. Panels are matched by their id.
+// A changed panel might have been moved too.
+type PanelChange struct {
+	Id     int64              `json:"id"`
+	Title  string             `json:"title"`
+	Type   string             `json:"type"`
+	Change SemanticChangeType `json:"change"`
+	Moved  bool               `json:"moved"`
+	// Properties are the properties of a changed panel that differ, other than its queries.
+	Properties []string       `json:"properties,omitempty"`
+	Queries    []*QueryChange `json:"queries,omitempty"`
+}
+
+// QueryChange is a query of a panel that was added, removed or changed. Queries are matched by their
+// refId.
+type QueryChange struct {
+	RefId  string             `json:"refId"`
+	Change SemanticChangeType `json:"change"`
+	Before interface{}        `json:"before,omitempty"`
+	After  interface{}        `json:"after,omitempty"`
+}
+
+// VariableChange is a template variable that was added, removed or changed. Variables are matched by
+// their name.
+type VariableChange struct {
+	Name       string             `json:"name"`
+	Change     SemanticChangeType `json:"change"`
+	Properties []string           `json:"properties,omitempty"`
+}
+
+// SettingChange is a time setting that was changed, such as `time.from`.
+type SettingChange struct {
+	Setting string      `json:"setting"`
+	Before  interface{} `json:"before"`
+	After   interface{} `json:"after"`
+}
+
+// NewSemanticDiff returns the semantic diff of two dashboards.
+func NewSemanticDiff(baseData, newData *simplejson.Json) (*SemanticDiff, error) {
+	base, err := normalize(baseData)
+	if err != nil {
+		return nil, err
+	}
+	newDash, err := normalize(newData)
+	if err != nil {
+		return nil, err
+	}
+	left, right := simplejson.NewFromAny(base), simplejson.NewFromAny(newDash)
+
+	baseIds, basePanels := collectPanels(left)
+	newIds, newPanels := collectPanels(right)
+	return &SemanticDiff{
+		Panels:    diffPanels(baseIds, basePanels, newIds, newPanels),
+		Variables: diffVariables(left.GetPath("templating", "list"), right.GetPath("templating", "list")),
+		Time:      diffTimeSettings(left, right),
+	}, nil
+}
+
+type panelInfo struct {
+	json *simplejson.Json
+	// row is the id of the collapsed row the panel is in, or 0
+	row int64
+}
+
+// collectPanels returns the panels of a dashboard by id, in their order, including the panels of
+// collapsed rows. Panels without an id are skipped.
+func collectPanels(dash *simplejson.Json) ([]int64, map[int64]*panelInfo) {
+	ids := []int64{}
+	panels := map[int64]*panelInfo{}
+
+	var collect func(list *simplejson.Json, row int64)
+	collect = func(list *simplejson.Json, row int64) {
+		for i := range list.MustArray() {
+			panel := list.GetIndex(i)
+			id, err := panel.Get("id").Int64()
+			if err != nil {
+				continue
+			}
+			if _, exists := panels[id]; !exists {
+				ids = append(ids, id)
+				panels[id] = &panelInfo{json: panel, row: row}
+			}
+			collect(panel.Get("panels"), id)
+		}
+	}
+	collect(dash.Get("panels"), 0)
+
+	return ids, panels
+}
+
+func diffPanels(baseIds []int64, base map[int64]*panelInfo, newIds []int64, newPanels map[int64]*panelInfo) []*PanelChange {
+	changes := []*PanelChange{}
+
+	for _, id := range newIds {
+		after := newPanels[id]
+		change := newPanelChange(id, after.json)
+
+		before, exists := base[id]
+		if !exists {
+			change.Change = SemanticAdded
+			changes = append(changes, change)
+			continue
+		}
+
+		change.Moved = before.row != after.row ||
+			!reflect.DeepEqual(before.json.Get("gridPos").Interface(), after.json.Get("gridPos").Interface())
+		change.Properties = diffProperties(before.json, after.json, "id", "gridPos", "targets", "panels")
+		change.Queries = diffQueries(before.json.Get("targets"), after.json.Get("targets"))
+
+		switch {
+		case len(change.Properties) > 0 || len(change.Queries) > 0:
+			change.Change = SemanticChanged
+		case change.Moved:
+			change.Change = SemanticMoved
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+
+	for _, id := range baseIds {
+		if _, exists := newPanels[id]; !exists {
+			change := newPanelChange(id, base[id].json)
+			change.Change = SemanticRemoved
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+func newPanelChange(id int64, panel *simplejson.Json) *PanelChange {
+	return &PanelChange{
+		Id:    id,
+		Title: panel.Get("title").MustString(),
+		Type:  panel.Get("type").MustString(),
+	}
+}
+
+// diffQueries matches the queries of a panel by refId, or by their index if they don't have one.
+func diffQueries(baseTargets, newTargets *simplejson.Json) []*QueryChange {
+	refIds := func(targets *simplejson.Json) ([]string, map[string]interface{}) {
+		ids := []string{}
+		queries := map[string]interface{}{}
+		for i, target := range targets.MustArray() {
+			refId := simplejson.NewFromAny(target).Get("refId").MustString()
+			if refId == "" {
+				refId = fmt.Sprint(i)
+			}
+			if _, exists := queries[refId]; !exists {
+				ids = append(ids, refId)
+				queries[refId] = target
+			}
+		}
+		return ids, queries
+	}
+	baseIds, base := refIds(baseTargets)
+	newIds, newQueries := refIds(newTargets)
+
+	changes := []*QueryChange{}
+	for _, refId := range newIds {
+		before, exists := base[refId]
+		switch {
+		case !exists:
+			changes = append(changes, &QueryChange{RefId: refId, Change: SemanticAdded, After: newQueries[refId]})
+		case !reflect.DeepEqual(before, newQueries[refId]):
+			changes = append(changes, &QueryChange{RefId: refId, Change: SemanticChanged, Before: before, After: newQueries[refId]})
+		}
+	}
+	for _, refId := range baseIds {
+		if _, exists := newQueries[refId]; !exists {
+			changes = append(changes, &QueryChange{RefId: refId, Change: SemanticRemoved, Before: base[refId]})
+		}
+	}
+	return changes
+}
+
+func diffVariables(baseList, newList *simplejson.Json) []*VariableChange {
+	byName := func(list *simplejson.Json) ([]string, map[string]*simplejson.Json) {
+		names := []string{}
+		variables := map[string]*simplejson.Json{}
+		for i := range list.MustArray() {
+			variable := list.GetIndex(i)
+			name := variable.Get("name").MustString()
+			if _, exists := variables[name]; !exists {
+				names = append(names, name)
+				variables[name] = variable
+			}
+		}
+		return names, variables
+	}
+	baseNames, base := byName(baseList)
+	newNames, newVariables := byName(newList)
+
+	changes := []*VariableChange{}
+	for _, name := range newNames {
+		before, exists := base[name]
+		if !exists {
+			changes = append(changes, &VariableChange{Name: name, Change: SemanticAdded})
+			continue
+		}
+		// the current value and options of variables change whenever they're refreshed
+		if properties := diffProperties(before, newVariables[name], "current", "options"); len(properties) > 0 {
+			changes = append(changes, &VariableChange{Name: name, Change: SemanticChanged, Properties: properties})
+		}
+	}
+	for _, name := range baseNames {
+		if _, exists := newVariables[name]; !exists {
+			changes = append(changes, &VariableChange{Name: name, Change: SemanticRemoved})
+		}
+	}
+	return changes
+}
+
+func diffTimeSettings(base, newDash *simplejson.Json) []*SettingChange {
+	changes := []*SettingChange{}
+	for _, path := range timeSettings {
+		before := base.GetPath(path...).Interface()
+		after := newDash.GetPath(path...).Interface()
+		if !reflect.DeepEqual(before, after) {
+			setting := path[0]
+			for _, key := range path[1:] {
+				setting += "." + key
+			}
+			changes = append(changes, &SettingChange{Setting: setting, Before: before, After: after})
+		}
+	}
+	return changes
+}
+
+// diffProperties returns the sorted keys of two objects whose values differ, except for the ignored
+// keys.
+func diffProperties(base, newObject *simplejson.Json, ignored ...string) []string {
+	skip := map[string]bool{}
+	for _, key := range ignored {
+		skip[key] = true
+	}
+
+	before := base.MustMap(map[string]interface{}{})
+	after := newObject.MustMap(map[string]interface{}{})
+	keys := map[string]bool{}
+	for key := range before {
+		keys[key] = true
+	}
+	for key := range after {
+		keys[key] = true
+	}
+
+	properties := []string{}
+	for key := range keys {
+		if skip[key] {
+			continue
+		}
+		beforeValue, beforeOk := before[key]
+		afterValue, afterOk := after[key]
+		if beforeOk != afterOk || !reflect.DeepEqual(beforeValue, afterValue) {
+			properties = append(properties, key)
+		}
+	}
+	sort.Strings(properties)
+	return properties
+}
@@ -0,0 +1,126 @@
+package dashdiffs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+func TestSemanticDiff(t *testing.T) {
+	const (
+		baseJSON = `{
+			"title": "Dashboard",
+			"time": {"from": "now-6h", "to": "now"},
+			"refresh": "1m",
+			"panels": [
+				{"id": 1, "type": "graph", "title": "CPU", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+					"targets": [{"refId": "A", "expr": "cpu"}, {"refId": "B", "expr": "load"}]},
+				{"id": 2, "type": "graph", "title": "Memory", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8}},
+				{"id": 3, "type": "text", "title": "Notes", "gridPos": {"x": 0, "y": 8, "w": 24, "h": 4}},
+				{"id": 4, "type": "row", "title": "Disks", "collapsed": true, "gridPos": {"x": 0, "y": 12, "w": 24, "h": 1},
+					"panels": [{"id": 5, "type": "stat", "title": "Disk", "gridPos": {"x": 0, "y": 13, "w": 6, "h": 4}}]}
+			],
+			"templating": {"list": [
+				{"name": "host", "type": "query", "query": "hosts", "current": {"value": "a"}},
+				{"name": "env", "type": "custom", "query": "dev,prod"}
+			]}
+		}`
+
+		newJSON = `{
+			"title": "Dashboard",
+			"time": {"from": "now-24h", "to": "now"},
+			"refresh": "1m",
+			"panels": [
+				{"id": 1, "type": "graph", "title": "CPU usage", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+					"targets": [{"refId": "A", "expr": "sum(cpu)"}, {"refId": "C", "expr": "steal"}]},
+				{"id": 2, "type": "graph", "title": "Memory", "gridPos": {"x": 12, "y": 4, "w": 12, "h": 8}},
+				{"id": 4, "type": "row", "title": "Disks", "collapsed": true, "gridPos": {"x": 0, "y": 12, "w": 24, "h": 1},
+					"panels": [{"id": 5, "type": "stat", "title": "Disk", "gridPos": {"x": 0, "y": 13, "w": 6, "h": 4}}]},
+				{"id": 6, "type": "table", "title": "Processes", "gridPos": {"x": 0, "y": 20, "w": 24, "h": 8}}
+			],
+			"templating": {"list": [
+				{"name": "host", "type": "query", "query": "hosts", "current": {"value": "b"}},
+				{"name": "region", "type": "custom", "query": "eu,us"}
+			]}
+		}`
+	)
+
+	parse := func(t *testing.T, data string) *simplejson.Json {
+		t.Helper()
+		json, err := simplejson.NewJson([]byte(data))
+		require.NoError(t, err)
+		return json
+	}
+
+	diff, err := NewSemanticDiff(parse(t, baseJSON), parse(t, newJSON))
+	require.NoError(t, err)
+
+	t.Run("Panels are matched by id", func(t *testing.T) {
+		require.Len(t, diff.Panels, 4)
+
+		cpu := diff.Panels[0]
+		assert.Equal(t, int64(1), cpu.Id)
+		assert.Equal(t, "CPU usage", cpu.Title)
+		assert.Equal(t, SemanticChanged, cpu.Change)
+		assert.False(t, cpu.Moved)
+		assert.Equal(t, []string{"title"}, cpu.Properties)
+
+		require.Len(t, cpu.Queries, 3)
+		assert.Equal(t, "A", cpu.Queries[0].RefId)
+		assert.Equal(t, SemanticChanged, cpu.Queries[0].Change)
+		assert.Equal(t, "C", cpu.Queries[1].RefId)
+		assert.Equal(t, SemanticAdded, cpu.Queries[1].Change)
+		assert.Equal(t, "B", cpu.Queries[2].RefId)
+		assert.Equal(t, SemanticRemoved, cpu.Queries[2].Change)
+
+		assert.Equal(t, int64(2), diff.Panels[1].Id)
+		assert.Equal(t, SemanticMoved, diff.Panels[1].Change)
+		assert.True(t, diff.Panels[1].Moved)
+
+		assert.Equal(t, int64(6), diff.Panels[2].Id)
+		assert.Equal(t, SemanticAdded, diff.Panels[2].Change)
+
+		assert.Equal(t, int64(3), diff.Panels[3].Id)
+		assert.Equal(t, SemanticRemoved, diff.Panels[3].Change)
+	})
+
+	t.Run("Variables are matched by name, ignoring their current value", func(t *testing.T) {
+		require.Len(t, diff.Variables, 2)
+		assert.Equal(t, &VariableChange{Name: "region", Change: SemanticAdded}, diff.Variables[0])
+		assert.Equal(t, &VariableChange{Name: "env", Change: SemanticRemoved}, diff.Variables[1])
+	})
+
+	t.Run("Time settings", func(t *testing.T) {
+		require.Len(t, diff.Time, 1)
+		assert.Equal(t, &SettingChange{Setting: "time.from", Before: "now-6h", After: "now-24h"}, diff.Time[0])
+	})
+
+	t.Run("Markdown", func(t *testing.T) {
+		expected := "### Panels\n\n" +
+			"- Changed panel **CPU usage** (id 1): `title`\n" +
+			"  - Changed query `A`: `cpu` → `sum(cpu)`\n" +
+			"  - Added query `C`: `steal`\n" +
+			"  - Removed query `B`\n" +
+			"- Moved panel **Memory** (id 2)\n" +
+			"- Added panel **Processes** (id 6)\n" +
+			"- Removed panel **Notes** (id 3)\n" +
+			"\n" +
+			"### Variables\n\n" +
+			"- Added variable `region`\n" +
+			"- Removed variable `env`\n" +
+			"\n" +
+			"### Time settings\n\n" +
+			"- Changed `time.from`: `now-6h` → `now-24h`\n"
+		assert.Equal(t, expected, string(NewMarkdownFormatter().Format(diff)))
+	})
+
+	t.Run("Identical dashboards have an empty diff", func(t *testing.T) {
+		diff, err := NewSemanticDiff(parse(t, baseJSON), parse(t, baseJSON))
+		require.NoError(t, err)
+		assert.True(t, diff.IsEmpty())
+		assert.Equal(t, "No changes to panels, variables or time settings.\n", string(NewMarkdownFormatter().Format(diff)))
+	})
+}
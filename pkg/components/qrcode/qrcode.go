@@ -0,0 +1,67 @@
+// Package qrcode renders QR codes with error correction level M, for short payloads such as otpauth:// URIs.
+// The symbols are encoded by github.com/boombuler/barcode.
+package qrcode
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/qr"
+)
+
+// quietZone is the width of the light border around the symbol, in modules.
+const quietZone = 4
+
+// Code is an encoded QR code symbol.
+type Code struct {
+	// Size is the number of modules per side.
+	Size int
+
+	symbol barcode.Barcode
+}
+
+// New encodes content, using the smallest version and the most compact mode it fits in.
+func New(content string) (*Code, error) {
+	symbol, err := qr.Encode(content, qr.M, qr.Auto)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Code{Size: symbol.Bounds().Dx(), symbol: symbol}, nil
+}
+
+// Dark returns true if the module at column x and row y is dark.
+func (c *Code) Dark(x, y int) bool {
+	gray, _ := color.GrayModel.Convert(c.symbol.At(x, y)).(color.Gray)
+	return gray.Y < 128
+}
+
+// PNG renders the code as a black and white PNG image, with scale pixels per module.
+func (c *Code) PNG(scale int) ([]byte, error) {
+	if scale < 1 {
+		scale = 1
+	}
+
+	width := (c.Size + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, width, width))
+	for py := 0; py < width; py++ {
+		for px := 0; px < width; px++ {
+			x := px/scale - quietZone
+			y := py/scale - quietZone
+			if x >= 0 && y >= 0 && x < c.Size && y < c.Size && c.Dark(x, y) {
+				img.SetGray(px, py, color.Gray{Y: 0})
+			} else {
+				img.SetGray(px, py, color.Gray{Y: 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
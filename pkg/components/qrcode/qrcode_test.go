@@ -0,0 +1,48 @@
+package qrcode
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	t.Run("Uses the smallest version", func(t *testing.T) {
+		code, err := New("otpauth://totp/Grafana:admin?secret=JBSWY3DPEHPK3PXP&issuer=Grafana")
+		require.NoError(t, err)
+		assert.Equal(t, 5*4+17, code.Size)
+
+		// Finder pattern corners and the always dark module.
+		assert.True(t, code.Dark(0, 0))
+		assert.True(t, code.Dark(code.Size-1, 0))
+		assert.True(t, code.Dark(0, code.Size-1))
+		assert.False(t, code.Dark(7, 7))
+		assert.True(t, code.Dark(8, code.Size-8))
+	})
+
+	t.Run("Rejects content that is too long", func(t *testing.T) {
+		_, err := New(strings.Repeat("a", 3000))
+		assert.Error(t, err)
+	})
+
+	t.Run("Renders a PNG with a quiet zone", func(t *testing.T) {
+		code, err := New("hello")
+		require.NoError(t, err)
+
+		data, err := code.PNG(4)
+		require.NoError(t, err)
+		img, err := png.Decode(bytes.NewReader(data))
+		require.NoError(t, err)
+
+		width := (code.Size + 2*quietZone) * 4
+		assert.Equal(t, width, img.Bounds().Dx())
+		r, _, _, _ := img.At(0, 0).RGBA()
+		assert.Equal(t, uint32(0xffff), r)
+		r, _, _, _ = img.At(quietZone*4, quietZone*4).RGBA()
+		assert.Equal(t, uint32(0), r)
+	})
+}
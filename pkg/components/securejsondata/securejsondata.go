@@ -6,6 +6,44 @@ import (
 	"github.com/grafana/grafana/pkg/util"
 )
 
+// Encrypter encrypts and decrypts the values of SecureJsonData.
+type Encrypter interface {
+	Encrypt(payload []byte) ([]byte, error)
+	Decrypt(payload []byte) ([]byte, error)
+}
+
+// secretKeyEncrypter encrypts values with the secret_key of the server.
+type secretKeyEncrypter struct{}
+
+func (secretKeyEncrypter) Encrypt(payload []byte) ([]byte, error) {
+	return util.Encrypt(payload, setting.SecretKey)
+}
+
+func (secretKeyEncrypter) Decrypt(payload []byte) ([]byte, error) {
+	return util.Decrypt(payload, setting.SecretKey)
+}
+
+var encrypter Encrypter = secretKeyEncrypter{}
+
+// SetEncrypter replaces the encryption of all SecureJsonData values, for example with envelope encryption.
+// Passing nil restores the encryption with the secret_key of the server.
+func SetEncrypter(e Encrypter) {
+	if e == nil {
+		e = secretKeyEncrypter{}
+	}
+	encrypter = e
+}
+
+// Encrypt encrypts a single value with the configured Encrypter.
+func Encrypt(payload []byte) ([]byte, error) {
+	return encrypter.Encrypt(payload)
+}
+
+// Decrypt decrypts a single value with the configured Encrypter.
+func Decrypt(payload []byte) ([]byte, error) {
+	return encrypter.Decrypt(payload)
+}
+
 // SecureJsonData is used to store encrypted data (for example in data_source table). Only values are separately
 // encrypted.
 type SecureJsonData map[string][]byte
@@ -14,7 +52,7 @@ type SecureJsonData map[string][]byte
 // is true if the key exists and false if not.
 func (s SecureJsonData) DecryptedValue(key string) (string, bool) {
 	if value, ok := s[key]; ok {
-		decryptedData, err := util.Decrypt(value, setting.SecretKey)
+		decryptedData, err := encrypter.Decrypt(value)
 		if err != nil {
 			log.Fatalf(4, err.Error())
 		}
@@ -28,7 +66,7 @@ func (s SecureJsonData) DecryptedValue(key string) (string, bool) {
 func (s SecureJsonData) Decrypt() map[string]string {
 	decrypted := make(map[string]string)
 	for key, data := range s {
-		decryptedData, err := util.Decrypt(data, setting.SecretKey)
+		decryptedData, err := encrypter.Decrypt(data)
 		if err != nil {
 			log.Fatalf(4, err.Error())
 		}
@@ -42,7 +80,7 @@ func (s SecureJsonData) Decrypt() map[string]string {
 func GetEncryptedJsonData(sjd map[string]string) SecureJsonData {
 	encrypted := make(SecureJsonData)
 	for key, data := range sjd {
-		encryptedData, err := util.Encrypt([]byte(data), setting.SecretKey)
+		encryptedData, err := encrypter.Encrypt([]byte(data))
 		if err != nil {
 			log.Fatalf(4, err.Error())
 		}
@@ -96,6 +96,9 @@ var (
 	// MDBDataSourceQueryByID is a metric counter for getting datasource by id
 	MDBDataSourceQueryByID prometheus.Counter
 
+	// MDataSourceQueryCacheRequestsTotal is a metric counter for data source query cache hits and misses
+	MDataSourceQueryCacheRequestsTotal *prometheus.CounterVec
+
 	// LDAPUsersSyncExecutionTime is a metric summary for LDAP users sync execution duration
 	LDAPUsersSyncExecutionTime prometheus.Summary
 
@@ -104,6 +107,12 @@ var (
 
 	// MRenderingQueue is a metric gauge for image rendering queue size
 	MRenderingQueue prometheus.Gauge
+
+	// MDataSourceQueryQueue is a metric gauge for the number of queries waiting for a data source
+	MDataSourceQueryQueue *prometheus.GaugeVec
+
+	// MDataSourceQueryQueueTimeouts is a metric counter for queries that timed out waiting for a data source
+	MDataSourceQueryQueueTimeouts *prometheus.CounterVec
 )
 
 // Timers
@@ -116,6 +125,9 @@ var (
 
 	// MRenderingSummary is a metric summary for image rendering request duration
 	MRenderingSummary *prometheus.SummaryVec
+
+	// MDataSourceQueryQueueWait is a metric summary for how long queries wait for a data source
+	MDataSourceQueryQueueWait *prometheus.SummaryVec
 )
 
 // StatTotals
@@ -357,6 +369,15 @@ func init() {
 		Namespace: ExporterName,
 	})
 
+	MDataSourceQueryCacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:      "datasource_query_cache_requests_total",
+			Help:      "counter for data source query cache hits and misses",
+			Namespace: ExporterName,
+		},
+		[]string{"datasource_type", "result"},
+	)
+
 	LDAPUsersSyncExecutionTime = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name:       "ldap_users_sync_execution_time",
 		Help:       "summary for LDAP users sync execution duration",
@@ -389,6 +410,28 @@ func init() {
 		Namespace: ExporterName,
 	})
 
+	MDataSourceQueryQueue = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name:      "datasource_query_queue_size",
+		Help:      "number of queries waiting for a data source",
+		Namespace: ExporterName,
+	}, []string{"datasource"})
+
+	MDataSourceQueryQueueTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:      "datasource_query_queue_timeouts_total",
+		Help:      "counter for queries that timed out waiting for a data source",
+		Namespace: ExporterName,
+	}, []string{"datasource"})
+
+	MDataSourceQueryQueueWait = prometheus.NewSummaryVec(
+		prometheus.SummaryOpts{
+			Name:       "datasource_query_queue_wait_duration_milliseconds",
+			Help:       "summary of how long queries wait for a data source",
+			Objectives: objectiveMap,
+			Namespace:  ExporterName,
+		},
+		[]string{"datasource"},
+	)
+
 	MDataSourceProxyReqTimer = prometheus.NewSummary(prometheus.SummaryOpts{
 		Name:       "api_dataproxy_request_all_milliseconds",
 		Help:       "summary for dataproxy request duration",
@@ -578,10 +621,14 @@ func initMetricVars() {
 		MAwsCloudWatchListMetrics,
 		MAwsCloudWatchGetMetricData,
 		MDBDataSourceQueryByID,
+		MDataSourceQueryCacheRequestsTotal,
 		LDAPUsersSyncExecutionTime,
 		MRenderingRequestTotal,
 		MRenderingSummary,
 		MRenderingQueue,
+		MDataSourceQueryQueue,
+		MDataSourceQueryQueueTimeouts,
+		MDataSourceQueryQueueWait,
 		MAlertingActiveAlerts,
 		MStatTotalDashboards,
 		MStatTotalFolders,
@@ -5,6 +5,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
 )
 
 var (
@@ -45,3 +46,14 @@ var saveInvalidLoginAttempt = func(query *models.LoginUserQuery) error {
 
 	return bus.Dispatch(&loginAttemptCommand)
 }
+
+// ValidateLoginAttempts returns ErrTooManyLoginAttempts if logins of the user are temporarily blocked
+// because of too many failed attempts.
+func ValidateLoginAttempts(cfg *setting.Cfg, username string) error {
+	return validateLoginAttempts(&models.LoginUserQuery{Username: username, Cfg: cfg})
+}
+
+// SaveInvalidLoginAttempt records a failed login attempt of the user, for example a wrong second factor.
+func SaveInvalidLoginAttempt(cfg *setting.Cfg, username string, ipAddress string) error {
+	return saveInvalidLoginAttempt(&models.LoginUserQuery{Username: username, IpAddress: ipAddress, Cfg: cfg})
+}
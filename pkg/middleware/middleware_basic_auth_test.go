@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"encoding/json"
 	"testing"
 
@@ -73,6 +74,35 @@ func TestMiddlewareBasicAuth(t *testing.T) {
 		assert.Equal(t, id, sc.context.UserId)
 	}, configure)
 
+	middlewareScenario(t, "Rejects users that need a second factor", func(t *testing.T, sc *scenarioContext) {
+		bus.AddHandler("grafana-auth", func(query *models.LoginUserQuery) error {
+			query.User = &models.User{Id: id}
+			query.AuthModule = "grafana"
+			return nil
+		})
+
+		bus.AddHandlerCtx("mfa", func(ctx context.Context, query *models.IsMFARequiredQuery) error {
+			query.Result = query.UserId == id
+			return nil
+		})
+
+		signedIn := false
+		bus.AddHandler("get-sign-user", func(query *models.GetSignedInUserQuery) error {
+			signedIn = true
+			query.Result = &models.SignedInUser{UserId: id}
+			return nil
+		})
+
+		authHeader := util.GetBasicAuthHeader("myUser", "MyPass")
+		sc.fakeReq("GET", "/").withAuthorizationHeader(authHeader).exec()
+
+		assert.Equal(t, 401, sc.resp.Code)
+		assert.False(t, signedIn)
+	}, func(cfg *setting.Cfg) {
+		configure(cfg)
+		cfg.MFAEnabled = true
+	})
+
 	middlewareScenario(t, "Auth sequence", func(t *testing.T, sc *scenarioContext) {
 		const password = "MyPass"
 		const salt = "Salt"
@@ -0,0 +1,178 @@
+package models
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// Kinds of entities that dashboards depend on.
+const (
+	DependencyKindDataSource   = "datasource"
+	DependencyKindPlugin       = "plugin"
+	DependencyKindLibraryPanel = "librarypanel"
+	DependencyKindDashboard    = "dashboard"
+)
+
+// builtInDataSourceNames are the names of data sources that are part of Grafana and can't be deleted.
+var builtInDataSourceNames = map[string]bool{
+	"-- Mixed --":     true,
+	"-- Grafana --":   true,
+	"-- Dashboard --": true,
+}
+
+var dashboardLinkPattern = regexp.MustCompile(`/d/([a-zA-Z0-9\-_]+)`)
+
+// DashboardDependency is an entity that a dashboard refers to. Ref is the uid of the entity, or the
+// id of a plugin. Data sources that don't exist only have a name.
+type DashboardDependency struct {
+	Kind string `json:"kind"`
+	Ref  string `json:"ref"`
+	Name string `json:"name,omitempty"`
+}
+
+// GetDependencies returns the entities that the dashboard refers to: the data sources of panels,
+// queries, annotations and template variables, the plugins of panels and data source variables,
+// library panels, and dashboards linked to by dashboard and panel links.
+//
+// Data sources are returned as they are referred to: by uid, by name, or with neither for the default
+// data source of the org. Data sources referred to by template variables are skipped.
+func (d *Dashboard) GetDependencies() []DashboardDependency {
+	c := &dependencyCollector{seen: map[DashboardDependency]bool{}}
+
+	c.addPanels(d.Data.Get("panels"))
+	// dashboards of schema version < 16 have their panels in rows
+	for _, row := range d.Data.Get("rows").MustArray() {
+		c.addPanels(simplejson.NewFromAny(row).Get("panels"))
+	}
+
+	for _, annotation := range d.Data.Get("annotations").Get("list").MustArray() {
+		c.addDataSource(simplejson.NewFromAny(annotation).Get("datasource"), false)
+	}
+
+	for _, variable := range d.Data.Get("templating").Get("list").MustArray() {
+		v := simplejson.NewFromAny(variable)
+		switch v.Get("type").MustString() {
+		case "query":
+			c.addDataSource(v.Get("datasource"), true)
+		case "datasource":
+			c.add(DependencyKindPlugin, v.Get("query").MustString(), "")
+		}
+	}
+
+	c.addLinks(d.Data.Get("links"))
+
+	return c.dependencies
+}
+
+type dependencyCollector struct {
+	dependencies []DashboardDependency
+	seen         map[DashboardDependency]bool
+}
+
+func (c *dependencyCollector) add(kind, ref, name string) {
+	dep := DashboardDependency{Kind: kind, Ref: ref, Name: name}
+	if !c.seen[dep] {
+		c.seen[dep] = true
+		c.dependencies = append(c.dependencies, dep)
+	}
+}
+
+// addDataSource adds the data source of a panel, query, annotation or variable. A null data source
+// refers to the default data source, unless it's inherited from the panel.
+func (c *dependencyCollector) addDataSource(ds *simplejson.Json, nullIsDefault bool) {
+	switch v := ds.Interface().(type) {
+	case nil:
+		if nullIsDefault {
+			c.add(DependencyKindDataSource, "", "")
+		}
+	case string:
+		if v == "" || builtInDataSourceNames[v] || strings.HasPrefix(v, "$") {
+			return
+		}
+		c.add(DependencyKindDataSource, "", v)
+	case map[string]interface{}:
+		uid := ds.Get("uid").MustString()
+		if uid == "" || strings.HasPrefix(uid, "$") {
+			return
+		}
+		c.add(DependencyKindDataSource, uid, "")
+	}
+}
+
+func (c *dependencyCollector) addPanels(panels *simplejson.Json) {
+	for _, panel := range panels.MustArray() {
+		p := simplejson.NewFromAny(panel)
+
+		if libraryPanel := p.Get("libraryPanel"); libraryPanel.Interface() != nil {
+			c.add(DependencyKindLibraryPanel, libraryPanel.Get("uid").MustString(), libraryPanel.Get("name").MustString())
+			continue
+		}
+
+		if panelType := p.Get("type").MustString(); panelType != "" && panelType != "row" {
+			c.add(DependencyKindPlugin, panelType, "")
+		}
+
+		targets := p.Get("targets").MustArray()
+		// panels without queries, such as text panels, don't use the default data source
+		if len(targets) > 0 || p.Get("datasource").Interface() != nil {
+			c.addDataSource(p.Get("datasource"), true)
+		}
+		for _, target := range targets {
+			c.addDataSource(simplejson.NewFromAny(target).Get("datasource"), false)
+		}
+
+		c.addLinks(p.Get("links"))
+
+		// collapsed rows hold their panels
+		c.addPanels(p.Get("panels"))
+	}
+}
+
+func (c *dependencyCollector) addLinks(links *simplejson.Json) {
+	for _, link := range links.MustArray() {
+		url := simplejson.NewFromAny(link).Get("url").MustString()
+		if match := dashboardLinkPattern.FindStringSubmatch(url); match != nil {
+			c.add(DependencyKindDashboard, match[1], "")
+		}
+	}
+}
+
+// DependentDashboard is a dashboard that depends on an entity.
+type DependentDashboard struct {
+	Id       int64  `json:"id"`
+	Uid      string `json:"uid"`
+	Title    string `json:"title"`
+	FolderId int64  `json:"folderId"`
+}
+
+//
+// QUERIES
+//
+
+// GetDashboardDependenciesQuery returns the entities that a dashboard depends on.
+type GetDashboardDependenciesQuery struct {
+	OrgId       int64
+	DashboardId int64
+
+	Result []*DashboardDependency
+}
+
+// GetDependentDashboardsQuery returns the dashboards that depend on an entity, by its ref. Dashboards
+// that refer to a data source by a name that couldn't be resolved are matched by Name.
+type GetDependentDashboardsQuery struct {
+	OrgId int64
+	Kind  string
+	Refs  []string
+	Name  string
+
+	Result []*DependentDashboard
+}
+
+// IndexDashboardDependenciesCommand indexes the dependencies of the dashboards that haven't been
+// indexed yet, such as the dashboards that were saved before the index was added.
+type IndexDashboardDependenciesCommand struct {
+	// Result is the number of dashboards that were indexed.
+	Result int64
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboard_GetDependencies(t *testing.T) {
+	dash := NewDashboardFromJson(simplejson.NewFromAny(map[string]interface{}{
+		"title": "Services",
+		"panels": []interface{}{
+			map[string]interface{}{
+				"type": "graph", "datasource": "Prometheus",
+				"targets": []interface{}{map[string]interface{}{"expr": "up"}},
+				"links":   []interface{}{map[string]interface{}{"url": "/d/details/service-details?var-job=api"}},
+			},
+			map[string]interface{}{
+				"type": "table", "datasource": "-- Mixed --",
+				"targets": []interface{}{
+					map[string]interface{}{"datasource": "MySQL"},
+					map[string]interface{}{"datasource": map[string]interface{}{"uid": "loki", "type": "loki"}},
+					map[string]interface{}{"datasource": "$ds"},
+				},
+			},
+			map[string]interface{}{"type": "timeseries", "targets": []interface{}{map[string]interface{}{"refId": "A"}}},
+			map[string]interface{}{"type": "text", "datasource": nil},
+			map[string]interface{}{
+				"type": "row", "collapsed": true,
+				"panels": []interface{}{map[string]interface{}{"type": "stat", "datasource": "-- Grafana --"}},
+			},
+			map[string]interface{}{"libraryPanel": map[string]interface{}{"uid": "lp", "name": "Shared"}},
+		},
+		"annotations": map[string]interface{}{
+			"list": []interface{}{map[string]interface{}{"datasource": "-- Grafana --"}, map[string]interface{}{"datasource": "Elasticsearch"}},
+		},
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{"type": "query", "datasource": "Prometheus"},
+				map[string]interface{}{"type": "datasource", "query": "prometheus"},
+				map[string]interface{}{"type": "custom", "query": "a,b"},
+			},
+		},
+		"links": []interface{}{map[string]interface{}{"type": "link", "url": "https://grafana.example.com/d/overview"}},
+	}))
+
+	assert.Equal(t, []DashboardDependency{
+		{Kind: DependencyKindPlugin, Ref: "graph"},
+		{Kind: DependencyKindDataSource, Name: "Prometheus"},
+		{Kind: DependencyKindDashboard, Ref: "details"},
+		{Kind: DependencyKindPlugin, Ref: "table"},
+		{Kind: DependencyKindDataSource, Name: "MySQL"},
+		{Kind: DependencyKindDataSource, Ref: "loki"},
+		{Kind: DependencyKindPlugin, Ref: "timeseries"},
+		{Kind: DependencyKindDataSource},
+		{Kind: DependencyKindPlugin, Ref: "text"},
+		{Kind: DependencyKindPlugin, Ref: "stat"},
+		{Kind: DependencyKindLibraryPanel, Ref: "lp", Name: "Shared"},
+		{Kind: DependencyKindDataSource, Name: "Elasticsearch"},
+		{Kind: DependencyKindPlugin, Ref: "prometheus"},
+		{Kind: DependencyKindDashboard, Ref: "overview"},
+	}, dash.GetDependencies())
+}
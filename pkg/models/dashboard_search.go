@@ -0,0 +1,98 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// Fields of the dashboard search index.
+const (
+	DashboardSearchFieldTitle       = "title"
+	DashboardSearchFieldPanel       = "panel"
+	DashboardSearchFieldDescription = "description"
+	DashboardSearchFieldTarget      = "target"
+	DashboardSearchFieldDataSource  = "datasource"
+)
+
+// TargetExpressionKeys are the keys of panel targets and template variables that hold the query
+// expressions of the core data sources.
+var TargetExpressionKeys = []string{"expr", "query", "rawSql", "target", "expression", "queryText", "rawQuery"}
+
+// DashboardSearchTerm is a term of a dashboard that is indexed for search.
+type DashboardSearchTerm struct {
+	Field string
+	Term  string
+}
+
+// GetSearchTerms returns the terms of the dashboard that are indexed for search: its title and
+// description, the titles and descriptions of its panels, and the data sources and query
+// expressions of the panels and template variables.
+func (d *Dashboard) GetSearchTerms() []DashboardSearchTerm {
+	c := &searchTermCollector{seen: map[DashboardSearchTerm]bool{}}
+	c.add(DashboardSearchFieldTitle, d.Title)
+	c.add(DashboardSearchFieldDescription, d.Data.Get("description").MustString())
+
+	c.addPanels(d.Data.Get("panels"))
+	// dashboards of schema version < 16 have their panels in rows
+	for _, row := range d.Data.Get("rows").MustArray() {
+		c.addPanels(simplejson.NewFromAny(row).Get("panels"))
+	}
+
+	for _, variable := range d.Data.Get("templating").Get("list").MustArray() {
+		v := simplejson.NewFromAny(variable)
+		c.add(DashboardSearchFieldDataSource, v.Get("datasource").MustString())
+		c.addTarget(v)
+	}
+
+	return c.terms
+}
+
+type searchTermCollector struct {
+	terms []DashboardSearchTerm
+	seen  map[DashboardSearchTerm]bool
+}
+
+func (c *searchTermCollector) add(field, term string) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		return
+	}
+
+	t := DashboardSearchTerm{Field: field, Term: term}
+	if !c.seen[t] {
+		c.seen[t] = true
+		c.terms = append(c.terms, t)
+	}
+}
+
+func (c *searchTermCollector) addPanels(panels *simplejson.Json) {
+	for _, panel := range panels.MustArray() {
+		p := simplejson.NewFromAny(panel)
+		c.add(DashboardSearchFieldPanel, p.Get("title").MustString())
+		c.add(DashboardSearchFieldDescription, p.Get("description").MustString())
+		c.add(DashboardSearchFieldDataSource, p.Get("datasource").MustString())
+
+		for _, target := range p.Get("targets").MustArray() {
+			t := simplejson.NewFromAny(target)
+			c.add(DashboardSearchFieldDataSource, t.Get("datasource").MustString())
+			c.addTarget(t)
+		}
+
+		// collapsed rows hold their panels
+		c.addPanels(p.Get("panels"))
+	}
+}
+
+func (c *searchTermCollector) addTarget(target *simplejson.Json) {
+	for _, key := range TargetExpressionKeys {
+		c.add(DashboardSearchFieldTarget, target.Get(key).MustString())
+	}
+}
+
+// IndexDashboardSearchTermsCommand indexes the search terms of the dashboards that haven't been
+// indexed yet, such as the dashboards that were saved before the index was added.
+type IndexDashboardSearchTermsCommand struct {
+	// Result is the number of dashboards that were indexed.
+	Result int64
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboard_GetSearchTerms(t *testing.T) {
+	dash := NewDashboardFromJson(simplejson.NewFromAny(map[string]interface{}{
+		"title":       "Services",
+		"description": "Overview of the services",
+		"panels": []interface{}{
+			map[string]interface{}{
+				"title":       "Request rate",
+				"description": "Requests per second",
+				"datasource":  "Prometheus",
+				"targets": []interface{}{
+					map[string]interface{}{"expr": "rate(http_requests_total[5m])", "refId": "A"},
+					map[string]interface{}{"expr": "rate(http_requests_total[5m])", "refId": "B"},
+				},
+			},
+			map[string]interface{}{
+				"type":  "row",
+				"title": "Database",
+				"panels": []interface{}{
+					map[string]interface{}{
+						"title":      "Slow queries",
+						"datasource": "-- Mixed --",
+						"targets": []interface{}{
+							map[string]interface{}{"datasource": "MySQL", "rawSql": "SELECT * FROM slow_log"},
+						},
+					},
+				},
+			},
+		},
+		"rows": []interface{}{
+			map[string]interface{}{
+				"panels": []interface{}{
+					map[string]interface{}{"title": "Legacy", "targets": []interface{}{map[string]interface{}{"target": "stats.count"}}},
+				},
+			},
+		},
+		"templating": map[string]interface{}{
+			"list": []interface{}{
+				map[string]interface{}{"name": "job", "datasource": "Prometheus", "query": "label_values(up, job)"},
+				map[string]interface{}{"name": "interval", "query": map[string]interface{}{"not": "a string"}},
+			},
+		},
+	}))
+
+	assert.Equal(t, []DashboardSearchTerm{
+		{Field: DashboardSearchFieldTitle, Term: "Services"},
+		{Field: DashboardSearchFieldDescription, Term: "Overview of the services"},
+		{Field: DashboardSearchFieldPanel, Term: "Request rate"},
+		{Field: DashboardSearchFieldDescription, Term: "Requests per second"},
+		{Field: DashboardSearchFieldDataSource, Term: "Prometheus"},
+		{Field: DashboardSearchFieldTarget, Term: "rate(http_requests_total[5m])"},
+		{Field: DashboardSearchFieldPanel, Term: "Database"},
+		{Field: DashboardSearchFieldPanel, Term: "Slow queries"},
+		{Field: DashboardSearchFieldDataSource, Term: "-- Mixed --"},
+		{Field: DashboardSearchFieldDataSource, Term: "MySQL"},
+		{Field: DashboardSearchFieldTarget, Term: "SELECT * FROM slow_log"},
+		{Field: DashboardSearchFieldPanel, Term: "Legacy"},
+		{Field: DashboardSearchFieldTarget, Term: "stats.count"},
+		{Field: DashboardSearchFieldTarget, Term: "label_values(up, job)"},
+	}, dash.GetSearchTerms())
+}
@@ -8,8 +8,14 @@ import (
 )
 
 var (
-	ErrDashboardVersionNotFound = errors.New("dashboard version not found")
-	ErrNoVersionsForDashboardId = errors.New("no dashboard versions found for the given DashboardId")
+	ErrDashboardVersionNotFound           = errors.New("dashboard version not found")
+	ErrNoVersionsForDashboardId           = errors.New("no dashboard versions found for the given DashboardId")
+	ErrDashboardVersionTagInvalid         = errors.New("dashboard version tag must be between 1 and 255 characters")
+	ErrDashboardVersionTagNotFound        = errors.New("dashboard version tag not found")
+	ErrDashboardVersionRetentionNotFound  = errors.New("dashboard version retention policy not found")
+	ErrDashboardVersionRetentionInvalid   = errors.New("dashboard version retention policy must keep at least one version")
+	ErrDashboardVersionRetentionBadPeriod = errors.New("dashboard version retention period is invalid")
+	ErrDeletedDashboardNotFound           = errors.New("deleted dashboard not found")
 )
 
 // A DashboardVersion represents the comparable data in a dashboard, allowing
@@ -41,6 +47,7 @@ type DashboardVersionMeta struct {
 	Message       string           `json:"message"`
 	Data          *simplejson.Json `json:"data"`
 	CreatedBy     string           `json:"createdBy"`
+	Tags          []string         `json:"tags"`
 }
 
 // DashboardVersionDTO represents a dashboard version, without the dashboard
@@ -54,6 +61,7 @@ type DashboardVersionDTO struct {
 	Created       time.Time `json:"created"`
 	CreatedBy     string    `json:"createdBy"`
 	Message       string    `json:"message"`
+	Tags          []string  `json:"tags" xorm:"-"`
 }
 
 //
@@ -68,6 +76,14 @@ type GetDashboardVersionQuery struct {
 	Result *DashboardVersion
 }
 
+// GetDashboardVersionTagsQuery returns the tags of the versions of a dashboard, by version.
+type GetDashboardVersionTagsQuery struct {
+	DashboardId int64
+	OrgId       int64
+
+	Result map[int][]string
+}
+
 type GetDashboardVersionsQuery struct {
 	DashboardId int64
 	OrgId       int64
@@ -84,3 +100,19 @@ type GetDashboardVersionsQuery struct {
 type DeleteExpiredVersionsCommand struct {
 	DeletedRows int64
 }
+
+// AddDashboardVersionTagCommand tags a version of a dashboard. Tags are unique per dashboard, so a
+// tag that is already on another version of the dashboard is moved.
+type AddDashboardVersionTagCommand struct {
+	DashboardId int64
+	OrgId       int64
+	Version     int
+	Tag         string
+}
+
+type DeleteDashboardVersionTagCommand struct {
+	DashboardId int64
+	OrgId       int64
+	Version     int
+	Tag         string
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// DashboardVersionRetention is the policy for which versions of the dashboards in a folder are kept
+// by the clean up of expired versions. Folders without a policy keep the number of versions that is
+// configured by the versions_to_keep setting. Tagged versions are always kept.
+type DashboardVersionRetention struct {
+	Id       int64 `json:"-"`
+	OrgId    int64 `json:"-"`
+	FolderId int64 `json:"folderId"`
+
+	// VersionsToKeep is the number of most recent versions that are kept.
+	VersionsToKeep int `json:"versionsToKeep"`
+	// KeepYoungerThan is the period, such as 30d, during which all versions are kept. It's empty if
+	// versions aren't kept by their age.
+	KeepYoungerThan string `json:"keepYoungerThan"`
+	// KeepVersionsWithMessage keeps the versions that were saved with a message.
+	KeepVersionsWithMessage bool `json:"keepVersionsWithMessage"`
+
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// DeletedDashboard is a dashboard or folder in the trash. It's kept, together with its versions,
+// permissions and stars, until it's restored or purged. The versions of a deleted dashboard are kept
+// according to the retention policy of the folder it was in until it's purged, which deletes all its
+// versions, also those that the policy keeps.
+type DeletedDashboard struct {
+	Id          int64  `json:"-"`
+	OrgId       int64  `json:"-"`
+	DashboardId int64  `json:"dashboardId"`
+	Uid         string `json:"uid"`
+	Title       string `json:"title"`
+	FolderId    int64  `json:"folderId"`
+	IsFolder    bool   `json:"isFolder"`
+	// DeletedWith is the id of the folder whose deletion deleted the dashboard, or 0 if the dashboard
+	// was deleted on its own. Restoring a folder restores the dashboards that were deleted with it.
+	DeletedWith int64            `json:"deletedWith"`
+	Data        *simplejson.Json `json:"-"`
+	Deleted     time.Time        `json:"deleted"`
+}
+
+//
+// Queries
+//
+
+type GetDashboardVersionRetentionQuery struct {
+	OrgId    int64
+	FolderId int64
+
+	Result *DashboardVersionRetention
+}
+
+type GetDeletedDashboardsQuery struct {
+	OrgId int64
+
+	Result []*DeletedDashboard
+}
+
+type GetDeletedDashboardQuery struct {
+	OrgId       int64
+	DashboardId int64
+
+	Result *DeletedDashboard
+}
+
+// GetRestoredDashboardsQuery returns the dashboards that a RestoreDeletedDashboardCommand with the same
+// fields would restore, the deleted dashboard or folder first.
+type GetRestoredDashboardsQuery struct {
+	OrgId       int64
+	DashboardId int64
+	Version     int
+	FolderId    int64
+
+	Result []*Dashboard
+}
+
+//
+// Commands
+//
+
+type SetDashboardVersionRetentionCommand struct {
+	VersionsToKeep          int    `json:"versionsToKeep"`
+	KeepYoungerThan         string `json:"keepYoungerThan"`
+	KeepVersionsWithMessage bool   `json:"keepVersionsWithMessage"`
+
+	OrgId    int64 `json:"-"`
+	FolderId int64 `json:"-"`
+
+	Result *DashboardVersionRetention `json:"-"`
+}
+
+type DeleteDashboardVersionRetentionCommand struct {
+	OrgId    int64
+	FolderId int64
+}
+
+// RestoreDeletedDashboardCommand restores a deleted dashboard or folder, from one of its versions if
+// Version is set or else from its state when it was deleted. A restored folder is restored into the
+// General folder, along with the dashboards that were deleted with it. The dashboard keeps its id and
+// versions, and its uid unless another dashboard uses it.
+type RestoreDeletedDashboardCommand struct {
+	OrgId       int64
+	DashboardId int64
+	Version     int
+	FolderId    int64
+	UserId      int64
+
+	Result *Dashboard
+	// Children are the dashboards that were restored along with a folder.
+	Children []*Dashboard
+}
+
+// PurgeDeletedDashboardCommand permanently deletes a deleted dashboard or folder, its versions,
+// annotations and alerts, and the dashboards that were deleted with it.
+type PurgeDeletedDashboardCommand struct {
+	OrgId       int64
+	DashboardId int64
+}
+
+// PurgeDeletedDashboardsCommand permanently deletes the dashboards and folders that were deleted
+// before OlderThan.
+type PurgeDeletedDashboardsCommand struct {
+	OlderThan time.Time
+
+	PurgedRows int64
+}
@@ -340,6 +340,7 @@ type SaveDashboardCommand struct {
 	PluginId     string           `json:"-"`
 	FolderId     int64            `json:"folderId"`
 	IsFolder     bool             `json:"isFolder"`
+	Restored     bool             `json:"-"`
 
 	UpdatedAt time.Time
 
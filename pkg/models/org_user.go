@@ -116,4 +116,5 @@ type OrgUserDTO struct {
 	Role          string    `json:"role"`
 	LastSeenAt    time.Time `json:"lastSeenAt"`
 	LastSeenAtAge string    `json:"lastSeenAtAge"`
+	IsDisabled    bool      `json:"isDisabled"`
 }
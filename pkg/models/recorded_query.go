@@ -0,0 +1,102 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+)
+
+// Typed errors
+var (
+	ErrRecordedQueryNotFound        = errors.New("recorded query not found")
+	ErrRecordedQueryNameTaken       = errors.New("a recorded query with the same name already exists")
+	ErrRecordedQueryInvalidName     = errors.New("recorded query names must be valid metric names")
+	ErrRecordedQueryInvalidInterval = fmt.Errorf("recorded query interval and time range must be durations of at least %s",
+		RecordedQueryMinInterval)
+)
+
+// RecordedQueryMinInterval is the shortest interval at which recorded queries run.
+const RecordedQueryMinInterval = 10 * time.Second
+
+// RecordedQuery is a data source query that is run periodically, and whose results are sent to the
+// Prometheus remote write endpoint as a metric named after the recorded query.
+type RecordedQuery struct {
+	Id           int64            `json:"id"`
+	OrgId        int64            `json:"orgId"`
+	Uid          string           `json:"uid"`
+	Name         string           `json:"name"`
+	DatasourceId int64            `json:"datasourceId"`
+	Query        *simplejson.Json `json:"query"`
+	Interval     string           `json:"interval"`
+	TimeRange    string           `json:"timeRange"`
+	Active       bool             `json:"active"`
+	LastRun      *time.Time       `json:"lastRun"`
+	LastError    string           `json:"lastError"`
+	Created      time.Time        `json:"created"`
+	Updated      time.Time        `json:"updated"`
+}
+
+//
+// COMMANDS
+//
+
+type CreateRecordedQueryCommand struct {
+	Name         string           `json:"name" binding:"Required"`
+	DatasourceId int64            `json:"datasourceId" binding:"Required"`
+	Query        *simplejson.Json `json:"query" binding:"Required"`
+	Interval     string           `json:"interval" binding:"Required"`
+	TimeRange    string           `json:"timeRange"`
+	Active       bool             `json:"active"`
+
+	OrgId  int64          `json:"-"`
+	Result *RecordedQuery `json:"-"`
+}
+
+type UpdateRecordedQueryCommand struct {
+	Name         string           `json:"name" binding:"Required"`
+	DatasourceId int64            `json:"datasourceId" binding:"Required"`
+	Query        *simplejson.Json `json:"query" binding:"Required"`
+	Interval     string           `json:"interval" binding:"Required"`
+	TimeRange    string           `json:"timeRange"`
+	Active       bool             `json:"active"`
+
+	OrgId  int64          `json:"-"`
+	Uid    string         `json:"-"`
+	Result *RecordedQuery `json:"-"`
+}
+
+type DeleteRecordedQueryCommand struct {
+	OrgId int64
+	Uid   string
+}
+
+// SetRecordedQueryRunCommand records the last run of a recorded query.
+type SetRecordedQueryRunCommand struct {
+	Id        int64
+	LastRun   time.Time
+	LastError string
+}
+
+//
+// QUERIES
+//
+
+type GetRecordedQueriesQuery struct {
+	OrgId int64
+
+	Result []*RecordedQuery
+}
+
+type GetRecordedQueryQuery struct {
+	OrgId int64
+	Uid   string
+
+	Result *RecordedQuery
+}
+
+// GetActiveRecordedQueriesQuery gets the active recorded queries of all organizations.
+type GetActiveRecordedQueriesQuery struct {
+	Result []*RecordedQuery
+}
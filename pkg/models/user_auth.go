@@ -92,6 +92,12 @@ type LoginUserQuery struct {
 	Cfg        *setting.Cfg
 }
 
+// IsMFARequiredQuery checks whether a user has to provide a second factor when signing in with a password.
+type IsMFARequiredQuery struct {
+	UserId int64
+	Result bool
+}
+
 type GetUserByAuthInfoQuery struct {
 	AuthModule string
 	AuthId     string
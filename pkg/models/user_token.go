@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"net"
+	"time"
 )
 
 // Typed errors
 var (
-	ErrUserTokenNotFound = errors.New("user token not found")
+	ErrUserTokenNotFound    = errors.New("user token not found")
+	ErrInvalidSessionPolicy = errors.New("session lifetimes can not be negative or longer than the server defaults")
 )
 
 // CreateTokenErr represents a token creation error; used in Enterprise
@@ -52,6 +54,42 @@ type RevokeAuthTokenCmd struct {
 	AuthTokenId int64 `json:"authTokenId"`
 }
 
+// UserSession is a user token together with the user it belongs to.
+type UserSession struct {
+	UserToken
+	Login string
+	Email string
+	Name  string
+	// Role is the role of the user in the organization that was searched, empty when searching all organizations.
+	Role RoleType
+}
+
+// SearchUserSessionsQuery searches the active sessions of users. OrgId 0 searches all users.
+type SearchUserSessionsQuery struct {
+	OrgId int64
+	// Query matches the login, email or name of the user, or the client IP of the session.
+	Query string
+	// Role restricts the search to users with the role in the organization.
+	Role  RoleType
+	Limit int
+	Page  int
+}
+
+type SearchUserSessionsResult struct {
+	TotalCount int64
+	Sessions   []*UserSession
+}
+
+// SessionPolicy overrides the session settings for the members of an organization.
+// Zero values fall back to the server defaults. Policies only apply to the members that belong to no other
+// organization and aren't Grafana admins.
+type SessionPolicy struct {
+	OrgId                 int64
+	MaxInactiveLifetime   time.Duration
+	MaxLifetime           time.Duration
+	MaxConcurrentSessions int
+}
+
 // UserTokenService are used for generating and validating user tokens
 type UserTokenService interface {
 	CreateToken(ctx context.Context, user *User, clientIP net.IP, userAgent string) (*UserToken, error)
@@ -62,4 +100,8 @@ type UserTokenService interface {
 	ActiveTokenCount(ctx context.Context) (int64, error)
 	GetUserToken(ctx context.Context, userId, userTokenId int64) (*UserToken, error)
 	GetUserTokens(ctx context.Context, userId int64) ([]*UserToken, error)
+	SearchUserSessions(ctx context.Context, query *SearchUserSessionsQuery) (*SearchUserSessionsResult, error)
+	RevokeOrgUserTokens(ctx context.Context, orgId int64, role RoleType, exceptTokenId int64) (int64, error)
+	GetSessionPolicy(ctx context.Context, orgId int64) (*SessionPolicy, error)
+	SetSessionPolicy(ctx context.Context, policy *SessionPolicy) error
 }
@@ -12,6 +12,26 @@ func BuildServiceGraph(objs []interface{}, services []*Descriptor) error {
 	if services == nil {
 		services = GetServices()
 	}
+	if err := PopulateServiceGraph(objs, services); err != nil {
+		return err
+	}
+
+	// Initialize services.
+	for _, service := range services {
+		if IsDisabled(service.Instance) {
+			continue
+		}
+
+		if err := service.Instance.Init(); err != nil {
+			return fmt.Errorf("service init failed: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// PopulateServiceGraph builds a graph of services and their dependencies, without initializing the services.
+func PopulateServiceGraph(objs []interface{}, services []*Descriptor) error {
 	for _, service := range services {
 		objs = append(objs, service.Instance)
 	}
@@ -30,16 +50,5 @@ func BuildServiceGraph(objs []interface{}, services []*Descriptor) error {
 		return fmt.Errorf("failed to populate service dependencies: %w", err)
 	}
 
-	// Initialize services.
-	for _, service := range services {
-		if IsDisabled(service.Instance) {
-			continue
-		}
-
-		if err := service.Instance.Init(); err != nil {
-			return fmt.Errorf("service init failed: %w", err)
-		}
-	}
-
 	return nil
 }
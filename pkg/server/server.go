@@ -30,17 +30,22 @@ import (
 	"github.com/grafana/grafana/pkg/login/social"
 	"github.com/grafana/grafana/pkg/middleware"
 	_ "github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager"
 	"github.com/grafana/grafana/pkg/registry"
 	_ "github.com/grafana/grafana/pkg/services/alerting"
 	_ "github.com/grafana/grafana/pkg/services/auth"
 	_ "github.com/grafana/grafana/pkg/services/cleanup"
+	_ "github.com/grafana/grafana/pkg/services/dependencies"
 	_ "github.com/grafana/grafana/pkg/services/librarypanels"
 	_ "github.com/grafana/grafana/pkg/services/ngalert"
 	_ "github.com/grafana/grafana/pkg/services/notifications"
 	_ "github.com/grafana/grafana/pkg/services/provisioning"
+	_ "github.com/grafana/grafana/pkg/services/recordedqueries"
 	_ "github.com/grafana/grafana/pkg/services/rendering"
+	_ "github.com/grafana/grafana/pkg/services/scim"
 	_ "github.com/grafana/grafana/pkg/services/search"
-	_ "github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/secrets"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/setting"
 )
 
@@ -53,6 +58,10 @@ type Config struct {
 	Commit      string
 	BuildBranch string
 	Listener    net.Listener
+
+	// ProvisioningDryRun makes the server validate the provisioning config files and print the changes
+	// that provisioning them would apply, instead of starting.
+	ProvisioningDryRun bool
 }
 
 // New returns a new instance of Server.
@@ -76,6 +85,8 @@ func New(cfg Config) (*Server, error) {
 		commit:      cfg.Commit,
 		buildBranch: cfg.BuildBranch,
 		listener:    cfg.Listener,
+
+		provisioningDryRun: cfg.ProvisioningDryRun,
 	}
 
 	if err := s.init(); err != nil {
@@ -105,6 +116,8 @@ type Server struct {
 	commit      string
 	buildBranch string
 
+	provisioningDryRun bool
+
 	HTTPServer *api.HTTPServer `inject:""`
 }
 
@@ -119,6 +132,11 @@ func (s *Server) init() error {
 	s.isInitialized = true
 
 	s.loadConfiguration()
+	s.cfg.ProvisioningDryRun = s.provisioningDryRun
+	if s.provisioningDryRun {
+		return s.initProvisioningDryRun()
+	}
+
 	s.writePIDFile()
 	if err := metrics.SetEnvironmentInformation(s.cfg.MetricsGrafanaEnvironmentInfo); err != nil {
 		return err
@@ -146,6 +164,27 @@ func (s *Server) init() error {
 	return nil
 }
 
+// initProvisioningDryRun initializes only the services that the provisioning dry run reads from, the
+// database in read only mode, the plugins and the secrets service that decrypts the current secrets, so
+// that the dry run doesn't apply migrations or have the side effects of initializing the other services.
+func (s *Server) initProvisioningDryRun() error {
+	services := registry.GetServices()
+	if err := registry.PopulateServiceGraph(s.serviceGraphObjects(), services); err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		switch service.Instance.(type) {
+		case *sqlstore.SQLStore, *manager.PluginManager, *secrets.SecretsService:
+			if err := service.Instance.Init(); err != nil {
+				return fmt.Errorf("service init failed: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
 // Run initializes and starts services. This will block until all services have
 // exited. To initiate shutdown, call the Shutdown method in another goroutine.
 func (s *Server) Run() (err error) {
@@ -153,6 +192,10 @@ func (s *Server) Run() (err error) {
 		return
 	}
 
+	if s.provisioningDryRun {
+		return s.planProvisioning()
+	}
+
 	services := registry.GetServices()
 
 	// Start background services.
@@ -208,6 +251,20 @@ func (s *Server) Run() (err error) {
 	return nil
 }
 
+// planProvisioning prints the changes that provisioning the config files would apply, and returns an
+// error if the config files are invalid.
+func (s *Server) planProvisioning() error {
+	p := s.HTTPServer.ProvisioningService.Plan()
+	if err := p.Write(os.Stdout); err != nil {
+		return err
+	}
+
+	if p.HasErrors() {
+		return errors.New("provisioning config files are invalid")
+	}
+	return nil
+}
+
 func (s *Server) Shutdown(reason string) {
 	s.log.Info("Shutdown started", "reason", reason)
 	s.shutdownReason = reason
@@ -261,15 +318,18 @@ func (s *Server) writePIDFile() {
 
 // buildServiceGraph builds a graph of services and their dependencies.
 func (s *Server) buildServiceGraph(services []*registry.Descriptor) error {
-	// Specify service dependencies.
-	objs := []interface{}{
+	return registry.BuildServiceGraph(s.serviceGraphObjects(), services)
+}
+
+// serviceGraphObjects returns the dependencies of the services that aren't services themselves.
+func (s *Server) serviceGraphObjects() []interface{} {
+	return []interface{}{
 		bus.GetBus(),
 		s.cfg,
 		routing.NewRouteRegister(middleware.RequestTracing, middleware.RequestMetrics(s.cfg)),
 		localcache.New(5*time.Minute, 10*time.Minute),
 		s,
 	}
-	return registry.BuildServiceGraph(objs, services)
 }
 
 // loadConfiguration loads settings and configuration from config files.
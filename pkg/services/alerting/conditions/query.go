@@ -172,6 +172,10 @@ func (c *QueryCondition) executeQuery(context *alerting.EvalContext, timeRange p
 
 	for _, v := range resp.Results {
 		if v.Error != nil {
+			// data sources such as Prometheus return the errors of their queries in the query results
+			if prometheus.IsAPIError(v.Error) || errors.Is(v.Error, gocontext.DeadlineExceeded) {
+				return nil, toCustomError(v.Error)
+			}
 			return nil, fmt.Errorf("request handler response error %v", v)
 		}
 
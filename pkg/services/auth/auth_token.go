@@ -10,6 +10,7 @@ import (
 
 	"github.com/grafana/grafana/pkg/infra/serverlock"
 
+	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/registry"
@@ -37,10 +38,12 @@ type UserAuthTokenService struct {
 	ServerLockService *serverlock.ServerLockService `inject:""`
 	Cfg               *setting.Cfg                  `inject:""`
 	log               log.Logger
+	limitsCache       *localcache.CacheService
 }
 
 func (s *UserAuthTokenService) Init() error {
 	s.log = log.New("auth")
+	s.limitsCache = localcache.New(sessionLimitsCacheTTL, 2*sessionLimitsCacheTTL)
 	return nil
 }
 
@@ -96,6 +99,15 @@ func (s *UserAuthTokenService) CreateToken(ctx context.Context, user *models.Use
 		return nil, err
 	}
 
+	limits, err := s.userLimits(ctx, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.enforceConcurrentSessions(ctx, user.Id, limits.maxConcurrentSessions); err != nil {
+		return nil, err
+	}
+
 	userAuthToken.UnhashedToken = token
 
 	s.log.Debug("user auth token created", "tokenId", userAuthToken.Id, "userId", userAuthToken.UserId, "clientIP", userAuthToken.ClientIp, "userAgent", userAuthToken.UserAgent, "authToken", userAuthToken.AuthToken)
@@ -127,7 +139,12 @@ func (s *UserAuthTokenService) LookupToken(ctx context.Context, unhashedToken st
 		return nil, models.ErrUserTokenNotFound
 	}
 
-	if model.CreatedAt <= s.createdAfterParam() || model.RotatedAt <= s.rotatedAfterParam() {
+	limits, err := s.userLimits(ctx, model.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if limits.isExpired(&model) {
 		return nil, &models.TokenExpiredError{
 			UserID:  model.UserId,
 			TokenID: model.Id,
@@ -377,13 +394,18 @@ func (s *UserAuthTokenService) GetUserToken(ctx context.Context, userId, userTok
 }
 
 func (s *UserAuthTokenService) GetUserTokens(ctx context.Context, userId int64) ([]*models.UserToken, error) {
+	limits, err := s.userLimits(ctx, userId)
+	if err != nil {
+		return nil, err
+	}
+
 	result := []*models.UserToken{}
-	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+	err = s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
 		var tokens []*userAuthToken
 		err := dbSession.Where("user_id = ? AND created_at > ? AND rotated_at > ?",
 			userId,
-			s.createdAfterParam(),
-			s.rotatedAfterParam()).
+			getTime().Add(-limits.maxLifetime).Unix(),
+			getTime().Add(-limits.maxInactiveLifetime).Unix()).
 			Find(&tokens)
 		if err != nil {
 			return err
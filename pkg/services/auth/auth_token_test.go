@@ -10,6 +10,7 @@ import (
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/setting"
 
+	"github.com/grafana/grafana/pkg/infra/localcache"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
@@ -522,7 +523,8 @@ func createTestContext(t *testing.T) *testContext {
 			LoginMaxLifetime:             maxLifetimeDurationVal,
 			TokenRotationIntervalMinutes: 10,
 		},
-		log: log.New("test-logger"),
+		log:         log.New("test-logger"),
+		limitsCache: localcache.New(sessionLimitsCacheTTL, 2*sessionLimitsCacheTTL),
 	}
 
 	return &testContext{
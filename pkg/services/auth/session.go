@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+type orgSessionPolicy struct {
+	Id                    int64
+	OrgId                 int64
+	MaxInactiveLifetime   int64
+	MaxLifetime           int64
+	MaxConcurrentSessions int
+	Updated               time.Time
+}
+
+type userSession struct {
+	Token userAuthToken `xorm:"extends"`
+	Login string
+	Email string
+	Name  string
+	Role  string
+}
+
+// sessionLimitsCacheTTL is how long the session settings of a user are cached, so that looking up tokens
+// doesn't have to query the session policies on every request.
+const sessionLimitsCacheTTL = time.Minute
+
+// sessionLimits are the session settings that apply to a user.
+type sessionLimits struct {
+	maxInactiveLifetime   time.Duration
+	maxLifetime           time.Duration
+	maxConcurrentSessions int
+}
+
+func (l sessionLimits) isExpired(token *userAuthToken) bool {
+	now := getTime()
+	return token.CreatedAt <= now.Add(-l.maxLifetime).Unix() || token.RotatedAt <= now.Add(-l.maxInactiveLifetime).Unix()
+}
+
+// orgManagedUserCondition matches the org_user rows of members whose sessions the admins of the organization
+// manage: users that belong to no other organization and aren't Grafana admins. Policies and revocations of
+// an organization don't affect anyone else.
+func (s *UserAuthTokenService) orgManagedUserCondition() string {
+	userTable := s.SQLStore.Dialect.Quote("user")
+	return `NOT EXISTS (
+		SELECT 1 FROM org_user AS other_org_user
+		WHERE other_org_user.user_id = org_user.user_id AND other_org_user.org_id <> org_user.org_id
+	) AND NOT EXISTS (
+		SELECT 1 FROM ` + userTable + ` WHERE ` + userTable + `.id = org_user.user_id AND ` + userTable + `.is_admin = ` +
+		s.SQLStore.Dialect.BooleanStr(true) + `
+	)`
+}
+
+// orgPolicyExpiredCondition matches tokens that expired according to the session policy
+// of the organization of their user. It takes the current unix time twice as parameters.
+func (s *UserAuthTokenService) orgPolicyExpiredCondition() string {
+	return `EXISTS (
+	SELECT 1 FROM org_user
+	INNER JOIN org_session_policy ON org_session_policy.org_id = org_user.org_id
+	WHERE org_user.user_id = user_auth_token.user_id AND ` + s.orgManagedUserCondition() + ` AND (
+		(org_session_policy.max_lifetime > 0 AND user_auth_token.created_at <= ? - org_session_policy.max_lifetime) OR
+		(org_session_policy.max_inactive_lifetime > 0 AND user_auth_token.rotated_at <= ? - org_session_policy.max_inactive_lifetime)
+	)
+)`
+}
+
+func (s *UserAuthTokenService) GetSessionPolicy(ctx context.Context, orgId int64) (*models.SessionPolicy, error) {
+	var policy orgSessionPolicy
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		_, err := dbSession.Where("org_id = ?", orgId).Get(&policy)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SessionPolicy{
+		OrgId:                 orgId,
+		MaxInactiveLifetime:   time.Duration(policy.MaxInactiveLifetime) * time.Second,
+		MaxLifetime:           time.Duration(policy.MaxLifetime) * time.Second,
+		MaxConcurrentSessions: policy.MaxConcurrentSessions,
+	}, nil
+}
+
+// SetSessionPolicy stores the session policy of an organization. Policies can only make
+// the server session settings stricter.
+func (s *UserAuthTokenService) SetSessionPolicy(ctx context.Context, policy *models.SessionPolicy) error {
+	if policy.MaxInactiveLifetime < 0 || policy.MaxLifetime < 0 || policy.MaxConcurrentSessions < 0 ||
+		policy.MaxInactiveLifetime > s.Cfg.LoginMaxInactiveLifetime || policy.MaxLifetime > s.Cfg.LoginMaxLifetime {
+		return models.ErrInvalidSessionPolicy
+	}
+
+	err := s.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		var existing orgSessionPolicy
+		exists, err := dbSession.Where("org_id = ?", policy.OrgId).Get(&existing)
+		if err != nil {
+			return err
+		}
+
+		model := orgSessionPolicy{
+			OrgId:                 policy.OrgId,
+			MaxInactiveLifetime:   int64(policy.MaxInactiveLifetime / time.Second),
+			MaxLifetime:           int64(policy.MaxLifetime / time.Second),
+			MaxConcurrentSessions: policy.MaxConcurrentSessions,
+			Updated:               getTime(),
+		}
+
+		if exists {
+			_, err = dbSession.ID(existing.Id).AllCols().Omit("id").Update(&model)
+		} else {
+			_, err = dbSession.Insert(&model)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	// other instances pick up the policy once their cached limits expire
+	if s.limitsCache != nil {
+		s.limitsCache.Flush()
+	}
+	return nil
+}
+
+// userLimits returns the server session settings, overridden by the policy of the organization of the user
+// if the organization manages the user's sessions.
+// The limits are cached for sessionLimitsCacheTTL.
+func (s *UserAuthTokenService) userLimits(ctx context.Context, userId int64) (sessionLimits, error) {
+	if s.limitsCache == nil {
+		return s.queryUserLimits(ctx, userId)
+	}
+
+	cacheKey := "session-limits-" + strconv.FormatInt(userId, 10)
+	if cached, found := s.limitsCache.Get(cacheKey); found {
+		return cached.(sessionLimits), nil
+	}
+
+	limits, err := s.queryUserLimits(ctx, userId)
+	if err != nil {
+		return limits, err
+	}
+
+	s.limitsCache.Set(cacheKey, limits, sessionLimitsCacheTTL)
+	return limits, nil
+}
+
+func (s *UserAuthTokenService) queryUserLimits(ctx context.Context, userId int64) (sessionLimits, error) {
+	limits := sessionLimits{
+		maxInactiveLifetime:   s.Cfg.LoginMaxInactiveLifetime,
+		maxLifetime:           s.Cfg.LoginMaxLifetime,
+		maxConcurrentSessions: s.Cfg.LoginMaxConcurrentSessions,
+	}
+
+	var policies []*orgSessionPolicy
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		return dbSession.SQL(`SELECT org_session_policy.* FROM org_session_policy
+			INNER JOIN org_user ON org_user.org_id = org_session_policy.org_id
+			WHERE org_user.user_id = ? AND `+s.orgManagedUserCondition(), userId).Find(&policies)
+	})
+	if err != nil {
+		return limits, err
+	}
+
+	for _, policy := range policies {
+		if d := time.Duration(policy.MaxInactiveLifetime) * time.Second; d > 0 && d < limits.maxInactiveLifetime {
+			limits.maxInactiveLifetime = d
+		}
+		if d := time.Duration(policy.MaxLifetime) * time.Second; d > 0 && d < limits.maxLifetime {
+			limits.maxLifetime = d
+		}
+		if n := policy.MaxConcurrentSessions; n > 0 && (limits.maxConcurrentSessions <= 0 || n < limits.maxConcurrentSessions) {
+			limits.maxConcurrentSessions = n
+		}
+	}
+
+	return limits, nil
+}
+
+// enforceConcurrentSessions revokes the oldest sessions of a user that exceed the maximum number of concurrent sessions.
+func (s *UserAuthTokenService) enforceConcurrentSessions(ctx context.Context, userId int64, maxConcurrentSessions int) error {
+	if maxConcurrentSessions <= 0 {
+		return nil
+	}
+
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		var tokens []*userAuthToken
+		err := dbSession.Where("user_id = ?", userId).Desc("created_at").Desc("id").Find(&tokens)
+		if err != nil || len(tokens) <= maxConcurrentSessions {
+			return err
+		}
+
+		ids := make([]interface{}, 0, len(tokens)-maxConcurrentSessions)
+		for _, token := range tokens[maxConcurrentSessions:] {
+			ids = append(ids, token.Id)
+		}
+
+		if _, err := dbSession.In("id", ids...).Delete(&userAuthToken{}); err != nil {
+			return err
+		}
+
+		s.log.Debug("user auth tokens over the concurrent session limit revoked", "userId", userId, "count", len(ids))
+		return nil
+	})
+}
+
+// SearchUserSessions returns the active sessions of all users, or of the members of an organization.
+func (s *UserAuthTokenService) SearchUserSessions(ctx context.Context, query *models.SearchUserSessionsQuery) (*models.SearchUserSessionsResult, error) {
+	if query.Limit <= 0 {
+		query.Limit = 1000
+	}
+	if query.Page <= 0 {
+		query.Page = 1
+	}
+
+	dialect := s.SQLStore.Dialect
+	userTable := dialect.Quote("user")
+
+	from := ` FROM user_auth_token INNER JOIN ` + userTable + ` ON ` + userTable + `.id = user_auth_token.user_id`
+	if query.OrgId != 0 {
+		from += ` INNER JOIN org_user ON org_user.user_id = user_auth_token.user_id`
+	}
+
+	now := getTime().Unix()
+	whereConditions := []string{
+		"user_auth_token.created_at > ?",
+		"user_auth_token.rotated_at > ?",
+		"NOT " + s.orgPolicyExpiredCondition(),
+	}
+	whereParams := []interface{}{s.createdAfterParam(), s.rotatedAfterParam(), now, now}
+
+	if query.OrgId != 0 {
+		// the admins of an organization only see the sessions they manage
+		whereConditions = append(whereConditions, "org_user.org_id = ?", s.orgManagedUserCondition())
+		whereParams = append(whereParams, query.OrgId)
+
+		if query.Role != "" {
+			whereConditions = append(whereConditions, "org_user.role = ?")
+			whereParams = append(whereParams, query.Role)
+		}
+	}
+
+	if query.Query != "" {
+		like := " " + dialect.LikeStr() + " ?"
+		whereConditions = append(whereConditions, "("+userTable+".login"+like+" OR "+userTable+".email"+like+" OR "+
+			userTable+".name"+like+" OR user_auth_token.client_ip"+like+")")
+		queryWithWildcards := "%" + query.Query + "%"
+		whereParams = append(whereParams, queryWithWildcards, queryWithWildcards, queryWithWildcards, queryWithWildcards)
+	}
+
+	where := ` WHERE ` + strings.Join(whereConditions, " AND ")
+
+	selectCols := `SELECT user_auth_token.*, ` + userTable + `.login, ` + userTable + `.email, ` + userTable + `.name`
+	if query.OrgId != 0 {
+		selectCols += `, org_user.role`
+	}
+
+	result := &models.SearchUserSessionsResult{Sessions: []*models.UserSession{}}
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		var sessions []*userSession
+		sql := selectCols + from + where +
+			` ORDER BY user_auth_token.created_at DESC, user_auth_token.id DESC ` + dialect.LimitOffset(int64(query.Limit), int64(query.Limit*(query.Page-1)))
+		if err := dbSession.SQL(sql, whereParams...).Find(&sessions); err != nil {
+			return err
+		}
+
+		for _, session := range sessions {
+			userSession := &models.UserSession{
+				Login: session.Login,
+				Email: session.Email,
+				Name:  session.Name,
+				Role:  models.RoleType(session.Role),
+			}
+			if err := session.Token.toUserToken(&userSession.UserToken); err != nil {
+				return err
+			}
+			result.Sessions = append(result.Sessions, userSession)
+		}
+
+		count, err := dbSession.SQL(`SELECT COUNT(*)`+from+where, whereParams...).Count()
+		result.TotalCount = count
+		return err
+	})
+
+	return result, err
+}
+
+// RevokeOrgUserTokens revokes the sessions of the members of an organization whose sessions it manages, optionally
+// only of the members with a role. The token exceptTokenId is kept, so that admins can sign out everyone else.
+func (s *UserAuthTokenService) RevokeOrgUserTokens(ctx context.Context, orgId int64, role models.RoleType, exceptTokenId int64) (int64, error) {
+	var affected int64
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		sql := `DELETE FROM user_auth_token WHERE id <> ? AND user_id IN (SELECT user_id FROM org_user WHERE org_id = ? AND ` +
+			s.orgManagedUserCondition()
+		params := []interface{}{exceptTokenId, orgId}
+		if role != "" {
+			sql += ` AND role = ?`
+			params = append(params, role)
+		}
+		sql += `)`
+
+		res, err := dbSession.Exec(append([]interface{}{sql}, params...)...)
+		if err != nil {
+			return err
+		}
+
+		affected, err = res.RowsAffected()
+		if err != nil {
+			return err
+		}
+
+		s.log.Debug("user tokens for org revoked", "orgId", orgId, "role", role, "count", affected)
+		return nil
+	})
+
+	return affected, err
+}
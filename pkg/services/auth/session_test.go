@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestUserSessions(t *testing.T) {
+	ctx := createTestContext(t)
+	now := time.Date(2021, 3, 4, 12, 0, 0, 0, time.UTC)
+	getTime = func() time.Time { return now }
+
+	insertUser := func(login string, orgID int64, role models.RoleType) *models.User {
+		user := &models.User{Login: login, Email: login + "@example.com", Name: login, OrgId: orgID, Created: now, Updated: now}
+		_, err := ctx.sqlstore.NewSession().Insert(user)
+		require.NoError(t, err)
+		_, err = ctx.sqlstore.NewSession().Insert(&models.OrgUser{OrgId: orgID, UserId: user.Id, Role: role, Created: now, Updated: now})
+		require.NoError(t, err)
+		return user
+	}
+
+	viewer := insertUser("viewer", 1, models.ROLE_VIEWER)
+	editor := insertUser("editor", 1, models.ROLE_EDITOR)
+	other := insertUser("other", 2, models.ROLE_ADMIN)
+
+	createToken := func(user *models.User, ip string) *models.UserToken {
+		token, err := ctx.tokenService.CreateToken(context.Background(), user, net.ParseIP(ip), "some user agent")
+		require.NoError(t, err)
+		return token
+	}
+
+	viewerToken := createToken(viewer, "192.168.10.11")
+	editorToken := createToken(editor, "192.168.10.12")
+	otherToken := createToken(other, "10.0.0.1")
+
+	t.Run("Can search the sessions of an organization", func(t *testing.T) {
+		result, err := ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{OrgId: 1})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, result.TotalCount)
+		require.Len(t, result.Sessions, 2)
+		assert.Equal(t, "editor", result.Sessions[0].Login)
+		assert.Equal(t, models.ROLE_EDITOR, result.Sessions[0].Role)
+		assert.Equal(t, "192.168.10.12", result.Sessions[0].ClientIp)
+		assert.Equal(t, "viewer", result.Sessions[1].Login)
+	})
+
+	t.Run("Can search sessions by role, user and client IP", func(t *testing.T) {
+		result, err := ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{OrgId: 1, Role: models.ROLE_VIEWER})
+		require.NoError(t, err)
+		require.Len(t, result.Sessions, 1)
+		assert.Equal(t, viewerToken.Id, result.Sessions[0].Id)
+
+		result, err = ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{Query: "10.0.0"})
+		require.NoError(t, err)
+		require.Len(t, result.Sessions, 1)
+		assert.Equal(t, otherToken.Id, result.Sessions[0].Id)
+		assert.Empty(t, result.Sessions[0].Role)
+
+		result, err = ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{Query: "edit"})
+		require.NoError(t, err)
+		require.Len(t, result.Sessions, 1)
+		assert.Equal(t, editorToken.Id, result.Sessions[0].Id)
+	})
+
+	t.Run("Can page sessions of all organizations", func(t *testing.T) {
+		result, err := ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{Limit: 2, Page: 2})
+		require.NoError(t, err)
+		assert.EqualValues(t, 3, result.TotalCount)
+		require.Len(t, result.Sessions, 1)
+		assert.Equal(t, viewerToken.Id, result.Sessions[0].Id)
+	})
+
+	t.Run("Rejects policies that are longer than the server settings", func(t *testing.T) {
+		err := ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1, MaxLifetime: 1000 * time.Hour})
+		assert.Equal(t, models.ErrInvalidSessionPolicy, err)
+
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1, MaxConcurrentSessions: -1})
+		assert.Equal(t, models.ErrInvalidSessionPolicy, err)
+	})
+
+	t.Run("Sessions expire according to the policy of the organization", func(t *testing.T) {
+		err := ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1, MaxInactiveLifetime: time.Hour})
+		require.NoError(t, err)
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1, MaxInactiveLifetime: 2 * time.Hour})
+		require.NoError(t, err)
+
+		policy, err := ctx.tokenService.GetSessionPolicy(context.Background(), 1)
+		require.NoError(t, err)
+		assert.Equal(t, 2*time.Hour, policy.MaxInactiveLifetime)
+
+		getTime = func() time.Time { return now.Add(2 * time.Hour) }
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), viewerToken.UnhashedToken)
+		var expired *models.TokenExpiredError
+		require.ErrorAs(t, err, &expired)
+		assert.Equal(t, viewer.Id, expired.UserID)
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), otherToken.UnhashedToken)
+		require.NoError(t, err)
+
+		tokens, err := ctx.tokenService.GetUserTokens(context.Background(), viewer.Id)
+		require.NoError(t, err)
+		assert.Empty(t, tokens)
+
+		result, err := ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{})
+		require.NoError(t, err)
+		require.Len(t, result.Sessions, 1)
+		assert.Equal(t, otherToken.Id, result.Sessions[0].Id)
+
+		affected, err := ctx.tokenService.deleteExpiredTokens(context.Background(), ctx.tokenService.Cfg.LoginMaxInactiveLifetime, ctx.tokenService.Cfg.LoginMaxLifetime)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, affected)
+
+		getTime = func() time.Time { return now }
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1})
+		require.NoError(t, err)
+	})
+
+	t.Run("The session settings of users are cached until a policy changes", func(t *testing.T) {
+		_, err := ctx.tokenService.LookupToken(context.Background(), otherToken.UnhashedToken)
+		require.NoError(t, err)
+
+		// a policy written by another instance only applies once the cached settings expire
+		_, err = ctx.sqlstore.NewSession().Insert(&orgSessionPolicy{OrgId: 2, MaxInactiveLifetime: 60, Updated: now})
+		require.NoError(t, err)
+		getTime = func() time.Time { return now.Add(2 * time.Minute) }
+		_, err = ctx.tokenService.LookupToken(context.Background(), otherToken.UnhashedToken)
+		require.NoError(t, err)
+
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 2, MaxInactiveLifetime: time.Minute})
+		require.NoError(t, err)
+		_, err = ctx.tokenService.LookupToken(context.Background(), otherToken.UnhashedToken)
+		var expired *models.TokenExpiredError
+		require.ErrorAs(t, err, &expired)
+
+		getTime = func() time.Time { return now }
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 2})
+		require.NoError(t, err)
+	})
+
+	t.Run("Creating a session revokes the oldest sessions over the limit", func(t *testing.T) {
+		ctx.tokenService.Cfg.LoginMaxConcurrentSessions = 3
+		err := ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1, MaxConcurrentSessions: 2})
+		require.NoError(t, err)
+
+		first := createToken(viewer, "192.168.10.11")
+		getTime = func() time.Time { return now.Add(time.Minute) }
+		createToken(viewer, "192.168.10.11")
+		getTime = func() time.Time { return now.Add(2 * time.Minute) }
+		createToken(viewer, "192.168.10.11")
+
+		tokens, err := ctx.tokenService.GetUserTokens(context.Background(), viewer.Id)
+		require.NoError(t, err)
+		require.Len(t, tokens, 2)
+		for _, token := range tokens {
+			assert.NotEqual(t, first.Id, token.Id)
+		}
+
+		for i := 0; i < 4; i++ {
+			createToken(other, "10.0.0.1")
+		}
+		tokens, err = ctx.tokenService.GetUserTokens(context.Background(), other.Id)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 3)
+
+		ctx.tokenService.Cfg.LoginMaxConcurrentSessions = 0
+	})
+
+	t.Run("Can revoke the sessions of the members of an organization", func(t *testing.T) {
+		editorToken = createToken(editor, "192.168.10.12")
+		keep := createToken(editor, "192.168.10.12")
+
+		affected, err := ctx.tokenService.RevokeOrgUserTokens(context.Background(), 1, models.ROLE_VIEWER, 0)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, affected)
+
+		affected, err = ctx.tokenService.RevokeOrgUserTokens(context.Background(), 1, "", keep.Id)
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, affected)
+
+		tokens, err := ctx.tokenService.GetUserTokens(context.Background(), editor.Id)
+		require.NoError(t, err)
+		require.Len(t, tokens, 1)
+		assert.Equal(t, keep.Id, tokens[0].Id)
+
+		tokens, err = ctx.tokenService.GetUserTokens(context.Background(), other.Id)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 3)
+	})
+
+	t.Run("Policies and revocations don't affect members of other organizations and Grafana admins", func(t *testing.T) {
+		shared := insertUser("shared", 1, models.ROLE_EDITOR)
+		_, err := ctx.sqlstore.NewSession().Insert(&models.OrgUser{OrgId: 2, UserId: shared.Id, Role: models.ROLE_ADMIN, Created: now, Updated: now})
+		require.NoError(t, err)
+		grafanaAdmin := insertUser("grafana-admin", 1, models.ROLE_VIEWER)
+		grafanaAdmin.IsAdmin = true
+		_, err = ctx.sqlstore.NewSession().ID(grafanaAdmin.Id).Cols("is_admin").Update(grafanaAdmin)
+		require.NoError(t, err)
+
+		sharedToken := createToken(shared, "192.168.10.13")
+		adminToken := createToken(grafanaAdmin, "192.168.10.14")
+		viewerToken = createToken(viewer, "192.168.10.11")
+
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1, MaxInactiveLifetime: time.Hour})
+		require.NoError(t, err)
+		getTime = func() time.Time { return now.Add(2 * time.Hour) }
+
+		_, err = ctx.tokenService.LookupToken(context.Background(), viewerToken.UnhashedToken)
+		var expired *models.TokenExpiredError
+		require.ErrorAs(t, err, &expired)
+		_, err = ctx.tokenService.LookupToken(context.Background(), sharedToken.UnhashedToken)
+		require.NoError(t, err)
+		_, err = ctx.tokenService.LookupToken(context.Background(), adminToken.UnhashedToken)
+		require.NoError(t, err)
+
+		result, err := ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{Query: "192.168.10.1"})
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, result.TotalCount)
+
+		// the admins of the organization don't see the sessions that they don't manage
+		getTime = func() time.Time { return now }
+		result, err = ctx.tokenService.SearchUserSessions(context.Background(), &models.SearchUserSessionsQuery{OrgId: 1, Query: "192.168.10.1"})
+		require.NoError(t, err)
+		require.NotEmpty(t, result.Sessions)
+		for _, session := range result.Sessions {
+			assert.NotContains(t, []int64{shared.Id, grafanaAdmin.Id}, session.UserId)
+		}
+
+		getTime = func() time.Time { return now }
+		err = ctx.tokenService.SetSessionPolicy(context.Background(), &models.SessionPolicy{OrgId: 1})
+		require.NoError(t, err)
+
+		_, err = ctx.tokenService.RevokeOrgUserTokens(context.Background(), 1, "", 0)
+		require.NoError(t, err)
+		for _, user := range []*models.User{shared, grafanaAdmin} {
+			tokens, err := ctx.tokenService.GetUserTokens(context.Background(), user.Id)
+			require.NoError(t, err)
+			assert.Len(t, tokens, 1, user.Login)
+		}
+	})
+}
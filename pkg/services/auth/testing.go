@@ -17,6 +17,10 @@ type FakeUserAuthTokenService struct {
 	GetUserTokenProvider        func(ctx context.Context, userId, userTokenId int64) (*models.UserToken, error)
 	GetUserTokensProvider       func(ctx context.Context, userId int64) ([]*models.UserToken, error)
 	BatchRevokedTokenProvider   func(ctx context.Context, userIds []int64) error
+	SearchUserSessionsProvider  func(ctx context.Context, query *models.SearchUserSessionsQuery) (*models.SearchUserSessionsResult, error)
+	RevokeOrgUserTokensProvider func(ctx context.Context, orgId int64, role models.RoleType, exceptTokenId int64) (int64, error)
+	GetSessionPolicyProvider    func(ctx context.Context, orgId int64) (*models.SessionPolicy, error)
+	SetSessionPolicyProvider    func(ctx context.Context, policy *models.SessionPolicy) error
 }
 
 func NewFakeUserAuthTokenService() *FakeUserAuthTokenService {
@@ -54,6 +58,18 @@ func NewFakeUserAuthTokenService() *FakeUserAuthTokenService {
 		GetUserTokensProvider: func(ctx context.Context, userId int64) ([]*models.UserToken, error) {
 			return nil, nil
 		},
+		SearchUserSessionsProvider: func(ctx context.Context, query *models.SearchUserSessionsQuery) (*models.SearchUserSessionsResult, error) {
+			return &models.SearchUserSessionsResult{Sessions: []*models.UserSession{}}, nil
+		},
+		RevokeOrgUserTokensProvider: func(ctx context.Context, orgId int64, role models.RoleType, exceptTokenId int64) (int64, error) {
+			return 0, nil
+		},
+		GetSessionPolicyProvider: func(ctx context.Context, orgId int64) (*models.SessionPolicy, error) {
+			return &models.SessionPolicy{OrgId: orgId}, nil
+		},
+		SetSessionPolicyProvider: func(ctx context.Context, policy *models.SessionPolicy) error {
+			return nil
+		},
 	}
 }
 
@@ -99,3 +115,19 @@ func (s *FakeUserAuthTokenService) GetUserTokens(ctx context.Context, userId int
 func (s *FakeUserAuthTokenService) BatchRevokeAllUserTokens(ctx context.Context, userIds []int64) error {
 	return s.BatchRevokedTokenProvider(ctx, userIds)
 }
+
+func (s *FakeUserAuthTokenService) SearchUserSessions(ctx context.Context, query *models.SearchUserSessionsQuery) (*models.SearchUserSessionsResult, error) {
+	return s.SearchUserSessionsProvider(ctx, query)
+}
+
+func (s *FakeUserAuthTokenService) RevokeOrgUserTokens(ctx context.Context, orgId int64, role models.RoleType, exceptTokenId int64) (int64, error) {
+	return s.RevokeOrgUserTokensProvider(ctx, orgId, role, exceptTokenId)
+}
+
+func (s *FakeUserAuthTokenService) GetSessionPolicy(ctx context.Context, orgId int64) (*models.SessionPolicy, error) {
+	return s.GetSessionPolicyProvider(ctx, orgId)
+}
+
+func (s *FakeUserAuthTokenService) SetSessionPolicy(ctx context.Context, policy *models.SessionPolicy) error {
+	return s.SetSessionPolicyProvider(ctx, policy)
+}
@@ -47,8 +47,9 @@ func (s *UserAuthTokenService) deleteExpiredTokens(ctx context.Context, maxInact
 
 	var affected int64
 	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
-		sql := `DELETE from user_auth_token WHERE created_at <= ? OR rotated_at <= ?`
-		res, err := dbSession.Exec(sql, createdBefore.Unix(), rotatedBefore.Unix())
+		now := getTime().Unix()
+		sql := `DELETE from user_auth_token WHERE created_at <= ? OR rotated_at <= ? OR ` + s.orgPolicyExpiredCondition()
+		res, err := dbSession.Exec(sql, createdBefore.Unix(), rotatedBefore.Unix(), now, now)
 		if err != nil {
 			return err
 		}
@@ -47,6 +47,7 @@ func (srv *CleanUpService) Run(ctx context.Context) error {
 			srv.cleanUpTmpFiles()
 			srv.deleteExpiredSnapshots()
 			srv.deleteExpiredDashboardVersions()
+			srv.purgeDeletedDashboards()
 			srv.cleanUpOldAnnotations(ctxWithTimeout)
 			srv.expireOldUserInvites()
 			srv.deleteStaleShortURLs()
@@ -130,6 +131,17 @@ func (srv *CleanUpService) deleteExpiredDashboardVersions() {
 	}
 }
 
+func (srv *CleanUpService) purgeDeletedDashboards() {
+	cmd := models.PurgeDeletedDashboardsCommand{
+		OlderThan: time.Now().Add(-srv.Cfg.DashboardTrashPeriod),
+	}
+	if err := bus.Dispatch(&cmd); err != nil {
+		srv.log.Error("Failed to purge deleted dashboards", "error", err.Error())
+	} else {
+		srv.log.Debug("Purged deleted dashboards", "rows affected", cmd.PurgedRows)
+	}
+}
+
 func (srv *CleanUpService) deleteOldLoginAttempts() {
 	if srv.Cfg.DisableBruteForceLoginProtection {
 		return
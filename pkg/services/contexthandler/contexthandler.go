@@ -228,6 +228,19 @@ func (h *ContextHandler) initContextWithBasicAuth(ctx *models.ReqContext, orgID
 
 	user := authQuery.User
 
+	// A password alone is not enough for users that have to provide a second factor.
+	if h.Cfg.MFAEnabled && authQuery.AuthModule == "grafana" {
+		mfaQuery := models.IsMFARequiredQuery{UserId: user.Id}
+		if err := bus.DispatchCtx(ctx.Req.Context(), &mfaQuery); err != nil {
+			ctx.JsonApiErr(500, "Failed to authorize the user", err)
+			return true
+		}
+		if mfaQuery.Result {
+			ctx.JsonApiErr(401, "Basic auth is not allowed for users with multi-factor authentication, use an API key instead", nil)
+			return true
+		}
+	}
+
 	query := models.GetSignedInUserQuery{UserId: user.Id, OrgId: orgID}
 	if err := bus.Dispatch(&query); err != nil {
 		ctx.Logger.Error(
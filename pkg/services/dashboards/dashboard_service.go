@@ -20,6 +20,7 @@ type DashboardService interface {
 	SaveDashboard(dto *SaveDashboardDTO, allowUiUpdate bool) (*models.Dashboard, error)
 	ImportDashboard(dto *SaveDashboardDTO) (*models.Dashboard, error)
 	DeleteDashboard(dashboardId int64, orgId int64) error
+	RestoreDeletedDashboard(cmd *models.RestoreDeletedDashboardCommand, user *models.SignedInUser) error
 }
 
 // DashboardProvisioningService is a service for operating on provisioned dashboards.
@@ -327,6 +328,54 @@ func (dr *dashboardServiceImpl) deleteDashboard(dashboardId int64, orgId int64,
 	return bus.Dispatch(cmd)
 }
 
+// RestoreDeletedDashboard restores a dashboard or folder from the trash and updates the alerts of the restored
+// dashboards. Errors out if the alerts of a dashboard can't be extracted.
+func (dr *dashboardServiceImpl) RestoreDeletedDashboard(cmd *models.RestoreDeletedDashboardCommand, user *models.SignedInUser) error {
+	query := models.GetRestoredDashboardsQuery{
+		OrgId:       cmd.OrgId,
+		DashboardId: cmd.DashboardId,
+		Version:     cmd.Version,
+		FolderId:    cmd.FolderId,
+	}
+	if err := bus.Dispatch(&query); err != nil {
+		return err
+	}
+
+	for _, dash := range query.Result {
+		if dash.IsFolder {
+			continue
+		}
+		validateAlertsCmd := models.ValidateDashboardAlertsCommand{
+			OrgId:     cmd.OrgId,
+			Dashboard: dash,
+			User:      user,
+		}
+		if err := bus.Dispatch(&validateAlertsCmd); err != nil {
+			return err
+		}
+	}
+
+	if err := bus.Dispatch(cmd); err != nil {
+		return err
+	}
+
+	for _, dash := range append([]*models.Dashboard{cmd.Result}, cmd.Children...) {
+		if dash.IsFolder {
+			continue
+		}
+		alertCmd := models.UpdateDashboardAlertsCommand{
+			OrgId:     cmd.OrgId,
+			Dashboard: dash,
+			User:      user,
+		}
+		if err := bus.Dispatch(&alertCmd); err != nil {
+			return errutil.Wrapf(err, "failed to restore the alerts of dashboard %d", dash.Id)
+		}
+	}
+
+	return nil
+}
+
 func (dr *dashboardServiceImpl) ImportDashboard(dto *SaveDashboardDTO) (*models.Dashboard, error) {
 	if err := validateDashboardRefreshInterval(dto.Dashboard); err != nil {
 		dr.log.Warn("Changing refresh interval for imported dashboard to minimum refresh interval", "dashboardUid", dto.Dashboard.Uid, "dashboardTitle", dto.Dashboard.Title, "minRefreshInterval", setting.MinRefreshInterval)
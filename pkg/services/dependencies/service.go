@@ -0,0 +1,45 @@
+// Package dependencies keeps the index of the entities that dashboards depend on up to date.
+//
+// Dependencies are indexed when dashboards are saved. The service indexes the dashboards that were
+// saved before the index was added.
+package dependencies
+
+import (
+	"context"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/registry"
+)
+
+func init() {
+	registry.RegisterService(&DependencyService{})
+}
+
+// DependencyService indexes the dependencies of dashboards that haven't been indexed yet.
+type DependencyService struct {
+	Bus bus.Bus `inject:""`
+
+	log log.Logger
+}
+
+// Init initializes the service.
+func (s *DependencyService) Init() error {
+	s.log = log.New("dependencies")
+	return nil
+}
+
+// Run indexes the dependencies of the dashboards that haven't been indexed yet.
+func (s *DependencyService) Run(_ context.Context) error {
+	cmd := &models.IndexDashboardDependenciesCommand{}
+	if err := s.Bus.Dispatch(cmd); err != nil {
+		s.log.Error("Failed to index dashboard dependencies", "error", err)
+		return nil
+	}
+
+	if cmd.Result > 0 {
+		s.log.Info("Indexed dashboard dependencies", "count", cmd.Result)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package dependencies
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestDependencyService(t *testing.T) {
+	setup := func(t *testing.T, err error) (*DependencyService, *int) {
+		t.Helper()
+		calls := 0
+		s := &DependencyService{Bus: bus.New()}
+		s.Bus.AddHandler(func(cmd *models.IndexDashboardDependenciesCommand) error {
+			calls++
+			cmd.Result = 3
+			return err
+		})
+		require.NoError(t, s.Init())
+		return s, &calls
+	}
+
+	t.Run("Indexes the dashboards that aren't indexed", func(t *testing.T) {
+		s, calls := setup(t, nil)
+		require.NoError(t, s.Run(context.Background()))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("Doesn't stop the server if the dashboards can't be indexed", func(t *testing.T) {
+		s, calls := setup(t, errors.New("database is locked"))
+		require.NoError(t, s.Run(context.Background()))
+		assert.Equal(t, 1, *calls)
+	})
+}
@@ -0,0 +1,128 @@
+package mfa
+
+import (
+	"errors"
+
+	"github.com/go-macaron/binding"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func (s *MFAService) registerAPIEndpoints() {
+	if !s.IsEnabled() {
+		return
+	}
+
+	s.RouteRegister.Group("/api/user/mfa", func(userMFA routing.RouteRegister) {
+		userMFA.Get("/", routing.Wrap(s.getStatusHandler))
+		userMFA.Post("/enroll", routing.Wrap(s.enrollHandler))
+		userMFA.Post("/confirm", binding.Bind(verifyCodeCommand{}), routing.Wrap(s.confirmHandler))
+		userMFA.Post("/recovery-codes", binding.Bind(verifyCodeCommand{}), routing.Wrap(s.recoveryCodesHandler))
+		userMFA.Post("/disable", binding.Bind(verifyCodeCommand{}), routing.Wrap(s.disableHandler))
+	}, middleware.ReqSignedIn)
+
+	s.RouteRegister.Group("/api/org/mfa", func(orgMFA routing.RouteRegister) {
+		orgMFA.Get("/", routing.Wrap(s.getOrgPolicyHandler))
+		orgMFA.Put("/", binding.Bind(orgPolicyCommand{}), routing.Wrap(s.updateOrgPolicyHandler))
+	}, middleware.ReqSignedIn, middleware.ReqOrgAdmin)
+
+	s.RouteRegister.Delete("/api/admin/users/:id/mfa", middleware.ReqSignedIn, middleware.ReqGrafanaAdmin, routing.Wrap(s.resetHandler))
+}
+
+// getStatusHandler handles GET /api/user/mfa.
+func (s *MFAService) getStatusHandler(c *models.ReqContext) response.Response {
+	status, err := s.GetStatus(c.Req.Context(), c.SignedInUser.UserId)
+	if err != nil {
+		return response.Error(500, "Failed to get multi-factor authentication status", err)
+	}
+
+	return response.JSON(200, status)
+}
+
+// enrollHandler handles POST /api/user/mfa/enroll.
+func (s *MFAService) enrollHandler(c *models.ReqContext) response.Response {
+	enrollment, err := s.Enroll(c.Req.Context(), c.SignedInUser.UserId, c.SignedInUser.Login)
+	if err != nil {
+		return toMFAError(err, "Failed to set up multi-factor authentication")
+	}
+
+	return response.JSON(200, enrollment)
+}
+
+// confirmHandler handles POST /api/user/mfa/confirm.
+func (s *MFAService) confirmHandler(c *models.ReqContext, cmd verifyCodeCommand) response.Response {
+	codes, err := s.Confirm(c.Req.Context(), c.SignedInUser.UserId, cmd.Code)
+	if err != nil {
+		return toMFAError(err, "Failed to confirm multi-factor authentication")
+	}
+
+	return response.JSON(200, util.DynMap{"message": "Multi-factor authentication enabled", "recoveryCodes": codes})
+}
+
+// recoveryCodesHandler handles POST /api/user/mfa/recovery-codes.
+func (s *MFAService) recoveryCodesHandler(c *models.ReqContext, cmd verifyCodeCommand) response.Response {
+	codes, err := s.RegenerateRecoveryCodes(c.Req.Context(), c.SignedInUser.UserId, cmd.Code)
+	if err != nil {
+		return toMFAError(err, "Failed to generate recovery codes")
+	}
+
+	return response.JSON(200, util.DynMap{"recoveryCodes": codes})
+}
+
+// disableHandler handles POST /api/user/mfa/disable.
+func (s *MFAService) disableHandler(c *models.ReqContext, cmd verifyCodeCommand) response.Response {
+	if err := s.Disable(c.Req.Context(), c.SignedInUser.UserId, cmd.Code); err != nil {
+		return toMFAError(err, "Failed to disable multi-factor authentication")
+	}
+
+	return response.Success("Multi-factor authentication disabled")
+}
+
+// getOrgPolicyHandler handles GET /api/org/mfa.
+func (s *MFAService) getOrgPolicyHandler(c *models.ReqContext) response.Response {
+	enforced, err := s.isEnforcedByOrg(c.Req.Context(), c.OrgId)
+	if err != nil {
+		return response.Error(500, "Failed to get multi-factor authentication policy", err)
+	}
+
+	return response.JSON(200, orgPolicyDTO{Enforced: enforced, GlobalEnforced: s.Cfg.MFAEnforced})
+}
+
+// updateOrgPolicyHandler handles PUT /api/org/mfa.
+func (s *MFAService) updateOrgPolicyHandler(c *models.ReqContext, cmd orgPolicyCommand) response.Response {
+	if err := s.setOrgPolicy(c.Req.Context(), c.OrgId, cmd.Enforced); err != nil {
+		return response.Error(500, "Failed to update multi-factor authentication policy", err)
+	}
+
+	return response.Success("Multi-factor authentication policy updated")
+}
+
+// resetHandler handles DELETE /api/admin/users/:id/mfa.
+func (s *MFAService) resetHandler(c *models.ReqContext) response.Response {
+	userID := c.ParamsInt64(":id")
+	if err := s.Reset(c.Req.Context(), userID); err != nil {
+		return response.Error(500, "Failed to reset multi-factor authentication", err)
+	}
+
+	return response.Success("Multi-factor authentication reset")
+}
+
+func toMFAError(err error, message string) response.Response {
+	if errors.Is(err, ErrInvalidCode) {
+		return response.Error(400, ErrInvalidCode.Error(), err)
+	}
+	if errors.Is(err, ErrNotEnrolled) {
+		return response.Error(404, ErrNotEnrolled.Error(), err)
+	}
+	if errors.Is(err, ErrAlreadyEnrolled) {
+		return response.Error(409, ErrAlreadyEnrolled.Error(), err)
+	}
+	if errors.Is(err, ErrRequired) {
+		return response.Error(403, ErrRequired.Error(), err)
+	}
+	return response.Error(500, message, err)
+}
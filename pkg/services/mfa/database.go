@@ -0,0 +1,95 @@
+package mfa
+
+import (
+	"context"
+	"time"
+
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+// getUserMFA returns the second factor of a user, or ErrNotEnrolled.
+func (s *MFAService) getUserMFA(ctx context.Context, userID int64) (*UserMFA, error) {
+	var userMFA UserMFA
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		exists, err := session.Where("user_id = ?", userID).Get(&userMFA)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return ErrNotEnrolled
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &userMFA, nil
+}
+
+// saveUserMFA creates or replaces the second factor of a user.
+func (s *MFAService) saveUserMFA(ctx context.Context, userMFA *UserMFA) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *sqlstore.DBSession) error {
+		userMFA.Updated = time.Now()
+		if userMFA.ID != 0 {
+			_, err := session.ID(userMFA.ID).AllCols().Update(userMFA)
+			return err
+		}
+
+		if _, err := session.Exec("DELETE FROM user_mfa WHERE user_id = ?", userMFA.UserID); err != nil {
+			return err
+		}
+		userMFA.Created = userMFA.Updated
+		_, err := session.Insert(userMFA)
+		return err
+	})
+}
+
+// deleteUserMFA removes the second factor of a user.
+func (s *MFAService) deleteUserMFA(ctx context.Context, userID int64) error {
+	return s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		_, err := session.Exec("DELETE FROM user_mfa WHERE user_id = ?", userID)
+		return err
+	})
+}
+
+// isEnforcedByOrg returns true if the policy of the organization requires a second factor.
+func (s *MFAService) isEnforcedByOrg(ctx context.Context, orgID int64) (bool, error) {
+	var policy OrgMFAPolicy
+	var exists bool
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		var err error
+		exists, err = session.Where("org_id = ?", orgID).Get(&policy)
+		return err
+	})
+
+	return exists && policy.Enforced, err
+}
+
+// setOrgPolicy stores the multi-factor authentication policy of an organization.
+func (s *MFAService) setOrgPolicy(ctx context.Context, orgID int64, enforced bool) error {
+	return s.SQLStore.WithTransactionalDbSession(ctx, func(session *sqlstore.DBSession) error {
+		policy := OrgMFAPolicy{OrgID: orgID, Enforced: enforced, Updated: time.Now()}
+		updated, err := session.Where("org_id = ?", orgID).Cols("enforced", "updated").Update(&policy)
+		if err != nil || updated > 0 {
+			return err
+		}
+		_, err = session.Insert(&policy)
+		return err
+	})
+}
+
+// isEnforcedForUser returns true if any organization the user is a member of requires a second factor.
+func (s *MFAService) isEnforcedForUser(ctx context.Context, userID int64) (bool, error) {
+	var count int64
+	err := s.SQLStore.WithDbSession(ctx, func(session *sqlstore.DBSession) error {
+		var err error
+		count, err = session.Table("org_mfa_policy").
+			Join("INNER", "org_user", "org_user.org_id = org_mfa_policy.org_id").
+			Where("org_user.user_id = ? AND org_mfa_policy.enforced = ?", userID, true).
+			Count()
+		return err
+	})
+
+	return count > 0, err
+}
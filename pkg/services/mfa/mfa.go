@@ -0,0 +1,527 @@
+// Package mfa implements time-based one-time passwords (RFC 6238) as a second factor for users that sign in
+// with a password stored in Grafana.
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/qrcode"
+	"github.com/grafana/grafana/pkg/components/securejsondata"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/login"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	// challengeTTL is how long a user has to enter the second factor after the password was accepted.
+	challengeTTL = 5 * time.Minute
+	// maxChallengeAttempts is the number of codes that can be tried before the user has to enter the password again.
+	maxChallengeAttempts = 5
+	// recoveryCodeCount is the number of recovery codes generated for a user.
+	recoveryCodeCount = 10
+	// qrCodeScale is the size of a QR code module in pixels.
+	qrCodeScale = 4
+
+	challengeKeyPrefix = "mfa-challenge-"
+)
+
+func init() {
+	registry.RegisterService(&MFAService{})
+	remotecache.Register(&Challenge{})
+}
+
+// MFAService manages the second factors of users and the second step of password logins.
+type MFAService struct {
+	Cfg                *setting.Cfg             `inject:""`
+	SQLStore           *sqlstore.SQLStore       `inject:""`
+	RouteRegister      routing.RouteRegister    `inject:""`
+	RemoteCacheService *remotecache.RemoteCache `inject:""`
+	log                log.Logger
+}
+
+// Init initializes the MFA service.
+func (s *MFAService) Init() error {
+	s.log = log.New("mfa")
+
+	if s.IsEnabled() {
+		bus.AddHandlerCtx("mfa", s.isMFARequiredQuery)
+	}
+	s.registerAPIEndpoints()
+
+	return nil
+}
+
+// IsEnabled returns true if multi-factor authentication is enabled for this instance.
+func (s *MFAService) IsEnabled() bool {
+	if s == nil || s.Cfg == nil {
+		return false
+	}
+
+	return s.Cfg.MFAEnabled
+}
+
+// AddMigration defines database migrations.
+func (s *MFAService) AddMigration(mg *migrator.Migrator) {
+	userMFAV1 := migrator.Table{
+		Name: "user_mfa",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "user_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "secret", Type: migrator.DB_Text, Nullable: false},
+			{Name: "confirmed", Type: migrator.DB_Bool, Nullable: false},
+			{Name: "recovery_codes", Type: migrator.DB_Text, Nullable: true},
+			{Name: "last_used_step", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "created", Type: migrator.DB_DateTime, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"user_id"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create user_mfa table v1", migrator.NewAddTableMigration(userMFAV1))
+	mg.AddMigration("add unique index user_mfa.user_id", migrator.NewAddIndexMigration(userMFAV1, userMFAV1.Indices[0]))
+
+	orgMFAPolicyV1 := migrator.Table{
+		Name: "org_mfa_policy",
+		Columns: []*migrator.Column{
+			{Name: "id", Type: migrator.DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: migrator.DB_BigInt, Nullable: false},
+			{Name: "enforced", Type: migrator.DB_Bool, Nullable: false},
+			{Name: "updated", Type: migrator.DB_DateTime, Nullable: false},
+		},
+		Indices: []*migrator.Index{
+			{Cols: []string{"org_id"}, Type: migrator.UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create org_mfa_policy table v1", migrator.NewAddTableMigration(orgMFAPolicyV1))
+	mg.AddMigration("add unique index org_mfa_policy.org_id", migrator.NewAddIndexMigration(orgMFAPolicyV1, orgMFAPolicyV1.Indices[0]))
+}
+
+// IsRequired returns true if the user has to set up a second factor, because it is enforced for the instance
+// or by one of the organizations of the user.
+func (s *MFAService) IsRequired(ctx context.Context, userID int64) (bool, error) {
+	if s.Cfg.MFAEnforced {
+		return true, nil
+	}
+
+	return s.isEnforcedForUser(ctx, userID)
+}
+
+// isMFARequiredQuery tells the basic auth middleware whether a password alone is not enough for a user.
+func (s *MFAService) isMFARequiredQuery(ctx context.Context, query *models.IsMFARequiredQuery) error {
+	userMFA, err := s.getUserMFA(ctx, query.UserId)
+	if err != nil && !errors.Is(err, ErrNotEnrolled) {
+		return err
+	}
+	if userMFA != nil && userMFA.Confirmed {
+		query.Result = true
+		return nil
+	}
+
+	query.Result, err = s.IsRequired(ctx, query.UserId)
+	return err
+}
+
+// GetStatus returns the multi-factor authentication status of a user.
+func (s *MFAService) GetStatus(ctx context.Context, userID int64) (*Status, error) {
+	required, err := s.IsRequired(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{Required: required}
+
+	userMFA, err := s.getUserMFA(ctx, userID)
+	if errors.Is(err, ErrNotEnrolled) {
+		return status, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	status.Enabled = userMFA.Confirmed
+	status.PendingConfirmation = !userMFA.Confirmed
+	if userMFA.Confirmed {
+		codes, err := recoveryCodeHashes(userMFA)
+		if err != nil {
+			return nil, err
+		}
+		status.RecoveryCodesLeft = len(codes)
+	}
+
+	return status, nil
+}
+
+// Enroll creates a new secret for a user. The second factor is only enabled once the user confirms it with a code.
+func (s *MFAService) Enroll(ctx context.Context, userID int64, account string) (*Enrollment, error) {
+	userMFA, err := s.getUserMFA(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotEnrolled) {
+		return nil, err
+	}
+	if userMFA != nil && userMFA.Confirmed {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := securejsondata.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	if userMFA == nil {
+		userMFA = &UserMFA{UserID: userID}
+	}
+	userMFA.Secret = base64.StdEncoding.EncodeToString(encrypted)
+	userMFA.RecoveryCodes = ""
+	userMFA.LastUsedStep = 0
+	if err := s.saveUserMFA(ctx, userMFA); err != nil {
+		return nil, err
+	}
+
+	uri := keyURI(s.Cfg.MFAIssuer, account, secret)
+	code, err := qrcode.New(uri)
+	if err != nil {
+		return nil, err
+	}
+	image, err := code.PNG(qrCodeScale)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Enrollment{
+		Secret: secret,
+		URL:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(image),
+	}, nil
+}
+
+// Confirm enables a pending second factor if the code matches, and returns the recovery codes of the user.
+func (s *MFAService) Confirm(ctx context.Context, userID int64, code string) ([]string, error) {
+	userMFA, err := s.getUserMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA.Confirmed {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	if err := s.validateCode(userMFA, code); err != nil {
+		return nil, err
+	}
+
+	userMFA.Confirmed = true
+	codes, err := setRecoveryCodes(userMFA)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveUserMFA(ctx, userMFA); err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Multi-factor authentication enabled", "userId", userID)
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, who has to provide a valid one-time password.
+func (s *MFAService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) ([]string, error) {
+	userMFA, err := s.getConfirmedUserMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.validateCode(userMFA, code); err != nil {
+		return nil, err
+	}
+
+	codes, err := setRecoveryCodes(userMFA)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.saveUserMFA(ctx, userMFA); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes the second factor of a user, who has to provide a valid one-time password or recovery code.
+// ErrRequired is returned if a second factor is required for the user.
+func (s *MFAService) Disable(ctx context.Context, userID int64, code string) error {
+	required, err := s.IsRequired(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ErrRequired
+	}
+
+	userMFA, err := s.getConfirmedUserMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.verify(ctx, userMFA, code); err != nil {
+		return err
+	}
+
+	s.log.Info("Multi-factor authentication disabled", "userId", userID)
+	return s.deleteUserMFA(ctx, userID)
+}
+
+// Reset removes the second factor of a user, for example when they lost their device and recovery codes.
+// If a second factor is required for the user they have to set up a new one at the next login.
+func (s *MFAService) Reset(ctx context.Context, userID int64) error {
+	s.log.Info("Multi-factor authentication reset", "userId", userID)
+	return s.deleteUserMFA(ctx, userID)
+}
+
+// StartLogin is called once the password of a user was accepted. It returns nil if no second factor is needed,
+// otherwise a challenge that the user has to complete with VerifyLogin.
+func (s *MFAService) StartLogin(ctx context.Context, userID int64) (*LoginChallenge, error) {
+	if !s.IsEnabled() {
+		return nil, nil
+	}
+
+	userMFA, err := s.getUserMFA(ctx, userID)
+	if err != nil && !errors.Is(err, ErrNotEnrolled) {
+		return nil, err
+	}
+	enrolled := userMFA != nil && userMFA.Confirmed
+
+	if !enrolled {
+		required, err := s.IsRequired(ctx, userID)
+		if err != nil || !required {
+			return nil, err
+		}
+	}
+
+	token, err := util.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.RemoteCacheService.Set(challengeKeyPrefix+token, &Challenge{UserID: userID}, challengeTTL); err != nil {
+		return nil, err
+	}
+
+	return &LoginChallenge{Token: token, EnrollmentRequired: !enrolled}, nil
+}
+
+// EnrollLogin creates a secret for a user that has to set up a second factor before the login can complete.
+func (s *MFAService) EnrollLogin(ctx context.Context, token string) (*Enrollment, error) {
+	challenge, err := s.getChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.GetUserByIdQuery{Id: challenge.UserID}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+
+	return s.Enroll(ctx, challenge.UserID, query.Result.Login)
+}
+
+// VerifyLogin completes the second step of a login. If the user set up the second factor during the login,
+// the code confirms it and the result contains the new recovery codes.
+// Failed attempts count towards the brute force login protection of the user.
+func (s *MFAService) VerifyLogin(ctx context.Context, token string, code string, ipAddress string) (*VerifyLoginResult, error) {
+	challenge, err := s.getChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+
+	query := models.GetUserByIdQuery{Id: challenge.UserID}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+	if err := login.ValidateLoginAttempts(s.Cfg, query.Result.Login); err != nil {
+		s.deleteChallenge(token)
+		return nil, err
+	}
+
+	result := &VerifyLoginResult{UserID: challenge.UserID}
+	userMFA, err := s.getUserMFA(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if userMFA.Confirmed {
+		result.UsedRecoveryCode, err = s.verify(ctx, userMFA, code)
+	} else {
+		result.RecoveryCodes, err = s.Confirm(ctx, challenge.UserID, code)
+	}
+
+	if errors.Is(err, ErrInvalidCode) {
+		if err := login.SaveInvalidLoginAttempt(s.Cfg, query.Result.Login, ipAddress); err != nil {
+			s.log.Error("Failed to save invalid login attempt", "err", err)
+		}
+
+		challenge.Attempts++
+		if challenge.Attempts >= maxChallengeAttempts {
+			s.deleteChallenge(token)
+		} else if err := s.RemoteCacheService.Set(challengeKeyPrefix+token, challenge, challengeTTL); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCode
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.deleteChallenge(token)
+	return result, nil
+}
+
+func (s *MFAService) getChallenge(token string) (*Challenge, error) {
+	if token == "" {
+		return nil, ErrInvalidChallenge
+	}
+
+	value, err := s.RemoteCacheService.Get(challengeKeyPrefix + token)
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	challenge, ok := value.(*Challenge)
+	if !ok {
+		return nil, ErrInvalidChallenge
+	}
+	return challenge, nil
+}
+
+func (s *MFAService) deleteChallenge(token string) {
+	if err := s.RemoteCacheService.Delete(challengeKeyPrefix + token); err != nil {
+		s.log.Warn("Failed to delete multi-factor authentication challenge", "error", err)
+	}
+}
+
+func (s *MFAService) getConfirmedUserMFA(ctx context.Context, userID int64) (*UserMFA, error) {
+	userMFA, err := s.getUserMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !userMFA.Confirmed {
+		return nil, ErrNotEnrolled
+	}
+	return userMFA, nil
+}
+
+// validateCode checks a one-time password and remembers its time step, so that it cannot be used again.
+// The caller is responsible for saving the user MFA.
+func (s *MFAService) validateCode(userMFA *UserMFA, code string) error {
+	encrypted, err := base64.StdEncoding.DecodeString(userMFA.Secret)
+	if err != nil {
+		return err
+	}
+	secret, err := securejsondata.Decrypt(encrypted)
+	if err != nil {
+		return err
+	}
+
+	step, ok := validateCode(string(secret), code, time.Now(), userMFA.LastUsedStep)
+	if !ok {
+		return ErrInvalidCode
+	}
+	userMFA.LastUsedStep = step
+	return nil
+}
+
+// verify checks a one-time password or, failing that, a recovery code, which is used up.
+// It returns true if a recovery code was used.
+func (s *MFAService) verify(ctx context.Context, userMFA *UserMFA, code string) (bool, error) {
+	err := s.validateCode(userMFA, code)
+	if err == nil {
+		return false, s.saveUserMFA(ctx, userMFA)
+	}
+	if !errors.Is(err, ErrInvalidCode) {
+		return false, err
+	}
+
+	hashes, err := recoveryCodeHashes(userMFA)
+	if err != nil {
+		return false, err
+	}
+	hash := hashRecoveryCode(code)
+	for i, h := range hashes {
+		if h != hash {
+			continue
+		}
+
+		remaining := append(hashes[:i:i], hashes[i+1:]...)
+		encoded, err := json.Marshal(remaining)
+		if err != nil {
+			return false, err
+		}
+		userMFA.RecoveryCodes = string(encoded)
+		s.log.Info("Recovery code used", "userId", userMFA.UserID, "remaining", len(remaining))
+		return true, s.saveUserMFA(ctx, userMFA)
+	}
+
+	return false, ErrInvalidCode
+}
+
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijkmnpqrstuvwxyz23456789").WithPadding(base32.NoPadding)
+
+// setRecoveryCodes generates new recovery codes, stores their hashes and returns the codes.
+func setRecoveryCodes(userMFA *UserMFA) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		encoded := recoveryCodeEncoding.EncodeToString(b)
+		codes[i] = encoded[:4] + "-" + encoded[4:]
+		hashes[i] = hashRecoveryCode(codes[i])
+	}
+
+	encoded, err := json.Marshal(hashes)
+	if err != nil {
+		return nil, err
+	}
+	userMFA.RecoveryCodes = string(encoded)
+
+	return codes, nil
+}
+
+func recoveryCodeHashes(userMFA *UserMFA) ([]string, error) {
+	if userMFA.RecoveryCodes == "" {
+		return nil, nil
+	}
+
+	var hashes []string
+	if err := json.Unmarshal([]byte(userMFA.RecoveryCodes), &hashes); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
+
+// hashRecoveryCode ignores case, spaces and dashes, so that codes can be typed in loosely.
+func hashRecoveryCode(code string) string {
+	normalized := strings.NewReplacer("-", "", " ", "").Replace(strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
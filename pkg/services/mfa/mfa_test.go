@@ -0,0 +1,297 @@
+package mfa
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/login"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestEnrollment(t *testing.T) {
+	mfaScenario(t, "Enrolls after the code is confirmed", func(t *testing.T, sc *scenarioContext) {
+		enrollment, err := sc.service.Enroll(sc.ctx, sc.user.Id, sc.user.Login)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(enrollment.URL, "otpauth://totp/Grafana:mfa-user?"))
+		assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+		status, err := sc.service.GetStatus(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, &Status{PendingConfirmation: true}, status)
+
+		_, err = sc.service.Confirm(sc.ctx, sc.user.Id, "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+
+		codes, err := sc.service.Confirm(sc.ctx, sc.user.Id, code(t, enrollment.Secret, 0))
+		require.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+
+		status, err = sc.service.GetStatus(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, &Status{Enabled: true, RecoveryCodesLeft: recoveryCodeCount}, status)
+
+		_, err = sc.service.Enroll(sc.ctx, sc.user.Id, sc.user.Login)
+		assert.ErrorIs(t, err, ErrAlreadyEnrolled)
+	})
+
+	mfaScenario(t, "Stores the secret encrypted", func(t *testing.T, sc *scenarioContext) {
+		enrollment, err := sc.service.Enroll(sc.ctx, sc.user.Id, sc.user.Login)
+		require.NoError(t, err)
+
+		userMFA, err := sc.service.getUserMFA(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.NotContains(t, userMFA.Secret, enrollment.Secret)
+	})
+
+	mfaScenario(t, "Disables with a valid code unless required", func(t *testing.T, sc *scenarioContext) {
+		secret, _ := sc.enroll(t)
+
+		err := sc.service.Disable(sc.ctx, sc.user.Id, "000000")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+
+		sc.service.Cfg.MFAEnforced = true
+		err = sc.service.Disable(sc.ctx, sc.user.Id, code(t, secret, 1))
+		assert.ErrorIs(t, err, ErrRequired)
+
+		sc.service.Cfg.MFAEnforced = false
+		err = sc.service.Disable(sc.ctx, sc.user.Id, code(t, secret, 1))
+		require.NoError(t, err)
+
+		status, err := sc.service.GetStatus(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.False(t, status.Enabled)
+	})
+
+	mfaScenario(t, "Regenerates recovery codes", func(t *testing.T, sc *scenarioContext) {
+		secret, oldCodes := sc.enroll(t)
+
+		codes, err := sc.service.RegenerateRecoveryCodes(sc.ctx, sc.user.Id, code(t, secret, 1))
+		require.NoError(t, err)
+		assert.Len(t, codes, recoveryCodeCount)
+
+		challenge := sc.startLogin(t)
+		_, err = sc.service.VerifyLogin(sc.ctx, challenge.Token, oldCodes[0], "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+		result, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, codes[0], "127.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.UsedRecoveryCode)
+	})
+}
+
+func TestLogin(t *testing.T) {
+	mfaScenario(t, "Does not challenge users without a second factor", func(t *testing.T, sc *scenarioContext) {
+		challenge, err := sc.service.StartLogin(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	mfaScenario(t, "Does not challenge users when disabled", func(t *testing.T, sc *scenarioContext) {
+		sc.enroll(t)
+		sc.service.Cfg.MFAEnabled = false
+
+		challenge, err := sc.service.StartLogin(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.Nil(t, challenge)
+	})
+
+	mfaScenario(t, "Verifies the one-time password", func(t *testing.T, sc *scenarioContext) {
+		secret, _ := sc.enroll(t)
+
+		challenge := sc.startLogin(t)
+		assert.False(t, challenge.EnrollmentRequired)
+
+		_, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, "000000", "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+
+		result, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, code(t, secret, 1), "127.0.0.1")
+		require.NoError(t, err)
+		assert.Equal(t, sc.user.Id, result.UserID)
+		assert.False(t, result.UsedRecoveryCode)
+
+		_, err = sc.service.VerifyLogin(sc.ctx, challenge.Token, code(t, secret, 1), "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidChallenge, "challenges can only be completed once")
+	})
+
+	mfaScenario(t, "Rejects replayed codes", func(t *testing.T, sc *scenarioContext) {
+		secret, _ := sc.enroll(t)
+
+		_, err := sc.service.VerifyLogin(sc.ctx, sc.startLogin(t).Token, code(t, secret, 1), "127.0.0.1")
+		require.NoError(t, err)
+
+		_, err = sc.service.VerifyLogin(sc.ctx, sc.startLogin(t).Token, code(t, secret, 1), "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+	})
+
+	mfaScenario(t, "Accepts each recovery code once", func(t *testing.T, sc *scenarioContext) {
+		_, codes := sc.enroll(t)
+
+		result, err := sc.service.VerifyLogin(sc.ctx, sc.startLogin(t).Token, strings.ToUpper(codes[3]), "127.0.0.1")
+		require.NoError(t, err)
+		assert.True(t, result.UsedRecoveryCode)
+
+		_, err = sc.service.VerifyLogin(sc.ctx, sc.startLogin(t).Token, codes[3], "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidCode)
+
+		status, err := sc.service.GetStatus(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.Equal(t, recoveryCodeCount-1, status.RecoveryCodesLeft)
+	})
+
+	mfaScenario(t, "Invalidates the challenge after too many attempts", func(t *testing.T, sc *scenarioContext) {
+		secret, _ := sc.enroll(t)
+		sc.service.Cfg.DisableBruteForceLoginProtection = true
+
+		challenge := sc.startLogin(t)
+		for i := 0; i < maxChallengeAttempts; i++ {
+			_, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, "000000", "127.0.0.1")
+			assert.ErrorIs(t, err, ErrInvalidCode)
+		}
+
+		_, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, code(t, secret, 1), "127.0.0.1")
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+
+	mfaScenario(t, "Counts failed codes as failed login attempts", func(t *testing.T, sc *scenarioContext) {
+		sc.enroll(t)
+
+		for i := 0; i < 3; i++ {
+			_, err := sc.service.VerifyLogin(sc.ctx, sc.startLogin(t).Token, "000000", "127.0.0.1")
+			assert.ErrorIs(t, err, ErrInvalidCode)
+			_, err = sc.service.VerifyLogin(sc.ctx, sc.startLogin(t).Token, "000000", "127.0.0.1")
+			if i < 2 {
+				assert.ErrorIs(t, err, ErrInvalidCode)
+			} else {
+				assert.ErrorIs(t, err, login.ErrTooManyLoginAttempts)
+			}
+		}
+	})
+
+	mfaScenario(t, "Requires enrollment when enforced by an organization", func(t *testing.T, sc *scenarioContext) {
+		require.NoError(t, sc.service.setOrgPolicy(sc.ctx, sc.orgID, true))
+
+		challenge := sc.startLogin(t)
+		assert.True(t, challenge.EnrollmentRequired)
+
+		enrollment, err := sc.service.EnrollLogin(sc.ctx, challenge.Token)
+		require.NoError(t, err)
+
+		result, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, code(t, enrollment.Secret, 0), "127.0.0.1")
+		require.NoError(t, err)
+		assert.Len(t, result.RecoveryCodes, recoveryCodeCount)
+
+		status, err := sc.service.GetStatus(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.True(t, status.Enabled)
+		assert.True(t, status.Required)
+
+		require.NoError(t, sc.service.setOrgPolicy(sc.ctx, sc.orgID, false))
+		required, err := sc.service.IsRequired(sc.ctx, sc.user.Id)
+		require.NoError(t, err)
+		assert.False(t, required)
+	})
+
+	mfaScenario(t, "Requires enrollment when enforced globally", func(t *testing.T, sc *scenarioContext) {
+		sc.service.Cfg.MFAEnforced = true
+
+		challenge := sc.startLogin(t)
+		assert.True(t, challenge.EnrollmentRequired)
+
+		_, err := sc.service.VerifyLogin(sc.ctx, challenge.Token, "000000", "127.0.0.1")
+		assert.ErrorIs(t, err, ErrNotEnrolled)
+	})
+
+	mfaScenario(t, "Requires a new enrollment after a reset", func(t *testing.T, sc *scenarioContext) {
+		sc.enroll(t)
+		sc.service.Cfg.MFAEnforced = true
+
+		require.NoError(t, sc.service.Reset(sc.ctx, sc.user.Id))
+
+		challenge := sc.startLogin(t)
+		assert.True(t, challenge.EnrollmentRequired)
+	})
+
+	mfaScenario(t, "Answers whether a password is enough", func(t *testing.T, sc *scenarioContext) {
+		query := models.IsMFARequiredQuery{UserId: sc.user.Id}
+		require.NoError(t, sc.service.isMFARequiredQuery(sc.ctx, &query))
+		assert.False(t, query.Result)
+
+		sc.enroll(t)
+		require.NoError(t, sc.service.isMFARequiredQuery(sc.ctx, &query))
+		assert.True(t, query.Result)
+	})
+}
+
+type scenarioContext struct {
+	ctx     context.Context
+	service *MFAService
+	user    *models.User
+	orgID   int64
+}
+
+func mfaScenario(t *testing.T, desc string, fn func(t *testing.T, sc *scenarioContext)) {
+	t.Helper()
+
+	t.Run(desc, func(t *testing.T) {
+		remoteCache := remotecache.NewFakeStore(t)
+		sqlStore := sqlstore.InitTestDB(t)
+
+		cmd := models.CreateUserCommand{Login: "mfa-user", Email: "mfa-user@example.com"}
+		require.NoError(t, bus.Dispatch(&cmd))
+
+		cfg := setting.NewCfg()
+		cfg.MFAEnabled = true
+		cfg.MFAIssuer = "Grafana"
+
+		fn(t, &scenarioContext{
+			ctx: context.Background(),
+			service: &MFAService{
+				Cfg:                cfg,
+				SQLStore:           sqlStore,
+				RemoteCacheService: remoteCache,
+				log:                log.New("mfa.test"),
+			},
+			user:  &cmd.Result,
+			orgID: cmd.Result.OrgId,
+		})
+	})
+}
+
+// enroll sets up a second factor for the user and returns the secret and the recovery codes.
+func (sc *scenarioContext) enroll(t *testing.T) (string, []string) {
+	t.Helper()
+
+	enrollment, err := sc.service.Enroll(sc.ctx, sc.user.Id, sc.user.Login)
+	require.NoError(t, err)
+	codes, err := sc.service.Confirm(sc.ctx, sc.user.Id, code(t, enrollment.Secret, 0))
+	require.NoError(t, err)
+
+	return enrollment.Secret, codes
+}
+
+func (sc *scenarioContext) startLogin(t *testing.T) *LoginChallenge {
+	t.Helper()
+
+	challenge, err := sc.service.StartLogin(sc.ctx, sc.user.Id)
+	require.NoError(t, err)
+	require.NotNil(t, challenge)
+	return challenge
+}
+
+// code returns the one-time password of the current time step plus offset.
+func code(t *testing.T, secret string, offset int64) string {
+	t.Helper()
+
+	key, err := secretEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	return hotp(key, uint64(timeStep(time.Now())+offset))
+}
@@ -0,0 +1,106 @@
+package mfa
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrNotEnrolled is returned when a user has not set up a second factor.
+	ErrNotEnrolled = errors.New("multi-factor authentication is not set up for the user")
+	// ErrAlreadyEnrolled is returned when a user that already has a confirmed second factor enrolls again.
+	ErrAlreadyEnrolled = errors.New("multi-factor authentication is already set up for the user")
+	// ErrInvalidCode is returned when a one-time password or recovery code does not match.
+	ErrInvalidCode = errors.New("invalid verification code")
+	// ErrInvalidChallenge is returned when a login challenge is unknown, expired or used up.
+	ErrInvalidChallenge = errors.New("invalid or expired multi-factor authentication request")
+	// ErrRequired is returned when a user tries to disable a second factor that is required for them.
+	ErrRequired = errors.New("multi-factor authentication is required for the user")
+)
+
+// UserMFA is the second factor of a user.
+type UserMFA struct {
+	ID     int64 `xorm:"pk autoincr 'id'"`
+	UserID int64 `xorm:"user_id"`
+	// Secret is the encrypted TOTP secret, base64 encoded.
+	Secret string
+	// Confirmed is set once the user proved that the authenticator app works by entering a code.
+	Confirmed bool
+	// RecoveryCodes are the SHA-256 hashes of the unused recovery codes, as a JSON array.
+	RecoveryCodes string
+	// LastUsedStep is the time step of the last accepted code, older codes are rejected to prevent replays.
+	LastUsedStep int64
+
+	Created time.Time
+	Updated time.Time
+}
+
+// TableName returns the table name, which the default name mapper would spell user_m_f_a.
+func (UserMFA) TableName() string {
+	return "user_mfa"
+}
+
+// OrgMFAPolicy is the multi-factor authentication policy of an organization.
+type OrgMFAPolicy struct {
+	ID       int64 `xorm:"pk autoincr 'id'"`
+	OrgID    int64 `xorm:"org_id"`
+	Enforced bool
+
+	Updated time.Time
+}
+
+// TableName returns the table name, which the default name mapper would spell org_m_f_a_policy.
+func (OrgMFAPolicy) TableName() string {
+	return "org_mfa_policy"
+}
+
+// Status is the multi-factor authentication status of a user.
+type Status struct {
+	Enabled             bool `json:"enabled"`
+	Required            bool `json:"required"`
+	PendingConfirmation bool `json:"pendingConfirmation"`
+	RecoveryCodesLeft   int  `json:"recoveryCodesLeft"`
+}
+
+// Enrollment contains what a user needs to set up an authenticator app.
+type Enrollment struct {
+	Secret string `json:"secret"`
+	URL    string `json:"url"`
+	// QRCode is a PNG image of the URL as a data URI.
+	QRCode string `json:"qrCode"`
+}
+
+// Challenge is the pending second step of a login, stored in the remote cache.
+type Challenge struct {
+	UserID   int64
+	Attempts int
+}
+
+// LoginChallenge is returned to the client when a password login needs a second factor.
+type LoginChallenge struct {
+	Token string
+	// EnrollmentRequired is true if the user has to set up a second factor before signing in.
+	EnrollmentRequired bool
+}
+
+// VerifyLoginResult is the outcome of the second step of a login.
+type VerifyLoginResult struct {
+	UserID int64
+	// RecoveryCodes are set when the login completed an enrollment.
+	RecoveryCodes []string
+	// UsedRecoveryCode is true if the user signed in with a recovery code instead of a one-time password.
+	UsedRecoveryCode bool
+}
+
+type verifyCodeCommand struct {
+	Code string `json:"code" binding:"Required"`
+}
+
+type orgPolicyCommand struct {
+	Enforced bool `json:"enforced"`
+}
+
+type orgPolicyDTO struct {
+	Enforced       bool `json:"enforced"`
+	GlobalEnforced bool `json:"globalEnforced"`
+}
@@ -0,0 +1,78 @@
+package mfa
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret is the SHA-1 seed of the test vectors in appendix B of RFC 6238, "12345678901234567890".
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestValidateCode(t *testing.T) {
+	// The RFC vectors have 8 digits, codes with 6 digits are the last 6 of them.
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, code := range vectors {
+		step, ok := validateCode(rfc6238Secret, code, time.Unix(unix, 0), 0)
+		assert.True(t, ok, "time %d", unix)
+		assert.Equal(t, timeStep(time.Unix(unix, 0)), step, "time %d", unix)
+	}
+
+	t.Run("Accepts codes of adjacent time steps", func(t *testing.T) {
+		_, ok := validateCode(rfc6238Secret, "287082", time.Unix(59+30, 0), 0)
+		assert.True(t, ok)
+
+		_, ok = validateCode(rfc6238Secret, "287082", time.Unix(59+60, 0), 0)
+		assert.False(t, ok)
+	})
+
+	t.Run("Rejects codes of used time steps", func(t *testing.T) {
+		now := time.Unix(1234567890, 0)
+		_, ok := validateCode(rfc6238Secret, "005924", now, timeStep(now))
+		assert.False(t, ok)
+	})
+
+	t.Run("Ignores spaces", func(t *testing.T) {
+		_, ok := validateCode(rfc6238Secret, "005 924", time.Unix(1234567890, 0), 0)
+		assert.True(t, ok)
+	})
+
+	t.Run("Rejects malformed codes", func(t *testing.T) {
+		for _, code := range []string{"", "00592", "0059245", "abcdef"} {
+			_, ok := validateCode(rfc6238Secret, code, time.Unix(1234567890, 0), 0)
+			assert.False(t, ok, "code %q", code)
+		}
+	})
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := generateSecret()
+	require.NoError(t, err)
+
+	key, err := secretEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	assert.Len(t, key, secretLength)
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := keyURI("Grafana", "admin@example.com", rfc6238Secret)
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/Grafana:admin@example.com", u.Path)
+	assert.Equal(t, rfc6238Secret, u.Query().Get("secret"))
+	assert.Equal(t, "Grafana", u.Query().Get("issuer"))
+	assert.Equal(t, "6", u.Query().Get("digits"))
+	assert.Equal(t, "30", u.Query().Get("period"))
+}
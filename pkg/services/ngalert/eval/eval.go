@@ -132,7 +132,8 @@ func execute(ctx AlertExecCtx, c *models.Condition, now time.Time, dataService *
 		Cfg:         &setting.Cfg{ExpressionsEnabled: ctx.ExpressionsEnabled},
 		DataService: dataService,
 	}
-	pbRes, err := exprService.TransformData(ctx.Ctx, queryDataReq)
+	// alert rules are evaluated with the latest data
+	pbRes, err := exprService.TransformData(tsdb.ContextWithoutQueryCache(ctx.Ctx), queryDataReq)
 	if err != nil {
 		return &result, err
 	}
@@ -8,6 +8,7 @@ import (
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
 
@@ -22,17 +23,26 @@ type DashboardProvisioner interface {
 }
 
 // DashboardProvisionerFactory creates DashboardProvisioners based on input
-type DashboardProvisionerFactory func(string) (DashboardProvisioner, error)
+type DashboardProvisionerFactory func(configDirectory, dataPath string) (DashboardProvisioner, error)
+
+// dashboardReader syncs the dashboards of a single provisioner config to Grafana's database.
+type dashboardReader interface {
+	walkDisk() error
+	pollChanges(ctx context.Context)
+	resolvedPath() string
+	providerName() string
+	planChanges(p *plan.Plan, uids map[string]string) error
+}
 
 // Provisioner is responsible for syncing dashboard from disk to Grafana's database.
 type Provisioner struct {
 	log         log.Logger
-	fileReaders []*FileReader
+	fileReaders []dashboardReader
 	configs     []*config
 }
 
-// New returns a new DashboardProvisioner
-func New(configDirectory string) (*Provisioner, error) {
+// New returns a new DashboardProvisioner. Git repositories are checked out in the data path.
+func New(configDirectory, dataPath string) (*Provisioner, error) {
 	logger := log.New("provisioning.dashboard")
 	cfgReader := &configReader{path: configDirectory, log: logger}
 	configs, err := cfgReader.readConfig()
@@ -41,7 +51,7 @@ func New(configDirectory string) (*Provisioner, error) {
 		return nil, errutil.Wrap("Failed to read dashboards config", err)
 	}
 
-	fileReaders, err := getFileReaders(configs, logger)
+	fileReaders, err := getFileReaders(configs, logger, dataPath)
 	if err != nil {
 		return nil, errutil.Wrap("Failed to initialize file readers", err)
 	}
@@ -62,11 +72,11 @@ func (provider *Provisioner) Provision() error {
 		if err := reader.walkDisk(); err != nil {
 			if os.IsNotExist(err) {
 				// don't stop the provisioning service in case the folder is missing. The folder can appear after the startup
-				provider.log.Warn("Failed to provision config", "name", reader.Cfg.Name, "error", err)
+				provider.log.Warn("Failed to provision config", "name", reader.providerName(), "error", err)
 				return nil
 			}
 
-			return errutil.Wrapf(err, "Failed to provision config %v", reader.Cfg.Name)
+			return errutil.Wrapf(err, "Failed to provision config %v", reader.providerName())
 		}
 	}
 
@@ -78,7 +88,7 @@ func (provider *Provisioner) CleanUpOrphanedDashboards() {
 	currentReaders := make([]string, len(provider.fileReaders))
 
 	for index, reader := range provider.fileReaders {
-		currentReaders[index] = reader.Cfg.Name
+		currentReaders[index] = reader.providerName()
 	}
 
 	if err := bus.Dispatch(&models.DeleteOrphanedProvisionedDashboardsCommand{ReaderNames: currentReaders}); err != nil {
@@ -98,7 +108,7 @@ func (provider *Provisioner) PollChanges(ctx context.Context) {
 // relative path to provisioning file from it's external_id.
 func (provider *Provisioner) GetProvisionerResolvedPath(name string) string {
 	for _, reader := range provider.fileReaders {
-		if reader.Cfg.Name == name {
+		if reader.providerName() == name {
 			return reader.resolvedPath()
 		}
 	}
@@ -115,8 +125,8 @@ func (provider *Provisioner) GetAllowUIUpdatesFromConfig(name string) bool {
 	return false
 }
 
-func getFileReaders(configs []*config, logger log.Logger) ([]*FileReader, error) {
-	var readers []*FileReader
+func getFileReaders(configs []*config, logger log.Logger, dataPath string) ([]dashboardReader, error) {
+	var readers []dashboardReader
 
 	for _, config := range configs {
 		switch config.Type {
@@ -126,6 +136,12 @@ func getFileReaders(configs []*config, logger log.Logger) ([]*FileReader, error)
 				return nil, errutil.Wrapf(err, "Failed to create file reader for config %v", config.Name)
 			}
 			readers = append(readers, fileReader)
+		case "git":
+			gitReader, err := NewDashboardGitReader(config, logger.New("type", config.Type, "name", config.Name), dataPath)
+			if err != nil {
+				return nil, errutil.Wrapf(err, "Failed to create git reader for config %v", config.Name)
+			}
+			readers = append(readers, gitReader)
 		default:
 			return nil, fmt.Errorf("type %s is not supported", config.Type)
 		}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
 	"github.com/grafana/grafana/pkg/util"
 )
 
@@ -32,6 +34,13 @@ type FileReader struct {
 	log                          log.Logger
 	dashboardProvisioningService dashboards.DashboardProvisioningService
 	FoldersFromFilesStructure    bool
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
+	// planUIDs are the files of the planned dashboards by org and uid, across all providers.
+	planUIDs map[string]string
+	// planPath is the directory that is walked instead of the resolved path when planning a copy of the
+	// dashboard files. The dashboards are still planned by their path in the resolved path.
+	planPath string
 }
 
 // NewDashboardFileReader returns a new filereader based on `config`
@@ -81,12 +90,16 @@ func (fr *FileReader) pollChanges(ctx context.Context) {
 func (fr *FileReader) walkDisk() error {
 	fr.log.Debug("Start walking disk", "path", fr.Path)
 	resolvedPath := fr.resolvedPath()
-	if _, err := os.Stat(resolvedPath); err != nil {
+	provisionedDashboardRefs, err := getProvisionedDashboardsByPath(fr.dashboardProvisioningService, fr.Cfg.Name)
+	if err != nil {
 		return err
 	}
 
-	provisionedDashboardRefs, err := getProvisionedDashboardsByPath(fr.dashboardProvisioningService, fr.Cfg.Name)
-	if err != nil {
+	if fr.planPath != "" {
+		provisionedDashboardRefs = rebaseProvisionedPaths(provisionedDashboardRefs, resolvedPath, fr.planPath)
+		resolvedPath = fr.planPath
+	}
+	if _, err := os.Stat(resolvedPath); err != nil {
 		return err
 	}
 
@@ -96,7 +109,9 @@ func (fr *FileReader) walkDisk() error {
 		return err
 	}
 
-	fr.handleMissingDashboardFiles(provisionedDashboardRefs, filesFoundOnDisk)
+	if err := fr.handleMissingDashboardFiles(provisionedDashboardRefs, filesFoundOnDisk); err != nil {
+		return err
+	}
 
 	sanityChecker := newProvisioningSanityChecker(fr.Cfg.Name)
 
@@ -109,7 +124,11 @@ func (fr *FileReader) walkDisk() error {
 		return err
 	}
 
-	sanityChecker.logWarnings(fr.log)
+	if fr.plan != nil {
+		sanityChecker.addPlanWarnings(fr.plan)
+	} else {
+		sanityChecker.logWarnings(fr.log)
+	}
 
 	return nil
 }
@@ -117,16 +136,16 @@ func (fr *FileReader) walkDisk() error {
 // storeDashboardsInFolder saves dashboards from the filesystem on disk to the folder from config
 func (fr *FileReader) storeDashboardsInFolder(filesFoundOnDisk map[string]os.FileInfo,
 	dashboardRefs map[string]*models.DashboardProvisioning, sanityChecker *provisioningSanityChecker) error {
-	folderID, err := getOrCreateFolderID(fr.Cfg, fr.dashboardProvisioningService, fr.Cfg.Folder)
+	folderID, err := getOrCreateFolderID(fr.Cfg, fr.dashboardProvisioningService, fr.Cfg.Folder, fr.plan)
 	if err != nil && !errors.Is(err, ErrFolderNameMissing) {
 		return err
 	}
 
 	// save dashboards based on json files
-	for path, fileInfo := range filesFoundOnDisk {
-		provisioningMetadata, err := fr.saveDashboard(path, folderID, fileInfo, dashboardRefs)
+	for _, path := range sortedPaths(filesFoundOnDisk) {
+		provisioningMetadata, err := fr.saveDashboard(path, folderID, filesFoundOnDisk[path], dashboardRefs)
 		if err != nil {
-			fr.log.Error("failed to save dashboard", "error", err)
+			fr.logError("failed to save dashboard", err)
 			continue
 		}
 
@@ -139,7 +158,7 @@ func (fr *FileReader) storeDashboardsInFolder(filesFoundOnDisk map[string]os.Fil
 // in Grafana as they are in on the filesystem.
 func (fr *FileReader) storeDashboardsInFoldersFromFileStructure(filesFoundOnDisk map[string]os.FileInfo,
 	dashboardRefs map[string]*models.DashboardProvisioning, resolvedPath string, sanityChecker *provisioningSanityChecker) error {
-	for path, fileInfo := range filesFoundOnDisk {
+	for _, path := range sortedPaths(filesFoundOnDisk) {
 		folderName := ""
 
 		dashboardsFolder := filepath.Dir(path)
@@ -147,15 +166,20 @@ func (fr *FileReader) storeDashboardsInFoldersFromFileStructure(filesFoundOnDisk
 			folderName = filepath.Base(dashboardsFolder)
 		}
 
-		folderID, err := getOrCreateFolderID(fr.Cfg, fr.dashboardProvisioningService, folderName)
+		folderID, err := getOrCreateFolderID(fr.Cfg, fr.dashboardProvisioningService, folderName, fr.plan)
 		if err != nil && !errors.Is(err, ErrFolderNameMissing) {
-			return fmt.Errorf("can't provision folder %q from file system structure: %w", folderName, err)
+			err = fmt.Errorf("can't provision folder %q from file system structure: %w", folderName, err)
+			if fr.plan == nil {
+				return err
+			}
+			fr.plan.AddError(plan.KindDashboard, err)
+			continue
 		}
 
-		provisioningMetadata, err := fr.saveDashboard(path, folderID, fileInfo, dashboardRefs)
+		provisioningMetadata, err := fr.saveDashboard(path, folderID, filesFoundOnDisk[path], dashboardRefs)
 		sanityChecker.track(provisioningMetadata)
 		if err != nil {
-			fr.log.Error("failed to save dashboard", "error", err)
+			fr.logError("failed to save dashboard", err)
 		}
 	}
 	return nil
@@ -163,7 +187,7 @@ func (fr *FileReader) storeDashboardsInFoldersFromFileStructure(filesFoundOnDisk
 
 // handleMissingDashboardFiles will unprovision or delete dashboards which are missing on disk.
 func (fr *FileReader) handleMissingDashboardFiles(provisionedDashboardRefs map[string]*models.DashboardProvisioning,
-	filesFoundOnDisk map[string]os.FileInfo) {
+	filesFoundOnDisk map[string]os.FileInfo) error {
 	// find dashboards to delete since json file is missing
 	var dashboardsToDelete []int64
 	for path, provisioningData := range provisionedDashboardRefs {
@@ -173,6 +197,14 @@ func (fr *FileReader) handleMissingDashboardFiles(provisionedDashboardRefs map[s
 		}
 	}
 
+	if fr.plan != nil {
+		// unprovisioned dashboards are kept, so only deletions are planned
+		if fr.Cfg.DisableDeletion {
+			return nil
+		}
+		return fr.planDeletedDashboards(dashboardsToDelete)
+	}
+
 	if fr.Cfg.DisableDeletion {
 		// If deletion is disabled for the provisioner we just remove provisioning metadata about the dashboard
 		// so afterwards the dashboard is considered unprovisioned.
@@ -193,6 +225,8 @@ func (fr *FileReader) handleMissingDashboardFiles(provisionedDashboardRefs map[s
 			}
 		}
 	}
+
+	return nil
 }
 
 // saveDashboard saves or updates the dashboard provisioning file at path.
@@ -209,7 +243,7 @@ func (fr *FileReader) saveDashboard(path string, folderID int64, fileInfo os.Fil
 
 	jsonFile, err := fr.readDashboardFromFile(path, resolvedFileInfo.ModTime(), folderID)
 	if err != nil {
-		fr.log.Error("failed to load dashboard from ", "file", path, "error", err)
+		fr.logError("failed to load dashboard from "+path, err)
 		return provisioningMetadata, nil
 	}
 
@@ -235,6 +269,10 @@ func (fr *FileReader) saveDashboard(path string, folderID int64, fileInfo os.Fil
 		dash.Dashboard.SetId(provisionedData.DashboardId)
 	}
 
+	if fr.plan != nil {
+		return provisioningMetadata, fr.planSavedDashboard(path, dash.Dashboard, alreadyProvisioned)
+	}
+
 	fr.log.Debug("saving new dashboard", "provisioner", fr.Cfg.Name, "file", path, "folderId", dash.Dashboard.FolderId)
 	dp := &models.DashboardProvisioning{
 		ExternalId: path,
@@ -262,7 +300,9 @@ func getProvisionedDashboardsByPath(service dashboards.DashboardProvisioningServ
 	return byPath, nil
 }
 
-func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningService, folderName string) (int64, error) {
+// getOrCreateFolderID returns the id of the folder with the name, and creates the folder if it doesn't exist. If p is
+// set, the folder is added to the plan instead of being created, and 0 is returned.
+func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningService, folderName string, p *plan.Plan) (int64, error) {
 	if folderName == "" {
 		return 0, ErrFolderNameMissing
 	}
@@ -276,6 +316,10 @@ func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningSe
 
 	// dashboard folder not found. create one.
 	if errors.Is(err, models.ErrDashboardNotFound) {
+		if p != nil {
+			return 0, planFolder(cfg, p, folderName)
+		}
+
 		dash := &dashboards.SaveDashboardDTO{}
 		dash.Dashboard = models.NewDashboardFolder(folderName)
 		dash.Dashboard.IsFolder = true
@@ -292,12 +336,30 @@ func getOrCreateFolderID(cfg *config, service dashboards.DashboardProvisioningSe
 	}
 
 	if !cmd.Result.IsFolder {
-		return 0, fmt.Errorf("got invalid response. expected folder, found dashboard")
+		return 0, fmt.Errorf("folder %q of provider %q is a dashboard", folderName, cfg.Name)
 	}
 
 	return cmd.Result.Id, nil
 }
 
+func sortedPaths(filesFoundOnDisk map[string]os.FileInfo) []string {
+	paths := make([]string, 0, len(filesFoundOnDisk))
+	for path := range filesFoundOnDisk {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// logError logs an error of a dashboard file, or adds it to the plan.
+func (fr *FileReader) logError(msg string, err error) {
+	if fr.plan != nil {
+		fr.plan.AddError(plan.KindDashboard, fmt.Errorf("%s: %w", msg, err))
+		return
+	}
+	fr.log.Error(msg, "error", err)
+}
+
 func resolveSymlink(fileinfo os.FileInfo, path string) (os.FileInfo, error) {
 	checkFilepath, err := filepath.EvalSymlinks(path)
 	if path != checkFilepath {
@@ -411,6 +473,10 @@ func (fr *FileReader) resolvedPath() string {
 	return path
 }
 
+func (fr *FileReader) providerName() string {
+	return fr.Cfg.Name
+}
+
 type provisioningMetadata struct {
 	uid      string
 	identity dashboardIdentity
@@ -461,3 +527,13 @@ func (checker provisioningSanityChecker) logWarnings(log log.Logger) {
 		}
 	}
 }
+
+// addPlanWarnings adds the titles that are used more than once to the plan. Duplicated uids are already reported
+// as errors of the plan.
+func (checker provisioningSanityChecker) addPlanWarnings(p *plan.Plan) {
+	for identity, times := range checker.titleUsage {
+		if times > 1 {
+			p.AddWarning(plan.KindDashboard, "the title %q is used more than once by provider %q", identity.title, checker.provisioningProvider)
+		}
+	}
+}
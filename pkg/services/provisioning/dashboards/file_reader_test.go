@@ -264,7 +264,7 @@ func TestDashboardFileReader(t *testing.T) {
 				},
 			}
 
-			_, err := getOrCreateFolderID(cfg, fakeService, cfg.Folder)
+			_, err := getOrCreateFolderID(cfg, fakeService, cfg.Folder, nil)
 			So(err, ShouldEqual, ErrFolderNameMissing)
 		})
 
@@ -279,7 +279,7 @@ func TestDashboardFileReader(t *testing.T) {
 				},
 			}
 
-			folderID, err := getOrCreateFolderID(cfg, fakeService, cfg.Folder)
+			folderID, err := getOrCreateFolderID(cfg, fakeService, cfg.Folder, nil)
 			So(err, ShouldBeNil)
 			inserted := false
 			for _, d := range fakeService.inserted {
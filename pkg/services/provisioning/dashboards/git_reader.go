@@ -0,0 +1,176 @@
+package dashboards
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// gitCommandTimeout limits how long a single git command, like fetching the repository, may take.
+var gitCommandTimeout = 5 * time.Minute
+
+// GitReader is responsible for syncing dashboards from a git repository to the Grafana database.
+// It fetches the configured branch or tag into a local checkout and provisions the dashboards
+// of the checkout like a FileReader, so that dashboards removed from the repository are deleted
+// or unprovisioned in the same way.
+type GitReader struct {
+	*FileReader
+	URL          string
+	Ref          string
+	CheckoutPath string
+}
+
+// NewDashboardGitReader returns a new git reader based on `config`. Unless the config sets the
+// checkout path, the repository is checked out in the data path, where it survives restarts.
+func NewDashboardGitReader(cfg *config, log log.Logger, dataPath string) (*GitReader, error) {
+	repoURL, ok := cfg.Options["url"].(string)
+	if !ok || repoURL == "" {
+		return nil, fmt.Errorf("failed to load dashboards, url param is not a string")
+	}
+
+	ref, _ := cfg.Options["ref"].(string)
+	// git would parse values that start with a dash as options
+	if strings.HasPrefix(repoURL, "-") || strings.HasPrefix(ref, "-") {
+		return nil, fmt.Errorf("failed to load dashboards, url and ref params must not start with '-'")
+	}
+
+	path, _ := cfg.Options["path"].(string)
+	if cleaned := filepath.Clean(path); filepath.IsAbs(cleaned) || cleaned == ".." || strings.HasPrefix(cleaned, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("failed to load dashboards, path param must be relative to the repository root")
+	}
+
+	checkoutPath, _ := cfg.Options["checkoutPath"].(string)
+	if checkoutPath == "" {
+		checkoutPath = filepath.Join(dataPath, "provisioning", "git", fmt.Sprintf("%d-%s", cfg.OrgID, models.SlugifyTitle(cfg.Name)))
+	}
+
+	foldersFromFilesStructure, _ := cfg.Options["foldersFromFilesStructure"].(bool)
+	if foldersFromFilesStructure && cfg.Folder != "" && cfg.FolderUID != "" {
+		return nil, fmt.Errorf("'folder' and 'folderUID' should be empty using 'foldersFromFilesStructure' option")
+	}
+
+	return &GitReader{
+		FileReader: &FileReader{
+			Cfg:                          cfg,
+			Path:                         filepath.Join(checkoutPath, path),
+			log:                          log,
+			dashboardProvisioningService: dashboards.NewProvisioningService(),
+			FoldersFromFilesStructure:    foldersFromFilesStructure,
+		},
+		URL:          repoURL,
+		Ref:          ref,
+		CheckoutPath: checkoutPath,
+	}, nil
+}
+
+// pollChanges periodically runs walkDisk based on interval specified in the config.
+func (gr *GitReader) pollChanges(ctx context.Context) {
+	ticker := time.NewTicker(time.Duration(int64(time.Second) * gr.Cfg.UpdateIntervalSeconds))
+	for {
+		select {
+		case <-ticker.C:
+			if err := gr.walkDisk(); err != nil {
+				gr.log.Error("failed to search for dashboards", "error", err)
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// walkDisk updates the checkout to the latest commit of the configured ref, and applies any change
+// of the dashboard definition files to the database. If the repository can't be fetched, the
+// database is left as it is and the next poll tries again.
+func (gr *GitReader) walkDisk() error {
+	if err := gr.sync(context.Background()); err != nil {
+		if gr.plan != nil {
+			gr.plan.AddError(plan.KindDashboard, fmt.Errorf("failed to sync git repository %s of provider %q: %w",
+				redactURL(gr.URL), gr.Cfg.Name, err))
+			return nil
+		}
+		gr.log.Warn("Failed to sync git repository", "url", redactURL(gr.URL), "ref", gr.Ref, "error", err)
+		return nil
+	}
+
+	return gr.FileReader.walkDisk()
+}
+
+// sync fetches the configured ref and checks it out, removing files that are not part of it.
+func (gr *GitReader) sync(ctx context.Context) error {
+	if _, err := os.Stat(filepath.Join(gr.CheckoutPath, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(gr.CheckoutPath, 0750); err != nil {
+			return err
+		}
+		if _, err := gr.git(ctx, "init", "--quiet"); err != nil {
+			return err
+		}
+	}
+
+	ref := gr.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	if _, err := gr.git(ctx, "fetch", "--quiet", "--force", "--depth=1", "--no-tags", "--", gr.URL, ref); err != nil {
+		return err
+	}
+
+	before, _ := gr.git(ctx, "rev-parse", "--quiet", "--verify", "HEAD")
+	if _, err := gr.git(ctx, "checkout", "--quiet", "--force", "--detach", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	if _, err := gr.git(ctx, "clean", "--quiet", "-ffdx"); err != nil {
+		return err
+	}
+
+	after, err := gr.git(ctx, "rev-parse", "HEAD")
+	if err != nil {
+		return err
+	}
+	if before != after {
+		gr.log.Info("Checked out git repository", "url", redactURL(gr.URL), "ref", ref, "commit", after)
+	}
+
+	return nil
+}
+
+// git runs a git command in the checkout and returns its trimmed output.
+func (gr *GitReader) git(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, gitCommandTimeout)
+	defer cancel()
+
+	// nolint:gosec
+	// The arguments come from the provisioning configuration file.
+	cmd := exec.CommandContext(ctx, "git", append([]string{"-C", gr.CheckoutPath}, args...)...)
+	// never prompt for credentials, they have to be part of the url or the git configuration
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// redactURL removes the password from repository urls before they are logged.
+func redactURL(repoURL string) string {
+	u, err := url.Parse(repoURL)
+	if err != nil {
+		return repoURL
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,252 @@
+package dashboards
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+func TestGitReader(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+
+	origNewDashboardProvisioningService := dashboards.NewProvisioningService
+	t.Cleanup(func() {
+		dashboards.NewProvisioningService = origNewDashboardProvisioningService
+	})
+
+	bus.ClearBusHandlers()
+	bus.AddHandler("test", mockGetDashboardQuery)
+
+	setup := func(t *testing.T, options map[string]interface{}) (*GitReader, *gitTestRepository) {
+		t.Helper()
+		fakeService = mockDashboardProvisioningService()
+
+		repo := newGitTestRepository(t)
+		options["url"] = "file://" + repo.bare
+		options["checkoutPath"] = filepath.Join(t.TempDir(), "checkout")
+
+		reader, err := NewDashboardGitReader(&config{Name: "git", Type: "git", OrgID: 1, Options: options}, log.New("test-logger"), "")
+		require.NoError(t, err)
+		return reader, repo
+	}
+
+	t.Run("Provisions the dashboards of the default branch", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{})
+		repo.write(t, "one.json", `{"title": "One"}`)
+		repo.write(t, "team/two.json", `{"title": "Two"}`)
+		repo.commit(t, "main")
+
+		require.NoError(t, reader.walkDisk())
+		assert.Equal(t, []string{"One", "Two"}, provisionedTitles())
+	})
+
+	t.Run("Maps directories to folders", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{"foldersFromFilesStructure": true, "path": "dashboards"})
+		repo.write(t, "dashboards/one.json", `{"title": "One"}`)
+		repo.write(t, "dashboards/team/two.json", `{"title": "Two"}`)
+		repo.write(t, "other/three.json", `{"title": "Three"}`)
+		repo.commit(t, "main")
+
+		require.NoError(t, reader.walkDisk())
+
+		var folders []string
+		for _, i := range fakeService.inserted {
+			if i.Dashboard.IsFolder {
+				folders = append(folders, i.Dashboard.Title)
+			}
+		}
+		assert.Equal(t, []string{"team"}, folders)
+		assert.Equal(t, []string{"One", "Two"}, provisionedTitles())
+	})
+
+	t.Run("Checks out a tag", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{"ref": "v1"})
+		reader.URL = repo.bare
+		repo.write(t, "one.json", `{"title": "One"}`)
+		repo.commit(t, "main")
+		repo.run(t, "tag", "v1")
+		repo.run(t, "push", "--quiet", "origin", "v1")
+		repo.write(t, "two.json", `{"title": "Two"}`)
+		repo.commit(t, "main")
+
+		require.NoError(t, reader.walkDisk())
+		assert.Equal(t, []string{"One"}, provisionedTitles())
+	})
+
+	t.Run("Deletes dashboards that were removed from the branch", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{"ref": "main"})
+		repo.write(t, "one.json", `{"title": "One"}`)
+		repo.write(t, "two.json", `{"title": "Two"}`)
+		repo.commit(t, "main")
+		require.NoError(t, reader.walkDisk())
+
+		repo.run(t, "rm", "--quiet", "two.json")
+		repo.commit(t, "main")
+		require.NoError(t, reader.walkDisk())
+
+		assert.Equal(t, []string{"One"}, provisionedTitles())
+		assert.Len(t, fakeService.provisioned["git"], 1)
+	})
+
+	t.Run("Unprovisions removed dashboards when deletion is disabled", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{})
+		reader.Cfg.DisableDeletion = true
+		repo.write(t, "one.json", `{"title": "One"}`)
+		repo.write(t, "two.json", `{"title": "Two"}`)
+		repo.commit(t, "main")
+		require.NoError(t, reader.walkDisk())
+
+		repo.run(t, "rm", "--quiet", "two.json")
+		repo.commit(t, "main")
+		require.NoError(t, reader.walkDisk())
+
+		assert.Equal(t, []string{"One", "Two"}, provisionedTitles())
+		assert.Len(t, fakeService.provisioned["git"], 1)
+	})
+
+	t.Run("Keeps dashboards when the repository can't be fetched", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{})
+		repo.write(t, "one.json", `{"title": "One"}`)
+		repo.commit(t, "main")
+		require.NoError(t, reader.walkDisk())
+
+		require.NoError(t, os.RemoveAll(repo.bare))
+		require.NoError(t, reader.walkDisk())
+
+		assert.Len(t, fakeService.provisioned["git"], 1)
+	})
+
+	t.Run("Plans the changes in a temporary checkout", func(t *testing.T) {
+		reader, repo := setup(t, map[string]interface{}{"path": "dashboards"})
+		repo.write(t, "dashboards/one.json", `{"title": "One"}`)
+		repo.commit(t, "main")
+		require.NoError(t, reader.walkDisk())
+		// the checkout of the plan is newer than the provisioned file
+		fakeService.provisioned["git"][0].Updated -= 10
+
+		repo.write(t, "dashboards/one.json", `{"title": "One v2"}`)
+		repo.write(t, "dashboards/two.json", `{"title": "Two"}`)
+		repo.commit(t, "main")
+		before, err := reader.git(context.Background(), "rev-parse", "HEAD")
+		require.NoError(t, err)
+
+		p := plan.New()
+		require.NoError(t, reader.planChanges(p, map[string]string{}))
+		assert.Empty(t, p.Errors)
+		assert.Equal(t, []*plan.Change{
+			{Action: plan.ActionUpdate, Kind: plan.KindDashboard, OrgID: 1, Name: "One v2", Source: filepath.Join(reader.resolvedPath(), "one.json")},
+			{Action: plan.ActionCreate, Kind: plan.KindDashboard, OrgID: 1, Name: "Two", Source: filepath.Join(reader.resolvedPath(), "two.json")},
+		}, p.Changes)
+
+		after, err := reader.git(context.Background(), "rev-parse", "HEAD")
+		require.NoError(t, err)
+		assert.Equal(t, before, after)
+		assert.NoFileExists(t, filepath.Join(reader.Path, "two.json"))
+		assert.Equal(t, []string{"One"}, provisionedTitles())
+	})
+
+	t.Run("Checks out the repository in the data path by default", func(t *testing.T) {
+		dataPath := t.TempDir()
+		reader, err := NewDashboardGitReader(&config{Name: "Git dashboards", Type: "git", OrgID: 1, Options: map[string]interface{}{
+			"url":  "file:///tmp/repo.git",
+			"path": "dashboards",
+		}}, log.New("test-logger"), dataPath)
+		require.NoError(t, err)
+
+		assert.Equal(t, filepath.Join(dataPath, "provisioning", "git", "1-git-dashboards"), reader.CheckoutPath)
+		assert.Equal(t, filepath.Join(dataPath, "provisioning", "git", "1-git-dashboards", "dashboards"), reader.Path)
+	})
+
+	t.Run("Rejects invalid options", func(t *testing.T) {
+		_, err := NewDashboardGitReader(&config{Name: "git", Type: "git", Options: map[string]interface{}{}}, log.New("test-logger"), "")
+		assert.Error(t, err)
+
+		_, err = NewDashboardGitReader(&config{Name: "git", Type: "git", Options: map[string]interface{}{
+			"url":  "file:///tmp/repo.git",
+			"path": "../dashboards",
+		}}, log.New("test-logger"), "")
+		assert.Error(t, err)
+
+		_, err = NewDashboardGitReader(&config{Name: "git", Type: "git", Options: map[string]interface{}{
+			"url": "--upload-pack=touch /tmp/pwned",
+		}}, log.New("test-logger"), "")
+		assert.Error(t, err)
+
+		_, err = NewDashboardGitReader(&config{Name: "git", Type: "git", Options: map[string]interface{}{
+			"url": "file:///tmp/repo.git",
+			"ref": "--upload-pack=touch /tmp/pwned",
+		}}, log.New("test-logger"), "")
+		assert.Error(t, err)
+	})
+}
+
+func provisionedTitles() []string {
+	var titles []string
+	for _, i := range fakeService.inserted {
+		if !i.Dashboard.IsFolder {
+			titles = append(titles, i.Dashboard.Title)
+		}
+	}
+	sort.Strings(titles)
+	return titles
+}
+
+// gitTestRepository is a bare repository with a working copy that pushes to it.
+type gitTestRepository struct {
+	bare string
+	work string
+}
+
+func newGitTestRepository(t *testing.T) *gitTestRepository {
+	t.Helper()
+
+	dir := t.TempDir()
+	repo := &gitTestRepository{bare: filepath.Join(dir, "repo.git"), work: filepath.Join(dir, "work")}
+	runGit(t, dir, "init", "--quiet", "--bare", "--initial-branch=main", repo.bare)
+	runGit(t, dir, "clone", "--quiet", repo.bare, repo.work)
+	return repo
+}
+
+func (r *gitTestRepository) write(t *testing.T, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(r.work, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+}
+
+func (r *gitTestRepository) commit(t *testing.T, branch string) {
+	t.Helper()
+
+	r.run(t, "add", "--all")
+	r.run(t, "commit", "--quiet", "--message", "update dashboards")
+	r.run(t, "push", "--quiet", "origin", "HEAD:"+branch)
+}
+
+func (r *gitTestRepository) run(t *testing.T, args ...string) {
+	t.Helper()
+	runGit(t, r.work, args...)
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+
+	args = append([]string{"-c", "user.name=grafana", "-c", "user.email=grafana@example.com", "-c", "commit.gpgsign=false"}, args...)
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(out))
+}
@@ -0,0 +1,176 @@
+package dashboards
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+	"github.com/grafana/grafana/pkg/util/errutil"
+)
+
+// Plan walks the dashboard providers of a directory like Provision does, but adds the folders and
+// dashboards that would be saved or deleted to the plan. Uids are checked across all providers, since
+// Provision would overwrite the dashboard of one provider with the file of another.
+func Plan(configDirectory, dataPath string, p *plan.Plan) error {
+	provisioner, err := New(configDirectory, dataPath)
+	if err != nil {
+		return err
+	}
+
+	// files of the planned dashboards by org and uid
+	uids := map[string]string{}
+	for _, reader := range provisioner.fileReaders {
+		if err := reader.planChanges(p, uids); err != nil {
+			if os.IsNotExist(err) {
+				p.AddWarning(plan.KindDashboard, "the path of provider %q doesn't exist: %v", reader.providerName(), err)
+				continue
+			}
+			p.AddError(plan.KindDashboard, errutil.Wrapf(err, "Failed to plan config %v", reader.providerName()))
+		}
+	}
+
+	return nil
+}
+
+// planChanges runs walkDisk with the changes added to the plan.
+func (fr *FileReader) planChanges(p *plan.Plan, uids map[string]string) error {
+	fr.plan = p
+	fr.planUIDs = uids
+	return fr.walkDisk()
+}
+
+// planChanges fetches the repository into a temporary checkout, and runs walkDisk on it with the changes
+// added to the plan. The checkout of the provider is left as it is, since it's also used by the poller.
+func (gr *GitReader) planChanges(p *plan.Plan, uids map[string]string) error {
+	checkoutPath, err := ioutil.TempDir("", "grafana-provisioning-plan")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := os.RemoveAll(checkoutPath); err != nil {
+			gr.log.Warn("Failed to remove temporary checkout", "path", checkoutPath, "error", err)
+		}
+	}()
+
+	path, err := filepath.Rel(gr.CheckoutPath, gr.Path)
+	if err != nil {
+		return err
+	}
+
+	fr := *gr.FileReader
+	fr.plan = p
+	fr.planUIDs = uids
+	fr.planPath = filepath.Join(checkoutPath, path)
+	planReader := &GitReader{FileReader: &fr, URL: gr.URL, Ref: gr.Ref, CheckoutPath: checkoutPath}
+	return planReader.walkDisk()
+}
+
+// sourcePath returns the path of a dashboard file in the resolved path, for files of the plan path.
+func (fr *FileReader) sourcePath(path string) string {
+	if fr.planPath == "" {
+		return path
+	}
+	if rel, err := filepath.Rel(fr.planPath, path); err == nil {
+		return filepath.Join(fr.resolvedPath(), rel)
+	}
+	return path
+}
+
+// rebaseProvisionedPaths returns the provisioned dashboards by the path of their files in the plan path.
+func rebaseProvisionedPaths(refs map[string]*models.DashboardProvisioning, resolvedPath, planPath string) map[string]*models.DashboardProvisioning {
+	rebased := make(map[string]*models.DashboardProvisioning, len(refs))
+	for path, ref := range refs {
+		if rel, err := filepath.Rel(resolvedPath, path); err == nil {
+			path = filepath.Join(planPath, rel)
+		}
+		rebased[path] = ref
+	}
+	return rebased
+}
+
+// planDeletedDashboards adds the deletion of the provisioned dashboards that are missing on disk to the plan.
+func (fr *FileReader) planDeletedDashboards(dashboardIDs []int64) error {
+	for _, dashboardID := range dashboardIDs {
+		query := &models.GetDashboardQuery{OrgId: fr.Cfg.OrgID, Id: dashboardID}
+		if err := bus.Dispatch(query); err != nil {
+			if errors.Is(err, models.ErrDashboardNotFound) {
+				continue
+			}
+			return err
+		}
+
+		fr.plan.Add(&plan.Change{
+			Action: plan.ActionDelete, Kind: plan.KindDashboard, OrgID: fr.Cfg.OrgID, Name: query.Result.Title, UID: query.Result.Uid,
+		})
+	}
+
+	return nil
+}
+
+// planFolder adds the folder that getOrCreateFolderID would create to the plan.
+func planFolder(cfg *config, p *plan.Plan, folderName string) error {
+	if p.Find(plan.KindFolder, cfg.OrgID, cfg.FolderUID, folderName) != nil {
+		return nil
+	}
+
+	if cfg.FolderUID != "" {
+		query := &models.GetDashboardQuery{Uid: cfg.FolderUID, OrgId: cfg.OrgID}
+		err := bus.Dispatch(query)
+		if err == nil {
+			return fmt.Errorf("folder uid %q of provider %q is already used by %q", cfg.FolderUID, cfg.Name, query.Result.Title)
+		}
+		if !errors.Is(err, models.ErrDashboardNotFound) {
+			return err
+		}
+	}
+
+	p.Add(&plan.Change{Action: plan.ActionCreate, Kind: plan.KindFolder, OrgID: cfg.OrgID, Name: folderName, UID: cfg.FolderUID})
+	return nil
+}
+
+// planSavedDashboard adds the dashboard that saveDashboard would save from the file at path to the plan.
+func (fr *FileReader) planSavedDashboard(path string, dash *models.Dashboard, alreadyProvisioned bool) error {
+	if dash.Uid != "" {
+		key := fmt.Sprintf("%d/%s", fr.Cfg.OrgID, dash.Uid)
+		if other, ok := fr.planUIDs[key]; ok {
+			return fmt.Errorf("the uid %q is used by both %s and %s", dash.Uid, other, fr.sourcePath(path))
+		}
+		fr.planUIDs[key] = fr.sourcePath(path)
+	}
+
+	change := &plan.Change{
+		Action: plan.ActionCreate, Kind: plan.KindDashboard, OrgID: fr.Cfg.OrgID, Name: dash.Title, UID: dash.Uid, Source: fr.sourcePath(path),
+	}
+	if alreadyProvisioned {
+		change.Action = plan.ActionUpdate
+	} else if dash.Uid != "" {
+		query := &models.GetDashboardQuery{OrgId: fr.Cfg.OrgID, Uid: dash.Uid}
+		err := bus.Dispatch(query)
+		if err != nil && !errors.Is(err, models.ErrDashboardNotFound) {
+			return err
+		}
+
+		if err == nil {
+			provisioned, err := fr.dashboardProvisioningService.GetProvisionedDashboardDataByDashboardID(query.Result.Id)
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case provisioned == nil:
+				// the dashboard was saved in Grafana and is overwritten by the file
+				change.Action = plan.ActionUpdate
+			case provisioned.Name != fr.Cfg.Name:
+				return fmt.Errorf("the uid %q of %s is already used by a dashboard of provider %q", dash.Uid, fr.sourcePath(path), provisioned.Name)
+			}
+		}
+	}
+
+	fr.plan.Add(change)
+	return nil
+}
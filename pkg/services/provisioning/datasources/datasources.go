@@ -8,6 +8,7 @@ import (
 	"github.com/grafana/grafana/pkg/infra/log"
 
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
 )
 
 var (
@@ -28,6 +29,8 @@ func Provision(configDirectory string) error {
 type DatasourceProvisioner struct {
 	log         log.Logger
 	cfgProvider *configReader
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
 }
 
 func newDatasourceProvisioner(log log.Logger) DatasourceProvisioner {
@@ -50,14 +53,24 @@ func (dc *DatasourceProvisioner) apply(cfg *configs) error {
 		}
 
 		if errors.Is(err, models.ErrDataSourceNotFound) {
+			if dc.plan != nil {
+				dc.plan.Add(&plan.Change{Action: plan.ActionCreate, Kind: plan.KindDataSource, OrgID: ds.OrgID, Name: ds.Name, UID: ds.UID})
+				continue
+			}
+
 			dc.log.Info("inserting datasource from configuration ", "name", ds.Name, "uid", ds.UID)
 			insertCmd := createInsertCommand(ds)
 			if err := bus.Dispatch(insertCmd); err != nil {
 				return err
 			}
 		} else {
-			dc.log.Debug("updating datasource from configuration", "name", ds.Name, "uid", ds.UID)
 			updateCmd := createUpdateCommand(ds, cmd.Result.Id)
+			if dc.plan != nil {
+				planUpdate(dc.plan, cmd.Result, updateCmd)
+				continue
+			}
+
+			dc.log.Debug("updating datasource from configuration", "name", ds.Name, "uid", ds.UID)
 			if err := bus.Dispatch(updateCmd); err != nil {
 				return err
 			}
@@ -84,6 +97,13 @@ func (dc *DatasourceProvisioner) applyChanges(configPath string) error {
 
 func (dc *DatasourceProvisioner) deleteDatasources(dsToDelete []*deleteDatasourceConfig) error {
 	for _, ds := range dsToDelete {
+		if dc.plan != nil {
+			if err := planDelete(dc.plan, ds); err != nil {
+				return err
+			}
+			continue
+		}
+
 		cmd := &models.DeleteDataSourceCommand{OrgID: ds.OrgID, Name: ds.Name}
 		if err := bus.Dispatch(cmd); err != nil {
 			return err
@@ -0,0 +1,86 @@
+package datasources
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins/manager"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// Plan adds the data sources that provisioning would insert, update or delete to the plan, along with the
+// data sources whose type isn't installed.
+func Plan(configDirectory string, p *plan.Plan) error {
+	dc := newDatasourceProvisioner(log.New("provisioning.datasources"))
+	dc.plan = p
+
+	configs, err := dc.cfgProvider.readConfig(configDirectory)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		for _, ds := range cfg.Datasources {
+			if _, exists := manager.DataSources[ds.Type]; !exists {
+				p.AddError(plan.KindDataSource, fmt.Errorf("data source %q has type %q, which isn't installed", ds.Name, ds.Type))
+			}
+		}
+
+		if err := dc.apply(cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// planDelete adds the deletion of a data source to the plan, if it exists.
+func planDelete(p *plan.Plan, ds *deleteDatasourceConfig) error {
+	query := &models.GetDataSourceQuery{OrgId: ds.OrgID, Name: ds.Name}
+	if err := bus.Dispatch(query); err != nil {
+		if errors.Is(err, models.ErrDataSourceNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	p.Add(&plan.Change{Action: plan.ActionDelete, Kind: plan.KindDataSource, OrgID: ds.OrgID, Name: ds.Name, UID: query.Result.Uid})
+	return nil
+}
+
+// planUpdate adds the update of a data source to the plan, if the update command changes any of its fields.
+func planUpdate(p *plan.Plan, current *models.DataSource, cmd *models.UpdateDataSourceCommand) {
+	var jsonData map[string]interface{}
+	if current.JsonData != nil {
+		jsonData = current.JsonData.MustMap()
+	}
+
+	d := &plan.Diff{}
+	if cmd.Uid != "" {
+		d.Compare("uid", current.Uid, cmd.Uid)
+	}
+	d.Compare("type", current.Type, cmd.Type)
+	d.Compare("access", current.Access, cmd.Access)
+	d.Compare("url", current.Url, cmd.Url)
+	d.Compare("password", current.Password, cmd.Password)
+	d.Compare("user", current.User, cmd.User)
+	d.Compare("database", current.Database, cmd.Database)
+	d.Compare("basicAuth", current.BasicAuth, cmd.BasicAuth)
+	d.Compare("basicAuthUser", current.BasicAuthUser, cmd.BasicAuthUser)
+	d.Compare("basicAuthPassword", current.BasicAuthPassword, cmd.BasicAuthPassword)
+	d.Compare("withCredentials", current.WithCredentials, cmd.WithCredentials)
+	d.Compare("isDefault", current.IsDefault, cmd.IsDefault)
+	d.CompareJSON("jsonData", jsonData, cmd.JsonData.MustMap())
+	d.CompareJSON("secureJsonData", current.SecureJsonData.Decrypt(), cmd.SecureJsonData)
+	d.Compare("editable", !current.ReadOnly, !cmd.ReadOnly)
+	if len(d.Fields) == 0 {
+		return
+	}
+
+	p.Add(&plan.Change{
+		Action: plan.ActionUpdate, Kind: plan.KindDataSource, OrgID: cmd.OrgId, Name: cmd.Name, UID: current.Uid, Fields: d.Fields,
+	})
+}
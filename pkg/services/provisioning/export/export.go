@@ -0,0 +1,555 @@
+// Package export writes the data sources, alert notification channels, plugin settings, teams, folders,
+// preferences and dashboards of an instance to files that the provisioning services can read back.
+package export
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins/manager"
+	"github.com/grafana/grafana/pkg/services/search"
+)
+
+// SecretsMode controls how secrets are written to the exported files.
+type SecretsMode string
+
+const (
+	// SecretsTemplate replaces secrets with references to environment variables,
+	// which are interpolated when the files are provisioned.
+	SecretsTemplate SecretsMode = "template"
+	// SecretsRedact replaces secrets with empty values.
+	SecretsRedact SecretsMode = "redact"
+)
+
+// generalFolder is the directory name used for dashboards that are not in a folder.
+const generalFolder = "general"
+
+const dashboardsPageSize = 1000
+
+// Options configures an export.
+type Options struct {
+	// OrgID restricts the export to a single organization, all organizations are exported if it is 0.
+	OrgID int64
+	// Secrets controls how secrets are written, it defaults to SecretsTemplate.
+	Secrets SecretsMode
+	// DashboardsPath is the directory the dashboard providers load the exported dashboard files from.
+	DashboardsPath string
+}
+
+// File is an exported file. Its path is relative to the provisioning directory.
+type File struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+}
+
+// Result holds the exported files and the environment variables they reference in place of secrets.
+type Result struct {
+	Files     []*File  `json:"files"`
+	Variables []string `json:"variables"`
+}
+
+// ParseSecretsMode returns the secrets mode with the given name, the empty name is SecretsTemplate.
+func ParseSecretsMode(name string) (SecretsMode, error) {
+	switch SecretsMode(name) {
+	case "", SecretsTemplate:
+		return SecretsTemplate, nil
+	case SecretsRedact:
+		return SecretsRedact, nil
+	default:
+		return "", fmt.Errorf("unknown secrets mode %q, expected %q or %q", name, SecretsTemplate, SecretsRedact)
+	}
+}
+
+// Export reads the instance state from the database and returns it as provisioning files.
+func Export(opts Options) (*Result, error) {
+	if opts.Secrets == "" {
+		opts.Secrets = SecretsTemplate
+	}
+	if opts.DashboardsPath == "" {
+		return nil, fmt.Errorf("the dashboards path is required")
+	}
+
+	orgs, err := getOrgs(opts.OrgID)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &exporter{opts: opts, variables: map[string]struct{}{}}
+	for _, org := range orgs {
+		if err := e.exportOrg(org.Id); err != nil {
+			return nil, fmt.Errorf("failed to export organization %q: %w", org.Name, err)
+		}
+	}
+
+	return e.result()
+}
+
+func getOrgs(orgID int64) ([]*models.OrgDTO, error) {
+	query := &models.SearchOrgsQuery{}
+	if orgID != 0 {
+		query.Ids = []int64{orgID}
+	}
+	if err := bus.Dispatch(query); err != nil {
+		return nil, err
+	}
+	if orgID != 0 && len(query.Result) == 0 {
+		return nil, models.ErrOrgNotFound
+	}
+
+	sort.Slice(query.Result, func(i, j int) bool { return query.Result[i].Id < query.Result[j].Id })
+	return query.Result, nil
+}
+
+type exporter struct {
+	opts      Options
+	variables map[string]struct{}
+
+	datasources datasourcesConfig
+	notifiers   notifiersConfig
+	plugins     pluginsConfig
+	teams       teamsConfig
+	folders     foldersConfig
+	preferences preferencesConfig
+	dashboards  dashboardsConfig
+
+	dashboardFiles []*File
+}
+
+func (e *exporter) exportOrg(orgID int64) error {
+	if err := e.exportDatasources(orgID); err != nil {
+		return err
+	}
+	if err := e.exportNotifiers(orgID); err != nil {
+		return err
+	}
+	if err := e.exportPlugins(orgID); err != nil {
+		return err
+	}
+	if err := e.exportTeams(orgID); err != nil {
+		return err
+	}
+	if err := e.exportFolders(orgID); err != nil {
+		return err
+	}
+	if err := e.exportPreferences(orgID); err != nil {
+		return err
+	}
+	return e.exportDashboards(orgID)
+}
+
+func (e *exporter) exportDatasources(orgID int64) error {
+	query := &models.GetDataSourcesQuery{OrgId: orgID}
+	if err := bus.Dispatch(query); err != nil {
+		return err
+	}
+
+	for _, ds := range query.Result {
+		secureKeys := make([]string, 0, len(ds.SecureJsonData))
+		for key := range ds.SecureJsonData {
+			secureKeys = append(secureKeys, key)
+		}
+		// passwords that are not encrypted yet are moved to the secure json data
+		if ds.Password != "" {
+			secureKeys = append(secureKeys, "password")
+		}
+		if ds.BasicAuthPassword != "" {
+			secureKeys = append(secureKeys, "basicAuthPassword")
+		}
+
+		e.datasources.Datasources = append(e.datasources.Datasources, &datasourceConfig{
+			OrgID:           ds.OrgId,
+			Name:            escape(ds.Name),
+			Type:            ds.Type,
+			Access:          string(ds.Access),
+			URL:             escape(ds.Url),
+			User:            escape(ds.User),
+			Database:        escape(ds.Database),
+			BasicAuth:       ds.BasicAuth,
+			BasicAuthUser:   escape(ds.BasicAuthUser),
+			WithCredentials: ds.WithCredentials,
+			IsDefault:       ds.IsDefault,
+			JSONData:        escapeJSON(ds.JsonData.MustMap()),
+			SecureJSONData:  e.secrets(secureKeys, "datasource", orgID, ds.Name),
+			Editable:        !ds.ReadOnly,
+			UID:             ds.Uid,
+		})
+	}
+
+	return nil
+}
+
+func (e *exporter) exportNotifiers(orgID int64) error {
+	query := &models.GetAllAlertNotificationsQuery{OrgId: orgID}
+	if err := bus.Dispatch(query); err != nil {
+		return err
+	}
+
+	for _, n := range query.Result {
+		secureKeys := make([]string, 0, len(n.SecureSettings))
+		for key := range n.SecureSettings {
+			secureKeys = append(secureKeys, key)
+		}
+
+		var frequency string
+		if n.Frequency != 0 {
+			frequency = n.Frequency.String()
+		}
+
+		e.notifiers.Notifiers = append(e.notifiers.Notifiers, &notifierConfig{
+			UID:                   n.Uid,
+			Name:                  escape(n.Name),
+			Type:                  n.Type,
+			OrgID:                 n.OrgId,
+			IsDefault:             n.IsDefault,
+			SendReminder:          n.SendReminder,
+			DisableResolveMessage: n.DisableResolveMessage,
+			Frequency:             frequency,
+			Settings:              escapeJSON(n.Settings.MustMap()),
+			SecureSettings:        e.secrets(secureKeys, "notifier", orgID, n.Uid),
+		})
+	}
+
+	return nil
+}
+
+func (e *exporter) exportPlugins(orgID int64) error {
+	query := &models.GetPluginSettingsQuery{OrgId: orgID}
+	if err := bus.Dispatch(query); err != nil {
+		return err
+	}
+
+	for _, info := range query.Result {
+		// only app plugins can be provisioned, settings of plugins that are unknown are exported as they are
+		if _, ok := manager.Plugins[info.PluginId]; ok && !manager.IsAppInstalled(info.PluginId) {
+			continue
+		}
+
+		settingQuery := &models.GetPluginSettingByIdQuery{OrgId: orgID, PluginId: info.PluginId}
+		if err := bus.Dispatch(settingQuery); err != nil {
+			return err
+		}
+		ps := settingQuery.Result
+
+		secureKeys := make([]string, 0, len(ps.SecureJsonData))
+		for key := range ps.SecureJsonData {
+			secureKeys = append(secureKeys, key)
+		}
+
+		e.plugins.Apps = append(e.plugins.Apps, &appConfig{
+			Type:           ps.PluginId,
+			OrgID:          ps.OrgId,
+			Disabled:       !ps.Enabled,
+			JSONData:       escapeJSON(ps.JsonData),
+			SecureJSONData: e.secrets(secureKeys, "plugin", orgID, ps.PluginId),
+		})
+	}
+
+	return nil
+}
+
+func (e *exporter) exportTeams(orgID int64) error {
+	query := &models.SearchTeamsQuery{OrgId: orgID}
+	if err := bus.Dispatch(query); err != nil {
+		return err
+	}
+
+	for _, team := range query.Result.Teams {
+		membersQuery := &models.GetTeamMembersQuery{OrgId: orgID, TeamId: team.Id}
+		if err := bus.Dispatch(membersQuery); err != nil {
+			return err
+		}
+
+		members := make([]string, 0, len(membersQuery.Result))
+		for _, member := range membersQuery.Result {
+			members = append(members, escape(member.Login))
+		}
+		sort.Strings(members)
+
+		e.teams.Teams = append(e.teams.Teams, &teamConfig{
+			OrgID:   orgID,
+			Name:    escape(team.Name),
+			Email:   escape(team.Email),
+			Members: members,
+		})
+	}
+
+	return nil
+}
+
+// exportFolders exports the folders of the organization with the permissions that are set on them. The
+// permissions of folders that use the default permissions are left out, so that they aren't managed.
+func (e *exporter) exportFolders(orgID int64) error {
+	hits, err := findDashboards(orgID, search.DashHitFolder)
+	if err != nil {
+		return err
+	}
+
+	for _, hit := range hits {
+		query := &models.GetDashboardAclInfoListQuery{OrgID: orgID, DashboardID: hit.ID}
+		if err := bus.Dispatch(query); err != nil {
+			return err
+		}
+
+		var permissions []*permissionConfig
+		for _, acl := range query.Result {
+			if acl.DashboardId != hit.ID {
+				continue
+			}
+			permission := &permissionConfig{Permission: acl.Permission.String()}
+			switch {
+			case acl.Role != nil:
+				permission.Role = string(*acl.Role)
+			case acl.TeamId != 0:
+				permission.Team = escape(acl.Team)
+			case acl.UserId != 0:
+				permission.User = escape(acl.UserLogin)
+			}
+			permissions = append(permissions, permission)
+		}
+
+		e.folders.Folders = append(e.folders.Folders, &folderConfig{
+			OrgID:       orgID,
+			UID:         hit.UID,
+			Title:       escape(hit.Title),
+			Permissions: permissions,
+		})
+	}
+
+	return nil
+}
+
+func (e *exporter) exportPreferences(orgID int64) error {
+	query := &models.GetPreferencesQuery{OrgId: orgID}
+	if err := bus.Dispatch(query); err != nil {
+		return err
+	}
+	prefs := query.Result
+	if prefs.Id == 0 {
+		return nil
+	}
+
+	config := &orgPreferencesConfig{OrgID: orgID, Theme: prefs.Theme, Timezone: prefs.Timezone}
+	if prefs.HomeDashboardId != 0 {
+		dashboardQuery := &models.GetDashboardQuery{OrgId: orgID, Id: prefs.HomeDashboardId}
+		if err := bus.Dispatch(dashboardQuery); err != nil && !errors.Is(err, models.ErrDashboardNotFound) {
+			return err
+		}
+		if dashboardQuery.Result != nil {
+			config.HomeDashboardUID = dashboardQuery.Result.Uid
+		}
+	}
+	if config.Theme == "" && config.Timezone == "" && config.HomeDashboardUID == "" {
+		return nil
+	}
+
+	e.preferences.Preferences = append(e.preferences.Preferences, config)
+	return nil
+}
+
+// exportDashboards writes the dashboards of the organization to one directory per folder and adds a
+// dashboard provider for each of the directories. Dashboards that are already provisioned are skipped.
+func (e *exporter) exportDashboards(orgID int64) error {
+	hits, err := findDashboards(orgID, search.DashHitDB)
+	if err != nil {
+		return err
+	}
+
+	providers := map[string]*dashboardProviderConfig{}
+	for _, hit := range hits {
+		provisioned := &models.GetProvisionedDashboardDataByIdQuery{DashboardId: hit.ID}
+		if err := bus.Dispatch(provisioned); err != nil {
+			return err
+		}
+		if provisioned.Result != nil {
+			continue
+		}
+
+		query := &models.GetDashboardQuery{OrgId: orgID, Id: hit.ID}
+		if err := bus.Dispatch(query); err != nil {
+			return err
+		}
+
+		data := query.Result.Data.MustMap()
+		delete(data, "id")
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		dir := path.Join(fmt.Sprintf("org-%d", orgID), generalFolder)
+		if hit.FolderUID != "" {
+			dir = path.Join(fmt.Sprintf("org-%d", orgID), hit.FolderUID)
+		}
+		e.dashboardFiles = append(e.dashboardFiles, &File{
+			Path:    path.Join("dashboards", dir, hit.UID+".json"),
+			Content: string(content) + "\n",
+		})
+
+		if _, ok := providers[dir]; ok {
+			continue
+		}
+		provider := &dashboardProviderConfig{
+			Name:    fmt.Sprintf("org-%d-%s", orgID, generalFolder),
+			OrgID:   orgID,
+			Type:    "file",
+			Options: map[string]interface{}{"path": escape(path.Join(e.opts.DashboardsPath, dir))},
+		}
+		if hit.FolderUID != "" {
+			provider.Name = fmt.Sprintf("org-%d-%s", orgID, hit.FolderUID)
+			provider.Folder = escape(hit.FolderTitle)
+			provider.FolderUID = hit.FolderUID
+		}
+		providers[dir] = provider
+		e.dashboards.Providers = append(e.dashboards.Providers, provider)
+	}
+
+	return nil
+}
+
+func findDashboards(orgID int64, hitType search.HitType) ([]*search.Hit, error) {
+	var hits []*search.Hit
+	user := &models.SignedInUser{OrgId: orgID, OrgRole: models.ROLE_ADMIN}
+	for page := int64(1); ; page++ {
+		query := &search.FindPersistedDashboardsQuery{
+			OrgId:        orgID,
+			SignedInUser: user,
+			Type:         string(hitType),
+			Limit:        dashboardsPageSize,
+			Page:         page,
+		}
+		if err := bus.Dispatch(query); err != nil {
+			return nil, err
+		}
+		hits = append(hits, query.Result...)
+		if len(query.Result) < dashboardsPageSize {
+			return hits, nil
+		}
+	}
+}
+
+// secrets returns the values written for the given secret keys.
+func (e *exporter) secrets(keys []string, kind string, orgID int64, name string) map[string]string {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	secrets := make(map[string]string, len(keys))
+	for _, key := range keys {
+		if e.opts.Secrets == SecretsRedact {
+			secrets[key] = ""
+			continue
+		}
+
+		variable := variableName(kind, orgID, name, key)
+		e.variables[variable] = struct{}{}
+		secrets[key] = "${" + variable + "}"
+	}
+	return secrets
+}
+
+func (e *exporter) result() (*Result, error) {
+	result := &Result{Variables: make([]string, 0, len(e.variables))}
+
+	configs := []struct {
+		path  string
+		empty bool
+		value interface{}
+	}{
+		{"datasources/datasources.yaml", len(e.datasources.Datasources) == 0, &e.datasources},
+		{"notifiers/notifiers.yaml", len(e.notifiers.Notifiers) == 0, &e.notifiers},
+		{"plugins/plugins.yaml", len(e.plugins.Apps) == 0, &e.plugins},
+		{"teams/teams.yaml", len(e.teams.Teams) == 0, &e.teams},
+		{"folders/folders.yaml", len(e.folders.Folders) == 0, &e.folders},
+		{"preferences/preferences.yaml", len(e.preferences.Preferences) == 0, &e.preferences},
+		{"dashboards/dashboards.yaml", len(e.dashboards.Providers) == 0, &e.dashboards},
+	}
+	e.datasources.APIVersion = 1
+	e.plugins.APIVersion = 1
+	e.teams.APIVersion = 1
+	e.folders.APIVersion = 1
+	e.preferences.APIVersion = 1
+	e.dashboards.APIVersion = 1
+
+	for _, c := range configs {
+		if c.empty {
+			continue
+		}
+		content, err := yaml.Marshal(c.value)
+		if err != nil {
+			return nil, err
+		}
+		result.Files = append(result.Files, &File{Path: c.path, Content: string(content)})
+	}
+	result.Files = append(result.Files, e.dashboardFiles...)
+
+	for variable := range e.variables {
+		result.Variables = append(result.Variables, variable)
+	}
+	sort.Strings(result.Variables)
+
+	return result, nil
+}
+
+var (
+	camelCaseBoundary = regexp.MustCompile(`([a-z0-9])([A-Z])`)
+	nonAlphanumeric   = regexp.MustCompile(`[^A-Z0-9]+`)
+)
+
+// variableName returns the name of the environment variable referenced in place of a secret, for example
+// DATASOURCE_PROMETHEUS_BASIC_AUTH_PASSWORD. The organization is part of the name unless it is the main one.
+func variableName(kind string, orgID int64, name, key string) string {
+	parts := []string{kind}
+	if orgID != 1 {
+		parts = append(parts, fmt.Sprintf("org%d", orgID))
+	}
+	parts = append(parts, name, key)
+
+	for i, part := range parts {
+		part = strings.ToUpper(camelCaseBoundary.ReplaceAllString(part, "${1}_${2}"))
+		parts[i] = strings.Trim(nonAlphanumeric.ReplaceAllString(part, "_"), "_")
+	}
+	return strings.Join(parts, "_")
+}
+
+// escape doubles the '$' characters of values that are interpolated when they are provisioned.
+func escape(value string) string {
+	return strings.ReplaceAll(value, "$", "$$")
+}
+
+// escapeJSON returns a copy of the json data with all its string values escaped, or nil if it is empty.
+func escapeJSON(m map[string]interface{}) map[string]interface{} {
+	if len(m) == 0 {
+		return nil
+	}
+	return escapeValue(m).(map[string]interface{})
+}
+
+func escapeValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return escape(v)
+	case map[string]interface{}:
+		escaped := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			escaped[key] = escapeValue(item)
+		}
+		return escaped
+	case []interface{}:
+		escaped := make([]interface{}, len(v))
+		for i, item := range v {
+			escaped[i] = escapeValue(item)
+		}
+		return escaped
+	default:
+		return v
+	}
+}
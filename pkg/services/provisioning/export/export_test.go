@@ -0,0 +1,249 @@
+package export
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v2"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestExport(t *testing.T) {
+	setup := func(t *testing.T) {
+		t.Helper()
+		sqlstore.InitTestDB(t)
+		require.NoError(t, sqlstore.CreateOrg(&models.CreateOrgCommand{Name: "Main Org."}))
+
+		require.NoError(t, bus.Dispatch(&models.AddDataSourceCommand{
+			OrgId: 1, Name: "Prometheus", Type: "prometheus", Access: models.DS_ACCESS_PROXY, Url: "http://prometheus:9090",
+			BasicAuth: true, BasicAuthUser: "grafana", BasicAuthPassword: "legacy-secret", Uid: "prometheus",
+			JsonData:       simplejson.NewFromAny(map[string]interface{}{"httpMethod": "POST", "query": "up{job=\"$job\"}"}),
+			SecureJsonData: map[string]string{"httpHeaderValue1": "header-secret"},
+		}))
+		require.NoError(t, bus.Dispatch(&models.CreateAlertNotificationCommand{
+			OrgId: 1, Uid: "slack", Name: "Slack", Type: "slack", SendReminder: true, Frequency: "15m",
+			Settings:       simplejson.NewFromAny(map[string]interface{}{"recipient": "#alerts"}),
+			SecureSettings: map[string]string{"url": "notification-secret"},
+		}))
+		require.NoError(t, bus.Dispatch(&models.UpdatePluginSettingCmd{
+			OrgId: 1, PluginId: "example-app", Enabled: true,
+			JsonData:       map[string]interface{}{"endpoint": "http://example"},
+			SecureJsonData: map[string]string{"apiKey": "plugin-secret"},
+		}))
+
+		folder := saveDashboard(t, &models.SaveDashboardCommand{OrgId: 1, IsFolder: true, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"uid": "team", "title": "Team",
+		})})
+		home := saveDashboard(t, &models.SaveDashboardCommand{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"uid": "home", "title": "Home",
+		})})
+		saveDashboard(t, &models.SaveDashboardCommand{OrgId: 1, FolderId: folder.Id, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"uid": "services", "title": "Services",
+		})})
+		require.NoError(t, bus.Dispatch(&models.SaveProvisionedDashboardCommand{
+			DashboardCmd: &models.SaveDashboardCommand{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"uid": "provisioned", "title": "Provisioned",
+			})},
+			DashboardProvisioning: &models.DashboardProvisioning{Name: "default", ExternalId: "/dashboards/provisioned.json"},
+		}))
+
+		user := &models.CreateUserCommand{Login: "alice", Email: "alice@example.com", SkipOrgSetup: true}
+		require.NoError(t, bus.Dispatch(user))
+		team := &models.CreateTeamCommand{OrgId: 1, Name: "Backend", Email: "backend@example.com"}
+		require.NoError(t, bus.Dispatch(team))
+		require.NoError(t, bus.Dispatch(&models.AddTeamMemberCommand{OrgId: 1, TeamId: team.Result.Id, UserId: user.Result.Id}))
+
+		now := time.Now()
+		viewer := models.ROLE_VIEWER
+		require.NoError(t, bus.Dispatch(&models.UpdateDashboardAclCommand{DashboardID: folder.Id, Items: []*models.DashboardAcl{
+			{OrgID: 1, DashboardID: folder.Id, Role: &viewer, Permission: models.PERMISSION_VIEW, Created: now, Updated: now},
+			{OrgID: 1, DashboardID: folder.Id, TeamID: team.Result.Id, Permission: models.PERMISSION_EDIT, Created: now, Updated: now},
+			{OrgID: 1, DashboardID: folder.Id, UserID: user.Result.Id, Permission: models.PERMISSION_ADMIN, Created: now, Updated: now},
+		}}))
+
+		require.NoError(t, bus.Dispatch(&models.SavePreferencesCommand{OrgId: 1, Theme: "light", Timezone: "utc", HomeDashboardId: home.Id}))
+	}
+
+	t.Run("Exports data sources with templated secrets", func(t *testing.T) {
+		setup(t)
+
+		result, err := Export(Options{DashboardsPath: "/etc/grafana/provisioning/dashboards"})
+		require.NoError(t, err)
+
+		var config datasourcesConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "datasources/datasources.yaml")), &config))
+		assert.Equal(t, int64(1), config.APIVersion)
+		require.Len(t, config.Datasources, 1)
+		assert.Equal(t, &datasourceConfig{
+			OrgID: 1, Name: "Prometheus", Type: "prometheus", Access: "proxy", URL: "http://prometheus:9090",
+			BasicAuth: true, BasicAuthUser: "grafana", Editable: true, UID: "prometheus",
+			JSONData: map[string]interface{}{"httpMethod": "POST", "query": "up{job=\"$$job\"}"},
+			SecureJSONData: map[string]string{
+				"basicAuthPassword": "${DATASOURCE_PROMETHEUS_BASIC_AUTH_PASSWORD}",
+				"httpHeaderValue1":  "${DATASOURCE_PROMETHEUS_HTTP_HEADER_VALUE1}",
+			},
+		}, config.Datasources[0])
+
+		assert.Equal(t, []string{
+			"DATASOURCE_PROMETHEUS_BASIC_AUTH_PASSWORD",
+			"DATASOURCE_PROMETHEUS_HTTP_HEADER_VALUE1",
+			"NOTIFIER_SLACK_URL",
+			"PLUGIN_EXAMPLE_APP_API_KEY",
+		}, result.Variables)
+	})
+
+	t.Run("Redacts secrets", func(t *testing.T) {
+		setup(t)
+
+		result, err := Export(Options{Secrets: SecretsRedact, DashboardsPath: "/etc/grafana/provisioning/dashboards"})
+		require.NoError(t, err)
+
+		var notifiers notifiersConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "notifiers/notifiers.yaml")), &notifiers))
+		assert.Equal(t, []*notifierConfig{{
+			UID: "slack", Name: "Slack", Type: "slack", OrgID: 1, SendReminder: true, Frequency: "15m0s",
+			Settings:       map[string]interface{}{"recipient": "#alerts"},
+			SecureSettings: map[string]string{"url": ""},
+		}}, notifiers.Notifiers)
+
+		var plugins pluginsConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "plugins/plugins.yaml")), &plugins))
+		assert.Equal(t, []*appConfig{{
+			Type: "example-app", OrgID: 1,
+			JSONData:       map[string]interface{}{"endpoint": "http://example"},
+			SecureJSONData: map[string]string{"apiKey": ""},
+		}}, plugins.Apps)
+
+		assert.Empty(t, result.Variables)
+		for _, f := range result.Files {
+			assert.NotContains(t, f.Content, "secret")
+		}
+	})
+
+	t.Run("Exports dashboards by folder", func(t *testing.T) {
+		setup(t)
+
+		result, err := Export(Options{DashboardsPath: "/etc/grafana/provisioning/dashboards"})
+		require.NoError(t, err)
+
+		var config dashboardsConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "dashboards/dashboards.yaml")), &config))
+		assert.Equal(t, []*dashboardProviderConfig{
+			{
+				Name: "org-1-general", OrgID: 1, Type: "file",
+				Options: map[string]interface{}{"path": "/etc/grafana/provisioning/dashboards/org-1/general"},
+			},
+			{
+				Name: "org-1-team", OrgID: 1, Folder: "Team", FolderUID: "team", Type: "file",
+				Options: map[string]interface{}{"path": "/etc/grafana/provisioning/dashboards/org-1/team"},
+			},
+		}, config.Providers)
+
+		var dashboard map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(fileContent(t, result, "dashboards/org-1/team/services.json")), &dashboard))
+		assert.Equal(t, "Services", dashboard["title"])
+		assert.NotContains(t, dashboard, "id")
+
+		fileContent(t, result, "dashboards/org-1/general/home.json")
+		for _, f := range result.Files {
+			assert.NotContains(t, f.Path, "provisioned")
+		}
+	})
+
+	t.Run("Exports teams, folders with their permissions and preferences", func(t *testing.T) {
+		setup(t)
+
+		result, err := Export(Options{DashboardsPath: "dashboards"})
+		require.NoError(t, err)
+
+		var teams teamsConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "teams/teams.yaml")), &teams))
+		assert.Equal(t, []*teamConfig{
+			{OrgID: 1, Name: "Backend", Email: "backend@example.com", Members: []string{"alice"}},
+		}, teams.Teams)
+
+		var folders foldersConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "folders/folders.yaml")), &folders))
+		assert.Equal(t, []*folderConfig{{
+			OrgID: 1, UID: "team", Title: "Team",
+			Permissions: []*permissionConfig{
+				{Role: "Viewer", Permission: "View"},
+				{Team: "Backend", Permission: "Edit"},
+				{User: "alice", Permission: "Admin"},
+			},
+		}}, folders.Folders)
+
+		var preferences preferencesConfig
+		require.NoError(t, yaml.Unmarshal([]byte(fileContent(t, result, "preferences/preferences.yaml")), &preferences))
+		assert.Equal(t, []*orgPreferencesConfig{
+			{OrgID: 1, Theme: "light", Timezone: "utc", HomeDashboardUID: "home"},
+		}, preferences.Preferences)
+	})
+
+	t.Run("Exports a single organization", func(t *testing.T) {
+		setup(t)
+		org := &models.CreateOrgCommand{Name: "Other"}
+		require.NoError(t, sqlstore.CreateOrg(org))
+		require.NoError(t, bus.Dispatch(&models.AddDataSourceCommand{
+			OrgId: org.Result.Id, Name: "Loki", Type: "loki", Access: models.DS_ACCESS_PROXY,
+			SecureJsonData: map[string]string{"password": "secret"},
+		}))
+
+		result, err := Export(Options{OrgID: org.Result.Id, DashboardsPath: "dashboards"})
+		require.NoError(t, err)
+		require.Len(t, result.Files, 1)
+		assert.Equal(t, []string{"DATASOURCE_ORG2_LOKI_PASSWORD"}, result.Variables)
+
+		_, err = Export(Options{OrgID: 100, DashboardsPath: "dashboards"})
+		assert.Equal(t, models.ErrOrgNotFound, err)
+	})
+
+	t.Run("Exported data sources can be provisioned", func(t *testing.T) {
+		setup(t)
+
+		result, err := Export(Options{DashboardsPath: "dashboards"})
+		require.NoError(t, err)
+
+		dir := t.TempDir()
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "datasources"), 0750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "datasources", "datasources.yaml"), []byte(fileContent(t, result, "datasources/datasources.yaml")), 0600))
+		t.Setenv("DATASOURCE_PROMETHEUS_BASIC_AUTH_PASSWORD", "new-secret")
+		t.Setenv("DATASOURCE_PROMETHEUS_HTTP_HEADER_VALUE1", "new-header")
+
+		sqlstore.InitTestDB(t)
+		require.NoError(t, sqlstore.CreateOrg(&models.CreateOrgCommand{Name: "Main Org."}))
+		require.NoError(t, datasources.Provision(filepath.Join(dir, "datasources")))
+
+		query := &models.GetDataSourceQuery{OrgId: 1, Uid: "prometheus"}
+		require.NoError(t, bus.Dispatch(query))
+		assert.Equal(t, "up{job=\"$job\"}", query.Result.JsonData.Get("query").MustString())
+		assert.Equal(t, map[string]string{"basicAuthPassword": "new-secret", "httpHeaderValue1": "new-header"}, query.Result.SecureJsonData.Decrypt())
+	})
+}
+
+func saveDashboard(t *testing.T, cmd *models.SaveDashboardCommand) *models.Dashboard {
+	t.Helper()
+	require.NoError(t, bus.Dispatch(cmd))
+	return cmd.Result
+}
+
+func fileContent(t *testing.T, result *Result, path string) string {
+	t.Helper()
+	for _, f := range result.Files {
+		if f.Path == path {
+			return f.Content
+		}
+	}
+	require.Failf(t, "missing file", "file %s was not exported", path)
+	return ""
+}
@@ -0,0 +1,114 @@
+package export
+
+// The types below mirror the configuration files read by the provisioning services, using the latest version
+// of each format.
+
+type datasourcesConfig struct {
+	APIVersion  int64               `yaml:"apiVersion"`
+	Datasources []*datasourceConfig `yaml:"datasources"`
+}
+
+type datasourceConfig struct {
+	OrgID           int64                  `yaml:"orgId"`
+	Name            string                 `yaml:"name"`
+	Type            string                 `yaml:"type"`
+	Access          string                 `yaml:"access,omitempty"`
+	URL             string                 `yaml:"url,omitempty"`
+	User            string                 `yaml:"user,omitempty"`
+	Database        string                 `yaml:"database,omitempty"`
+	BasicAuth       bool                   `yaml:"basicAuth,omitempty"`
+	BasicAuthUser   string                 `yaml:"basicAuthUser,omitempty"`
+	WithCredentials bool                   `yaml:"withCredentials,omitempty"`
+	IsDefault       bool                   `yaml:"isDefault,omitempty"`
+	JSONData        map[string]interface{} `yaml:"jsonData,omitempty"`
+	SecureJSONData  map[string]string      `yaml:"secureJsonData,omitempty"`
+	Editable        bool                   `yaml:"editable"`
+	UID             string                 `yaml:"uid,omitempty"`
+}
+
+type notifiersConfig struct {
+	Notifiers []*notifierConfig `yaml:"notifiers"`
+}
+
+type notifierConfig struct {
+	UID                   string                 `yaml:"uid"`
+	Name                  string                 `yaml:"name"`
+	Type                  string                 `yaml:"type"`
+	OrgID                 int64                  `yaml:"org_id"`
+	IsDefault             bool                   `yaml:"is_default,omitempty"`
+	SendReminder          bool                   `yaml:"send_reminder,omitempty"`
+	DisableResolveMessage bool                   `yaml:"disable_resolve_message,omitempty"`
+	Frequency             string                 `yaml:"frequency,omitempty"`
+	Settings              map[string]interface{} `yaml:"settings,omitempty"`
+	SecureSettings        map[string]string      `yaml:"secure_settings,omitempty"`
+}
+
+type pluginsConfig struct {
+	APIVersion int64        `yaml:"apiVersion"`
+	Apps       []*appConfig `yaml:"apps"`
+}
+
+type appConfig struct {
+	Type           string                 `yaml:"type"`
+	OrgID          int64                  `yaml:"org_id"`
+	Disabled       bool                   `yaml:"disabled"`
+	JSONData       map[string]interface{} `yaml:"jsonData,omitempty"`
+	SecureJSONData map[string]string      `yaml:"secureJsonData,omitempty"`
+}
+
+type dashboardsConfig struct {
+	APIVersion int64                      `yaml:"apiVersion"`
+	Providers  []*dashboardProviderConfig `yaml:"providers"`
+}
+
+type dashboardProviderConfig struct {
+	Name      string                 `yaml:"name"`
+	OrgID     int64                  `yaml:"orgId"`
+	Folder    string                 `yaml:"folder,omitempty"`
+	FolderUID string                 `yaml:"folderUid,omitempty"`
+	Type      string                 `yaml:"type"`
+	Options   map[string]interface{} `yaml:"options"`
+}
+
+type teamsConfig struct {
+	APIVersion int64         `yaml:"apiVersion"`
+	Teams      []*teamConfig `yaml:"teams"`
+}
+
+type teamConfig struct {
+	OrgID   int64    `yaml:"orgId"`
+	Name    string   `yaml:"name"`
+	Email   string   `yaml:"email,omitempty"`
+	Members []string `yaml:"members,omitempty"`
+}
+
+type foldersConfig struct {
+	APIVersion int64           `yaml:"apiVersion"`
+	Folders    []*folderConfig `yaml:"folders"`
+}
+
+type folderConfig struct {
+	OrgID       int64               `yaml:"orgId"`
+	UID         string              `yaml:"uid"`
+	Title       string              `yaml:"title"`
+	Permissions []*permissionConfig `yaml:"permissions,omitempty"`
+}
+
+type permissionConfig struct {
+	Role       string `yaml:"role,omitempty"`
+	Team       string `yaml:"team,omitempty"`
+	User       string `yaml:"user,omitempty"`
+	Permission string `yaml:"permission"`
+}
+
+type preferencesConfig struct {
+	APIVersion  int64                   `yaml:"apiVersion"`
+	Preferences []*orgPreferencesConfig `yaml:"preferences"`
+}
+
+type orgPreferencesConfig struct {
+	OrgID            int64  `yaml:"orgId"`
+	Theme            string `yaml:"theme,omitempty"`
+	Timezone         string `yaml:"timezone,omitempty"`
+	HomeDashboardUID string `yaml:"homeDashboardUid,omitempty"`
+}
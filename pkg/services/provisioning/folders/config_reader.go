@@ -0,0 +1,134 @@
+package folders
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"gopkg.in/yaml.v2"
+)
+
+type configReader struct {
+	log log.Logger
+}
+
+func (cr *configReader) readConfig(path string) ([]*foldersAsConfig, error) {
+	var folders []*foldersAsConfig
+	cr.log.Debug("Looking for folder provisioning files", "path", path)
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read folder provisioning files from directory", "path", path, "error", err)
+		return folders, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			cr.log.Debug("Parsing folder provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parseFolderConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg != nil {
+				folders = append(folders, cfg)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating folders")
+	if err := validateRequiredField(folders); err != nil {
+		return nil, err
+	}
+
+	return folders, nil
+}
+
+func (cr *configReader) parseFolderConfig(path string, file os.FileInfo) (*foldersAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *foldersAsConfigV1
+	if err := yaml.Unmarshal(yamlFile, &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg.mapToFoldersFromConfig()
+}
+
+func validateRequiredField(folders []*foldersAsConfig) error {
+	for i := range folders {
+		var errStrings []string
+		for index, folder := range folders[i].Folders {
+			if folder.UID == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Added folder item %d in configuration doesn't contain required field uid", index+1),
+				)
+			}
+
+			if folder.Title == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Added folder item %d in configuration doesn't contain required field title", index+1),
+				)
+			}
+
+			for _, permission := range folder.Permissions {
+				if err := validatePermission(permission); err != nil {
+					errStrings = append(errStrings, fmt.Sprintf("Added folder item %d in configuration %s", index+1, err))
+				}
+			}
+		}
+
+		for index, folder := range folders[i].DeleteFolders {
+			if folder.UID == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Deleted folder item %d in configuration doesn't contain required field uid", index+1),
+				)
+			}
+		}
+
+		if len(errStrings) != 0 {
+			return fmt.Errorf(strings.Join(errStrings, "\n"))
+		}
+	}
+
+	return nil
+}
+
+// validatePermission checks that a permission is granted to exactly one role, team or user.
+func validatePermission(permission *permissionFromConfig) error {
+	targets := 0
+	for _, target := range []string{permission.Role, permission.Team, permission.User} {
+		if target != "" {
+			targets++
+		}
+	}
+	if targets != 1 {
+		return fmt.Errorf("has a permission that must contain exactly one of role, team or user")
+	}
+
+	if permission.Role != "" {
+		role := models.RoleType(permission.Role)
+		if role != models.ROLE_VIEWER && role != models.ROLE_EDITOR {
+			return fmt.Errorf("has a permission with invalid role %q, must be Viewer or Editor", permission.Role)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,263 @@
+package folders
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// Provision scans a directory for provisioning config files
+// and provisions the folders in those files.
+func Provision(configDirectory string) error {
+	fp := newFolderProvisioner(log.New("provisioning.folders"))
+	return fp.applyChanges(configDirectory)
+}
+
+// FolderProvisioner is responsible for provisioning folders and their permissions
+// based on configuration read by the `configReader`
+type FolderProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
+}
+
+func newFolderProvisioner(log log.Logger) FolderProvisioner {
+	return FolderProvisioner{
+		log:         log,
+		cfgProvider: &configReader{log: log},
+	}
+}
+
+func (fp *FolderProvisioner) apply(cfg *foldersAsConfig) error {
+	if err := fp.deleteFolders(cfg.DeleteFolders); err != nil {
+		return err
+	}
+
+	if err := fp.mergeFolders(cfg.Folders); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (fp *FolderProvisioner) deleteFolders(foldersToDelete []*deleteFolderConfig) error {
+	for _, folder := range foldersToDelete {
+		orgID, err := utils.GetOrgID(folder.OrgID, folder.OrgName)
+		if err != nil {
+			return err
+		}
+
+		existing, err := getFolderByUID(orgID, folder.UID)
+		if err != nil {
+			if fp.plan != nil {
+				fp.plan.AddError(plan.KindFolder, err)
+				continue
+			}
+			return err
+		}
+		if existing == nil {
+			continue
+		}
+
+		if fp.plan != nil {
+			fp.plan.Add(&plan.Change{Action: plan.ActionDelete, Kind: plan.KindFolder, OrgID: orgID, Name: existing.Title, UID: folder.UID})
+			continue
+		}
+
+		fp.log.Info("Deleting folder", "uid", folder.UID, "orgId", orgID)
+		if err := bus.Dispatch(&models.DeleteDashboardCommand{Id: existing.Id, OrgId: orgID}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (fp *FolderProvisioner) mergeFolders(foldersToMerge []*folderFromConfig) error {
+	service := dashboards.NewProvisioningService()
+
+	for _, folder := range foldersToMerge {
+		orgID, err := utils.GetOrgID(folder.OrgID, folder.OrgName)
+		if err != nil {
+			return err
+		}
+
+		existing, err := getFolderByUID(orgID, folder.UID)
+		if err != nil {
+			if fp.plan != nil {
+				fp.plan.AddError(plan.KindFolder, err)
+				continue
+			}
+			return err
+		}
+
+		folderID := int64(0)
+		var changed []string
+		if existing != nil {
+			folderID = existing.Id
+			if existing.Title != folder.Title {
+				changed = append(changed, "title")
+			}
+		}
+
+		if fp.plan == nil && (existing == nil || existing.Title != folder.Title) {
+			fp.log.Debug("saving folder from configuration", "uid", folder.UID, "title", folder.Title, "orgId", orgID)
+			dto := &dashboards.SaveDashboardDTO{
+				OrgId:     orgID,
+				Dashboard: models.NewDashboardFolder(folder.Title),
+				Overwrite: true,
+			}
+			dto.Dashboard.OrgId = orgID
+			dto.Dashboard.SetUid(folder.UID)
+
+			saved, err := service.SaveFolderForProvisionedDashboards(dto)
+			if err != nil {
+				return fmt.Errorf("failed to save folder %q: %w", folder.UID, err)
+			}
+			folderID = saved.Id
+		}
+
+		if folder.ManagePermissions {
+			permissionsChanged, err := fp.updatePermissions(orgID, folderID, folder)
+			if err != nil {
+				return err
+			}
+			if permissionsChanged {
+				changed = append(changed, "permissions")
+			}
+		}
+
+		if fp.plan != nil {
+			if existing == nil {
+				fp.plan.Add(&plan.Change{Action: plan.ActionCreate, Kind: plan.KindFolder, OrgID: orgID, Name: folder.Title, UID: folder.UID})
+			} else if len(changed) > 0 {
+				fp.plan.Add(&plan.Change{
+					Action: plan.ActionUpdate, Kind: plan.KindFolder, OrgID: orgID, Name: folder.Title, UID: folder.UID, Fields: changed,
+				})
+			}
+		}
+	}
+
+	return nil
+}
+
+// updatePermissions replaces the permissions of the folder with the permissions of the config. It returns
+// true if the permissions change.
+func (fp *FolderProvisioner) updatePermissions(orgID, folderID int64, folder *folderFromConfig) (bool, error) {
+	now := time.Now()
+	items := make([]*models.DashboardAcl, 0, len(folder.Permissions))
+	// wanted are the permissions by role, team or user
+	wanted := map[string]models.PermissionType{}
+
+	for _, permission := range folder.Permissions {
+		item := &models.DashboardAcl{
+			OrgID:       orgID,
+			DashboardID: folderID,
+			Permission:  permission.Permission,
+			Created:     now,
+			Updated:     now,
+		}
+
+		switch {
+		case permission.Role != "":
+			role := models.RoleType(permission.Role)
+			item.Role = &role
+			wanted["role:"+permission.Role] = permission.Permission
+		case permission.Team != "":
+			team, err := utils.GetTeamByName(orgID, permission.Team)
+			if err != nil {
+				return false, err
+			}
+			exists := team != nil
+			// the plan doesn't apply the changes to teams, so the teams are as the plan leaves them
+			if fp.plan != nil {
+				if change := fp.plan.Find(plan.KindTeam, orgID, "", permission.Team); change != nil {
+					exists = change.Action != plan.ActionDelete
+				}
+			}
+			if !exists {
+				err := fmt.Errorf("failed to provision permissions of folder %q: team %q not found", folder.UID, permission.Team)
+				if fp.plan != nil {
+					fp.plan.AddError(plan.KindFolder, err)
+					continue
+				}
+				return false, err
+			}
+			if team != nil {
+				item.TeamID = team.Id
+			}
+			wanted["team:"+permission.Team] = permission.Permission
+		case permission.User != "":
+			query := &models.GetUserByLoginQuery{LoginOrEmail: permission.User}
+			if err := bus.Dispatch(query); err != nil {
+				if errors.Is(err, models.ErrUserNotFound) {
+					if fp.plan != nil {
+						fp.plan.AddWarning(plan.KindFolder, "permissions of folder %q refer to user %q, which doesn't exist", folder.UID, permission.User)
+					} else {
+						fp.log.Warn("Skipping folder permission of user that doesn't exist", "uid", folder.UID, "user", permission.User)
+					}
+					continue
+				}
+				return false, err
+			}
+			item.UserID = query.Result.Id
+			wanted["user:"+query.Result.Login] = permission.Permission
+		}
+
+		items = append(items, item)
+	}
+
+	if fp.plan != nil {
+		if folderID == 0 {
+			return true, nil
+		}
+		current, err := currentPermissions(orgID, folderID)
+		if err != nil {
+			return false, err
+		}
+		return !reflect.DeepEqual(current, wanted), nil
+	}
+
+	fp.log.Debug("updating folder permissions from configuration", "uid", folder.UID, "orgId", orgID)
+	return true, bus.Dispatch(&models.UpdateDashboardAclCommand{DashboardID: folderID, Items: items})
+}
+
+func (fp *FolderProvisioner) applyChanges(configPath string) error {
+	configs, err := fp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		if err := fp.apply(cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getFolderByUID returns the folder with the uid in the org, or nil if there is none.
+func getFolderByUID(orgID int64, uid string) (*models.Dashboard, error) {
+	query := &models.GetDashboardQuery{OrgId: orgID, Uid: uid}
+	if err := bus.Dispatch(query); err != nil {
+		if errors.Is(err, models.ErrDashboardNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	if !query.Result.IsFolder {
+		return nil, fmt.Errorf("failed to provision folder %q: uid is used by a dashboard", uid)
+	}
+	return query.Result, nil
+}
@@ -0,0 +1,161 @@
+package folders
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/dashboards"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const (
+	correctProperties  = "./testdata/test-configs/correct-properties"
+	invalidPermissions = "./testdata/test-configs/invalid-permissions"
+)
+
+func TestConfigReader(t *testing.T) {
+	t.Run("Invalid permissions should return error", func(t *testing.T) {
+		reader := &configReader{log: log.New("test logger")}
+		_, err := reader.readConfig(invalidPermissions)
+		require.Error(t, err)
+		assert.Equal(t, "Added folder item 1 in configuration has a permission with invalid role \"Admin\", must be Viewer or Editor\n"+
+			"Added folder item 1 in configuration has a permission that must contain exactly one of role, team or user\n"+
+			"Added folder item 2 in configuration doesn't contain required field uid", err.Error())
+	})
+
+	t.Run("Unknown permission should return error", func(t *testing.T) {
+		dir := t.TempDir()
+		config := "folders:\n  - uid: infra\n    title: Infra\n    permissions:\n      - role: Viewer\n        permission: Owner\n"
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "folders.yaml"), []byte(config), 0600))
+
+		reader := &configReader{log: log.New("test logger")}
+		_, err := reader.readConfig(dir)
+		require.Error(t, err)
+		assert.Equal(t, `folder "infra": invalid permission "Owner", must be one of View, Edit or Admin`, err.Error())
+	})
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		setFolderTitleVar(t)
+
+		reader := &configReader{log: log.New("test logger")}
+		cfg, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+		require.Len(t, cfg[0].Folders, 2)
+
+		infra := cfg[0].Folders[0]
+		assert.Equal(t, "infra", infra.UID)
+		assert.Equal(t, "Infrastructure", infra.Title)
+		assert.True(t, infra.ManagePermissions)
+		require.Len(t, infra.Permissions, 4)
+		assert.Equal(t, &permissionFromConfig{Role: "Viewer", Permission: models.PERMISSION_VIEW}, infra.Permissions[0])
+		assert.Equal(t, &permissionFromConfig{Team: "Backend", Permission: models.PERMISSION_EDIT}, infra.Permissions[1])
+		assert.Equal(t, &permissionFromConfig{User: "alice", Permission: models.PERMISSION_ADMIN}, infra.Permissions[2])
+
+		assert.False(t, cfg[0].Folders[1].ManagePermissions)
+		require.Len(t, cfg[0].DeleteFolders, 1)
+		assert.Equal(t, "old", cfg[0].DeleteFolders[0].UID)
+	})
+}
+
+func TestFolderProvisioner(t *testing.T) {
+	sqlstore.InitTestDB(t)
+	setFolderTitleVar(t)
+
+	bus.AddHandler("test", func(cmd *models.UpdateDashboardAlertsCommand) error {
+		return nil
+	})
+
+	org := &models.CreateOrgCommand{Name: "Main Org."}
+	require.NoError(t, sqlstore.CreateOrg(org))
+	orgID := org.Result.Id
+
+	user := &models.CreateUserCommand{Login: "alice", SkipOrgSetup: true}
+	require.NoError(t, sqlstore.CreateUser(context.Background(), user))
+
+	team := &models.CreateTeamCommand{OrgId: orgID, Name: "Backend"}
+	require.NoError(t, bus.Dispatch(team))
+
+	service := dashboards.NewProvisioningService()
+	for uid, title := range map[string]string{"old": "Old", "renamed": "Previous title"} {
+		dto := &dashboards.SaveDashboardDTO{OrgId: orgID, Dashboard: models.NewDashboardFolder(title)}
+		dto.Dashboard.OrgId = orgID
+		dto.Dashboard.SetUid(uid)
+		_, err := service.SaveFolderForProvisionedDashboards(dto)
+		require.NoError(t, err)
+	}
+
+	provisioner := newFolderProvisioner(log.New("test logger"))
+	require.NoError(t, provisioner.applyChanges(correctProperties))
+	// applying the config again doesn't change anything
+	require.NoError(t, provisioner.applyChanges(correctProperties))
+
+	old, err := getFolderByUID(orgID, "old")
+	require.NoError(t, err)
+	assert.Nil(t, old)
+
+	renamed, err := getFolderByUID(orgID, "renamed")
+	require.NoError(t, err)
+	require.NotNil(t, renamed)
+	assert.Equal(t, "Renamed", renamed.Title)
+
+	infra, err := getFolderByUID(orgID, "infra")
+	require.NoError(t, err)
+	require.NotNil(t, infra)
+	assert.Equal(t, "Infrastructure", infra.Title)
+	assert.Equal(t, 1, infra.Version)
+	assert.True(t, infra.HasAcl)
+
+	query := &models.GetDashboardAclInfoListQuery{OrgID: orgID, DashboardID: infra.Id}
+	require.NoError(t, bus.Dispatch(query))
+
+	var permissions []string
+	for _, item := range query.Result {
+		if item.DashboardId != infra.Id {
+			continue
+		}
+		switch {
+		case item.Role != nil:
+			permissions = append(permissions, string(*item.Role)+":"+item.PermissionName)
+		case item.TeamId != 0:
+			permissions = append(permissions, item.Team+":"+item.PermissionName)
+		default:
+			permissions = append(permissions, item.UserLogin+":"+item.PermissionName)
+		}
+	}
+	assert.ElementsMatch(t, []string{"Viewer:View", "Backend:Edit", "alice:Admin"}, permissions)
+}
+
+func TestFolderProvisionerRejectsDashboardUIDs(t *testing.T) {
+	sqlstore.InitTestDB(t)
+	setFolderTitleVar(t)
+
+	org := &models.CreateOrgCommand{Name: "Main Org."}
+	require.NoError(t, sqlstore.CreateOrg(org))
+
+	dash := models.NewDashboard("Infra dashboard")
+	dash.SetUid("infra")
+	require.NoError(t, bus.Dispatch(&models.SaveDashboardCommand{OrgId: org.Result.Id, Dashboard: dash.Data}))
+
+	provisioner := newFolderProvisioner(log.New("test logger"))
+	err := provisioner.applyChanges(correctProperties)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "uid is used by a dashboard")
+}
+
+func setFolderTitleVar(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, os.Setenv("FOLDER_TITLE", "Infrastructure"))
+	t.Cleanup(func() {
+		_ = os.Unsetenv("FOLDER_TITLE")
+	})
+}
@@ -0,0 +1,40 @@
+package folders
+
+import (
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// Plan adds the folders that provisioning would create, update or delete to the plan. Teams referred to by
+// permissions have to exist, or be created by the plan.
+func Plan(configDirectory string, p *plan.Plan) error {
+	fp := newFolderProvisioner(log.New("provisioning.folders"))
+	fp.plan = p
+	return fp.applyChanges(configDirectory)
+}
+
+// currentPermissions returns the permissions of the folder by role, team or user.
+func currentPermissions(orgID, folderID int64) (map[string]models.PermissionType, error) {
+	query := &models.GetDashboardAclInfoListQuery{OrgID: orgID, DashboardID: folderID}
+	if err := bus.Dispatch(query); err != nil {
+		return nil, err
+	}
+
+	permissions := map[string]models.PermissionType{}
+	for _, acl := range query.Result {
+		if acl.DashboardId != folderID {
+			continue
+		}
+		switch {
+		case acl.Role != nil:
+			permissions["role:"+string(*acl.Role)] = acl.Permission
+		case acl.TeamId != 0:
+			permissions["team:"+acl.Team] = acl.Permission
+		case acl.UserId != 0:
+			permissions["user:"+acl.UserLogin] = acl.Permission
+		}
+	}
+	return permissions, nil
+}
@@ -0,0 +1,122 @@
+package folders
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/values"
+)
+
+// foldersAsConfig is a normalized data object for folders config data. Any config version should be mappable
+// to this type.
+type foldersAsConfig struct {
+	Folders       []*folderFromConfig
+	DeleteFolders []*deleteFolderConfig
+}
+
+type folderFromConfig struct {
+	OrgID   int64
+	OrgName string
+	UID     string
+	Title   string
+	// Permissions replace the permissions of the folder. The permissions of the folder are
+	// only synced if the permissions are part of the config.
+	Permissions       []*permissionFromConfig
+	ManagePermissions bool
+}
+
+type permissionFromConfig struct {
+	Role       string
+	Team       string
+	User       string
+	Permission models.PermissionType
+}
+
+type deleteFolderConfig struct {
+	OrgID   int64
+	OrgName string
+	UID     string
+}
+
+// foldersAsConfigV1 is a mapping for version one configs. This is mapped to its normalised version.
+type foldersAsConfigV1 struct {
+	Folders       []*folderFromConfigV1   `json:"folders" yaml:"folders"`
+	DeleteFolders []*deleteFolderConfigV1 `json:"deleteFolders" yaml:"deleteFolders"`
+}
+
+type folderFromConfigV1 struct {
+	OrgID       values.Int64Value         `json:"orgId" yaml:"orgId"`
+	OrgName     values.StringValue        `json:"orgName" yaml:"orgName"`
+	UID         values.StringValue        `json:"uid" yaml:"uid"`
+	Title       values.StringValue        `json:"title" yaml:"title"`
+	Permissions []*permissionFromConfigV1 `json:"permissions" yaml:"permissions"`
+}
+
+type permissionFromConfigV1 struct {
+	Role       values.StringValue `json:"role" yaml:"role"`
+	Team       values.StringValue `json:"team" yaml:"team"`
+	User       values.StringValue `json:"user" yaml:"user"`
+	Permission values.StringValue `json:"permission" yaml:"permission"`
+}
+
+type deleteFolderConfigV1 struct {
+	OrgID   values.Int64Value  `json:"orgId" yaml:"orgId"`
+	OrgName values.StringValue `json:"orgName" yaml:"orgName"`
+	UID     values.StringValue `json:"uid" yaml:"uid"`
+}
+
+// mapToFoldersFromConfig maps config syntax to a normalized foldersAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *foldersAsConfigV1) mapToFoldersFromConfig() (*foldersAsConfig, error) {
+	r := &foldersAsConfig{}
+	if cfg == nil {
+		return r, nil
+	}
+
+	for _, folder := range cfg.Folders {
+		f := &folderFromConfig{
+			OrgID:             folder.OrgID.Value(),
+			OrgName:           folder.OrgName.Value(),
+			UID:               folder.UID.Value(),
+			Title:             folder.Title.Value(),
+			ManagePermissions: folder.Permissions != nil,
+		}
+
+		for _, permission := range folder.Permissions {
+			p, err := parsePermission(permission.Permission.Value())
+			if err != nil {
+				return nil, fmt.Errorf("folder %q: %w", f.UID, err)
+			}
+
+			f.Permissions = append(f.Permissions, &permissionFromConfig{
+				Role:       permission.Role.Value(),
+				Team:       permission.Team.Value(),
+				User:       permission.User.Value(),
+				Permission: p,
+			})
+		}
+
+		r.Folders = append(r.Folders, f)
+	}
+
+	for _, folder := range cfg.DeleteFolders {
+		r.DeleteFolders = append(r.DeleteFolders, &deleteFolderConfig{
+			OrgID:   folder.OrgID.Value(),
+			OrgName: folder.OrgName.Value(),
+			UID:     folder.UID.Value(),
+		})
+	}
+
+	return r, nil
+}
+
+// parsePermission parses the name of a permission, like View, Edit or Admin.
+func parsePermission(name string) (models.PermissionType, error) {
+	for _, p := range []models.PermissionType{models.PERMISSION_VIEW, models.PERMISSION_EDIT, models.PERMISSION_ADMIN} {
+		if strings.EqualFold(name, p.String()) {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid permission %q, must be one of View, Edit or Admin", name)
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
 )
 
 // Provision alert notifiers
@@ -16,6 +17,8 @@ func Provision(configDirectory string) error {
 type NotificationProvisioner struct {
 	log         log.Logger
 	cfgProvider *configReader
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
 }
 
 func newNotificationProvisioner(log log.Logger) NotificationProvisioner {
@@ -39,8 +42,6 @@ func (dc *NotificationProvisioner) apply(cfg *notificationsAsConfig) error {
 
 func (dc *NotificationProvisioner) deleteNotifications(notificationToDelete []*deleteNotificationConfig) error {
 	for _, notification := range notificationToDelete {
-		dc.log.Info("Deleting alert notification", "name", notification.Name, "uid", notification.UID)
-
 		if notification.OrgID == 0 && notification.OrgName != "" {
 			getOrg := &models.GetOrgByNameQuery{Name: notification.OrgName}
 			if err := bus.Dispatch(getOrg); err != nil {
@@ -58,6 +59,14 @@ func (dc *NotificationProvisioner) deleteNotifications(notificationToDelete []*d
 		}
 
 		if getNotification.Result != nil {
+			if dc.plan != nil {
+				dc.plan.Add(&plan.Change{
+					Action: plan.ActionDelete, Kind: plan.KindNotifier, OrgID: notification.OrgID, Name: getNotification.Result.Name, UID: notification.UID,
+				})
+				continue
+			}
+
+			dc.log.Info("Deleting alert notification", "name", notification.Name, "uid", notification.UID)
 			cmd := &models.DeleteAlertNotificationWithUidCommand{Uid: getNotification.Result.Uid, OrgId: getNotification.OrgId}
 			if err := bus.Dispatch(cmd); err != nil {
 				return err
@@ -87,6 +96,11 @@ func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*not
 		}
 
 		if cmd.Result == nil {
+			if dc.plan != nil {
+				dc.plan.Add(&plan.Change{Action: plan.ActionCreate, Kind: plan.KindNotifier, OrgID: notification.OrgID, Name: notification.Name, UID: notification.UID})
+				continue
+			}
+
 			dc.log.Debug("inserting alert notification from configuration", "name", notification.Name, "uid", notification.UID)
 			insertCmd := &models.CreateAlertNotificationCommand{
 				Uid:                   notification.UID,
@@ -105,7 +119,6 @@ func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*not
 				return err
 			}
 		} else {
-			dc.log.Debug("updating alert notification from configuration", "name", notification.Name)
 			updateCmd := &models.UpdateAlertNotificationWithUidCommand{
 				Uid:                   notification.UID,
 				Name:                  notification.Name,
@@ -118,7 +131,12 @@ func (dc *NotificationProvisioner) mergeNotifications(notificationToMerge []*not
 				Frequency:             notification.Frequency,
 				SendReminder:          notification.SendReminder,
 			}
+			if dc.plan != nil {
+				planUpdate(dc.plan, cmd.Result, updateCmd)
+				continue
+			}
 
+			dc.log.Debug("updating alert notification from configuration", "name", notification.Name)
 			if err := bus.Dispatch(updateCmd); err != nil {
 				return err
 			}
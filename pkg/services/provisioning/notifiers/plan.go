@@ -0,0 +1,58 @@
+package notifiers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// Plan adds the alert notifications that provisioning would create, update or delete to the plan. The
+// settings of the notifiers are validated when the files are read.
+func Plan(configDirectory string, p *plan.Plan) error {
+	dc := newNotificationProvisioner(log.New("provisioning.notifiers"))
+	dc.plan = p
+	return dc.applyChanges(configDirectory)
+}
+
+// planUpdate adds the update of an alert notification to the plan, if the update command changes any of
+// its fields.
+func planUpdate(p *plan.Plan, current *models.AlertNotification, cmd *models.UpdateAlertNotificationWithUidCommand) {
+	var frequency time.Duration
+	if cmd.Frequency != "" {
+		var err error
+		if frequency, err = time.ParseDuration(cmd.Frequency); err != nil {
+			p.AddError(plan.KindNotifier, fmt.Errorf("alert notification %q has an invalid frequency: %w", cmd.Name, err))
+			return
+		}
+	}
+
+	var settings, wantedSettings map[string]interface{}
+	if current.Settings != nil {
+		settings = current.Settings.MustMap()
+	}
+	if cmd.Settings != nil {
+		wantedSettings = cmd.Settings.MustMap()
+	}
+
+	d := &plan.Diff{}
+	d.Compare("name", current.Name, cmd.Name)
+	d.Compare("type", current.Type, cmd.Type)
+	d.Compare("is_default", current.IsDefault, cmd.IsDefault)
+	d.Compare("send_reminder", current.SendReminder, cmd.SendReminder)
+	d.Compare("disable_resolve_message", current.DisableResolveMessage, cmd.DisableResolveMessage)
+	if cmd.SendReminder {
+		d.Compare("frequency", current.Frequency, frequency)
+	}
+	d.CompareJSON("settings", settings, wantedSettings)
+	d.CompareJSON("secure_settings", current.SecureSettings.Decrypt(), cmd.SecureSettings)
+	if len(d.Fields) == 0 {
+		return
+	}
+
+	p.Add(&plan.Change{
+		Action: plan.ActionUpdate, Kind: plan.KindNotifier, OrgID: cmd.OrgId, Name: cmd.Name, UID: cmd.Uid, Fields: d.Fields,
+	})
+}
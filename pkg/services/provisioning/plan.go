@@ -0,0 +1,49 @@
+package provisioning
+
+import (
+	"path/filepath"
+
+	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
+	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/folders"
+	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/preferences"
+	"github.com/grafana/grafana/pkg/services/provisioning/teams"
+)
+
+type planner struct {
+	kind string
+	dir  string
+	plan func(string, *plan.Plan) error
+}
+
+// planners returns the planners in the same order as the provisioning is applied, so that later kinds
+// can refer to entities that are created by earlier ones.
+func planners(dataPath string) []planner {
+	return []planner{
+		{plan.KindDataSource, "datasources", datasources.Plan},
+		{plan.KindPlugin, "plugins", plugins.Plan},
+		{plan.KindNotifier, "notifiers", notifiers.Plan},
+		{plan.KindTeam, "teams", teams.Plan},
+		{plan.KindFolder, "folders", folders.Plan},
+		{plan.KindDashboard, "dashboards", func(configDirectory string, p *plan.Plan) error {
+			return dashboards.Plan(configDirectory, dataPath, p)
+		}},
+		{plan.KindPreferences, "preferences", preferences.Plan},
+	}
+}
+
+// Plan reads all provisioning config files of the provisioning path and returns the changes that
+// provisioning them would apply, without applying them. Invalid config files are reported as
+// errors of the plan. Git repositories of dashboard providers are fetched into the data path.
+func Plan(provisioningPath, dataPath string) *plan.Plan {
+	p := plan.New()
+	for _, planner := range planners(dataPath) {
+		if err := planner.plan(filepath.Join(provisioningPath, planner.dir), p); err != nil {
+			p.AddError(planner.kind, err)
+		}
+	}
+	return p
+}
@@ -0,0 +1,184 @@
+// Package plan describes the changes that provisioning would apply to the database, so that provisioning
+// files can be validated without applying them.
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// Action is the kind of change applied to an entity.
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	ActionDelete Action = "delete"
+)
+
+// Kinds of provisioned entities.
+const (
+	KindDataSource  = "datasource"
+	KindPlugin      = "plugin"
+	KindNotifier    = "notifier"
+	KindTeam        = "team"
+	KindFolder      = "folder"
+	KindDashboard   = "dashboard"
+	KindPreferences = "preferences"
+)
+
+// Change is a change that provisioning would apply to an entity.
+type Change struct {
+	Action Action `json:"action"`
+	Kind   string `json:"kind"`
+	OrgID  int64  `json:"orgId"`
+	Name   string `json:"name"`
+	UID    string `json:"uid,omitempty"`
+	// Fields lists the fields that are changed by an update.
+	Fields []string `json:"fields,omitempty"`
+	// Source is the file the change comes from, if it isn't a configuration file.
+	Source string `json:"source,omitempty"`
+}
+
+// Plan holds the changes that provisioning would apply, and the problems found in the configuration.
+type Plan struct {
+	Changes  []*Change `json:"changes"`
+	Errors   []string  `json:"errors"`
+	Warnings []string  `json:"warnings"`
+}
+
+// New returns an empty plan.
+func New() *Plan {
+	return &Plan{Changes: []*Change{}, Errors: []string{}, Warnings: []string{}}
+}
+
+// Add adds a change to the plan.
+func (p *Plan) Add(change *Change) {
+	p.Changes = append(p.Changes, change)
+}
+
+// AddError records a problem that would make provisioning fail or skip an entity.
+func (p *Plan) AddError(kind string, err error) {
+	p.Errors = append(p.Errors, fmt.Sprintf("%s: %s", kind, err))
+}
+
+// AddWarning records a problem that provisioning would only log.
+func (p *Plan) AddWarning(kind string, format string, args ...interface{}) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf("%s: %s", kind, fmt.Sprintf(format, args...)))
+}
+
+// HasErrors returns true if the configuration has problems.
+func (p *Plan) HasErrors() bool {
+	return len(p.Errors) > 0
+}
+
+// Find returns the change of the entity with the given uid, or name if the uid is empty, or nil if the plan
+// doesn't change the entity.
+func (p *Plan) Find(kind string, orgID int64, uid, name string) *Change {
+	for _, c := range p.Changes {
+		if c.Kind != kind || c.OrgID != orgID {
+			continue
+		}
+		if (uid != "" && c.UID == uid) || (uid == "" && c.Name == name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Creates returns true if the plan creates or updates the entity, so that other entities can refer to it.
+func (p *Plan) Creates(kind string, orgID int64, uid, name string) bool {
+	c := p.Find(kind, orgID, uid, name)
+	return c != nil && c.Action != ActionDelete
+}
+
+// Write writes the plan in a human readable format.
+func (p *Plan) Write(w io.Writer) error {
+	var b strings.Builder
+
+	counts := map[Action]int{}
+	for _, c := range p.Changes {
+		counts[c.Action]++
+
+		symbol := map[Action]string{ActionCreate: "+", ActionUpdate: "~", ActionDelete: "-"}[c.Action]
+		fmt.Fprintf(&b, "%s %s %q (org %d", symbol, c.Kind, c.Name, c.OrgID)
+		if c.UID != "" {
+			fmt.Fprintf(&b, ", uid %s", c.UID)
+		}
+		b.WriteString(")")
+		if len(c.Fields) > 0 {
+			fmt.Fprintf(&b, ": %s", strings.Join(c.Fields, ", "))
+		}
+		if c.Source != "" {
+			fmt.Fprintf(&b, " from %s", c.Source)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(p.Changes) == 0 {
+		b.WriteString("No changes.\n")
+	}
+	fmt.Fprintf(&b, "\nPlan: %d to create, %d to update, %d to delete.\n", counts[ActionCreate], counts[ActionUpdate], counts[ActionDelete])
+
+	for _, warning := range p.Warnings {
+		fmt.Fprintf(&b, "Warning: %s\n", warning)
+	}
+	for _, err := range p.Errors {
+		fmt.Fprintf(&b, "Error: %s\n", err)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Diff collects the fields that differ between an entity and its configuration.
+type Diff struct {
+	Fields []string
+}
+
+// Compare adds the field if the values differ.
+func (d *Diff) Compare(field string, current, wanted interface{}) {
+	if !reflect.DeepEqual(current, wanted) {
+		d.Fields = append(d.Fields, field)
+	}
+}
+
+// CompareJSON adds the field if the json representations of the values differ, treating nil and empty values
+// as equal. Values read from the database and from configuration files can have different go types, such as
+// integers and floats, so they are compared after a round trip through json.
+func (d *Diff) CompareJSON(field string, current, wanted interface{}) {
+	if !reflect.DeepEqual(normalizeJSON(current), normalizeJSON(wanted)) {
+		d.Fields = append(d.Fields, field)
+	}
+}
+
+func normalizeJSON(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+
+	switch v := normalized.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+	}
+	return normalized
+}
@@ -0,0 +1,61 @@
+package plan
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	t.Run("Writes changes, warnings and errors", func(t *testing.T) {
+		p := New()
+		p.Add(&Change{Action: ActionCreate, Kind: KindDataSource, OrgID: 1, Name: "Prometheus", UID: "prom"})
+		p.Add(&Change{Action: ActionUpdate, Kind: KindDashboard, OrgID: 1, Name: "Home", Fields: []string{"title"}, Source: "/dashboards/home.json"})
+		p.Add(&Change{Action: ActionDelete, Kind: KindTeam, OrgID: 2, Name: "Ops"})
+		p.AddWarning(KindTeam, "member %q doesn't exist", "bob")
+		p.AddError(KindDataSource, errors.New("unknown type"))
+
+		var b strings.Builder
+		require.NoError(t, p.Write(&b))
+		assert.Equal(t, `+ datasource "Prometheus" (org 1, uid prom)
+~ dashboard "Home" (org 1): title from /dashboards/home.json
+- team "Ops" (org 2)
+
+Plan: 1 to create, 1 to update, 1 to delete.
+Warning: team: member "bob" doesn't exist
+Error: datasource: unknown type
+`, b.String())
+		assert.True(t, p.HasErrors())
+	})
+
+	t.Run("Writes an empty plan", func(t *testing.T) {
+		var b strings.Builder
+		require.NoError(t, New().Write(&b))
+		assert.Equal(t, "No changes.\n\nPlan: 0 to create, 0 to update, 0 to delete.\n", b.String())
+	})
+
+	t.Run("Finds changes by uid or name", func(t *testing.T) {
+		p := New()
+		p.Add(&Change{Action: ActionCreate, Kind: KindDashboard, OrgID: 1, Name: "Home", UID: "home"})
+		p.Add(&Change{Action: ActionDelete, Kind: KindTeam, OrgID: 1, Name: "Ops"})
+
+		assert.True(t, p.Creates(KindDashboard, 1, "home", ""))
+		assert.False(t, p.Creates(KindDashboard, 2, "home", ""))
+		assert.False(t, p.Creates(KindTeam, 1, "", "Ops"))
+		assert.NotNil(t, p.Find(KindTeam, 1, "", "Ops"))
+	})
+}
+
+func TestDiff(t *testing.T) {
+	d := &Diff{}
+	d.Compare("name", "a", "a")
+	d.Compare("url", "http://a", "http://b")
+	d.CompareJSON("jsonData", map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1.0})
+	d.CompareJSON("secureJsonData", map[string]string{}, nil)
+	d.CompareJSON("settings", map[string]interface{}{"a": 1}, map[string]interface{}{"a": 2})
+
+	assert.Equal(t, []string{"url", "settings"}, d.Fields)
+}
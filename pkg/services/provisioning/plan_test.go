@@ -0,0 +1,115 @@
+package provisioning
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/plugins/manager"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+func TestPlan(t *testing.T) {
+	setup := func(t *testing.T) string {
+		t.Helper()
+		sqlstore.InitTestDB(t)
+		require.NoError(t, sqlstore.CreateOrg(&models.CreateOrgCommand{Name: "Main Org."}))
+
+		origDataSources := manager.DataSources
+		t.Cleanup(func() { manager.DataSources = origDataSources })
+		manager.DataSources = map[string]*plugins.DataSourcePlugin{"prometheus": {}, "loki": {}}
+
+		require.NoError(t, bus.Dispatch(&models.AddDataSourceCommand{
+			OrgId: 1, Name: "Prometheus", Type: "prometheus", Access: models.DS_ACCESS_PROXY, Url: "http://prometheus:9090", Uid: "prometheus",
+		}))
+		require.NoError(t, bus.Dispatch(&models.AddDataSourceCommand{
+			OrgId: 1, Name: "Old", Type: "prometheus", Access: models.DS_ACCESS_PROXY, Url: "http://old:9090", Uid: "old",
+		}))
+		require.NoError(t, bus.Dispatch(&models.SaveDashboardCommand{OrgId: 1, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"uid": "team", "title": "Team",
+		})}))
+
+		return t.TempDir()
+	}
+
+	writeFile := func(t *testing.T, path, content string) {
+		t.Helper()
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0750))
+		require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	}
+
+	t.Run("Plans data source changes", func(t *testing.T) {
+		dir := setup(t)
+		writeFile(t, filepath.Join(dir, "datasources", "datasources.yaml"), `
+apiVersion: 1
+deleteDatasources:
+  - name: Old
+  - name: Missing
+datasources:
+  - name: Prometheus
+    type: prometheus
+    access: proxy
+    url: http://prometheus:9091
+    editable: true
+  - name: Loki
+    type: loki
+    access: proxy
+    url: http://loki:3100
+  - name: Graphite
+    type: graphite
+    access: proxy
+`)
+
+		p := Plan(dir, t.TempDir())
+		assert.Equal(t, []*plan.Change{
+			{Action: plan.ActionDelete, Kind: plan.KindDataSource, OrgID: 1, Name: "Old", UID: "old"},
+			{Action: plan.ActionUpdate, Kind: plan.KindDataSource, OrgID: 1, Name: "Prometheus", UID: "prometheus", Fields: []string{"url"}},
+			{Action: plan.ActionCreate, Kind: plan.KindDataSource, OrgID: 1, Name: "Loki"},
+			{Action: plan.ActionCreate, Kind: plan.KindDataSource, OrgID: 1, Name: "Graphite"},
+		}, p.Changes)
+		require.Len(t, p.Errors, 1)
+		assert.Contains(t, p.Errors[0], "graphite")
+
+		query := &models.GetDataSourceQuery{OrgId: 1, Name: "Old"}
+		require.NoError(t, bus.Dispatch(query), "the plan shouldn't apply the changes")
+	})
+
+	t.Run("Reports dashboard uid collisions and folders that are dashboards", func(t *testing.T) {
+		dir := setup(t)
+		dashboardsPath := filepath.Join(dir, "files")
+		writeFile(t, filepath.Join(dir, "dashboards", "dashboards.yaml"), `
+apiVersion: 1
+providers:
+  - name: default
+    folder: Services
+    options:
+      path: `+filepath.Join(dashboardsPath, "services")+`
+  - name: team
+    folder: Team
+    options:
+      path: `+filepath.Join(dashboardsPath, "team")+`
+`)
+		writeFile(t, filepath.Join(dashboardsPath, "services", "a.json"), `{"uid": "services", "title": "Services"}`)
+		writeFile(t, filepath.Join(dashboardsPath, "services", "b.json"), `{"uid": "services", "title": "Services copy"}`)
+		writeFile(t, filepath.Join(dashboardsPath, "team", "c.json"), `{"uid": "team-overview", "title": "Team overview"}`)
+
+		p := Plan(dir, t.TempDir())
+		assert.Equal(t, []*plan.Change{
+			{Action: plan.ActionCreate, Kind: plan.KindFolder, OrgID: 1, Name: "Services"},
+			{Action: plan.ActionCreate, Kind: plan.KindDashboard, OrgID: 1, Name: "Services", UID: "services",
+				Source: filepath.Join(dashboardsPath, "services", "a.json")},
+		}, p.Changes)
+		require.Len(t, p.Errors, 2)
+		assert.Contains(t, p.Errors[0], `the uid "services" is used by both`)
+		assert.Contains(t, p.Errors[1], `folder "Team" of provider "team" is a dashboard`)
+	})
+}
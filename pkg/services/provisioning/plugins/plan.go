@@ -0,0 +1,33 @@
+package plugins
+
+import (
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// Plan adds the app settings that provisioning would create or change to the plan. Apps that aren't
+// installed are rejected when the files are read.
+func Plan(configDirectory string, p *plan.Plan) error {
+	ap := newAppProvisioner(log.New("provisioning.plugins"))
+	ap.plan = p
+	return ap.applyChanges(configDirectory)
+}
+
+// planUpdate adds the settings of an app to the plan, if they don't exist yet or if the update command
+// changes them.
+func planUpdate(p *plan.Plan, current *models.PluginSetting, cmd *models.UpdatePluginSettingCmd) {
+	if current == nil {
+		p.Add(&plan.Change{Action: plan.ActionCreate, Kind: plan.KindPlugin, OrgID: cmd.OrgId, Name: cmd.PluginId})
+		return
+	}
+
+	d := &plan.Diff{}
+	d.Compare("enabled", current.Enabled, cmd.Enabled)
+	d.Compare("pinned", current.Pinned, cmd.Pinned)
+	d.CompareJSON("jsonData", current.JsonData, cmd.JsonData)
+	d.CompareJSON("secureJsonData", current.SecureJsonData.Decrypt(), cmd.SecureJsonData)
+	if len(d.Fields) > 0 {
+		p.Add(&plan.Change{Action: plan.ActionUpdate, Kind: plan.KindPlugin, OrgID: cmd.OrgId, Name: cmd.PluginId, Fields: d.Fields})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
 )
 
 // Provision scans a directory for provisioning config files
@@ -20,6 +21,8 @@ func Provision(configDirectory string) error {
 type PluginProvisioner struct {
 	log         log.Logger
 	cfgProvider configReader
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
 }
 
 func newAppProvisioner(log log.Logger) PluginProvisioner {
@@ -51,7 +54,6 @@ func (ap *PluginProvisioner) apply(cfg *pluginsAsConfig) error {
 			app.PluginVersion = query.Result.PluginVersion
 		}
 
-		ap.log.Info("Updating app from configuration ", "type", app.PluginID, "enabled", app.Enabled)
 		cmd := &models.UpdatePluginSettingCmd{
 			OrgId:          app.OrgID,
 			PluginId:       app.PluginID,
@@ -61,6 +63,12 @@ func (ap *PluginProvisioner) apply(cfg *pluginsAsConfig) error {
 			SecureJsonData: app.SecureJSONData,
 			PluginVersion:  app.PluginVersion,
 		}
+		if ap.plan != nil {
+			planUpdate(ap.plan, query.Result, cmd)
+			continue
+		}
+
+		ap.log.Info("Updating app from configuration ", "type", app.PluginID, "enabled", app.Enabled)
 		if err := bus.Dispatch(cmd); err != nil {
 			return err
 		}
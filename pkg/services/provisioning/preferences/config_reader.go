@@ -0,0 +1,100 @@
+package preferences
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"gopkg.in/yaml.v2"
+)
+
+var validThemes = map[string]bool{"": true, "light": true, "dark": true}
+
+var validTimezones = map[string]bool{"": true, "browser": true, "utc": true}
+
+type configReader struct {
+	log log.Logger
+}
+
+func (cr *configReader) readConfig(path string) ([]*preferencesAsConfig, error) {
+	var preferences []*preferencesAsConfig
+	cr.log.Debug("Looking for preferences provisioning files", "path", path)
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read preferences provisioning files from directory", "path", path, "error", err)
+		return preferences, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			cr.log.Debug("Parsing preferences provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parsePreferencesConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg != nil {
+				preferences = append(preferences, cfg)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating preferences")
+	if err := validatePreferences(preferences); err != nil {
+		return nil, err
+	}
+
+	return preferences, nil
+}
+
+func (cr *configReader) parsePreferencesConfig(path string, file os.FileInfo) (*preferencesAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *preferencesAsConfigV1
+	if err := yaml.Unmarshal(yamlFile, &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg.mapToPreferencesFromConfig(), nil
+}
+
+func validatePreferences(preferences []*preferencesAsConfig) error {
+	for i := range preferences {
+		var errStrings []string
+		for index, prefs := range preferences[i].Preferences {
+			if !validThemes[prefs.Theme] {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Preferences item %d in configuration has invalid theme %q, must be light or dark", index+1, prefs.Theme),
+				)
+			}
+
+			if !validTimezones[prefs.Timezone] {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Preferences item %d in configuration has invalid timezone %q, must be browser or utc", index+1, prefs.Timezone),
+				)
+			}
+		}
+
+		if len(errStrings) != 0 {
+			return fmt.Errorf(strings.Join(errStrings, "\n"))
+		}
+	}
+
+	return nil
+}
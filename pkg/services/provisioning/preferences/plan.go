@@ -0,0 +1,39 @@
+package preferences
+
+import (
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// Plan adds the org preferences that provisioning would change to the plan. The home dashboard has to
+// exist, or be created by the plan.
+func Plan(configDirectory string, p *plan.Plan) error {
+	pp := newPreferencesProvisioner(log.New("provisioning.preferences"))
+	pp.plan = p
+	return pp.applyChanges(configDirectory)
+}
+
+// planUpdate adds the update of the preferences of an org to the plan, if the save command changes them.
+// The home dashboard always changes if it's created by the plan.
+func planUpdate(p *plan.Plan, cmd *models.SavePreferencesCommand, homeDashboardPlanned bool) error {
+	query := &models.GetPreferencesQuery{OrgId: cmd.OrgId}
+	if err := bus.Dispatch(query); err != nil {
+		return err
+	}
+
+	d := &plan.Diff{}
+	d.Compare("theme", query.Result.Theme, cmd.Theme)
+	d.Compare("timezone", query.Result.Timezone, cmd.Timezone)
+	if homeDashboardPlanned {
+		d.Fields = append(d.Fields, "homeDashboardUid")
+	} else {
+		d.Compare("homeDashboardUid", query.Result.HomeDashboardId, cmd.HomeDashboardId)
+	}
+
+	if len(d.Fields) > 0 {
+		p.Add(&plan.Change{Action: plan.ActionUpdate, Kind: plan.KindPreferences, OrgID: cmd.OrgId, Name: "org preferences", Fields: d.Fields})
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package preferences
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// Provision scans a directory for provisioning config files
+// and provisions the org preferences in those files.
+func Provision(configDirectory string) error {
+	pp := newPreferencesProvisioner(log.New("provisioning.preferences"))
+	return pp.applyChanges(configDirectory)
+}
+
+// PreferencesProvisioner is responsible for provisioning org preferences based on
+// configuration read by the `configReader`
+type PreferencesProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
+}
+
+func newPreferencesProvisioner(log log.Logger) PreferencesProvisioner {
+	return PreferencesProvisioner{
+		log:         log,
+		cfgProvider: &configReader{log: log},
+	}
+}
+
+func (pp *PreferencesProvisioner) apply(cfg *preferencesAsConfig) error {
+	for _, prefs := range cfg.Preferences {
+		orgID, err := utils.GetOrgID(prefs.OrgID, prefs.OrgName)
+		if err != nil {
+			return err
+		}
+
+		var homeDashboardID int64
+		// set when the home dashboard doesn't exist yet, but is created by the plan
+		homeDashboardPlanned := false
+		if prefs.HomeDashboardUID != "" {
+			query := &models.GetDashboardQuery{OrgId: orgID, Uid: prefs.HomeDashboardUID}
+			if err := bus.Dispatch(query); err != nil {
+				if !errors.Is(err, models.ErrDashboardNotFound) {
+					return err
+				}
+
+				homeDashboardPlanned = pp.plan != nil && pp.plan.Creates(plan.KindDashboard, orgID, prefs.HomeDashboardUID, "")
+				if !homeDashboardPlanned {
+					err := fmt.Errorf("failed to provision preferences of org %d: home dashboard %q not found", orgID, prefs.HomeDashboardUID)
+					if pp.plan != nil {
+						pp.plan.AddError(plan.KindPreferences, err)
+						continue
+					}
+					return err
+				}
+			} else {
+				homeDashboardID = query.Result.Id
+			}
+		}
+
+		cmd := &models.SavePreferencesCommand{
+			OrgId:           orgID,
+			Theme:           prefs.Theme,
+			Timezone:        prefs.Timezone,
+			HomeDashboardId: homeDashboardID,
+		}
+		if pp.plan != nil {
+			if err := planUpdate(pp.plan, cmd, homeDashboardPlanned); err != nil {
+				return err
+			}
+			continue
+		}
+
+		pp.log.Debug("updating org preferences from configuration", "orgId", orgID)
+		if err := bus.Dispatch(cmd); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (pp *PreferencesProvisioner) applyChanges(configPath string) error {
+	configs, err := pp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		if err := pp.apply(cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
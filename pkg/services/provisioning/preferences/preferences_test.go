@@ -0,0 +1,90 @@
+package preferences
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const (
+	correctProperties = "./testdata/test-configs/correct-properties"
+	invalidTheme      = "./testdata/test-configs/invalid-theme"
+)
+
+func TestConfigReader(t *testing.T) {
+	t.Run("Invalid preferences should return error", func(t *testing.T) {
+		reader := &configReader{log: log.New("test logger")}
+		_, err := reader.readConfig(invalidTheme)
+		require.Error(t, err)
+		assert.Equal(t, "Preferences item 1 in configuration has invalid theme \"blue\", must be light or dark\n"+
+			"Preferences item 1 in configuration has invalid timezone \"Europe/Paris\", must be browser or utc", err.Error())
+	})
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		setThemeVar(t)
+
+		reader := &configReader{log: log.New("test logger")}
+		cfg, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+		require.Len(t, cfg[0].Preferences, 1)
+		assert.Equal(t, &preferencesFromConfig{
+			OrgName:          "Main Org.",
+			Theme:            "dark",
+			Timezone:         "utc",
+			HomeDashboardUID: "home",
+		}, cfg[0].Preferences[0])
+	})
+}
+
+func TestPreferencesProvisioner(t *testing.T) {
+	sqlstore.InitTestDB(t)
+	setThemeVar(t)
+
+	org := &models.CreateOrgCommand{Name: "Main Org."}
+	require.NoError(t, sqlstore.CreateOrg(org))
+
+	provisioner := newPreferencesProvisioner(log.New("test logger"))
+
+	t.Run("Fails if the home dashboard doesn't exist", func(t *testing.T) {
+		err := provisioner.applyChanges(correctProperties)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `home dashboard "home" not found`)
+	})
+
+	t.Run("Saves the org preferences", func(t *testing.T) {
+		dash := models.NewDashboard("Home")
+		dash.SetUid("home")
+		saveCmd := &models.SaveDashboardCommand{OrgId: org.Result.Id, Dashboard: dash.Data}
+		require.NoError(t, bus.Dispatch(saveCmd))
+
+		require.NoError(t, provisioner.applyChanges(correctProperties))
+
+		query := &models.GetPreferencesQuery{OrgId: org.Result.Id}
+		require.NoError(t, bus.Dispatch(query))
+		assert.Equal(t, "dark", query.Result.Theme)
+		assert.Equal(t, "utc", query.Result.Timezone)
+		assert.Equal(t, saveCmd.Result.Id, query.Result.HomeDashboardId)
+	})
+
+	t.Run("Skips missing directories", func(t *testing.T) {
+		require.NoError(t, provisioner.applyChanges(filepath.Join(t.TempDir(), "missing")))
+	})
+}
+
+func setThemeVar(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, os.Setenv("THEME", "dark"))
+	t.Cleanup(func() {
+		_ = os.Unsetenv("THEME")
+	})
+}
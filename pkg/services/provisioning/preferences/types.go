@@ -0,0 +1,51 @@
+package preferences
+
+import "github.com/grafana/grafana/pkg/services/provisioning/values"
+
+// preferencesAsConfig is a normalized data object for org preferences config data. Any config version
+// should be mappable to this type.
+type preferencesAsConfig struct {
+	Preferences []*preferencesFromConfig
+}
+
+type preferencesFromConfig struct {
+	OrgID            int64
+	OrgName          string
+	Theme            string
+	Timezone         string
+	HomeDashboardUID string
+}
+
+// preferencesAsConfigV1 is a mapping for version one configs. This is mapped to its normalised version.
+type preferencesAsConfigV1 struct {
+	Preferences []*preferencesFromConfigV1 `json:"preferences" yaml:"preferences"`
+}
+
+type preferencesFromConfigV1 struct {
+	OrgID            values.Int64Value  `json:"orgId" yaml:"orgId"`
+	OrgName          values.StringValue `json:"orgName" yaml:"orgName"`
+	Theme            values.StringValue `json:"theme" yaml:"theme"`
+	Timezone         values.StringValue `json:"timezone" yaml:"timezone"`
+	HomeDashboardUID values.StringValue `json:"homeDashboardUid" yaml:"homeDashboardUid"`
+}
+
+// mapToPreferencesFromConfig maps config syntax to a normalized preferencesAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *preferencesAsConfigV1) mapToPreferencesFromConfig() *preferencesAsConfig {
+	r := &preferencesAsConfig{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, prefs := range cfg.Preferences {
+		r.Preferences = append(r.Preferences, &preferencesFromConfig{
+			OrgID:            prefs.OrgID.Value(),
+			OrgName:          prefs.OrgName.Value(),
+			Theme:            prefs.Theme.Value(),
+			Timezone:         prefs.Timezone.Value(),
+			HomeDashboardUID: prefs.HomeDashboardUID.Value(),
+		})
+	}
+
+	return r
+}
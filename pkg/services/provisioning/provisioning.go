@@ -9,8 +9,12 @@ import (
 	"github.com/grafana/grafana/pkg/registry"
 	"github.com/grafana/grafana/pkg/services/provisioning/dashboards"
 	"github.com/grafana/grafana/pkg/services/provisioning/datasources"
+	"github.com/grafana/grafana/pkg/services/provisioning/folders"
 	"github.com/grafana/grafana/pkg/services/provisioning/notifiers"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
 	"github.com/grafana/grafana/pkg/services/provisioning/plugins"
+	"github.com/grafana/grafana/pkg/services/provisioning/preferences"
+	"github.com/grafana/grafana/pkg/services/provisioning/teams"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/util/errutil"
 )
@@ -19,7 +23,11 @@ type ProvisioningService interface {
 	ProvisionDatasources() error
 	ProvisionPlugins() error
 	ProvisionNotifications() error
+	ProvisionTeams() error
+	ProvisionFolders() error
 	ProvisionDashboards() error
+	ProvisionPreferences() error
+	Plan() *plan.Plan
 	GetDashboardProvisionerResolvedPath(name string) string
 	GetAllowUIUpdatesFromConfig(name string) bool
 }
@@ -28,12 +36,15 @@ func init() {
 	registry.Register(&registry.Descriptor{
 		Name: "ProvisioningService",
 		Instance: NewProvisioningServiceImpl(
-			func(path string) (dashboards.DashboardProvisioner, error) {
-				return dashboards.New(path)
+			func(path, dataPath string) (dashboards.DashboardProvisioner, error) {
+				return dashboards.New(path, dataPath)
 			},
 			notifiers.Provision,
 			datasources.Provision,
 			plugins.Provision,
+			teams.Provision,
+			folders.Provision,
+			preferences.Provision,
 		),
 		InitPriority: registry.Low,
 	})
@@ -44,6 +55,9 @@ func NewProvisioningServiceImpl(
 	provisionNotifiers func(string) error,
 	provisionDatasources func(string) error,
 	provisionPlugins func(string) error,
+	provisionTeams func(string) error,
+	provisionFolders func(string) error,
+	provisionPreferences func(string) error,
 ) *provisioningServiceImpl {
 	return &provisioningServiceImpl{
 		log:                     log.New("provisioning"),
@@ -51,6 +65,10 @@ func NewProvisioningServiceImpl(
 		provisionNotifiers:      provisionNotifiers,
 		provisionDatasources:    provisionDatasources,
 		provisionPlugins:        provisionPlugins,
+		provisionTeams:          provisionTeams,
+		provisionFolders:        provisionFolders,
+		provisionPreferences:    provisionPreferences,
+		planProvisioning:        Plan,
 	}
 }
 
@@ -63,6 +81,10 @@ type provisioningServiceImpl struct {
 	provisionNotifiers      func(string) error
 	provisionDatasources    func(string) error
 	provisionPlugins        func(string) error
+	provisionTeams          func(string) error
+	provisionFolders        func(string) error
+	provisionPreferences    func(string) error
+	planProvisioning        func(provisioningPath, dataPath string) *plan.Plan
 	mutex                   sync.Mutex
 }
 
@@ -82,6 +104,17 @@ func (ps *provisioningServiceImpl) Init() error {
 		return err
 	}
 
+	// teams are provisioned first, as folder permissions can refer to them
+	err = ps.ProvisionTeams()
+	if err != nil {
+		return err
+	}
+
+	err = ps.ProvisionFolders()
+	if err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -92,6 +125,13 @@ func (ps *provisioningServiceImpl) Run(ctx context.Context) error {
 		return err
 	}
 
+	// preferences are provisioned after the dashboards, as they can refer to a home dashboard
+	err = ps.ProvisionPreferences()
+	if err != nil {
+		ps.log.Error("Failed to provision preferences", "error", err)
+		return err
+	}
+
 	for {
 		// Wait for unlock. This is tied to new dashboardProvisioner to be instantiated before we start polling.
 		ps.mutex.Lock()
@@ -132,9 +172,27 @@ func (ps *provisioningServiceImpl) ProvisionNotifications() error {
 	return errutil.Wrap("Alert notification provisioning error", err)
 }
 
+func (ps *provisioningServiceImpl) ProvisionTeams() error {
+	teamsPath := filepath.Join(ps.Cfg.ProvisioningPath, "teams")
+	err := ps.provisionTeams(teamsPath)
+	return errutil.Wrap("Team provisioning error", err)
+}
+
+func (ps *provisioningServiceImpl) ProvisionFolders() error {
+	foldersPath := filepath.Join(ps.Cfg.ProvisioningPath, "folders")
+	err := ps.provisionFolders(foldersPath)
+	return errutil.Wrap("Folder provisioning error", err)
+}
+
+func (ps *provisioningServiceImpl) ProvisionPreferences() error {
+	preferencesPath := filepath.Join(ps.Cfg.ProvisioningPath, "preferences")
+	err := ps.provisionPreferences(preferencesPath)
+	return errutil.Wrap("Preferences provisioning error", err)
+}
+
 func (ps *provisioningServiceImpl) ProvisionDashboards() error {
 	dashboardPath := filepath.Join(ps.Cfg.ProvisioningPath, "dashboards")
-	dashProvisioner, err := ps.newDashboardProvisioner(dashboardPath)
+	dashProvisioner, err := ps.newDashboardProvisioner(dashboardPath, ps.Cfg.DataPath)
 	if err != nil {
 		return errutil.Wrap("Failed to create provisioner", err)
 	}
@@ -155,6 +213,11 @@ func (ps *provisioningServiceImpl) ProvisionDashboards() error {
 	return nil
 }
 
+// Plan returns the changes that provisioning the config files would apply, without applying them.
+func (ps *provisioningServiceImpl) Plan() *plan.Plan {
+	return ps.planProvisioning(ps.Cfg.ProvisioningPath, ps.Cfg.DataPath)
+}
+
 func (ps *provisioningServiceImpl) GetDashboardProvisionerResolvedPath(name string) string {
 	return ps.dashboardProvisioner.GetProvisionerResolvedPath(name)
 }
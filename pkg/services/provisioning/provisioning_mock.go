@@ -1,10 +1,16 @@
 package provisioning
 
+import "github.com/grafana/grafana/pkg/services/provisioning/plan"
+
 type Calls struct {
 	ProvisionDatasources                []interface{}
 	ProvisionPlugins                    []interface{}
 	ProvisionNotifications              []interface{}
+	ProvisionTeams                      []interface{}
+	ProvisionFolders                    []interface{}
 	ProvisionDashboards                 []interface{}
+	ProvisionPreferences                []interface{}
+	Plan                                []interface{}
 	GetDashboardProvisionerResolvedPath []interface{}
 	GetAllowUIUpdatesFromConfig         []interface{}
 }
@@ -14,7 +20,11 @@ type ProvisioningServiceMock struct {
 	ProvisionDatasourcesFunc                func() error
 	ProvisionPluginsFunc                    func() error
 	ProvisionNotificationsFunc              func() error
+	ProvisionTeamsFunc                      func() error
+	ProvisionFoldersFunc                    func() error
 	ProvisionDashboardsFunc                 func() error
+	ProvisionPreferencesFunc                func() error
+	PlanFunc                                func() *plan.Plan
 	GetDashboardProvisionerResolvedPathFunc func(name string) string
 	GetAllowUIUpdatesFromConfigFunc         func(name string) bool
 }
@@ -49,6 +59,38 @@ func (mock *ProvisioningServiceMock) ProvisionNotifications() error {
 	return nil
 }
 
+func (mock *ProvisioningServiceMock) ProvisionTeams() error {
+	mock.Calls.ProvisionTeams = append(mock.Calls.ProvisionTeams, nil)
+	if mock.ProvisionTeamsFunc != nil {
+		return mock.ProvisionTeamsFunc()
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionFolders() error {
+	mock.Calls.ProvisionFolders = append(mock.Calls.ProvisionFolders, nil)
+	if mock.ProvisionFoldersFunc != nil {
+		return mock.ProvisionFoldersFunc()
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) ProvisionPreferences() error {
+	mock.Calls.ProvisionPreferences = append(mock.Calls.ProvisionPreferences, nil)
+	if mock.ProvisionPreferencesFunc != nil {
+		return mock.ProvisionPreferencesFunc()
+	}
+	return nil
+}
+
+func (mock *ProvisioningServiceMock) Plan() *plan.Plan {
+	mock.Calls.Plan = append(mock.Calls.Plan, nil)
+	if mock.PlanFunc != nil {
+		return mock.PlanFunc()
+	}
+	return plan.New()
+}
+
 func (mock *ProvisioningServiceMock) ProvisionDashboards() error {
 	mock.Calls.ProvisionDashboards = append(mock.Calls.ProvisionDashboards, nil)
 	if mock.ProvisionDashboardsFunc != nil {
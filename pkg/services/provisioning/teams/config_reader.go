@@ -0,0 +1,98 @@
+package teams
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"gopkg.in/yaml.v2"
+)
+
+type configReader struct {
+	log log.Logger
+}
+
+func (cr *configReader) readConfig(path string) ([]*teamsAsConfig, error) {
+	var teams []*teamsAsConfig
+	cr.log.Debug("Looking for team provisioning files", "path", path)
+
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		cr.log.Error("Can't read team provisioning files from directory", "path", path, "error", err)
+		return teams, nil
+	}
+
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), ".yaml") || strings.HasSuffix(file.Name(), ".yml") {
+			cr.log.Debug("Parsing team provisioning file", "path", path, "file.Name", file.Name())
+			cfg, err := cr.parseTeamConfig(path, file)
+			if err != nil {
+				return nil, err
+			}
+
+			if cfg != nil {
+				teams = append(teams, cfg)
+			}
+		}
+	}
+
+	cr.log.Debug("Validating teams")
+	if err := validateRequiredField(teams); err != nil {
+		return nil, err
+	}
+
+	return teams, nil
+}
+
+func (cr *configReader) parseTeamConfig(path string, file os.FileInfo) (*teamsAsConfig, error) {
+	filename, err := filepath.Abs(filepath.Join(path, file.Name()))
+	if err != nil {
+		return nil, err
+	}
+
+	// nolint:gosec
+	// We can ignore the gosec G304 warning on this one because `filename` comes from ps.Cfg.ProvisioningPath
+	yamlFile, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg *teamsAsConfigV1
+	if err := yaml.Unmarshal(yamlFile, &cfg); err != nil {
+		return nil, err
+	}
+
+	return cfg.mapToTeamsFromConfig(), nil
+}
+
+func validateRequiredField(teams []*teamsAsConfig) error {
+	for i := range teams {
+		var errStrings []string
+		for index, team := range teams[i].Teams {
+			if team.Name == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Added team item %d in configuration doesn't contain required field name", index+1),
+				)
+			}
+		}
+
+		for index, team := range teams[i].DeleteTeams {
+			if team.Name == "" {
+				errStrings = append(
+					errStrings,
+					fmt.Sprintf("Deleted team item %d in configuration doesn't contain required field name", index+1),
+				)
+			}
+		}
+
+		if len(errStrings) != 0 {
+			return fmt.Errorf(strings.Join(errStrings, "\n"))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,14 @@
+package teams
+
+import (
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+)
+
+// Plan adds the teams that provisioning would create, update or delete to the plan, and warns about the
+// members that provisioning would skip because they don't exist or aren't members of the org.
+func Plan(configDirectory string, p *plan.Plan) error {
+	tp := newTeamProvisioner(log.New("provisioning.teams"))
+	tp.plan = p
+	return tp.applyChanges(configDirectory)
+}
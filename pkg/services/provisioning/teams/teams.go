@@ -0,0 +1,242 @@
+package teams
+
+import (
+	"errors"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/plan"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+)
+
+// Provision scans a directory for provisioning config files
+// and provisions the teams in those files.
+func Provision(configDirectory string) error {
+	tp := newTeamProvisioner(log.New("provisioning.teams"))
+	return tp.applyChanges(configDirectory)
+}
+
+// TeamProvisioner is responsible for provisioning teams based on
+// configuration read by the `configReader`
+type TeamProvisioner struct {
+	log         log.Logger
+	cfgProvider *configReader
+	// plan is set when the changes are only added to the plan, instead of being applied.
+	plan *plan.Plan
+}
+
+func newTeamProvisioner(log log.Logger) TeamProvisioner {
+	return TeamProvisioner{
+		log:         log,
+		cfgProvider: &configReader{log: log},
+	}
+}
+
+func (tp *TeamProvisioner) apply(cfg *teamsAsConfig) error {
+	if err := tp.deleteTeams(cfg.DeleteTeams); err != nil {
+		return err
+	}
+
+	if err := tp.mergeTeams(cfg.Teams); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (tp *TeamProvisioner) deleteTeams(teamsToDelete []*deleteTeamConfig) error {
+	for _, team := range teamsToDelete {
+		orgID, err := utils.GetOrgID(team.OrgID, team.OrgName)
+		if err != nil {
+			return err
+		}
+
+		existing, err := utils.GetTeamByName(orgID, team.Name)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			continue
+		}
+
+		if tp.plan != nil {
+			tp.plan.Add(&plan.Change{Action: plan.ActionDelete, Kind: plan.KindTeam, OrgID: orgID, Name: team.Name})
+			continue
+		}
+
+		tp.log.Info("Deleting team", "name", team.Name, "orgId", orgID)
+		if err := bus.Dispatch(&models.DeleteTeamCommand{OrgId: orgID, Id: existing.Id}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (tp *TeamProvisioner) mergeTeams(teamsToMerge []*teamFromConfig) error {
+	for _, team := range teamsToMerge {
+		orgID, err := utils.GetOrgID(team.OrgID, team.OrgName)
+		if err != nil {
+			return err
+		}
+
+		existing, err := utils.GetTeamByName(orgID, team.Name)
+		if err != nil {
+			return err
+		}
+
+		var teamID int64
+		var changed []string
+		if existing == nil {
+			if tp.plan == nil {
+				tp.log.Debug("inserting team from configuration", "name", team.Name, "orgId", orgID)
+				cmd := &models.CreateTeamCommand{OrgId: orgID, Name: team.Name, Email: team.Email}
+				if err := bus.Dispatch(cmd); err != nil {
+					return err
+				}
+				teamID = cmd.Result.Id
+			}
+		} else {
+			teamID = existing.Id
+			if existing.Email != team.Email {
+				changed = append(changed, "email")
+				if tp.plan == nil {
+					tp.log.Debug("updating team from configuration", "name", team.Name, "orgId", orgID)
+					cmd := &models.UpdateTeamCommand{Id: teamID, OrgId: orgID, Name: team.Name, Email: team.Email}
+					if err := bus.Dispatch(cmd); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		if team.ManageMembers {
+			membersChanged, err := tp.syncMembers(orgID, teamID, team)
+			if err != nil {
+				return err
+			}
+			if membersChanged {
+				changed = append(changed, "members")
+			}
+		}
+
+		if tp.plan != nil {
+			if existing == nil {
+				tp.plan.Add(&plan.Change{Action: plan.ActionCreate, Kind: plan.KindTeam, OrgID: orgID, Name: team.Name})
+			} else if len(changed) > 0 {
+				tp.plan.Add(&plan.Change{Action: plan.ActionUpdate, Kind: plan.KindTeam, OrgID: orgID, Name: team.Name, Fields: changed})
+			}
+		}
+	}
+
+	return nil
+}
+
+// syncMembers adds the members of the config to the team and removes the other members, except
+// for the members that were added by external systems, such as LDAP. It returns true if members
+// were added or removed.
+func (tp *TeamProvisioner) syncMembers(orgID, teamID int64, team *teamFromConfig) (bool, error) {
+	query := &models.GetTeamMembersQuery{OrgId: orgID, TeamId: teamID}
+	if err := bus.Dispatch(query); err != nil {
+		return false, err
+	}
+
+	current := map[int64]*models.TeamMemberDTO{}
+	for _, member := range query.Result {
+		current[member.UserId] = member
+	}
+
+	wanted := map[int64]bool{}
+	changed := false
+	for _, loginOrEmail := range team.Members {
+		userQuery := &models.GetUserByLoginQuery{LoginOrEmail: loginOrEmail}
+		if err := bus.Dispatch(userQuery); err != nil {
+			if errors.Is(err, models.ErrUserNotFound) {
+				if tp.plan != nil {
+					tp.plan.AddWarning(plan.KindTeam, "member %q of team %q doesn't exist", loginOrEmail, team.Name)
+				} else {
+					tp.log.Warn("Skipping team member that doesn't exist", "team", team.Name, "user", loginOrEmail)
+				}
+				continue
+			}
+			return false, err
+		}
+		userID := userQuery.Result.Id
+
+		isOrgMember, err := isOrgMember(orgID, userID)
+		if err != nil {
+			return false, err
+		}
+		if !isOrgMember {
+			if tp.plan != nil {
+				tp.plan.AddWarning(plan.KindTeam, "member %q of team %q isn't a member of org %d", loginOrEmail, team.Name, orgID)
+			} else {
+				tp.log.Warn("Skipping team member that isn't a member of the org", "team", team.Name, "user", loginOrEmail, "orgId", orgID)
+			}
+			continue
+		}
+
+		wanted[userID] = true
+		if _, ok := current[userID]; ok {
+			continue
+		}
+
+		changed = true
+		if tp.plan != nil {
+			continue
+		}
+
+		tp.log.Debug("adding team member from configuration", "team", team.Name, "user", loginOrEmail)
+		if err := bus.Dispatch(&models.AddTeamMemberCommand{OrgId: orgID, TeamId: teamID, UserId: userID}); err != nil {
+			return false, err
+		}
+	}
+
+	for userID, member := range current {
+		if wanted[userID] || member.External {
+			continue
+		}
+
+		changed = true
+		if tp.plan != nil {
+			continue
+		}
+
+		tp.log.Debug("removing team member missing in configuration", "team", team.Name, "user", member.Login)
+		if err := bus.Dispatch(&models.RemoveTeamMemberCommand{OrgId: orgID, TeamId: teamID, UserId: userID}); err != nil {
+			return false, err
+		}
+	}
+
+	return changed, nil
+}
+
+func (tp *TeamProvisioner) applyChanges(configPath string) error {
+	configs, err := tp.cfgProvider.readConfig(configPath)
+	if err != nil {
+		return err
+	}
+
+	for _, cfg := range configs {
+		if err := tp.apply(cfg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func isOrgMember(orgID, userID int64) (bool, error) {
+	query := &models.GetUserOrgListQuery{UserId: userID}
+	if err := bus.Dispatch(query); err != nil {
+		return false, err
+	}
+
+	for _, org := range query.Result {
+		if org.OrgId == orgID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
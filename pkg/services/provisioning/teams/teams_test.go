@@ -0,0 +1,141 @@
+package teams
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/provisioning/utils"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+)
+
+const (
+	correctProperties = "./testdata/test-configs/correct-properties"
+	brokenYaml        = "./testdata/test-configs/broken-yaml"
+	noRequiredFields  = "./testdata/test-configs/no-required-fields"
+	emptyFolder       = "./testdata/test-configs/empty_folder"
+)
+
+func TestConfigReader(t *testing.T) {
+	t.Run("Broken yaml should return error", func(t *testing.T) {
+		reader := &configReader{log: log.New("test logger")}
+		_, err := reader.readConfig(brokenYaml)
+		require.Error(t, err)
+	})
+
+	t.Run("Skip invalid directory", func(t *testing.T) {
+		reader := &configReader{log: log.New("test logger")}
+		cfg, err := reader.readConfig(emptyFolder)
+		require.NoError(t, err)
+		require.Len(t, cfg, 0)
+	})
+
+	t.Run("Missing required fields should return error", func(t *testing.T) {
+		reader := &configReader{log: log.New("test logger")}
+		_, err := reader.readConfig(noRequiredFields)
+		require.Error(t, err)
+		assert.Equal(t, "Added team item 1 in configuration doesn't contain required field name\n"+
+			"Deleted team item 1 in configuration doesn't contain required field name", err.Error())
+	})
+
+	t.Run("Can read correct properties", func(t *testing.T) {
+		setTeamNameVar(t)
+
+		reader := &configReader{log: log.New("test logger")}
+		cfg, err := reader.readConfig(correctProperties)
+		require.NoError(t, err)
+		require.Len(t, cfg, 1)
+		require.Len(t, cfg[0].Teams, 3)
+
+		backend := cfg[0].Teams[0]
+		assert.Equal(t, "Backend", backend.Name)
+		assert.Equal(t, "backend@example.com", backend.Email)
+		assert.Equal(t, []string{"alice", "bob@example.com", "unknown"}, backend.Members)
+		assert.True(t, backend.ManageMembers)
+
+		assert.Equal(t, "Main Org.", cfg[0].Teams[1].OrgName)
+		assert.False(t, cfg[0].Teams[2].ManageMembers)
+
+		require.Len(t, cfg[0].DeleteTeams, 1)
+		assert.Equal(t, "Old team", cfg[0].DeleteTeams[0].Name)
+		assert.Equal(t, int64(1), cfg[0].DeleteTeams[0].OrgID)
+	})
+}
+
+func TestTeamProvisioner(t *testing.T) {
+	sqlstore.InitTestDB(t)
+	setTeamNameVar(t)
+
+	org := &models.CreateOrgCommand{Name: "Main Org."}
+	require.NoError(t, sqlstore.CreateOrg(org))
+
+	var userIDs []int64
+	for _, login := range []string{"alice", "bob", "carol"} {
+		cmd := &models.CreateUserCommand{Login: login, Email: login + "@example.com", SkipOrgSetup: true}
+		require.NoError(t, sqlstore.CreateUser(context.Background(), cmd))
+		require.NoError(t, bus.Dispatch(&models.AddOrgUserCommand{OrgId: org.Result.Id, UserId: cmd.Result.Id, Role: models.ROLE_VIEWER}))
+		userIDs = append(userIDs, cmd.Result.Id)
+	}
+
+	oldTeam := &models.CreateTeamCommand{OrgId: org.Result.Id, Name: "Old team"}
+	require.NoError(t, bus.Dispatch(oldTeam))
+
+	// carol isn't part of the config and is removed from the team
+	backend := &models.CreateTeamCommand{OrgId: org.Result.Id, Name: "Backend"}
+	require.NoError(t, bus.Dispatch(backend))
+	require.NoError(t, bus.Dispatch(&models.AddTeamMemberCommand{OrgId: org.Result.Id, TeamId: backend.Result.Id, UserId: userIDs[2]}))
+
+	unmanaged := &models.CreateTeamCommand{OrgId: org.Result.Id, Name: "Unmanaged members"}
+	require.NoError(t, bus.Dispatch(unmanaged))
+	require.NoError(t, bus.Dispatch(&models.AddTeamMemberCommand{OrgId: org.Result.Id, TeamId: unmanaged.Result.Id, UserId: userIDs[2]}))
+
+	provisioner := newTeamProvisioner(log.New("test logger"))
+	require.NoError(t, provisioner.applyChanges(correctProperties))
+	// applying the config again doesn't change anything
+	require.NoError(t, provisioner.applyChanges(correctProperties))
+
+	team, err := utils.GetTeamByName(org.Result.Id, "Old team")
+	require.NoError(t, err)
+	assert.Nil(t, team)
+
+	team, err = utils.GetTeamByName(org.Result.Id, "Backend")
+	require.NoError(t, err)
+	require.NotNil(t, team)
+	assert.Equal(t, "backend@example.com", team.Email)
+	assert.Equal(t, []string{"alice", "bob"}, teamMembers(t, org.Result.Id, team.Id))
+
+	team, err = utils.GetTeamByName(org.Result.Id, "Frontend")
+	require.NoError(t, err)
+	require.NotNil(t, team)
+	assert.Empty(t, teamMembers(t, org.Result.Id, team.Id))
+
+	assert.Equal(t, []string{"carol"}, teamMembers(t, org.Result.Id, unmanaged.Result.Id))
+}
+
+func setTeamNameVar(t *testing.T) {
+	t.Helper()
+
+	require.NoError(t, os.Setenv("TEAM_NAME", "Backend"))
+	t.Cleanup(func() {
+		_ = os.Unsetenv("TEAM_NAME")
+	})
+}
+
+func teamMembers(t *testing.T, orgID, teamID int64) []string {
+	t.Helper()
+
+	query := &models.GetTeamMembersQuery{OrgId: orgID, TeamId: teamID}
+	require.NoError(t, bus.Dispatch(query))
+
+	var logins []string
+	for _, member := range query.Result {
+		logins = append(logins, member.Login)
+	}
+	return logins
+}
@@ -0,0 +1,80 @@
+package teams
+
+import "github.com/grafana/grafana/pkg/services/provisioning/values"
+
+// teamsAsConfig is a normalized data object for teams config data. Any config version should be mappable
+// to this type.
+type teamsAsConfig struct {
+	Teams       []*teamFromConfig
+	DeleteTeams []*deleteTeamConfig
+}
+
+type teamFromConfig struct {
+	OrgID   int64
+	OrgName string
+	Name    string
+	Email   string
+	// Members are the logins or emails of the team members. The members of the team are
+	// only synced if the members are part of the config.
+	Members       []string
+	ManageMembers bool
+}
+
+type deleteTeamConfig struct {
+	OrgID   int64
+	OrgName string
+	Name    string
+}
+
+// teamsAsConfigV1 is a mapping for version one configs. This is mapped to its normalised version.
+type teamsAsConfigV1 struct {
+	Teams       []*teamFromConfigV1   `json:"teams" yaml:"teams"`
+	DeleteTeams []*deleteTeamConfigV1 `json:"deleteTeams" yaml:"deleteTeams"`
+}
+
+type teamFromConfigV1 struct {
+	OrgID   values.Int64Value    `json:"orgId" yaml:"orgId"`
+	OrgName values.StringValue   `json:"orgName" yaml:"orgName"`
+	Name    values.StringValue   `json:"name" yaml:"name"`
+	Email   values.StringValue   `json:"email" yaml:"email"`
+	Members []values.StringValue `json:"members" yaml:"members"`
+}
+
+type deleteTeamConfigV1 struct {
+	OrgID   values.Int64Value  `json:"orgId" yaml:"orgId"`
+	OrgName values.StringValue `json:"orgName" yaml:"orgName"`
+	Name    values.StringValue `json:"name" yaml:"name"`
+}
+
+// mapToTeamsFromConfig maps config syntax to a normalized teamsAsConfig object. Every version
+// of the config syntax should have this function.
+func (cfg *teamsAsConfigV1) mapToTeamsFromConfig() *teamsAsConfig {
+	r := &teamsAsConfig{}
+	if cfg == nil {
+		return r
+	}
+
+	for _, team := range cfg.Teams {
+		t := &teamFromConfig{
+			OrgID:         team.OrgID.Value(),
+			OrgName:       team.OrgName.Value(),
+			Name:          team.Name.Value(),
+			Email:         team.Email.Value(),
+			ManageMembers: team.Members != nil,
+		}
+		for _, member := range team.Members {
+			t.Members = append(t.Members, member.Value())
+		}
+		r.Teams = append(r.Teams, t)
+	}
+
+	for _, team := range cfg.DeleteTeams {
+		r.DeleteTeams = append(r.DeleteTeams, &deleteTeamConfig{
+			OrgID:   team.OrgID.Value(),
+			OrgName: team.OrgName.Value(),
+			Name:    team.Name.Value(),
+		})
+	}
+
+	return r
+}
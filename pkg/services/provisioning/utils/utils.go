@@ -18,3 +18,34 @@ func CheckOrgExists(orgID int64) error {
 	}
 	return nil
 }
+
+// GetOrgID returns the id of the org with orgName if orgID isn't set. It
+// defaults to the main org if neither is set.
+func GetOrgID(orgID int64, orgName string) (int64, error) {
+	if orgID > 0 {
+		return orgID, nil
+	}
+
+	if orgName == "" {
+		return 1, nil
+	}
+
+	query := models.GetOrgByNameQuery{Name: orgName}
+	if err := bus.Dispatch(&query); err != nil {
+		return 0, fmt.Errorf("failed to get org %q: %w", orgName, err)
+	}
+	return query.Result.Id, nil
+}
+
+// GetTeamByName returns the team with the name in the org, or nil if there is none.
+func GetTeamByName(orgID int64, name string) (*models.TeamDTO, error) {
+	query := models.SearchTeamsQuery{OrgId: orgID, Name: name, Limit: 1, Page: 1}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+
+	if len(query.Result.Teams) == 0 {
+		return nil, nil
+	}
+	return query.Result.Teams[0], nil
+}
@@ -0,0 +1,160 @@
+package recordedqueries
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/serverlock"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb"
+)
+
+// tickInterval is how often the service checks for recorded queries that are due.
+const tickInterval = 10 * time.Second
+
+func init() {
+	registry.RegisterService(&RecordedQueriesService{})
+}
+
+// RecordedQueriesService periodically runs the active recorded queries and sends their results to a
+// Prometheus remote write endpoint, from where dashboards and alerts query them like any other metric.
+// When Grafana runs in HA mode, each run of a recorded query happens on one of the servers.
+type RecordedQueriesService struct {
+	Cfg               *setting.Cfg                  `inject:""`
+	DataService       *tsdb.Service                 `inject:""`
+	ServerLockService *serverlock.ServerLockService `inject:""`
+
+	log        log.Logger
+	httpClient *http.Client
+}
+
+func (s *RecordedQueriesService) Init() error {
+	s.log = log.New("recordedqueries")
+	s.httpClient = &http.Client{Timeout: 30 * time.Second}
+	return nil
+}
+
+func (s *RecordedQueriesService) IsDisabled() bool {
+	return !s.Cfg.RecordedQueries.Enabled || s.Cfg.RecordedQueries.RemoteWriteURL == ""
+}
+
+func (s *RecordedQueriesService) Run(ctx context.Context) error {
+	ticker := time.NewTicker(tickInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.runDueQueries(ctx)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// runDueQueries runs the recorded queries whose interval has passed since their last run on any server.
+func (s *RecordedQueriesService) runDueQueries(ctx context.Context) {
+	query := models.GetActiveRecordedQueriesQuery{}
+	if err := bus.Dispatch(&query); err != nil {
+		s.log.Error("Failed to get recorded queries", "error", err)
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, rq := range query.Result {
+		interval, err := gtime.ParseDuration(rq.Interval)
+		if err != nil {
+			s.log.Warn("Invalid recorded query interval", "uid", rq.Uid, "interval", rq.Interval)
+			continue
+		}
+
+		wg.Add(1)
+		go func(rq *models.RecordedQuery) {
+			defer wg.Done()
+			err := s.ServerLockService.LockAndExecute(ctx, fmt.Sprintf("recorded query %d", rq.Id), interval, func() {
+				s.runQuery(ctx, rq, interval)
+			})
+			if err != nil {
+				s.log.Error("Failed to lock and execute recorded query", "uid", rq.Uid, "error", err)
+			}
+		}(rq)
+	}
+	wg.Wait()
+}
+
+func (s *RecordedQueriesService) runQuery(ctx context.Context, rq *models.RecordedQuery, interval time.Duration) {
+	ctx, cancel := context.WithTimeout(ctx, interval)
+	defer cancel()
+
+	now := time.Now()
+	cmd := models.SetRecordedQueryRunCommand{Id: rq.Id, LastRun: now}
+	if err := s.recordQuery(ctx, rq, now); err != nil {
+		s.log.Warn("Recorded query failed", "uid", rq.Uid, "name", rq.Name, "error", err)
+		cmd.LastError = err.Error()
+	}
+
+	if err := bus.Dispatch(&cmd); err != nil {
+		s.log.Error("Failed to save run of recorded query", "uid", rq.Uid, "error", err)
+	}
+}
+
+// recordQuery runs a recorded query and writes the last value of each of the series it returns.
+func (s *RecordedQueriesService) recordQuery(ctx context.Context, rq *models.RecordedQuery, now time.Time) error {
+	samples, err := s.querySamples(ctx, rq, now)
+	if err != nil {
+		return err
+	}
+
+	return s.remoteWrite(ctx, rq.Name, samples, now)
+}
+
+func (s *RecordedQueriesService) querySamples(ctx context.Context, rq *models.RecordedQuery, now time.Time) ([]sample, error) {
+	interval, err := gtime.ParseDuration(rq.Interval)
+	if err != nil {
+		return nil, err
+	}
+	timeRange, err := gtime.ParseDuration(rq.TimeRange)
+	if err != nil {
+		return nil, err
+	}
+
+	dsQuery := models.GetDataSourceQuery{Id: rq.DatasourceId, OrgId: rq.OrgId}
+	if err := bus.Dispatch(&dsQuery); err != nil {
+		return nil, fmt.Errorf("could not find data source: %w", err)
+	}
+	ds := dsQuery.Result
+
+	tr := plugins.NewDataTimeRange(strconv.FormatInt(now.Add(-timeRange).UnixNano()/int64(time.Millisecond), 10),
+		strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10))
+	query := plugins.DataQuery{
+		TimeRange: &tr,
+		Queries: []plugins.DataSubQuery{
+			{
+				RefID:         rq.Query.Get("refId").MustString("A"),
+				MaxDataPoints: int64(timeRange/interval) + 1,
+				IntervalMS:    interval.Milliseconds(),
+				QueryType:     rq.Query.Get("queryType").MustString(""),
+				Model:         rq.Query,
+				DataSource:    ds,
+			},
+		},
+	}
+
+	// recorded queries store the latest data
+	resp, err := s.DataService.HandleRequest(tsdb.ContextWithoutQueryCache(ctx), ds, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return responseSamples(resp)
+}
@@ -0,0 +1,86 @@
+package recordedqueries
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+// maxErrorBodyLength is the number of bytes of a failed remote write response that end up in the error.
+const maxErrorBodyLength = 512
+
+// remoteWrite sends the samples of a recorded query to the configured Prometheus remote write endpoint,
+// as series of the metric named after the recorded query.
+func (s *RecordedQueriesService) remoteWrite(ctx context.Context, name string, samples []sample, now time.Time) error {
+	if s.Cfg.RecordedQueries.RemoteWriteURL == "" {
+		return fmt.Errorf("remote write URL is not configured")
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+
+	body, err := encodeWriteRequest(name, samples, now)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.Cfg.RecordedQueries.RemoteWriteURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if s.Cfg.RecordedQueries.RemoteWriteUser != "" {
+		req.SetBasicAuth(s.Cfg.RecordedQueries.RemoteWriteUser, s.Cfg.RecordedQueries.RemoteWritePassword)
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			s.log.Warn("Failed to close response body", "err", err)
+		}
+	}()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyLength))
+		return fmt.Errorf("remote write failed with status %s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+func encodeWriteRequest(name string, samples []sample, now time.Time) ([]byte, error) {
+	timestamp := now.UnixNano() / int64(time.Millisecond)
+	req := prompb.WriteRequest{Timeseries: make([]prompb.TimeSeries, 0, len(samples))}
+	for _, sample := range samples {
+		labels := []prompb.Label{{Name: "__name__", Value: name}}
+		for key, value := range sample.labels {
+			labels = append(labels, prompb.Label{Name: key, Value: value})
+		}
+		sort.Slice(labels, func(i, j int) bool {
+			return labels[i].Name < labels[j].Name
+		})
+
+		req.Timeseries = append(req.Timeseries, prompb.TimeSeries{
+			Labels:  labels,
+			Samples: []prompb.Sample{{Value: sample.value, Timestamp: timestamp}},
+		})
+	}
+
+	data, err := req.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return snappy.Encode(nil, data), nil
+}
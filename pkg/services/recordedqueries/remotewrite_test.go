@@ -0,0 +1,72 @@
+package recordedqueries
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRemoteWrite(t *testing.T) {
+	newService := func(url string) *RecordedQueriesService {
+		cfg := setting.NewCfg()
+		cfg.RecordedQueries.RemoteWriteURL = url
+		cfg.RecordedQueries.RemoteWriteUser = "user"
+		cfg.RecordedQueries.RemoteWritePassword = "password"
+		return &RecordedQueriesService{Cfg: cfg, log: log.New("test"), httpClient: http.DefaultClient}
+	}
+
+	t.Run("Sends the samples as series of the recorded query metric", func(t *testing.T) {
+		var received prompb.WriteRequest
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "snappy", r.Header.Get("Content-Encoding"))
+			assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+			user, password, ok := r.BasicAuth()
+			assert.True(t, ok)
+			assert.Equal(t, "user", user)
+			assert.Equal(t, "password", password)
+
+			body, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			decoded, err := snappy.Decode(nil, body)
+			require.NoError(t, err)
+			require.NoError(t, received.Unmarshal(decoded))
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer server.Close()
+
+		now := time.Unix(1600000000, 0)
+		samples := []sample{{labels: map[string]string{"job": "api", "host": "a"}, value: 1.5}}
+		err := newService(server.URL).remoteWrite(context.Background(), "job:requests:rate5m", samples, now)
+		require.NoError(t, err)
+
+		require.Len(t, received.Timeseries, 1)
+		assert.Equal(t, []prompb.Label{
+			{Name: "__name__", Value: "job:requests:rate5m"},
+			{Name: "host", Value: "a"},
+			{Name: "job", Value: "api"},
+		}, received.Timeseries[0].Labels)
+		assert.Equal(t, []prompb.Sample{{Value: 1.5, Timestamp: 1600000000000}}, received.Timeseries[0].Samples)
+	})
+
+	t.Run("Fails when the endpoint returns an error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "out of order sample", http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		samples := []sample{{labels: map[string]string{}, value: 1}}
+		err := newService(server.URL).remoteWrite(context.Background(), "metric", samples, time.Now())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "400 Bad Request: out of order sample")
+	})
+}
@@ -0,0 +1,105 @@
+package recordedqueries
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// seriesLabel is the label that identifies series without labels of their own.
+const seriesLabel = "series"
+
+var invalidLabelCharsPattern = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// sample is the latest value of a series returned by a recorded query.
+type sample struct {
+	labels map[string]string
+	value  float64
+}
+
+// responseSamples returns the last non-null value of each series in a query response. Series with labels
+// are identified by them, other series by their name.
+func responseSamples(resp plugins.DataResponse) ([]sample, error) {
+	refIDs := make([]string, 0, len(resp.Results))
+	for refID := range resp.Results {
+		refIDs = append(refIDs, refID)
+	}
+	sort.Strings(refIDs)
+
+	var samples []sample
+	for _, refID := range refIDs {
+		result := resp.Results[refID]
+		if result.Error != nil {
+			return nil, fmt.Errorf("query %s failed: %w", refID, result.Error)
+		}
+		if result.ErrorString != "" {
+			return nil, fmt.Errorf("query %s failed: %s", refID, result.ErrorString)
+		}
+
+		for _, series := range result.Series {
+			for i := len(series.Points) - 1; i >= 0; i-- {
+				if value := series.Points[i][0]; value.Valid {
+					samples = append(samples, sample{labels: sampleLabels(series.Tags, series.Name), value: value.Float64})
+					break
+				}
+			}
+		}
+
+		if result.Dataframes == nil {
+			continue
+		}
+		frames, err := result.Dataframes.Decoded()
+		if err != nil {
+			return nil, err
+		}
+		for _, frame := range frames {
+			samples = append(samples, frameSamples(frame)...)
+		}
+	}
+
+	return samples, nil
+}
+
+func frameSamples(frame *data.Frame) []sample {
+	var samples []sample
+	for _, field := range frame.Fields {
+		if !field.Type().Numeric() {
+			continue
+		}
+
+		for i := field.Len() - 1; i >= 0; i-- {
+			value, err := field.FloatAt(i)
+			if err != nil || math.IsNaN(value) {
+				continue
+			}
+
+			name := field.Name
+			if field.Config != nil && field.Config.DisplayNameFromDS != "" {
+				name = field.Config.DisplayNameFromDS
+			}
+			samples = append(samples, sample{labels: sampleLabels(field.Labels, name), value: value})
+			break
+		}
+	}
+	return samples
+}
+
+func sampleLabels(labels map[string]string, name string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for key, value := range labels {
+		key = invalidLabelCharsPattern.ReplaceAllString(key, "_")
+		if key == "__name__" {
+			continue
+		}
+		result[key] = value
+	}
+
+	if len(result) == 0 && name != "" {
+		result[seriesLabel] = name
+	}
+	return result
+}
@@ -0,0 +1,69 @@
+package recordedqueries
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/components/null"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseSamples(t *testing.T) {
+	t.Run("Uses the last non-null value of each series", func(t *testing.T) {
+		resp := plugins.DataResponse{Results: map[string]plugins.DataQueryResult{
+			"A": {
+				RefID: "A",
+				Series: plugins.DataTimeSeriesSlice{
+					{
+						Name: "cpu",
+						Tags: map[string]string{"host": "a", "data.center": "eu"},
+						Points: plugins.DataTimeSeriesPoints{
+							{null.FloatFrom(1), null.FloatFrom(1000)},
+							{null.FloatFrom(2), null.FloatFrom(2000)},
+							{null.FloatFromPtr(nil), null.FloatFrom(3000)},
+						},
+					},
+					{Name: "empty", Points: plugins.DataTimeSeriesPoints{{null.FloatFromPtr(nil), null.FloatFrom(1000)}}},
+				},
+			},
+		}}
+
+		samples, err := responseSamples(resp)
+		require.NoError(t, err)
+		assert.Equal(t, []sample{{labels: map[string]string{"host": "a", "data_center": "eu"}, value: 2}}, samples)
+	})
+
+	t.Run("Uses the last value of each numeric field of data frames", func(t *testing.T) {
+		value := 5.0
+		frame := data.NewFrame("",
+			data.NewField("time", nil, []time.Time{time.Unix(1, 0), time.Unix(2, 0)}),
+			data.NewField("value", data.Labels{"host": "a"}, []float64{3, 4}),
+			data.NewField("other", nil, []*float64{&value, nil}),
+			data.NewField("text", nil, []string{"a", "b"}),
+		)
+		resp := plugins.DataResponse{Results: map[string]plugins.DataQueryResult{
+			"A": {RefID: "A", Dataframes: plugins.NewDecodedDataFrames(data.Frames{frame})},
+		}}
+
+		samples, err := responseSamples(resp)
+		require.NoError(t, err)
+		assert.Equal(t, []sample{
+			{labels: map[string]string{"host": "a"}, value: 4},
+			{labels: map[string]string{"series": "other"}, value: 5},
+		}, samples)
+	})
+
+	t.Run("Fails when a query fails", func(t *testing.T) {
+		resp := plugins.DataResponse{Results: map[string]plugins.DataQueryResult{
+			"A": {RefID: "A", Error: errors.New("boom")},
+		}}
+
+		_, err := responseSamples(resp)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "query A failed: boom")
+	})
+}
@@ -0,0 +1,132 @@
+package saml
+
+import (
+	"strings"
+
+	gosaml "github.com/crewjam/saml"
+
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// assertionAttributes returns the values of the assertion attributes, by name and by friendly name.
+func assertionAttributes(assertion *gosaml.Assertion) map[string][]string {
+	attributes := map[string][]string{}
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			var values []string
+			for _, v := range attr.Values {
+				if v.Value != "" {
+					values = append(values, v.Value)
+				}
+			}
+			attributes[attr.Name] = append(attributes[attr.Name], values...)
+			if attr.FriendlyName != "" && attr.FriendlyName != attr.Name {
+				attributes[attr.FriendlyName] = append(attributes[attr.FriendlyName], values...)
+			}
+		}
+	}
+	return attributes
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+// externalUser maps a validated assertion to the external user that is synced into Grafana.
+func (s *Settings) externalUser(assertion *gosaml.Assertion) (*models.ExternalUserInfo, error) {
+	var nameID string
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		nameID = assertion.Subject.NameID.Value
+	}
+
+	attributes := assertionAttributes(assertion)
+	extUser := &models.ExternalUserInfo{
+		AuthModule: AuthModule,
+		AuthId:     nameID,
+		Name:       first(attributes[s.AttributeName]),
+		Login:      first(attributes[s.AttributeLogin]),
+		Email:      first(attributes[s.AttributeEmail]),
+		OrgRoles:   map[int64]models.RoleType{},
+	}
+
+	if extUser.Email == "" && strings.Contains(nameID, "@") {
+		extUser.Email = nameID
+	}
+	if extUser.Login == "" {
+		extUser.Login = extUser.Email
+	}
+	if extUser.Login == "" {
+		return nil, ErrMissingIdentity
+	}
+	if extUser.AuthId == "" {
+		extUser.AuthId = extUser.Login
+	}
+
+	if s.AttributeGroups != "" {
+		extUser.Groups = attributes[s.AttributeGroups]
+	}
+
+	var role models.RoleType
+	if s.AttributeRole != "" {
+		roleValues := attributes[s.AttributeRole]
+		role = s.role(roleValues)
+
+		isGrafanaAdmin := containsAny(roleValues, s.RoleValuesGrafanaAdmin)
+		extUser.IsGrafanaAdmin = &isGrafanaAdmin
+	}
+
+	if s.AttributeOrg != "" && len(s.OrgMapping) > 0 {
+		orgValues := attributes[s.AttributeOrg]
+		for _, mapping := range s.OrgMapping {
+			if !containsAny(orgValues, []string{mapping.Value}) {
+				continue
+			}
+			orgRole := mapping.Role
+			if orgRole == "" {
+				orgRole = role
+			}
+			if orgRole == "" {
+				orgRole = models.ROLE_VIEWER
+			}
+			if current, ok := extUser.OrgRoles[mapping.OrgID]; !ok || orgRole.Includes(current) {
+				extUser.OrgRoles[mapping.OrgID] = orgRole
+			}
+		}
+	} else if role != "" {
+		// Like OAuth, the role applies to the auto-assigned organization or the default one.
+		orgID := int64(1)
+		if setting.AutoAssignOrg && setting.AutoAssignOrgId > 0 {
+			orgID = int64(setting.AutoAssignOrgId)
+		}
+		extUser.OrgRoles[orgID] = role
+	}
+
+	return extUser, nil
+}
+
+// role returns the organization role matching the values of the role attribute.
+func (s *Settings) role(values []string) models.RoleType {
+	switch {
+	case containsAny(values, s.RoleValuesAdmin) || containsAny(values, s.RoleValuesGrafanaAdmin):
+		return models.ROLE_ADMIN
+	case containsAny(values, s.RoleValuesEditor):
+		return models.ROLE_EDITOR
+	default:
+		return models.ROLE_VIEWER
+	}
+}
+
+func containsAny(values []string, candidates []string) bool {
+	for _, v := range values {
+		for _, c := range candidates {
+			if strings.EqualFold(v, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
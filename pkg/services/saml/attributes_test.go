@@ -0,0 +1,154 @@
+package saml
+
+import (
+	"testing"
+
+	gosaml "github.com/crewjam/saml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestExternalUser(t *testing.T) {
+	assertion := func(nameID string, attributes ...gosaml.Attribute) *gosaml.Assertion {
+		return &gosaml.Assertion{
+			Subject:             &gosaml.Subject{NameID: &gosaml.NameID{Value: nameID}},
+			AttributeStatements: []gosaml.AttributeStatement{{Attributes: attributes}},
+		}
+	}
+
+	t.Run("Falls back to the name ID for the email and login", func(t *testing.T) {
+		settings := &Settings{AttributeLogin: "login", AttributeEmail: "mail"}
+
+		extUser, err := settings.externalUser(assertion("bjensen@example.com"))
+		require.NoError(t, err)
+
+		assert.Equal(t, "bjensen@example.com", extUser.AuthId)
+		assert.Equal(t, "bjensen@example.com", extUser.Email)
+		assert.Equal(t, "bjensen@example.com", extUser.Login)
+		assert.Empty(t, extUser.OrgRoles)
+		assert.Nil(t, extUser.IsGrafanaAdmin)
+	})
+
+	t.Run("Matches attributes by friendly name", func(t *testing.T) {
+		settings := &Settings{AttributeEmail: "mail"}
+		attr := stringAttribute("urn:oid:0.9.2342.19200300.100.1.3", "bjensen@example.com")
+		attr.FriendlyName = "mail"
+
+		extUser, err := settings.externalUser(assertion("transient-id", attr))
+		require.NoError(t, err)
+		assert.Equal(t, "bjensen@example.com", extUser.Email)
+	})
+
+	t.Run("Requires a login or an email", func(t *testing.T) {
+		settings := &Settings{AttributeLogin: "login", AttributeEmail: "mail"}
+
+		_, err := settings.externalUser(assertion("transient-id"))
+		assert.ErrorIs(t, err, ErrMissingIdentity)
+	})
+
+	t.Run("Maps role values", func(t *testing.T) {
+		settings := &Settings{
+			AttributeLogin:         "login",
+			AttributeRole:          "role",
+			RoleValuesEditor:       []string{"editor"},
+			RoleValuesAdmin:        []string{"admin"},
+			RoleValuesGrafanaAdmin: []string{"superadmin"},
+		}
+
+		tests := []struct {
+			values         []string
+			role           models.RoleType
+			isGrafanaAdmin bool
+		}{
+			{values: []string{"Editor"}, role: models.ROLE_EDITOR},
+			{values: []string{"editor", "admin"}, role: models.ROLE_ADMIN},
+			{values: []string{"superadmin"}, role: models.ROLE_ADMIN, isGrafanaAdmin: true},
+			{values: []string{"unknown"}, role: models.ROLE_VIEWER},
+			{values: nil, role: models.ROLE_VIEWER},
+		}
+		for _, tc := range tests {
+			extUser, err := settings.externalUser(assertion("id",
+				stringAttribute("login", "bjensen"),
+				stringAttribute("role", tc.values...),
+			))
+			require.NoError(t, err)
+
+			assert.Equal(t, map[int64]models.RoleType{1: tc.role}, extUser.OrgRoles, "values %v", tc.values)
+			require.NotNil(t, extUser.IsGrafanaAdmin)
+			assert.Equal(t, tc.isGrafanaAdmin, *extUser.IsGrafanaAdmin, "values %v", tc.values)
+		}
+	})
+
+	t.Run("Maps organizations", func(t *testing.T) {
+		settings := &Settings{
+			AttributeLogin:   "login",
+			AttributeRole:    "role",
+			AttributeOrg:     "org",
+			RoleValuesEditor: []string{"editor"},
+			OrgMapping: []OrgMapping{
+				{Value: "engineering", OrgID: 2},
+				{Value: "sales", OrgID: 3, Role: models.ROLE_VIEWER},
+				{Value: "platform", OrgID: 2, Role: models.ROLE_ADMIN},
+				{Value: "finance", OrgID: 4},
+			},
+		}
+
+		extUser, err := settings.externalUser(assertion("id",
+			stringAttribute("login", "bjensen"),
+			stringAttribute("role", "editor"),
+			stringAttribute("org", "engineering", "sales", "platform"),
+		))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[int64]models.RoleType{
+			2: models.ROLE_ADMIN,
+			3: models.ROLE_VIEWER,
+		}, extUser.OrgRoles)
+	})
+}
+
+func TestReadSettings(t *testing.T) {
+	t.Run("Reads the [auth.saml] section", func(t *testing.T) {
+		file, err := ini.Load([]byte(`
+[auth.saml]
+enabled = true
+signature_algorithm = rsa-sha512
+name_id_format = email
+role_values_editor = editor, developer
+org_mapping = engineering:2, sales:3:Viewer
+`))
+		require.NoError(t, err)
+
+		settings, err := readSettings(file.Section("auth.saml"))
+		require.NoError(t, err)
+
+		assert.True(t, settings.Enabled)
+		assert.True(t, settings.AllowSignUp)
+		assert.Equal(t, "http://www.w3.org/2001/04/xmldsig-more#rsa-sha512", settings.SignatureAlgorithm)
+		assert.Equal(t, "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress", settings.NameIDFormat)
+		assert.Equal(t, []string{"editor", "developer"}, settings.RoleValuesEditor)
+		assert.Equal(t, []OrgMapping{
+			{Value: "engineering", OrgID: 2},
+			{Value: "sales", OrgID: 3, Role: models.ROLE_VIEWER},
+		}, settings.OrgMapping)
+	})
+
+	errorCases := map[string]string{
+		"unknown signature algorithm":   "signature_algorithm = dsa-sha1",
+		"org mapping without org":       "org_mapping = engineering",
+		"org mapping with invalid org":  "org_mapping = engineering:main",
+		"org mapping with invalid role": "org_mapping = engineering:2:Owner",
+	}
+	for desc, config := range errorCases {
+		t.Run("Rejects "+desc, func(t *testing.T) {
+			file, err := ini.Load([]byte("[auth.saml]\n" + config))
+			require.NoError(t, err)
+
+			_, err = readSettings(file.Section("auth.saml"))
+			assert.Error(t, err)
+		})
+	}
+}
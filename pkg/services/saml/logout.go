@@ -0,0 +1,353 @@
+package saml
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/beevik/etree"
+	gosaml "github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+)
+
+var hashes = map[string]crypto.Hash{
+	dsig.RSASHA1SignatureMethod:   crypto.SHA1,
+	dsig.RSASHA256SignatureMethod: crypto.SHA256,
+	dsig.RSASHA512SignatureMethod: crypto.SHA512,
+}
+
+// NewLogoutRequest creates a logout request that ends the identity provider session that belongs to an auth token.
+// ErrNoSession is returned if the auth token was not issued for a SAML login.
+func (s *SAMLService) NewLogoutRequest(tokenID int64) (*Message, error) {
+	value, err := s.RemoteCacheService.Get(sessionKey(tokenID))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil, ErrNoSession
+		}
+		return nil, err
+	}
+	session := value.(*Session)
+	s.deleteSession(tokenID, session)
+
+	binding, location := s.binding(s.sp.GetSLOBindingLocation)
+	if location == "" {
+		return nil, errors.New("the identity provider has no single logout service")
+	}
+
+	// The request is signed once the session index has been added.
+	unsigned := *s.sp
+	unsigned.SignatureMethod = ""
+	req, err := unsigned.MakeLogoutRequest(location, session.NameID)
+	if err != nil {
+		return nil, err
+	}
+	if session.SessionIndex != "" {
+		req.SessionIndex = &gosaml.SessionIndex{Value: session.SessionIndex}
+	}
+
+	if binding == gosaml.HTTPPostBinding {
+		if s.sp.SignatureMethod != "" {
+			if err := s.sp.SignLogoutRequest(req); err != nil {
+				return nil, err
+			}
+		}
+		return &Message{PostForm: req.Post("")}, nil
+	}
+
+	redirectURL, err := s.signRedirect(req.Redirect(""), "SAMLRequest")
+	if err != nil {
+		return nil, err
+	}
+	return &Message{RedirectURL: redirectURL}, nil
+}
+
+func (s *SAMLService) deleteSession(tokenID int64, session *Session) {
+	if err := s.RemoteCacheService.Delete(sessionKey(tokenID)); err != nil {
+		s.log.Warn("Failed to delete SAML session", "error", err)
+	}
+	if session.NameID == "" {
+		return
+	}
+	if err := s.RemoteCacheService.Delete(nameIDKey(session.NameID)); err != nil {
+		s.log.Warn("Failed to delete SAML session", "error", err)
+	}
+}
+
+// ValidateLogoutResponse validates the response of the identity provider to a logout request.
+func (s *SAMLService) ValidateLogoutResponse(req *http.Request) error {
+	raw, err := s.readMessage(req, "SAMLResponse")
+	if err != nil {
+		return err
+	}
+
+	var resp gosaml.LogoutResponse
+	if err := xml.Unmarshal(raw, &resp); err != nil {
+		return fmt.Errorf("failed to parse logout response: %w", err)
+	}
+
+	if err := s.validateMessage(resp.Destination, resp.Issuer, resp.IssueInstant); err != nil {
+		return err
+	}
+	if resp.Status.StatusCode.Value != gosaml.StatusSuccess {
+		return fmt.Errorf("logout failed with status %s", resp.Status.StatusCode.Value)
+	}
+
+	return nil
+}
+
+// ParseLogoutRequest validates a logout request sent by the identity provider.
+// The returned session is nil if the request does not match a session of this instance.
+func (s *SAMLService) ParseLogoutRequest(req *http.Request) (*gosaml.LogoutRequest, *Session, error) {
+	raw, err := s.readMessage(req, "SAMLRequest")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var logoutReq gosaml.LogoutRequest
+	if err := xml.Unmarshal(raw, &logoutReq); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse logout request: %w", err)
+	}
+
+	if err := s.validateMessage(logoutReq.Destination, logoutReq.Issuer, logoutReq.IssueInstant); err != nil {
+		return nil, nil, err
+	}
+	if logoutReq.NameID == nil || logoutReq.NameID.Value == "" {
+		return nil, nil, errors.New("logout request does not contain a name ID")
+	}
+
+	value, err := s.RemoteCacheService.Get(nameIDKey(logoutReq.NameID.Value))
+	if err != nil {
+		if errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return &logoutReq, nil, nil
+		}
+		return nil, nil, err
+	}
+
+	return &logoutReq, value.(*Session), nil
+}
+
+// NewLogoutResponse creates the response to a logout request of the identity provider.
+func (s *SAMLService) NewLogoutResponse(requestID string, relayState string) (*Message, error) {
+	binding, location := s.binding(s.sp.GetSLOBindingLocation)
+	if location == "" {
+		return nil, errors.New("the identity provider has no single logout service")
+	}
+
+	resp, err := s.sp.MakeLogoutResponse(location, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if binding == gosaml.HTTPPostBinding {
+		return &Message{PostForm: resp.Post(relayState)}, nil
+	}
+
+	resp.Signature = nil
+	redirectURL, err := s.signRedirect(resp.Redirect(relayState), "SAMLResponse")
+	if err != nil {
+		return nil, err
+	}
+	return &Message{RedirectURL: redirectURL}, nil
+}
+
+// readMessage decodes a SAML message received with the HTTP-Redirect or HTTP-POST binding and verifies its signature.
+func (s *SAMLService) readMessage(req *http.Request, param string) ([]byte, error) {
+	certs, err := s.idpSigningCerts()
+	if err != nil {
+		return nil, err
+	}
+
+	if encoded := req.URL.Query().Get(param); encoded != "" {
+		compressed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode %s: %w", param, err)
+		}
+		raw, err := ioutil.ReadAll(flate.NewReader(bytes.NewReader(compressed)))
+		if err != nil {
+			return nil, fmt.Errorf("failed to inflate %s: %w", param, err)
+		}
+		if err := verifyRedirectSignature(req.URL.RawQuery, param, certs); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}
+
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(req.PostForm.Get(param))
+	if err != nil || len(raw) == 0 {
+		return nil, fmt.Errorf("missing or invalid %s", param)
+	}
+
+	doc := etree.NewDocument()
+	if err := doc.ReadFromBytes(raw); err != nil {
+		return nil, err
+	}
+	if doc.Root() == nil {
+		return nil, fmt.Errorf("empty %s", param)
+	}
+
+	validationContext := dsig.NewDefaultValidationContext(&dsig.MemoryX509CertificateStore{Roots: certs})
+	validationContext.IdAttribute = "ID"
+	validated, err := validationContext.Validate(doc.Root())
+	if err != nil {
+		return nil, fmt.Errorf("invalid signature: %w", err)
+	}
+
+	// Only the signed element is used from here on.
+	validatedDoc := etree.NewDocument()
+	validatedDoc.SetRoot(validated)
+	return validatedDoc.WriteToBytes()
+}
+
+// validateMessage checks the common fields of logout requests and responses.
+func (s *SAMLService) validateMessage(destination string, issuer *gosaml.Issuer, issueInstant time.Time) error {
+	if destination != s.sp.SloURL.String() {
+		return fmt.Errorf("destination %q does not match %q", destination, s.sp.SloURL.String())
+	}
+	if issuer == nil || issuer.Value != s.sp.IDPMetadata.EntityID {
+		return fmt.Errorf("issuer does not match %q", s.sp.IDPMetadata.EntityID)
+	}
+
+	now := gosaml.TimeNow()
+	if issueInstant.Add(gosaml.MaxIssueDelay).Before(now) {
+		return errors.New("the message has expired")
+	}
+	if issueInstant.Add(-gosaml.MaxClockSkew).After(now) {
+		return errors.New("the message was issued in the future")
+	}
+
+	return nil
+}
+
+// signRedirect signs the query of an HTTP-Redirect binding URL as described in section 3.4.4.1 of the SAML bindings.
+func (s *SAMLService) signRedirect(u *url.URL, param string) (string, error) {
+	if s.sp.SignatureMethod == "" {
+		return u.String(), nil
+	}
+
+	query := u.Query()
+	signed := param + "=" + url.QueryEscape(query.Get(param))
+	if relayState := query.Get("RelayState"); relayState != "" {
+		signed += "&RelayState=" + url.QueryEscape(relayState)
+	}
+	signed += "&SigAlg=" + url.QueryEscape(s.sp.SignatureMethod)
+
+	hash := hashes[s.sp.SignatureMethod]
+	h := hash.New()
+	h.Write([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.sp.Key, hash, h.Sum(nil))
+	if err != nil {
+		return "", err
+	}
+	signed += "&Signature=" + url.QueryEscape(base64.StdEncoding.EncodeToString(signature))
+
+	query.Del(param)
+	query.Del("RelayState")
+	if len(query) > 0 {
+		signed = query.Encode() + "&" + signed
+	}
+	u.RawQuery = signed
+
+	return u.String(), nil
+}
+
+// verifyRedirectSignature verifies the signature of an HTTP-Redirect binding query, using the parameters as they were received.
+func verifyRedirectSignature(rawQuery string, param string, certs []*x509.Certificate) error {
+	values := map[string]string{}
+	for _, part := range strings.Split(rawQuery, "&") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) == 2 {
+			values[kv[0]] = kv[1]
+		}
+	}
+
+	if values["Signature"] == "" || values["SigAlg"] == "" {
+		return errors.New("the message is not signed")
+	}
+
+	sigAlg, err := url.QueryUnescape(values["SigAlg"])
+	if err != nil {
+		return err
+	}
+	hash, ok := hashes[sigAlg]
+	if !ok {
+		return fmt.Errorf("unsupported signature algorithm %s", sigAlg)
+	}
+
+	encodedSignature, err := url.QueryUnescape(values["Signature"])
+	if err != nil {
+		return err
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return err
+	}
+
+	signed := param + "=" + values[param]
+	if relayState, ok := values["RelayState"]; ok {
+		signed += "&RelayState=" + relayState
+	}
+	signed += "&SigAlg=" + values["SigAlg"]
+
+	h := hash.New()
+	h.Write([]byte(signed))
+	digest := h.Sum(nil)
+	for _, cert := range certs {
+		if key, ok := cert.PublicKey.(*rsa.PublicKey); ok {
+			if rsa.VerifyPKCS1v15(key, hash, digest, signature) == nil {
+				return nil
+			}
+		}
+	}
+
+	return errors.New("invalid signature")
+}
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// idpSigningCerts returns the certificates the identity provider signs messages with.
+func (s *SAMLService) idpSigningCerts() ([]*x509.Certificate, error) {
+	var encoded []string
+	for _, descriptor := range s.sp.IDPMetadata.IDPSSODescriptors {
+		for _, keyDescriptor := range descriptor.KeyDescriptors {
+			if keyDescriptor.Use != "encryption" && keyDescriptor.KeyInfo.Certificate != "" {
+				encoded = append(encoded, keyDescriptor.KeyInfo.Certificate)
+			}
+		}
+	}
+
+	var certs []*x509.Certificate
+	for _, e := range encoded {
+		der, err := base64.StdEncoding.DecodeString(whitespace.ReplaceAllString(e, ""))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode identity provider certificate: %w", err)
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity provider certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, errors.New("the identity provider metadata contains no signing certificate")
+	}
+	return certs, nil
+}
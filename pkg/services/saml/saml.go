@@ -0,0 +1,360 @@
+// Package saml implements a SAML 2.0 service provider that authenticates users against an
+// external identity provider.
+package saml
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	gosaml "github.com/crewjam/saml"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func init() {
+	remotecache.Register(&authnRequestState{})
+	remotecache.Register(&Session{})
+	registry.RegisterService(&SAMLService{})
+}
+
+const (
+	// AuthModule is the auth module of users authenticated with SAML.
+	AuthModule = "auth.saml"
+
+	requestKeyPrefix = "saml-request-"
+	sessionKeyPrefix = "saml-session-"
+	nameIDKeyPrefix  = "saml-nameid-"
+
+	// requestTTL is how long a user has to authenticate at the identity provider.
+	requestTTL = 10 * time.Minute
+)
+
+var (
+	// ErrUnknownRequest is returned for responses that don't belong to a request issued by Grafana.
+	ErrUnknownRequest = errors.New("SAML response does not belong to a known authentication request")
+	// ErrMissingIdentity is returned when an assertion has neither a login nor an email.
+	ErrMissingIdentity = errors.New("SAML assertion does not contain a login or an email")
+	// ErrNoSession is returned when no SAML session is known for an auth token.
+	ErrNoSession = errors.New("no SAML session found")
+)
+
+// SAMLService is a SAML 2.0 service provider. It issues signed authentication requests,
+// validates the assertions of the identity provider and implements single logout.
+type SAMLService struct {
+	Cfg                *setting.Cfg             `inject:""`
+	RemoteCacheService *remotecache.RemoteCache `inject:""`
+
+	settings *Settings
+	sp       *gosaml.ServiceProvider
+	log      log.Logger
+}
+
+// authnRequestState is stored between the authentication request and the response.
+type authnRequestState struct {
+	RequestID  string
+	RedirectTo string
+}
+
+// Session identifies the session of a user at the identity provider.
+type Session struct {
+	UserID       int64
+	NameID       string
+	SessionIndex string
+}
+
+// Message is an outgoing SAML protocol message. It is delivered either with the HTTP-Redirect binding,
+// in which case RedirectURL is set, or with the HTTP-POST binding as an auto-submitting HTML form.
+type Message struct {
+	RedirectURL string
+	PostForm    []byte
+}
+
+// Init initializes the SAML service provider.
+func (s *SAMLService) Init() error {
+	s.log = log.New("saml")
+
+	if !s.IsEnabled() {
+		return nil
+	}
+
+	settings, err := readSettings(s.Cfg.Raw.Section("auth.saml"))
+	if err != nil {
+		return fmt.Errorf("invalid SAML configuration: %w", err)
+	}
+
+	sp, err := s.newServiceProvider(settings)
+	if err != nil {
+		return fmt.Errorf("invalid SAML configuration: %w", err)
+	}
+
+	gosaml.MaxIssueDelay = settings.MaxIssueDelay
+	s.settings = settings
+	s.sp = sp
+
+	return nil
+}
+
+// IsEnabled returns true if SAML authentication is enabled.
+func (s *SAMLService) IsEnabled() bool {
+	if s == nil || s.Cfg == nil {
+		return false
+	}
+
+	return s.Cfg.SAMLEnabled
+}
+
+// IsSingleLogoutEnabled returns true if logging out of Grafana also logs the user out of the identity provider.
+func (s *SAMLService) IsSingleLogoutEnabled() bool {
+	return s.IsEnabled() && s.Cfg.SAMLSingleLogoutEnabled
+}
+
+// IsSignupAllowed returns true if users that don't exist yet are created on login.
+func (s *SAMLService) IsSignupAllowed() bool {
+	return s.settings.AllowSignUp
+}
+
+func (s *SAMLService) newServiceProvider(settings *Settings) (*gosaml.ServiceProvider, error) {
+	cert, key, err := settings.keyPair()
+	if err != nil {
+		return nil, err
+	}
+
+	idpMetadata, err := loadIDPMetadata(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	rootURL, err := url.Parse(strings.TrimSuffix(s.Cfg.AppURL, "/"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &gosaml.ServiceProvider{
+		Key:                   key,
+		Certificate:           cert,
+		MetadataURL:           *rootURL.ResolveReference(&url.URL{Path: rootURL.Path + "/saml/metadata"}),
+		AcsURL:                *rootURL.ResolveReference(&url.URL{Path: rootURL.Path + "/saml/acs"}),
+		SloURL:                *rootURL.ResolveReference(&url.URL{Path: rootURL.Path + "/saml/slo"}),
+		IDPMetadata:           idpMetadata,
+		AuthnNameIDFormat:     gosaml.NameIDFormat(settings.NameIDFormat),
+		MetadataValidDuration: settings.MetadataValidDuration,
+		AllowIDPInitiated:     settings.AllowIDPInitiated,
+		SignatureMethod:       settings.SignatureAlgorithm,
+	}, nil
+}
+
+// loadIDPMetadata reads the identity provider metadata from the configured source.
+func loadIDPMetadata(settings *Settings) (*gosaml.EntityDescriptor, error) {
+	var raw []byte
+	var err error
+
+	switch {
+	case settings.IDPMetadata != "":
+		raw, err = readValue("idp_metadata", settings.IDPMetadata, "")
+	case settings.IDPMetadataPath != "":
+		// We can ignore the gosec G304 warning on this one because `IDPMetadataPath` comes
+		// from the Grafana configuration file.
+		// nolint:gosec
+		raw, err = ioutil.ReadFile(filepath.Clean(settings.IDPMetadataPath))
+	case settings.IDPMetadataURL != "":
+		raw, err = fetchIDPMetadata(settings.IDPMetadataURL)
+	default:
+		return nil, errors.New("one of idp_metadata, idp_metadata_path or idp_metadata_url must be set")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the identity provider metadata: %w", err)
+	}
+
+	return parseIDPMetadata(raw)
+}
+
+var metadataClient = &http.Client{
+	Timeout: 30 * time.Second,
+}
+
+func fetchIDPMetadata(metadataURL string) ([]byte, error) {
+	resp, err := metadataClient.Get(metadataURL)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Warnf("Failed to close response body: %v", err)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return ioutil.ReadAll(resp.Body)
+}
+
+// parseIDPMetadata parses an EntityDescriptor, or the first identity provider of an EntitiesDescriptor.
+func parseIDPMetadata(raw []byte) (*gosaml.EntityDescriptor, error) {
+	entity := &gosaml.EntityDescriptor{}
+	if err := xml.Unmarshal(raw, entity); err == nil {
+		if len(entity.IDPSSODescriptors) == 0 {
+			return nil, errors.New("the metadata does not describe an identity provider")
+		}
+		return entity, nil
+	}
+
+	entities := &gosaml.EntitiesDescriptor{}
+	if err := xml.Unmarshal(raw, entities); err != nil {
+		return nil, fmt.Errorf("failed to parse the identity provider metadata: %w", err)
+	}
+	for i := range entities.EntityDescriptors {
+		if len(entities.EntityDescriptors[i].IDPSSODescriptors) > 0 {
+			return &entities.EntityDescriptors[i], nil
+		}
+	}
+
+	return nil, errors.New("the metadata does not describe an identity provider")
+}
+
+// Metadata returns the XML metadata of the service provider.
+func (s *SAMLService) Metadata() ([]byte, error) {
+	return xml.MarshalIndent(s.sp.Metadata(), "", "  ")
+}
+
+// NewAuthnRequest creates a signed authentication request for the identity provider.
+// The user is sent back to redirectTo after a successful login.
+func (s *SAMLService) NewAuthnRequest(redirectTo string) (*Message, error) {
+	binding, location := s.binding(s.sp.GetSSOBindingLocation)
+	if location == "" {
+		return nil, errors.New("the identity provider has no single sign-on service")
+	}
+
+	req, err := s.sp.MakeAuthenticationRequest(location)
+	if err != nil {
+		return nil, err
+	}
+
+	relayState, err := util.GetRandomString(32)
+	if err != nil {
+		return nil, err
+	}
+	state := &authnRequestState{RequestID: req.ID, RedirectTo: redirectTo}
+	if err := s.RemoteCacheService.Set(requestKeyPrefix+relayState, state, requestTTL); err != nil {
+		return nil, err
+	}
+
+	if binding == gosaml.HTTPPostBinding {
+		return &Message{PostForm: req.Post(relayState)}, nil
+	}
+
+	// The HTTP-Redirect binding signs the query string instead of the XML document.
+	req.Signature = nil
+	redirectURL, err := s.signRedirect(req.Redirect(relayState), "SAMLRequest")
+	if err != nil {
+		return nil, err
+	}
+	return &Message{RedirectURL: redirectURL}, nil
+}
+
+// binding returns the binding and location of an identity provider endpoint, preferring HTTP-Redirect.
+func (s *SAMLService) binding(locate func(binding string) string) (string, string) {
+	if location := locate(gosaml.HTTPRedirectBinding); location != "" {
+		return gosaml.HTTPRedirectBinding, location
+	}
+	return gosaml.HTTPPostBinding, locate(gosaml.HTTPPostBinding)
+}
+
+// LoginResult is the outcome of a successful SAML login.
+type LoginResult struct {
+	ExternalUser *models.ExternalUserInfo
+	Session      *Session
+	RedirectTo   string
+}
+
+// ParseResponse validates the SAML response posted to the assertion consumer service.
+func (s *SAMLService) ParseResponse(req *http.Request) (*LoginResult, error) {
+	if err := req.ParseForm(); err != nil {
+		return nil, err
+	}
+
+	var possibleRequestIDs []string
+	var redirectTo string
+	if relayState := req.PostForm.Get("RelayState"); relayState != "" {
+		key := requestKeyPrefix + relayState
+		value, err := s.RemoteCacheService.Get(key)
+		if err == nil {
+			if err := s.RemoteCacheService.Delete(key); err != nil {
+				s.log.Warn("Failed to delete SAML request state", "error", err)
+			}
+			state := value.(*authnRequestState)
+			possibleRequestIDs = []string{state.RequestID}
+			redirectTo = state.RedirectTo
+		} else if !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			return nil, err
+		}
+	}
+
+	if possibleRequestIDs == nil && !s.settings.AllowIDPInitiated {
+		return nil, ErrUnknownRequest
+	}
+
+	assertion, err := s.sp.ParseResponse(req, possibleRequestIDs)
+	if err != nil {
+		var invalidErr *gosaml.InvalidResponseError
+		if errors.As(err, &invalidErr) {
+			s.log.Debug("Invalid SAML response", "error", invalidErr.PrivateErr)
+		}
+		return nil, err
+	}
+
+	extUser, err := s.settings.externalUser(assertion)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &Session{}
+	if assertion.Subject != nil && assertion.Subject.NameID != nil {
+		session.NameID = assertion.Subject.NameID.Value
+	}
+	for _, statement := range assertion.AuthnStatements {
+		if statement.SessionIndex != "" {
+			session.SessionIndex = statement.SessionIndex
+			break
+		}
+	}
+
+	return &LoginResult{ExternalUser: extUser, Session: session, RedirectTo: redirectTo}, nil
+}
+
+// StoreSession remembers the identity provider session of a logged in user for the lifetime of the auth token,
+// so that the session can be ended at the identity provider on logout and vice versa.
+func (s *SAMLService) StoreSession(tokenID int64, session *Session) error {
+	if err := s.RemoteCacheService.Set(sessionKey(tokenID), session, s.Cfg.LoginMaxLifetime); err != nil {
+		return err
+	}
+	if session.NameID == "" {
+		return nil
+	}
+	return s.RemoteCacheService.Set(nameIDKey(session.NameID), session, s.Cfg.LoginMaxLifetime)
+}
+
+func sessionKey(tokenID int64) string {
+	return fmt.Sprintf("%s%d", sessionKeyPrefix, tokenID)
+}
+
+// nameIDKey hashes the name ID since it may contain characters that are not allowed in cache keys.
+func nameIDKey(nameID string) string {
+	hash := sha256.Sum256([]byte(nameID))
+	return nameIDKeyPrefix + hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,382 @@
+package saml
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"html"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/beevik/etree"
+	gosaml "github.com/crewjam/saml"
+	"github.com/crewjam/saml/logger"
+	dsig "github.com/russellhaering/goxmldsig"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestSAMLLogin(t *testing.T) {
+	samlScenario(t, "Should log in with a signed response to a signed request", func(t *testing.T, sc *scenarioContext) {
+		message, err := sc.service.NewAuthnRequest("/d/abc")
+		require.NoError(t, err)
+		require.NotEmpty(t, message.RedirectURL)
+
+		redirectURL, err := url.Parse(message.RedirectURL)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(message.RedirectURL, "https://idp.example.com/sso?"))
+		require.NoError(t, verifyRedirectSignature(redirectURL.RawQuery, "SAMLRequest", []*x509.Certificate{sc.spCert}))
+
+		form := sc.authenticate(t, message.RedirectURL)
+		result, err := sc.service.ParseResponse(acsRequest(form))
+		require.NoError(t, err)
+
+		assert.Equal(t, "/d/abc", result.RedirectTo)
+		assert.Equal(t, "bjensen-id", result.Session.NameID)
+		assert.NotEmpty(t, result.Session.SessionIndex)
+
+		extUser := result.ExternalUser
+		assert.Equal(t, AuthModule, extUser.AuthModule)
+		assert.Equal(t, "bjensen-id", extUser.AuthId)
+		assert.Equal(t, "bjensen", extUser.Login)
+		assert.Equal(t, "bjensen@example.com", extUser.Email)
+		assert.Equal(t, "Barbara Jensen", extUser.Name)
+		assert.Equal(t, []string{"ops", "dev"}, extUser.Groups)
+		assert.Equal(t, map[int64]models.RoleType{1: models.ROLE_EDITOR}, extUser.OrgRoles)
+		require.NotNil(t, extUser.IsGrafanaAdmin)
+		assert.False(t, *extUser.IsGrafanaAdmin)
+
+		_, err = sc.service.ParseResponse(acsRequest(form))
+		assert.ErrorIs(t, err, ErrUnknownRequest)
+	})
+
+	samlScenario(t, "Should reject responses to unknown requests", func(t *testing.T, sc *scenarioContext) {
+		message, err := sc.service.NewAuthnRequest("")
+		require.NoError(t, err)
+
+		form := sc.authenticate(t, message.RedirectURL)
+		form.Set("RelayState", "unknown")
+		_, err = sc.service.ParseResponse(acsRequest(form))
+		assert.ErrorIs(t, err, ErrUnknownRequest)
+	})
+
+	samlScenario(t, "Should reject responses signed by another identity provider", func(t *testing.T, sc *scenarioContext) {
+		message, err := sc.service.NewAuthnRequest("")
+		require.NoError(t, err)
+
+		key, cert := generateKeyPair(t)
+		sc.idp.Key = key
+		sc.idp.Certificate = cert
+
+		form := sc.authenticate(t, message.RedirectURL)
+		_, err = sc.service.ParseResponse(acsRequest(form))
+		assert.Error(t, err)
+	})
+
+	samlScenario(t, "Should accept IdP initiated logins when allowed", func(t *testing.T, sc *scenarioContext) {
+		sc.service.settings.AllowIDPInitiated = true
+		sc.service.sp.AllowIDPInitiated = true
+
+		rec := httptest.NewRecorder()
+		sc.idp.ServeIDPInitiated(rec, httptest.NewRequest(http.MethodGet, "/", nil), sc.service.sp.MetadataURL.String(), "")
+		form := responseForm(t, rec)
+
+		result, err := sc.service.ParseResponse(acsRequest(form))
+		require.NoError(t, err)
+		assert.Equal(t, "bjensen", result.ExternalUser.Login)
+		assert.Empty(t, result.RedirectTo)
+	})
+}
+
+func TestSAMLMetadata(t *testing.T) {
+	samlScenario(t, "Should describe the service provider endpoints and certificate", func(t *testing.T, sc *scenarioContext) {
+		raw, err := sc.service.Metadata()
+		require.NoError(t, err)
+
+		var metadata gosaml.EntityDescriptor
+		require.NoError(t, xml.Unmarshal(raw, &metadata))
+		assert.Equal(t, "https://grafana.example.com/saml/metadata", metadata.EntityID)
+
+		require.Len(t, metadata.SPSSODescriptors, 1)
+		descriptor := metadata.SPSSODescriptors[0]
+		require.NotEmpty(t, descriptor.AssertionConsumerServices)
+		assert.Equal(t, "https://grafana.example.com/saml/acs", descriptor.AssertionConsumerServices[0].Location)
+		require.NotEmpty(t, descriptor.SingleLogoutServices)
+		assert.Equal(t, "https://grafana.example.com/saml/slo", descriptor.SingleLogoutServices[0].Location)
+		require.NotEmpty(t, descriptor.KeyDescriptors)
+		assert.Equal(t, base64.StdEncoding.EncodeToString(sc.spCert.Raw), descriptor.KeyDescriptors[0].KeyInfo.Certificate)
+	})
+}
+
+func TestSAMLSingleLogout(t *testing.T) {
+	samlScenario(t, "Should create a signed logout request for a stored session", func(t *testing.T, sc *scenarioContext) {
+		require.NoError(t, sc.service.StoreSession(7, &Session{UserID: 3, NameID: "bjensen-id", SessionIndex: "index-1"}))
+
+		message, err := sc.service.NewLogoutRequest(7)
+		require.NoError(t, err)
+
+		redirectURL, err := url.Parse(message.RedirectURL)
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(message.RedirectURL, "https://idp.example.com/slo?"))
+		require.NoError(t, verifyRedirectSignature(redirectURL.RawQuery, "SAMLRequest", []*x509.Certificate{sc.spCert}))
+
+		_, err = sc.service.NewLogoutRequest(7)
+		assert.ErrorIs(t, err, ErrNoSession)
+	})
+
+	samlScenario(t, "Should accept logout requests of the identity provider", func(t *testing.T, sc *scenarioContext) {
+		require.NoError(t, sc.service.StoreSession(7, &Session{UserID: 3, NameID: "bjensen-id"}))
+
+		logoutReq, err := sc.idpSigner().MakeLogoutRequest(sc.service.sp.SloURL.String(), "bjensen-id")
+		require.NoError(t, err)
+		raw, err := logoutReq.Bytes()
+		require.NoError(t, err)
+
+		parsed, session, err := sc.service.ParseLogoutRequest(postRequest("SAMLRequest", raw))
+		require.NoError(t, err)
+		assert.Equal(t, logoutReq.ID, parsed.ID)
+		require.NotNil(t, session)
+		assert.Equal(t, int64(3), session.UserID)
+
+		message, err := sc.service.NewLogoutResponse(parsed.ID, "")
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(message.RedirectURL, "https://idp.example.com/slo?"))
+	})
+
+	samlScenario(t, "Should accept logout requests with the redirect binding", func(t *testing.T, sc *scenarioContext) {
+		logoutReq, err := sc.idpSigner().MakeLogoutRequest(sc.service.sp.SloURL.String(), "unknown-id")
+		require.NoError(t, err)
+		logoutReq.Signature = nil
+
+		idpService := &SAMLService{sp: sc.idpSigner()}
+		redirectURL, err := idpService.signRedirect(logoutReq.Redirect("state"), "SAMLRequest")
+		require.NoError(t, err)
+
+		parsed, session, err := sc.service.ParseLogoutRequest(httptest.NewRequest(http.MethodGet, redirectURL, nil))
+		require.NoError(t, err)
+		assert.Equal(t, "unknown-id", parsed.NameID.Value)
+		assert.Nil(t, session)
+	})
+
+	samlScenario(t, "Should reject unsigned logout requests", func(t *testing.T, sc *scenarioContext) {
+		signer := sc.idpSigner()
+		signer.SignatureMethod = ""
+		logoutReq, err := signer.MakeLogoutRequest(sc.service.sp.SloURL.String(), "bjensen-id")
+		require.NoError(t, err)
+
+		_, _, err = sc.service.ParseLogoutRequest(httptest.NewRequest(http.MethodGet, logoutReq.Redirect("").String(), nil))
+		assert.Error(t, err)
+
+		raw, err := logoutReq.Bytes()
+		require.NoError(t, err)
+		_, _, err = sc.service.ParseLogoutRequest(postRequest("SAMLRequest", raw))
+		assert.Error(t, err)
+	})
+
+	samlScenario(t, "Should validate logout responses", func(t *testing.T, sc *scenarioContext) {
+		resp, err := sc.idpSigner().MakeLogoutResponse(sc.service.sp.SloURL.String(), "id-1")
+		require.NoError(t, err)
+		raw, err := elementBytes(resp.Element())
+		require.NoError(t, err)
+		assert.NoError(t, sc.service.ValidateLogoutResponse(postRequest("SAMLResponse", raw)))
+
+		resp, err = sc.idpSigner().MakeLogoutResponse("https://other.example.com/saml/slo", "id-1")
+		require.NoError(t, err)
+		raw, err = elementBytes(resp.Element())
+		require.NoError(t, err)
+		assert.Error(t, sc.service.ValidateLogoutResponse(postRequest("SAMLResponse", raw)))
+	})
+}
+
+type scenarioContext struct {
+	service *SAMLService
+	idp     *gosaml.IdentityProvider
+	spCert  *x509.Certificate
+}
+
+// authenticate sends an authentication request to the identity provider and returns the response form.
+func (sc *scenarioContext) authenticate(t *testing.T, requestURL string) url.Values {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	sc.idp.ServeSSO(rec, httptest.NewRequest(http.MethodGet, requestURL, nil))
+	return responseForm(t, rec)
+}
+
+// idpSigner returns a service provider that signs messages like the identity provider.
+func (sc *scenarioContext) idpSigner() *gosaml.ServiceProvider {
+	return &gosaml.ServiceProvider{
+		EntityID:        sc.idp.MetadataURL.String(),
+		Key:             sc.idp.Key.(*rsa.PrivateKey),
+		Certificate:     sc.idp.Certificate,
+		IDPMetadata:     &gosaml.EntityDescriptor{},
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}
+}
+
+var formInput = regexp.MustCompile(`name="(SAMLResponse|RelayState)" value="([^"]*)"`)
+
+func responseForm(t *testing.T, rec *httptest.ResponseRecorder) url.Values {
+	t.Helper()
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	form := url.Values{}
+	for _, match := range formInput.FindAllStringSubmatch(rec.Body.String(), -1) {
+		form.Set(match[1], html.UnescapeString(match[2]))
+	}
+	require.NotEmpty(t, form.Get("SAMLResponse"))
+	return form
+}
+
+func acsRequest(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "https://grafana.example.com/saml/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+func postRequest(param string, raw []byte) *http.Request {
+	form := url.Values{param: {base64.StdEncoding.EncodeToString(raw)}}
+	req := httptest.NewRequest(http.MethodPost, "https://grafana.example.com/saml/slo", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return req
+}
+
+type sessionProvider struct {
+	session *gosaml.Session
+}
+
+func (p sessionProvider) GetSession(w http.ResponseWriter, r *http.Request, req *gosaml.IdpAuthnRequest) *gosaml.Session {
+	return p.session
+}
+
+type serviceProviderProvider struct {
+	service *SAMLService
+}
+
+func (p serviceProviderProvider) GetServiceProvider(r *http.Request, serviceProviderID string) (*gosaml.EntityDescriptor, error) {
+	return p.service.sp.Metadata(), nil
+}
+
+func samlScenario(t *testing.T, desc string, fn func(t *testing.T, sc *scenarioContext)) {
+	t.Helper()
+
+	t.Run(desc, func(t *testing.T) {
+		spKey, spCert := generateKeyPair(t)
+		idpKey, idpCert := generateKeyPair(t)
+
+		idp := &gosaml.IdentityProvider{
+			Key:         idpKey,
+			Certificate: idpCert,
+			Logger:      logger.DefaultLogger,
+			MetadataURL: url.URL{Scheme: "https", Host: "idp.example.com", Path: "/metadata"},
+			SSOURL:      url.URL{Scheme: "https", Host: "idp.example.com", Path: "/sso"},
+			LogoutURL:   url.URL{Scheme: "https", Host: "idp.example.com", Path: "/slo"},
+			SessionProvider: sessionProvider{session: &gosaml.Session{
+				ID:         "session-1",
+				CreateTime: time.Now(),
+				ExpireTime: time.Now().Add(time.Hour),
+				Index:      "index-1",
+				NameID:     "bjensen-id",
+				UserName:   "bjensen",
+				CustomAttributes: []gosaml.Attribute{
+					stringAttribute("login", "bjensen"),
+					stringAttribute("mail", "bjensen@example.com"),
+					stringAttribute("displayName", "Barbara Jensen"),
+					stringAttribute("groups", "ops", "dev"),
+					stringAttribute("role", "grafana-editor"),
+				},
+			}},
+		}
+		idpMetadata, err := xml.Marshal(idp.Metadata())
+		require.NoError(t, err)
+
+		cfg := setting.NewCfg()
+		cfg.AppURL = "https://grafana.example.com/"
+		cfg.SAMLEnabled = true
+		cfg.SAMLSingleLogoutEnabled = true
+		cfg.LoginMaxLifetime = 24 * time.Hour
+
+		settings := &Settings{
+			Enabled:            true,
+			SingleLogout:       true,
+			AllowSignUp:        true,
+			Certificate:        base64.StdEncoding.EncodeToString(pemEncode("CERTIFICATE", spCert.Raw)),
+			PrivateKey:         base64.StdEncoding.EncodeToString(pemEncode("RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(spKey))),
+			SignatureAlgorithm: dsig.RSASHA256SignatureMethod,
+			NameIDFormat:       nameIDFormats["persistent"],
+			IDPMetadata:        base64.StdEncoding.EncodeToString(idpMetadata),
+			AttributeName:      "displayName",
+			AttributeLogin:     "login",
+			AttributeEmail:     "mail",
+			AttributeGroups:    "groups",
+			AttributeRole:      "role",
+			RoleValuesEditor:   []string{"grafana-editor"},
+			RoleValuesAdmin:    []string{"grafana-admin"},
+		}
+
+		service := &SAMLService{
+			Cfg:                cfg,
+			RemoteCacheService: remotecache.NewFakeStore(t),
+			settings:           settings,
+			log:                log.New("saml.test"),
+		}
+		service.sp, err = service.newServiceProvider(settings)
+		require.NoError(t, err)
+		idp.ServiceProviderProvider = serviceProviderProvider{service: service}
+
+		fn(t, &scenarioContext{service: service, idp: idp, spCert: spCert})
+	})
+}
+
+func elementBytes(el *etree.Element) ([]byte, error) {
+	doc := etree.NewDocument()
+	doc.SetRoot(el)
+	return doc.WriteToBytes()
+}
+
+func stringAttribute(name string, values ...string) gosaml.Attribute {
+	attr := gosaml.Attribute{Name: name, NameFormat: "urn:oasis:names:tc:SAML:2.0:attrname-format:basic"}
+	for _, v := range values {
+		attr.Values = append(attr.Values, gosaml.AttributeValue{Type: "xs:string", Value: v})
+	}
+	return attr
+}
+
+func generateKeyPair(t *testing.T) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "saml.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	return key, cert
+}
+
+func pemEncode(blockType string, der []byte) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+}
@@ -0,0 +1,225 @@
+package saml
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	dsig "github.com/russellhaering/goxmldsig"
+	"gopkg.in/ini.v1"
+
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// Settings holds the configuration of the [auth.saml] section.
+type Settings struct {
+	Enabled           bool
+	SingleLogout      bool
+	AllowSignUp       bool
+	AllowIDPInitiated bool
+
+	Certificate     string
+	CertificatePath string
+	PrivateKey      string
+	PrivateKeyPath  string
+
+	SignatureAlgorithm string
+	NameIDFormat       string
+
+	IDPMetadata     string
+	IDPMetadataPath string
+	IDPMetadataURL  string
+
+	MaxIssueDelay         time.Duration
+	MetadataValidDuration time.Duration
+
+	AttributeName   string
+	AttributeLogin  string
+	AttributeEmail  string
+	AttributeGroups string
+	AttributeRole   string
+	AttributeOrg    string
+
+	RoleValuesEditor       []string
+	RoleValuesAdmin        []string
+	RoleValuesGrafanaAdmin []string
+
+	// OrgMapping maps values of the organization attribute to organization roles.
+	OrgMapping []OrgMapping
+}
+
+// OrgMapping assigns users with a given value of the organization attribute a role in an organization.
+// An empty role means the role is taken from the role attribute.
+type OrgMapping struct {
+	Value string
+	OrgID int64
+	Role  models.RoleType
+}
+
+var signatureAlgorithms = map[string]string{
+	"":           "",
+	"rsa-sha1":   dsig.RSASHA1SignatureMethod,
+	"rsa-sha256": dsig.RSASHA256SignatureMethod,
+	"rsa-sha512": dsig.RSASHA512SignatureMethod,
+	"http://www.w3.org/2000/09/xmldsig#rsa-sha1":        dsig.RSASHA1SignatureMethod,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha256": dsig.RSASHA256SignatureMethod,
+	"http://www.w3.org/2001/04/xmldsig-more#rsa-sha512": dsig.RSASHA512SignatureMethod,
+}
+
+var nameIDFormats = map[string]string{
+	"":            "urn:oasis:names:tc:SAML:2.0:nameid-format:transient",
+	"transient":   "urn:oasis:names:tc:SAML:2.0:nameid-format:transient",
+	"persistent":  "urn:oasis:names:tc:SAML:2.0:nameid-format:persistent",
+	"email":       "urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress",
+	"unspecified": "urn:oasis:names:tc:SAML:1.1:nameid-format:unspecified",
+}
+
+// readSettings reads the SAML settings from the [auth.saml] section.
+func readSettings(section *ini.Section) (*Settings, error) {
+	s := &Settings{
+		Enabled:           section.Key("enabled").MustBool(false),
+		SingleLogout:      section.Key("single_logout").MustBool(false),
+		AllowSignUp:       section.Key("allow_sign_up").MustBool(true),
+		AllowIDPInitiated: section.Key("allow_idp_initiated").MustBool(false),
+
+		Certificate:     section.Key("certificate").String(),
+		CertificatePath: section.Key("certificate_path").String(),
+		PrivateKey:      section.Key("private_key").String(),
+		PrivateKeyPath:  section.Key("private_key_path").String(),
+
+		IDPMetadata:     section.Key("idp_metadata").String(),
+		IDPMetadataPath: section.Key("idp_metadata_path").String(),
+		IDPMetadataURL:  section.Key("idp_metadata_url").String(),
+
+		MaxIssueDelay:         section.Key("max_issue_delay").MustDuration(90 * time.Second),
+		MetadataValidDuration: section.Key("metadata_valid_duration").MustDuration(48 * time.Hour),
+
+		AttributeName:   section.Key("assertion_attribute_name").MustString("displayName"),
+		AttributeLogin:  section.Key("assertion_attribute_login").MustString("mail"),
+		AttributeEmail:  section.Key("assertion_attribute_email").MustString("mail"),
+		AttributeGroups: section.Key("assertion_attribute_groups").String(),
+		AttributeRole:   section.Key("assertion_attribute_role").String(),
+		AttributeOrg:    section.Key("assertion_attribute_org").String(),
+
+		RoleValuesEditor:       splitList(section.Key("role_values_editor").String()),
+		RoleValuesAdmin:        splitList(section.Key("role_values_admin").String()),
+		RoleValuesGrafanaAdmin: splitList(section.Key("role_values_grafana_admin").String()),
+	}
+
+	algorithm, ok := signatureAlgorithms[strings.ToLower(section.Key("signature_algorithm").String())]
+	if !ok {
+		return nil, fmt.Errorf("unsupported signature_algorithm %q", section.Key("signature_algorithm").String())
+	}
+	s.SignatureAlgorithm = algorithm
+
+	nameIDFormat := section.Key("name_id_format").String()
+	if format, ok := nameIDFormats[nameIDFormat]; ok {
+		nameIDFormat = format
+	}
+	s.NameIDFormat = nameIDFormat
+
+	orgMapping, err := parseOrgMapping(section.Key("org_mapping").String())
+	if err != nil {
+		return nil, err
+	}
+	s.OrgMapping = orgMapping
+
+	return s, nil
+}
+
+// parseOrgMapping parses a comma separated list of <attribute value>:<org id>[:<role>] entries.
+func parseOrgMapping(raw string) ([]OrgMapping, error) {
+	var mappings []OrgMapping
+	for _, entry := range splitList(raw) {
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid org_mapping entry %q", entry)
+		}
+
+		orgID, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil || orgID < 1 {
+			return nil, fmt.Errorf("invalid organization ID in org_mapping entry %q", entry)
+		}
+
+		mapping := OrgMapping{Value: parts[0], OrgID: orgID}
+		if len(parts) == 3 {
+			mapping.Role = models.RoleType(parts[2])
+			if !mapping.Role.IsValid() {
+				return nil, fmt.Errorf("invalid role in org_mapping entry %q", entry)
+			}
+		}
+		mappings = append(mappings, mapping)
+	}
+	return mappings, nil
+}
+
+// splitList splits a comma or space separated list of values.
+func splitList(raw string) []string {
+	return strings.FieldsFunc(raw, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+// keyPair loads the certificate and private key used to sign requests and published in the metadata.
+func (s *Settings) keyPair() (*x509.Certificate, *rsa.PrivateKey, error) {
+	certPEM, err := readValue("certificate", s.Certificate, s.CertificatePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := readValue("private_key", s.PrivateKey, s.PrivateKeyPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load the SAML key pair: %w", err)
+	}
+
+	key, ok := pair.PrivateKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, nil, errors.New("the SAML private key must be an RSA key")
+	}
+
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the SAML certificate: %w", err)
+	}
+
+	return cert, key, nil
+}
+
+// readValue returns an inline value, which may be base64 encoded, or the content of a file.
+func readValue(name string, value string, path string) ([]byte, error) {
+	if value != "" {
+		if decoded, err := base64.StdEncoding.DecodeString(value); err == nil {
+			return decoded, nil
+		}
+		if block, _ := pem.Decode([]byte(value)); block != nil {
+			return []byte(value), nil
+		}
+		return nil, fmt.Errorf("%s must be base64 encoded or in PEM format", name)
+	}
+
+	if path == "" {
+		return nil, fmt.Errorf("either %s or %s_path must be set", name, name)
+	}
+
+	// We can ignore the gosec G304 warning on this one because `path` comes
+	// from the Grafana configuration file.
+	// nolint:gosec
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s_path: %w", name, err)
+	}
+	return content, nil
+}
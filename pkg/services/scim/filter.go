@@ -0,0 +1,174 @@
+package scim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// condition is a single attribute expression of a SCIM filter, e.g. `userName eq "bjensen"`.
+type condition struct {
+	attr  string
+	op    string
+	value string
+}
+
+// filter is a conjunction of conditions. Only the subset of RFC 7644 filters that
+// identity providers use for lookups is supported: attribute expressions joined by "and".
+type filter []condition
+
+var supportedOperators = map[string]bool{
+	"eq": true,
+	"ne": true,
+	"co": true,
+	"sw": true,
+	"ew": true,
+	"pr": true,
+}
+
+// parseFilter parses the filter query parameter. An empty string yields an empty filter that matches everything.
+func parseFilter(raw string) (filter, error) {
+	tokens, err := tokenizeFilter(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var f filter
+	for i := 0; i < len(tokens); {
+		if len(f) > 0 {
+			if !strings.EqualFold(tokens[i], "and") {
+				return nil, fmt.Errorf("%w: expected 'and', got %q", errInvalidFilter, tokens[i])
+			}
+			i++
+		}
+
+		if i+1 >= len(tokens) {
+			return nil, fmt.Errorf("%w: incomplete expression", errInvalidFilter)
+		}
+
+		c := condition{attr: strings.ToLower(tokens[i]), op: strings.ToLower(tokens[i+1])}
+		if !supportedOperators[c.op] {
+			return nil, fmt.Errorf("%w: unsupported operator %q", errInvalidFilter, tokens[i+1])
+		}
+		i += 2
+
+		if c.op != "pr" {
+			if i >= len(tokens) {
+				return nil, fmt.Errorf("%w: missing value for %q", errInvalidFilter, c.attr)
+			}
+			c.value = tokens[i]
+			i++
+		}
+
+		f = append(f, c)
+	}
+
+	return f, nil
+}
+
+func tokenizeFilter(raw string) ([]string, error) {
+	var tokens []string
+	var current strings.Builder
+	inQuotes := false
+
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		switch {
+		case inQuotes && ch == '\\' && i+1 < len(raw):
+			i++
+			current.WriteByte(raw[i])
+		case ch == '"':
+			if inQuotes {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+			inQuotes = !inQuotes
+		case !inQuotes && (ch == ' ' || ch == '\t'):
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteByte(ch)
+		}
+	}
+
+	if inQuotes {
+		return nil, fmt.Errorf("%w: unterminated string", errInvalidFilter)
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens, nil
+}
+
+// matches reports whether the resource attributes satisfy every condition of the filter.
+// Attribute names are expected in lower case; comparisons are case-insensitive.
+func (f filter) matches(attrs map[string][]string) bool {
+	for _, c := range f {
+		if !c.matches(attrs[c.attr]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c condition) matches(values []string) bool {
+	if c.op == "pr" {
+		for _, v := range values {
+			if v != "" {
+				return true
+			}
+		}
+		return false
+	}
+
+	if c.op == "ne" {
+		for _, v := range values {
+			if strings.EqualFold(v, c.value) {
+				return false
+			}
+		}
+		return true
+	}
+
+	expected := strings.ToLower(c.value)
+	for _, v := range values {
+		actual := strings.ToLower(v)
+		switch c.op {
+		case "eq":
+			if actual == expected {
+				return true
+			}
+		case "co":
+			if strings.Contains(actual, expected) {
+				return true
+			}
+		case "sw":
+			if strings.HasPrefix(actual, expected) {
+				return true
+			}
+		case "ew":
+			if strings.HasSuffix(actual, expected) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// equalityValue returns the value of an "eq" condition on one of the given attributes,
+// which lets lookups by userName or displayName use an indexed query instead of a scan.
+func (f filter) equalityValue(attrs ...string) (string, bool) {
+	for _, c := range f {
+		if c.op != "eq" {
+			continue
+		}
+		for _, attr := range attrs {
+			if c.attr == attr {
+				return c.value, true
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,79 @@
+package scim
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilter(t *testing.T) {
+	t.Run("Empty filter matches everything", func(t *testing.T) {
+		f, err := parseFilter("")
+		require.NoError(t, err)
+		assert.True(t, f.matches(map[string][]string{}))
+	})
+
+	t.Run("Parses conjunctions of attribute expressions", func(t *testing.T) {
+		f, err := parseFilter(`userName eq "bjensen@example.com" and active eq true and emails pr`)
+		require.NoError(t, err)
+		assert.Equal(t, filter{
+			{attr: "username", op: "eq", value: "bjensen@example.com"},
+			{attr: "active", op: "eq", value: "true"},
+			{attr: "emails", op: "pr"},
+		}, f)
+	})
+
+	t.Run("Keeps escaped quotes and spaces in values", func(t *testing.T) {
+		f, err := parseFilter(`displayName eq "Team \"A\" ops"`)
+		require.NoError(t, err)
+		assert.Equal(t, `Team "A" ops`, f[0].value)
+	})
+
+	errorCases := []string{
+		`userName`,
+		`userName eq`,
+		`userName gt "a"`,
+		`userName eq "a" or active eq true`,
+		`userName eq "a`,
+	}
+	for _, raw := range errorCases {
+		t.Run("Rejects "+raw, func(t *testing.T) {
+			_, err := parseFilter(raw)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errInvalidFilter))
+		})
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	attrs := map[string][]string{
+		"username": {"BJensen"},
+		"emails":   {"bjensen@example.com", "babs@example.org"},
+		"active":   {"true"},
+	}
+
+	tests := []struct {
+		filter  string
+		matches bool
+	}{
+		{`userName eq "bjensen"`, true},
+		{`userName ne "bjensen"`, false},
+		{`userName sw "bj"`, true},
+		{`userName ew "sen"`, true},
+		{`emails co "example.org"`, true},
+		{`emails eq "nobody@example.com"`, false},
+		{`active eq "true" and userName eq "bjensen"`, true},
+		{`active eq "false" and userName eq "bjensen"`, false},
+		{`displayName pr`, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.filter, func(t *testing.T) {
+			f, err := parseFilter(tc.filter)
+			require.NoError(t, err)
+			assert.Equal(t, tc.matches, f.matches(attrs))
+		})
+	}
+}
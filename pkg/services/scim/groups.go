@@ -0,0 +1,472 @@
+package scim
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// listGroupsHandler handles GET /scim/v2/Groups.
+func (s *SCIMService) listGroupsHandler(c *models.ReqContext) response.Response {
+	f, err := parseFilter(c.Query("filter"))
+	if err != nil {
+		return s.scimError(err, "Failed to parse filter")
+	}
+
+	startIndex, count := pagination(c)
+	result, err := s.listGroups(c.OrgId, f, startIndex, count)
+	if err != nil {
+		return s.scimError(err, "Failed to list groups")
+	}
+
+	return scimJSON(http.StatusOK, result)
+}
+
+// getGroupHandler handles GET /scim/v2/Groups/:id.
+func (s *SCIMService) getGroupHandler(c *models.ReqContext) response.Response {
+	group, err := s.getGroup(c.OrgId, c.Params(":id"))
+	if err != nil {
+		return s.scimError(err, "Failed to get group")
+	}
+
+	return scimJSON(http.StatusOK, group)
+}
+
+// createGroupHandler handles POST /scim/v2/Groups.
+func (s *SCIMService) createGroupHandler(c *models.ReqContext, cmd Group) response.Response {
+	group, err := s.createGroup(c.OrgId, &cmd)
+	if err != nil {
+		return s.scimError(err, "Failed to create group")
+	}
+
+	return scimJSON(http.StatusCreated, group)
+}
+
+// replaceGroupHandler handles PUT /scim/v2/Groups/:id.
+func (s *SCIMService) replaceGroupHandler(c *models.ReqContext, cmd Group) response.Response {
+	group, err := s.replaceGroup(c.OrgId, c.Params(":id"), &cmd)
+	if err != nil {
+		return s.scimError(err, "Failed to update group")
+	}
+
+	return scimJSON(http.StatusOK, group)
+}
+
+// patchGroupHandler handles PATCH /scim/v2/Groups/:id.
+func (s *SCIMService) patchGroupHandler(c *models.ReqContext, cmd PatchRequest) response.Response {
+	group, err := s.patchGroup(c.OrgId, c.Params(":id"), cmd)
+	if err != nil {
+		return s.scimError(err, "Failed to update group")
+	}
+
+	return scimJSON(http.StatusOK, group)
+}
+
+// deleteGroupHandler handles DELETE /scim/v2/Groups/:id.
+func (s *SCIMService) deleteGroupHandler(c *models.ReqContext) response.Response {
+	if err := s.deleteGroup(c.OrgId, c.Params(":id")); err != nil {
+		return s.scimError(err, "Failed to delete group")
+	}
+
+	return response.Empty(http.StatusNoContent)
+}
+
+func (s *SCIMService) listGroups(orgID int64, f filter, startIndex int, count int) (*ListResponse, error) {
+	query := models.SearchTeamsQuery{OrgId: orgID}
+	if name, ok := f.equalityValue("displayname"); ok {
+		query.Name = name
+	}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+
+	matching := make([]*models.TeamDTO, 0)
+	for _, team := range query.Result.Teams {
+		attrs := map[string][]string{
+			"id":          {strconv.FormatInt(team.Id, 10)},
+			"displayname": {team.Name},
+		}
+		if f.matches(attrs) {
+			matching = append(matching, team)
+		}
+	}
+
+	from, to := page(len(matching), startIndex, count)
+	resources := make([]*Group, 0, to-from)
+	for _, team := range matching[from:to] {
+		group, err := s.toGroupResource(team)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, group)
+	}
+
+	return &ListResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(matching),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func (s *SCIMService) getTeam(orgID int64, id string) (*models.TeamDTO, error) {
+	teamID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errNotFound
+	}
+
+	query := models.GetTeamByIdQuery{OrgId: orgID, Id: teamID}
+	if err := bus.Dispatch(&query); err != nil {
+		if errors.Is(err, models.ErrTeamNotFound) {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+
+	return query.Result, nil
+}
+
+func (s *SCIMService) getGroup(orgID int64, id string) (*Group, error) {
+	team, err := s.getTeam(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.toGroupResource(team)
+}
+
+func (s *SCIMService) toGroupResource(team *models.TeamDTO) (*Group, error) {
+	membersQuery := models.GetTeamMembersQuery{OrgId: team.OrgId, TeamId: team.Id}
+	if err := bus.Dispatch(&membersQuery); err != nil {
+		return nil, err
+	}
+
+	id := strconv.FormatInt(team.Id, 10)
+	group := &Group{
+		Schemas:     []string{schemaGroup},
+		ID:          id,
+		DisplayName: team.Name,
+		Members:     make([]MultiValued, 0, len(membersQuery.Result)),
+		Meta: &Meta{
+			ResourceType: "Group",
+			Location:     s.location("Groups", id),
+		},
+	}
+
+	for _, member := range membersQuery.Result {
+		userID := strconv.FormatInt(member.UserId, 10)
+		group.Members = append(group.Members, MultiValued{
+			Value:   userID,
+			Display: member.Login,
+			Ref:     s.location("Users", userID),
+		})
+	}
+
+	return group, nil
+}
+
+func (s *SCIMService) createGroup(orgID int64, resource *Group) (*Group, error) {
+	if resource.DisplayName == "" {
+		return nil, fmt.Errorf("%w: displayName is required", errInvalidValue)
+	}
+
+	memberIDs, err := memberUserIDs(orgID, resource.Members)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := models.CreateTeamCommand{OrgId: orgID, Name: resource.DisplayName}
+	if err := bus.Dispatch(&cmd); err != nil {
+		if errors.Is(err, models.ErrTeamNameTaken) {
+			return nil, fmt.Errorf("%w: group %q", errUniqueness, resource.DisplayName)
+		}
+		return nil, err
+	}
+
+	for _, userID := range memberIDs {
+		if err := addMember(orgID, cmd.Result.Id, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.getGroup(orgID, strconv.FormatInt(cmd.Result.Id, 10))
+}
+
+func (s *SCIMService) replaceGroup(orgID int64, id string, resource *Group) (*Group, error) {
+	team, err := s.getTeam(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if resource.DisplayName == "" {
+		return nil, fmt.Errorf("%w: displayName is required", errInvalidValue)
+	}
+
+	if err := renameTeam(team, resource.DisplayName); err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := memberUserIDs(orgID, resource.Members)
+	if err != nil {
+		return nil, err
+	}
+	if err := syncMembers(team, memberIDs); err != nil {
+		return nil, err
+	}
+
+	return s.getGroup(orgID, id)
+}
+
+func (s *SCIMService) patchGroup(orgID int64, id string, patch PatchRequest) (*Group, error) {
+	team, err := s.getTeam(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, op := range patch.Operations {
+		if err := applyGroupPatch(team, op); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.getGroup(orgID, id)
+}
+
+// applyGroupPatch applies a single PATCH operation directly to the team and its members.
+func applyGroupPatch(team *models.TeamDTO, op PatchOperation) error {
+	opName := strings.ToLower(op.Op)
+
+	if op.Path == "" {
+		values, ok := op.Value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: operation without path requires an object value", errInvalidValue)
+		}
+		for path, value := range values {
+			if strings.EqualFold(path, "id") || strings.EqualFold(path, "externalId") {
+				continue
+			}
+			if err := applyGroupPatch(team, PatchOperation{Op: op.Op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path := strings.ToLower(op.Path)
+	var memberFilter filter
+	if i := strings.Index(path, "["); i >= 0 {
+		j := strings.LastIndex(op.Path, "]")
+		if j < i {
+			return fmt.Errorf("%w: %q", errInvalidPath, op.Path)
+		}
+		f, err := parseFilter(op.Path[i+1 : j])
+		if err != nil {
+			return fmt.Errorf("%w: %q", errInvalidPath, op.Path)
+		}
+		memberFilter = f
+		path = path[:i]
+	}
+
+	switch {
+	case path == "displayname" && (opName == "add" || opName == "replace"):
+		name, err := stringValue(op.Value)
+		if err != nil {
+			return err
+		}
+		return renameTeam(team, name)
+	case path == "externalid":
+		return nil
+	case path != "members":
+		return fmt.Errorf("%w: %q", errInvalidPath, op.Path)
+	}
+
+	members, err := patchMembers(op.Value)
+	if err != nil {
+		return err
+	}
+	memberIDs, err := memberUserIDs(team.OrgId, members)
+	if err != nil {
+		return err
+	}
+
+	switch opName {
+	case "add":
+		current, err := currentMembers(team)
+		if err != nil {
+			return err
+		}
+		for _, userID := range memberIDs {
+			if _, ok := current[userID]; ok {
+				continue
+			}
+			if err := addMember(team.OrgId, team.Id, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "replace":
+		return syncMembers(team, memberIDs)
+	case "remove":
+		current, err := currentMembers(team)
+		if err != nil {
+			return err
+		}
+		for userID := range current {
+			remove := len(memberFilter) == 0 && len(memberIDs) == 0
+			if len(memberFilter) > 0 {
+				remove = memberFilter.matches(map[string][]string{"value": {strconv.FormatInt(userID, 10)}})
+			}
+			for _, id := range memberIDs {
+				if id == userID {
+					remove = true
+				}
+			}
+			if !remove {
+				continue
+			}
+			if err := removeMember(team.OrgId, team.Id, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return fmt.Errorf("%w: unsupported operation %q", errInvalidSyntax, op.Op)
+}
+
+func (s *SCIMService) deleteGroup(orgID int64, id string) error {
+	team, err := s.getTeam(orgID, id)
+	if err != nil {
+		return err
+	}
+
+	return bus.Dispatch(&models.DeleteTeamCommand{OrgId: orgID, Id: team.Id})
+}
+
+func renameTeam(team *models.TeamDTO, name string) error {
+	if name == team.Name {
+		return nil
+	}
+
+	cmd := models.UpdateTeamCommand{OrgId: team.OrgId, Id: team.Id, Name: name, Email: team.Email}
+	if err := bus.Dispatch(&cmd); err != nil {
+		if errors.Is(err, models.ErrTeamNameTaken) {
+			return fmt.Errorf("%w: group %q", errUniqueness, name)
+		}
+		return err
+	}
+
+	team.Name = name
+	return nil
+}
+
+func currentMembers(team *models.TeamDTO) (map[int64]struct{}, error) {
+	query := models.GetTeamMembersQuery{OrgId: team.OrgId, TeamId: team.Id}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+
+	members := make(map[int64]struct{}, len(query.Result))
+	for _, member := range query.Result {
+		members[member.UserId] = struct{}{}
+	}
+	return members, nil
+}
+
+// syncMembers makes the team members exactly match the given users.
+func syncMembers(team *models.TeamDTO, userIDs []int64) error {
+	current, err := currentMembers(team)
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[int64]struct{}, len(userIDs))
+	for _, userID := range userIDs {
+		desired[userID] = struct{}{}
+		if _, ok := current[userID]; ok {
+			continue
+		}
+		if err := addMember(team.OrgId, team.Id, userID); err != nil {
+			return err
+		}
+	}
+
+	for userID := range current {
+		if _, ok := desired[userID]; ok {
+			continue
+		}
+		if err := removeMember(team.OrgId, team.Id, userID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func addMember(orgID int64, teamID int64, userID int64) error {
+	cmd := models.AddTeamMemberCommand{OrgId: orgID, TeamId: teamID, UserId: userID, External: true}
+	if err := bus.Dispatch(&cmd); err != nil && !errors.Is(err, models.ErrTeamMemberAlreadyAdded) {
+		return err
+	}
+	return nil
+}
+
+func removeMember(orgID int64, teamID int64, userID int64) error {
+	cmd := models.RemoveTeamMemberCommand{OrgId: orgID, TeamId: teamID, UserId: userID}
+	if err := bus.Dispatch(&cmd); err != nil && !errors.Is(err, models.ErrTeamMemberNotFound) {
+		return err
+	}
+	return nil
+}
+
+// memberUserIDs resolves member references to user ids, ensuring every user belongs to the organization.
+func memberUserIDs(orgID int64, members []MultiValued) ([]int64, error) {
+	userIDs := make([]int64, 0, len(members))
+	for _, member := range members {
+		userID, err := strconv.ParseInt(member.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: unknown member %q", errInvalidValue, member.Value)
+		}
+		if _, err := orgRole(orgID, userID); err != nil {
+			if errors.Is(err, errNotFound) {
+				return nil, fmt.Errorf("%w: unknown member %q", errInvalidValue, member.Value)
+			}
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, nil
+}
+
+// patchMembers reads the member list of a PATCH operation value.
+func patchMembers(value interface{}) ([]MultiValued, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	members := make([]MultiValued, 0, len(items))
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: members must be objects", errInvalidValue)
+		}
+		id, err := stringValue(m["value"])
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, MultiValued{Value: id})
+	}
+	return members, nil
+}
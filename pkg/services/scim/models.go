@@ -0,0 +1,195 @@
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Schema URNs defined by RFC 7643 and RFC 7644.
+const (
+	schemaUser          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	schemaGroup         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	schemaListResponse  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	schemaError         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	schemaSPConfig      = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	schemaResourceType  = "urn:ietf:params:scim:schemas:core:2.0:ResourceType"
+	contentTypeSCIMJSON = "application/scim+json"
+)
+
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
+)
+
+var (
+	errInvalidFilter = errors.New("invalid filter")
+	errInvalidValue  = errors.New("invalid value")
+	errInvalidPath   = errors.New("invalid path")
+	errNoTarget      = errors.New("no target")
+	errUniqueness    = errors.New("resource already exists")
+	errNotFound      = errors.New("resource not found")
+	errMutability    = errors.New("attribute cannot be modified")
+	errInvalidSyntax = errors.New("invalid syntax")
+)
+
+// Meta holds the resource metadata returned for every SCIM resource.
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+// Name is the SCIM complex name attribute of a user.
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+// MultiValued is the generic SCIM multi-valued attribute shape used for
+// emails, roles, group memberships and group members.
+type MultiValued struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// User is the SCIM representation of a Grafana user in an organization.
+type User struct {
+	Schemas     []string      `json:"schemas"`
+	ID          string        `json:"id,omitempty"`
+	ExternalID  string        `json:"externalId,omitempty"`
+	UserName    string        `json:"userName"`
+	Name        *Name         `json:"name,omitempty"`
+	DisplayName string        `json:"displayName,omitempty"`
+	Emails      []MultiValued `json:"emails,omitempty"`
+	Active      *bool         `json:"active,omitempty"`
+	Roles       []MultiValued `json:"roles,omitempty"`
+	Groups      []MultiValued `json:"groups,omitempty"`
+	Meta        *Meta         `json:"meta,omitempty"`
+}
+
+// PrimaryEmail returns the primary email, or the first one if none is flagged as primary.
+func (u *User) PrimaryEmail() string {
+	for _, e := range u.Emails {
+		if e.Primary {
+			return e.Value
+		}
+	}
+	if len(u.Emails) > 0 {
+		return u.Emails[0].Value
+	}
+	return ""
+}
+
+// FullName returns the name that is stored in Grafana for the user.
+func (u *User) FullName() string {
+	if u.DisplayName != "" {
+		return u.DisplayName
+	}
+	if u.Name == nil {
+		return ""
+	}
+	if u.Name.Formatted != "" {
+		return u.Name.Formatted
+	}
+	if u.Name.GivenName != "" && u.Name.FamilyName != "" {
+		return u.Name.GivenName + " " + u.Name.FamilyName
+	}
+	return u.Name.GivenName + u.Name.FamilyName
+}
+
+// IsActive returns false only if the active attribute is explicitly set to false.
+func (u *User) IsActive() bool {
+	return u.Active == nil || *u.Active
+}
+
+// PrimaryRole returns the primary role value, or the first one if none is flagged as primary.
+func (u *User) PrimaryRole() string {
+	for _, r := range u.Roles {
+		if r.Primary {
+			return r.Value
+		}
+	}
+	if len(u.Roles) > 0 {
+		return u.Roles[0].Value
+	}
+	return ""
+}
+
+// Group is the SCIM representation of a Grafana team.
+type Group struct {
+	Schemas     []string      `json:"schemas"`
+	ID          string        `json:"id,omitempty"`
+	ExternalID  string        `json:"externalId,omitempty"`
+	DisplayName string        `json:"displayName"`
+	Members     []MultiValued `json:"members,omitempty"`
+	Meta        *Meta         `json:"meta,omitempty"`
+}
+
+// ListResponse is the envelope returned by resource queries.
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// PatchOperation is a single operation of a PATCH request.
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// PatchRequest is the body of a PATCH request.
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Error is the SCIM error response body.
+type Error struct {
+	Schemas  []string `json:"schemas"`
+	Status   string   `json:"status"`
+	ScimType string   `json:"scimType,omitempty"`
+	Detail   string   `json:"detail,omitempty"`
+}
+
+func newError(status int, scimType string, detail string) Error {
+	return Error{
+		Schemas:  []string{schemaError},
+		Status:   strconv.Itoa(status),
+		ScimType: scimType,
+		Detail:   detail,
+	}
+}
+
+// errorStatus maps internal errors to the HTTP status and scimType of the error response.
+func errorStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, errInvalidFilter):
+		return http.StatusBadRequest, "invalidFilter"
+	case errors.Is(err, errInvalidValue):
+		return http.StatusBadRequest, "invalidValue"
+	case errors.Is(err, errInvalidPath):
+		return http.StatusBadRequest, "invalidPath"
+	case errors.Is(err, errNoTarget):
+		return http.StatusBadRequest, "noTarget"
+	case errors.Is(err, errInvalidSyntax):
+		return http.StatusBadRequest, "invalidSyntax"
+	case errors.Is(err, errMutability):
+		return http.StatusBadRequest, "mutability"
+	case errors.Is(err, errUniqueness):
+		return http.StatusConflict, "uniqueness"
+	case errors.Is(err, errNotFound):
+		return http.StatusNotFound, ""
+	}
+	return http.StatusInternalServerError, ""
+}
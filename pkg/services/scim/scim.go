@@ -0,0 +1,176 @@
+// Package scim implements a SCIM 2.0 (RFC 7643, RFC 7644) server that lets identity providers
+// provision users and teams into an organization.
+package scim
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/go-macaron/binding"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/api/routing"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/middleware"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func init() {
+	registry.RegisterService(&SCIMService{})
+}
+
+// SCIMService exposes users and teams of an organization as SCIM Users and Groups.
+// Provisioning can change the login, email and active state of users, which aren't scoped to an organization,
+// so requests have to be made by a Grafana server admin and operate on the admin's current organization.
+type SCIMService struct {
+	Cfg              *setting.Cfg            `inject:""`
+	RouteRegister    routing.RouteRegister   `inject:""`
+	AuthTokenService models.UserTokenService `inject:""`
+	log              log.Logger
+}
+
+// Init initializes the SCIM service.
+func (s *SCIMService) Init() error {
+	s.log = log.New("scim")
+
+	s.registerAPIEndpoints()
+
+	return nil
+}
+
+// IsEnabled returns true if the SCIM API is enabled for this instance.
+func (s *SCIMService) IsEnabled() bool {
+	if s.Cfg == nil {
+		return false
+	}
+
+	return s.Cfg.SCIMEnabled
+}
+
+func (s *SCIMService) registerAPIEndpoints() {
+	if !s.IsEnabled() {
+		return
+	}
+
+	s.RouteRegister.Group("/scim/v2", func(scimRoute routing.RouteRegister) {
+		scimRoute.Get("/ServiceProviderConfig", routing.Wrap(s.getServiceProviderConfig))
+		scimRoute.Get("/ResourceTypes", routing.Wrap(s.getResourceTypes))
+
+		scimRoute.Get("/Users", routing.Wrap(s.listUsersHandler))
+		scimRoute.Post("/Users", binding.Bind(User{}), routing.Wrap(s.createUserHandler))
+		scimRoute.Get("/Users/:id", routing.Wrap(s.getUserHandler))
+		scimRoute.Put("/Users/:id", binding.Bind(User{}), routing.Wrap(s.replaceUserHandler))
+		scimRoute.Patch("/Users/:id", binding.Bind(PatchRequest{}), routing.Wrap(s.patchUserHandler))
+		scimRoute.Delete("/Users/:id", routing.Wrap(s.deleteUserHandler))
+
+		scimRoute.Get("/Groups", routing.Wrap(s.listGroupsHandler))
+		scimRoute.Post("/Groups", binding.Bind(Group{}), routing.Wrap(s.createGroupHandler))
+		scimRoute.Get("/Groups/:id", routing.Wrap(s.getGroupHandler))
+		scimRoute.Put("/Groups/:id", binding.Bind(Group{}), routing.Wrap(s.replaceGroupHandler))
+		scimRoute.Patch("/Groups/:id", binding.Bind(PatchRequest{}), routing.Wrap(s.patchGroupHandler))
+		scimRoute.Delete("/Groups/:id", routing.Wrap(s.deleteGroupHandler))
+	}, middleware.ReqSignedIn, middleware.ReqGrafanaAdmin)
+}
+
+// getServiceProviderConfig handles GET /scim/v2/ServiceProviderConfig.
+func (s *SCIMService) getServiceProviderConfig(c *models.ReqContext) response.Response {
+	return scimJSON(http.StatusOK, map[string]interface{}{
+		"schemas":        []string{schemaSPConfig},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": maxPageSize},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]interface{}{
+			{
+				"type":        "httpbasic",
+				"name":        "Grafana Admin",
+				"description": "Authentication with the credentials of a Grafana server admin",
+				"primary":     true,
+			},
+		},
+	})
+}
+
+// getResourceTypes handles GET /scim/v2/ResourceTypes.
+func (s *SCIMService) getResourceTypes(c *models.ReqContext) response.Response {
+	resourceTypes := []map[string]interface{}{
+		{
+			"schemas":  []string{schemaResourceType},
+			"id":       "User",
+			"name":     "User",
+			"endpoint": "/Users",
+			"schema":   schemaUser,
+		},
+		{
+			"schemas":  []string{schemaResourceType},
+			"id":       "Group",
+			"name":     "Group",
+			"endpoint": "/Groups",
+			"schema":   schemaGroup,
+		},
+	}
+
+	return scimJSON(http.StatusOK, ListResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(resourceTypes),
+		StartIndex:   1,
+		ItemsPerPage: len(resourceTypes),
+		Resources:    resourceTypes,
+	})
+}
+
+func (s *SCIMService) location(resource string, id string) string {
+	return strings.TrimSuffix(s.Cfg.AppURL, "/") + "/scim/v2/" + resource + "/" + id
+}
+
+// pagination reads the 1-based startIndex and count query parameters.
+func pagination(c *models.ReqContext) (startIndex int, count int) {
+	startIndex = c.QueryInt("startIndex")
+	if startIndex < 1 {
+		startIndex = 1
+	}
+
+	count = defaultPageSize
+	if c.Query("count") != "" {
+		count = c.QueryInt("count")
+	}
+	if count < 0 {
+		count = 0
+	}
+	if count > maxPageSize {
+		count = maxPageSize
+	}
+
+	return startIndex, count
+}
+
+// page returns the bounds of a page of total elements.
+func page(total int, startIndex int, count int) (from int, to int) {
+	from = startIndex - 1
+	if from > total {
+		from = total
+	}
+	to = from + count
+	if to > total {
+		to = total
+	}
+	return from, to
+}
+
+func scimJSON(status int, body interface{}) response.Response {
+	return response.JSON(status, body).Header("Content-Type", contentTypeSCIMJSON)
+}
+
+func (s *SCIMService) scimError(err error, message string) response.Response {
+	status, scimType := errorStatus(err)
+	if status == http.StatusInternalServerError {
+		s.log.Error(message, "error", err)
+		return scimJSON(status, newError(status, "", message))
+	}
+
+	return scimJSON(status, newError(status, scimType, err.Error()))
+}
@@ -0,0 +1,350 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/auth"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestSCIMUsers(t *testing.T) {
+	scimScenario(t, "Should create a user as a member of the organization", func(t *testing.T, sc *scenarioContext) {
+		user, err := sc.service.createUser(context.Background(), sc.orgID, &User{
+			UserName: "bjensen",
+			Name:     &Name{GivenName: "Barbara", FamilyName: "Jensen"},
+			Emails:   []MultiValued{{Value: "bjensen@example.com", Primary: true}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "bjensen", user.UserName)
+		assert.Equal(t, "Barbara Jensen", user.DisplayName)
+		assert.Equal(t, "bjensen@example.com", user.PrimaryEmail())
+		assert.Equal(t, string(models.ROLE_VIEWER), user.PrimaryRole())
+		assert.True(t, user.IsActive())
+		assert.Equal(t, "http://localhost:3000/scim/v2/Users/"+user.ID, user.Meta.Location)
+
+		_, err = sc.service.createUser(context.Background(), sc.orgID, &User{UserName: "bjensen"})
+		assert.True(t, errors.Is(err, errUniqueness))
+	})
+
+	scimScenario(t, "Should find users with a filter", func(t *testing.T, sc *scenarioContext) {
+		sc.createUser(t, "bjensen", "Editor")
+		sc.createUser(t, "jsmith", "Viewer")
+
+		f, err := parseFilter(`userName eq "BJENSEN"`)
+		require.NoError(t, err)
+		result, err := sc.service.listUsers(sc.orgID, f, 1, 10)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, result.TotalResults)
+		users := result.Resources.([]*User)
+		require.Len(t, users, 1)
+		assert.Equal(t, "bjensen", users[0].UserName)
+		assert.Equal(t, "Editor", users[0].PrimaryRole())
+	})
+
+	scimScenario(t, "Should page through users", func(t *testing.T, sc *scenarioContext) {
+		sc.createUser(t, "user1", "Viewer")
+		sc.createUser(t, "user2", "Viewer")
+
+		result, err := sc.service.listUsers(sc.orgID, nil, 2, 2)
+		require.NoError(t, err)
+
+		// The admin user is a member as well.
+		assert.Equal(t, 3, result.TotalResults)
+		assert.Equal(t, 2, result.ItemsPerPage)
+	})
+
+	scimScenario(t, "Should not return users of other organizations", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+
+		_, err := sc.service.getUser(sc.orgID+1, user.ID)
+		assert.True(t, errors.Is(err, errNotFound))
+	})
+
+	scimScenario(t, "Should disable a deactivated user and revoke the sessions", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+
+		user, err := sc.service.patchUser(context.Background(), sc.orgID, user.ID, PatchRequest{
+			Operations: []PatchOperation{{Op: "Replace", Path: "active", Value: "False"}},
+		})
+		require.NoError(t, err)
+
+		assert.False(t, user.IsActive())
+		assert.Equal(t, []string{user.ID}, sc.revokedUsers)
+
+		query := models.GetUserByLoginQuery{LoginOrEmail: "bjensen"}
+		require.NoError(t, bus.Dispatch(&query))
+		assert.True(t, query.Result.IsDisabled)
+	})
+
+	scimScenario(t, "Should apply patch operations without a path", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+
+		user, err := sc.service.patchUser(context.Background(), sc.orgID, user.ID, PatchRequest{
+			Operations: []PatchOperation{{Op: "replace", Value: map[string]interface{}{
+				"displayName":                  "Babs Jensen",
+				`emails[type eq "work"].value`: "babs@example.com",
+				"roles":                        []interface{}{map[string]interface{}{"value": "editor"}},
+			}}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Babs Jensen", user.DisplayName)
+		assert.Equal(t, "babs@example.com", user.PrimaryEmail())
+		assert.Equal(t, "Editor", user.PrimaryRole())
+		assert.Empty(t, sc.revokedUsers)
+	})
+
+	scimScenario(t, "Should replace a user", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+
+		active := false
+		user, err := sc.service.replaceUser(context.Background(), sc.orgID, user.ID, &User{
+			UserName: "barbara",
+			Emails:   []MultiValued{{Value: "barbara@example.com"}},
+			Active:   &active,
+			Roles:    []MultiValued{{Value: "Admin"}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "barbara", user.UserName)
+		assert.Equal(t, "Admin", user.PrimaryRole())
+		assert.False(t, user.IsActive())
+	})
+
+	scimScenario(t, "Should reject unknown roles", func(t *testing.T, sc *scenarioContext) {
+		_, err := sc.service.createUser(context.Background(), sc.orgID, &User{
+			UserName: "bjensen",
+			Roles:    []MultiValued{{Value: "Owner"}},
+		})
+		assert.True(t, errors.Is(err, errInvalidValue))
+	})
+
+	scimScenario(t, "Should not change users that belong to other organizations", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+		otherOrgCmd := models.CreateOrgCommand{Name: "Other", UserId: sc.adminID}
+		require.NoError(t, bus.Dispatch(&otherOrgCmd))
+		_, err := sc.service.createUser(context.Background(), otherOrgCmd.Result.Id, &User{UserName: "bjensen"})
+		require.NoError(t, err)
+
+		_, err = sc.service.replaceUser(context.Background(), sc.orgID, user.ID, &User{
+			UserName: "barbara",
+			Emails:   []MultiValued{{Value: "barbara@example.com"}},
+		})
+		assert.True(t, errors.Is(err, errMutability))
+
+		_, err = sc.service.patchUser(context.Background(), sc.orgID, user.ID, PatchRequest{
+			Operations: []PatchOperation{{Op: "Replace", Path: "active", Value: false}},
+		})
+		assert.True(t, errors.Is(err, errMutability))
+
+		user, err = sc.service.patchUser(context.Background(), sc.orgID, user.ID, PatchRequest{
+			Operations: []PatchOperation{{Op: "Replace", Path: "roles", Value: "Editor"}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "bjensen", user.UserName)
+		assert.Equal(t, "Editor", user.PrimaryRole())
+		assert.True(t, user.IsActive())
+
+		require.NoError(t, sc.service.deleteUser(context.Background(), sc.orgID, user.ID))
+		assert.Empty(t, sc.revokedUsers)
+
+		query := models.GetUserByLoginQuery{LoginOrEmail: "bjensen"}
+		require.NoError(t, bus.Dispatch(&query))
+		assert.False(t, query.Result.IsDisabled)
+	})
+
+	scimScenario(t, "Should not change Grafana admins", func(t *testing.T, sc *scenarioContext) {
+		adminCmd := models.CreateUserCommand{Login: "grafana-admin", IsAdmin: true, SkipOrgSetup: true}
+		require.NoError(t, bus.Dispatch(&adminCmd))
+
+		active := false
+		_, err := sc.service.createUser(context.Background(), sc.orgID, &User{UserName: "grafana-admin", Active: &active})
+		assert.True(t, errors.Is(err, errMutability))
+
+		user := sc.createUser(t, "grafana-admin", "Viewer")
+		_, err = sc.service.replaceUser(context.Background(), sc.orgID, user.ID, &User{
+			UserName: "grafana-admin",
+			Emails:   []MultiValued{{Value: "attacker@example.com"}},
+		})
+		assert.True(t, errors.Is(err, errMutability))
+
+		query := models.GetUserByIdQuery{Id: adminCmd.Result.Id}
+		require.NoError(t, bus.Dispatch(&query))
+		assert.Equal(t, "grafana-admin", query.Result.Email)
+		assert.False(t, query.Result.IsDisabled)
+	})
+
+	scimScenario(t, "Should delete a user and revoke the sessions", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+
+		err := sc.service.deleteUser(context.Background(), sc.orgID, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{user.ID}, sc.revokedUsers)
+
+		_, err = sc.service.getUser(sc.orgID, user.ID)
+		assert.True(t, errors.Is(err, errNotFound))
+
+		query := models.GetUserByLoginQuery{LoginOrEmail: "bjensen"}
+		assert.True(t, errors.Is(bus.Dispatch(&query), models.ErrUserNotFound))
+	})
+}
+
+func TestSCIMGroups(t *testing.T) {
+	scimScenario(t, "Should create a group with members", func(t *testing.T, sc *scenarioContext) {
+		user := sc.createUser(t, "bjensen", "Viewer")
+
+		group, err := sc.service.createGroup(sc.orgID, &Group{
+			DisplayName: "Operations",
+			Members:     []MultiValued{{Value: user.ID}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "Operations", group.DisplayName)
+		require.Len(t, group.Members, 1)
+		assert.Equal(t, user.ID, group.Members[0].Value)
+
+		user, err = sc.service.getUser(sc.orgID, user.ID)
+		require.NoError(t, err)
+		require.Len(t, user.Groups, 1)
+		assert.Equal(t, group.ID, user.Groups[0].Value)
+
+		_, err = sc.service.createGroup(sc.orgID, &Group{DisplayName: "Operations"})
+		assert.True(t, errors.Is(err, errUniqueness))
+	})
+
+	scimScenario(t, "Should reject members outside of the organization", func(t *testing.T, sc *scenarioContext) {
+		_, err := sc.service.createGroup(sc.orgID, &Group{
+			DisplayName: "Operations",
+			Members:     []MultiValued{{Value: "9999"}},
+		})
+		assert.True(t, errors.Is(err, errInvalidValue))
+	})
+
+	scimScenario(t, "Should add and remove members with patch operations", func(t *testing.T, sc *scenarioContext) {
+		user1 := sc.createUser(t, "user1", "Viewer")
+		user2 := sc.createUser(t, "user2", "Viewer")
+		group, err := sc.service.createGroup(sc.orgID, &Group{DisplayName: "Operations"})
+		require.NoError(t, err)
+
+		group, err = sc.service.patchGroup(sc.orgID, group.ID, PatchRequest{
+			Operations: []PatchOperation{
+				{Op: "add", Path: "members", Value: []interface{}{
+					map[string]interface{}{"value": user1.ID},
+					map[string]interface{}{"value": user2.ID},
+				}},
+				{Op: "replace", Path: "displayName", Value: "SRE"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "SRE", group.DisplayName)
+		assert.Len(t, group.Members, 2)
+
+		group, err = sc.service.patchGroup(sc.orgID, group.ID, PatchRequest{
+			Operations: []PatchOperation{
+				{Op: "remove", Path: `members[value eq "` + user1.ID + `"]`},
+			},
+		})
+		require.NoError(t, err)
+		require.Len(t, group.Members, 1)
+		assert.Equal(t, user2.ID, group.Members[0].Value)
+	})
+
+	scimScenario(t, "Should replace a group", func(t *testing.T, sc *scenarioContext) {
+		user1 := sc.createUser(t, "user1", "Viewer")
+		user2 := sc.createUser(t, "user2", "Viewer")
+		group, err := sc.service.createGroup(sc.orgID, &Group{
+			DisplayName: "Operations",
+			Members:     []MultiValued{{Value: user1.ID}},
+		})
+		require.NoError(t, err)
+
+		group, err = sc.service.replaceGroup(sc.orgID, group.ID, &Group{
+			DisplayName: "SRE",
+			Members:     []MultiValued{{Value: user2.ID}},
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, "SRE", group.DisplayName)
+		require.Len(t, group.Members, 1)
+		assert.Equal(t, user2.ID, group.Members[0].Value)
+	})
+
+	scimScenario(t, "Should list and delete groups", func(t *testing.T, sc *scenarioContext) {
+		group, err := sc.service.createGroup(sc.orgID, &Group{DisplayName: "Operations"})
+		require.NoError(t, err)
+		_, err = sc.service.createGroup(sc.orgID, &Group{DisplayName: "Developers"})
+		require.NoError(t, err)
+
+		f, err := parseFilter(`displayName eq "Operations"`)
+		require.NoError(t, err)
+		result, err := sc.service.listGroups(sc.orgID, f, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, 1, result.TotalResults)
+
+		require.NoError(t, sc.service.deleteGroup(sc.orgID, group.ID))
+		_, err = sc.service.getGroup(sc.orgID, group.ID)
+		assert.True(t, errors.Is(err, errNotFound))
+	})
+}
+
+type scenarioContext struct {
+	service      *SCIMService
+	orgID        int64
+	adminID      int64
+	revokedUsers []string
+}
+
+func (sc *scenarioContext) createUser(t *testing.T, login string, role string) *User {
+	t.Helper()
+
+	user, err := sc.service.createUser(context.Background(), sc.orgID, &User{
+		UserName: login,
+		Roles:    []MultiValued{{Value: role}},
+	})
+	require.NoError(t, err)
+	return user
+}
+
+func scimScenario(t *testing.T, desc string, fn func(t *testing.T, sc *scenarioContext)) {
+	t.Helper()
+
+	t.Run(desc, func(t *testing.T) {
+		sqlstore.InitTestDB(t)
+
+		adminCmd := models.CreateUserCommand{Login: "admin", SkipOrgSetup: true}
+		require.NoError(t, bus.Dispatch(&adminCmd))
+		orgCmd := models.CreateOrgCommand{Name: "SCIM", UserId: adminCmd.Result.Id}
+		require.NoError(t, bus.Dispatch(&orgCmd))
+
+		cfg := setting.NewCfg()
+		cfg.AppURL = "http://localhost:3000/"
+		cfg.AutoAssignOrgRole = string(models.ROLE_VIEWER)
+
+		sc := &scenarioContext{orgID: orgCmd.Result.Id, adminID: adminCmd.Result.Id}
+		tokenService := auth.NewFakeUserAuthTokenService()
+		tokenService.RevokeAllUserTokensProvider = func(ctx context.Context, userID int64) error {
+			sc.revokedUsers = append(sc.revokedUsers, strconv.FormatInt(userID, 10))
+			return nil
+		}
+
+		sc.service = &SCIMService{
+			Cfg:              cfg,
+			AuthTokenService: tokenService,
+			log:              log.New("scim.test"),
+		}
+
+		fn(t, sc)
+	})
+}
@@ -0,0 +1,607 @@
+package scim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/api/response"
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// listUsersHandler handles GET /scim/v2/Users.
+func (s *SCIMService) listUsersHandler(c *models.ReqContext) response.Response {
+	f, err := parseFilter(c.Query("filter"))
+	if err != nil {
+		return s.scimError(err, "Failed to parse filter")
+	}
+
+	startIndex, count := pagination(c)
+	result, err := s.listUsers(c.OrgId, f, startIndex, count)
+	if err != nil {
+		return s.scimError(err, "Failed to list users")
+	}
+
+	return scimJSON(http.StatusOK, result)
+}
+
+// getUserHandler handles GET /scim/v2/Users/:id.
+func (s *SCIMService) getUserHandler(c *models.ReqContext) response.Response {
+	user, err := s.getUser(c.OrgId, c.Params(":id"))
+	if err != nil {
+		return s.scimError(err, "Failed to get user")
+	}
+
+	return scimJSON(http.StatusOK, user)
+}
+
+// createUserHandler handles POST /scim/v2/Users.
+func (s *SCIMService) createUserHandler(c *models.ReqContext, cmd User) response.Response {
+	user, err := s.createUser(c.Req.Context(), c.OrgId, &cmd)
+	if err != nil {
+		return s.scimError(err, "Failed to create user")
+	}
+
+	return scimJSON(http.StatusCreated, user)
+}
+
+// replaceUserHandler handles PUT /scim/v2/Users/:id.
+func (s *SCIMService) replaceUserHandler(c *models.ReqContext, cmd User) response.Response {
+	user, err := s.replaceUser(c.Req.Context(), c.OrgId, c.Params(":id"), &cmd)
+	if err != nil {
+		return s.scimError(err, "Failed to update user")
+	}
+
+	return scimJSON(http.StatusOK, user)
+}
+
+// patchUserHandler handles PATCH /scim/v2/Users/:id.
+func (s *SCIMService) patchUserHandler(c *models.ReqContext, cmd PatchRequest) response.Response {
+	user, err := s.patchUser(c.Req.Context(), c.OrgId, c.Params(":id"), cmd)
+	if err != nil {
+		return s.scimError(err, "Failed to update user")
+	}
+
+	return scimJSON(http.StatusOK, user)
+}
+
+// deleteUserHandler handles DELETE /scim/v2/Users/:id.
+func (s *SCIMService) deleteUserHandler(c *models.ReqContext) response.Response {
+	if err := s.deleteUser(c.Req.Context(), c.OrgId, c.Params(":id")); err != nil {
+		return s.scimError(err, "Failed to delete user")
+	}
+
+	return response.Empty(http.StatusNoContent)
+}
+
+func (s *SCIMService) listUsers(orgID int64, f filter, startIndex int, count int) (*ListResponse, error) {
+	query := models.GetOrgUsersQuery{OrgId: orgID}
+	if err := bus.Dispatch(&query); err != nil {
+		return nil, err
+	}
+
+	matching := make([]*models.OrgUserDTO, 0)
+	for _, orgUser := range query.Result {
+		if f.matches(orgUserAttributes(orgUser)) {
+			matching = append(matching, orgUser)
+		}
+	}
+
+	from, to := page(len(matching), startIndex, count)
+	resources := make([]*User, 0, to-from)
+	for _, orgUser := range matching[from:to] {
+		user, err := s.getOrgUser(orgID, orgUser.UserId)
+		if err != nil {
+			return nil, err
+		}
+		resources = append(resources, user)
+	}
+
+	return &ListResponse{
+		Schemas:      []string{schemaListResponse},
+		TotalResults: len(matching),
+		StartIndex:   startIndex,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	}, nil
+}
+
+func orgUserAttributes(orgUser *models.OrgUserDTO) map[string][]string {
+	return map[string][]string{
+		"id":             {strconv.FormatInt(orgUser.UserId, 10)},
+		"username":       {orgUser.Login},
+		"displayname":    {orgUser.Name},
+		"name.formatted": {orgUser.Name},
+		"emails":         {orgUser.Email},
+		"emails.value":   {orgUser.Email},
+		"active":         {strconv.FormatBool(!orgUser.IsDisabled)},
+		"roles":          {orgUser.Role},
+		"roles.value":    {orgUser.Role},
+	}
+}
+
+func (s *SCIMService) getUser(orgID int64, id string) (*User, error) {
+	userID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return nil, errNotFound
+	}
+
+	return s.getOrgUser(orgID, userID)
+}
+
+// getOrgUser returns the user as a SCIM resource, or errNotFound if the user is not a member of the organization.
+func (s *SCIMService) getOrgUser(orgID int64, userID int64) (*User, error) {
+	userQuery := models.GetUserByIdQuery{Id: userID}
+	if err := bus.Dispatch(&userQuery); err != nil {
+		if errors.Is(err, models.ErrUserNotFound) {
+			return nil, errNotFound
+		}
+		return nil, err
+	}
+
+	role, err := orgRole(orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	teamsQuery := models.GetTeamsByUserQuery{OrgId: orgID, UserId: userID}
+	if err := bus.Dispatch(&teamsQuery); err != nil {
+		return nil, err
+	}
+
+	return s.toUserResource(userQuery.Result, role, teamsQuery.Result), nil
+}
+
+func orgRole(orgID int64, userID int64) (models.RoleType, error) {
+	orgsQuery := models.GetUserOrgListQuery{UserId: userID}
+	if err := bus.Dispatch(&orgsQuery); err != nil {
+		return "", err
+	}
+
+	for _, org := range orgsQuery.Result {
+		if org.OrgId == orgID {
+			return org.Role, nil
+		}
+	}
+
+	return "", errNotFound
+}
+
+// isSharedUser returns true if the user is a Grafana admin or a member of organizations other than orgID.
+// The login, email, name and active state of such users affect more than the provisioned organization, so
+// SCIM doesn't change them.
+func isSharedUser(orgID int64, userID int64) (bool, error) {
+	userQuery := models.GetUserByIdQuery{Id: userID}
+	if err := bus.Dispatch(&userQuery); err != nil {
+		return false, err
+	}
+	if userQuery.Result.IsAdmin {
+		return true, nil
+	}
+
+	orgsQuery := models.GetUserOrgListQuery{UserId: userID}
+	if err := bus.Dispatch(&orgsQuery); err != nil {
+		return false, err
+	}
+	for _, org := range orgsQuery.Result {
+		if org.OrgId != orgID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s *SCIMService) toUserResource(user *models.User, role models.RoleType, teams []*models.TeamDTO) *User {
+	id := strconv.FormatInt(user.Id, 10)
+	active := !user.IsDisabled
+	created := user.Created
+	updated := user.Updated
+
+	resource := &User{
+		Schemas:     []string{schemaUser},
+		ID:          id,
+		UserName:    user.Login,
+		DisplayName: user.Name,
+		Active:      &active,
+		Roles:       []MultiValued{{Value: string(role), Primary: true}},
+		Meta: &Meta{
+			ResourceType: "User",
+			Created:      &created,
+			LastModified: &updated,
+			Location:     s.location("Users", id),
+		},
+	}
+
+	if user.Name != "" {
+		resource.Name = &Name{Formatted: user.Name}
+	}
+
+	if user.Email != "" {
+		resource.Emails = []MultiValued{{Value: user.Email, Type: "work", Primary: true}}
+	}
+
+	for _, team := range teams {
+		teamID := strconv.FormatInt(team.Id, 10)
+		resource.Groups = append(resource.Groups, MultiValued{
+			Value:   teamID,
+			Display: team.Name,
+			Ref:     s.location("Groups", teamID),
+		})
+	}
+
+	return resource
+}
+
+func (s *SCIMService) createUser(ctx context.Context, orgID int64, resource *User) (*User, error) {
+	if resource.UserName == "" {
+		return nil, fmt.Errorf("%w: userName is required", errInvalidValue)
+	}
+
+	role, err := s.parseRole(resource.PrimaryRole())
+	if err != nil {
+		return nil, err
+	}
+
+	userQuery := models.GetUserByLoginQuery{LoginOrEmail: resource.UserName}
+	err = bus.Dispatch(&userQuery)
+	switch {
+	case err == nil:
+		// The user already exists, possibly provisioned into another organization.
+		if _, err := orgRole(orgID, userQuery.Result.Id); err == nil {
+			return nil, fmt.Errorf("%w: user %q", errUniqueness, resource.UserName)
+		} else if !errors.Is(err, errNotFound) {
+			return nil, err
+		}
+	case errors.Is(err, models.ErrUserNotFound):
+		cmd := models.CreateUserCommand{
+			Login:         resource.UserName,
+			Email:         resource.PrimaryEmail(),
+			Name:          resource.FullName(),
+			IsDisabled:    !resource.IsActive(),
+			EmailVerified: true,
+			SkipOrgSetup:  true,
+		}
+		if err := bus.Dispatch(&cmd); err != nil {
+			if errors.Is(err, models.ErrUserAlreadyExists) {
+				return nil, fmt.Errorf("%w: user %q", errUniqueness, resource.UserName)
+			}
+			return nil, err
+		}
+		userQuery.Result = &cmd.Result
+	default:
+		return nil, err
+	}
+
+	userID := userQuery.Result.Id
+	if userQuery.Result.IsDisabled == resource.IsActive() {
+		shared, err := isSharedUser(orgID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if shared {
+			return nil, fmt.Errorf("%w: user %q belongs to other organizations or is a Grafana admin", errMutability, resource.UserName)
+		}
+	}
+
+	if err := bus.Dispatch(&models.AddOrgUserCommand{OrgId: orgID, UserId: userID, Role: role}); err != nil {
+		return nil, err
+	}
+
+	if userQuery.Result.IsDisabled == resource.IsActive() {
+		if err := s.setActive(ctx, userID, resource.IsActive()); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.getOrgUser(orgID, userID)
+}
+
+func (s *SCIMService) replaceUser(ctx context.Context, orgID int64, id string, resource *User) (*User, error) {
+	existing, err := s.getUser(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.updateUser(ctx, orgID, existing, resource)
+}
+
+// updateUser applies the attributes of the desired resource to the existing user.
+func (s *SCIMService) updateUser(ctx context.Context, orgID int64, existing *User, desired *User) (*User, error) {
+	userID, err := strconv.ParseInt(existing.ID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	if desired.UserName == "" {
+		return nil, fmt.Errorf("%w: userName is required", errInvalidValue)
+	}
+
+	if desired.UserName != existing.UserName {
+		loginQuery := models.GetUserByLoginQuery{LoginOrEmail: desired.UserName}
+		if err := bus.Dispatch(&loginQuery); err == nil && loginQuery.Result.Id != userID {
+			return nil, fmt.Errorf("%w: user %q", errUniqueness, desired.UserName)
+		} else if err != nil && !errors.Is(err, models.ErrUserNotFound) {
+			return nil, err
+		}
+	}
+
+	email := desired.PrimaryEmail()
+	if email == "" {
+		email = existing.PrimaryEmail()
+	}
+
+	identityChanged := desired.UserName != existing.UserName || email != existing.PrimaryEmail() ||
+		desired.FullName() != existing.DisplayName
+	activeChanged := desired.IsActive() != existing.IsActive()
+	if identityChanged || activeChanged {
+		shared, err := isSharedUser(orgID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if shared {
+			return nil, fmt.Errorf("%w: user %q belongs to other organizations or is a Grafana admin", errMutability, existing.UserName)
+		}
+	}
+
+	if identityChanged {
+		updateCmd := models.UpdateUserCommand{
+			UserId: userID,
+			Login:  desired.UserName,
+			Email:  email,
+			Name:   desired.FullName(),
+		}
+		if err := bus.Dispatch(&updateCmd); err != nil {
+			return nil, err
+		}
+	}
+
+	if role := desired.PrimaryRole(); role != "" && !strings.EqualFold(role, existing.PrimaryRole()) {
+		newRole, err := s.parseRole(role)
+		if err != nil {
+			return nil, err
+		}
+		if err := bus.Dispatch(&models.UpdateOrgUserCommand{OrgId: orgID, UserId: userID, Role: newRole}); err != nil {
+			if errors.Is(err, models.ErrLastOrgAdmin) {
+				return nil, fmt.Errorf("%w: %s", errMutability, err)
+			}
+			return nil, err
+		}
+	}
+
+	if activeChanged {
+		if err := s.setActive(ctx, userID, desired.IsActive()); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.getOrgUser(orgID, userID)
+}
+
+func (s *SCIMService) patchUser(ctx context.Context, orgID int64, id string, patch PatchRequest) (*User, error) {
+	existing, err := s.getUser(orgID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	desired := *existing
+	desired.Emails = append([]MultiValued(nil), existing.Emails...)
+	desired.Roles = append([]MultiValued(nil), existing.Roles...)
+	if existing.Name != nil {
+		name := *existing.Name
+		desired.Name = &name
+	}
+
+	for _, op := range patch.Operations {
+		if err := applyUserPatch(&desired, op); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.updateUser(ctx, orgID, existing, &desired)
+}
+
+// applyUserPatch applies a single PATCH operation to the user resource.
+func applyUserPatch(user *User, op PatchOperation) error {
+	opName := strings.ToLower(op.Op)
+	if opName != "add" && opName != "replace" && opName != "remove" {
+		return fmt.Errorf("%w: unsupported operation %q", errInvalidSyntax, op.Op)
+	}
+
+	// Without a path the value is an object of attribute values.
+	if op.Path == "" {
+		values, ok := op.Value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: operation without path requires an object value", errInvalidValue)
+		}
+		for path, value := range values {
+			if err := applyUserPatch(user, PatchOperation{Op: op.Op, Path: path, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path := strings.ToLower(op.Path)
+	// Value filters such as emails[type eq "work"].value only target the single value we store.
+	if i := strings.Index(path, "["); i >= 0 {
+		j := strings.Index(path, "]")
+		if j < i {
+			return fmt.Errorf("%w: %q", errInvalidPath, op.Path)
+		}
+		path = path[:i] + path[j+1:]
+	}
+
+	if opName == "remove" {
+		switch path {
+		case "displayname", "name", "name.formatted", "name.givenname", "name.familyname":
+			user.DisplayName = ""
+			user.Name = nil
+		case "externalid":
+		default:
+			return fmt.Errorf("%w: %q cannot be removed", errMutability, op.Path)
+		}
+		return nil
+	}
+
+	switch path {
+	case "active":
+		active, err := boolValue(op.Value)
+		if err != nil {
+			return err
+		}
+		user.Active = &active
+	case "username":
+		value, err := stringValue(op.Value)
+		if err != nil {
+			return err
+		}
+		user.UserName = value
+	case "displayname", "name.formatted":
+		value, err := stringValue(op.Value)
+		if err != nil {
+			return err
+		}
+		user.DisplayName = value
+		user.Name = nil
+	case "name.givenname", "name.familyname":
+		value, err := stringValue(op.Value)
+		if err != nil {
+			return err
+		}
+		if user.Name == nil {
+			user.Name = &Name{}
+		}
+		user.DisplayName = ""
+		user.Name.Formatted = ""
+		if path == "name.givenname" {
+			user.Name.GivenName = value
+		} else {
+			user.Name.FamilyName = value
+		}
+	case "emails", "emails.value":
+		value, err := multiValue(op.Value)
+		if err != nil {
+			return err
+		}
+		user.Emails = []MultiValued{{Value: value, Primary: true}}
+	case "roles", "roles.value":
+		value, err := multiValue(op.Value)
+		if err != nil {
+			return err
+		}
+		user.Roles = []MultiValued{{Value: value, Primary: true}}
+	case "externalid":
+	default:
+		return fmt.Errorf("%w: %q", errInvalidPath, op.Path)
+	}
+
+	return nil
+}
+
+func (s *SCIMService) deleteUser(ctx context.Context, orgID int64, id string) error {
+	existing, err := s.getUser(orgID, id)
+	if err != nil {
+		return err
+	}
+	userID, err := strconv.ParseInt(existing.ID, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	cmd := models.RemoveOrgUserCommand{OrgId: orgID, UserId: userID, ShouldDeleteOrphanedUser: true}
+	if err := bus.Dispatch(&cmd); err != nil {
+		if errors.Is(err, models.ErrLastOrgAdmin) {
+			return fmt.Errorf("%w: %s", errMutability, err)
+		}
+		return err
+	}
+
+	// users that still belong to other organizations keep their sessions there
+	if !cmd.UserWasDeleted {
+		return nil
+	}
+
+	return s.AuthTokenService.RevokeAllUserTokens(ctx, userID)
+}
+
+// setActive enables or disables the user. Disabled users are logged out of all their sessions, so callers
+// only disable users that don't belong to other organizations.
+func (s *SCIMService) setActive(ctx context.Context, userID int64, active bool) error {
+	if err := bus.Dispatch(&models.DisableUserCommand{UserId: userID, IsDisabled: !active}); err != nil {
+		return err
+	}
+
+	if active {
+		return nil
+	}
+
+	return s.AuthTokenService.RevokeAllUserTokens(ctx, userID)
+}
+
+// parseRole converts a SCIM role value to an organization role, falling back to the
+// auto assigned role when the identity provider does not send one.
+func (s *SCIMService) parseRole(value string) (models.RoleType, error) {
+	if value == "" {
+		value = s.Cfg.AutoAssignOrgRole
+	}
+
+	for _, role := range []models.RoleType{models.ROLE_VIEWER, models.ROLE_EDITOR, models.ROLE_ADMIN} {
+		if strings.EqualFold(value, string(role)) {
+			return role, nil
+		}
+	}
+
+	return "", fmt.Errorf("%w: unknown role %q", errInvalidValue, value)
+}
+
+func stringValue(value interface{}) (string, error) {
+	s, ok := value.(string)
+	if !ok {
+		return "", fmt.Errorf("%w: expected a string", errInvalidValue)
+	}
+	return s, nil
+}
+
+// boolValue also accepts "True" and "False" strings, which some identity providers send.
+func boolValue(value interface{}) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		b, err := strconv.ParseBool(strings.ToLower(v))
+		if err != nil {
+			return false, fmt.Errorf("%w: expected a boolean", errInvalidValue)
+		}
+		return b, nil
+	}
+	return false, fmt.Errorf("%w: expected a boolean", errInvalidValue)
+}
+
+// multiValue returns the primary value of a multi-valued attribute, which can be sent
+// as a plain string, a single object or a list of objects.
+func multiValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case map[string]interface{}:
+		return stringValue(v["value"])
+	case []interface{}:
+		if len(v) == 0 {
+			return "", fmt.Errorf("%w: empty list", errInvalidValue)
+		}
+		for _, item := range v {
+			if m, ok := item.(map[string]interface{}); ok {
+				if primary, _ := m["primary"].(bool); primary {
+					return stringValue(m["value"])
+				}
+			}
+		}
+		return multiValue(v[0])
+	}
+	return "", fmt.Errorf("%w: unexpected value type", errInvalidValue)
+}
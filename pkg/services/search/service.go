@@ -1,8 +1,10 @@
 package search
 
 import (
+	"context"
 	"sort"
 
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/setting"
 
 	"github.com/grafana/grafana/pkg/bus"
@@ -14,8 +16,16 @@ func init() {
 	registry.RegisterService(&SearchService{})
 }
 
+// TermQuery matches dashboards that have an indexed search term of the field containing Term. An empty
+// Field matches the terms of all fields.
+type TermQuery struct {
+	Field string
+	Term  string
+}
+
 type Query struct {
 	Title        string
+	Terms        []TermQuery
 	Tags         []string
 	OrgId        int64
 	SignedInUser *models.SignedInUser
@@ -33,6 +43,7 @@ type Query struct {
 
 type FindPersistedDashboardsQuery struct {
 	Title        string
+	Terms        []TermQuery
 	OrgId        int64
 	SignedInUser *models.SignedInUser
 	IsStarred    bool
@@ -54,10 +65,12 @@ type SearchService struct {
 	Bus bus.Bus      `inject:""`
 	Cfg *setting.Cfg `inject:""`
 
+	log         log.Logger
 	sortOptions map[string]SortOption
 }
 
 func (s *SearchService) Init() error {
+	s.log = log.New("search")
 	s.Bus.AddHandler(s.searchHandler)
 	s.sortOptions = map[string]SortOption{
 		sortAlphaAsc.Name:  sortAlphaAsc,
@@ -67,9 +80,25 @@ func (s *SearchService) Init() error {
 	return nil
 }
 
+// Run indexes the search terms of the dashboards that were saved before the search index was added.
+// Dashboards are indexed when they are saved, so this only has work to do after an upgrade.
+func (s *SearchService) Run(_ context.Context) error {
+	cmd := &models.IndexDashboardSearchTermsCommand{}
+	if err := s.Bus.Dispatch(cmd); err != nil {
+		s.log.Error("Failed to index dashboards for search", "error", err)
+		return nil
+	}
+
+	if cmd.Result > 0 {
+		s.log.Info("Indexed dashboards for search", "count", cmd.Result)
+	}
+	return nil
+}
+
 func (s *SearchService) searchHandler(query *Query) error {
 	dashboardQuery := FindPersistedDashboardsQuery{
 		Title:        query.Title,
+		Terms:        query.Terms,
 		SignedInUser: query.SignedInUser,
 		IsStarred:    query.IsStarred,
 		DashboardIds: query.DashboardIds,
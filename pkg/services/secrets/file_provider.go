@@ -0,0 +1,217 @@
+package secrets
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const masterKeyLength = 32
+
+// FileProvider encrypts data keys with AES-GCM and master keys that are stored in a local file, one key per line as
+// "<id>:<base64 key>". The last key encrypts, and every key in the file can decrypt, so that the master key can be
+// rotated. It is meant for testing and as an example of a key management service.
+type FileProvider struct {
+	path string
+
+	mtx         sync.RWMutex
+	keys        map[string][]byte
+	activeKeyID string
+}
+
+// NewFileProvider reads the master keys from path, and creates the file with a new master key if it does not exist.
+func NewFileProvider(path string) (*FileProvider, error) {
+	if path == "" {
+		return nil, errors.New("key_file is required for the file encryption provider")
+	}
+
+	p := &FileProvider{path: path, keys: map[string][]byte{}}
+	if err := p.load(); err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		if err := p.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+func (p *FileProvider) load() error {
+	// #nosec G304 - the path of the key file is configured by the server admin
+	f, err := os.Open(p.path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = f.Close()
+	}()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf("invalid line in key file %s", p.path)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil || len(key) != masterKeyLength {
+			return fmt.Errorf("invalid key %q in key file %s", parts[0], p.path)
+		}
+
+		p.keys[parts[0]] = key
+		p.activeKeyID = parts[0]
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if p.activeKeyID == "" {
+		return fmt.Errorf("key file %s has no keys", p.path)
+	}
+
+	return nil
+}
+
+// Rotate adds a new master key to the file that encrypts from now on.
+func (p *FileProvider) Rotate() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	key := make([]byte, masterKeyLength)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return err
+	}
+	id := util.GenerateShortUID()
+
+	if err := os.MkdirAll(filepath.Dir(p.path), 0750); err != nil {
+		return err
+	}
+
+	// #nosec G304 - the path of the key file is configured by the server admin
+	f, err := os.OpenFile(p.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f, "%s:%s\n", id, base64.StdEncoding.EncodeToString(key)); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	p.keys[id] = key
+	p.activeKeyID = id
+	return nil
+}
+
+// Backup returns the content of the key file, which Restore writes back.
+func (p *FileProvider) Backup() ([]byte, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+
+	// #nosec G304 - the path of the key file is configured by the server admin
+	return os.ReadFile(p.path)
+}
+
+// Restore replaces the key file with content returned by Backup and reloads its keys.
+func (p *FileProvider) Restore(content []byte) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err := os.WriteFile(p.path, content, 0600); err != nil {
+		return err
+	}
+
+	p.keys = map[string][]byte{}
+	p.activeKeyID = ""
+	return p.load()
+}
+
+// RemoveInactiveKeys removes every master key except the one that encrypts from the file.
+func (p *FileProvider) RemoveInactiveKeys() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	content := fmt.Sprintf("%s:%s\n", p.activeKeyID, base64.StdEncoding.EncodeToString(p.keys[p.activeKeyID]))
+	if err := os.WriteFile(p.path, []byte(content), 0600); err != nil {
+		return err
+	}
+
+	p.keys = map[string][]byte{p.activeKeyID: p.keys[p.activeKeyID]}
+	return nil
+}
+
+// Encrypt returns the id of the active master key, followed by "#", the nonce and the encrypted blob.
+func (p *FileProvider) Encrypt(_ context.Context, blob []byte) ([]byte, error) {
+	p.mtx.RLock()
+	id, key := p.activeKeyID, p.keys[p.activeKeyID]
+	p.mtx.RUnlock()
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	encrypted := append([]byte(id+"#"), nonce...)
+	return gcm.Seal(encrypted, nonce, blob, []byte(id)), nil
+}
+
+func (p *FileProvider) Decrypt(_ context.Context, blob []byte) ([]byte, error) {
+	i := bytes.IndexByte(blob, '#')
+	if i < 0 {
+		return nil, errors.New("invalid payload encrypted by the file provider")
+	}
+	id := string(blob[:i])
+
+	p.mtx.RLock()
+	key, ok := p.keys[id]
+	p.mtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("master key %q not found in key file %s", id, p.path)
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	blob = blob[i+1:]
+	if len(blob) < gcm.NonceSize() {
+		return nil, errors.New("invalid payload encrypted by the file provider")
+	}
+	return gcm.Open(nil, blob[:gcm.NonceSize()], blob[gcm.NonceSize():], []byte(id))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "encryption", "keys")
+
+	provider, err := NewFileProvider(path)
+	require.NoError(t, err)
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	encrypted, err := provider.Encrypt(context.Background(), []byte("data key"))
+	require.NoError(t, err)
+
+	t.Run("Decrypts with the keys of the file", func(t *testing.T) {
+		other, err := NewFileProvider(path)
+		require.NoError(t, err)
+
+		decrypted, err := other.Decrypt(context.Background(), encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "data key", string(decrypted))
+	})
+
+	t.Run("Rejects modified payloads", func(t *testing.T) {
+		modified := append([]byte{}, encrypted...)
+		modified[len(modified)-1] ^= 1
+
+		_, err := provider.Decrypt(context.Background(), modified)
+		assert.Error(t, err)
+	})
+
+	t.Run("Decrypts with previous keys after a rotation", func(t *testing.T) {
+		require.NoError(t, provider.Rotate())
+
+		decrypted, err := provider.Decrypt(context.Background(), encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "data key", string(decrypted))
+
+		rotated, err := provider.Encrypt(context.Background(), []byte("data key"))
+		require.NoError(t, err)
+
+		require.NoError(t, provider.RemoveInactiveKeys())
+
+		_, err = provider.Decrypt(context.Background(), encrypted)
+		assert.Error(t, err)
+
+		other, err := NewFileProvider(path)
+		require.NoError(t, err)
+		decrypted, err = other.Decrypt(context.Background(), rotated)
+		require.NoError(t, err)
+		assert.Equal(t, "data key", string(decrypted))
+	})
+
+	t.Run("Rejects invalid key files", func(t *testing.T) {
+		invalid := filepath.Join(t.TempDir(), "keys")
+		require.NoError(t, os.WriteFile(invalid, []byte("key:not base64\n"), 0600))
+
+		_, err := NewFileProvider(invalid)
+		assert.Error(t, err)
+
+		_, err = NewFileProvider("")
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,59 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const (
+	// ProviderSecretKey encrypts data keys with the secret_key of the server.
+	ProviderSecretKey = "secret_key"
+	// ProviderFile encrypts data keys with master keys stored in a local file.
+	ProviderFile = "file"
+)
+
+// Provider is a key management service that encrypts data keys with a master key.
+// The master key never leaves the provider.
+type Provider interface {
+	Encrypt(ctx context.Context, blob []byte) ([]byte, error)
+	Decrypt(ctx context.Context, blob []byte) ([]byte, error)
+}
+
+// providerName returns the name of the provider configured in [security.encryption].
+func providerName(cfg *setting.Cfg) string {
+	if cfg.EncryptionProvider == "" {
+		return ProviderSecretKey
+	}
+	return cfg.EncryptionProvider
+}
+
+// newProvider returns the provider configured in [security.encryption].
+func newProvider(cfg *setting.Cfg, secretKey string) (Provider, error) {
+	switch name := providerName(cfg); name {
+	case ProviderSecretKey:
+		return newSecretKeyProvider(secretKey), nil
+	case ProviderFile:
+		return NewFileProvider(cfg.EncryptionKeyFile)
+	default:
+		return nil, fmt.Errorf("unknown encryption provider %q", name)
+	}
+}
+
+type secretKeyProvider struct {
+	secretKey string
+}
+
+func newSecretKeyProvider(secretKey string) *secretKeyProvider {
+	return &secretKeyProvider{secretKey: secretKey}
+}
+
+func (p *secretKeyProvider) Encrypt(_ context.Context, blob []byte) ([]byte, error) {
+	return util.Encrypt(blob, p.secretKey)
+}
+
+func (p *secretKeyProvider) Decrypt(_ context.Context, blob []byte) ([]byte, error) {
+	return util.Decrypt(blob, p.secretKey)
+}
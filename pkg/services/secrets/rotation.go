@@ -0,0 +1,258 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/components/securejsondata"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// RotationResult counts the rows with secrets that were re-encrypted by RotateMasterKey.
+type RotationResult struct {
+	DataSources        int
+	AlertNotifications int
+	PluginSettings     int
+	OAuthTokens        int
+	MFASecrets         int
+	DashboardSnapshots int
+}
+
+// secretsEncoding is how the encrypted secrets of a column are stored.
+type secretsEncoding int
+
+const (
+	// secretsJSON is a JSON object of encrypted values, as stored by securejsondata.SecureJsonData.
+	secretsJSON secretsEncoding = iota
+	// secretsBase64 is a single base64 encoded encrypted value.
+	secretsBase64
+	// secretsBinary is a single encrypted value.
+	secretsBinary
+)
+
+// secretTables are the tables with secrets that are re-encrypted when the master key is rotated.
+var secretTables = []struct {
+	table    string
+	columns  []string
+	encoding secretsEncoding
+}{
+	{table: "data_source", columns: []string{"secure_json_data"}, encoding: secretsJSON},
+	{table: "alert_notification", columns: []string{"secure_settings"}, encoding: secretsJSON},
+	{table: "plugin_setting", columns: []string{"secure_json_data"}, encoding: secretsJSON},
+	{table: "user_auth", columns: []string{"o_auth_access_token", "o_auth_refresh_token", "o_auth_token_type"}, encoding: secretsBase64},
+	{table: "user_mfa", columns: []string{"secret"}, encoding: secretsBase64},
+	{table: "dashboard_snapshot", columns: []string{"dashboard_encrypted"}, encoding: secretsBinary},
+}
+
+// RotateMasterKey re-encrypts the secrets of data sources, alert notifications and plugin settings, the OAuth
+// tokens and second factors of users and the dashboards of snapshots, and deletes the data keys that encrypted
+// them before.
+//
+// With envelope encryption, the secrets are encrypted with a new data key. The file provider encrypts
+// it with a new master key, and the previous master keys are removed from the key file when all secrets
+// were re-encrypted. Otherwise the secrets are encrypted with the secret_key directly.
+//
+// Secrets and data keys that were encrypted with oldSecretKey are decrypted with it, so that the
+// secret_key can be changed in the configuration before running the rotation. An empty oldSecretKey
+// means that the secret_key did not change. Grafana has to be restarted afterwards.
+func RotateMasterKey(ctx context.Context, cfg *setting.Cfg, sqlStore *sqlstore.SQLStore, oldSecretKey string) (*RotationResult, error) {
+	if oldSecretKey == "" {
+		oldSecretKey = setting.SecretKey
+	}
+
+	from := newService(sqlStore, cfg, oldSecretKey)
+	to := newService(sqlStore, cfg, setting.SecretKey)
+
+	var fileProvider *FileProvider
+	if to.provider == ProviderFile {
+		var err error
+		if fileProvider, err = NewFileProvider(cfg.EncryptionKeyFile); err != nil {
+			return nil, err
+		}
+		from.providers[ProviderFile] = fileProvider
+		to.providers[ProviderFile] = fileProvider
+	}
+
+	if err := from.loadDataKeys(ctx); err != nil {
+		return nil, err
+	}
+
+	// The new master key is added to the key file before the data key it encrypts is committed, so that the
+	// data key can always be decrypted, and the key file is restored if the secrets can't be re-encrypted.
+	var keyFileBackup []byte
+	if fileProvider != nil && to.envelope {
+		var err error
+		if keyFileBackup, err = fileProvider.Backup(); err != nil {
+			return nil, err
+		}
+		if err := fileProvider.Rotate(); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &RotationResult{}
+	counts := []*int{&result.DataSources, &result.AlertNotifications, &result.PluginSettings,
+		&result.OAuthTokens, &result.MFASecrets, &result.DashboardSnapshots}
+
+	err := sqlStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		activeDataKey := ""
+		if to.envelope {
+			name, err := to.rotateDataKey(ctx, dbSession)
+			if err != nil {
+				return err
+			}
+			activeDataKey = name
+		}
+
+		for i, t := range secretTables {
+			count, err := reEncryptTable(dbSession, t.table, t.columns, t.encoding, from, to)
+			if err != nil {
+				return err
+			}
+			*counts[i] = count
+		}
+
+		_, err := dbSession.Exec("DELETE FROM data_key WHERE name <> ?", activeDataKey)
+		return err
+	})
+	if err != nil {
+		if keyFileBackup != nil {
+			if restoreErr := fileProvider.Restore(keyFileBackup); restoreErr != nil {
+				return nil, fmt.Errorf("%v, and restoring the key file failed: %w", err, restoreErr)
+			}
+		}
+		return nil, err
+	}
+
+	if fileProvider != nil && to.envelope {
+		if err := fileProvider.RemoveInactiveKeys(); err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
+
+// rotateDataKey stores a new data key that encrypts from now on in the session's transaction.
+func (s *SecretsService) rotateDataKey(ctx context.Context, dbSession *sqlstore.DBSession) (string, error) {
+	dk, key, err := s.newDataKey(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if err := s.activateDataKey(dbSession, dk); err != nil {
+		return "", err
+	}
+
+	s.mtx.Lock()
+	s.dataKeys[dk.Name] = key
+	s.activeDataKey = dk.Name
+	s.mtx.Unlock()
+
+	return dk.Name, nil
+}
+
+// reEncryptTable decrypts the secrets of the columns of every row of a table with from and encrypts them with to.
+// It returns the number of rows with secrets. Tables of services that aren't set up yet are skipped.
+func reEncryptTable(dbSession *sqlstore.DBSession, table string, columns []string, encoding secretsEncoding,
+	from, to *SecretsService) (int, error) {
+	exists, err := dbSession.IsTableExist(table)
+	if err != nil || !exists {
+		return 0, err
+	}
+
+	// #nosec G202 - table and column names are constants
+	rows, err := dbSession.Query("SELECT id, " + strings.Join(columns, ", ") + " FROM " + table)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, row := range rows {
+		id, err := strconv.ParseInt(string(row["id"]), 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		updated := false
+		for _, column := range columns {
+			if len(row[column]) == 0 {
+				continue
+			}
+
+			value, err := reEncryptValue(row[column], encoding, from, to)
+			if err != nil {
+				return 0, fmt.Errorf("failed to re-encrypt %s.%s of row %d: %w", table, column, id, err)
+			}
+			if value == nil {
+				continue
+			}
+
+			// #nosec G202 - table and column names are constants
+			if _, err := dbSession.Exec("UPDATE "+table+" SET "+column+" = ? WHERE id = ?", value, id); err != nil {
+				return 0, err
+			}
+			updated = true
+		}
+		if updated {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// reEncryptValue returns the re-encrypted value of a column in the same encoding, or nil if it has no secrets.
+func reEncryptValue(value []byte, encoding secretsEncoding, from, to *SecretsService) (interface{}, error) {
+	switch encoding {
+	case secretsJSON:
+		var secrets securejsondata.SecureJsonData
+		if err := json.Unmarshal(value, &secrets); err != nil {
+			return nil, err
+		}
+		if len(secrets) == 0 {
+			return nil, nil
+		}
+
+		for key, secret := range secrets {
+			decrypted, err := from.Decrypt(secret)
+			if err != nil {
+				return nil, err
+			}
+			if secrets[key], err = to.Encrypt(decrypted); err != nil {
+				return nil, err
+			}
+		}
+
+		encoded, err := json.Marshal(secrets)
+		if err != nil {
+			return nil, err
+		}
+		return string(encoded), nil
+	case secretsBase64:
+		decoded, err := base64.StdEncoding.DecodeString(string(value))
+		if err != nil {
+			return nil, err
+		}
+		decrypted, err := from.Decrypt(decoded)
+		if err != nil {
+			return nil, err
+		}
+		encrypted, err := to.Encrypt(decrypted)
+		if err != nil {
+			return nil, err
+		}
+		return base64.StdEncoding.EncodeToString(encrypted), nil
+	default:
+		decrypted, err := from.Decrypt(value)
+		if err != nil {
+			return nil, err
+		}
+		return to.Encrypt(decrypted)
+	}
+}
@@ -0,0 +1,342 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/securejsondata"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/registry"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+const dataKeyLength = 32
+
+// envelopePrefix starts payloads that are encrypted with a data key, as "#<data key name>#<encrypted payload>".
+// Payloads encrypted with the secret_key directly start with an alphanumeric salt.
+const envelopePrefix = '#'
+
+var errInvalidPayload = errors.New("invalid envelope encrypted payload")
+
+func init() {
+	registry.RegisterServiceWithPriority(&SecretsService{}, registry.MediumHigh)
+}
+
+// SecretsService encrypts the secrets of data sources, alert notifications and plugin settings, and the other
+// secrets that are encrypted with securejsondata.Encrypt.
+// With envelope encryption, every secret is encrypted with a data key, and the data keys are stored
+// in the database encrypted by the master key of a Provider. Secrets encrypted with the secret_key
+// of the server can always be decrypted.
+type SecretsService struct {
+	SQLStore *sqlstore.SQLStore `inject:""`
+	Cfg      *setting.Cfg       `inject:""`
+
+	log       log.Logger
+	envelope  bool
+	secretKey string
+	// provider encrypts new data keys, providers decrypts the existing data keys by provider name.
+	provider     string
+	providers    map[string]Provider
+	providersMtx sync.Mutex
+
+	mtx           sync.RWMutex
+	dataKeys      map[string][]byte
+	activeDataKey string
+}
+
+func newService(sqlStore *sqlstore.SQLStore, cfg *setting.Cfg, secretKey string) *SecretsService {
+	s := &SecretsService{SQLStore: sqlStore, Cfg: cfg}
+	s.setup(secretKey)
+	return s
+}
+
+func (s *SecretsService) setup(secretKey string) {
+	s.log = log.New("secrets")
+	s.envelope = s.Cfg.EnvelopeEncryptionEnabled
+	s.secretKey = secretKey
+	s.provider = providerName(s.Cfg)
+	s.providers = map[string]Provider{}
+	s.dataKeys = map[string][]byte{}
+}
+
+func (s *SecretsService) Init() error {
+	s.setup(setting.SecretKey)
+
+	if s.envelope {
+		// fail early if the provider is not configured correctly
+		if _, err := s.getProvider(s.provider); err != nil {
+			return err
+		}
+
+		// secrets are encrypted in the transactions of the sqlstore, where creating the data key in its
+		// own transaction would wait for their lock on sqlite, so the active data key is loaded up front
+		if _, _, err := s.currentDataKey(context.Background()); err != nil {
+			return err
+		}
+	}
+
+	securejsondata.SetEncrypter(s)
+	return nil
+}
+
+// Encrypt encrypts a secret with the active data key, or with the secret_key if envelope encryption is disabled.
+func (s *SecretsService) Encrypt(payload []byte) ([]byte, error) {
+	if !s.envelope {
+		return util.Encrypt(payload, s.secretKey)
+	}
+
+	name, key, err := s.currentDataKey(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := util.Encrypt(payload, string(key))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]byte, 0, len(name)+2+len(encrypted))
+	result = append(result, envelopePrefix)
+	result = append(result, name...)
+	result = append(result, envelopePrefix)
+	return append(result, encrypted...), nil
+}
+
+// Decrypt decrypts a secret that was encrypted with a data key or with the secret_key.
+func (s *SecretsService) Decrypt(payload []byte) ([]byte, error) {
+	if len(payload) == 0 || payload[0] != envelopePrefix {
+		return util.Decrypt(payload, s.secretKey)
+	}
+
+	payload = payload[1:]
+	i := bytes.IndexByte(payload, envelopePrefix)
+	if i < 1 {
+		return nil, errInvalidPayload
+	}
+
+	key, err := s.dataKey(context.Background(), string(payload[:i]))
+	if err != nil {
+		return nil, err
+	}
+
+	return util.Decrypt(payload[i+1:], string(key))
+}
+
+func (s *SecretsService) getProvider(name string) (Provider, error) {
+	s.providersMtx.Lock()
+	defer s.providersMtx.Unlock()
+
+	if provider, ok := s.providers[name]; ok {
+		return provider, nil
+	}
+
+	if name != s.provider && name != ProviderSecretKey {
+		return nil, fmt.Errorf("data key was encrypted by the %q provider, but %q is configured", name, s.provider)
+	}
+
+	cfg := *s.Cfg
+	cfg.EncryptionProvider = name
+	provider, err := newProvider(&cfg, s.secretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.providers[name] = provider
+	return provider, nil
+}
+
+// currentDataKey returns the active data key of the configured provider, and creates it if there is none.
+func (s *SecretsService) currentDataKey(ctx context.Context) (string, []byte, error) {
+	s.mtx.RLock()
+	name := s.activeDataKey
+	key := s.dataKeys[name]
+	s.mtx.RUnlock()
+	if name != "" {
+		return name, key, nil
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.activeDataKey != "" {
+		return s.activeDataKey, s.dataKeys[s.activeDataKey], nil
+	}
+
+	var dk dataKey
+	var exists bool
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		var err error
+		exists, err = dbSession.Where("active = ? AND provider = ?", s.SQLStore.Dialect.BooleanStr(true), s.provider).
+			Desc("created").Get(&dk)
+		return err
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	if exists {
+		key, err = s.decryptDataKey(ctx, &dk)
+	} else {
+		dk.Name, key, err = s.createDataKey(ctx)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	s.dataKeys[dk.Name] = key
+	s.activeDataKey = dk.Name
+	return dk.Name, key, nil
+}
+
+// createDataKey stores a new data key that replaces the active data keys.
+func (s *SecretsService) createDataKey(ctx context.Context) (string, []byte, error) {
+	dk, key, err := s.newDataKey(ctx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	// the provisioning dry run must not write to the database, so its data key only lives in memory
+	if s.Cfg.ProvisioningDryRun {
+		return dk.Name, key, nil
+	}
+
+	err = s.SQLStore.WithTransactionalDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		return s.activateDataKey(dbSession, dk)
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return dk.Name, key, nil
+}
+
+// newDataKey generates a data key and encrypts it with the master key of the configured provider.
+func (s *SecretsService) newDataKey(ctx context.Context) (*dataKey, []byte, error) {
+	provider, err := s.getProvider(s.provider)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	key := make([]byte, dataKeyLength)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, err
+	}
+
+	encrypted, err := provider.Encrypt(ctx, key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	return &dataKey{
+		Name:          util.GenerateShortUID(),
+		Active:        true,
+		Provider:      s.provider,
+		EncryptedData: encrypted,
+		Created:       now,
+		Updated:       now,
+	}, key, nil
+}
+
+// activateDataKey stores a data key and deactivates the other data keys.
+func (s *SecretsService) activateDataKey(dbSession *sqlstore.DBSession, dk *dataKey) error {
+	_, err := dbSession.Exec("UPDATE data_key SET active = ?, updated = ? WHERE active = ?",
+		s.SQLStore.Dialect.BooleanStr(false), dk.Updated, s.SQLStore.Dialect.BooleanStr(true))
+	if err != nil {
+		return err
+	}
+
+	if _, err := dbSession.Insert(dk); err != nil {
+		return err
+	}
+
+	s.log.Info("Created data key", "name", dk.Name, "provider", dk.Provider)
+	return nil
+}
+
+// dataKey returns the decrypted data key with the name.
+func (s *SecretsService) dataKey(ctx context.Context, name string) ([]byte, error) {
+	s.mtx.RLock()
+	key, ok := s.dataKeys[name]
+	s.mtx.RUnlock()
+	if ok {
+		return key, nil
+	}
+
+	var dk dataKey
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		exists, err := dbSession.Where("name = ?", name).Get(&dk)
+		if err == nil && !exists {
+			err = fmt.Errorf("data key %q not found", name)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key, err = s.decryptDataKey(ctx, &dk)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mtx.Lock()
+	s.dataKeys[name] = key
+	s.mtx.Unlock()
+	return key, nil
+}
+
+// loadDataKeys decrypts all data keys, so that secrets can be decrypted while a transaction is open.
+func (s *SecretsService) loadDataKeys(ctx context.Context) error {
+	var dataKeys []*dataKey
+	err := s.SQLStore.WithDbSession(ctx, func(dbSession *sqlstore.DBSession) error {
+		return dbSession.Find(&dataKeys)
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, dk := range dataKeys {
+		key, err := s.decryptDataKey(ctx, dk)
+		if err != nil {
+			return err
+		}
+
+		s.mtx.Lock()
+		s.dataKeys[dk.Name] = key
+		s.mtx.Unlock()
+	}
+
+	return nil
+}
+
+func (s *SecretsService) decryptDataKey(ctx context.Context, dk *dataKey) ([]byte, error) {
+	provider, err := s.getProvider(dk.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := provider.Decrypt(ctx, dk.EncryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt data key %q: %w", dk.Name, err)
+	}
+
+	return key, nil
+}
+
+type dataKey struct {
+	Name          string `xorm:"pk"`
+	Active        bool
+	Provider      string
+	EncryptedData []byte
+	Created       time.Time
+	Updated       time.Time
+}
@@ -0,0 +1,297 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/securejsondata"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/sqlstore"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func TestSecretsService(t *testing.T) {
+	sqlStore := sqlstore.InitTestDB(t)
+	cfg := setting.NewCfg()
+	cfg.EnvelopeEncryptionEnabled = true
+
+	s := &SecretsService{SQLStore: sqlStore, Cfg: cfg}
+	require.NoError(t, s.Init())
+	t.Cleanup(func() { securejsondata.SetEncrypter(nil) })
+
+	t.Run("Encrypts with a data key", func(t *testing.T) {
+		encrypted, err := s.Encrypt([]byte("grafana"))
+		require.NoError(t, err)
+		assert.Equal(t, "#"+s.activeDataKey+"#", string(encrypted[:len(s.activeDataKey)+2]))
+
+		decrypted, err := s.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "grafana", string(decrypted))
+
+		var dataKeys []*dataKey
+		require.NoError(t, sqlStore.NewSession().Find(&dataKeys))
+		require.Len(t, dataKeys, 1)
+		assert.True(t, dataKeys[0].Active)
+		assert.Equal(t, ProviderSecretKey, dataKeys[0].Provider)
+		assert.NotContains(t, string(dataKeys[0].EncryptedData), string(s.dataKeys[s.activeDataKey]))
+	})
+
+	t.Run("Decrypts with data keys of the database", func(t *testing.T) {
+		encrypted, err := s.Encrypt([]byte("grafana"))
+		require.NoError(t, err)
+
+		other := newService(sqlStore, cfg, setting.SecretKey)
+		decrypted, err := other.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "grafana", string(decrypted))
+	})
+
+	t.Run("Decrypts secrets encrypted with the secret_key", func(t *testing.T) {
+		encrypted, err := util.Encrypt([]byte("grafana"), setting.SecretKey)
+		require.NoError(t, err)
+
+		decrypted, err := s.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "grafana", string(decrypted))
+	})
+
+	t.Run("Rejects unknown data keys", func(t *testing.T) {
+		_, err := s.Decrypt([]byte("#unknown#payload"))
+		assert.Error(t, err)
+
+		_, err = s.Decrypt([]byte("##payload"))
+		assert.Equal(t, errInvalidPayload, err)
+	})
+
+	t.Run("Encrypts SecureJsonData", func(t *testing.T) {
+		secureJSONData := securejsondata.GetEncryptedJsonData(map[string]string{"password": "grafana"})
+		assert.Equal(t, byte('#'), secureJSONData["password"][0])
+		assert.Equal(t, map[string]string{"password": "grafana"}, secureJSONData.Decrypt())
+	})
+
+	t.Run("Encrypts in the transactions of the sqlstore", func(t *testing.T) {
+		sqlStore := sqlstore.InitTestDB(t)
+		s := &SecretsService{SQLStore: sqlStore, Cfg: cfg}
+		require.NoError(t, s.Init())
+
+		err := sqlStore.WithTransactionalDbSession(context.Background(), func(sess *sqlstore.DBSession) error {
+			if _, err := sess.Exec("DELETE FROM data_source"); err != nil {
+				return err
+			}
+			_, err := s.Encrypt([]byte("grafana"))
+			return err
+		})
+		require.NoError(t, err)
+	})
+
+	t.Run("Doesn't store data keys in a provisioning dry run", func(t *testing.T) {
+		sqlStore := sqlstore.InitTestDB(t)
+		cfg := setting.NewCfg()
+		cfg.EnvelopeEncryptionEnabled = true
+		cfg.ProvisioningDryRun = true
+		dryRun := newService(sqlStore, cfg, setting.SecretKey)
+
+		encrypted, err := dryRun.Encrypt([]byte("grafana"))
+		require.NoError(t, err)
+		decrypted, err := dryRun.Decrypt(encrypted)
+		require.NoError(t, err)
+		assert.Equal(t, "grafana", string(decrypted))
+
+		count, err := sqlStore.NewSession().Count(&dataKey{})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+}
+
+func TestRotateMasterKey(t *testing.T) {
+	oldSecretKey := setting.SecretKey
+	t.Cleanup(func() { setting.SecretKey = oldSecretKey })
+
+	insertSecrets := func(t *testing.T, sqlStore *sqlstore.SQLStore, encrypter securejsondata.Encrypter) {
+		t.Helper()
+
+		securejsondata.SetEncrypter(encrypter)
+		defer securejsondata.SetEncrypter(nil)
+
+		now := time.Now()
+		sess := sqlStore.NewSession()
+		_, err := sess.Insert(&models.DataSource{
+			OrgId: 1, Name: "prometheus", Type: "prometheus", Uid: "prometheus", JsonData: simplejson.New(), Created: now, Updated: now,
+			SecureJsonData: securejsondata.GetEncryptedJsonData(map[string]string{"basicAuthPassword": "ds-secret"}),
+		})
+		require.NoError(t, err)
+		_, err = sess.Insert(&models.DataSource{OrgId: 1, Name: "testdata", Type: "testdata", Uid: "testdata", JsonData: simplejson.New(), Created: now, Updated: now})
+		require.NoError(t, err)
+		_, err = sess.Insert(&models.AlertNotification{
+			OrgId: 1, Name: "slack", Type: "slack", Settings: simplejson.New(), Created: now, Updated: now,
+			SecureSettings: securejsondata.GetEncryptedJsonData(map[string]string{"url": "notification-secret"}),
+		})
+		require.NoError(t, err)
+		_, err = sess.Insert(&models.PluginSetting{
+			OrgId: 1, PluginId: "app", Created: now, Updated: now,
+			SecureJsonData: securejsondata.GetEncryptedJsonData(map[string]string{"token": "plugin-secret"}),
+		})
+		require.NoError(t, err)
+
+		accessToken, err := encrypter.Encrypt([]byte("access-token"))
+		require.NoError(t, err)
+		_, err = sess.Insert(&models.UserAuth{
+			UserId: 1, AuthModule: "oauth_generic_oauth", AuthId: "1", Created: now,
+			OAuthAccessToken: base64.StdEncoding.EncodeToString(accessToken),
+		})
+		require.NoError(t, err)
+		dashboard, err := encrypter.Encrypt([]byte(`{"title":"snapshot"}`))
+		require.NoError(t, err)
+		_, err = sess.Insert(&models.DashboardSnapshot{
+			OrgId: 1, Name: "snapshot", Key: "key", DeleteKey: "delete-key", Created: now, Updated: now, Expires: now,
+			DashboardEncrypted: dashboard,
+		})
+		require.NoError(t, err)
+	}
+
+	assertSecrets := func(t *testing.T, sqlStore *sqlstore.SQLStore, s *SecretsService) {
+		t.Helper()
+
+		securejsondata.SetEncrypter(s)
+		defer securejsondata.SetEncrypter(nil)
+
+		sess := sqlStore.NewSession()
+		var ds models.DataSource
+		_, err := sess.Where("name = ?", "prometheus").Get(&ds)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"basicAuthPassword": "ds-secret"}, ds.SecureJsonData.Decrypt())
+
+		var an models.AlertNotification
+		_, err = sess.Get(&an)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"url": "notification-secret"}, an.SecureSettings.Decrypt())
+
+		var ps models.PluginSetting
+		_, err = sess.Get(&ps)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]string{"token": "plugin-secret"}, ps.SecureJsonData.Decrypt())
+
+		var ua models.UserAuth
+		_, err = sess.Get(&ua)
+		require.NoError(t, err)
+		accessToken, err := base64.StdEncoding.DecodeString(ua.OAuthAccessToken)
+		require.NoError(t, err)
+		decrypted, err := s.Decrypt(accessToken)
+		require.NoError(t, err)
+		assert.Equal(t, "access-token", string(decrypted))
+		assert.Empty(t, ua.OAuthRefreshToken)
+
+		var snapshot models.DashboardSnapshot
+		_, err = sess.Get(&snapshot)
+		require.NoError(t, err)
+		decrypted, err = s.Decrypt(snapshot.DashboardEncrypted)
+		require.NoError(t, err)
+		assert.Equal(t, `{"title":"snapshot"}`, string(decrypted))
+	}
+
+	t.Run("Re-encrypts secrets with a new secret_key", func(t *testing.T) {
+		sqlStore := sqlstore.InitTestDB(t)
+		cfg := setting.NewCfg()
+
+		setting.SecretKey = "old-secret"
+		insertSecrets(t, sqlStore, newService(sqlStore, cfg, setting.SecretKey))
+
+		setting.SecretKey = "new-secret"
+		result, err := RotateMasterKey(context.Background(), cfg, sqlStore, "old-secret")
+		require.NoError(t, err)
+		assert.Equal(t, &RotationResult{DataSources: 1, AlertNotifications: 1, PluginSettings: 1, OAuthTokens: 1, DashboardSnapshots: 1}, result)
+
+		assertSecrets(t, sqlStore, newService(sqlStore, cfg, "new-secret"))
+	})
+
+	t.Run("Moves secrets encrypted with the secret_key to data keys", func(t *testing.T) {
+		sqlStore := sqlstore.InitTestDB(t)
+		cfg := setting.NewCfg()
+		setting.SecretKey = "secret"
+
+		insertSecrets(t, sqlStore, newService(sqlStore, cfg, setting.SecretKey))
+
+		cfg.EnvelopeEncryptionEnabled = true
+		_, err := RotateMasterKey(context.Background(), cfg, sqlStore, "")
+		require.NoError(t, err)
+
+		var ds models.DataSource
+		_, err = sqlStore.NewSession().Where("name = ?", "prometheus").Get(&ds)
+		require.NoError(t, err)
+		assert.Equal(t, byte('#'), ds.SecureJsonData["basicAuthPassword"][0])
+
+		assertSecrets(t, sqlStore, newService(sqlStore, cfg, setting.SecretKey))
+	})
+
+	t.Run("Rotates the master key of the file provider", func(t *testing.T) {
+		sqlStore := sqlstore.InitTestDB(t)
+		cfg := setting.NewCfg()
+		cfg.EnvelopeEncryptionEnabled = true
+		cfg.EncryptionProvider = ProviderFile
+		cfg.EncryptionKeyFile = filepath.Join(t.TempDir(), "keys")
+
+		before := newService(sqlStore, cfg, setting.SecretKey)
+		insertSecrets(t, sqlStore, before)
+		oldDataKey := before.activeDataKey
+
+		_, err := RotateMasterKey(context.Background(), cfg, sqlStore, "")
+		require.NoError(t, err)
+
+		var dataKeys []*dataKey
+		require.NoError(t, sqlStore.NewSession().Find(&dataKeys))
+		require.Len(t, dataKeys, 1)
+		assert.NotEqual(t, oldDataKey, dataKeys[0].Name)
+		assert.True(t, dataKeys[0].Active)
+
+		provider, err := NewFileProvider(cfg.EncryptionKeyFile)
+		require.NoError(t, err)
+		assert.Len(t, provider.keys, 1)
+
+		assertSecrets(t, sqlStore, newService(sqlStore, cfg, setting.SecretKey))
+	})
+
+	t.Run("Restores the key file and data keys if the secrets can't be re-encrypted", func(t *testing.T) {
+		sqlStore := sqlstore.InitTestDB(t)
+		cfg := setting.NewCfg()
+		cfg.EnvelopeEncryptionEnabled = true
+		cfg.EncryptionProvider = ProviderFile
+		cfg.EncryptionKeyFile = filepath.Join(t.TempDir(), "keys")
+
+		before := newService(sqlStore, cfg, setting.SecretKey)
+		insertSecrets(t, sqlStore, before)
+		keyFile, err := os.ReadFile(cfg.EncryptionKeyFile)
+		require.NoError(t, err)
+
+		_, err = sqlStore.NewSession().Exec("UPDATE plugin_setting SET secure_json_data = ?", `{"token":"bm90IGVuY3J5cHRlZA=="}`)
+		require.NoError(t, err)
+
+		_, err = RotateMasterKey(context.Background(), cfg, sqlStore, "")
+		require.Error(t, err)
+
+		after, err := os.ReadFile(cfg.EncryptionKeyFile)
+		require.NoError(t, err)
+		assert.Equal(t, string(keyFile), string(after))
+
+		var dataKeys []*dataKey
+		require.NoError(t, sqlStore.NewSession().Find(&dataKeys))
+		require.Len(t, dataKeys, 1)
+		assert.Equal(t, before.activeDataKey, dataKeys[0].Name)
+		assert.True(t, dataKeys[0].Active)
+
+		var ds models.DataSource
+		_, err = sqlStore.NewSession().Where("name = ?", "prometheus").Get(&ds)
+		require.NoError(t, err)
+		securejsondata.SetEncrypter(newService(sqlStore, cfg, setting.SecretKey))
+		defer securejsondata.SetEncrypter(nil)
+		assert.Equal(t, map[string]string{"basicAuthPassword": "ds-secret"}, ds.SecureJsonData.Decrypt())
+	})
+}
@@ -40,7 +40,8 @@ func GetAlertById(query *models.GetAlertByIdQuery) error {
 
 func GetAllAlertQueryHandler(query *models.GetAllAlertsQuery) error {
 	var alerts []*models.Alert
-	err := x.SQL("select * from alert").Find(&alerts)
+	// the alerts of deleted dashboards are kept until the dashboards are purged from the trash, but don't run
+	err := x.SQL("select * from alert WHERE NOT EXISTS (SELECT 1 FROM deleted_dashboard WHERE deleted_dashboard.dashboard_id = alert.dashboard_id)").Find(&alerts)
 	if err != nil {
 		return err
 	}
@@ -50,6 +50,31 @@ func SaveDashboard(cmd *models.SaveDashboardCommand) error {
 	})
 }
 
+// insertNewDashboard inserts a dashboard with a new id. The trash keeps the versions, permissions, stars,
+// annotations and alerts of deleted dashboards by their id, and MySQL hands out the id of a deleted dashboard
+// again after a restart, so new dashboards skip the ids of dashboards in the trash.
+func insertNewDashboard(sess *DBSession, dash *models.Dashboard) (int64, error) {
+	for {
+		affectedRows, err := sess.Insert(dash)
+		if err != nil || affectedRows == 0 {
+			return affectedRows, err
+		}
+
+		inTrash, err := sess.Table("deleted_dashboard").Where("dashboard_id=?", dash.Id).Exist()
+		if err != nil {
+			return 0, err
+		}
+		if !inTrash {
+			return affectedRows, nil
+		}
+
+		if _, err := sess.Exec("DELETE FROM dashboard WHERE id=?", dash.Id); err != nil {
+			return 0, err
+		}
+		dash.Id = 0
+	}
+}
+
 func saveDashboard(sess *DBSession, cmd *models.SaveDashboardCommand) error {
 	dash := cmd.GetDashboardModel()
 
@@ -59,7 +84,8 @@ func saveDashboard(sess *DBSession, cmd *models.SaveDashboardCommand) error {
 		userId = -1
 	}
 
-	if dash.Id > 0 {
+	// a restored dashboard is inserted again with its previous id
+	if dash.Id > 0 && !cmd.Restored {
 		var existing models.Dashboard
 		dashWithIdExists, err := sess.Where("id=? AND org_id=?", dash.Id, dash.OrgId).Get(&existing)
 		if err != nil {
@@ -96,14 +122,22 @@ func saveDashboard(sess *DBSession, cmd *models.SaveDashboardCommand) error {
 	var affectedRows int64
 	var err error
 
-	if dash.Id == 0 {
-		dash.SetVersion(1)
+	if dash.Id == 0 || cmd.Restored {
+		if cmd.Restored {
+			dash.SetVersion(dash.Version + 1)
+		} else {
+			dash.SetVersion(1)
+		}
 		dash.Created = time.Now()
 		dash.CreatedBy = userId
 		dash.Updated = time.Now()
 		dash.UpdatedBy = userId
 		metrics.MApiDashboardInsert.Inc()
-		affectedRows, err = sess.Insert(dash)
+		if cmd.Restored {
+			affectedRows, err = sess.Insert(dash)
+		} else {
+			affectedRows, err = insertNewDashboard(sess, dash)
+		}
 	} else {
 		dash.SetVersion(dash.Version + 1)
 
@@ -144,8 +178,19 @@ func saveDashboard(sess *DBSession, cmd *models.SaveDashboardCommand) error {
 		return models.ErrDashboardNotFound
 	}
 
+	if err := saveDashboardIndices(sess, dash); err != nil {
+		return err
+	}
+
+	cmd.Result = dash
+
+	return err
+}
+
+// saveDashboardIndices replaces the tags, search terms and dependencies of the dashboard.
+func saveDashboardIndices(sess *DBSession, dash *models.Dashboard) error {
 	// delete existing tags
-	_, err = sess.Exec("DELETE FROM dashboard_tag WHERE dashboard_id=?", dash.Id)
+	_, err := sess.Exec("DELETE FROM dashboard_tag WHERE dashboard_id=?", dash.Id)
 	if err != nil {
 		return err
 	}
@@ -160,9 +205,11 @@ func saveDashboard(sess *DBSession, cmd *models.SaveDashboardCommand) error {
 		}
 	}
 
-	cmd.Result = dash
+	if err := saveDashboardSearchTerms(sess, dash); err != nil {
+		return err
+	}
 
-	return err
+	return saveDashboardDependencies(sess, dash)
 }
 
 func generateNewDashboardUid(sess *DBSession, orgId int64) (string, error) {
@@ -253,6 +300,10 @@ func findDashboards(query *search.FindPersistedDashboardsQuery) ([]DashboardSear
 		filters = append(filters, searchstore.TitleFilter{Dialect: dialect, Title: query.Title})
 	}
 
+	for _, term := range query.Terms {
+		filters = append(filters, searchstore.SearchTermFilter{Dialect: dialect, Field: term.Field, Term: term.Term})
+	}
+
 	if len(query.Type) > 0 {
 		filters = append(filters, searchstore.TypeFilter{Dialect: dialect, Type: query.Type})
 	}
@@ -374,42 +425,32 @@ func deleteDashboard(cmd *models.DeleteDashboardCommand, sess *DBSession) error
 		return models.ErrDashboardNotFound
 	}
 
+	// the versions, permissions, stars, annotations and alerts of deleted dashboards are kept until they're
+	// purged from the trash, so they can be restored
 	deletes := []string{
 		"DELETE FROM dashboard_tag WHERE dashboard_id = ? ",
-		"DELETE FROM star WHERE dashboard_id = ? ",
+		"DELETE FROM dashboard_search_term WHERE dashboard_id = ? ",
+		"DELETE FROM dashboard_dependency WHERE dashboard_id = ? ",
 		"DELETE FROM dashboard WHERE id = ?",
 		"DELETE FROM playlist_item WHERE type = 'dashboard_by_id' AND value = ?",
-		"DELETE FROM dashboard_version WHERE dashboard_id = ?",
-		"DELETE FROM annotation WHERE dashboard_id = ?",
 		"DELETE FROM dashboard_provisioning WHERE dashboard_id = ?",
-		"DELETE FROM dashboard_acl WHERE dashboard_id = ?",
 	}
 
+	now := time.Now()
 	if dashboard.IsFolder {
 		deletes = append(deletes, "DELETE FROM dashboard WHERE folder_id = ?")
 
-		dashIds := []struct {
-			Id int64
-		}{}
-		err := sess.SQL("SELECT id FROM dashboard WHERE folder_id = ?", dashboard.Id).Find(&dashIds)
-		if err != nil {
+		var children []*models.Dashboard
+		if err := sess.Where("org_id = ? AND folder_id = ?", dashboard.OrgId, dashboard.Id).Find(&children); err != nil {
 			return err
 		}
 
-		for _, id := range dashIds {
-			if err := deleteAlertDefinition(id.Id, sess); err != nil {
-				return err
-			}
-		}
-
-		if len(dashIds) > 0 {
+		if len(children) > 0 {
 			childrenDeletes := []string{
 				"DELETE FROM dashboard_tag WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
-				"DELETE FROM star WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
-				"DELETE FROM dashboard_version WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
-				"DELETE FROM annotation WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
+				"DELETE FROM dashboard_search_term WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
+				"DELETE FROM dashboard_dependency WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 				"DELETE FROM dashboard_provisioning WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
-				"DELETE FROM dashboard_acl WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ? AND folder_id = ?)",
 			}
 			for _, sql := range childrenDeletes {
 				_, err := sess.Exec(sql, dashboard.OrgId, dashboard.Id)
@@ -418,9 +459,36 @@ func deleteDashboard(cmd *models.DeleteDashboardCommand, sess *DBSession) error
 				}
 			}
 		}
+
+		for _, child := range children {
+			_, err := sess.Insert(&models.DeletedDashboard{
+				OrgId:       child.OrgId,
+				DashboardId: child.Id,
+				Uid:         child.Uid,
+				Title:       child.Title,
+				FolderId:    child.FolderId,
+				IsFolder:    child.IsFolder,
+				DeletedWith: dashboard.Id,
+				Data:        child.Data,
+				Deleted:     now,
+			})
+			if err != nil {
+				return err
+			}
+		}
 	}
 
-	if err := deleteAlertDefinition(dashboard.Id, sess); err != nil {
+	_, err = sess.Insert(&models.DeletedDashboard{
+		OrgId:       dashboard.OrgId,
+		DashboardId: dashboard.Id,
+		Uid:         dashboard.Uid,
+		Title:       dashboard.Title,
+		FolderId:    dashboard.FolderId,
+		IsFolder:    dashboard.IsFolder,
+		Data:        dashboard.Data,
+		Deleted:     now,
+	})
+	if err != nil {
 		return err
 	}
 
@@ -0,0 +1,162 @@
+package sqlstore
+
+import (
+	"strings"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// dashboardDependencyIndexBatchSize is the number of dashboards that are indexed per transaction.
+const dashboardDependencyIndexBatchSize = 100
+
+func init() {
+	bus.AddHandler("sql", GetDashboardDependencies)
+	bus.AddHandler("sql", GetDependentDashboards)
+	bus.AddHandler("sql", IndexDashboardDependencies)
+}
+
+// saveDashboardDependencies replaces the indexed dependencies of the dashboard. Data sources are
+// resolved to their uid, and add a dependency on their plugin.
+func saveDashboardDependencies(sess *DBSession, dash *models.Dashboard) error {
+	if _, err := sess.Exec("DELETE FROM dashboard_dependency WHERE dashboard_id=?", dash.Id); err != nil {
+		return err
+	}
+
+	seen := map[models.DashboardDependency]bool{}
+	rows := []*DashboardDependency{}
+	add := func(dep models.DashboardDependency) {
+		if !seen[dep] {
+			seen[dep] = true
+			rows = append(rows, &DashboardDependency{
+				OrgId: dash.OrgId, DashboardId: dash.Id, Kind: dep.Kind, Ref: dep.Ref, Name: dep.Name,
+			})
+		}
+	}
+
+	for _, dep := range dash.GetDependencies() {
+		if dep.Kind != models.DependencyKindDataSource {
+			add(dep)
+			continue
+		}
+
+		ds, err := getDashboardDataSource(sess, dash.OrgId, dep)
+		if err != nil {
+			return err
+		}
+		if ds == nil {
+			// the data source doesn't exist, or the org has no default data source
+			if dep.Ref != "" || dep.Name != "" {
+				add(dep)
+			}
+			continue
+		}
+
+		add(models.DashboardDependency{Kind: models.DependencyKindDataSource, Ref: ds.Uid, Name: ds.Name})
+		add(models.DashboardDependency{Kind: models.DependencyKindPlugin, Ref: ds.Type})
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+	_, err := sess.InsertMulti(rows)
+	return err
+}
+
+// getDashboardDataSource returns the data source a dashboard refers to by uid, name or as the default
+// data source, or nil if it doesn't exist.
+func getDashboardDataSource(sess *DBSession, orgID int64, dep models.DashboardDependency) (*models.DataSource, error) {
+	ds := &models.DataSource{}
+	query := sess.Where("org_id=?", orgID)
+	switch {
+	case dep.Ref != "":
+		query = query.And("uid=?", dep.Ref)
+	case dep.Name != "":
+		query = query.And("name=?", dep.Name)
+	default:
+		query = query.And("is_default=?", true)
+	}
+
+	has, err := query.Get(ds)
+	if err != nil || !has {
+		return nil, err
+	}
+	return ds, nil
+}
+
+func GetDashboardDependencies(query *models.GetDashboardDependenciesQuery) error {
+	var rows []*DashboardDependency
+	err := x.Where("org_id=? AND dashboard_id=?", query.OrgId, query.DashboardId).Asc("kind", "ref", "name").Find(&rows)
+	if err != nil {
+		return err
+	}
+
+	query.Result = make([]*models.DashboardDependency, 0, len(rows))
+	for _, row := range rows {
+		query.Result = append(query.Result, &models.DashboardDependency{Kind: row.Kind, Ref: row.Ref, Name: row.Name})
+	}
+	return nil
+}
+
+func GetDependentDashboards(query *models.GetDependentDashboardsQuery) error {
+	params := []interface{}{query.OrgId, query.Kind}
+	conditions := []string{}
+	if len(query.Refs) > 0 {
+		conditions = append(conditions, "dashboard_dependency.ref IN (?"+strings.Repeat(",?", len(query.Refs)-1)+")")
+		for _, ref := range query.Refs {
+			params = append(params, ref)
+		}
+	}
+	if query.Name != "" {
+		conditions = append(conditions, "(dashboard_dependency.ref = '' AND dashboard_dependency.name = ?)")
+		params = append(params, query.Name)
+	}
+
+	query.Result = make([]*models.DependentDashboard, 0)
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	sql := `SELECT DISTINCT dashboard.id, dashboard.uid, dashboard.title, dashboard.folder_id
+		FROM dashboard
+		INNER JOIN dashboard_dependency ON dashboard_dependency.dashboard_id = dashboard.id
+		WHERE dashboard_dependency.org_id = ? AND dashboard_dependency.kind = ? AND (` + strings.Join(conditions, " OR ") + `)
+		ORDER BY dashboard.title ASC`
+	return x.SQL(sql, params...).Find(&query.Result)
+}
+
+// IndexDashboardDependencies indexes the dashboards that don't have any dependencies, in batches.
+// Dashboards without dependencies are indexed again, which is cheap since they have no panels.
+func IndexDashboardDependencies(cmd *models.IndexDashboardDependenciesCommand) error {
+	var lastID int64
+	for {
+		var indexed int
+		err := inTransaction(func(sess *DBSession) error {
+			var dashboards []*models.Dashboard
+			err := sess.SQL(`SELECT * FROM dashboard
+				WHERE dashboard.id > ? AND dashboard.is_folder = `+dialect.BooleanStr(false)+` AND
+				NOT EXISTS (SELECT 1 FROM dashboard_dependency WHERE dashboard_dependency.dashboard_id = dashboard.id)
+				ORDER BY dashboard.id`+dialect.Limit(dashboardDependencyIndexBatchSize), lastID).Find(&dashboards)
+			if err != nil {
+				return err
+			}
+
+			for _, dash := range dashboards {
+				if err := saveDashboardDependencies(sess, dash); err != nil {
+					return err
+				}
+				lastID = dash.Id
+			}
+			indexed = len(dashboards)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		cmd.Result += int64(indexed)
+		if indexed < dashboardDependencyIndexBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestDashboardDependencies(t *testing.T) {
+	saveDashboard := func(t *testing.T, data map[string]interface{}) *models.Dashboard {
+		t.Helper()
+		cmd := models.SaveDashboardCommand{OrgId: 1, Overwrite: true, Dashboard: simplejson.NewFromAny(data)}
+		require.NoError(t, SaveDashboard(&cmd))
+		return cmd.Result
+	}
+
+	dependents := func(t *testing.T, kind string, refs []string, name string) []string {
+		t.Helper()
+		query := &models.GetDependentDashboardsQuery{OrgId: 1, Kind: kind, Refs: refs, Name: name}
+		require.NoError(t, GetDependentDashboards(query))
+		uids := []string{}
+		for _, dash := range query.Result {
+			uids = append(uids, dash.Uid)
+		}
+		return uids
+	}
+
+	setup := func(t *testing.T) *models.Dashboard {
+		InitTestDB(t)
+		require.NoError(t, AddDataSource(&models.AddDataSourceCommand{
+			OrgId: 1, Name: "Prometheus", Type: "prometheus", Uid: "prom", IsDefault: true,
+		}))
+		require.NoError(t, AddDataSource(&models.AddDataSourceCommand{OrgId: 1, Name: "Loki", Type: "loki", Uid: "loki"}))
+
+		saveDashboard(t, map[string]interface{}{
+			"uid": "logs", "title": "Logs",
+			"panels": []interface{}{map[string]interface{}{
+				"type": "logs", "datasource": "Loki", "targets": []interface{}{map[string]interface{}{"expr": "{job=\"api\"}"}},
+			}},
+		})
+		return saveDashboard(t, map[string]interface{}{
+			"uid": "api", "title": "API",
+			"panels": []interface{}{
+				map[string]interface{}{"type": "graph", "targets": []interface{}{map[string]interface{}{"expr": "up"}}},
+				map[string]interface{}{"type": "graph", "datasource": "Elasticsearch", "targets": []interface{}{map[string]interface{}{}}},
+			},
+			"links": []interface{}{map[string]interface{}{"url": "/d/logs/logs"}},
+		})
+	}
+
+	t.Run("Indexes resolved data sources, plugins and links", func(t *testing.T) {
+		dash := setup(t)
+
+		query := &models.GetDashboardDependenciesQuery{OrgId: 1, DashboardId: dash.Id}
+		require.NoError(t, GetDashboardDependencies(query))
+		assert.Equal(t, []*models.DashboardDependency{
+			{Kind: models.DependencyKindDashboard, Ref: "logs"},
+			{Kind: models.DependencyKindDataSource, Name: "Elasticsearch"},
+			{Kind: models.DependencyKindDataSource, Ref: "prom", Name: "Prometheus"},
+			{Kind: models.DependencyKindPlugin, Ref: "graph"},
+			{Kind: models.DependencyKindPlugin, Ref: "prometheus"},
+		}, query.Result)
+
+		assert.Equal(t, []string{"api"}, dependents(t, models.DependencyKindDataSource, []string{"prom"}, "Prometheus"))
+		assert.Equal(t, []string{"logs"}, dependents(t, models.DependencyKindDataSource, []string{"loki"}, ""))
+		assert.Equal(t, []string{"api"}, dependents(t, models.DependencyKindDataSource, nil, "Elasticsearch"))
+		assert.Equal(t, []string{"api", "logs"}, dependents(t, models.DependencyKindPlugin, []string{"graph", "logs"}, ""))
+		assert.Equal(t, []string{"api"}, dependents(t, models.DependencyKindDashboard, []string{"logs"}, ""))
+	})
+
+	t.Run("Updates the index when dashboards are saved and deleted", func(t *testing.T) {
+		dash := setup(t)
+
+		saveDashboard(t, map[string]interface{}{"id": dash.Id, "uid": "api", "title": "API"})
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindDataSource, []string{"prom"}, ""))
+
+		logs := &models.GetDashboardQuery{OrgId: 1, Uid: "logs"}
+		require.NoError(t, GetDashboard(logs))
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: logs.Result.Id, OrgId: 1}))
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindDataSource, []string{"loki"}, ""))
+	})
+
+	t.Run("Updates the index when the data source of a dashboard changes", func(t *testing.T) {
+		dash := setup(t)
+
+		saveDashboard(t, map[string]interface{}{
+			"id": dash.Id, "uid": "api", "title": "API",
+			"panels": []interface{}{map[string]interface{}{
+				"type": "logs", "datasource": "Loki", "targets": []interface{}{map[string]interface{}{}},
+			}},
+		})
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindDataSource, []string{"prom"}, "Prometheus"))
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindPlugin, []string{"graph"}, ""))
+		assert.Equal(t, []string{"api", "logs"}, dependents(t, models.DependencyKindDataSource, []string{"loki"}, ""))
+	})
+
+	t.Run("Looks up dependents by ref, or by name if the ref isn't known", func(t *testing.T) {
+		setup(t)
+
+		// the name of an indexed data source isn't used, since the data source may have been renamed
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindDataSource, nil, "Prometheus"))
+		assert.Equal(t, []string{"api"}, dependents(t, models.DependencyKindDataSource, []string{"prom"}, ""))
+		assert.Equal(t, []string{"api", "logs"}, dependents(t, models.DependencyKindDataSource, []string{"prom", "loki"}, ""))
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindDataSource, []string{"elasticsearch"}, ""))
+		assert.Equal(t, []string{}, dependents(t, models.DependencyKindDataSource, nil, ""))
+
+		// dependencies are looked up in the org of the query
+		query := &models.GetDependentDashboardsQuery{OrgId: 2, Kind: models.DependencyKindDataSource, Refs: []string{"prom"}}
+		require.NoError(t, GetDependentDashboards(query))
+		assert.Empty(t, query.Result)
+	})
+
+	t.Run("Deletes the index of the dashboards of a deleted folder", func(t *testing.T) {
+		setup(t)
+		folder := insertTestDashboard(t, "Ops", 1, 0, true)
+
+		child := models.SaveDashboardCommand{OrgId: 1, FolderId: folder.Id, Dashboard: simplejson.NewFromAny(map[string]interface{}{
+			"uid": "queue", "title": "Queue",
+			"panels": []interface{}{map[string]interface{}{"type": "logs", "datasource": "Loki"}},
+		})}
+		require.NoError(t, SaveDashboard(&child))
+		assert.Equal(t, []string{"logs", "queue"}, dependents(t, models.DependencyKindDataSource, []string{"loki"}, ""))
+
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: folder.Id, OrgId: 1}))
+		assert.Equal(t, []string{"logs"}, dependents(t, models.DependencyKindDataSource, []string{"loki"}, ""))
+	})
+
+	t.Run("Deletes the index of a deleted org", func(t *testing.T) {
+		setup(t)
+		org := &models.CreateOrgCommand{Name: "Main"}
+		require.NoError(t, CreateOrg(org))
+		require.Equal(t, int64(1), org.Result.Id)
+
+		require.NoError(t, DeleteOrg(&models.DeleteOrgCommand{Id: 1}))
+		var count int64
+		_, err := x.SQL("SELECT COUNT(*) FROM dashboard_dependency").Get(&count)
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Indexes dashboards that aren't indexed", func(t *testing.T) {
+		setup(t)
+		_, err := x.Exec("DELETE FROM dashboard_dependency")
+		require.NoError(t, err)
+
+		cmd := &models.IndexDashboardDependenciesCommand{}
+		require.NoError(t, IndexDashboardDependencies(cmd))
+		assert.Equal(t, int64(2), cmd.Result)
+		assert.Equal(t, []string{"logs"}, dependents(t, models.DependencyKindDataSource, []string{"loki"}, ""))
+	})
+}
@@ -0,0 +1,69 @@
+package sqlstore
+
+import (
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+// dashboardSearchIndexBatchSize is the number of dashboards that are indexed per transaction.
+const dashboardSearchIndexBatchSize = 100
+
+func init() {
+	bus.AddHandler("sql", IndexDashboardSearchTerms)
+}
+
+// saveDashboardSearchTerms replaces the indexed search terms of the dashboard. Every dashboard has
+// at least its title indexed, which tells indexed dashboards apart from dashboards that were saved
+// before the index was added.
+func saveDashboardSearchTerms(sess *DBSession, dash *models.Dashboard) error {
+	if _, err := sess.Exec("DELETE FROM dashboard_search_term WHERE dashboard_id=?", dash.Id); err != nil {
+		return err
+	}
+
+	terms := dash.GetSearchTerms()
+	if len(terms) == 0 {
+		return nil
+	}
+
+	rows := make([]*DashboardSearchTerm, 0, len(terms))
+	for _, t := range terms {
+		rows = append(rows, &DashboardSearchTerm{DashboardId: dash.Id, OrgId: dash.OrgId, Field: t.Field, Term: t.Term})
+	}
+	_, err := sess.InsertMulti(rows)
+	return err
+}
+
+// IndexDashboardSearchTerms indexes the dashboards that don't have any search terms, in batches.
+func IndexDashboardSearchTerms(cmd *models.IndexDashboardSearchTermsCommand) error {
+	var lastID int64
+	for {
+		var indexed int
+		err := inTransaction(func(sess *DBSession) error {
+			var dashboards []*models.Dashboard
+			err := sess.SQL(`SELECT * FROM dashboard
+				WHERE dashboard.id > ? AND
+				NOT EXISTS (SELECT 1 FROM dashboard_search_term WHERE dashboard_search_term.dashboard_id = dashboard.id)
+				ORDER BY dashboard.id`+dialect.Limit(dashboardSearchIndexBatchSize), lastID).Find(&dashboards)
+			if err != nil {
+				return err
+			}
+
+			for _, dash := range dashboards {
+				if err := saveDashboardSearchTerms(sess, dash); err != nil {
+					return err
+				}
+				lastID = dash.Id
+			}
+			indexed = len(dashboards)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		cmd.Result += int64(indexed)
+		if indexed < dashboardSearchIndexBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/search"
+)
+
+func TestDashboardSearchTerms(t *testing.T) {
+	user := &models.SignedInUser{UserId: 1, OrgId: 1, OrgRole: models.ROLE_ADMIN}
+
+	saveDashboard := func(t *testing.T, data map[string]interface{}) *models.Dashboard {
+		t.Helper()
+		cmd := models.SaveDashboardCommand{OrgId: 1, Overwrite: true, Dashboard: simplejson.NewFromAny(data)}
+		require.NoError(t, SaveDashboard(&cmd))
+		return cmd.Result
+	}
+
+	searchTitles := func(t *testing.T, terms ...search.TermQuery) []string {
+		t.Helper()
+		query := &search.FindPersistedDashboardsQuery{SignedInUser: user, OrgId: 1, Terms: terms}
+		require.NoError(t, SearchDashboards(query))
+		titles := []string{}
+		for _, hit := range query.Result {
+			titles = append(titles, hit.Title)
+		}
+		return titles
+	}
+
+	panel := func(title, datasource, expr string) map[string]interface{} {
+		return map[string]interface{}{
+			"title": title, "datasource": datasource, "targets": []interface{}{map[string]interface{}{"expr": expr}},
+		}
+	}
+
+	setup := func(t *testing.T) *models.Dashboard {
+		InitTestDB(t)
+		saveDashboard(t, map[string]interface{}{
+			"uid": "api", "title": "API", "panels": []interface{}{panel("Request rate", "Prometheus", "rate(http_requests_total[5m])")},
+		})
+		return saveDashboard(t, map[string]interface{}{
+			"uid": "db", "title": "Database", "description": "Owned by the storage team",
+			"panels": []interface{}{panel("Connections", "Prometheus", "pg_stat_activity_count")},
+		})
+	}
+
+	t.Run("Searches indexed fields", func(t *testing.T) {
+		setup(t)
+
+		assert.Equal(t, []string{"API"}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldTarget, Term: "http_requests"}))
+		assert.Equal(t, []string{"Database"}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldPanel, Term: "connections"}))
+		assert.Equal(t, []string{"API", "Database"}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldDataSource, Term: "Prometheus"}))
+		assert.Equal(t, []string{"Database"}, searchTitles(t, search.TermQuery{Term: "storage team"}))
+		assert.Equal(t, []string{}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldPanel, Term: "storage team"}))
+		assert.Equal(t, []string{"Database"}, searchTitles(t,
+			search.TermQuery{Field: models.DashboardSearchFieldDataSource, Term: "Prometheus"},
+			search.TermQuery{Field: models.DashboardSearchFieldTarget, Term: "pg_stat"},
+		))
+	})
+
+	t.Run("Updates the index when dashboards are saved and deleted", func(t *testing.T) {
+		dash := setup(t)
+
+		saveDashboard(t, map[string]interface{}{
+			"id": dash.Id, "uid": "db", "title": "Database", "panels": []interface{}{panel("Locks", "Prometheus", "pg_locks_count")},
+		})
+		assert.Equal(t, []string{}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldPanel, Term: "connections"}))
+		assert.Equal(t, []string{"Database"}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldPanel, Term: "locks"}))
+
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: dash.Id, OrgId: 1}))
+		count, err := x.Where("dashboard_id = ?", dash.Id).Count(&DashboardSearchTerm{})
+		require.NoError(t, err)
+		assert.Zero(t, count)
+	})
+
+	t.Run("Indexes dashboards that aren't indexed", func(t *testing.T) {
+		setup(t)
+		_, err := x.Exec("DELETE FROM dashboard_search_term")
+		require.NoError(t, err)
+
+		cmd := &models.IndexDashboardSearchTermsCommand{}
+		require.NoError(t, IndexDashboardSearchTerms(cmd))
+		assert.Equal(t, int64(2), cmd.Result)
+		assert.Equal(t, []string{"API"}, searchTitles(t, search.TermQuery{Field: models.DashboardSearchFieldTarget, Term: "http_requests"}))
+
+		cmd = &models.IndexDashboardSearchTermsCommand{}
+		require.NoError(t, IndexDashboardSearchTerms(cmd))
+		assert.Zero(t, cmd.Result)
+	})
+}
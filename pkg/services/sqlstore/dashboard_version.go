@@ -2,6 +2,7 @@ package sqlstore
 
 import (
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
 	"github.com/grafana/grafana/pkg/models"
@@ -14,11 +15,14 @@ func init() {
 	bus.AddHandler("sql", DeleteExpiredVersions)
 }
 
-// GetDashboardVersion gets the dashboard version for the given dashboard ID and version number.
+// GetDashboardVersion gets the dashboard version for the given dashboard ID and version number. The
+// versions of deleted dashboards are included.
 func GetDashboardVersion(query *models.GetDashboardVersionQuery) error {
 	version := models.DashboardVersion{}
-	has, err := x.Where("dashboard_version.dashboard_id=? AND dashboard_version.version=? AND dashboard.org_id=?", query.DashboardId, query.Version, query.OrgId).
+	has, err := x.Where("dashboard_version.dashboard_id=? AND dashboard_version.version=? AND (dashboard.org_id=? OR deleted_dashboard.org_id=?)",
+		query.DashboardId, query.Version, query.OrgId, query.OrgId).
 		Join("LEFT", "dashboard", `dashboard.id = dashboard_version.dashboard_id`).
+		Join("LEFT", "deleted_dashboard", `deleted_dashboard.dashboard_id = dashboard_version.dashboard_id`).
 		Get(&version)
 
 	if err != nil {
@@ -34,7 +38,8 @@ func GetDashboardVersion(query *models.GetDashboardVersionQuery) error {
 	return nil
 }
 
-// GetDashboardVersions gets all dashboard versions for the given dashboard ID.
+// GetDashboardVersions gets all dashboard versions for the given dashboard ID, including the versions
+// of deleted dashboards.
 func GetDashboardVersions(query *models.GetDashboardVersionsQuery) error {
 	if query.Limit == 0 {
 		query.Limit = 1000
@@ -53,7 +58,8 @@ func GetDashboardVersions(query *models.GetDashboardVersionsQuery) error {
 			dialect.Quote("user")+`.login as created_by`).
 		Join("LEFT", dialect.Quote("user"), `dashboard_version.created_by = `+dialect.Quote("user")+`.id`).
 		Join("LEFT", "dashboard", `dashboard.id = dashboard_version.dashboard_id`).
-		Where("dashboard_version.dashboard_id=? AND dashboard.org_id=?", query.DashboardId, query.OrgId).
+		Join("LEFT", "deleted_dashboard", `deleted_dashboard.dashboard_id = dashboard_version.dashboard_id`).
+		Where("dashboard_version.dashboard_id=? AND (dashboard.org_id=? OR deleted_dashboard.org_id=?)", query.DashboardId, query.OrgId, query.OrgId).
 		OrderBy("dashboard_version.version DESC").
 		Limit(query.Limit, query.Start).
 		Find(&query.Result)
@@ -64,6 +70,17 @@ func GetDashboardVersions(query *models.GetDashboardVersionsQuery) error {
 	if len(query.Result) < 1 {
 		return models.ErrNoVersionsForDashboardId
 	}
+
+	tagsQuery := models.GetDashboardVersionTagsQuery{DashboardId: query.DashboardId, OrgId: query.OrgId}
+	if err := GetDashboardVersionTags(&tagsQuery); err != nil {
+		return err
+	}
+	for _, version := range query.Result {
+		version.Tags = tagsQuery.Result[version.Version]
+		if version.Tags == nil {
+			version.Tags = []string{}
+		}
+	}
 	return nil
 }
 
@@ -75,60 +92,106 @@ func DeleteExpiredVersions(cmd *models.DeleteExpiredVersionsCommand) error {
 }
 
 func deleteExpiredVersions(cmd *models.DeleteExpiredVersionsCommand, perBatch int, maxBatches int) error {
-	versionsToKeep := setting.DashboardVersionsToKeep
-	if versionsToKeep < 1 {
-		versionsToKeep = 1
+	defaultPolicy := &versionRetention{versionsToKeep: setting.DashboardVersionsToKeep}
+	if defaultPolicy.versionsToKeep < 1 {
+		defaultPolicy.versionsToKeep = 1
 	}
 
-	for batch := 0; batch < maxBatches; batch++ {
-		deleted := int64(0)
-
-		batchErr := inTransaction(func(sess *DBSession) error {
-			// Idea of this query is finding version IDs to delete based on formula:
-			// min_version_to_keep = min_version + (versions_count - versions_to_keep)
-			// where version stats is processed for each dashboard. This guarantees that we keep at least versions_to_keep
-			// versions, but in some cases (when versions are sparse) this number may be more.
-			versionIdsToDeleteQuery := `SELECT id
-				FROM dashboard_version, (
-					SELECT dashboard_id, count(version) as count, min(version) as min
-					FROM dashboard_version
-					GROUP BY dashboard_id
-				) AS vtd
-				WHERE dashboard_version.dashboard_id=vtd.dashboard_id
-				AND version < vtd.min + vtd.count - ?
-				LIMIT ?`
-
-			var versionIdsToDelete []interface{}
-			err := sess.SQL(versionIdsToDeleteQuery, versionsToKeep, perBatch).Find(&versionIdsToDelete)
-			if err != nil {
-				return err
-			}
+	sess := newSession()
+	defer sess.Close()
 
-			if len(versionIdsToDelete) < 1 {
-				return nil
-			}
+	policies, err := getVersionRetentionPolicies(sess)
+	if err != nil {
+		return err
+	}
 
-			deleteExpiredSQL := `DELETE FROM dashboard_version WHERE id IN (?` + strings.Repeat(",?", len(versionIdsToDelete)-1) + `)`
-			sqlOrArgs := append([]interface{}{deleteExpiredSQL}, versionIdsToDelete...)
-			expiredResponse, err := sess.Exec(sqlOrArgs...)
+	// only dashboards with more versions than any policy keeps can have expired versions
+	minVersionsToKeep := defaultPolicy.versionsToKeep
+	for _, policy := range policies {
+		if policy.versionsToKeep < minVersionsToKeep {
+			minVersionsToKeep = policy.versionsToKeep
+		}
+	}
+
+	// versions of deleted dashboards are kept by the policy of the folder the dashboard was in
+	var dashboards []*struct {
+		DashboardId int64
+		OrgId       int64
+		FolderId    int64
+	}
+	err = sess.SQL(`SELECT versions.dashboard_id,
+			COALESCE(dashboard.org_id, deleted_dashboard.org_id, 0) AS org_id,
+			COALESCE(dashboard.folder_id, deleted_dashboard.folder_id, 0) AS folder_id
+		FROM (
+			SELECT dashboard_id FROM dashboard_version GROUP BY dashboard_id HAVING COUNT(*) > ?
+		) AS versions
+		LEFT JOIN dashboard ON dashboard.id = versions.dashboard_id
+		LEFT JOIN deleted_dashboard ON deleted_dashboard.dashboard_id = versions.dashboard_id
+		ORDER BY versions.dashboard_id`, minVersionsToKeep).Find(&dashboards)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	expired := []interface{}{}
+	batches := 0
+	deleteBatch := func() error {
+		ids := expired
+		if len(ids) > perBatch {
+			ids = ids[:perBatch]
+		}
+		expired = expired[len(ids):]
+		batches++
+
+		return inTransaction(func(sess *DBSession) error {
+			deleteExpiredSQL := `DELETE FROM dashboard_version WHERE id IN (?` + strings.Repeat(",?", len(ids)-1) + `)`
+			expiredResponse, err := sess.Exec(append([]interface{}{deleteExpiredSQL}, ids...)...)
 			if err != nil {
 				return err
 			}
 
-			deleted, err = expiredResponse.RowsAffected()
+			deleted, err := expiredResponse.RowsAffected()
+			cmd.DeletedRows += deleted
 			return err
 		})
+	}
 
-		if batchErr != nil {
-			return batchErr
+	for _, dashboard := range dashboards {
+		policy, ok := policies[retentionKey{orgID: dashboard.OrgId, folderID: dashboard.FolderId}]
+		if !ok {
+			policy = defaultPolicy
 		}
 
-		cmd.DeletedRows += deleted
+		var versions []*retainedVersion
+		err := sess.Table("dashboard_version").Cols("id", "version", "created", "message").
+			Where("dashboard_id=?", dashboard.DashboardId).Desc("version").Find(&versions)
+		if err != nil {
+			return err
+		}
 
-		if deleted < int64(perBatch) {
-			break
+		var taggedVersions []int
+		err = sess.Table("dashboard_version_tag").Cols("version").Where("dashboard_id=?", dashboard.DashboardId).Find(&taggedVersions)
+		if err != nil {
+			return err
+		}
+		tagged := make(map[int]bool, len(taggedVersions))
+		for _, version := range taggedVersions {
+			tagged[version] = true
+		}
+
+		expired = append(expired, policy.expiredVersions(versions, tagged, now)...)
+		for len(expired) >= perBatch {
+			if err := deleteBatch(); err != nil {
+				return err
+			}
+			if batches >= maxBatches {
+				return nil
+			}
 		}
 	}
 
+	if len(expired) > 0 {
+		return deleteBatch()
+	}
 	return nil
 }
@@ -0,0 +1,138 @@
+package sqlstore
+
+import (
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", GetDashboardVersionRetention)
+	bus.AddHandler("sql", SetDashboardVersionRetention)
+	bus.AddHandler("sql", DeleteDashboardVersionRetention)
+}
+
+func GetDashboardVersionRetention(query *models.GetDashboardVersionRetentionQuery) error {
+	retention := models.DashboardVersionRetention{}
+	has, err := x.Where("org_id=? AND folder_id=?", query.OrgId, query.FolderId).Get(&retention)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return models.ErrDashboardVersionRetentionNotFound
+	}
+
+	query.Result = &retention
+	return nil
+}
+
+func SetDashboardVersionRetention(cmd *models.SetDashboardVersionRetentionCommand) error {
+	if cmd.VersionsToKeep < 1 {
+		return models.ErrDashboardVersionRetentionInvalid
+	}
+	if cmd.KeepYoungerThan != "" {
+		if _, err := gtime.ParseDuration(cmd.KeepYoungerThan); err != nil {
+			return models.ErrDashboardVersionRetentionBadPeriod
+		}
+	}
+
+	return inTransaction(func(sess *DBSession) error {
+		retention := models.DashboardVersionRetention{}
+		has, err := sess.Where("org_id=? AND folder_id=?", cmd.OrgId, cmd.FolderId).Get(&retention)
+		if err != nil {
+			return err
+		}
+
+		retention.OrgId = cmd.OrgId
+		retention.FolderId = cmd.FolderId
+		retention.VersionsToKeep = cmd.VersionsToKeep
+		retention.KeepYoungerThan = cmd.KeepYoungerThan
+		retention.KeepVersionsWithMessage = cmd.KeepVersionsWithMessage
+		retention.Updated = time.Now()
+
+		if has {
+			_, err = sess.ID(retention.Id).AllCols().Update(&retention)
+		} else {
+			retention.Created = retention.Updated
+			_, err = sess.Insert(&retention)
+		}
+		if err != nil {
+			return err
+		}
+
+		cmd.Result = &retention
+		return nil
+	})
+}
+
+func DeleteDashboardVersionRetention(cmd *models.DeleteDashboardVersionRetentionCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		_, err := sess.Exec("DELETE FROM dashboard_version_retention WHERE org_id=? AND folder_id=?", cmd.OrgId, cmd.FolderId)
+		return err
+	})
+}
+
+// versionRetention is a retention policy that is ready to be applied to the versions of a dashboard.
+type versionRetention struct {
+	versionsToKeep          int
+	keepYoungerThan         time.Duration
+	keepVersionsWithMessage bool
+}
+
+type retentionKey struct {
+	orgID    int64
+	folderID int64
+}
+
+// getVersionRetentionPolicies returns the retention policies of all folders. Policies with a period
+// that can't be parsed keep versions by their number only.
+func getVersionRetentionPolicies(sess *DBSession) (map[retentionKey]*versionRetention, error) {
+	var rows []*models.DashboardVersionRetention
+	if err := sess.Table("dashboard_version_retention").Find(&rows); err != nil {
+		return nil, err
+	}
+
+	policies := make(map[retentionKey]*versionRetention, len(rows))
+	for _, row := range rows {
+		policy := &versionRetention{
+			versionsToKeep:          row.VersionsToKeep,
+			keepVersionsWithMessage: row.KeepVersionsWithMessage,
+		}
+		if row.KeepYoungerThan != "" {
+			if period, err := gtime.ParseDuration(row.KeepYoungerThan); err == nil {
+				policy.keepYoungerThan = period
+			}
+		}
+		if policy.versionsToKeep < 1 {
+			policy.versionsToKeep = 1
+		}
+		policies[retentionKey{orgID: row.OrgId, folderID: row.FolderId}] = policy
+	}
+	return policies, nil
+}
+
+type retainedVersion struct {
+	Id      int64
+	Version int
+	Created time.Time
+	Message string
+}
+
+// expiredVersions returns the ids of the versions of a dashboard that the policy doesn't keep. The
+// versions must be ordered from the most recent.
+func (p *versionRetention) expiredVersions(versions []*retainedVersion, tagged map[int]bool, now time.Time) []interface{} {
+	expired := []interface{}{}
+	for i, version := range versions {
+		switch {
+		case i < p.versionsToKeep:
+		case tagged[version.Version]:
+		case p.keepVersionsWithMessage && version.Message != "":
+		case p.keepYoungerThan > 0 && version.Created.After(now.Add(-p.keepYoungerThan)):
+		default:
+			expired = append(expired, version.Id)
+		}
+	}
+	return expired
+}
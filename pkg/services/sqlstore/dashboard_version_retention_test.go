@@ -0,0 +1,235 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+func TestDashboardVersionRetention(t *testing.T) {
+	saveVersion := func(t *testing.T, dash *models.Dashboard, message string) {
+		t.Helper()
+		cmd := models.SaveDashboardCommand{
+			OrgId:     dash.OrgId,
+			FolderId:  dash.FolderId,
+			Overwrite: true,
+			Message:   message,
+			Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"id":    dash.Id,
+				"uid":   dash.Uid,
+				"title": dash.Title,
+			}),
+		}
+		require.NoError(t, SaveDashboard(&cmd))
+	}
+
+	getVersions := func(t *testing.T, dash *models.Dashboard) []*models.DashboardVersionDTO {
+		t.Helper()
+		query := models.GetDashboardVersionsQuery{DashboardId: dash.Id, OrgId: dash.OrgId}
+		require.NoError(t, GetDashboardVersions(&query))
+		return query.Result
+	}
+
+	versionNumbers := func(versions []*models.DashboardVersionDTO) []int {
+		numbers := []int{}
+		for _, version := range versions {
+			numbers = append(numbers, version.Version)
+		}
+		return numbers
+	}
+
+	t.Run("Folders with a policy keep versions by number, message and tag", func(t *testing.T) {
+		InitTestDB(t)
+		setting.DashboardVersionsToKeep = 2
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false)
+		other := insertTestDashboard(t, "other", 1, 0, false)
+		for i := 2; i <= 8; i++ {
+			message := ""
+			if i == 3 {
+				message = "important change"
+			}
+			saveVersion(t, dash, message)
+			saveVersion(t, other, message)
+		}
+
+		require.NoError(t, SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{
+			OrgId: 1, FolderId: folder.Id, VersionsToKeep: 3, KeepVersionsWithMessage: true,
+		}))
+		require.NoError(t, AddDashboardVersionTag(&models.AddDashboardVersionTagCommand{
+			OrgId: 1, DashboardId: dash.Id, Version: 2, Tag: "release-2026-10",
+		}))
+
+		cmd := models.DeleteExpiredVersionsCommand{}
+		require.NoError(t, DeleteExpiredVersions(&cmd))
+		assert.Equal(t, int64(9), cmd.DeletedRows)
+
+		versions := getVersions(t, dash)
+		assert.Equal(t, []int{8, 7, 6, 3, 2}, versionNumbers(versions))
+		assert.Equal(t, []string{"release-2026-10"}, versions[4].Tags)
+		assert.Equal(t, []string{}, versions[0].Tags)
+
+		// dashboards in folders without a policy keep versions_to_keep versions
+		assert.Equal(t, []int{8, 7}, versionNumbers(getVersions(t, other)))
+	})
+
+	t.Run("Folders with a policy keep versions younger than the period", func(t *testing.T) {
+		InitTestDB(t)
+		setting.DashboardVersionsToKeep = 1
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false)
+		for i := 0; i < 3; i++ {
+			saveVersion(t, dash, "")
+		}
+		_, err := x.Exec("UPDATE dashboard_version SET created=? WHERE dashboard_id=? AND version < 3", time.Now().Add(-48*time.Hour), dash.Id)
+		require.NoError(t, err)
+
+		require.NoError(t, SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{
+			OrgId: 1, FolderId: folder.Id, VersionsToKeep: 1, KeepYoungerThan: "1d",
+		}))
+
+		require.NoError(t, DeleteExpiredVersions(&models.DeleteExpiredVersionsCommand{}))
+		assert.Equal(t, []int{4, 3}, versionNumbers(getVersions(t, dash)))
+	})
+
+	t.Run("Invalid policies are rejected", func(t *testing.T) {
+		InitTestDB(t)
+
+		err := SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{OrgId: 1, FolderId: 1})
+		assert.Equal(t, models.ErrDashboardVersionRetentionInvalid, err)
+
+		err = SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{
+			OrgId: 1, FolderId: 1, VersionsToKeep: 1, KeepYoungerThan: "a while",
+		})
+		assert.Equal(t, models.ErrDashboardVersionRetentionBadPeriod, err)
+	})
+
+	t.Run("Policies can be updated and deleted", func(t *testing.T) {
+		InitTestDB(t)
+
+		for _, versionsToKeep := range []int{5, 10} {
+			require.NoError(t, SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{
+				OrgId: 1, FolderId: 1, VersionsToKeep: versionsToKeep,
+			}))
+		}
+
+		query := models.GetDashboardVersionRetentionQuery{OrgId: 1, FolderId: 1}
+		require.NoError(t, GetDashboardVersionRetention(&query))
+		assert.Equal(t, 10, query.Result.VersionsToKeep)
+
+		require.NoError(t, DeleteDashboardVersionRetention(&models.DeleteDashboardVersionRetentionCommand{OrgId: 1, FolderId: 1}))
+		assert.Equal(t, models.ErrDashboardVersionRetentionNotFound, GetDashboardVersionRetention(&query))
+	})
+
+	t.Run("Tags are unique per dashboard", func(t *testing.T) {
+		InitTestDB(t)
+
+		dash := insertTestDashboard(t, "dash", 1, 0, false)
+		saveVersion(t, dash, "")
+
+		for _, version := range []int{1, 2} {
+			require.NoError(t, AddDashboardVersionTag(&models.AddDashboardVersionTagCommand{
+				OrgId: 1, DashboardId: dash.Id, Version: version, Tag: "stable",
+			}))
+		}
+
+		query := models.GetDashboardVersionTagsQuery{OrgId: 1, DashboardId: dash.Id}
+		require.NoError(t, GetDashboardVersionTags(&query))
+		assert.Equal(t, map[int][]string{2: {"stable"}}, query.Result)
+
+		err := AddDashboardVersionTag(&models.AddDashboardVersionTagCommand{OrgId: 1, DashboardId: dash.Id, Version: 3, Tag: "stable"})
+		assert.Equal(t, models.ErrDashboardVersionNotFound, err)
+
+		// the versions of other orgs can't be tagged
+		err = AddDashboardVersionTag(&models.AddDashboardVersionTagCommand{OrgId: 2, DashboardId: dash.Id, Version: 1, Tag: "stable"})
+		assert.Equal(t, models.ErrDashboardVersionNotFound, err)
+		require.NoError(t, GetDashboardVersionTags(&query))
+		assert.Equal(t, map[int][]string{2: {"stable"}}, query.Result)
+
+		require.NoError(t, DeleteDashboardVersionTag(&models.DeleteDashboardVersionTagCommand{OrgId: 1, DashboardId: dash.Id, Version: 2, Tag: "stable"}))
+		err = DeleteDashboardVersionTag(&models.DeleteDashboardVersionTagCommand{OrgId: 1, DashboardId: dash.Id, Version: 2, Tag: "stable"})
+		assert.Equal(t, models.ErrDashboardVersionTagNotFound, err)
+	})
+}
+
+func TestRestoreDeletedDashboard(t *testing.T) {
+	t.Run("Deleted dashboards are restored from their versions", func(t *testing.T) {
+		InitTestDB(t)
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false, "tag")
+		updateCmd := models.SaveDashboardCommand{
+			OrgId:    1,
+			FolderId: folder.Id,
+			Dashboard: simplejson.NewFromAny(map[string]interface{}{
+				"id": dash.Id, "uid": dash.Uid, "title": "dash", "version": dash.Version, "tags": []interface{}{"tag"},
+			}),
+		}
+		require.NoError(t, SaveDashboard(&updateCmd))
+
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: folder.Id, OrgId: 1}))
+
+		deletedQuery := models.GetDeletedDashboardQuery{OrgId: 1, DashboardId: dash.Id}
+		require.NoError(t, GetDeletedDashboard(&deletedQuery))
+		assert.Equal(t, dash.Uid, deletedQuery.Result.Uid)
+		assert.Equal(t, folder.Id, deletedQuery.Result.FolderId)
+
+		// the folder doesn't exist anymore
+		err := RestoreDeletedDashboard(&models.RestoreDeletedDashboardCommand{OrgId: 1, DashboardId: dash.Id, FolderId: folder.Id})
+		assert.Equal(t, models.ErrFolderNotFound, err)
+
+		err = RestoreDeletedDashboard(&models.RestoreDeletedDashboardCommand{OrgId: 2, DashboardId: dash.Id})
+		assert.Equal(t, models.ErrDeletedDashboardNotFound, err)
+
+		restoreCmd := models.RestoreDeletedDashboardCommand{OrgId: 1, DashboardId: dash.Id, Version: 1}
+		require.NoError(t, RestoreDeletedDashboard(&restoreCmd))
+		assert.Equal(t, dash.Id, restoreCmd.Result.Id)
+		assert.Equal(t, dash.Uid, restoreCmd.Result.Uid)
+		assert.Equal(t, 3, restoreCmd.Result.Version)
+
+		dashQuery := models.GetDashboardQuery{OrgId: 1, Uid: dash.Uid}
+		require.NoError(t, GetDashboard(&dashQuery))
+		assert.Equal(t, int64(0), dashQuery.Result.FolderId)
+		assert.Equal(t, 3, dashQuery.Result.Version)
+
+		versionsQuery := models.GetDashboardVersionsQuery{DashboardId: dash.Id, OrgId: 1}
+		require.NoError(t, GetDashboardVersions(&versionsQuery))
+		require.Len(t, versionsQuery.Result, 3)
+		assert.Equal(t, 1, versionsQuery.Result[0].RestoredFrom)
+
+		tagsQuery := models.GetDashboardTagsQuery{OrgId: 1}
+		require.NoError(t, GetDashboardTags(&tagsQuery))
+		require.Len(t, tagsQuery.Result, 1)
+
+		err = GetDeletedDashboard(&deletedQuery)
+		assert.Equal(t, models.ErrDeletedDashboardNotFound, err)
+	})
+
+	t.Run("Restored dashboards get a new uid if it's taken", func(t *testing.T) {
+		InitTestDB(t)
+
+		dash := insertTestDashboard(t, "dash", 1, 0, false)
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: dash.Id, OrgId: 1}))
+
+		other := models.SaveDashboardCommand{
+			OrgId:     1,
+			Dashboard: simplejson.NewFromAny(map[string]interface{}{"uid": dash.Uid, "title": "other"}),
+		}
+		require.NoError(t, SaveDashboard(&other))
+
+		restoreCmd := models.RestoreDeletedDashboardCommand{OrgId: 1, DashboardId: dash.Id}
+		require.NoError(t, RestoreDeletedDashboard(&restoreCmd))
+		assert.NotEqual(t, dash.Uid, restoreCmd.Result.Uid)
+		assert.Equal(t, "dash", restoreCmd.Result.Title)
+	})
+}
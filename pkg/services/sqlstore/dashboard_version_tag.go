@@ -0,0 +1,81 @@
+package sqlstore
+
+import (
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", GetDashboardVersionTags)
+	bus.AddHandler("sql", AddDashboardVersionTag)
+	bus.AddHandler("sql", DeleteDashboardVersionTag)
+}
+
+func GetDashboardVersionTags(query *models.GetDashboardVersionTagsQuery) error {
+	var tags []*DashboardVersionTag
+	if err := x.Where("org_id=? AND dashboard_id=?", query.OrgId, query.DashboardId).Asc("tag").Find(&tags); err != nil {
+		return err
+	}
+
+	query.Result = map[int][]string{}
+	for _, tag := range tags {
+		query.Result[tag.Version] = append(query.Result[tag.Version], tag.Tag)
+	}
+	return nil
+}
+
+func AddDashboardVersionTag(cmd *models.AddDashboardVersionTagCommand) error {
+	tag := strings.TrimSpace(cmd.Tag)
+	if tag == "" || len(tag) > 255 {
+		return models.ErrDashboardVersionTagInvalid
+	}
+
+	return inTransaction(func(sess *DBSession) error {
+		// dashboard versions don't have an org, so the org is checked on their dashboard
+		exists, err := sess.Table("dashboard_version").
+			Join("INNER", "dashboard", "dashboard.id = dashboard_version.dashboard_id").
+			Where("dashboard.org_id=? AND dashboard_version.dashboard_id=? AND dashboard_version.version=?",
+				cmd.OrgId, cmd.DashboardId, cmd.Version).
+			Exist()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrDashboardVersionNotFound
+		}
+
+		if _, err := sess.Exec("DELETE FROM dashboard_version_tag WHERE org_id=? AND dashboard_id=? AND tag=?",
+			cmd.OrgId, cmd.DashboardId, tag); err != nil {
+			return err
+		}
+
+		_, err = sess.Insert(&DashboardVersionTag{
+			OrgId:       cmd.OrgId,
+			DashboardId: cmd.DashboardId,
+			Version:     cmd.Version,
+			Tag:         tag,
+			Created:     time.Now(),
+		})
+		return err
+	})
+}
+
+func DeleteDashboardVersionTag(cmd *models.DeleteDashboardVersionTagCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		res, err := sess.Exec("DELETE FROM dashboard_version_tag WHERE org_id=? AND dashboard_id=? AND version=? AND tag=?",
+			cmd.OrgId, cmd.DashboardId, cmd.Version, cmd.Tag)
+		if err != nil {
+			return err
+		}
+
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return models.ErrDashboardVersionTagNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,327 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func init() {
+	bus.AddHandler("sql", GetDeletedDashboards)
+	bus.AddHandler("sql", GetDeletedDashboard)
+	bus.AddHandler("sql", GetRestoredDashboards)
+	bus.AddHandler("sql", RestoreDeletedDashboard)
+	bus.AddHandler("sql", PurgeDeletedDashboard)
+	bus.AddHandler("sql", PurgeDeletedDashboards)
+}
+
+// GetDeletedDashboards returns the dashboards and folders in the trash of an org that can be restored,
+// most recently deleted first.
+func GetDeletedDashboards(query *models.GetDeletedDashboardsQuery) error {
+	query.Result = make([]*models.DeletedDashboard, 0)
+	return x.Where("org_id=?", query.OrgId).
+		And("(data IS NOT NULL OR EXISTS (SELECT 1 FROM dashboard_version WHERE dashboard_version.dashboard_id = deleted_dashboard.dashboard_id))").
+		Omit("data").Desc("deleted").Asc("title").Find(&query.Result)
+}
+
+func GetDeletedDashboard(query *models.GetDeletedDashboardQuery) error {
+	deleted := models.DeletedDashboard{}
+	has, err := x.Where("org_id=? AND dashboard_id=?", query.OrgId, query.DashboardId).Get(&deleted)
+	if err != nil {
+		return err
+	}
+	if !has {
+		return models.ErrDeletedDashboardNotFound
+	}
+
+	query.Result = &deleted
+	return nil
+}
+
+// GetRestoredDashboards returns the dashboards that RestoreDeletedDashboard would restore, without
+// restoring them.
+func GetRestoredDashboards(query *models.GetRestoredDashboardsQuery) error {
+	return withDbSession(context.Background(), func(sess *DBSession) error {
+		restored, err := getRestoredDashboards(sess, query.OrgId, query.DashboardId, query.Version, query.FolderId)
+		if err != nil {
+			return err
+		}
+
+		query.Result = make([]*models.Dashboard, 0, len(restored))
+		for _, r := range restored {
+			query.Result = append(query.Result, r.dash)
+		}
+		return nil
+	})
+}
+
+func RestoreDeletedDashboard(cmd *models.RestoreDeletedDashboardCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		restored, err := getRestoredDashboards(sess, cmd.OrgId, cmd.DashboardId, cmd.Version, cmd.FolderId)
+		if err != nil {
+			return err
+		}
+
+		cmd.Children = nil
+		for i, r := range restored {
+			// the dashboard keeps its id, so its versions, permissions, stars, annotations and alerts
+			// belong to it again
+			saveCmd := models.SaveDashboardCommand{
+				Dashboard:    r.dash.Data,
+				UserId:       cmd.UserId,
+				Message:      r.message,
+				OrgId:        r.dash.OrgId,
+				RestoredFrom: r.restoredFrom,
+				FolderId:     r.dash.FolderId,
+				IsFolder:     r.dash.IsFolder,
+				Restored:     true,
+			}
+			if err := saveDashboard(sess, &saveCmd); err != nil {
+				return err
+			}
+
+			if _, err := sess.Exec("DELETE FROM deleted_dashboard WHERE dashboard_id=?", r.deleted.DashboardId); err != nil {
+				return err
+			}
+
+			if i == 0 {
+				cmd.Result = saveCmd.Result
+			} else {
+				cmd.Children = append(cmd.Children, saveCmd.Result)
+			}
+		}
+		return nil
+	})
+}
+
+// restoredDashboard is a deleted dashboard as it is restored.
+type restoredDashboard struct {
+	deleted      *models.DeletedDashboard
+	dash         *models.Dashboard
+	restoredFrom int
+	message      string
+}
+
+// getRestoredDashboards returns a deleted dashboard or folder as it is restored into a folder, followed
+// by the dashboards that were deleted with the folder.
+func getRestoredDashboards(sess *DBSession, orgID int64, dashboardID int64, version int, folderID int64) ([]*restoredDashboard, error) {
+	deleted := models.DeletedDashboard{}
+	has, err := sess.Where("org_id=? AND dashboard_id=?", orgID, dashboardID).Get(&deleted)
+	if err != nil {
+		return nil, err
+	}
+	if !has {
+		return nil, models.ErrDeletedDashboardNotFound
+	}
+
+	if deleted.IsFolder {
+		folderID = 0
+	}
+	if folderID > 0 {
+		exists, err := sess.Table("dashboard").Where("org_id=? AND id=? AND is_folder="+dialect.BooleanStr(true), orgID, folderID).Exist()
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, models.ErrFolderNotFound
+		}
+	}
+
+	r, err := getRestoredDashboard(sess, &deleted, version, folderID)
+	if err != nil {
+		return nil, err
+	}
+	restored := []*restoredDashboard{r}
+
+	if !deleted.IsFolder {
+		return restored, nil
+	}
+
+	var children []*models.DeletedDashboard
+	if err := sess.Where("org_id=? AND deleted_with=?", orgID, deleted.DashboardId).Asc("title").Find(&children); err != nil {
+		return nil, err
+	}
+	for _, child := range children {
+		r, err := getRestoredDashboard(sess, child, 0, deleted.DashboardId)
+		if err != nil {
+			return nil, err
+		}
+		restored = append(restored, r)
+	}
+	return restored, nil
+}
+
+// getRestoredDashboard returns a deleted dashboard as it is restored into a folder, from one of its versions
+// if version is set or else from its state when it was deleted.
+func getRestoredDashboard(sess *DBSession, deleted *models.DeletedDashboard, version int, folderID int64) (*restoredDashboard, error) {
+	var latest models.DashboardVersion
+	hasVersions, err := sess.Where("dashboard_id=?", deleted.DashboardId).Desc("version").Get(&latest)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &restoredDashboard{deleted: deleted, message: "Restored deleted dashboard"}
+	data := deleted.Data
+	if version > 0 || data == nil {
+		if !hasVersions {
+			return nil, models.ErrDashboardVersionNotFound
+		}
+
+		restored := latest
+		if version > 0 && version != latest.Version {
+			restored = models.DashboardVersion{}
+			if has, err := sess.Where("dashboard_id=? AND version=?", deleted.DashboardId, version).Get(&restored); err != nil {
+				return nil, err
+			} else if !has {
+				return nil, models.ErrDashboardVersionNotFound
+			}
+		}
+		data = restored.Data
+		r.restoredFrom = restored.Version
+		r.message = fmt.Sprintf("Restored deleted dashboard from version %d", restored.Version)
+	}
+
+	dash := models.NewDashboardFromJson(data)
+	dash.SetId(deleted.DashboardId)
+	dash.OrgId = deleted.OrgId
+	dash.FolderId = folderID
+	dash.IsFolder = deleted.IsFolder
+
+	exists, err := sess.Table("dashboard").Where("org_id=? AND folder_id=? AND title=?", deleted.OrgId, folderID, dash.Title).Exist()
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if deleted.IsFolder {
+			return nil, models.ErrFolderSameNameExists
+		}
+		return nil, models.ErrDashboardWithSameNameInFolderExists
+	}
+
+	// another dashboard might have been created with the uid of the deleted dashboard
+	uid := deleted.Uid
+	if exists, err := sess.Table("dashboard").Where("org_id=? AND uid=?", deleted.OrgId, uid).Exist(); err != nil {
+		return nil, err
+	} else if exists || uid == "" {
+		if uid, err = generateNewDashboardUid(sess, deleted.OrgId); err != nil {
+			return nil, err
+		}
+	}
+	dash.SetUid(uid)
+
+	// the restored dashboard is saved as the next version
+	if hasVersions {
+		dash.SetVersion(latest.Version)
+	}
+
+	r.dash = dash
+	return r, nil
+}
+
+func PurgeDeletedDashboard(cmd *models.PurgeDeletedDashboardCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		deleted := models.DeletedDashboard{}
+		has, err := sess.Where("org_id=? AND dashboard_id=?", cmd.OrgId, cmd.DashboardId).Omit("data").Get(&deleted)
+		if err != nil {
+			return err
+		}
+		if !has {
+			return models.ErrDeletedDashboardNotFound
+		}
+
+		_, err = purgeDeletedDashboards(sess, []*models.DeletedDashboard{&deleted})
+		return err
+	})
+}
+
+func PurgeDeletedDashboards(cmd *models.PurgeDeletedDashboardsCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		var deleted []*models.DeletedDashboard
+		if err := sess.Where("deleted < ?", cmd.OlderThan).Omit("data").Find(&deleted); err != nil {
+			return err
+		}
+
+		purged, err := purgeDeletedDashboards(sess, deleted)
+		cmd.PurgedRows = purged
+		return err
+	})
+}
+
+// purgeDeletedDashboards permanently deletes dashboards from the trash along with the dashboards that
+// were deleted with folders, and returns the number of purged dashboards. All versions are deleted, also
+// those that the retention policy of the folder keeps.
+func purgeDeletedDashboards(sess *DBSession, deleted []*models.DeletedDashboard) (int64, error) {
+	deletes := []string{
+		"DELETE FROM dashboard_version WHERE dashboard_id = ?",
+		"DELETE FROM dashboard_version_tag WHERE dashboard_id = ?",
+		"DELETE FROM dashboard_acl WHERE dashboard_id = ?",
+		"DELETE FROM star WHERE dashboard_id = ?",
+		"DELETE FROM annotation WHERE dashboard_id = ?",
+	}
+	childrenDeletes := []string{
+		"DELETE FROM dashboard_version WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE deleted_with = ?)",
+		"DELETE FROM dashboard_version_tag WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE deleted_with = ?)",
+		"DELETE FROM dashboard_acl WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE deleted_with = ?)",
+		"DELETE FROM star WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE deleted_with = ?)",
+		"DELETE FROM annotation WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE deleted_with = ?)",
+	}
+
+	var purged int64
+	for _, dashboard := range deleted {
+		if dashboard.IsFolder {
+			var children []*models.DeletedDashboard
+			if err := sess.Where("deleted_with = ?", dashboard.DashboardId).Omit("data").Find(&children); err != nil {
+				return purged, err
+			}
+			for _, child := range children {
+				if err := deleteAlertDefinition(child.DashboardId, sess); err != nil {
+					return purged, err
+				}
+			}
+
+			for _, sql := range childrenDeletes {
+				if _, err := sess.Exec(sql, dashboard.DashboardId); err != nil {
+					return purged, err
+				}
+			}
+
+			res, err := sess.Exec("DELETE FROM deleted_dashboard WHERE deleted_with = ?", dashboard.DashboardId)
+			if err != nil {
+				return purged, err
+			}
+			purgedChildren, err := res.RowsAffected()
+			if err != nil {
+				return purged, err
+			}
+			purged += purgedChildren
+
+			if _, err := sess.Exec("DELETE FROM dashboard_version_retention WHERE org_id = ? AND folder_id = ?", dashboard.OrgId, dashboard.DashboardId); err != nil {
+				return purged, err
+			}
+		}
+
+		if err := deleteAlertDefinition(dashboard.DashboardId, sess); err != nil {
+			return purged, err
+		}
+
+		for _, sql := range deletes {
+			if _, err := sess.Exec(sql, dashboard.DashboardId); err != nil {
+				return purged, err
+			}
+		}
+
+		res, err := sess.Exec("DELETE FROM deleted_dashboard WHERE dashboard_id = ?", dashboard.DashboardId)
+		if err != nil {
+			return purged, err
+		}
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += rows
+	}
+
+	return purged, nil
+}
@@ -0,0 +1,259 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/services/annotations"
+)
+
+func TestDashboardTrash(t *testing.T) {
+	t.Run("Deleted folders are restored with their dashboards and permissions", func(t *testing.T) {
+		InitTestDB(t)
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false, "tag")
+		deletedEarlier := insertTestDashboard(t, "deleted earlier", 1, folder.Id, false)
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: deletedEarlier.Id, OrgId: 1}))
+
+		require.NoError(t, testHelperUpdateDashboardAcl(folder.Id, models.DashboardAcl{
+			OrgID: 1, DashboardID: folder.Id, UserID: 10, Permission: models.PERMISSION_EDIT,
+		}))
+		require.NoError(t, StarDashboard(&models.StarDashboardCommand{UserId: 10, DashboardId: dash.Id}))
+
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: folder.Id, OrgId: 1}))
+
+		deletedQuery := models.GetDeletedDashboardsQuery{OrgId: 1}
+		require.NoError(t, GetDeletedDashboards(&deletedQuery))
+		require.Len(t, deletedQuery.Result, 3)
+		deletedWith := map[string]int64{}
+		for _, deleted := range deletedQuery.Result {
+			deletedWith[deleted.Title] = deleted.DeletedWith
+		}
+		assert.Equal(t, map[string]int64{"folder": 0, "dash": folder.Id, "deleted earlier": 0}, deletedWith)
+
+		err := GetDashboard(&models.GetDashboardQuery{OrgId: 1, Uid: dash.Uid})
+		assert.Equal(t, models.ErrDashboardNotFound, err)
+
+		restoreCmd := models.RestoreDeletedDashboardCommand{OrgId: 1, DashboardId: folder.Id}
+		require.NoError(t, RestoreDeletedDashboard(&restoreCmd))
+		assert.True(t, restoreCmd.Result.IsFolder)
+		assert.Equal(t, folder.Uid, restoreCmd.Result.Uid)
+		require.Len(t, restoreCmd.Children, 1)
+		assert.Equal(t, dash.Id, restoreCmd.Children[0].Id)
+
+		dashQuery := models.GetDashboardQuery{OrgId: 1, Uid: dash.Uid}
+		require.NoError(t, GetDashboard(&dashQuery))
+		assert.Equal(t, folder.Id, dashQuery.Result.FolderId)
+
+		aclQuery := models.GetDashboardAclInfoListQuery{OrgID: 1, DashboardID: dash.Id}
+		require.NoError(t, GetDashboardAclInfoList(&aclQuery))
+		userIDs := []int64{}
+		for _, item := range aclQuery.Result {
+			userIDs = append(userIDs, item.UserId)
+		}
+		assert.Contains(t, userIDs, int64(10))
+
+		starQuery := models.IsStarredByUserQuery{UserId: 10, DashboardId: dash.Id}
+		require.NoError(t, IsStarredByUser(&starQuery))
+		assert.True(t, starQuery.Result)
+
+		// dashboards that were deleted on their own stay in the trash
+		deletedQuery = models.GetDeletedDashboardsQuery{OrgId: 1}
+		require.NoError(t, GetDeletedDashboards(&deletedQuery))
+		require.Len(t, deletedQuery.Result, 1)
+		assert.Equal(t, deletedEarlier.Id, deletedQuery.Result[0].DashboardId)
+	})
+
+	t.Run("Annotations and alerts of deleted dashboards are kept until they're purged", func(t *testing.T) {
+		InitTestDB(t)
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false)
+		alert, err := insertTestAlert("alert", "message", 1, dash.Id, simplejson.New())
+		require.NoError(t, err)
+		require.NoError(t, (&SQLAnnotationRepo{}).Save(&annotations.Item{OrgId: 1, DashboardId: dash.Id, Text: "annotation"}))
+
+		countRows := func(table string) int64 {
+			count, err := x.Table(table).Where("dashboard_id=?", dash.Id).Count()
+			require.NoError(t, err)
+			return count
+		}
+
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: folder.Id, OrgId: 1}))
+		assert.Equal(t, int64(1), countRows("annotation"))
+		assert.Equal(t, int64(1), countRows("alert"))
+
+		// the alerts of deleted dashboards don't run
+		alertsQuery := models.GetAllAlertsQuery{}
+		require.NoError(t, GetAllAlertQueryHandler(&alertsQuery))
+		assert.Empty(t, alertsQuery.Result)
+
+		restoreCmd := models.RestoreDeletedDashboardCommand{OrgId: 1, DashboardId: folder.Id}
+		require.NoError(t, RestoreDeletedDashboard(&restoreCmd))
+		alertsQuery = models.GetAllAlertsQuery{}
+		require.NoError(t, GetAllAlertQueryHandler(&alertsQuery))
+		require.Len(t, alertsQuery.Result, 1)
+		assert.Equal(t, alert.Id, alertsQuery.Result[0].Id)
+
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: folder.Id, OrgId: 1}))
+		require.NoError(t, PurgeDeletedDashboard(&models.PurgeDeletedDashboardCommand{OrgId: 1, DashboardId: folder.Id}))
+		assert.Zero(t, countRows("annotation"))
+		assert.Zero(t, countRows("alert"))
+	})
+
+	t.Run("Restored dashboards are saved as a new version", func(t *testing.T) {
+		InitTestDB(t)
+
+		dash := insertTestDashboard(t, "dash", 1, 0, false)
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: dash.Id, OrgId: 1}))
+
+		query := models.GetRestoredDashboardsQuery{OrgId: 1, DashboardId: dash.Id}
+		require.NoError(t, GetRestoredDashboards(&query))
+		require.Len(t, query.Result, 1)
+		assert.Equal(t, dash.Id, query.Result[0].Id)
+
+		// looking up the restored dashboards doesn't restore them
+		err := GetDashboard(&models.GetDashboardQuery{OrgId: 1, Uid: dash.Uid})
+		assert.Equal(t, models.ErrDashboardNotFound, err)
+
+		restoreCmd := models.RestoreDeletedDashboardCommand{OrgId: 1, DashboardId: dash.Id, UserId: 10}
+		require.NoError(t, RestoreDeletedDashboard(&restoreCmd))
+		assert.Equal(t, dash.Id, restoreCmd.Result.Id)
+		assert.Equal(t, dash.Version+1, restoreCmd.Result.Version)
+
+		versionQuery := models.GetDashboardVersionQuery{OrgId: 1, DashboardId: dash.Id, Version: restoreCmd.Result.Version}
+		require.NoError(t, GetDashboardVersion(&versionQuery))
+		assert.Equal(t, "Restored deleted dashboard", versionQuery.Result.Message)
+		assert.Equal(t, int64(10), versionQuery.Result.CreatedBy)
+	})
+
+	t.Run("Purged folders are deleted with their dashboards, versions and permissions", func(t *testing.T) {
+		InitTestDB(t)
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false)
+		require.NoError(t, testHelperUpdateDashboardAcl(dash.Id, models.DashboardAcl{
+			OrgID: 1, DashboardID: dash.Id, UserID: 10, Permission: models.PERMISSION_EDIT,
+		}))
+		require.NoError(t, SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{
+			OrgId: 1, FolderId: folder.Id, VersionsToKeep: 5,
+		}))
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: folder.Id, OrgId: 1}))
+
+		err := PurgeDeletedDashboard(&models.PurgeDeletedDashboardCommand{OrgId: 2, DashboardId: folder.Id})
+		assert.Equal(t, models.ErrDeletedDashboardNotFound, err)
+
+		require.NoError(t, PurgeDeletedDashboard(&models.PurgeDeletedDashboardCommand{OrgId: 1, DashboardId: folder.Id}))
+
+		deletedQuery := models.GetDeletedDashboardsQuery{OrgId: 1}
+		require.NoError(t, GetDeletedDashboards(&deletedQuery))
+		assert.Empty(t, deletedQuery.Result)
+
+		for _, table := range []string{"dashboard_version", "dashboard_acl"} {
+			count, err := x.Table(table).Where("dashboard_id IN (?, ?)", folder.Id, dash.Id).Count()
+			require.NoError(t, err)
+			assert.Zero(t, count, table)
+		}
+
+		err = GetDashboardVersionRetention(&models.GetDashboardVersionRetentionQuery{OrgId: 1, FolderId: folder.Id})
+		assert.Equal(t, models.ErrDashboardVersionRetentionNotFound, err)
+	})
+
+	t.Run("Dashboards deleted before the trash period are purged", func(t *testing.T) {
+		InitTestDB(t)
+
+		old := insertTestDashboard(t, "old", 1, 0, false)
+		recent := insertTestDashboard(t, "recent", 1, 0, false)
+		for _, dash := range []*models.Dashboard{old, recent} {
+			require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: dash.Id, OrgId: 1}))
+		}
+		_, err := x.Exec("UPDATE deleted_dashboard SET deleted=? WHERE dashboard_id=?", time.Now().Add(-31*24*time.Hour), old.Id)
+		require.NoError(t, err)
+
+		cmd := models.PurgeDeletedDashboardsCommand{OlderThan: time.Now().Add(-30 * 24 * time.Hour)}
+		require.NoError(t, PurgeDeletedDashboards(&cmd))
+		assert.Equal(t, int64(1), cmd.PurgedRows)
+
+		deletedQuery := models.GetDeletedDashboardsQuery{OrgId: 1}
+		require.NoError(t, GetDeletedDashboards(&deletedQuery))
+		require.Len(t, deletedQuery.Result, 1)
+		assert.Equal(t, recent.Id, deletedQuery.Result[0].DashboardId)
+	})
+
+	t.Run("New dashboards don't get the ids of dashboards in the trash", func(t *testing.T) {
+		InitTestDB(t)
+
+		deleted := insertTestDashboard(t, "deleted", 1, 0, false)
+		require.NoError(t, testHelperUpdateDashboardAcl(deleted.Id, models.DashboardAcl{
+			OrgID: 1, DashboardID: deleted.Id, UserID: 10, Permission: models.PERMISSION_EDIT,
+		}))
+		_, err := insertTestAlert("alert", "message", 1, deleted.Id, simplejson.New())
+		require.NoError(t, err)
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: deleted.Id, OrgId: 1}))
+
+		// MySQL hands out the ids of deleted dashboards again after a restart, which is the same as the
+		// trash keeping the next id
+		reused := deleted.Id + 1
+		for _, table := range []string{"deleted_dashboard", "dashboard_version", "dashboard_acl", "alert"} {
+			_, err := x.Exec("UPDATE "+table+" SET dashboard_id=? WHERE dashboard_id=?", reused, deleted.Id)
+			require.NoError(t, err)
+		}
+
+		dash := insertTestDashboard(t, "dash", 1, 0, false)
+		assert.NotEqual(t, reused, dash.Id)
+
+		aclQuery := models.GetDashboardAclInfoListQuery{OrgID: 1, DashboardID: dash.Id}
+		require.NoError(t, GetDashboardAclInfoList(&aclQuery))
+		for _, item := range aclQuery.Result {
+			assert.NotEqual(t, int64(10), item.UserId)
+		}
+
+		versionsQuery := models.GetDashboardVersionsQuery{OrgId: 1, DashboardId: dash.Id}
+		require.NoError(t, GetDashboardVersions(&versionsQuery))
+		assert.Len(t, versionsQuery.Result, 1)
+
+		_, err = insertTestAlert("new alert", "message", 1, dash.Id, simplejson.New())
+		require.NoError(t, err)
+		alertsQuery := models.GetAllAlertsQuery{}
+		require.NoError(t, GetAllAlertQueryHandler(&alertsQuery))
+		require.Len(t, alertsQuery.Result, 1)
+		assert.Equal(t, dash.Id, alertsQuery.Result[0].DashboardId)
+
+		require.NoError(t, GetDeletedDashboard(&models.GetDeletedDashboardQuery{OrgId: 1, DashboardId: reused}))
+	})
+
+	t.Run("Purged dashboards are deleted with the versions that the retention policy of the folder keeps", func(t *testing.T) {
+		InitTestDB(t)
+
+		folder := insertTestDashboard(t, "folder", 1, 0, true)
+		require.NoError(t, SetDashboardVersionRetention(&models.SetDashboardVersionRetentionCommand{
+			OrgId: 1, FolderId: folder.Id, VersionsToKeep: 1, KeepVersionsWithMessage: true,
+		}))
+		dash := insertTestDashboard(t, "dash", 1, folder.Id, false)
+		dash.Data.Set("description", "changed")
+		require.NoError(t, SaveDashboard(&models.SaveDashboardCommand{
+			OrgId: 1, FolderId: folder.Id, Dashboard: dash.Data, Overwrite: true, Message: "kept",
+		}))
+		require.NoError(t, AddDashboardVersionTag(&models.AddDashboardVersionTagCommand{
+			OrgId: 1, DashboardId: dash.Id, Version: 1, Tag: "release",
+		}))
+		require.NoError(t, DeleteDashboard(&models.DeleteDashboardCommand{Id: dash.Id, OrgId: 1}))
+
+		require.NoError(t, PurgeDeletedDashboard(&models.PurgeDeletedDashboardCommand{OrgId: 1, DashboardId: dash.Id}))
+
+		// purging a dashboard deletes all its versions, also those that are tagged or have a message
+		for _, table := range []string{"dashboard_version", "dashboard_version_tag"} {
+			count, err := x.Table(table).Where("dashboard_id=?", dash.Id).Count()
+			require.NoError(t, err)
+			assert.Zero(t, count, table)
+		}
+	})
+}
@@ -0,0 +1,26 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addDashboardDependencyMigrations(mg *Migrator) {
+	dashboardDependencyV1 := Table{
+		Name: "dashboard_dependency",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "kind", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "ref", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 255, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"dashboard_id"}},
+			{Cols: []string{"org_id", "kind", "ref"}},
+		},
+	}
+
+	mg.AddMigration("create dashboard_dependency table", NewAddTableMigration(dashboardDependencyV1))
+	addTableIndicesMigrations(mg, "v1", dashboardDependencyV1)
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addDashboardSearchMigrations(mg *Migrator) {
+	dashboardSearchTermV1 := Table{
+		Name: "dashboard_search_term",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "field", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "term", Type: DB_Text, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"dashboard_id"}},
+			{Cols: []string{"org_id", "field"}},
+		},
+	}
+
+	mg.AddMigration("create dashboard_search_term table", NewAddTableMigration(dashboardSearchTermV1))
+	addTableIndicesMigrations(mg, "v1", dashboardSearchTermV1)
+}
@@ -0,0 +1,80 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addDashboardVersionRetentionMigrations(mg *Migrator) {
+	dashboardVersionRetentionV1 := Table{
+		Name: "dashboard_version_retention",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "folder_id", Type: DB_BigInt, Nullable: false},
+			{Name: "versions_to_keep", Type: DB_Int, Nullable: false},
+			{Name: "keep_younger_than", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "keep_versions_with_message", Type: DB_Bool, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "folder_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create dashboard_version_retention table", NewAddTableMigration(dashboardVersionRetentionV1))
+	addTableIndicesMigrations(mg, "v1", dashboardVersionRetentionV1)
+
+	dashboardVersionTagV1 := Table{
+		Name: "dashboard_version_tag",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "version", Type: DB_Int, Nullable: false},
+			{Name: "tag", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"dashboard_id", "tag"}, Type: UniqueIndex},
+			{Cols: []string{"dashboard_id", "version"}},
+		},
+	}
+
+	mg.AddMigration("create dashboard_version_tag table", NewAddTableMigration(dashboardVersionTagV1))
+	addTableIndicesMigrations(mg, "v1", dashboardVersionTagV1)
+
+	deletedDashboardV1 := Table{
+		Name: "deleted_dashboard",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "dashboard_id", Type: DB_BigInt, Nullable: false},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "title", Type: DB_NVarchar, Length: 255, Nullable: false},
+			{Name: "folder_id", Type: DB_BigInt, Nullable: false},
+			{Name: "deleted", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"dashboard_id"}, Type: UniqueIndex},
+			{Cols: []string{"org_id"}},
+		},
+	}
+
+	mg.AddMigration("create deleted_dashboard table", NewAddTableMigration(deletedDashboardV1))
+	addTableIndicesMigrations(mg, "v1", deletedDashboardV1)
+
+	// deleted dashboards and folders are kept in the trash with their data until they're purged
+	mg.AddMigration("Add column is_folder in deleted_dashboard", NewAddColumnMigration(deletedDashboardV1, &Column{
+		Name: "is_folder", Type: DB_Bool, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("Add column deleted_with in deleted_dashboard", NewAddColumnMigration(deletedDashboardV1, &Column{
+		Name: "deleted_with", Type: DB_BigInt, Nullable: false, Default: "0",
+	}))
+	mg.AddMigration("Add column data in deleted_dashboard", NewAddColumnMigration(deletedDashboardV1, &Column{
+		Name: "data", Type: DB_MediumText, Nullable: true,
+	}))
+	mg.AddMigration("Add index deleted in deleted_dashboard", NewAddIndexMigration(deletedDashboardV1, &Index{
+		Cols: []string{"deleted"},
+	}))
+}
@@ -35,6 +35,11 @@ func AddMigrations(mg *Migrator) {
 	addUserAuthTokenMigrations(mg)
 	addCacheMigration(mg)
 	addShortURLMigrations(mg)
+	addSecretsMigrations(mg)
+	addDashboardSearchMigrations(mg)
+	addDashboardDependencyMigrations(mg)
+	addDashboardVersionRetentionMigrations(mg)
+	addRecordedQueryMigrations(mg)
 }
 
 func addMigrationLogMigrations(mg *Migrator) {
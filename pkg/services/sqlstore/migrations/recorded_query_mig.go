@@ -0,0 +1,34 @@
+package migrations
+
+import (
+	. "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+)
+
+func addRecordedQueryMigrations(mg *Migrator) {
+	recordedQueryV1 := Table{
+		Name: "recorded_query",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, Nullable: false, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "uid", Type: DB_NVarchar, Length: 40, Nullable: false},
+			{Name: "name", Type: DB_NVarchar, Length: 190, Nullable: false},
+			{Name: "datasource_id", Type: DB_BigInt, Nullable: false},
+			{Name: "query", Type: DB_Text, Nullable: false},
+			{Name: "interval", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "time_range", Type: DB_NVarchar, Length: 20, Nullable: false},
+			{Name: "active", Type: DB_Bool, Nullable: false},
+			{Name: "last_run", Type: DB_DateTime, Nullable: true},
+			{Name: "last_error", Type: DB_Text, Nullable: true},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id", "uid"}, Type: UniqueIndex},
+			{Cols: []string{"org_id", "name"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create recorded_query table v1", NewAddTableMigration(recordedQueryV1))
+	addTableIndicesMigrations(mg, "v1", recordedQueryV1)
+
+}
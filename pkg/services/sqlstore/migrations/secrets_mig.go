@@ -0,0 +1,19 @@
+package migrations
+
+import . "github.com/grafana/grafana/pkg/services/sqlstore/migrator"
+
+func addSecretsMigrations(mg *Migrator) {
+	dataKeyV1 := Table{
+		Name: "data_key",
+		Columns: []*Column{
+			{Name: "name", Type: DB_NVarchar, Length: 100, IsPrimaryKey: true},
+			{Name: "active", Type: DB_Bool, Nullable: false},
+			{Name: "provider", Type: DB_NVarchar, Length: 50, Nullable: false},
+			{Name: "encrypted_data", Type: DB_Blob, Nullable: false},
+			{Name: "created", Type: DB_DateTime, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+	}
+
+	mg.AddMigration("create data_key table", NewAddTableMigration(dataKeyV1))
+}
@@ -32,4 +32,22 @@ func addUserAuthTokenMigrations(mg *Migrator) {
 	mg.AddMigration("add unique index user_auth_token.prev_auth_token", NewAddIndexMigration(userAuthTokenV1, userAuthTokenV1.Indices[1]))
 
 	mg.AddMigration("add index user_auth_token.user_id", NewAddIndexMigration(userAuthTokenV1, userAuthTokenV1.Indices[2]))
+
+	orgSessionPolicyV1 := Table{
+		Name: "org_session_policy",
+		Columns: []*Column{
+			{Name: "id", Type: DB_BigInt, IsPrimaryKey: true, IsAutoIncrement: true},
+			{Name: "org_id", Type: DB_BigInt, Nullable: false},
+			{Name: "max_inactive_lifetime", Type: DB_BigInt, Nullable: false},
+			{Name: "max_lifetime", Type: DB_BigInt, Nullable: false},
+			{Name: "max_concurrent_sessions", Type: DB_Int, Nullable: false},
+			{Name: "updated", Type: DB_DateTime, Nullable: false},
+		},
+		Indices: []*Index{
+			{Cols: []string{"org_id"}, Type: UniqueIndex},
+		},
+	}
+
+	mg.AddMigration("create org_session_policy table v1", NewAddTableMigration(orgSessionPolicyV1))
+	mg.AddMigration("add unique index org_session_policy.org_id", NewAddIndexMigration(orgSessionPolicyV1, orgSessionPolicyV1.Indices[0]))
 }
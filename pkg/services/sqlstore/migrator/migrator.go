@@ -71,6 +71,22 @@ func (mg *Migrator) GetMigrationLog() (map[string]MigrationLog, error) {
 	return logMap, nil
 }
 
+// PendingMigrations returns the ids of the migrations that haven't been executed.
+func (mg *Migrator) PendingMigrations() ([]string, error) {
+	logMap, err := mg.GetMigrationLog()
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []string
+	for _, m := range mg.migrations {
+		if _, exists := logMap[m.Id()]; !exists {
+			pending = append(pending, m.Id())
+		}
+	}
+	return pending, nil
+}
+
 func (mg *Migrator) Start() error {
 	mg.Logger.Info("Starting DB migrations")
 
@@ -235,12 +235,23 @@ func DeleteOrg(cmd *models.DeleteOrgCommand) error {
 		deletes := []string{
 			"DELETE FROM star WHERE EXISTS (SELECT 1 FROM dashboard WHERE org_id = ? AND star.dashboard_id = dashboard.id)",
 			"DELETE FROM dashboard_tag WHERE EXISTS (SELECT 1 FROM dashboard WHERE org_id = ? AND dashboard_tag.dashboard_id = dashboard.id)",
+			"DELETE FROM dashboard_search_term WHERE org_id = ?",
+			"DELETE FROM dashboard_dependency WHERE org_id = ?",
+			"DELETE FROM dashboard_version WHERE dashboard_id IN (SELECT id FROM dashboard WHERE org_id = ?)",
+			"DELETE FROM dashboard_version WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE org_id = ?)",
+			"DELETE FROM star WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE org_id = ?)",
+			"DELETE FROM dashboard_acl WHERE dashboard_id IN (SELECT dashboard_id FROM deleted_dashboard WHERE org_id = ?)",
+			"DELETE FROM dashboard_version_tag WHERE org_id = ?",
+			"DELETE FROM dashboard_version_retention WHERE org_id = ?",
+			"DELETE FROM deleted_dashboard WHERE org_id = ?",
 			"DELETE FROM dashboard WHERE org_id = ?",
 			"DELETE FROM api_key WHERE org_id = ?",
+			"DELETE FROM recorded_query WHERE org_id = ?",
 			"DELETE FROM data_source WHERE org_id = ?",
 			"DELETE FROM org_user WHERE org_id = ?",
 			"DELETE FROM org WHERE id = ?",
 			"DELETE FROM temp_user WHERE org_id = ?",
+			"DELETE FROM org_session_policy WHERE org_id = ?",
 		}
 
 		for _, sql := range deletes {
@@ -128,6 +128,7 @@ func GetOrgUsers(query *models.GetOrgUsersQuery) error {
 		"user.login",
 		"org_user.role",
 		"user.last_seen_at",
+		"user.is_disabled",
 	)
 	sess.Asc("user.email", "user.login")
 
@@ -0,0 +1,172 @@
+package sqlstore
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/util"
+)
+
+func init() {
+	bus.AddHandler("sql", GetRecordedQueries)
+	bus.AddHandler("sql", GetRecordedQuery)
+	bus.AddHandler("sql", GetActiveRecordedQueries)
+	bus.AddHandler("sql", CreateRecordedQuery)
+	bus.AddHandler("sql", UpdateRecordedQuery)
+	bus.AddHandler("sql", DeleteRecordedQuery)
+	bus.AddHandler("sql", SetRecordedQueryRun)
+}
+
+var recordedQueryNamePattern = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// validateRecordedQuery validates the settings of a recorded query and sets the defaults of the
+// optional ones.
+func validateRecordedQuery(rq *models.RecordedQuery) error {
+	if !recordedQueryNamePattern.MatchString(rq.Name) {
+		return models.ErrRecordedQueryInvalidName
+	}
+
+	if rq.TimeRange == "" {
+		rq.TimeRange = rq.Interval
+	}
+	for _, value := range []string{rq.Interval, rq.TimeRange} {
+		duration, err := gtime.ParseDuration(value)
+		if err != nil || duration < models.RecordedQueryMinInterval {
+			return models.ErrRecordedQueryInvalidInterval
+		}
+	}
+
+	return nil
+}
+
+func isRecordedQueryNameTaken(sess *DBSession, orgID int64, name string, id int64) (bool, error) {
+	var existing models.RecordedQuery
+	exists, err := sess.Where("org_id=? AND name=?", orgID, name).Get(&existing)
+	if err != nil {
+		return false, err
+	}
+	return exists && existing.Id != id, nil
+}
+
+func GetRecordedQueries(query *models.GetRecordedQueriesQuery) error {
+	query.Result = make([]*models.RecordedQuery, 0)
+	return x.Where("org_id=?", query.OrgId).Asc("name").Find(&query.Result)
+}
+
+func GetRecordedQuery(query *models.GetRecordedQueryQuery) error {
+	var rq models.RecordedQuery
+	exists, err := x.Where("org_id=? AND uid=?", query.OrgId, query.Uid).Get(&rq)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return models.ErrRecordedQueryNotFound
+	}
+
+	query.Result = &rq
+	return nil
+}
+
+func GetActiveRecordedQueries(query *models.GetActiveRecordedQueriesQuery) error {
+	query.Result = make([]*models.RecordedQuery, 0)
+	return x.Where("active=" + dialect.BooleanStr(true)).Asc("id").Find(&query.Result)
+}
+
+func CreateRecordedQuery(cmd *models.CreateRecordedQueryCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		now := time.Now()
+		rq := &models.RecordedQuery{
+			OrgId:        cmd.OrgId,
+			Uid:          util.GenerateShortUID(),
+			Name:         cmd.Name,
+			DatasourceId: cmd.DatasourceId,
+			Query:        cmd.Query,
+			Interval:     cmd.Interval,
+			TimeRange:    cmd.TimeRange,
+			Active:       cmd.Active,
+			Created:      now,
+			Updated:      now,
+		}
+		if err := validateRecordedQuery(rq); err != nil {
+			return err
+		}
+
+		if taken, err := isRecordedQueryNameTaken(sess, cmd.OrgId, cmd.Name, 0); err != nil {
+			return err
+		} else if taken {
+			return models.ErrRecordedQueryNameTaken
+		}
+
+		if _, err := sess.Insert(rq); err != nil {
+			return err
+		}
+
+		cmd.Result = rq
+		return nil
+	})
+}
+
+func UpdateRecordedQuery(cmd *models.UpdateRecordedQueryCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		var rq models.RecordedQuery
+		exists, err := sess.Where("org_id=? AND uid=?", cmd.OrgId, cmd.Uid).Get(&rq)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return models.ErrRecordedQueryNotFound
+		}
+
+		rq.Name = cmd.Name
+		rq.DatasourceId = cmd.DatasourceId
+		rq.Query = cmd.Query
+		rq.Interval = cmd.Interval
+		rq.TimeRange = cmd.TimeRange
+		rq.Active = cmd.Active
+		rq.Updated = time.Now()
+		if err := validateRecordedQuery(&rq); err != nil {
+			return err
+		}
+
+		if taken, err := isRecordedQueryNameTaken(sess, cmd.OrgId, cmd.Name, rq.Id); err != nil {
+			return err
+		} else if taken {
+			return models.ErrRecordedQueryNameTaken
+		}
+
+		if _, err := sess.ID(rq.Id).AllCols().Update(&rq); err != nil {
+			return err
+		}
+
+		cmd.Result = &rq
+		return nil
+	})
+}
+
+func DeleteRecordedQuery(cmd *models.DeleteRecordedQueryCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		res, err := sess.Exec("DELETE FROM recorded_query WHERE org_id=? AND uid=?", cmd.OrgId, cmd.Uid)
+		if err != nil {
+			return err
+		}
+		if rows, err := res.RowsAffected(); err != nil {
+			return err
+		} else if rows == 0 {
+			return models.ErrRecordedQueryNotFound
+		}
+		return nil
+	})
+}
+
+func SetRecordedQueryRun(cmd *models.SetRecordedQueryRunCommand) error {
+	return inTransaction(func(sess *DBSession) error {
+		_, err := sess.Table("recorded_query").ID(cmd.Id).Update(map[string]interface{}{
+			"last_run":   cmd.LastRun,
+			"last_error": cmd.LastError,
+		})
+		return err
+	})
+}
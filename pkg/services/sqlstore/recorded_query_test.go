@@ -0,0 +1,97 @@
+// +build integration
+
+package sqlstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+)
+
+func TestRecordedQueries(t *testing.T) {
+	newCreateCommand := func(name string) models.CreateRecordedQueryCommand {
+		return models.CreateRecordedQueryCommand{
+			OrgId:        1,
+			Name:         name,
+			DatasourceId: 1,
+			Query:        simplejson.NewFromAny(map[string]interface{}{"rawSql": "SELECT 1"}),
+			Interval:     "1m",
+			Active:       true,
+		}
+	}
+
+	t.Run("Recorded queries are created, updated and deleted", func(t *testing.T) {
+		InitTestDB(t)
+
+		cmd := newCreateCommand("requests_total")
+		require.NoError(t, CreateRecordedQuery(&cmd))
+		assert.NotEmpty(t, cmd.Result.Uid)
+		assert.Equal(t, "1m", cmd.Result.TimeRange)
+
+		duplicate := newCreateCommand("requests_total")
+		assert.Equal(t, models.ErrRecordedQueryNameTaken, CreateRecordedQuery(&duplicate))
+
+		invalid := newCreateCommand("requests total")
+		assert.Equal(t, models.ErrRecordedQueryInvalidName, CreateRecordedQuery(&invalid))
+		invalid = newCreateCommand("requests")
+		invalid.Interval = "1s"
+		assert.Equal(t, models.ErrRecordedQueryInvalidInterval, CreateRecordedQuery(&invalid))
+
+		updateCmd := models.UpdateRecordedQueryCommand{
+			OrgId:        1,
+			Uid:          cmd.Result.Uid,
+			Name:         "requests",
+			DatasourceId: 1,
+			Query:        cmd.Result.Query,
+			Interval:     "5m",
+			TimeRange:    "1h",
+		}
+		require.NoError(t, UpdateRecordedQuery(&updateCmd))
+
+		query := models.GetRecordedQueryQuery{OrgId: 1, Uid: cmd.Result.Uid}
+		require.NoError(t, GetRecordedQuery(&query))
+		assert.Equal(t, "requests", query.Result.Name)
+		assert.Equal(t, "1h", query.Result.TimeRange)
+		assert.False(t, query.Result.Active)
+		assert.Equal(t, "SELECT 1", query.Result.Query.Get("rawSql").MustString())
+
+		activeQuery := models.GetActiveRecordedQueriesQuery{}
+		require.NoError(t, GetActiveRecordedQueries(&activeQuery))
+		assert.Empty(t, activeQuery.Result)
+
+		otherOrgQuery := models.GetRecordedQueryQuery{OrgId: 2, Uid: cmd.Result.Uid}
+		assert.Equal(t, models.ErrRecordedQueryNotFound, GetRecordedQuery(&otherOrgQuery))
+
+		err := DeleteRecordedQuery(&models.DeleteRecordedQueryCommand{OrgId: 2, Uid: cmd.Result.Uid})
+		assert.Equal(t, models.ErrRecordedQueryNotFound, err)
+		require.NoError(t, DeleteRecordedQuery(&models.DeleteRecordedQueryCommand{OrgId: 1, Uid: cmd.Result.Uid}))
+		listQuery := models.GetRecordedQueriesQuery{OrgId: 1}
+		require.NoError(t, GetRecordedQueries(&listQuery))
+		assert.Empty(t, listQuery.Result)
+	})
+
+	t.Run("Runs of recorded queries are stored", func(t *testing.T) {
+		InitTestDB(t)
+
+		cmd := newCreateCommand("requests_total")
+		require.NoError(t, CreateRecordedQuery(&cmd))
+
+		lastRun := time.Now()
+		require.NoError(t, SetRecordedQueryRun(&models.SetRecordedQueryRunCommand{
+			Id: cmd.Result.Id, LastRun: lastRun, LastError: "failed",
+		}))
+
+		activeQuery := models.GetActiveRecordedQueriesQuery{}
+		require.NoError(t, GetActiveRecordedQueries(&activeQuery))
+		require.Len(t, activeQuery.Result, 1)
+		require.NotNil(t, activeQuery.Result[0].LastRun)
+		assert.Equal(t, lastRun.Unix(), activeQuery.Result[0].LastRun.Unix())
+		assert.Equal(t, "failed", activeQuery.Result[0].LastError)
+
+	})
+}
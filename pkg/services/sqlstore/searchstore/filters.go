@@ -86,6 +86,25 @@ func (f TitleFilter) Where() (string, []interface{}) {
 	return fmt.Sprintf("dashboard.title %s ?", f.Dialect.LikeStr()), []interface{}{"%" + f.Title + "%"}
 }
 
+// SearchTermFilter matches dashboards that have an indexed search term of the field containing Term.
+// An empty Field matches the terms of all fields.
+type SearchTermFilter struct {
+	Dialect migrator.Dialect
+	Field   string
+	Term    string
+}
+
+func (f SearchTermFilter) Where() (string, []interface{}) {
+	sql := `EXISTS (SELECT 1 FROM dashboard_search_term
+		WHERE dashboard_search_term.dashboard_id = dashboard.id AND dashboard_search_term.term ` + f.Dialect.LikeStr() + ` ?`
+	params := []interface{}{"%" + f.Term + "%"}
+	if f.Field != "" {
+		sql += " AND dashboard_search_term.field = ?"
+		params = append(params, f.Field)
+	}
+	return sql + ")", params
+}
+
 type FolderFilter struct {
 	IDs []int64
 }
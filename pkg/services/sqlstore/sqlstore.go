@@ -87,18 +87,13 @@ func (ss *SQLStore) Init() error {
 	x = ss.engine
 	dialect = ss.Dialect
 
-	if !ss.dbCfg.SkipMigrations {
-		migrator := migrator.NewMigrator(ss.engine)
-		migrations.AddMigrations(migrator)
-
-		for _, descriptor := range registry.GetServices() {
-			sc, ok := descriptor.Instance.(registry.DatabaseMigrator)
-			if ok {
-				sc.AddMigration(migrator)
-			}
+	// the provisioning dry run only reads the database, so it doesn't migrate it
+	if ss.Cfg.ProvisioningDryRun {
+		if err := ss.checkMigrations(); err != nil {
+			return err
 		}
-
-		if err := migrator.Start(); err != nil {
+	} else if !ss.dbCfg.SkipMigrations {
+		if err := ss.newMigrator().Start(); err != nil {
 			return err
 		}
 	}
@@ -113,6 +108,10 @@ func (ss *SQLStore) Init() error {
 	ss.addAlertNotificationUidByIdHandler()
 	ss.addPreferencesQueryAndCommandHandlers()
 
+	if ss.Cfg.ProvisioningDryRun {
+		return nil
+	}
+
 	if err := ss.Reset(); err != nil {
 		return err
 	}
@@ -126,6 +125,31 @@ func (ss *SQLStore) Init() error {
 	return nil
 }
 
+func (ss *SQLStore) newMigrator() *migrator.Migrator {
+	mg := migrator.NewMigrator(ss.engine)
+	migrations.AddMigrations(mg)
+
+	for _, descriptor := range registry.GetServices() {
+		sc, ok := descriptor.Instance.(registry.DatabaseMigrator)
+		if ok {
+			sc.AddMigration(mg)
+		}
+	}
+	return mg
+}
+
+// checkMigrations returns an error if the database has migrations that haven't been applied yet.
+func (ss *SQLStore) checkMigrations() error {
+	pending, err := ss.newMigrator().PendingMigrations()
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("the database has %d migrations that haven't been applied, start Grafana to apply them", len(pending))
+	}
+	return nil
+}
+
 // Sync syncs changes to the database.
 func (ss *SQLStore) Sync() error {
 	return ss.engine.Sync2()
@@ -1,5 +1,7 @@
 package sqlstore
 
+import "time"
+
 // extra tables not required by the core/outside model
 
 type DashboardTag struct {
@@ -7,3 +9,29 @@ type DashboardTag struct {
 	DashboardId int64
 	Term        string
 }
+
+type DashboardSearchTerm struct {
+	Id          int64
+	DashboardId int64
+	OrgId       int64
+	Field       string
+	Term        string
+}
+
+type DashboardDependency struct {
+	Id          int64
+	OrgId       int64
+	DashboardId int64
+	Kind        string
+	Ref         string
+	Name        string
+}
+
+type DashboardVersionTag struct {
+	Id          int64
+	OrgId       int64
+	DashboardId int64
+	Version     int
+	Tag         string
+	Created     time.Time
+}
@@ -6,9 +6,8 @@ import (
 	"time"
 
 	"github.com/grafana/grafana/pkg/bus"
+	"github.com/grafana/grafana/pkg/components/securejsondata"
 	"github.com/grafana/grafana/pkg/models"
-	"github.com/grafana/grafana/pkg/setting"
-	"github.com/grafana/grafana/pkg/util"
 )
 
 var getTime = time.Now
@@ -256,7 +255,7 @@ func DeleteAuthInfo(cmd *models.DeleteAuthInfoCommand) error {
 }
 
 // decodeAndDecrypt will decode the string with the standard bas64 decoder
-// and then decrypt it with grafana's secretKey
+// and then decrypt it with the encrypter of the secrets in the database
 func decodeAndDecrypt(s string) (string, error) {
 	// Bail out if empty string since it'll cause a segfault in util.Decrypt
 	if s == "" {
@@ -266,17 +265,17 @@ func decodeAndDecrypt(s string) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	decrypted, err := util.Decrypt(decoded, setting.SecretKey)
+	decrypted, err := securejsondata.Decrypt(decoded)
 	if err != nil {
 		return "", err
 	}
 	return string(decrypted), nil
 }
 
-// encryptAndEncode will encrypt a string with grafana's secretKey, and
+// encryptAndEncode will encrypt a string with the encrypter of the secrets in the database, and
 // then encode it with the standard bas64 encoder
 func encryptAndEncode(s string) (string, error) {
-	encrypted, err := util.Encrypt([]byte(s), setting.SecretKey)
+	encrypted, err := securejsondata.Encrypt([]byte(s))
 	if err != nil {
 		return "", err
 	}
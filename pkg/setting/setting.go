@@ -210,7 +210,10 @@ type Cfg struct {
 	Packaging string
 
 	// Paths
-	ProvisioningPath   string
+	ProvisioningPath string
+	// ProvisioningDryRun is set when the provisioning config files are only validated, and not applied.
+	// The database is only read from, and isn't migrated.
+	ProvisioningDryRun bool
 	DataPath           string
 	LogsPath           string
 	PluginsPath        string
@@ -243,6 +246,11 @@ type Cfg struct {
 	// CSPTemplate contains the Content Security Policy template.
 	CSPTemplate string
 
+	// Envelope encryption of secrets
+	EnvelopeEncryptionEnabled bool
+	EncryptionProvider        string
+	EncryptionKeyFile         string
+
 	TempDataLifetime         time.Duration
 	PluginsEnableAlpha       bool
 	PluginsAppsSkipVerifyTLS bool
@@ -266,6 +274,7 @@ type Cfg struct {
 	LoginCookieName              string
 	LoginMaxInactiveLifetime     time.Duration
 	LoginMaxLifetime             time.Duration
+	LoginMaxConcurrentSessions   int
 	TokenRotationIntervalMinutes int
 	SigV4AuthEnabled             bool
 	BasicAuthEnabled             bool
@@ -294,6 +303,14 @@ type Cfg struct {
 	SAMLEnabled             bool
 	SAMLSingleLogoutEnabled bool
 
+	// SCIM provisioning
+	SCIMEnabled bool
+
+	// Multi-factor authentication
+	MFAEnabled  bool
+	MFAEnforced bool
+	MFAIssuer   string
+
 	// Dataproxy
 	SendUserHeader bool
 
@@ -317,6 +334,9 @@ type Cfg struct {
 	UserInviteMaxLifetime time.Duration
 	HiddenUsers           map[string]struct{}
 
+	// Dashboards
+	DashboardTrashPeriod time.Duration
+
 	// Annotations
 	AnnotationCleanupJobBatchSize      int64
 	AlertingAnnotationCleanupSetting   AnnotationCleanupSettings
@@ -329,6 +349,15 @@ type Cfg struct {
 	// Data sources
 	DataSourceLimit int
 
+	// Query caching
+	QueryCaching QueryCachingSettings
+
+	// Recorded queries
+	RecordedQueries RecordedQueriesSettings
+
+	// SQL data sources
+	SQLDataSources SQLDataSourcesSettings
+
 	// Snapshots
 	SnapshotPublicMode bool
 
@@ -812,6 +841,11 @@ func (cfg *Cfg) Load(args *CommandLineArgs) error {
 	dashboards := iniFile.Section("dashboards")
 	DashboardVersionsToKeep = dashboards.Key("versions_to_keep").MustInt(20)
 	MinRefreshInterval = valueAsString(dashboards, "min_refresh_interval", "5s")
+	trashPeriod, err := gtime.ParseDuration(valueAsString(dashboards, "trash_period", "30d"))
+	if err != nil {
+		return err
+	}
+	cfg.DashboardTrashPeriod = trashPeriod
 
 	cfg.DefaultHomeDashboardPath = dashboards.Key("default_home_dashboard_path").MustString("")
 
@@ -887,6 +921,13 @@ func (cfg *Cfg) Load(args *CommandLineArgs) error {
 	}
 
 	cfg.readDataSourcesSettings()
+	if err := cfg.readQueryCachingSettings(); err != nil {
+		return err
+	}
+	cfg.readRecordedQueriesSettings()
+	if err := cfg.readSQLDataSourcesSettings(); err != nil {
+		return err
+	}
 
 	if VerifyEmailEnabled && !cfg.Smtp.Enabled {
 		log.Warnf("require_email_validation is enabled but smtp is disabled")
@@ -1075,6 +1116,13 @@ func readSecuritySettings(iniFile *ini.File, cfg *Cfg) error {
 	cfg.CSPEnabled = security.Key("content_security_policy").MustBool(false)
 	cfg.CSPTemplate = security.Key("content_security_policy_template").MustString("")
 
+	encryption := iniFile.Section("security.encryption")
+	cfg.EnvelopeEncryptionEnabled = encryption.Key("envelope_encryption").MustBool(false)
+	cfg.EncryptionProvider = valueAsString(encryption, "provider", "secret_key")
+	if keyFile := valueAsString(encryption, "key_file", ""); keyFile != "" {
+		cfg.EncryptionKeyFile = makeAbsolute(keyFile, HomePath)
+	}
+
 	// read data source proxy whitelist
 	DataProxyWhiteList = make(map[string]bool)
 	securityStr := valueAsString(security, "data_source_proxy_whitelist", "")
@@ -1121,6 +1169,8 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 		return err
 	}
 
+	cfg.LoginMaxConcurrentSessions = auth.Key("login_maximum_concurrent_sessions").MustInt(0)
+
 	cfg.ApiKeyMaxSecondsToLive = auth.Key("api_key_max_seconds_to_live").MustInt64(-1)
 
 	cfg.TokenRotationIntervalMinutes = auth.Key("token_rotation_interval_minutes").MustInt(10)
@@ -1142,6 +1192,15 @@ func readAuthSettings(iniFile *ini.File, cfg *Cfg) (err error) {
 	cfg.SAMLEnabled = iniFile.Section("auth.saml").Key("enabled").MustBool(false)
 	cfg.SAMLSingleLogoutEnabled = iniFile.Section("auth.saml").Key("single_logout").MustBool(false)
 
+	// SCIM provisioning
+	cfg.SCIMEnabled = iniFile.Section("auth.scim").Key("enabled").MustBool(false)
+
+	// Multi-factor authentication
+	authMFA := iniFile.Section("auth.mfa")
+	cfg.MFAEnabled = authMFA.Key("enabled").MustBool(false)
+	cfg.MFAEnforced = authMFA.Key("enforced").MustBool(false)
+	cfg.MFAIssuer = valueAsString(authMFA, "issuer", "Grafana")
+
 	// anonymous access
 	AnonymousEnabled = iniFile.Section("auth.anonymous").Key("enabled").MustBool(false)
 	cfg.AnonymousEnabled = AnonymousEnabled
@@ -1368,3 +1427,79 @@ func (cfg *Cfg) readDataSourcesSettings() {
 	datasources := cfg.Raw.Section("datasources")
 	cfg.DataSourceLimit = datasources.Key("datasource_limit").MustInt(5000)
 }
+
+// QueryCachingSettings configures the cache of data source query results.
+type QueryCachingSettings struct {
+	Enabled    bool
+	Backend    string
+	MaxItems   int
+	DefaultTTL time.Duration
+}
+
+func (cfg *Cfg) readQueryCachingSettings() error {
+	section := cfg.Raw.Section("query_caching")
+	cfg.QueryCaching.Enabled = section.Key("enabled").MustBool(true)
+	cfg.QueryCaching.Backend = valueAsString(section, "backend", "memory")
+	if cfg.QueryCaching.Backend != "memory" && cfg.QueryCaching.Backend != "remote_cache" {
+		return fmt.Errorf("invalid query caching backend %q, must be either \"memory\" or \"remote_cache\"",
+			cfg.QueryCaching.Backend)
+	}
+	cfg.QueryCaching.MaxItems = section.Key("max_items").MustInt(1000)
+
+	ttl, err := gtime.ParseDuration(valueAsString(section, "default_ttl", "1m"))
+	if err != nil {
+		return err
+	}
+	cfg.QueryCaching.DefaultTTL = ttl
+	return nil
+}
+
+// RecordedQueriesSettings configures the remote write endpoint that the results of recorded queries are
+// sent to.
+type RecordedQueriesSettings struct {
+	Enabled             bool
+	RemoteWriteURL      string
+	RemoteWriteUser     string
+	RemoteWritePassword string
+}
+
+func (cfg *Cfg) readRecordedQueriesSettings() {
+	section := cfg.Raw.Section("recorded_queries")
+	cfg.RecordedQueries.Enabled = section.Key("enabled").MustBool(true)
+	cfg.RecordedQueries.RemoteWriteURL = valueAsString(section, "remote_write_url", "")
+	cfg.RecordedQueries.RemoteWriteUser = valueAsString(section, "remote_write_user", "")
+	cfg.RecordedQueries.RemoteWritePassword = valueAsString(section, "remote_write_password", "")
+}
+
+// SQLDataSourcesSettings limits the queries of SQL data sources. Data sources can override the limits with
+// rowLimit and queryTimeout in their jsonData.
+type SQLDataSourcesSettings struct {
+	RowLimit     int64
+	QueryTimeout time.Duration
+	// SQLiteAllowedPaths are the directories that SQLite data sources can open database files in.
+	SQLiteAllowedPaths []string
+}
+
+func (cfg *Cfg) readSQLDataSourcesSettings() error {
+	section := cfg.Raw.Section("sql_datasources")
+	cfg.SQLDataSources.RowLimit = section.Key("row_limit").MustInt64(1000000)
+	if cfg.SQLDataSources.RowLimit <= 0 {
+		return fmt.Errorf("invalid SQL data source row limit %d, must be greater than 0", cfg.SQLDataSources.RowLimit)
+	}
+
+	timeout, err := gtime.ParseDuration(valueAsString(section, "query_timeout", "0"))
+	if err != nil {
+		return err
+	}
+	cfg.SQLDataSources.QueryTimeout = timeout
+
+	cfg.SQLDataSources.SQLiteAllowedPaths = nil
+	for _, path := range util.SplitString(valueAsString(section, "sqlite_allowed_paths", "")) {
+		absPath, err := filepath.Abs(path)
+		if err != nil {
+			return fmt.Errorf("invalid SQLite allowed path %q: %w", path, err)
+		}
+		cfg.SQLDataSources.SQLiteAllowedPaths = append(cfg.SQLDataSources.SQLiteAllowedPaths, absPath)
+	}
+	return nil
+}
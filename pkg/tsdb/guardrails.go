@@ -0,0 +1,149 @@
+package tsdb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/tsdb/interval"
+)
+
+// ErrQueryLimitExceeded is returned for query requests and query results that exceed the limits of
+// a data source.
+var ErrQueryLimitExceeded = errors.New("query exceeds the limits of the data source")
+
+// queryGuardrails are the limits that admins set on the queries of a data source in its jsonData.
+// Limits that are zero aren't enforced.
+type queryGuardrails struct {
+	maxTimeRange time.Duration
+	minInterval  time.Duration
+	maxSeries    int
+	maxRows      int
+}
+
+func getQueryGuardrails(ds *models.DataSource) (queryGuardrails, error) {
+	guardrails := queryGuardrails{}
+	if ds.JsonData == nil {
+		return guardrails, nil
+	}
+
+	parseDuration := func(key string) (time.Duration, error) {
+		value := ds.JsonData.Get(key).MustString("")
+		if value == "" {
+			return 0, nil
+		}
+		duration, err := gtime.ParseDuration(value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q of data source: %w", key, value, err)
+		}
+		return duration, nil
+	}
+
+	var err error
+	if guardrails.maxTimeRange, err = parseDuration("maxQueryTimeRange"); err != nil {
+		return guardrails, err
+	}
+	if guardrails.minInterval, err = parseDuration("minQueryInterval"); err != nil {
+		return guardrails, err
+	}
+	guardrails.maxSeries = ds.JsonData.Get("maxQuerySeries").MustInt(0)
+	guardrails.maxRows = ds.JsonData.Get("maxQueryRows").MustInt(0)
+
+	return guardrails, nil
+}
+
+// checkRequest returns an error if the time range or the interval of a query request exceed the limits.
+func (g queryGuardrails) checkRequest(query plugins.DataQuery) error {
+	if g.maxTimeRange > 0 && query.TimeRange != nil {
+		from, err := query.TimeRange.ParseFrom()
+		if err != nil {
+			return err
+		}
+		to, err := query.TimeRange.ParseTo()
+		if err != nil {
+			return err
+		}
+
+		if timeRange := to.Sub(from); timeRange > g.maxTimeRange {
+			return fmt.Errorf("%w: the time range of %s is longer than the maximum of %s", ErrQueryLimitExceeded,
+				interval.FormatDuration(timeRange), interval.FormatDuration(g.maxTimeRange))
+		}
+	}
+
+	if g.minInterval > 0 {
+		for _, q := range query.Queries {
+			// queries without an interval use the interval of the data source
+			queryInterval := time.Duration(q.IntervalMS) * time.Millisecond
+			if queryInterval > 0 && queryInterval < g.minInterval {
+				return fmt.Errorf("%w: the interval of %s of query %s is shorter than the minimum of %s",
+					ErrQueryLimitExceeded, interval.FormatDuration(queryInterval), q.RefID,
+					interval.FormatDuration(g.minInterval))
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkResponse replaces the query results that return more series or rows than the limits with an error.
+func (g queryGuardrails) checkResponse(resp plugins.DataResponse) {
+	if g.maxSeries == 0 && g.maxRows == 0 {
+		return
+	}
+
+	for refID, result := range resp.Results {
+		if result.Error != nil {
+			continue
+		}
+
+		series, rows, err := resultSize(result)
+		if err != nil {
+			continue
+		}
+
+		var limitErr error
+		switch {
+		case g.maxSeries > 0 && series > g.maxSeries:
+			limitErr = fmt.Errorf("%w: the query returned %d series, more than the maximum of %d",
+				ErrQueryLimitExceeded, series, g.maxSeries)
+		case g.maxRows > 0 && rows > g.maxRows:
+			limitErr = fmt.Errorf("%w: the query returned %d rows, more than the maximum of %d",
+				ErrQueryLimitExceeded, rows, g.maxRows)
+		default:
+			continue
+		}
+
+		resp.Results[refID] = plugins.DataQueryResult{RefID: refID, Error: limitErr}
+	}
+}
+
+// resultSize returns the number of series and rows of a query result. Time series points count as rows.
+func resultSize(result plugins.DataQueryResult) (int, int, error) {
+	series, rows := len(result.Series), 0
+	for _, s := range result.Series {
+		rows += len(s.Points)
+	}
+
+	for _, table := range result.Tables {
+		series++
+		rows += len(table.Rows)
+	}
+
+	if result.Dataframes != nil {
+		frames, err := result.Dataframes.Decoded()
+		if err != nil {
+			return 0, 0, err
+		}
+		for _, frame := range frames {
+			series++
+			if len(frame.Fields) > 0 {
+				rows += frame.Fields[0].Len()
+			}
+		}
+	}
+
+	return series, rows, nil
+}
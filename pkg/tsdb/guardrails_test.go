@@ -0,0 +1,87 @@
+package tsdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/components/null"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryGuardrails(t *testing.T) {
+	newRequest := func(from string, intervalMS int64) plugins.DataQuery {
+		timeRange := plugins.NewDataTimeRange(from, "now")
+		return plugins.DataQuery{
+			TimeRange: &timeRange,
+			Queries: []plugins.DataSubQuery{
+				{RefID: "A", IntervalMS: intervalMS},
+				{RefID: "B", IntervalMS: intervalMS},
+			},
+		}
+	}
+
+	newDataSource := func(jsonData map[string]interface{}) *models.DataSource {
+		return &models.DataSource{Id: 1, Type: "test", JsonData: simplejson.NewFromAny(jsonData)}
+	}
+
+	t.Run("Requests over the maximum time range or under the minimum interval fail", func(t *testing.T) {
+		svc, exe := createService()
+		exe.Return("A", plugins.DataTimeSeriesSlice{{Name: "series"}})
+		ds := newDataSource(map[string]interface{}{"maxQueryTimeRange": "30d", "minQueryInterval": "1m"})
+
+		_, err := svc.HandleRequest(context.Background(), ds, newRequest("now-7d", 60000))
+		require.NoError(t, err)
+
+		_, err = svc.HandleRequest(context.Background(), ds, newRequest("now-5y", 60000))
+		require.True(t, errors.Is(err, ErrQueryLimitExceeded))
+		assert.Contains(t, err.Error(), "the time range of 5y is longer than the maximum of 30d")
+
+		_, err = svc.HandleRequest(context.Background(), ds, newRequest("now-7d", 1000))
+		require.True(t, errors.Is(err, ErrQueryLimitExceeded))
+		assert.Contains(t, err.Error(), "the interval of 1s of query A is shorter than the minimum of 1m")
+	})
+
+	t.Run("Results with more series or rows than the maximum are replaced with an error", func(t *testing.T) {
+		svc, exe := createService()
+		exe.Return("A", plugins.DataTimeSeriesSlice{
+			{Name: "a", Points: plugins.DataTimeSeriesPoints{{null.FloatFrom(1), null.FloatFrom(1)}}},
+			{Name: "b", Points: plugins.DataTimeSeriesPoints{{null.FloatFrom(1), null.FloatFrom(1)}}},
+		})
+		exe.HandleQuery("B", func(plugins.DataQuery) plugins.DataQueryResult {
+			frame := data.NewFrame("", data.NewField("value", nil, []float64{1, 2, 3, 4}))
+			return plugins.DataQueryResult{RefID: "B", Dataframes: plugins.NewDecodedDataFrames(data.Frames{frame})}
+		})
+
+		ds := newDataSource(map[string]interface{}{"maxQuerySeries": 2, "maxQueryRows": 3})
+		resp, err := svc.HandleRequest(context.Background(), ds, newRequest("now-1h", 0))
+		require.NoError(t, err)
+		assert.NoError(t, resp.Results["A"].Error)
+		assert.Len(t, resp.Results["A"].Series, 2)
+		require.Error(t, resp.Results["B"].Error)
+		assert.True(t, errors.Is(resp.Results["B"].Error, ErrQueryLimitExceeded))
+		assert.Contains(t, resp.Results["B"].Error.Error(), "the query returned 4 rows, more than the maximum of 3")
+		assert.Nil(t, resp.Results["B"].Dataframes)
+
+		ds = newDataSource(map[string]interface{}{"maxQuerySeries": 1})
+		resp, err = svc.HandleRequest(context.Background(), ds, newRequest("now-1h", 0))
+		require.NoError(t, err)
+		require.Error(t, resp.Results["A"].Error)
+		assert.Contains(t, resp.Results["A"].Error.Error(), "the query returned 2 series, more than the maximum of 1")
+		assert.NoError(t, resp.Results["B"].Error)
+	})
+
+	t.Run("Invalid limits fail the request", func(t *testing.T) {
+		svc, _ := createService()
+		ds := newDataSource(map[string]interface{}{"maxQueryTimeRange": "forever"})
+
+		_, err := svc.HandleRequest(context.Background(), ds, newRequest("now-1h", 0))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid maxQueryTimeRange")
+	})
+}
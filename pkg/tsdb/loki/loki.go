@@ -2,12 +2,17 @@ package loki
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/components/null"
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
@@ -38,6 +43,10 @@ var (
 	legendFormat = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
 )
 
+// defaultMaxLines is the number of lines that log queries return if neither the query nor the data source
+// set a limit.
+const defaultMaxLines = 1000
+
 // DataQuery executes a Loki query.
 func (e *LokiExecutor) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 	queryContext plugins.DataQuery) (plugins.DataResponse, error) {
@@ -64,12 +73,8 @@ func (e *LokiExecutor) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 		span.SetTag("stop_unixnano", query.End.UnixNano())
 		defer span.Finish()
 
-		//Currently hard coded as not used - applies to log queries
-		limit := 1000
-		//Currently hard coded as not used - applies to queries which produce a stream response
-		interval := time.Second * 1
-
-		value, err := client.QueryRange(query.Expr, limit, query.Start, query.End, logproto.BACKWARD, query.Step, interval, false)
+		// the interval isn't set, since it would make Loki skip the lines of log queries within the interval
+		value, err := client.QueryRange(query.Expr, query.MaxLines, query.Start, query.End, query.Direction, query.Step, 0, false)
 		if err != nil {
 			return plugins.DataResponse{}, err
 		}
@@ -131,6 +136,20 @@ func (e *LokiExecutor) parseQuery(dsInfo *models.DataSource, queryContext plugin
 		interval := e.intervalCalculator.Calculate(*queryContext.TimeRange, dsInterval)
 		step := time.Duration(int64(interval.Value))
 
+		maxLines, err := getMaxLines(dsInfo, queryModel.Model)
+		if err != nil {
+			return nil, err
+		}
+
+		direction := logproto.BACKWARD
+		if value := queryModel.Model.Get("direction").MustString(""); value != "" {
+			d, ok := logproto.Direction_value[strings.ToUpper(value)]
+			if !ok {
+				return nil, fmt.Errorf("invalid direction %q, must be forward or backward", value)
+			}
+			direction = logproto.Direction(d)
+		}
+
 		qs = append(qs, &lokiQuery{
 			Expr:         expr,
 			Step:         step,
@@ -138,22 +157,58 @@ func (e *LokiExecutor) parseQuery(dsInfo *models.DataSource, queryContext plugin
 			Start:        start,
 			End:          end,
 			RefID:        queryModel.RefID,
+			MaxLines:     maxLines,
+			Direction:    direction,
 		})
 	}
 
 	return qs, nil
 }
 
+// getMaxLines returns the maxLines of a query, or else of the data source, where it's stored as a string.
+func getMaxLines(dsInfo *models.DataSource, model *simplejson.Json) (int, error) {
+	if value, ok := model.CheckGet("maxLines"); ok {
+		maxLines, err := value.Int()
+		if err != nil || maxLines < 1 {
+			return 0, fmt.Errorf("invalid maxLines %v, must be a positive number", value.Interface())
+		}
+		return maxLines, nil
+	}
+
+	if dsInfo.JsonData != nil {
+		if value := dsInfo.JsonData.Get("maxLines"); value.Interface() != nil {
+			maxLines, err := value.Int()
+			if err != nil {
+				maxLines, err = strconv.Atoi(value.MustString())
+			}
+			if err == nil && maxLines > 0 {
+				return maxLines, nil
+			}
+		}
+	}
+
+	return defaultMaxLines, nil
+}
+
 func parseResponse(value *loghttp.QueryResponse, query *lokiQuery) (plugins.DataQueryResult, error) {
 	var queryRes plugins.DataQueryResult
 
-	//We are currently processing only matrix results (for alerting)
-	data, ok := value.Data.Result.(loghttp.Matrix)
+	// log queries return streams, metric queries return a matrix
+	if streams, ok := value.Data.Result.(loghttp.Streams); ok {
+		frame, err := streamsToFrame(streams, query)
+		if err != nil {
+			return queryRes, err
+		}
+		queryRes.Dataframes = plugins.NewDecodedDataFrames(data.Frames{frame})
+		return queryRes, nil
+	}
+
+	matrix, ok := value.Data.Result.(loghttp.Matrix)
 	if !ok {
 		return queryRes, fmt.Errorf("unsupported result format: %q", value.Data.ResultType)
 	}
 
-	for _, v := range data {
+	for _, v := range matrix {
 		series := plugins.DataTimeSeries{
 			Name:   formatLegend(v.Metric, query),
 			Tags:   make(map[string]string, len(v.Metric)),
@@ -175,3 +230,61 @@ func parseResponse(value *loghttp.QueryResponse, query *lokiQuery) (plugins.Data
 
 	return queryRes, nil
 }
+
+// streamsToFrame converts the streams of a log query into a logs frame with a row per line, ordered
+// in the direction of the query. The labels of the stream of a line are encoded as a JSON object, and
+// the timestamp of a line is also returned in nanoseconds as a string, to keep its precision.
+func streamsToFrame(streams loghttp.Streams, query *lokiQuery) (*data.Frame, error) {
+	type line struct {
+		timestamp time.Time
+		line      string
+		labels    string
+	}
+
+	lines := []line{}
+	for _, stream := range streams {
+		labels, err := json.Marshal(stream.Labels)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range stream.Entries {
+			lines = append(lines, line{timestamp: entry.Timestamp, line: entry.Line, labels: string(labels)})
+		}
+	}
+
+	sort.SliceStable(lines, func(i, j int) bool {
+		if query.Direction == logproto.FORWARD {
+			return lines[i].timestamp.Before(lines[j].timestamp)
+		}
+		return lines[i].timestamp.After(lines[j].timestamp)
+	})
+
+	timeField := data.NewField("ts", nil, make([]time.Time, len(lines)))
+	lineField := data.NewField("line", nil, make([]string, len(lines)))
+	labelsField := data.NewField("labels", nil, make([]string, len(lines)))
+	tsNsField := data.NewField("tsNs", nil, make([]string, len(lines)))
+	for i, l := range lines {
+		timeField.Set(i, l.timestamp)
+		lineField.Set(i, l.line)
+		labelsField.Set(i, l.labels)
+		tsNsField.Set(i, strconv.FormatInt(l.timestamp.UnixNano(), 10))
+	}
+
+	frame := data.NewFrame("", timeField, lineField, labelsField, tsNsField)
+	frame.RefID = query.RefID
+	frame.Meta = &data.FrameMeta{
+		PreferredVisualization: data.VisTypeLogs,
+		Custom: map[string]interface{}{
+			"limit":     query.MaxLines,
+			"direction": strings.ToLower(query.Direction.String()),
+		},
+	}
+	if len(lines) >= query.MaxLines {
+		frame.AppendNotices(data.Notice{
+			Severity: data.NoticeSeverityInfo,
+			Text:     fmt.Sprintf("The query returned the maximum of %d lines, there might be more lines in the time range", query.MaxLines),
+		})
+	}
+
+	return frame, nil
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/loki/pkg/loghttp"
+	"github.com/grafana/loki/pkg/logproto"
 	p "github.com/prometheus/common/model"
 	"github.com/stretchr/testify/require"
 )
@@ -94,3 +96,85 @@ func TestLoki(t *testing.T) {
 		require.Equal(t, time.Second*2, models[0].Step)
 	})
 }
+
+func TestLokiLogQueries(t *testing.T) {
+	parse := func(t *testing.T, dsInfo *models.DataSource, json string) ([]*lokiQuery, error) {
+		t.Helper()
+		jsonModel, err := simplejson.NewJson([]byte(json))
+		require.NoError(t, err)
+		timeRange := plugins.NewDataTimeRange("1h", "now")
+		return newExecutor().parseQuery(dsInfo, plugins.DataQuery{
+			TimeRange: &timeRange,
+			Queries:   []plugins.DataSubQuery{{Model: jsonModel}},
+		})
+	}
+
+	t.Run("parsing max lines and direction", func(t *testing.T) {
+		dsInfo := &models.DataSource{JsonData: simplejson.New()}
+		queries, err := parse(t, dsInfo, `{"expr": "{app=\"backend\"}"}`)
+		require.NoError(t, err)
+		require.Equal(t, 1000, queries[0].MaxLines)
+		require.Equal(t, logproto.BACKWARD, queries[0].Direction)
+
+		dsInfo.JsonData.Set("maxLines", "500")
+		queries, err = parse(t, dsInfo, `{"expr": "{app=\"backend\"}", "direction": "forward"}`)
+		require.NoError(t, err)
+		require.Equal(t, 500, queries[0].MaxLines)
+		require.Equal(t, logproto.FORWARD, queries[0].Direction)
+
+		queries, err = parse(t, dsInfo, `{"expr": "{app=\"backend\"}", "maxLines": 20}`)
+		require.NoError(t, err)
+		require.Equal(t, 20, queries[0].MaxLines)
+
+		_, err = parse(t, dsInfo, `{"expr": "{app=\"backend\"}", "maxLines": 0}`)
+		require.Error(t, err)
+
+		_, err = parse(t, dsInfo, `{"expr": "{app=\"backend\"}", "direction": "sideways"}`)
+		require.Error(t, err)
+	})
+
+	t.Run("converting streams to a log frame", func(t *testing.T) {
+		start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+		value := &loghttp.QueryResponse{
+			Data: loghttp.QueryResponseData{
+				ResultType: loghttp.ResultTypeStream,
+				Result: loghttp.Streams{
+					{
+						Labels: loghttp.LabelSet{"app": "backend"},
+						Entries: []loghttp.Entry{
+							{Timestamp: start.Add(3 * time.Second), Line: "third"},
+							{Timestamp: start.Add(1*time.Second + 5), Line: "first"},
+						},
+					},
+					{
+						Labels:  loghttp.LabelSet{"app": "frontend"},
+						Entries: []loghttp.Entry{{Timestamp: start.Add(2 * time.Second), Line: "second"}},
+					},
+				},
+			},
+		}
+
+		result, err := parseResponse(value, &lokiQuery{RefID: "A", MaxLines: 3, Direction: logproto.BACKWARD})
+		require.NoError(t, err)
+		frames, err := result.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, 3, frame.Rows())
+		require.Equal(t, "ts", frame.Fields[0].Name)
+		require.Equal(t, "third", frame.Fields[1].At(0))
+		require.Equal(t, "second", frame.Fields[1].At(1))
+		require.Equal(t, `{"app":"frontend"}`, frame.Fields[2].At(1))
+		require.Equal(t, "1577836801000000005", frame.Fields[3].At(2))
+		require.Len(t, frame.Meta.Notices, 1)
+
+		result, err = parseResponse(value, &lokiQuery{RefID: "A", MaxLines: 10, Direction: logproto.FORWARD})
+		require.NoError(t, err)
+		frames, err = result.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Equal(t, "first", frames[0].Fields[1].At(0))
+		require.Empty(t, frames[0].Meta.Notices)
+	})
+}
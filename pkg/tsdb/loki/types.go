@@ -1,6 +1,10 @@
 package loki
 
-import "time"
+import (
+	"time"
+
+	"github.com/grafana/loki/pkg/logproto"
+)
 
 type lokiQuery struct {
 	Expr         string
@@ -9,4 +13,9 @@ type lokiQuery struct {
 	Start        time.Time
 	End          time.Time
 	RefID        string
+
+	// MaxLines is the maximum number of lines that log queries return.
+	MaxLines int
+	// Direction is the order in which log queries search and return lines.
+	Direction logproto.Direction
 }
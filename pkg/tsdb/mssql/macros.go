@@ -95,6 +95,51 @@ func (m *msSQLMacroEngine) evaluateMacro(name string, args []string) (string, er
 			return tg + " AS [time]", nil
 		}
 		return "", err
+	case "__timeFilterTz":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+		from, err := sqleng.TimeFilterTzFrom(m.query, m.timeRange, args)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s BETWEEN '%s' AND '%s'", args[0], from.Format(time.RFC3339), m.timeRange.GetToAsTimeUTC().Format(time.RFC3339)), nil
+	case "__timeFilterRange":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+
+		return fmt.Sprintf("%s >= '%s' AND %s < '%s'", args[0], m.timeRange.GetFromAsTimeUTC().Format(time.RFC3339), args[0], m.timeRange.GetToAsTimeUTC().Format(time.RFC3339)), nil
+	case "__timeGroupTz":
+		if len(args) < 2 {
+			return "", fmt.Errorf("macro %v needs time column and interval", name)
+		}
+		interval, err := gtime.ParseInterval(strings.Trim(args[1], `'"`))
+		if err != nil {
+			return "", fmt.Errorf("error parsing interval %v", args[1])
+		}
+		loc, err := sqleng.QueryLocation(m.query)
+		if err != nil {
+			return "", err
+		}
+		if len(args) == 3 {
+			err := sqleng.SetupFillmode(m.query, interval, args[2])
+			if err != nil {
+				return "", err
+			}
+			sqleng.SetFillTimezone(m.query, loc)
+		}
+
+		return sqleng.TimeGroupTz(fmt.Sprintf("DATEDIFF(second, '1970-01-01', %s)", args[0]), interval, loc, m.timeRange, func(epoch string) string {
+			return fmt.Sprintf("FLOOR(%s/%.0f)*%.0f", epoch, interval.Seconds(), interval.Seconds())
+		}), nil
+	case "__timeGroupTzAlias":
+		tg, err := m.evaluateMacro("__timeGroupTz", args)
+		if err == nil {
+			return tg + " AS [time]", nil
+		}
+		return "", err
 	case "__unixEpochFilter":
 		if len(args) == 0 {
 			return "", fmt.Errorf("missing time column argument for macro %v", name)
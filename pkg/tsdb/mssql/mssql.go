@@ -20,7 +20,7 @@ import (
 
 var logger = log.New("tsdb.mssql")
 
-func NewExecutor(datasource *models.DataSource) (plugins.DataPlugin, error) {
+func NewExecutor(datasource *models.DataSource, settings setting.SQLDataSourcesSettings) (plugins.DataPlugin, error) {
 	cnnstr, err := generateConnectionString(datasource)
 	if err != nil {
 		return nil, err
@@ -35,6 +35,8 @@ func NewExecutor(datasource *models.DataSource) (plugins.DataPlugin, error) {
 		ConnectionString:  cnnstr,
 		Datasource:        datasource,
 		MetricColumnTypes: []string{"VARCHAR", "CHAR", "NVARCHAR", "NCHAR"},
+		RowLimit:          settings.RowLimit,
+		QueryTimeout:      settings.QueryTimeout,
 	}
 
 	queryResultTransformer := mssqlQueryResultTransformer{
@@ -13,6 +13,7 @@ import (
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/sqlstore/sqlutil"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/sqleng"
 	. "github.com/smartystreets/goconvey/convey"
 	"xorm.io/xorm"
@@ -45,7 +46,7 @@ func TestMSSQL(t *testing.T) {
 		endpoint, err := NewExecutor(&models.DataSource{
 			JsonData:       simplejson.New(),
 			SecureJsonData: securejsondata.SecureJsonData{},
-		})
+		}, setting.SQLDataSourcesSettings{})
 		So(err, ShouldBeNil)
 
 		sess := x.NewSession()
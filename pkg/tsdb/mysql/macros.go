@@ -100,6 +100,51 @@ func (m *mySQLMacroEngine) evaluateMacro(name string, args []string) (string, er
 			return tg + " AS \"time\"", nil
 		}
 		return "", err
+	case "__timeFilterTz":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+		from, err := sqleng.TimeFilterTzFrom(m.query, m.timeRange, args)
+		if err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("%s BETWEEN FROM_UNIXTIME(%d) AND FROM_UNIXTIME(%d)", args[0], from.Unix(), m.timeRange.GetToAsSecondsEpoch()), nil
+	case "__timeFilterRange":
+		if len(args) == 0 {
+			return "", fmt.Errorf("missing time column argument for macro %v", name)
+		}
+
+		return fmt.Sprintf("%s >= FROM_UNIXTIME(%d) AND %s < FROM_UNIXTIME(%d)", args[0], m.timeRange.GetFromAsSecondsEpoch(), args[0], m.timeRange.GetToAsSecondsEpoch()), nil
+	case "__timeGroupTz":
+		if len(args) < 2 {
+			return "", fmt.Errorf("macro %v needs time column and interval", name)
+		}
+		interval, err := gtime.ParseInterval(strings.Trim(args[1], `'"`))
+		if err != nil {
+			return "", fmt.Errorf("error parsing interval %v", args[1])
+		}
+		loc, err := sqleng.QueryLocation(m.query)
+		if err != nil {
+			return "", err
+		}
+		if len(args) == 3 {
+			err := sqleng.SetupFillmode(m.query, interval, args[2])
+			if err != nil {
+				return "", err
+			}
+			sqleng.SetFillTimezone(m.query, loc)
+		}
+
+		return sqleng.TimeGroupTz(fmt.Sprintf("UNIX_TIMESTAMP(%s)", args[0]), interval, loc, m.timeRange, func(epoch string) string {
+			return fmt.Sprintf("%s DIV %.0f * %.0f", epoch, interval.Seconds(), interval.Seconds())
+		}), nil
+	case "__timeGroupTzAlias":
+		tg, err := m.evaluateMacro("__timeGroupTz", args)
+		if err == nil {
+			return tg + " AS \"time\"", nil
+		}
+		return "", err
 	case "__unixEpochFilter":
 		if len(args) == 0 {
 			return "", fmt.Errorf("missing time column argument for macro %v", name)
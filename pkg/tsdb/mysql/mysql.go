@@ -24,7 +24,7 @@ func characterEscape(s string, escapeChar string) string {
 	return strings.ReplaceAll(s, escapeChar, url.QueryEscape(escapeChar))
 }
 
-func NewExecutor(datasource *models.DataSource) (plugins.DataPlugin, error) {
+func NewExecutor(datasource *models.DataSource, settings setting.SQLDataSourcesSettings) (plugins.DataPlugin, error) {
 	logger := log.New("tsdb.mysql")
 
 	protocol := "tcp"
@@ -63,6 +63,8 @@ func NewExecutor(datasource *models.DataSource) (plugins.DataPlugin, error) {
 		Datasource:        datasource,
 		TimeColumnNames:   []string{"time", "time_sec"},
 		MetricColumnTypes: []string{"CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT"},
+		RowLimit:          settings.RowLimit,
+		QueryTimeout:      settings.QueryTimeout,
 	}
 
 	rowTransformer := mysqlQueryResultTransformer{
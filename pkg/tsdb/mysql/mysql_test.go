@@ -16,6 +16,7 @@ import (
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/services/sqlstore"
 	"github.com/grafana/grafana/pkg/services/sqlstore/sqlutil"
+	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/sqleng"
 	"xorm.io/xorm"
 
@@ -55,7 +56,7 @@ func TestMySQL(t *testing.T) {
 		exe, err := NewExecutor(&models.DataSource{
 			JsonData:       simplejson.New(),
 			SecureJsonData: securejsondata.SecureJsonData{},
-		})
+		}, setting.SQLDataSourcesSettings{})
 		So(err, ShouldBeNil)
 
 		sess := x.NewSession()
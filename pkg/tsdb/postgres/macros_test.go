@@ -3,9 +3,11 @@ package postgres
 import (
 	"fmt"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/grafana/grafana/pkg/components/simplejson"
 	"github.com/grafana/grafana/pkg/plugins"
 	. "github.com/smartystreets/goconvey/convey"
 )
@@ -219,5 +221,86 @@ func TestMacroEngine(t *testing.T) {
 				So(sql, ShouldEqual, fmt.Sprintf("WHERE time_column BETWEEN '%s' AND '%s'", from.Format(time.RFC3339Nano), to.Format(time.RFC3339Nano)))
 			})
 		})
+
+		Convey("Given a time range across the daylight saving time change in the dashboard's time zone", func() {
+			// Berlin changes from UTC+1 to UTC+2 at 2021-03-28 01:00 UTC
+			from := time.Date(2021, 3, 27, 0, 0, 0, 0, time.UTC)
+			to := from.Add(48 * time.Hour)
+			timeRange := plugins.NewDataTimeRange(
+				strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10),
+				strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10))
+			tzQuery := plugins.DataSubQuery{Model: simplejson.NewFromAny(map[string]interface{}{"timezone": "Europe/Berlin"})}
+
+			Convey("interpolate __timeFilterTz function", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilterTz(time_column)")
+				So(err, ShouldBeNil)
+				expected, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilter(time_column)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "WHERE time_column BETWEEN '2021-03-27T00:00:00Z' AND '2021-03-29T00:00:00Z'")
+				So(sql, ShouldEqual, expected)
+			})
+
+			Convey("interpolate __timeFilterTz function with interval", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilterTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				// the range starts at midnight in Berlin
+				So(sql, ShouldEqual, "WHERE time_column BETWEEN '2021-03-26T23:00:00Z' AND '2021-03-29T00:00:00Z'")
+			})
+
+			Convey("interpolate __timeFilterTz and __timeGroupTz functions in the same query", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "SELECT $__timeGroupTz(time_column,'1d') WHERE $__timeFilterTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+				group, err := engine.Interpolate(tzQuery, timeRange, "$__timeGroupTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				// both take the column to store UTC times, and the filter starts at the start of the first group
+				So(sql, ShouldEqual, "SELECT "+group+" WHERE time_column BETWEEN '2021-03-26T23:00:00Z' AND '2021-03-29T00:00:00Z'")
+			})
+
+			Convey("interpolate __timeFilterRange function", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilterRange(time_column)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "WHERE time_column >= '2021-03-27T00:00:00Z' AND time_column < '2021-03-29T00:00:00Z'")
+			})
+
+			Convey("interpolate __timeGroupTz function", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "GROUP BY $__timeGroupTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+				sql2, err := engine.Interpolate(tzQuery, timeRange, "SELECT $__timeGroupTzAlias(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				start := "floor((extract(epoch from time_column) + CASE WHEN extract(epoch from time_column) < 1616893200 THEN 3600 ELSE 7200 END)/86400)*86400"
+				So(sql, ShouldEqual, fmt.Sprintf("GROUP BY (%s - CASE WHEN %s < 1616896800 THEN 3600 ELSE 7200 END)", start, start))
+				So(sql2, ShouldEqual, strings.Replace(sql, "GROUP BY", "SELECT", 1)+" AS \"time\"")
+			})
+
+			Convey("interpolate __timeGroupTz function with fill", func() {
+				_, err := engine.Interpolate(tzQuery, timeRange, "GROUP BY $__timeGroupTz(time_column,'1d',0)")
+				So(err, ShouldBeNil)
+
+				So(tzQuery.Model.Get("fillInterval").MustFloat64(), ShouldEqual, 86400)
+				So(tzQuery.Model.Get("fillTimezone").MustString(), ShouldEqual, "Europe/Berlin")
+			})
+
+			Convey("interpolate __timeGroupTz function in UTC", func() {
+				sql, err := engine.Interpolate(plugins.DataSubQuery{Model: simplejson.New()}, timeRange, "GROUP BY $__timeGroupTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+				expected, err := engine.Interpolate(plugins.DataSubQuery{Model: simplejson.New()}, timeRange, "GROUP BY $__timeGroup(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, expected)
+			})
+
+			Convey("interpolate __timeGroupTz function with an invalid time zone", func() {
+				badQuery := plugins.DataSubQuery{Model: simplejson.NewFromAny(map[string]interface{}{"timezone": "Mars/Olympus_Mons"})}
+				_, err := engine.Interpolate(badQuery, timeRange, "GROUP BY $__timeGroupTz(time_column,'1d')")
+
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldContainSubstring, "invalid time zone")
+			})
+		})
 	})
 }
@@ -54,6 +54,8 @@ func (s *PostgresService) NewExecutor(datasource *models.DataSource) (plugins.Da
 		ConnectionString:  cnnstr,
 		Datasource:        datasource,
 		MetricColumnTypes: []string{"UNKNOWN", "TEXT", "VARCHAR", "CHAR"},
+		RowLimit:          s.Cfg.SQLDataSources.RowLimit,
+		QueryTimeout:      s.Cfg.SQLDataSources.QueryTimeout,
 	}
 
 	queryResultTransformer := postgresQueryResultTransformer{
@@ -0,0 +1,120 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/prometheus/client_golang/api"
+	"github.com/prometheus/common/model"
+)
+
+// exemplarSeries are the exemplars of a series, as returned by the query_exemplars endpoint.
+type exemplarSeries struct {
+	SeriesLabels map[string]string `json:"seriesLabels"`
+	Exemplars    []struct {
+		Labels    map[string]string `json:"labels"`
+		Value     model.SampleValue `json:"value"`
+		Timestamp model.Time        `json:"timestamp"`
+	} `json:"exemplars"`
+}
+
+type exemplarResponse struct {
+	Status    string            `json:"status"`
+	Data      []*exemplarSeries `json:"data"`
+	ErrorType string            `json:"errorType"`
+	Error     string            `json:"error"`
+}
+
+// queryExemplars fetches the exemplars of the series of a query over its time range. The Prometheus client
+// doesn't support exemplars yet, so the endpoint is called directly.
+func queryExemplars(ctx context.Context, client api.Client, query *PrometheusQuery) ([]*exemplarSeries, error) {
+	u := client.URL("/api/v1/query_exemplars", nil)
+	q := u.Query()
+	q.Set("query", query.Expr)
+	q.Set("start", formatTime(query.Start))
+	q.Set("end", formatTime(query.End))
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	plog.Debug("Sending exemplar query", "start", query.Start, "end", query.End, "query", query.Expr)
+
+	resp, body, err := client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	var result exemplarResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse exemplars, status code %d: %w", resp.StatusCode, err)
+	}
+	if result.Status != "success" {
+		return nil, fmt.Errorf("failed to query exemplars: %s: %s", result.ErrorType, result.Error)
+	}
+
+	return result.Data, nil
+}
+
+func formatTime(t time.Time) string {
+	return strconv.FormatFloat(float64(t.Unix())+float64(t.Nanosecond())/1e9, 'f', -1, 64)
+}
+
+// exemplarFrame returns a frame with a row per exemplar, with a time and value field and a field per label of
+// the exemplars and their series.
+func exemplarFrame(series []*exemplarSeries, query *PrometheusQuery) *data.Frame {
+	labelNames := []string{}
+	seen := map[string]bool{}
+	addLabelNames := func(labels map[string]string) {
+		for name := range labels {
+			if !seen[name] {
+				seen[name] = true
+				labelNames = append(labelNames, name)
+			}
+		}
+	}
+	for _, s := range series {
+		addLabelNames(s.SeriesLabels)
+		for _, exemplar := range s.Exemplars {
+			addLabelNames(exemplar.Labels)
+		}
+	}
+	sort.Strings(labelNames)
+
+	timeField := data.NewField("Time", nil, []time.Time{})
+	valueField := data.NewField("Value", nil, []float64{})
+	labelFields := make([]*data.Field, len(labelNames))
+	for i, name := range labelNames {
+		labelFields[i] = data.NewField(name, nil, []string{})
+	}
+
+	for _, s := range series {
+		for _, exemplar := range s.Exemplars {
+			timeField.Append(exemplar.Timestamp.Time())
+			valueField.Append(float64(exemplar.Value))
+			for i, name := range labelNames {
+				// the labels of an exemplar take precedence over the labels of its series
+				value, ok := exemplar.Labels[name]
+				if !ok {
+					value = s.SeriesLabels[name]
+				}
+				labelFields[i].Append(value)
+			}
+		}
+	}
+
+	frame := data.NewFrame("exemplar", append([]*data.Field{timeField, valueField}, labelFields...)...)
+	frame.RefID = query.RefId
+	frame.Meta = &data.FrameMeta{
+		Custom: map[string]interface{}{"resultType": "exemplar"},
+	}
+	return frame
+}
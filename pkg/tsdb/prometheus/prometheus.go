@@ -6,13 +6,14 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/opentracing/opentracing-go"
 
 	"net/http"
 
-	"github.com/grafana/grafana/pkg/components/null"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
@@ -57,11 +58,14 @@ var (
 	legendFormat *regexp.Regexp = regexp.MustCompile(`\{\{\s*(.+?)\s*\}\}`)
 )
 
+// maxConcurrentQueries is the number of queries of a request that are sent to Prometheus at the same time.
+const maxConcurrentQueries = 10
+
 func init() {
 	plog = log.New("tsdb.prometheus")
 }
 
-func (e *PrometheusExecutor) getClient(dsInfo *models.DataSource) (apiv1.API, error) {
+func (e *PrometheusExecutor) getClient(dsInfo *models.DataSource) (api.Client, error) {
 	cfg := api.Config{
 		Address:      dsInfo.Url,
 		RoundTripper: e.Transport,
@@ -75,12 +79,7 @@ func (e *PrometheusExecutor) getClient(dsInfo *models.DataSource) (apiv1.API, er
 		}
 	}
 
-	client, err := api.NewClient(cfg)
-	if err != nil {
-		return nil, err
-	}
-
-	return apiv1.NewAPI(client), nil
+	return api.NewClient(cfg)
 }
 
 func (e *PrometheusExecutor) DataQuery(ctx context.Context, dsInfo *models.DataSource,
@@ -99,7 +98,65 @@ func (e *PrometheusExecutor) DataQuery(ctx context.Context, dsInfo *models.DataS
 		return result, err
 	}
 
-	for _, query := range queries {
+	queryResults := make([]plugins.DataQueryResult, len(queries))
+	runConcurrently(len(queries), maxConcurrentQueries, func(i int) {
+		queryResults[i] = e.runQuery(ctx, client, queries[i])
+	})
+
+	for _, queryResult := range queryResults {
+		result.Results[queryResult.RefID] = queryResult
+	}
+
+	return result, nil
+}
+
+// runConcurrently calls run for the indexes up to count, from at most workers goroutines at a time.
+func runConcurrently(count int, workers int, run func(i int)) {
+	if workers > count {
+		workers = count
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				run(i)
+			}
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// runQuery runs the range and instant queries and fetches the exemplars of a query. Errors are returned
+// in the result of the query, so that they don't fail the other queries of the request.
+func (e *PrometheusExecutor) runQuery(ctx context.Context, client api.Client, query *PrometheusQuery) (queryResult plugins.DataQueryResult) {
+	queryResult.RefID = query.RefId
+
+	defer func() {
+		if err := recover(); err != nil {
+			plog.Error("Prometheus query panic", "error", err, "stack", log.Stack(1))
+			queryResult.Error = fmt.Errorf("query failed: %v", err)
+		}
+	}()
+
+	span, ctx := opentracing.StartSpanFromContext(ctx, "alerting.prometheus")
+	span.SetTag("expr", query.Expr)
+	span.SetTag("start_unixnano", query.Start.UnixNano())
+	span.SetTag("stop_unixnano", query.End.UnixNano())
+	defer span.Finish()
+
+	promAPI := apiv1.NewAPI(client)
+	frames := data.Frames{}
+
+	if query.RangeQuery {
 		timeRange := apiv1.Range{
 			Start: query.Start,
 			End:   query.End,
@@ -108,26 +165,49 @@ func (e *PrometheusExecutor) DataQuery(ctx context.Context, dsInfo *models.DataS
 
 		plog.Debug("Sending query", "start", timeRange.Start, "end", timeRange.End, "step", timeRange.Step, "query", query.Expr)
 
-		span, ctx := opentracing.StartSpanFromContext(ctx, "alerting.prometheus")
-		span.SetTag("expr", query.Expr)
-		span.SetTag("start_unixnano", query.Start.UnixNano())
-		span.SetTag("stop_unixnano", query.End.UnixNano())
-		defer span.Finish()
+		value, _, err := promAPI.QueryRange(ctx, query.Expr, timeRange)
+		if err != nil {
+			queryResult.Error = err
+			return
+		}
+
+		rangeFrames, err := parseResponse(value, query)
+		if err != nil {
+			queryResult.Error = err
+			return
+		}
+		frames = append(frames, rangeFrames...)
+	}
 
-		value, _, err := client.QueryRange(ctx, query.Expr, timeRange)
+	if query.InstantQuery {
+		plog.Debug("Sending instant query", "time", query.End, "query", query.Expr)
 
+		value, _, err := promAPI.Query(ctx, query.Expr, query.End)
 		if err != nil {
-			return result, err
+			queryResult.Error = err
+			return
 		}
 
-		queryResult, err := parseResponse(value, query)
+		instantFrames, err := parseResponse(value, query)
 		if err != nil {
-			return result, err
+			queryResult.Error = err
+			return
 		}
-		result.Results[query.RefId] = queryResult
+		frames = append(frames, instantFrames...)
 	}
 
-	return result, nil
+	if query.ExemplarQuery {
+		// Prometheus servers without exemplar storage fail exemplar queries, which shouldn't fail the query
+		exemplars, err := queryExemplars(ctx, client, query)
+		if err != nil {
+			plog.Warn("Failed to fetch exemplars", "query", query.Expr, "error", err)
+		} else if len(exemplars) > 0 {
+			frames = append(frames, exemplarFrame(exemplars, query))
+		}
+	}
+
+	queryResult.Dataframes = plugins.NewDecodedDataFrames(frames)
+	return
 }
 
 func formatLegend(metric model.Metric, query *PrometheusQuery) string {
@@ -151,6 +231,7 @@ func formatLegend(metric model.Metric, query *PrometheusQuery) string {
 func (e *PrometheusExecutor) parseQuery(dsInfo *models.DataSource, query plugins.DataQuery) (
 	[]*PrometheusQuery, error) {
 	qs := []*PrometheusQuery{}
+	_, fromAlert := query.Headers["FromAlert"]
 	for _, queryModel := range query.Queries {
 		expr, err := queryModel.Model.Get("expr").String()
 		if err != nil {
@@ -178,49 +259,84 @@ func (e *PrometheusExecutor) parseQuery(dsInfo *models.DataSource, query plugins
 		interval := e.intervalCalculator.Calculate(*query.TimeRange, dsInterval)
 		step := time.Duration(int64(interval.Value) * intervalFactor)
 
+		rangeQuery := queryModel.Model.Get("range").MustBool(false)
+		instantQuery := queryModel.Model.Get("instant").MustBool(false)
+		// queries are range queries unless they are only instant queries
+		if !instantQuery {
+			rangeQuery = true
+		}
+		exemplarQuery := queryModel.Model.Get("exemplar").MustBool(false)
+		// alerts reduce the series of a single frame type and have no use for exemplars
+		if fromAlert {
+			instantQuery = instantQuery && !rangeQuery
+			exemplarQuery = false
+		}
+
 		qs = append(qs, &PrometheusQuery{
-			Expr:         expr,
-			Step:         step,
-			LegendFormat: format,
-			Start:        start,
-			End:          end,
-			RefId:        queryModel.RefID,
+			Expr:          expr,
+			Step:          step,
+			LegendFormat:  format,
+			Start:         start,
+			End:           end,
+			RefId:         queryModel.RefID,
+			RangeQuery:    rangeQuery,
+			InstantQuery:  instantQuery,
+			ExemplarQuery: exemplarQuery,
 		})
 	}
 
 	return qs, nil
 }
 
-func parseResponse(value model.Value, query *PrometheusQuery) (plugins.DataQueryResult, error) {
-	var queryRes plugins.DataQueryResult
-
-	data, ok := value.(model.Matrix)
-	if !ok {
-		return queryRes, fmt.Errorf("unsupported result format: %q", value.Type().String())
-	}
-
-	for _, v := range data {
-		series := plugins.DataTimeSeries{
-			Name:   formatLegend(v.Metric, query),
-			Tags:   make(map[string]string, len(v.Metric)),
-			Points: make([]plugins.DataTimePoint, 0, len(v.Values)),
+// parseResponse converts the result of a range or instant query into data frames with a time field and
+// a value field per series.
+func parseResponse(value model.Value, query *PrometheusQuery) (data.Frames, error) {
+	frames := data.Frames{}
+
+	switch v := value.(type) {
+	case model.Matrix:
+		for _, series := range v {
+			times := make([]time.Time, 0, len(series.Values))
+			values := make([]float64, 0, len(series.Values))
+			for _, pair := range series.Values {
+				times = append(times, pair.Timestamp.Time())
+				values = append(values, float64(pair.Value))
+			}
+			frames = append(frames, newSeriesFrame(series.Metric, times, values, query, "matrix"))
 		}
-
-		for k, v := range v.Metric {
-			series.Tags[string(k)] = string(v)
+	case model.Vector:
+		for _, sample := range v {
+			frames = append(frames, newSeriesFrame(sample.Metric, []time.Time{sample.Timestamp.Time()},
+				[]float64{float64(sample.Value)}, query, "vector"))
 		}
-
-		for _, k := range v.Values {
-			series.Points = append(series.Points, plugins.DataTimePoint{
-				null.FloatFrom(float64(k.Value)),
-				null.FloatFrom(float64(k.Timestamp.Unix() * 1000)),
-			})
+	case *model.Scalar:
+		frame := newSeriesFrame(model.Metric{}, []time.Time{v.Timestamp.Time()}, []float64{float64(v.Value)}, query, "scalar")
+		if query.LegendFormat == "" {
+			frame.Fields[1].Config.DisplayNameFromDS = query.Expr
 		}
+		frames = append(frames, frame)
+	default:
+		return nil, fmt.Errorf("unsupported result format: %q", value.Type().String())
+	}
+
+	return frames, nil
+}
 
-		queryRes.Series = append(queryRes.Series, series)
+func newSeriesFrame(metric model.Metric, times []time.Time, values []float64, query *PrometheusQuery, resultType string) *data.Frame {
+	labels := make(data.Labels, len(metric))
+	for k, v := range metric {
+		labels[string(k)] = string(v)
 	}
 
-	return queryRes, nil
+	valueField := data.NewField("Value", labels, values)
+	valueField.SetConfig(&data.FieldConfig{DisplayNameFromDS: formatLegend(metric, query)})
+
+	frame := data.NewFrame("", data.NewField("Time", nil, times), valueField)
+	frame.RefID = query.RefId
+	frame.Meta = &data.FrameMeta{
+		Custom: map[string]interface{}{"resultType": resultType},
+	}
+	return frame
 }
 
 // IsAPIError returns whether err is or wraps a Prometheus error.
@@ -1,6 +1,12 @@
 package prometheus
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -142,3 +148,213 @@ func TestPrometheus(t *testing.T) {
 		require.Equal(t, time.Minute*2, models[0].Step)
 	})
 }
+
+func TestPrometheusQueryTypes(t *testing.T) {
+	dsInfo := &models.DataSource{
+		JsonData: simplejson.New(),
+	}
+	plug, err := NewExecutor(dsInfo)
+	require.NoError(t, err)
+	executor := plug.(*PrometheusExecutor)
+
+	parseWithHeaders := func(t *testing.T, json string, headers map[string]string) *PrometheusQuery {
+		t.Helper()
+		jsonModel, err := simplejson.NewJson([]byte(json))
+		require.NoError(t, err)
+
+		timeRange := plugins.NewDataTimeRange("1h", "now")
+		queries, err := executor.parseQuery(dsInfo, plugins.DataQuery{
+			TimeRange: &timeRange,
+			Queries:   []plugins.DataSubQuery{{Model: jsonModel}},
+			Headers:   headers,
+		})
+		require.NoError(t, err)
+		return queries[0]
+	}
+	parse := func(t *testing.T, json string) *PrometheusQuery {
+		t.Helper()
+		return parseWithHeaders(t, json, nil)
+	}
+
+	t.Run("queries are range queries by default", func(t *testing.T) {
+		query := parse(t, `{"expr": "go_goroutines", "refId": "A"}`)
+		require.True(t, query.RangeQuery)
+		require.False(t, query.InstantQuery)
+		require.False(t, query.ExemplarQuery)
+	})
+
+	t.Run("instant queries", func(t *testing.T) {
+		query := parse(t, `{"expr": "go_goroutines", "instant": true, "exemplar": true, "refId": "A"}`)
+		require.False(t, query.RangeQuery)
+		require.True(t, query.InstantQuery)
+		require.True(t, query.ExemplarQuery)
+	})
+
+	t.Run("range and instant queries", func(t *testing.T) {
+		query := parse(t, `{"expr": "go_goroutines", "instant": true, "range": true, "refId": "A"}`)
+		require.True(t, query.RangeQuery)
+		require.True(t, query.InstantQuery)
+	})
+
+	t.Run("alert queries are either range or instant queries without exemplars", func(t *testing.T) {
+		fromAlert := map[string]string{"FromAlert": "true"}
+
+		query := parseWithHeaders(t, `{"expr": "go_goroutines", "instant": true, "range": true, "exemplar": true, "refId": "A"}`, fromAlert)
+		require.True(t, query.RangeQuery)
+		require.False(t, query.InstantQuery)
+		require.False(t, query.ExemplarQuery)
+
+		query = parseWithHeaders(t, `{"expr": "go_goroutines", "instant": true, "exemplar": true, "refId": "A"}`, fromAlert)
+		require.False(t, query.RangeQuery)
+		require.True(t, query.InstantQuery)
+		require.False(t, query.ExemplarQuery)
+	})
+}
+
+func TestPrometheusParseResponse(t *testing.T) {
+	query := &PrometheusQuery{RefId: "A", Expr: "1+1", LegendFormat: "{{app}}"}
+	now := p.TimeFromUnix(1600000000)
+
+	t.Run("matrix", func(t *testing.T) {
+		frames, err := parseResponse(p.Matrix{
+			{
+				Metric: p.Metric{"app": "backend"},
+				Values: []p.SamplePair{{Timestamp: now, Value: 1}, {Timestamp: now.Add(time.Minute), Value: 2}},
+			},
+		}, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		require.Equal(t, "A", frame.RefID)
+		require.Equal(t, 2, frame.Rows())
+		require.Equal(t, "backend", frame.Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, "backend", frame.Fields[1].Labels["app"])
+		require.Equal(t, 2.0, frame.Fields[1].At(1))
+		require.Equal(t, now.Time().UTC(), frame.Fields[0].At(0).(time.Time).UTC())
+	})
+
+	t.Run("vector", func(t *testing.T) {
+		frames, err := parseResponse(p.Vector{
+			{Metric: p.Metric{"app": "backend"}, Timestamp: now, Value: 3},
+			{Metric: p.Metric{"app": "frontend"}, Timestamp: now, Value: 4},
+		}, query)
+		require.NoError(t, err)
+		require.Len(t, frames, 2)
+		require.Equal(t, 1, frames[1].Rows())
+		require.Equal(t, "frontend", frames[1].Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, 4.0, frames[1].Fields[1].At(0))
+	})
+
+	t.Run("scalar", func(t *testing.T) {
+		frames, err := parseResponse(&p.Scalar{Timestamp: now, Value: 2}, &PrometheusQuery{RefId: "A", Expr: "1+1"})
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		require.Equal(t, "1+1", frames[0].Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, 2.0, frames[0].Fields[1].At(0))
+	})
+
+	t.Run("strings aren't supported", func(t *testing.T) {
+		_, err := parseResponse(&p.String{Timestamp: now, Value: "a"}, query)
+		require.Error(t, err)
+	})
+}
+
+func TestPrometheusDataQuery(t *testing.T) {
+	var running, maxRunning int32
+	var mu sync.Mutex
+	paths := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		mu.Lock()
+		paths[r.URL.Path]++
+		if current > maxRunning {
+			maxRunning = current
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+
+		require.NoError(t, r.ParseForm())
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Form.Get("query") == "broken":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		case r.URL.Path == "/api/v1/query_range":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"matrix","result":[
+				{"metric":{"__name__":"up","job":"prometheus"},"values":[[1600000000,"1"],[1600000060,"1"]]}]}}`))
+		case r.URL.Path == "/api/v1/query":
+			_, _ = w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{"__name__":"up","job":"prometheus"},"value":[1600000060,"1"]}]}}`))
+		case r.URL.Path == "/api/v1/query_exemplars":
+			_, _ = w.Write([]byte(`{"status":"success","data":[{"seriesLabels":{"__name__":"up","job":"prometheus"},
+				"exemplars":[{"labels":{"traceID":"abc"},"value":"0.5","timestamp":1600000030.5}]}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	dsInfo := &models.DataSource{
+		Url:      server.URL,
+		JsonData: simplejson.New(),
+	}
+	plug, err := NewExecutor(dsInfo)
+	require.NoError(t, err)
+
+	subQuery := func(refID string, json string) plugins.DataSubQuery {
+		model, err := simplejson.NewJson([]byte(json))
+		require.NoError(t, err)
+		return plugins.DataSubQuery{RefID: refID, Model: model}
+	}
+
+	queries := []plugins.DataSubQuery{
+		subQuery("A", `{"expr": "up", "range": true, "instant": true, "exemplar": true}`),
+		subQuery("B", `{"expr": "broken"}`),
+	}
+	for i := 0; i < 2*maxConcurrentQueries; i++ {
+		queries = append(queries, subQuery(fmt.Sprintf("C%d", i), `{"expr": "up"}`))
+	}
+
+	timeRange := plugins.NewDataTimeRange("1h", "now")
+	resp, err := plug.DataQuery(context.Background(), dsInfo, plugins.DataQuery{
+		TimeRange: &timeRange,
+		Queries:   queries,
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, len(queries))
+	require.LessOrEqual(t, maxRunning, int32(maxConcurrentQueries))
+
+	t.Run("range, instant and exemplar results are returned as frames", func(t *testing.T) {
+		result := resp.Results["A"]
+		require.NoError(t, result.Error)
+
+		frames, err := result.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 3)
+		require.Equal(t, "matrix", frames[0].Meta.Custom.(map[string]interface{})["resultType"])
+		require.Equal(t, 2, frames[0].Rows())
+		require.Equal(t, `up{job="prometheus"}`, frames[0].Fields[1].Config.DisplayNameFromDS)
+		require.Equal(t, "vector", frames[1].Meta.Custom.(map[string]interface{})["resultType"])
+		require.Equal(t, 1, frames[1].Rows())
+
+		exemplars := frames[2]
+		require.Equal(t, "exemplar", exemplars.Name)
+		require.Equal(t, 1, exemplars.Rows())
+		require.Equal(t, 0.5, exemplars.Fields[1].At(0))
+		// the label fields are sorted by name
+		require.Len(t, exemplars.Fields, 5)
+		require.Equal(t, "job", exemplars.Fields[3].Name)
+		require.Equal(t, "prometheus", exemplars.Fields[3].At(0))
+		require.Equal(t, "traceID", exemplars.Fields[4].Name)
+		require.Equal(t, "abc", exemplars.Fields[4].At(0))
+	})
+
+	t.Run("errors are returned per query", func(t *testing.T) {
+		require.Error(t, resp.Results["B"].Error)
+		require.True(t, IsAPIError(resp.Results["B"].Error))
+		require.NoError(t, resp.Results["C0"].Error)
+	})
+}
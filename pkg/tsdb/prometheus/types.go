@@ -9,4 +9,11 @@ type PrometheusQuery struct {
 	Start        time.Time
 	End          time.Time
 	RefId        string
+
+	// RangeQuery runs the query over the time range, which is the default.
+	RangeQuery bool
+	// InstantQuery runs the query at the end of the time range.
+	InstantQuery bool
+	// ExemplarQuery fetches the exemplars of the series of the query.
+	ExemplarQuery bool
 }
@@ -0,0 +1,374 @@
+package tsdb
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/infra/metrics"
+	"github.com/grafana/grafana/pkg/infra/remotecache"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/setting"
+)
+
+// QueryCacheSkipHeader is the HTTP header with which query requests skip the query cache, e.g. for live
+// queries.
+const QueryCacheSkipHeader = "X-Cache-Skip"
+
+type skipQueryCacheKey struct{}
+
+// ContextWithoutQueryCache returns a context for query requests that shouldn't use the query cache.
+func ContextWithoutQueryCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipQueryCacheKey{}, true)
+}
+
+func isQueryCacheSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipQueryCacheKey{}).(bool)
+	return skip
+}
+
+// queryCache caches the responses of query requests to data sources that have query caching enabled.
+type queryCache struct {
+	log        log.Logger
+	storage    remotecache.CacheStorage
+	defaultTTL time.Duration
+}
+
+func newQueryCache(cfg setting.QueryCachingSettings, remoteCache *remotecache.RemoteCache) *queryCache {
+	if !cfg.Enabled {
+		return nil
+	}
+
+	var storage remotecache.CacheStorage = newMemoryCache(cfg.MaxItems)
+	if cfg.Backend == "remote_cache" {
+		storage = remoteCache
+	}
+
+	return &queryCache{
+		log:        log.New("tsdb.querycache"),
+		storage:    storage,
+		defaultTTL: cfg.DefaultTTL,
+	}
+}
+
+// ttl returns how long the responses of queries to a data source are cached, zero if they aren't.
+func (c *queryCache) ttl(ctx context.Context, ds *models.DataSource, query plugins.DataQuery) time.Duration {
+	if c == nil || ds.JsonData == nil || !ds.JsonData.Get("queryCachingEnabled").MustBool(false) {
+		return 0
+	}
+
+	// the responses of data sources that forward the user's identity depend on the user
+	if ds.JsonData.Get("oauthPassThru").MustBool(false) {
+		return 0
+	}
+
+	if isQueryCacheSkipped(ctx) || query.Debug || query.TimeRange == nil {
+		return 0
+	}
+	if _, fromAlert := query.Headers["FromAlert"]; fromAlert {
+		return 0
+	}
+
+	ttl := c.defaultTTL
+	if value := ds.JsonData.Get("queryCachingTTL").MustString(""); value != "" {
+		var err error
+		if ttl, err = gtime.ParseDuration(value); err != nil {
+			c.log.Warn("Invalid query caching TTL", "datasource", ds.Uid, "ttl", value)
+			return 0
+		}
+	}
+
+	if ttl < 0 {
+		return 0
+	}
+	return ttl
+}
+
+// key returns the cache key of a query request. Relative time ranges are aligned to the TTL, or to their
+// own length if that's shorter, so that requests for the same relative range that are sent within the TTL
+// share their response. Absolute time ranges are used as they are.
+func (c *queryCache) key(ds *models.DataSource, query plugins.DataQuery, ttl time.Duration) (string, error) {
+	from, err := query.TimeRange.ParseFrom()
+	if err != nil {
+		return "", err
+	}
+	to, err := query.TimeRange.ParseTo()
+	if err != nil {
+		return "", err
+	}
+
+	if isRelativeTimeRange(*query.TimeRange) {
+		step := ttl
+		if length := to.Sub(from); length > 0 && length < step {
+			step = length
+		}
+		from, to = from.Truncate(step), to.Truncate(step)
+	}
+
+	type cacheKeyQuery struct {
+		RefID         string
+		QueryType     string
+		MaxDataPoints int64
+		IntervalMS    int64
+		Model         map[string]interface{}
+	}
+
+	queries := make([]cacheKeyQuery, 0, len(query.Queries))
+	for _, q := range query.Queries {
+		model := map[string]interface{}{}
+		if q.Model != nil {
+			for k, v := range q.Model.MustMap() {
+				model[k] = v
+			}
+		}
+		// the request id differs between otherwise identical requests
+		delete(model, "requestId")
+
+		queries = append(queries, cacheKeyQuery{
+			RefID:         q.RefID,
+			QueryType:     q.QueryType,
+			MaxDataPoints: q.MaxDataPoints,
+			IntervalMS:    q.IntervalMS,
+			Model:         model,
+		})
+	}
+
+	// uids are only unique per org, so the org and id are part of the key to never share responses across orgs
+	b, err := json.Marshal(struct {
+		OrgID             int64
+		DataSourceID      int64
+		DataSourceUID     string
+		DataSourceVersion int
+		From              int64
+		To                int64
+		Queries           []cacheKeyQuery
+	}{
+		OrgID:             ds.OrgId,
+		DataSourceID:      ds.Id,
+		DataSourceUID:     ds.Uid,
+		DataSourceVersion: ds.Version,
+		From:              from.UnixNano(),
+		To:                to.UnixNano(),
+		Queries:           queries,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	hash := sha256.Sum256(b)
+	return "query-cache-" + hex.EncodeToString(hash[:]), nil
+}
+
+// isRelativeTimeRange returns true if the time range depends on the time of the request, i.e. isn't given
+// as Unix times in milliseconds.
+func isRelativeTimeRange(timeRange plugins.DataTimeRange) bool {
+	for _, value := range []string{timeRange.From, timeRange.To} {
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// cachedResult is a query result as it is stored in the cache.
+type cachedResult struct {
+	RefID      string                      `json:"refId"`
+	Meta       *simplejson.Json            `json:"meta,omitempty"`
+	Series     plugins.DataTimeSeriesSlice `json:"series,omitempty"`
+	Tables     []plugins.DataTable         `json:"tables,omitempty"`
+	Dataframes [][]byte                    `json:"dataframes,omitempty"`
+}
+
+type cachedResponse struct {
+	Message string         `json:"message,omitempty"`
+	Results []cachedResult `json:"results"`
+}
+
+func (c *queryCache) get(key string) (plugins.DataResponse, bool) {
+	value, err := c.storage.Get(key)
+	if err != nil {
+		if !errors.Is(err, remotecache.ErrCacheItemNotFound) {
+			c.log.Warn("Failed to read query response from cache", "error", err)
+		}
+		return plugins.DataResponse{}, false
+	}
+
+	b, ok := value.([]byte)
+	if !ok {
+		return plugins.DataResponse{}, false
+	}
+
+	var cached cachedResponse
+	if err := json.Unmarshal(b, &cached); err != nil {
+		c.log.Warn("Failed to decode cached query response", "error", err)
+		return plugins.DataResponse{}, false
+	}
+
+	resp := plugins.DataResponse{
+		Message: cached.Message,
+		Results: make(map[string]plugins.DataQueryResult, len(cached.Results)),
+	}
+	for _, result := range cached.Results {
+		queryResult := plugins.DataQueryResult{
+			RefID:  result.RefID,
+			Meta:   result.Meta,
+			Series: result.Series,
+			Tables: result.Tables,
+		}
+		if result.Dataframes != nil {
+			queryResult.Dataframes = plugins.NewEncodedDataFrames(result.Dataframes)
+		}
+		resp.Results[result.RefID] = queryResult
+	}
+	return resp, true
+}
+
+// set stores a query response in the cache. Responses with errors aren't cached.
+func (c *queryCache) set(key string, resp plugins.DataResponse, ttl time.Duration) {
+	cached := cachedResponse{
+		Message: resp.Message,
+		Results: make([]cachedResult, 0, len(resp.Results)),
+	}
+	for refID, result := range resp.Results {
+		if result.Error != nil || result.ErrorString != "" {
+			return
+		}
+
+		cachedResult := cachedResult{
+			RefID:  refID,
+			Meta:   result.Meta,
+			Series: result.Series,
+			Tables: result.Tables,
+		}
+		if result.Dataframes != nil {
+			encoded, err := result.Dataframes.Encoded()
+			if err != nil {
+				c.log.Warn("Failed to encode query response for cache", "error", err)
+				return
+			}
+			cachedResult.Dataframes = encoded
+		}
+		cached.Results = append(cached.Results, cachedResult)
+	}
+
+	b, err := json.Marshal(cached)
+	if err != nil {
+		c.log.Warn("Failed to encode query response for cache", "error", err)
+		return
+	}
+
+	if err := c.storage.Set(key, b, ttl); err != nil {
+		c.log.Warn("Failed to write query response to cache", "error", err)
+	}
+}
+
+// handleRequest returns the cached response of a query request, or calls handle and caches its response.
+func (c *queryCache) handleRequest(ctx context.Context, ds *models.DataSource, query plugins.DataQuery,
+	handle func() (plugins.DataResponse, error)) (plugins.DataResponse, error) {
+	ttl := c.ttl(ctx, ds, query)
+	if ttl == 0 {
+		return handle()
+	}
+
+	key, err := c.key(ds, query, ttl)
+	if err != nil {
+		return handle()
+	}
+
+	if resp, ok := c.get(key); ok {
+		metrics.MDataSourceQueryCacheRequestsTotal.WithLabelValues(ds.Type, "hit").Inc()
+		return resp, nil
+	}
+	metrics.MDataSourceQueryCacheRequestsTotal.WithLabelValues(ds.Type, "miss").Inc()
+
+	resp, err := handle()
+	if err != nil {
+		return resp, err
+	}
+
+	c.set(key, resp, ttl)
+	return resp, nil
+}
+
+// memoryCache is an in-memory least recently used cache.
+type memoryCache struct {
+	mu       sync.Mutex
+	maxItems int
+	items    map[string]*list.Element
+	order    *list.List
+}
+
+type memoryCacheItem struct {
+	key     string
+	value   interface{}
+	expires time.Time
+}
+
+func newMemoryCache(maxItems int) *memoryCache {
+	return &memoryCache{
+		maxItems: maxItems,
+		items:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+}
+
+func (c *memoryCache) Get(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return nil, remotecache.ErrCacheItemNotFound
+	}
+
+	item := element.Value.(*memoryCacheItem)
+	if time.Now().After(item.expires) {
+		c.remove(element)
+		return nil, remotecache.ErrCacheItemNotFound
+	}
+
+	c.order.MoveToFront(element)
+	return item.value, nil
+}
+
+func (c *memoryCache) Set(key string, value interface{}, expire time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+
+	item := &memoryCacheItem{key: key, value: value, expires: time.Now().Add(expire)}
+	c.items[key] = c.order.PushFront(item)
+
+	for c.maxItems > 0 && c.order.Len() > c.maxItems {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *memoryCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.remove(element)
+	}
+	return nil
+}
+
+func (c *memoryCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.items, element.Value.(*memoryCacheItem).key)
+}
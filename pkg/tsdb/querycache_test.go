@@ -0,0 +1,183 @@
+package tsdb
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryCache(t *testing.T) {
+	newRequest := func(expr string) plugins.DataQuery {
+		timeRange := plugins.NewDataTimeRange("1600000000000", "1600003600000")
+		return plugins.DataQuery{
+			TimeRange: &timeRange,
+			Queries: []plugins.DataSubQuery{
+				{RefID: "A", Model: simplejson.NewFromAny(map[string]interface{}{"expr": expr, "requestId": time.Now().String()})},
+			},
+		}
+	}
+
+	setup := func(t *testing.T, jsonData map[string]interface{}) (Service, *models.DataSource, *int) {
+		t.Helper()
+
+		svc, exe := createService()
+		svc.queryCache = newQueryCache(setting.QueryCachingSettings{
+			Enabled: true, Backend: "memory", MaxItems: 10, DefaultTTL: time.Minute,
+		}, nil)
+
+		calls := 0
+		exe.HandleQuery("A", func(query plugins.DataQuery) plugins.DataQueryResult {
+			calls++
+			frame := data.NewFrame("", data.NewField("value", nil, []float64{float64(calls)}))
+			return plugins.DataQueryResult{RefID: "A", Dataframes: plugins.NewDecodedDataFrames(data.Frames{frame})}
+		})
+
+		ds := &models.DataSource{Id: 1, Uid: "uid", Type: "test", JsonData: simplejson.NewFromAny(jsonData)}
+		return svc, ds, &calls
+	}
+
+	firstValue := func(t *testing.T, resp plugins.DataResponse) float64 {
+		t.Helper()
+		frames, err := resp.Results["A"].Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+		return frames[0].Fields[0].At(0).(float64)
+	}
+
+	t.Run("Identical requests to data sources with query caching are answered from the cache", func(t *testing.T) {
+		svc, ds, calls := setup(t, map[string]interface{}{"queryCachingEnabled": true})
+
+		for i := 0; i < 3; i++ {
+			resp, err := svc.HandleRequest(context.Background(), ds, newRequest("up"))
+			require.NoError(t, err)
+			assert.Equal(t, float64(1), firstValue(t, resp))
+		}
+		assert.Equal(t, 1, *calls)
+
+		resp, err := svc.HandleRequest(context.Background(), ds, newRequest("down"))
+		require.NoError(t, err)
+		assert.Equal(t, float64(2), firstValue(t, resp))
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Data sources of different orgs with the same uid aren't answered from each other's response", func(t *testing.T) {
+		svc, ds, calls := setup(t, map[string]interface{}{"queryCachingEnabled": true})
+		ds.OrgId = 1
+		other := &models.DataSource{Id: 2, OrgId: 2, Uid: ds.Uid, Type: ds.Type, Version: ds.Version, JsonData: ds.JsonData}
+
+		resp, err := svc.HandleRequest(context.Background(), ds, newRequest("up"))
+		require.NoError(t, err)
+		assert.Equal(t, float64(1), firstValue(t, resp))
+
+		resp, err = svc.HandleRequest(context.Background(), other, newRequest("up"))
+		require.NoError(t, err)
+		assert.Equal(t, float64(2), firstValue(t, resp))
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Requests aren't cached for data sources without query caching", func(t *testing.T) {
+		svc, ds, calls := setup(t, map[string]interface{}{})
+
+		for i := 0; i < 2; i++ {
+			_, err := svc.HandleRequest(context.Background(), ds, newRequest("up"))
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Requests that skip the cache and alert queries aren't answered from the cache", func(t *testing.T) {
+		svc, ds, calls := setup(t, map[string]interface{}{"queryCachingEnabled": true})
+
+		_, err := svc.HandleRequest(context.Background(), ds, newRequest("up"))
+		require.NoError(t, err)
+
+		resp, err := svc.HandleRequest(ContextWithoutQueryCache(context.Background()), ds, newRequest("up"))
+		require.NoError(t, err)
+		assert.Equal(t, float64(2), firstValue(t, resp))
+
+		alertRequest := newRequest("up")
+		alertRequest.Headers = map[string]string{"FromAlert": "true"}
+		resp, err = svc.HandleRequest(context.Background(), ds, alertRequest)
+		require.NoError(t, err)
+		assert.Equal(t, float64(3), firstValue(t, resp))
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("Data sources can set their own TTL", func(t *testing.T) {
+		svc, ds, calls := setup(t, map[string]interface{}{"queryCachingEnabled": true, "queryCachingTTL": "1ms"})
+
+		_, err := svc.HandleRequest(context.Background(), ds, newRequest("up"))
+		require.NoError(t, err)
+		time.Sleep(5 * time.Millisecond)
+		_, err = svc.HandleRequest(context.Background(), ds, newRequest("up"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Absolute time ranges in the same TTL bucket aren't answered from each other's response", func(t *testing.T) {
+		svc, ds, calls := setup(t, map[string]interface{}{"queryCachingEnabled": true, "queryCachingTTL": "1h"})
+
+		// 10:05-10:10 and 10:20-10:25 on the same hour
+		bucket := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+		for i, window := range [][2]time.Duration{{5 * time.Minute, 10 * time.Minute}, {20 * time.Minute, 25 * time.Minute}} {
+			query := newRequest("up")
+			timeRange := plugins.NewDataTimeRange(
+				strconv.FormatInt(bucket.Add(window[0]).UnixNano()/int64(time.Millisecond), 10),
+				strconv.FormatInt(bucket.Add(window[1]).UnixNano()/int64(time.Millisecond), 10))
+			query.TimeRange = &timeRange
+
+			resp, err := svc.HandleRequest(context.Background(), ds, query)
+			require.NoError(t, err)
+			assert.Equal(t, float64(i+1), firstValue(t, resp))
+		}
+		assert.Equal(t, 2, *calls)
+	})
+
+	t.Run("Relative time ranges are aligned to the TTL or their length", func(t *testing.T) {
+		cache := newQueryCache(setting.QueryCachingSettings{Enabled: true, Backend: "memory"}, nil)
+		ds := &models.DataSource{Uid: "uid"}
+		now := time.Date(2021, 1, 1, 10, 0, 0, 0, time.UTC)
+		keyAt := func(from string, now time.Time) string {
+			query := newRequest("up")
+			query.TimeRange = &plugins.DataTimeRange{From: from, To: "now", Now: now}
+			key, err := cache.key(ds, query, time.Hour)
+			require.NoError(t, err)
+			return key
+		}
+
+		assert.Equal(t, keyAt("now-6h", now.Add(time.Minute)), keyAt("now-6h", now.Add(50*time.Minute)))
+		assert.Equal(t, keyAt("now-5m", now.Add(time.Minute)), keyAt("now-5m", now.Add(4*time.Minute)))
+		assert.NotEqual(t, keyAt("now-5m", now.Add(time.Minute)), keyAt("now-5m", now.Add(6*time.Minute)))
+	})
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := newMemoryCache(2)
+	require.NoError(t, cache.Set("a", 1, time.Minute))
+	require.NoError(t, cache.Set("b", 2, time.Minute))
+
+	_, err := cache.Get("a")
+	require.NoError(t, err)
+
+	// b is the least recently used item
+	require.NoError(t, cache.Set("c", 3, time.Minute))
+	_, err = cache.Get("b")
+	assert.Error(t, err)
+
+	value, err := cache.Get("a")
+	require.NoError(t, err)
+	assert.Equal(t, 1, value)
+
+	require.NoError(t, cache.Set("d", 4, -time.Second))
+	_, err = cache.Get("d")
+	assert.Error(t, err)
+}
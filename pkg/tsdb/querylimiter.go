@@ -0,0 +1,230 @@
+package tsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/infra/metrics"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// ErrQueryQueueTimeout is returned when a query request times out waiting for a data source that is
+// running as many queries as it allows.
+var ErrQueryQueueTimeout = errors.New("timed out waiting for the data source to run the query")
+
+// defaultQueryQueueTimeout is how long query requests wait for data sources that don't set their own
+// queue timeout.
+const defaultQueryQueueTimeout = 30 * time.Second
+
+// queryLimiter limits the number of query requests that are sent to a data source at the same time.
+// Data sources enable the limit by setting maxConcurrentQueries in their jsonData. Requests that exceed
+// the limit wait in a queue that takes turns between users, so that a single user can't take all the
+// query slots of a data source.
+type queryLimiter struct {
+	mu     sync.Mutex
+	queues map[int64]*queryQueue
+}
+
+// queryQueue holds the requests that are waiting for a data source, by user.
+type queryQueue struct {
+	inFlight int
+	limit    int
+	users    []string
+	waiting  map[string][]*queryWaiter
+	next     int
+}
+
+type queryWaiter struct {
+	ready   chan struct{}
+	granted bool
+}
+
+func newQueryLimiter() *queryLimiter {
+	return &queryLimiter{queues: map[int64]*queryQueue{}}
+}
+
+// queryLimits returns the limit of concurrent query requests of a data source and how long requests
+// wait for it. The limit is zero for data sources without a limit.
+func queryLimits(ds *models.DataSource) (int, time.Duration, error) {
+	if ds.JsonData == nil {
+		return 0, 0, nil
+	}
+
+	limit := ds.JsonData.Get("maxConcurrentQueries").MustInt(0)
+	if limit <= 0 {
+		return 0, 0, nil
+	}
+
+	timeout := defaultQueryQueueTimeout
+	if value := ds.JsonData.Get("queryQueueTimeout").MustString(""); value != "" {
+		var err error
+		if timeout, err = gtime.ParseDuration(value); err != nil {
+			return 0, 0, fmt.Errorf("invalid query queue timeout %q: %w", value, err)
+		}
+	}
+
+	return limit, timeout, nil
+}
+
+func queryUser(query plugins.DataQuery) string {
+	if query.User == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d/%d", query.User.OrgId, query.User.UserId)
+}
+
+// handleRequest calls handle when the data source has a free query slot.
+func (l *queryLimiter) handleRequest(ctx context.Context, ds *models.DataSource, query plugins.DataQuery,
+	handle func() (plugins.DataResponse, error)) (plugins.DataResponse, error) {
+	if l == nil {
+		return handle()
+	}
+
+	limit, timeout, err := queryLimits(ds)
+	if err != nil {
+		return plugins.DataResponse{}, err
+	}
+	if limit == 0 {
+		return handle()
+	}
+
+	if err := l.acquire(ctx, ds, queryUser(query), limit, timeout); err != nil {
+		return plugins.DataResponse{}, err
+	}
+	defer l.release(ds.Id)
+
+	return handle()
+}
+
+func (l *queryLimiter) acquire(ctx context.Context, ds *models.DataSource, user string, limit int,
+	timeout time.Duration) error {
+	l.mu.Lock()
+	queue, ok := l.queues[ds.Id]
+	if !ok {
+		queue = &queryQueue{waiting: map[string][]*queryWaiter{}}
+		l.queues[ds.Id] = queue
+	}
+	queue.limit = limit
+
+	if queue.inFlight < queue.limit && len(queue.users) == 0 {
+		queue.inFlight++
+		l.mu.Unlock()
+		return nil
+	}
+
+	waiter := &queryWaiter{ready: make(chan struct{})}
+	if len(queue.waiting[user]) == 0 {
+		queue.users = append(queue.users, user)
+	}
+	queue.waiting[user] = append(queue.waiting[user], waiter)
+	l.mu.Unlock()
+
+	queueSize := metrics.MDataSourceQueryQueue.WithLabelValues(ds.Uid)
+	queueSize.Inc()
+	defer queueSize.Dec()
+
+	start := time.Now()
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-waiter.ready:
+	case <-timer.C:
+		err = ErrQueryQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	metrics.MDataSourceQueryQueueWait.WithLabelValues(ds.Uid).Observe(float64(time.Since(start) / time.Millisecond))
+	if err == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// the request may have gotten a query slot while timing out, which it keeps unless it was canceled
+	if waiter.granted {
+		if errors.Is(err, ErrQueryQueueTimeout) {
+			return nil
+		}
+		queue.inFlight--
+		l.dispatch(queue)
+		return err
+	}
+
+	queue.remove(user, waiter)
+	if errors.Is(err, ErrQueryQueueTimeout) {
+		metrics.MDataSourceQueryQueueTimeouts.WithLabelValues(ds.Uid).Inc()
+	}
+	return err
+}
+
+func (l *queryLimiter) release(dsID int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	queue := l.queues[dsID]
+	queue.inFlight--
+	l.dispatch(queue)
+
+	if queue.inFlight == 0 && len(queue.users) == 0 {
+		delete(l.queues, dsID)
+	}
+}
+
+// dispatch gives the free query slots of a data source to the waiting requests, taking turns between
+// users.
+func (l *queryLimiter) dispatch(queue *queryQueue) {
+	for queue.inFlight < queue.limit && len(queue.users) > 0 {
+		if queue.next >= len(queue.users) {
+			queue.next = 0
+		}
+		user := queue.users[queue.next]
+
+		waiter := queue.waiting[user][0]
+		queue.waiting[user] = queue.waiting[user][1:]
+		if len(queue.waiting[user]) == 0 {
+			delete(queue.waiting, user)
+			queue.users = append(queue.users[:queue.next], queue.users[queue.next+1:]...)
+		} else {
+			queue.next++
+		}
+
+		waiter.granted = true
+		close(waiter.ready)
+		queue.inFlight++
+	}
+}
+
+func (q *queryQueue) remove(user string, waiter *queryWaiter) {
+	waiters := q.waiting[user]
+	for i, w := range waiters {
+		if w == waiter {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			break
+		}
+	}
+
+	if len(waiters) > 0 {
+		q.waiting[user] = waiters
+		return
+	}
+
+	delete(q.waiting, user)
+	for i, u := range q.users {
+		if u == user {
+			q.users = append(q.users[:i], q.users[i+1:]...)
+			if i < q.next {
+				q.next--
+			}
+			break
+		}
+	}
+}
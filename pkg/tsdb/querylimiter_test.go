@@ -0,0 +1,176 @@
+package tsdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryLimiter(t *testing.T) {
+	newDataSource := func(jsonData map[string]interface{}) *models.DataSource {
+		return &models.DataSource{Id: 1, Uid: "uid", Type: "test", JsonData: simplejson.NewFromAny(jsonData)}
+	}
+
+	userQuery := func(userID int64) plugins.DataQuery {
+		return plugins.DataQuery{User: &models.SignedInUser{OrgId: 1, UserId: userID}}
+	}
+
+	// waitForQueue waits until the given number of requests wait for the data source.
+	waitForQueue := func(t *testing.T, limiter *queryLimiter, waiting int) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			limiter.mu.Lock()
+			defer limiter.mu.Unlock()
+			count := 0
+			if queue, ok := limiter.queues[1]; ok {
+				for _, waiters := range queue.waiting {
+					count += len(waiters)
+				}
+			}
+			return count == waiting
+		}, time.Second, time.Millisecond)
+	}
+
+	// waitForSlot waits until a request has the query slot of the data source.
+	waitForSlot := func(t *testing.T, limiter *queryLimiter) {
+		t.Helper()
+		require.Eventually(t, func() bool {
+			limiter.mu.Lock()
+			defer limiter.mu.Unlock()
+			queue, ok := limiter.queues[1]
+			return ok && queue.inFlight == 1
+		}, time.Second, time.Millisecond)
+	}
+
+	t.Run("Data sources without a limit run all requests", func(t *testing.T) {
+		limiter := newQueryLimiter()
+		_, err := limiter.handleRequest(context.Background(), newDataSource(map[string]interface{}{}), userQuery(1),
+			func() (plugins.DataResponse, error) {
+				assert.Empty(t, limiter.queues)
+				return plugins.DataResponse{}, nil
+			})
+		require.NoError(t, err)
+	})
+
+	t.Run("Requests exceeding the limit wait for a free query slot", func(t *testing.T) {
+		limiter := newQueryLimiter()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 2})
+
+		var mu sync.Mutex
+		inFlight, maxInFlight := 0, 0
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(userID int64) {
+				defer wg.Done()
+				_, err := limiter.handleRequest(context.Background(), ds, userQuery(userID), func() (plugins.DataResponse, error) {
+					mu.Lock()
+					inFlight++
+					if inFlight > maxInFlight {
+						maxInFlight = inFlight
+					}
+					mu.Unlock()
+
+					time.Sleep(5 * time.Millisecond)
+
+					mu.Lock()
+					inFlight--
+					mu.Unlock()
+					return plugins.DataResponse{}, nil
+				})
+				assert.NoError(t, err)
+			}(int64(i % 3))
+		}
+		wg.Wait()
+
+		assert.Equal(t, 2, maxInFlight)
+		assert.Empty(t, limiter.queues)
+	})
+
+	t.Run("Waiting requests take turns between users", func(t *testing.T) {
+		limiter := newQueryLimiter()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 1})
+
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := limiter.handleRequest(context.Background(), ds, userQuery(1), func() (plugins.DataResponse, error) {
+				<-release
+				return plugins.DataResponse{}, nil
+			})
+			assert.NoError(t, err)
+		}()
+		waitForSlot(t, limiter)
+
+		var mu sync.Mutex
+		order := []string{}
+		var wg sync.WaitGroup
+		enqueue := func(name string, userID int64, waiting int) {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := limiter.handleRequest(context.Background(), ds, userQuery(userID), func() (plugins.DataResponse, error) {
+					mu.Lock()
+					order = append(order, name)
+					mu.Unlock()
+					return plugins.DataResponse{}, nil
+				})
+				assert.NoError(t, err)
+			}()
+			waitForQueue(t, limiter, waiting)
+		}
+
+		enqueue("user 1, query 1", 1, 1)
+		enqueue("user 1, query 2", 1, 2)
+		enqueue("user 2, query 1", 2, 3)
+
+		close(release)
+		<-done
+		wg.Wait()
+
+		assert.Equal(t, []string{"user 1, query 1", "user 2, query 1", "user 1, query 2"}, order)
+	})
+
+	t.Run("Requests time out waiting for a free query slot", func(t *testing.T) {
+		limiter := newQueryLimiter()
+		ds := newDataSource(map[string]interface{}{"maxConcurrentQueries": 1, "queryQueueTimeout": "10ms"})
+
+		release := make(chan struct{})
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, err := limiter.handleRequest(context.Background(), ds, userQuery(1), func() (plugins.DataResponse, error) {
+				<-release
+				return plugins.DataResponse{}, nil
+			})
+			assert.NoError(t, err)
+		}()
+		waitForSlot(t, limiter)
+
+		_, err := limiter.handleRequest(context.Background(), ds, userQuery(2), func() (plugins.DataResponse, error) {
+			t.Fatal("request shouldn't run")
+			return plugins.DataResponse{}, nil
+		})
+		assert.Equal(t, ErrQueryQueueTimeout, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = limiter.handleRequest(ctx, ds, userQuery(2), func() (plugins.DataResponse, error) {
+			t.Fatal("request shouldn't run")
+			return plugins.DataResponse{}, nil
+		})
+		assert.Equal(t, context.Canceled, err)
+
+		close(release)
+		<-done
+		assert.Empty(t, limiter.queues)
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/grafana/grafana/pkg/infra/remotecache"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/plugins/manager"
@@ -21,6 +22,7 @@ import (
 	"github.com/grafana/grafana/pkg/tsdb/opentsdb"
 	"github.com/grafana/grafana/pkg/tsdb/postgres"
 	"github.com/grafana/grafana/pkg/tsdb/prometheus"
+	"github.com/grafana/grafana/pkg/tsdb/sqlite"
 	"github.com/grafana/grafana/pkg/tsdb/tempo"
 )
 
@@ -47,8 +49,11 @@ type Service struct {
 	CloudMonitoringService *cloudmonitoring.Service      `inject:""`
 	AzureMonitorService    *azuremonitor.Service         `inject:""`
 	PluginManager          *manager.PluginManager        `inject:""`
+	RemoteCache            *remotecache.RemoteCache      `inject:""`
 
-	registry map[string]func(*models.DataSource) (plugins.DataPlugin, error)
+	registry     map[string]func(*models.DataSource) (plugins.DataPlugin, error)
+	queryCache   *queryCache
+	queryLimiter *queryLimiter
 }
 
 // Init initialises the service.
@@ -57,15 +62,24 @@ func (s *Service) Init() error {
 	s.registry["opentsdb"] = opentsdb.NewExecutor
 	s.registry["prometheus"] = prometheus.NewExecutor
 	s.registry["influxdb"] = influxdb.NewExecutor
-	s.registry["mssql"] = mssql.NewExecutor
+	s.registry["mssql"] = func(ds *models.DataSource) (plugins.DataPlugin, error) {
+		return mssql.NewExecutor(ds, s.Cfg.SQLDataSources)
+	}
 	s.registry["postgres"] = s.PostgresService.NewExecutor
-	s.registry["mysql"] = mysql.NewExecutor
+	s.registry["mysql"] = func(ds *models.DataSource) (plugins.DataPlugin, error) {
+		return mysql.NewExecutor(ds, s.Cfg.SQLDataSources)
+	}
+	s.registry["sqlite"] = func(ds *models.DataSource) (plugins.DataPlugin, error) {
+		return sqlite.NewExecutor(ds, s.Cfg.SQLDataSources)
+	}
 	s.registry["elasticsearch"] = elasticsearch.NewExecutor
 	s.registry["cloudwatch"] = s.CloudWatchService.NewExecutor
 	s.registry["stackdriver"] = s.CloudMonitoringService.NewExecutor
 	s.registry["grafana-azure-monitor-datasource"] = s.AzureMonitorService.NewExecutor
 	s.registry["loki"] = loki.NewExecutor
 	s.registry["tempo"] = tempo.NewExecutor
+	s.queryCache = newQueryCache(s.Cfg.QueryCaching, s.RemoteCache)
+	s.queryLimiter = newQueryLimiter()
 	return nil
 }
 
@@ -87,7 +101,26 @@ func (s *Service) HandleRequest(ctx context.Context, ds *models.DataSource, quer
 		}
 	}
 
-	return plugin.DataQuery(ctx, ds, query)
+	guardrails, err := getQueryGuardrails(ds)
+	if err != nil {
+		return plugins.DataResponse{}, err
+	}
+	if err := guardrails.checkRequest(query); err != nil {
+		return plugins.DataResponse{}, err
+	}
+
+	// cached responses don't count towards the concurrent queries of the data source
+	resp, err := s.queryCache.handleRequest(ctx, ds, query, func() (plugins.DataResponse, error) {
+		return s.queryLimiter.handleRequest(ctx, ds, query, func() (plugins.DataResponse, error) {
+			return plugin.DataQuery(ctx, ds, query)
+		})
+	})
+	if err != nil {
+		return resp, err
+	}
+
+	guardrails.checkResponse(resp)
+	return resp, nil
 }
 
 // RegisterQueryHandler registers a query handler factory.
@@ -4,14 +4,15 @@ import (
 	"container/list"
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
-	"math"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/grafana/grafana/pkg/components/gtime"
 	"github.com/grafana/grafana/pkg/plugins"
 	"github.com/grafana/grafana/pkg/setting"
 	"github.com/grafana/grafana/pkg/tsdb/interval"
@@ -70,6 +71,8 @@ type dataPlugin struct {
 	engine                 *xorm.Engine
 	timeColumnNames        []string
 	metricColumnTypes      []string
+	rowLimit               int64
+	queryTimeout           time.Duration
 	log                    log.Logger
 }
 
@@ -79,6 +82,10 @@ type DataPluginConfiguration struct {
 	ConnectionString  string
 	TimeColumnNames   []string
 	MetricColumnTypes []string
+	// RowLimit and QueryTimeout are the defaults for data sources that don't set rowLimit and
+	// queryTimeout in their jsonData.
+	RowLimit     int64
+	QueryTimeout time.Duration
 }
 
 // NewDataPlugin returns a new plugins.DataPlugin
@@ -99,6 +106,10 @@ func NewDataPlugin(config DataPluginConfiguration, queryResultTransformer SqlQue
 		plugin.metricColumnTypes = config.MetricColumnTypes
 	}
 
+	if err := plugin.setQueryLimits(config); err != nil {
+		return nil, err
+	}
+
 	engineCache.Lock()
 	defer engineCache.Unlock()
 
@@ -128,7 +139,67 @@ func NewDataPlugin(config DataPluginConfiguration, queryResultTransformer SqlQue
 	return &plugin, nil
 }
 
-const rowLimit = 1000000
+// defaultRowLimit is the row limit of data sources that are configured without one.
+const defaultRowLimit = 1000000
+
+// setQueryLimits sets the row limit and query timeout of the data source, or the defaults if it
+// doesn't set them.
+func (e *dataPlugin) setQueryLimits(config DataPluginConfiguration) error {
+	e.rowLimit = config.RowLimit
+	if e.rowLimit <= 0 {
+		e.rowLimit = defaultRowLimit
+	}
+	e.queryTimeout = config.QueryTimeout
+
+	jsonData := config.Datasource.JsonData
+	if jsonData == nil {
+		return nil
+	}
+	if rowLimit := jsonData.Get("rowLimit").MustInt64(0); rowLimit > 0 {
+		e.rowLimit = rowLimit
+	}
+	if value := jsonData.Get("queryTimeout").MustString(""); value != "" {
+		timeout, err := gtime.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid queryTimeout %q of data source: %w", value, err)
+		}
+		e.queryTimeout = timeout
+	}
+	return nil
+}
+
+// queryContext returns the context of a query, which is cancelled when the request is cancelled or the
+// query runs for longer than the data source's timeout.
+func (e *dataPlugin) queryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if e.queryTimeout > 0 {
+		return context.WithTimeout(ctx, e.queryTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// queryError returns the error of a query that failed, which is the error of the request's context if
+// the request was cancelled, or a timeout error if the query ran for longer than the data source's timeout.
+func (e *dataPlugin) queryError(ctx context.Context, queryCtx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("query was cancelled after the time limit of %s", e.queryTimeout)
+	}
+	return err
+}
+
+// setTruncatedNotice records in the meta of a query result that rows were left out because the result
+// has more rows than the row limit. The values that are filled in for time series count as rows.
+func (e *dataPlugin) setTruncatedNotice(result *plugins.DataQueryResult) {
+	result.Meta.Set("notices", []interface{}{
+		map[string]interface{}{
+			"severity": "warning",
+			"text": fmt.Sprintf("The query result has more than %d rows, the rows after the first %d were left out. "+
+				"Add a LIMIT, narrow the time range or fill in fewer values to see all rows.", e.rowLimit, e.rowLimit),
+		},
+	})
+}
 
 // Query is the main function for the SqlQueryEndpoint
 func (e *dataPlugin) DataQuery(ctx context.Context, dsInfo *models.DataSource,
@@ -154,6 +225,9 @@ func (e *dataPlugin) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 				Meta:  simplejson.New(),
 				RefID: query.RefID,
 			}
+			defer func() {
+				ch <- queryResult
+			}()
 
 			rawSQL := query.Model.Get("rawSql").MustString()
 			if rawSQL == "" {
@@ -164,7 +238,6 @@ func (e *dataPlugin) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 			rawSQL, err := Interpolate(query, timeRange, rawSQL)
 			if err != nil {
 				queryResult.Error = err
-				ch <- queryResult
 				return
 			}
 
@@ -172,7 +245,6 @@ func (e *dataPlugin) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 			rawSQL, err = e.macroEngine.Interpolate(query, timeRange, rawSQL)
 			if err != nil {
 				queryResult.Error = err
-				ch <- queryResult
 				return
 			}
 
@@ -182,12 +254,17 @@ func (e *dataPlugin) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 			defer session.Close()
 			db := session.DB()
 
-			rows, err := db.Query(rawSQL)
+			queryCtx, cancel := e.queryContext(ctx)
+			defer cancel()
+
+			rows, err := db.QueryContext(queryCtx, rawSQL)
 			if err != nil {
-				queryResult.Error = e.queryResultTransformer.TransformQueryError(err)
+				queryResult.Error = e.queryError(ctx, queryCtx, e.queryResultTransformer.TransformQueryError(err))
 				return
 			}
 			defer func() {
+				// cancel queries whose rows weren't all read, rather than reading the rest of the rows
+				cancel()
 				if err := rows.Close(); err != nil {
 					e.log.Warn("Failed to close rows", "err", err)
 				}
@@ -199,18 +276,16 @@ func (e *dataPlugin) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 			case "time_series":
 				err := e.transformToTimeSeries(query, rows, &queryResult, queryContext)
 				if err != nil {
-					queryResult.Error = err
+					queryResult.Error = e.queryError(ctx, queryCtx, err)
 					return
 				}
 			case "table":
 				err := e.transformToTable(query, rows, &queryResult, queryContext)
 				if err != nil {
-					queryResult.Error = err
+					queryResult.Error = e.queryError(ctx, queryCtx, err)
 					return
 				}
 			}
-
-			ch <- queryResult
 		}(query)
 	}
 
@@ -284,8 +359,9 @@ func (e *dataPlugin) transformToTable(query plugins.DataSubQuery, rows *core.Row
 	}
 
 	for ; rows.Next(); rowCount++ {
-		if rowCount > rowLimit {
-			return fmt.Errorf("query row limit exceeded, limit %d", rowLimit)
+		if int64(rowCount) >= e.rowLimit {
+			e.setTruncatedNotice(result)
+			break
 		}
 
 		values, err := e.queryResultTransformer.TransformQueryResult(columnTypes, rows)
@@ -300,6 +376,9 @@ func (e *dataPlugin) transformToTable(query plugins.DataSubQuery, rows *core.Row
 		ConvertSqlTimeColumnToEpochMs(values, timeEndIndex)
 		table.Rows = append(table.Rows, values)
 	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
 
 	result.Tables = append(result.Tables, table)
 	result.Meta.Set("rowCount", rowCount)
@@ -386,12 +465,25 @@ func (e *dataPlugin) transformToTimeSeries(query plugins.DataSubQuery, rows *cor
 			cfg.fillValue.Float64 = query.Model.Get("fillValue").MustFloat64()
 			cfg.fillValue.Valid = true
 		}
+		if tz := query.Model.Get("fillTimezone").MustString(); tz != "" {
+			if cfg.fillLocation, err = time.LoadLocation(tz); err != nil {
+				return err
+			}
+		}
 	}
 
+	cfg.rowLimit = e.rowLimit
 	for rows.Next() {
+		if cfg.limitReached() {
+			break
+		}
 		if err := e.processRow(cfg); err != nil {
 			return err
 		}
+		cfg.rowsRead++
+	}
+	if err := rows.Err(); err != nil {
+		return err
 	}
 
 	for elem := cfg.seriesByQueryOrder.Front(); elem != nil; elem = elem.Next() {
@@ -415,8 +507,8 @@ func (e *dataPlugin) transformToTimeSeries(query plugins.DataSubQuery, rows *cor
 		}
 
 		// align interval start
-		intervalStart = math.Floor(intervalStart/cfg.fillInterval) * cfg.fillInterval
-		for i := intervalStart + cfg.fillInterval; i < intervalEnd; i += cfg.fillInterval {
+		intervalStart = cfg.alignFill(intervalStart)
+		for i := cfg.nextFill(intervalStart); i < intervalEnd && !cfg.limitReached(); i = cfg.nextFill(i) {
 			series.Points = append(series.Points, plugins.DataTimePoint{cfg.fillValue, null.FloatFrom(i)})
 			cfg.rowCount++
 		}
@@ -424,12 +516,19 @@ func (e *dataPlugin) transformToTimeSeries(query plugins.DataSubQuery, rows *cor
 		result.Series = append(result.Series, *series)
 	}
 
+	if cfg.truncated {
+		e.setTruncatedNotice(result)
+	}
 	result.Meta.Set("rowCount", cfg.rowCount)
 	return nil
 }
 
 type processCfg struct {
+	// rowCount is the number of filled in values, rowsRead the number of rows that were read
 	rowCount           int
+	rowsRead           int64
+	rowLimit           int64
+	truncated          bool
 	columnTypes        []*sql.ColumnType
 	columnNames        []string
 	rows               *core.Rows
@@ -444,6 +543,17 @@ type processCfg struct {
 	queryContext       plugins.DataQuery
 	fillInterval       float64
 	fillPrevious       bool
+	fillLocation       *time.Location
+}
+
+// limitReached returns true, and marks the result as truncated, if the rows that were read and the
+// filled in values reach the row limit.
+func (cfg *processCfg) limitReached() bool {
+	if cfg.rowsRead+int64(cfg.rowCount) < cfg.rowLimit {
+		return false
+	}
+	cfg.truncated = true
+	return true
 }
 
 func (e *dataPlugin) processRow(cfg *processCfg) error {
@@ -451,10 +561,6 @@ func (e *dataPlugin) processRow(cfg *processCfg) error {
 	var value null.Float
 	var metric string
 
-	if cfg.rowCount > rowLimit {
-		return fmt.Errorf("query row limit exceeded, limit %d", rowLimit)
-	}
-
 	values, err := e.queryResultTransformer.TransformQueryResult(cfg.columnTypes, cfg.rows)
 	if err != nil {
 		return err
@@ -517,7 +623,7 @@ func (e *dataPlugin) processRow(cfg *processCfg) error {
 			if !exists {
 				intervalStart = float64(cfg.queryContext.TimeRange.MustGetFrom().UnixNano() / 1e6)
 			} else {
-				intervalStart = series.Points[len(series.Points)-1][1].Float64 + cfg.fillInterval
+				intervalStart = cfg.nextFill(series.Points[len(series.Points)-1][1].Float64)
 			}
 
 			if cfg.fillPrevious {
@@ -529,9 +635,12 @@ func (e *dataPlugin) processRow(cfg *processCfg) error {
 			}
 
 			// align interval start
-			intervalStart = math.Floor(intervalStart/cfg.fillInterval) * cfg.fillInterval
+			intervalStart = cfg.alignFill(intervalStart)
 
-			for i := intervalStart; i < timestamp; i += cfg.fillInterval {
+			for i := intervalStart; i < timestamp; i = cfg.nextFill(i) {
+				if cfg.limitReached() {
+					return nil
+				}
 				series.Points = append(series.Points, plugins.DataTimePoint{cfg.fillValue, null.FloatFrom(i)})
 				cfg.rowCount++
 			}
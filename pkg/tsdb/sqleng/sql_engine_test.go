@@ -1,15 +1,22 @@
 package sqleng
 
 import (
+	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana/pkg/components/null"
 	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/infra/log"
 	"github.com/grafana/grafana/pkg/models"
 	"github.com/grafana/grafana/pkg/plugins"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"xorm.io/core"
 )
 
 func TestSQLEngine(t *testing.T) {
@@ -310,3 +317,149 @@ func TestSQLEngine(t *testing.T) {
 		}
 	})
 }
+
+func TestSQLEngineQueryLimits(t *testing.T) {
+	newPlugin := func(t *testing.T, id int64, jsonData map[string]interface{}, config DataPluginConfiguration) plugins.DataPlugin {
+		config.DriverName = "sqlite3"
+		config.ConnectionString = ":memory:"
+		config.Datasource = &models.DataSource{Id: id, JsonData: simplejson.NewFromAny(jsonData)}
+		plugin, err := NewDataPlugin(config, &testQueryResultTransformer{}, &testMacroEngine{}, log.New("test"))
+		require.NoError(t, err)
+		return plugin
+	}
+	newQuery := func(format string, sql string) plugins.DataQuery {
+		timeRange := plugins.NewDataTimeRange("0", "1000000")
+		return plugins.DataQuery{
+			TimeRange: &timeRange,
+			Queries: []plugins.DataSubQuery{
+				{
+					RefID:      "A",
+					DataSource: &models.DataSource{},
+					Model:      simplejson.NewFromAny(map[string]interface{}{"rawSql": sql, "format": format}),
+				},
+			},
+		}
+	}
+	const rowsSQL = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c LIMIT 20) " +
+		"SELECT x * 1000 AS time, x AS value FROM c"
+
+	t.Run("Results with more rows than the row limit are truncated", func(t *testing.T) {
+		plugin := newPlugin(t, 1001, nil, DataPluginConfiguration{RowLimit: 5})
+
+		for _, format := range []string{"table", "time_series"} {
+			resp, err := plugin.DataQuery(context.Background(), nil, newQuery(format, rowsSQL))
+			require.NoError(t, err)
+
+			result := resp.Results["A"]
+			require.NoError(t, result.Error)
+			if format == "table" {
+				require.Len(t, result.Tables, 1)
+				assert.Len(t, result.Tables[0].Rows, 5)
+				assert.Equal(t, 5, result.Meta.Get("rowCount").MustInt())
+			} else {
+				require.Len(t, result.Series, 1)
+				assert.Len(t, result.Series[0].Points, 5)
+			}
+			notices := result.Meta.Get("notices")
+			require.Len(t, notices.MustArray(), 1)
+			assert.Equal(t, "warning", notices.GetIndex(0).Get("severity").MustString())
+			assert.Contains(t, notices.GetIndex(0).Get("text").MustString(), "more than 5 rows")
+		}
+	})
+
+	t.Run("Filled in values count towards the row limit", func(t *testing.T) {
+		plugin := newPlugin(t, 1006, nil, DataPluginConfiguration{RowLimit: 5})
+
+		for _, sql := range []string{
+			// values are filled in between the rows, and after the last row until the end of the time range
+			"SELECT 1000 AS time, 1 AS value UNION ALL SELECT 900000, 2",
+			"SELECT 1000 AS time, 1 AS value",
+		} {
+			query := newQuery("time_series", sql)
+			query.Queries[0].Model.Set("fill", true)
+			query.Queries[0].Model.Set("fillInterval", 1)
+			query.Queries[0].Model.Set("fillMode", "null")
+
+			resp, err := plugin.DataQuery(context.Background(), nil, query)
+			require.NoError(t, err)
+
+			result := resp.Results["A"]
+			require.NoError(t, result.Error)
+			require.Len(t, result.Series, 1)
+			assert.Len(t, result.Series[0].Points, 5)
+			notices := result.Meta.Get("notices")
+			require.Len(t, notices.MustArray(), 1)
+			assert.Contains(t, notices.GetIndex(0).Get("text").MustString(), "more than 5 rows")
+		}
+	})
+
+	t.Run("The row limit of the data source overrides the default", func(t *testing.T) {
+		plugin := newPlugin(t, 1002, map[string]interface{}{"rowLimit": 10}, DataPluginConfiguration{RowLimit: 5})
+
+		resp, err := plugin.DataQuery(context.Background(), nil, newQuery("table", rowsSQL))
+		require.NoError(t, err)
+		assert.Len(t, resp.Results["A"].Tables[0].Rows, 10)
+
+		plugin = newPlugin(t, 1003, map[string]interface{}{"rowLimit": 100}, DataPluginConfiguration{RowLimit: 5})
+		resp, err = plugin.DataQuery(context.Background(), nil, newQuery("table", rowsSQL))
+		require.NoError(t, err)
+		assert.Len(t, resp.Results["A"].Tables[0].Rows, 20)
+		assert.Nil(t, resp.Results["A"].Meta.Get("notices").Interface())
+	})
+
+	t.Run("Queries that run for longer than the timeout are cancelled", func(t *testing.T) {
+		plugin := newPlugin(t, 1004, map[string]interface{}{"queryTimeout": "100ms"}, DataPluginConfiguration{})
+		endlessSQL := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) AS value FROM c"
+
+		resp, err := plugin.DataQuery(context.Background(), nil, newQuery("table", endlessSQL))
+		require.NoError(t, err)
+		require.Error(t, resp.Results["A"].Error)
+		assert.Contains(t, resp.Results["A"].Error.Error(), "query was cancelled after the time limit of 100ms")
+	})
+
+	t.Run("Queries are cancelled with their request", func(t *testing.T) {
+		plugin := newPlugin(t, 1005, nil, DataPluginConfiguration{})
+		endlessSQL := "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c) SELECT count(*) AS value FROM c"
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		resp, err := plugin.DataQuery(ctx, nil, newQuery("table", endlessSQL))
+		require.NoError(t, err)
+		require.Error(t, resp.Results["A"].Error)
+		assert.True(t, errors.Is(resp.Results["A"].Error, context.DeadlineExceeded))
+	})
+
+	t.Run("Invalid query timeouts fail", func(t *testing.T) {
+		_, err := NewDataPlugin(DataPluginConfiguration{
+			DriverName:       "sqlite3",
+			ConnectionString: ":memory:",
+			Datasource:       &models.DataSource{Id: 1006, JsonData: simplejson.NewFromAny(map[string]interface{}{"queryTimeout": "soon"})},
+		}, &testQueryResultTransformer{}, &testMacroEngine{}, log.New("test"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid queryTimeout "soon"`)
+	})
+}
+
+type testQueryResultTransformer struct{}
+
+func (t *testQueryResultTransformer) TransformQueryResult(columnTypes []*sql.ColumnType, rows *core.Rows) (plugins.DataRowValues, error) {
+	values := make([]interface{}, len(columnTypes))
+	pointers := make([]interface{}, len(columnTypes))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	if err := rows.Scan(pointers...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func (t *testQueryResultTransformer) TransformQueryError(err error) error {
+	return err
+}
+
+type testMacroEngine struct{}
+
+func (m *testMacroEngine) Interpolate(query plugins.DataSubQuery, timeRange plugins.DataTimeRange, sql string) (string, error) {
+	return sql, nil
+}
@@ -0,0 +1,196 @@
+package sqleng
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/gtime"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+// QueryLocation returns the time zone of the dashboard that a query is run from, which is sent as the
+// timezone property of the query. Queries without a time zone, or with the browser's time zone that
+// can't be known here, use UTC.
+func QueryLocation(query plugins.DataSubQuery) (*time.Location, error) {
+	if query.Model == nil {
+		return time.UTC, nil
+	}
+
+	name := query.Model.Get("timezone").MustString("")
+	switch strings.ToLower(name) {
+	case "", "utc", "browser":
+		return time.UTC, nil
+	}
+
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", name)
+	}
+	return loc, nil
+}
+
+// tzOffset is the UTC offset in seconds of a time zone from start, a Unix time in seconds, until the
+// start of the next offset.
+type tzOffset struct {
+	start  int64
+	offset int64
+}
+
+// timeZoneOffsets returns the UTC offsets of loc between from and to, starting with the offset at from.
+func timeZoneOffsets(loc *time.Location, from, to time.Time) []tzOffset {
+	offsetAt := func(sec int64) int64 {
+		_, offset := time.Unix(sec, 0).In(loc).Zone()
+		return int64(offset)
+	}
+
+	current := offsetAt(from.Unix())
+	offsets := []tzOffset{{start: from.Unix(), offset: current}}
+	// time zones change their offset at most once a day, so it's enough to compare the offsets of
+	// consecutive days, and search for the second of the change in between
+	for lo := from.Unix(); lo < to.Unix(); {
+		hi := lo + 24*60*60
+		if hi > to.Unix() {
+			hi = to.Unix()
+		}
+
+		if next := offsetAt(hi); next != current {
+			start, end := lo, hi
+			for end-start > 1 {
+				mid := start + (end-start)/2
+				if offsetAt(mid) == current {
+					start = mid
+				} else {
+					end = mid
+				}
+			}
+			offsets = append(offsets, tzOffset{start: end, offset: next})
+			current = next
+		}
+		lo = hi
+	}
+
+	return offsets
+}
+
+// TimeGroupTz returns SQL that rounds the Unix time in seconds of epochSQL down to the start of its
+// interval in loc, so that for example daily intervals start at midnight in loc. floorSQL returns the
+// SQL dialect's expression that rounds its argument down to a multiple of the interval. The offsets of
+// loc are those in the time range, so that intervals stay aligned across daylight saving time changes.
+func TimeGroupTz(epochSQL string, interval time.Duration, loc *time.Location, timeRange plugins.DataTimeRange,
+	floorSQL func(string) string) string {
+	seconds := int64(interval.Seconds())
+	// intervals start before the time range
+	offsets := timeZoneOffsets(loc, timeRange.GetFromAsTimeUTC().Add(-interval), timeRange.GetToAsTimeUTC())
+
+	aligned := true
+	for _, o := range offsets {
+		if seconds == 0 || o.offset%seconds != 0 {
+			aligned = false
+		}
+	}
+	// offsets that are multiples of the interval, e.g. whole hours for hourly intervals, don't move the
+	// start of intervals
+	if aligned {
+		return floorSQL(epochSQL)
+	}
+
+	local := fmt.Sprintf("(%s%s)", epochSQL, signedSQL("+", offsetSQL(epochSQL, offsets, false)))
+	start := floorSQL(local)
+	return fmt.Sprintf("(%s%s)", start, signedSQL("-", offsetSQL(start, offsets, true)))
+}
+
+// offsetSQL returns the UTC offset at the time of epochSQL, which is in local time if local is true.
+func offsetSQL(epochSQL string, offsets []tzOffset, local bool) string {
+	if len(offsets) == 1 {
+		return fmt.Sprintf("%d", offsets[0].offset)
+	}
+
+	sql := "CASE"
+	for i := 1; i < len(offsets); i++ {
+		boundary := offsets[i].start
+		if local {
+			// local times during a change are taken to be in the earlier offset
+			boundary += offsets[i-1].offset
+		}
+		sql += fmt.Sprintf(" WHEN %s < %d THEN %d", epochSQL, boundary, offsets[i-1].offset)
+	}
+	return sql + fmt.Sprintf(" ELSE %d END", offsets[len(offsets)-1].offset)
+}
+
+// signedSQL adds or subtracts value, avoiding double signs for negative offsets.
+func signedSQL(op string, value string) string {
+	if strings.HasPrefix(value, "-") {
+		if op == "+" {
+			op = "-"
+		} else {
+			op = "+"
+		}
+		value = value[1:]
+	}
+	return fmt.Sprintf(" %s %s", op, value)
+}
+
+// TimeFilterTzFrom returns the start of the time range of $__timeFilterTz. Like all macros it filters
+// columns that store UTC times, and with an interval argument the start is moved back to the start of
+// its interval in the dashboard's time zone, so that the first interval of $__timeGroupTz with the same
+// interval isn't cut off.
+func TimeFilterTzFrom(query plugins.DataSubQuery, timeRange plugins.DataTimeRange, args []string) (time.Time, error) {
+	from := timeRange.GetFromAsTimeUTC()
+	if len(args) < 2 {
+		return from, nil
+	}
+
+	interval, err := gtime.ParseInterval(strings.Trim(args[1], `'"`))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing interval %v", args[1])
+	}
+	loc, err := QueryLocation(query)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ms := alignInLocation(float64(from.UnixNano()/1e6), float64(interval/time.Millisecond), loc)
+	return time.Unix(0, int64(ms)*int64(time.Millisecond)).UTC(), nil
+}
+
+// SetFillTimezone makes the values that fill in missing intervals start at the intervals of loc
+// instead of UTC, to match intervals from TimeGroupTz.
+func SetFillTimezone(query plugins.DataSubQuery, loc *time.Location) {
+	if loc != time.UTC {
+		query.Model.Set("fillTimezone", loc.String())
+	}
+}
+
+// alignFill rounds a time in milliseconds down to the start of its fill interval.
+func (cfg *processCfg) alignFill(ms float64) float64 {
+	if cfg.fillLocation == nil {
+		return math.Floor(ms/cfg.fillInterval) * cfg.fillInterval
+	}
+	return alignInLocation(ms, cfg.fillInterval, cfg.fillLocation)
+}
+
+// alignInLocation rounds a time in milliseconds down to the start of its interval in loc.
+func alignInLocation(ms float64, interval float64, loc *time.Location) float64 {
+	offsetAt := func(ms float64) float64 {
+		_, offset := time.Unix(0, int64(ms)*int64(time.Millisecond)).In(loc).Zone()
+		return float64(offset) * 1000
+	}
+	offset := offsetAt(ms)
+	start := math.Floor((ms+offset)/interval) * interval
+	return start - offsetAt(start-offset)
+}
+
+// nextFill returns the start of the fill interval after the one of a time in milliseconds.
+func (cfg *processCfg) nextFill(ms float64) float64 {
+	if cfg.fillLocation == nil {
+		return ms + cfg.fillInterval
+	}
+
+	// intervals in a time zone are shorter or longer when its offset changes
+	if next := cfg.alignFill(ms + cfg.fillInterval*1.5); next > ms {
+		return next
+	}
+	return ms + cfg.fillInterval
+}
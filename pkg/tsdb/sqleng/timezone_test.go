@@ -0,0 +1,116 @@
+package sqleng
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimeZoneMacros(t *testing.T) {
+	floorSQL := func(interval time.Duration) func(string) string {
+		return func(epoch string) string {
+			return fmt.Sprintf("floor(%s/%.0f)*%.0f", epoch, interval.Seconds(), interval.Seconds())
+		}
+	}
+	newTimeRange := func(from, to time.Time) plugins.DataTimeRange {
+		return plugins.NewDataTimeRange(fmt.Sprint(from.UnixNano()/1e6), fmt.Sprint(to.UnixNano()/1e6))
+	}
+	mustLoadLocation := func(name string) *time.Location {
+		loc, err := time.LoadLocation(name)
+		require.NoError(t, err)
+		return loc
+	}
+
+	t.Run("QueryLocation uses the time zone of the query", func(t *testing.T) {
+		for name, expected := range map[string]string{"": "UTC", "browser": "UTC", "utc": "UTC", "Europe/Berlin": "Europe/Berlin"} {
+			query := plugins.DataSubQuery{Model: simplejson.NewFromAny(map[string]interface{}{"timezone": name})}
+			loc, err := QueryLocation(query)
+			require.NoError(t, err)
+			assert.Equal(t, expected, loc.String())
+		}
+
+		query := plugins.DataSubQuery{Model: simplejson.NewFromAny(map[string]interface{}{"timezone": "Mars/Olympus_Mons"})}
+		_, err := QueryLocation(query)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `invalid time zone "Mars/Olympus_Mons"`)
+	})
+
+	t.Run("TimeGroupTz doesn't shift intervals that the offset is a multiple of", func(t *testing.T) {
+		from := time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC)
+		timeRange := newTimeRange(from, from.Add(6*time.Hour))
+
+		sql := TimeGroupTz("e", time.Hour, mustLoadLocation("Europe/Berlin"), timeRange, floorSQL(time.Hour))
+		assert.Equal(t, "floor(e/3600)*3600", sql)
+
+		sql = TimeGroupTz("e", 24*time.Hour, time.UTC, timeRange, floorSQL(24*time.Hour))
+		assert.Equal(t, "floor(e/86400)*86400", sql)
+	})
+
+	t.Run("TimeGroupTz shifts intervals by the offset of the time zone", func(t *testing.T) {
+		from := time.Date(2021, 1, 10, 0, 0, 0, 0, time.UTC)
+		timeRange := newTimeRange(from, from.Add(72*time.Hour))
+
+		sql := TimeGroupTz("e", 24*time.Hour, mustLoadLocation("Asia/Kolkata"), timeRange, floorSQL(24*time.Hour))
+		assert.Equal(t, "(floor((e + 19800)/86400)*86400 - 19800)", sql)
+
+		sql = TimeGroupTz("e", time.Hour, mustLoadLocation("America/St_Johns"), timeRange, floorSQL(time.Hour))
+		assert.Equal(t, "(floor((e - 12600)/3600)*3600 + 12600)", sql)
+	})
+
+	t.Run("TimeGroupTz uses the offsets before and after daylight saving time changes", func(t *testing.T) {
+		// Berlin changes from UTC+1 to UTC+2 at 2021-03-28 01:00 UTC
+		from := time.Date(2021, 3, 27, 0, 0, 0, 0, time.UTC)
+		timeRange := newTimeRange(from, from.Add(48*time.Hour))
+
+		sql := TimeGroupTz("e", 24*time.Hour, mustLoadLocation("Europe/Berlin"), timeRange, floorSQL(24*time.Hour))
+		start := "floor((e + CASE WHEN e < 1616893200 THEN 3600 ELSE 7200 END)/86400)*86400"
+		assert.Equal(t, fmt.Sprintf("(%s - CASE WHEN %s < 1616896800 THEN 3600 ELSE 7200 END)", start, start), sql)
+	})
+
+	t.Run("Fill values start at the intervals of the time zone", func(t *testing.T) {
+		loc := mustLoadLocation("Europe/Berlin")
+		cfg := &processCfg{fillInterval: float64(24 * time.Hour / time.Millisecond), fillLocation: loc}
+		toMS := func(t time.Time) float64 {
+			return float64(t.UnixNano() / 1e6)
+		}
+
+		start := cfg.alignFill(toMS(time.Date(2021, 3, 27, 15, 0, 0, 0, loc)))
+		assert.Equal(t, toMS(time.Date(2021, 3, 27, 0, 0, 0, 0, loc)), start)
+
+		// the day of the change has 23 hours in Berlin
+		next := cfg.nextFill(start)
+		assert.Equal(t, toMS(time.Date(2021, 3, 28, 0, 0, 0, 0, loc)), next)
+		next = cfg.nextFill(next)
+		assert.Equal(t, toMS(time.Date(2021, 3, 29, 0, 0, 0, 0, loc)), next)
+
+		cfg.fillLocation = nil
+		assert.Equal(t, toMS(time.Date(2021, 3, 27, 0, 0, 0, 0, time.UTC)), cfg.alignFill(toMS(time.Date(2021, 3, 27, 15, 0, 0, 0, time.UTC))))
+	})
+
+	t.Run("TimeFilterTzFrom starts at the start of the first interval in the time zone", func(t *testing.T) {
+		loc := mustLoadLocation("Europe/Berlin")
+		from := time.Date(2021, 3, 27, 15, 0, 0, 0, loc)
+		timeRange := newTimeRange(from, from.Add(48*time.Hour))
+		query := plugins.DataSubQuery{Model: simplejson.NewFromAny(map[string]interface{}{"timezone": "Europe/Berlin"})}
+
+		start, err := TimeFilterTzFrom(query, timeRange, []string{"time_column"})
+		require.NoError(t, err)
+		assert.True(t, from.Equal(start))
+
+		start, err = TimeFilterTzFrom(query, timeRange, []string{"time_column", "'1d'"})
+		require.NoError(t, err)
+		assert.True(t, time.Date(2021, 3, 27, 0, 0, 0, 0, loc).Equal(start))
+
+		// the first filled in value of $__timeGroupTz starts at the same time
+		cfg := &processCfg{fillInterval: float64(24 * time.Hour / time.Millisecond), fillLocation: loc}
+		assert.Equal(t, float64(start.UnixNano()/1e6), cfg.alignFill(float64(from.UnixNano()/1e6)))
+
+		_, err = TimeFilterTzFrom(query, timeRange, []string{"time_column", "'1x'"})
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,191 @@
+package sqlite
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/plugins"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestMacroEngine(t *testing.T) {
+	Convey("MacroEngine", t, func() {
+		engine := newSqliteMacroEngine()
+		query := plugins.DataSubQuery{}
+
+		Convey("Given a time range between 2018-04-12 00:00 and 2018-04-12 00:05", func() {
+			from := time.Date(2018, 4, 12, 18, 0, 0, 0, time.UTC)
+			to := from.Add(5 * time.Minute)
+			timeRange := plugins.DataTimeRange{From: "5m", Now: to, To: "now"}
+
+			Convey("interpolate __time function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__time(time_column)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "select CAST(strftime('%s', time_column) AS INTEGER) AS time")
+			})
+
+			Convey("interpolate __timeGroup function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column,'5m')")
+				So(err, ShouldBeNil)
+				sql2, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroupAlias(time_column,'5m')")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "GROUP BY CAST(CAST(strftime('%s', time_column) AS INTEGER) / 300 AS INTEGER) * 300")
+				So(sql2, ShouldEqual, sql+" AS \"time\"")
+			})
+
+			Convey("interpolate __timeGroup function with spaces around arguments", func() {
+				sql, err := engine.Interpolate(query, timeRange, "GROUP BY $__timeGroup(time_column , '5m')")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "GROUP BY CAST(CAST(strftime('%s', time_column) AS INTEGER) / 300 AS INTEGER) * 300")
+			})
+
+			Convey("interpolate __timeGroup function with fill", func() {
+				fillQuery := plugins.DataSubQuery{Model: simplejson.New()}
+				_, err := engine.Interpolate(fillQuery, timeRange, "GROUP BY $__timeGroup(time_column,'5m', previous)")
+				So(err, ShouldBeNil)
+
+				So(fillQuery.Model.Get("fill").MustBool(), ShouldBeTrue)
+				So(fillQuery.Model.Get("fillInterval").MustFloat64(), ShouldEqual, 300)
+				So(fillQuery.Model.Get("fillMode").MustString(), ShouldEqual, "previous")
+			})
+
+			Convey("interpolate __timeFilter function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "WHERE $__timeFilter(time_column)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("WHERE CAST(strftime('%%s', time_column) AS INTEGER) BETWEEN %d AND %d", from.Unix(), to.Unix()))
+			})
+
+			Convey("interpolate __timeFrom function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__timeFrom()")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("select datetime(%d, 'unixepoch')", from.Unix()))
+			})
+
+			Convey("interpolate __timeTo function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__timeTo()")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("select datetime(%d, 'unixepoch')", to.Unix()))
+			})
+
+			Convey("interpolate __unixEpochFilter function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochFilter(time)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("select time >= %d AND time <= %d", from.Unix(), to.Unix()))
+			})
+
+			Convey("interpolate __unixEpochNanoFilter function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochNanoFilter(time)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("select time >= %d AND time <= %d", from.UnixNano(), to.UnixNano()))
+			})
+
+			Convey("interpolate __unixEpochNanoFrom function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochNanoFrom()")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("select %d", from.UnixNano()))
+			})
+
+			Convey("interpolate __unixEpochNanoTo function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "select $__unixEpochNanoTo()")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, fmt.Sprintf("select %d", to.UnixNano()))
+			})
+
+			Convey("interpolate __unixEpochGroup function", func() {
+				sql, err := engine.Interpolate(query, timeRange, "SELECT $__unixEpochGroup(time_column,'5m')")
+				So(err, ShouldBeNil)
+				sql2, err := engine.Interpolate(query, timeRange, "SELECT $__unixEpochGroupAlias(time_column,'5m')")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "SELECT CAST(time_column / 300 AS INTEGER) * 300")
+				So(sql2, ShouldEqual, sql+" AS \"time\"")
+			})
+
+			Convey("interpolate unknown macro", func() {
+				_, err := engine.Interpolate(query, timeRange, "SELECT $__unknown(time_column)")
+				So(err, ShouldNotBeNil)
+				So(err.Error(), ShouldEqual, "unknown macro __unknown")
+			})
+		})
+
+		Convey("Given a time range across the daylight saving time change in the dashboard's time zone", func() {
+			// Berlin changes from UTC+1 to UTC+2 at 2021-03-28 01:00 UTC
+			from := time.Date(2021, 3, 27, 0, 0, 0, 0, time.UTC)
+			to := from.Add(48 * time.Hour)
+			timeRange := plugins.NewDataTimeRange(
+				strconv.FormatInt(from.UnixNano()/int64(time.Millisecond), 10),
+				strconv.FormatInt(to.UnixNano()/int64(time.Millisecond), 10))
+			tzQuery := plugins.DataSubQuery{Model: simplejson.NewFromAny(map[string]interface{}{"timezone": "Europe/Berlin"})}
+
+			Convey("interpolate __timeFilterTz function", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilterTz(time_column)")
+				So(err, ShouldBeNil)
+
+				expected, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilter(time_column)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "WHERE CAST(strftime('%s', time_column) AS INTEGER) BETWEEN 1616803200 AND 1616976000")
+				So(sql, ShouldEqual, expected)
+			})
+
+			Convey("interpolate __timeFilterTz function with interval", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilterTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				// the range starts at midnight in Berlin
+				So(sql, ShouldEqual, "WHERE CAST(strftime('%s', time_column) AS INTEGER) BETWEEN 1616799600 AND 1616976000")
+			})
+
+			Convey("interpolate __timeFilterTz and __timeGroupTz functions in the same query", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "SELECT $__timeGroupTz(time_column,'1d') WHERE $__timeFilterTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+				group, err := engine.Interpolate(tzQuery, timeRange, "$__timeGroupTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				// both take the column to store UTC times, and the filter starts at the start of the first group
+				So(sql, ShouldEqual, "SELECT "+group+" WHERE CAST(strftime('%s', time_column) AS INTEGER) BETWEEN 1616799600 AND 1616976000")
+			})
+
+			Convey("interpolate __timeFilterRange function", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "WHERE $__timeFilterRange(time_column)")
+				So(err, ShouldBeNil)
+
+				So(sql, ShouldEqual, "WHERE CAST(strftime('%s', time_column) AS INTEGER) >= 1616803200 AND CAST(strftime('%s', time_column) AS INTEGER) < 1616976000")
+			})
+
+			Convey("interpolate __timeGroupTz function", func() {
+				sql, err := engine.Interpolate(tzQuery, timeRange, "GROUP BY $__timeGroupTz(time_column,'1d')")
+				So(err, ShouldBeNil)
+				sql2, err := engine.Interpolate(tzQuery, timeRange, "SELECT $__timeGroupTzAlias(time_column,'1d')")
+				So(err, ShouldBeNil)
+
+				epoch := "CAST(strftime('%s', time_column) AS INTEGER)"
+				start := fmt.Sprintf("CAST((%s + CASE WHEN %s < 1616893200 THEN 3600 ELSE 7200 END) / 86400 AS INTEGER) * 86400", epoch, epoch)
+				So(sql, ShouldEqual, fmt.Sprintf("GROUP BY (%s - CASE WHEN %s < 1616896800 THEN 3600 ELSE 7200 END)", start, start))
+				So(sql2, ShouldEqual, strings.Replace(sql, "GROUP BY", "SELECT", 1)+" AS \"time\"")
+			})
+
+			Convey("interpolate __timeGroupTz function with fill", func() {
+				_, err := engine.Interpolate(tzQuery, timeRange, "GROUP BY $__timeGroupTz(time_column,'1d',0)")
+				So(err, ShouldBeNil)
+
+				So(tzQuery.Model.Get("fillInterval").MustFloat64(), ShouldEqual, 86400)
+				So(tzQuery.Model.Get("fillTimezone").MustString(), ShouldEqual, "Europe/Berlin")
+			})
+		})
+	})
+}
@@ -0,0 +1,152 @@
+package sqlite
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/grafana/grafana/pkg/infra/log"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/grafana/grafana/pkg/tsdb/sqleng"
+	"github.com/mattn/go-sqlite3"
+	"xorm.io/core"
+)
+
+// driverName is the database/sql driver of SQLite data sources, which only allows queries that read data.
+const driverName = "sqlite3_datasource"
+
+// sqliteRecursive is the authorizer action of recursive common table expressions, which go-sqlite3
+// doesn't export.
+const sqliteRecursive = 33
+
+func init() {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			conn.RegisterAuthorizer(authorize)
+			return nil
+		},
+	})
+	core.RegisterDriver(driverName, core.QueryDriver("sqlite3"))
+}
+
+// authorize denies everything but reading data, so that queries can't change the database or attach
+// other database files.
+func authorize(action int, arg1, arg2, arg3 string) int {
+	switch action {
+	case sqlite3.SQLITE_SELECT, sqlite3.SQLITE_READ, sqliteRecursive:
+		return sqlite3.SQLITE_OK
+	case sqlite3.SQLITE_FUNCTION:
+		if strings.EqualFold(arg2, "load_extension") {
+			return sqlite3.SQLITE_DENY
+		}
+		return sqlite3.SQLITE_OK
+	default:
+		return sqlite3.SQLITE_DENY
+	}
+}
+
+func NewExecutor(datasource *models.DataSource, settings setting.SQLDataSourcesSettings) (plugins.DataPlugin, error) {
+	logger := log.New("tsdb.sqlite")
+
+	path, err := databasePath(datasource.Database, settings.SQLiteAllowedPaths)
+	if err != nil {
+		return nil, err
+	}
+
+	cnnstr := fmt.Sprintf("file:%s?mode=ro", (&url.URL{Path: path}).EscapedPath())
+	if setting.Env == setting.Dev {
+		logger.Debug("getEngine", "connection", cnnstr)
+	}
+
+	config := sqleng.DataPluginConfiguration{
+		DriverName:        driverName,
+		ConnectionString:  cnnstr,
+		Datasource:        datasource,
+		MetricColumnTypes: []string{"TEXT", "text", "VARCHAR", "varchar", "CHAR", "char"},
+		RowLimit:          settings.RowLimit,
+		QueryTimeout:      settings.QueryTimeout,
+	}
+
+	rowTransformer := sqliteQueryResultTransformer{
+		log: logger,
+	}
+
+	return sqleng.NewDataPlugin(config, &rowTransformer, newSqliteMacroEngine(), logger)
+}
+
+// databasePath returns the absolute path of the database file of a data source, which has to be in one of
+// the allowed directories.
+func databasePath(path string, allowedPaths []string) (string, error) {
+	if path == "" {
+		return "", errors.New("SQLite database path is not set")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	// files that don't exist fail when they're opened, but links have to point into the allowed directories
+	if resolved, err := filepath.EvalSymlinks(absPath); err == nil {
+		absPath = resolved
+	} else if _, err := os.Lstat(absPath); err == nil {
+		return "", fmt.Errorf("SQLite database %q is a link that can't be resolved", path)
+	}
+
+	for _, allowed := range allowedPaths {
+		if resolved, err := filepath.EvalSymlinks(allowed); err == nil {
+			allowed = resolved
+		}
+		rel, err := filepath.Rel(allowed, absPath)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return absPath, nil
+		}
+	}
+
+	return "", fmt.Errorf("SQLite database %q is not in one of the directories of sqlite_allowed_paths", path)
+}
+
+type sqliteQueryResultTransformer struct {
+	log log.Logger
+}
+
+func (t *sqliteQueryResultTransformer) TransformQueryResult(columnTypes []*sql.ColumnType, rows *core.Rows) (
+	plugins.DataRowValues, error) {
+	values := make([]interface{}, len(columnTypes))
+	valuePtrs := make([]interface{}, len(columnTypes))
+
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+
+	// SQLite values are integers, floats, text or blobs, and columns declared as dates or timestamps
+	// are times
+	for i := range values {
+		if value, ok := values[i].([]byte); ok {
+			values[i] = string(value)
+		}
+	}
+
+	return values, nil
+}
+
+func (t *sqliteQueryResultTransformer) TransformQueryError(err error) error {
+	var driverErr sqlite3.Error
+	if errors.As(err, &driverErr) && driverErr.Code == sqlite3.ErrAuth {
+		t.log.Debug("query denied", "err", err)
+		return errQueryNotAllowed
+	}
+
+	return err
+}
+
+var errQueryNotAllowed = errors.New("query not allowed - SQLite data sources can only read data")
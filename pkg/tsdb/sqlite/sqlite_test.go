@@ -0,0 +1,173 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/grafana/grafana/pkg/setting"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLite(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "metrics.db")
+	from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	createTestDatabase(t, dbPath, from)
+
+	settings := setting.SQLDataSourcesSettings{SQLiteAllowedPaths: []string{dir}}
+	timeRange := plugins.NewDataTimeRange(strconv.FormatInt(from.UnixNano()/1e6, 10),
+		strconv.FormatInt(from.Add(10*time.Minute).UnixNano()/1e6, 10))
+	newQuery := func(format string, rawSQL string) plugins.DataQuery {
+		return plugins.DataQuery{
+			TimeRange: &timeRange,
+			Queries: []plugins.DataSubQuery{
+				{
+					RefID:      "A",
+					DataSource: &models.DataSource{},
+					Model:      simplejson.NewFromAny(map[string]interface{}{"rawSql": rawSQL, "format": format}),
+				},
+			},
+		}
+	}
+
+	executor, err := NewExecutor(&models.DataSource{Id: 1, Type: "sqlite", Database: dbPath, JsonData: simplejson.New()}, settings)
+	require.NoError(t, err)
+
+	t.Run("Time series queries group rows by interval and metric", func(t *testing.T) {
+		resp, err := executor.DataQuery(context.Background(), nil, newQuery("time_series",
+			"SELECT $__timeGroupAlias(time, '5m'), host AS metric, sum(value) AS value FROM metrics "+
+				"WHERE $__timeFilter(time) GROUP BY 1, 2 ORDER BY 1"))
+		require.NoError(t, err)
+
+		result := resp.Results["A"]
+		require.NoError(t, result.Error)
+		require.Len(t, result.Series, 2)
+		assert.Equal(t, "a", result.Series[0].Name)
+		assert.Equal(t, "b", result.Series[1].Name)
+		// the time filter includes the end of the time range
+		require.Len(t, result.Series[0].Points, 3)
+		assert.Equal(t, float64(from.UnixNano()/1e6), result.Series[0].Points[0][1].Float64)
+		assert.Equal(t, 0+1+2+3+4.0, result.Series[0].Points[0][0].Float64)
+		assert.Equal(t, float64(from.Add(5*time.Minute).UnixNano()/1e6), result.Series[0].Points[1][1].Float64)
+		assert.Equal(t, 5+6+7+8+9.0, result.Series[0].Points[1][0].Float64)
+		assert.Equal(t, 10.0, result.Series[0].Points[2][0].Float64)
+	})
+
+	t.Run("Time series queries fill in missing intervals", func(t *testing.T) {
+		resp, err := executor.DataQuery(context.Background(), nil, newQuery("time_series",
+			"SELECT $__unixEpochGroupAlias(epoch, '1m', 0), value FROM metrics "+
+				"WHERE host = 'a' AND value % 2 = 0 AND $__unixEpochFilter(epoch) ORDER BY 1"))
+		require.NoError(t, err)
+
+		result := resp.Results["A"]
+		require.NoError(t, result.Error)
+		require.Len(t, result.Series, 1)
+		points := result.Series[0].Points
+		require.Len(t, points, 11)
+		for i, point := range points {
+			assert.Equal(t, float64(from.Add(time.Duration(i)*time.Minute).UnixNano()/1e6), point[1].Float64)
+			if i%2 == 0 {
+				assert.Equal(t, float64(i), point[0].Float64)
+			} else {
+				assert.Equal(t, float64(0), point[0].Float64)
+			}
+		}
+	})
+
+	t.Run("Table queries return times in milliseconds and text as strings", func(t *testing.T) {
+		resp, err := executor.DataQuery(context.Background(), nil, newQuery("table",
+			"SELECT time, host, value FROM metrics WHERE host = 'b' AND $__timeFilter(time) ORDER BY time LIMIT 2"))
+		require.NoError(t, err)
+
+		result := resp.Results["A"]
+		require.NoError(t, result.Error)
+		require.Len(t, result.Tables, 1)
+		table := result.Tables[0]
+		require.Len(t, table.Rows, 2)
+		assert.Equal(t, "time", table.Columns[0].Text)
+		assert.Equal(t, float64(from.UnixNano()/1e6), table.Rows[0][0])
+		assert.Equal(t, "b", table.Rows[0][1])
+		assert.Equal(t, float64(0), table.Rows[0][2])
+	})
+
+	t.Run("Queries can't change the database", func(t *testing.T) {
+		for _, rawSQL := range []string{
+			"DELETE FROM metrics",
+			"UPDATE metrics SET value = 0",
+			"DROP TABLE metrics",
+			fmt.Sprintf("ATTACH DATABASE '%s' AS other", filepath.Join(dir, "other.db")),
+			"PRAGMA journal_mode = DELETE",
+		} {
+			resp, err := executor.DataQuery(context.Background(), nil, newQuery("table", rawSQL))
+			require.NoError(t, err)
+
+			require.Error(t, resp.Results["A"].Error, rawSQL)
+			assert.Equal(t, errQueryNotAllowed, resp.Results["A"].Error, rawSQL)
+		}
+
+		resp, err := executor.DataQuery(context.Background(), nil, newQuery("table", "SELECT count(*) FROM metrics"))
+		require.NoError(t, err)
+		require.NoError(t, resp.Results["A"].Error)
+		assert.Equal(t, int64(40), resp.Results["A"].Tables[0].Rows[0][0])
+	})
+
+	t.Run("Databases have to be in the allowed paths", func(t *testing.T) {
+		_, err := NewExecutor(&models.DataSource{Id: 2, Type: "sqlite", Database: dbPath, JsonData: simplejson.New()},
+			setting.SQLDataSourcesSettings{})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not in one of the directories of sqlite_allowed_paths")
+
+		outside := filepath.Join(dir, "..", "grafana.db")
+		_, err = NewExecutor(&models.DataSource{Id: 3, Type: "sqlite", Database: outside, JsonData: simplejson.New()}, settings)
+		require.Error(t, err)
+
+		link := filepath.Join(dir, "link.db")
+		require.NoError(t, os.Symlink(outside, link))
+		_, err = NewExecutor(&models.DataSource{Id: 4, Type: "sqlite", Database: link, JsonData: simplejson.New()}, settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is a link that can't be resolved")
+
+		require.NoError(t, ioutil.WriteFile(outside, nil, 0600))
+		_, err = NewExecutor(&models.DataSource{Id: 5, Type: "sqlite", Database: link, JsonData: simplejson.New()}, settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not in one of the directories of sqlite_allowed_paths")
+
+		_, err = NewExecutor(&models.DataSource{Id: 6, Type: "sqlite", JsonData: simplejson.New()}, settings)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "path is not set")
+	})
+}
+
+// createTestDatabase creates a database with a row of each of the hosts a and b per minute from from on,
+// whose values are the minutes since from.
+func createTestDatabase(t *testing.T, path string, from time.Time) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", path)
+	require.NoError(t, err)
+	defer func() {
+		require.NoError(t, db.Close())
+	}()
+
+	_, err = db.Exec("CREATE TABLE metrics (time DATETIME, epoch INTEGER, host TEXT, value REAL)")
+	require.NoError(t, err)
+	for i := 0; i < 20; i++ {
+		ts := from.Add(time.Duration(i) * time.Minute)
+		for _, host := range []string{"a", "b"} {
+			_, err := db.Exec("INSERT INTO metrics (time, epoch, host, value) VALUES (?, ?, ?, ?)",
+				ts.Format("2006-01-02 15:04:05"), ts.Unix(), host, float64(i))
+			require.NoError(t, err)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package tempo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+)
+
+const (
+	queryTypeSearch = "search"
+
+	// defaultSearchLimit is the number of traces that searches return if the query doesn't set a limit.
+	defaultSearchLimit = 20
+)
+
+// traceSearchQuery is a search for traces. Service and operation names are matched against the root
+// span of the traces, tags against any span.
+type traceSearchQuery struct {
+	ServiceName string
+	SpanName    string
+	// Tags are matched in logfmt, such as http.status_code=500 error=true.
+	Tags        string
+	MinDuration string
+	MaxDuration string
+	Limit       int
+}
+
+type traceSearchResponse struct {
+	Traces []*traceSearchMetadata `json:"traces"`
+}
+
+type traceSearchMetadata struct {
+	TraceID           string `json:"traceID"`
+	RootServiceName   string `json:"rootServiceName"`
+	RootTraceName     string `json:"rootTraceName"`
+	StartTimeUnixNano string `json:"startTimeUnixNano"`
+	DurationMs        int64  `json:"durationMs"`
+}
+
+func parseSearchQuery(query plugins.DataSubQuery) (*traceSearchQuery, error) {
+	search := &traceSearchQuery{
+		ServiceName: query.Model.Get("serviceName").MustString(""),
+		SpanName:    query.Model.Get("spanName").MustString(""),
+		Tags:        query.Model.Get("search").MustString(""),
+		MinDuration: query.Model.Get("minDuration").MustString(""),
+		MaxDuration: query.Model.Get("maxDuration").MustString(""),
+		Limit:       query.Model.Get("limit").MustInt(defaultSearchLimit),
+	}
+
+	for _, duration := range []string{search.MinDuration, search.MaxDuration} {
+		if duration == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(duration); err != nil {
+			return nil, fmt.Errorf("invalid duration %q, must be a duration such as 100ms or 1.5s", duration)
+		}
+	}
+	if search.Limit < 1 {
+		return nil, fmt.Errorf("invalid limit %d, must be a positive number", search.Limit)
+	}
+
+	return search, nil
+}
+
+// tags returns the tags of the search in logfmt, which is how Tempo expects them.
+func (q *traceSearchQuery) tags() string {
+	tags := []string{}
+	if q.ServiceName != "" {
+		tags = append(tags, "service.name="+strconv.Quote(q.ServiceName))
+	}
+	if q.SpanName != "" {
+		tags = append(tags, "name="+strconv.Quote(q.SpanName))
+	}
+	if q.Tags != "" {
+		tags = append(tags, strings.TrimSpace(q.Tags))
+	}
+	return strings.Join(tags, " ")
+}
+
+// searchTraces searches for traces in the time range and returns them as a table.
+func (e *tempoExecutor) searchTraces(ctx context.Context, dsInfo *models.DataSource, timeRange *plugins.DataTimeRange,
+	query plugins.DataSubQuery) (plugins.DataQueryResult, error) {
+	queryResult := plugins.DataQueryResult{}
+
+	search, err := parseSearchQuery(query)
+	if err != nil {
+		return queryResult, err
+	}
+
+	req, err := e.createSearchRequest(ctx, dsInfo, timeRange, search)
+	if err != nil {
+		return queryResult, err
+	}
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return queryResult, fmt.Errorf("failed to search tempo: %w", err)
+	}
+
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			tlog.Warn("failed to close response body", "err", err)
+		}
+	}()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return queryResult, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		queryResult.ErrorString = fmt.Sprintf("failed to search traces Status: %s Body: %s", resp.Status, string(body))
+		return queryResult, nil
+	}
+
+	var searchResponse traceSearchResponse
+	if err := json.Unmarshal(body, &searchResponse); err != nil {
+		return queryResult, fmt.Errorf("failed to parse tempo search response: %w", err)
+	}
+
+	frame, err := traceSearchFrame(searchResponse.Traces, query.RefID)
+	if err != nil {
+		return queryResult, err
+	}
+	queryResult.Dataframes = plugins.NewDecodedDataFrames(data.Frames{frame})
+
+	return queryResult, nil
+}
+
+func (e *tempoExecutor) createSearchRequest(ctx context.Context, dsInfo *models.DataSource, timeRange *plugins.DataTimeRange,
+	search *traceSearchQuery) (*http.Request, error) {
+	params := url.Values{}
+	if tags := search.tags(); tags != "" {
+		params.Set("tags", tags)
+	}
+	if search.MinDuration != "" {
+		params.Set("minDuration", search.MinDuration)
+	}
+	if search.MaxDuration != "" {
+		params.Set("maxDuration", search.MaxDuration)
+	}
+	params.Set("limit", strconv.Itoa(search.Limit))
+
+	if timeRange != nil {
+		start, err := timeRange.ParseFrom()
+		if err != nil {
+			return nil, err
+		}
+		end, err := timeRange.ParseTo()
+		if err != nil {
+			return nil, err
+		}
+		params.Set("start", strconv.FormatInt(start.Unix(), 10))
+		params.Set("end", strconv.FormatInt(end.Unix(), 10))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", dsInfo.Url+"/api/search?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if dsInfo.BasicAuth {
+		req.SetBasicAuth(dsInfo.BasicAuthUser, dsInfo.DecryptedBasicAuthPassword())
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	tlog.Debug("Tempo search request", "url", req.URL.String())
+	return req, nil
+}
+
+// traceSearchFrame returns a table with a row per trace, in the order Tempo returned them.
+func traceSearchFrame(traces []*traceSearchMetadata, refID string) (*data.Frame, error) {
+	traceIDs := make([]string, len(traces))
+	traceNames := make([]string, len(traces))
+	serviceNames := make([]string, len(traces))
+	startTimes := make([]time.Time, len(traces))
+	durations := make([]float64, len(traces))
+
+	for i, trace := range traces {
+		startTime, err := strconv.ParseInt(trace.StartTimeUnixNano, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid start time of trace %s: %w", trace.TraceID, err)
+		}
+
+		traceIDs[i] = trace.TraceID
+		traceNames[i] = trace.RootTraceName
+		serviceNames[i] = trace.RootServiceName
+		startTimes[i] = time.Unix(0, startTime)
+		durations[i] = float64(trace.DurationMs)
+	}
+
+	durationField := data.NewField("duration", nil, durations)
+	durationField.SetConfig(&data.FieldConfig{Unit: "ms"})
+
+	frame := data.NewFrame("Traces",
+		data.NewField("traceID", nil, traceIDs),
+		data.NewField("traceName", nil, traceNames),
+		data.NewField("rootServiceName", nil, serviceNames),
+		data.NewField("startTime", nil, startTimes),
+		durationField,
+	)
+	frame.RefID = refID
+	frame.Meta = &data.FrameMeta{PreferredVisualization: data.VisTypeTable}
+	return frame, nil
+}
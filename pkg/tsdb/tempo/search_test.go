@@ -0,0 +1,87 @@
+package tempo
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana/pkg/components/simplejson"
+	"github.com/grafana/grafana/pkg/models"
+	"github.com/grafana/grafana/pkg/plugins"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTempoSearch(t *testing.T) {
+	var searchParams map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/search":
+			searchParams = r.URL.Query()
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"traces": [
+				{"traceID": "2f3e0cee77ae5dc9", "rootServiceName": "shop-backend", "rootTraceName": "update-billing",
+					"startTimeUnixNano": "1600000000000000000", "durationMs": 65}
+			]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("trace not found"))
+		}
+	}))
+	defer server.Close()
+
+	dsInfo := &models.DataSource{Url: server.URL}
+	plug, err := NewExecutor(dsInfo)
+	require.NoError(t, err)
+
+	subQuery := func(refID string, json string) plugins.DataSubQuery {
+		model, err := simplejson.NewJson([]byte(json))
+		require.NoError(t, err)
+		return plugins.DataSubQuery{RefID: refID, Model: model}
+	}
+
+	timeRange := plugins.NewDataTimeRange("1h", "now")
+	resp, err := plug.DataQuery(context.Background(), dsInfo, plugins.DataQuery{
+		TimeRange: &timeRange,
+		Queries: []plugins.DataSubQuery{
+			subQuery("A", `{"queryType": "search", "serviceName": "shop-backend", "spanName": "update billing",
+				"search": "http.status_code=500", "minDuration": "50ms", "limit": 5}`),
+			subQuery("B", `{"query": "missing"}`),
+			subQuery("C", `{"queryType": "search", "maxDuration": "a while"}`),
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, resp.Results, 3)
+
+	t.Run("searches return a table of traces", func(t *testing.T) {
+		result := resp.Results["A"]
+		require.NoError(t, result.Error)
+		require.Empty(t, result.ErrorString)
+
+		assert.Equal(t, []string{`service.name="shop-backend" name="update billing" http.status_code=500`}, searchParams["tags"])
+		assert.Equal(t, []string{"50ms"}, searchParams["minDuration"])
+		assert.Equal(t, []string{"5"}, searchParams["limit"])
+		assert.NotEmpty(t, searchParams["start"])
+
+		frames, err := result.Dataframes.Decoded()
+		require.NoError(t, err)
+		require.Len(t, frames, 1)
+
+		frame := frames[0]
+		assert.Equal(t, "A", frame.RefID)
+		require.Equal(t, 1, frame.Rows())
+		assert.Equal(t, "2f3e0cee77ae5dc9", frame.Fields[0].At(0))
+		assert.Equal(t, "update-billing", frame.Fields[1].At(0))
+		assert.Equal(t, "shop-backend", frame.Fields[2].At(0))
+		assert.Equal(t, time.Unix(1600000000, 0).UTC(), frame.Fields[3].At(0).(time.Time).UTC())
+		assert.Equal(t, 65.0, frame.Fields[4].At(0))
+	})
+
+	t.Run("errors are returned per query", func(t *testing.T) {
+		assert.Contains(t, resp.Results["B"].ErrorString, "trace not found")
+		assert.Error(t, resp.Results["C"].Error)
+		assert.Equal(t, "C", resp.Results["C"].RefID)
+	})
+}
@@ -39,20 +39,45 @@ var (
 	tlog = log.New("tsdb.tempo")
 )
 
+// DataQuery runs each query of the request, which either fetches a trace by its id or searches for traces.
+// Errors are returned in the result of the query they belong to.
 func (e *tempoExecutor) DataQuery(ctx context.Context, dsInfo *models.DataSource,
 	queryContext plugins.DataQuery) (plugins.DataResponse, error) {
-	refID := queryContext.Queries[0].RefID
+	result := plugins.DataResponse{
+		Results: map[string]plugins.DataQueryResult{},
+	}
+
+	for _, query := range queryContext.Queries {
+		var queryResult plugins.DataQueryResult
+		var err error
+		if query.Model.Get("queryType").MustString("") == queryTypeSearch {
+			queryResult, err = e.searchTraces(ctx, dsInfo, queryContext.TimeRange, query)
+		} else {
+			queryResult, err = e.getTrace(ctx, dsInfo, query)
+		}
+		if err != nil {
+			queryResult = plugins.DataQueryResult{Error: err}
+		}
+
+		queryResult.RefID = query.RefID
+		result.Results[query.RefID] = queryResult
+	}
+
+	return result, nil
+}
+
+func (e *tempoExecutor) getTrace(ctx context.Context, dsInfo *models.DataSource, query plugins.DataSubQuery) (plugins.DataQueryResult, error) {
 	queryResult := plugins.DataQueryResult{}
-	traceID := queryContext.Queries[0].Model.Get("query").MustString("")
+	traceID := query.Model.Get("query").MustString("")
 
 	req, err := e.createRequest(ctx, dsInfo, traceID)
 	if err != nil {
-		return plugins.DataResponse{}, err
+		return queryResult, err
 	}
 
 	resp, err := e.httpClient.Do(req)
 	if err != nil {
-		return plugins.DataResponse{}, fmt.Errorf("failed get to tempo: %w", err)
+		return queryResult, fmt.Errorf("failed get to tempo: %w", err)
 	}
 
 	defer func() {
@@ -63,27 +88,23 @@ func (e *tempoExecutor) DataQuery(ctx context.Context, dsInfo *models.DataSource
 
 	body, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return plugins.DataResponse{}, err
+		return queryResult, err
 	}
 
 	if resp.StatusCode != http.StatusOK {
 		queryResult.ErrorString = fmt.Sprintf("failed to get trace with id: %s Status: %s Body: %s", traceID, resp.Status, string(body))
-		return plugins.DataResponse{
-			Results: map[string]plugins.DataQueryResult{
-				refID: queryResult,
-			},
-		}, nil
+		return queryResult, nil
 	}
 
 	otTrace := ot_pdata.NewTraces()
 	err = otTrace.FromOtlpProtoBytes(body)
 	if err != nil {
-		return plugins.DataResponse{}, fmt.Errorf("failed to convert tempo response to Otlp: %w", err)
+		return queryResult, fmt.Errorf("failed to convert tempo response to Otlp: %w", err)
 	}
 
 	jaegerBatches, err := ot_jaeger.InternalTracesToJaegerProto(otTrace)
 	if err != nil {
-		return plugins.DataResponse{}, fmt.Errorf("failed to translate to jaegerBatches %v: %w", traceID, err)
+		return queryResult, fmt.Errorf("failed to translate to jaegerBatches %v: %w", traceID, err)
 	}
 
 	jaegerTrace := &jaeger.Trace{
@@ -107,19 +128,15 @@ func (e *tempoExecutor) DataQuery(ctx context.Context, dsInfo *models.DataSource
 
 	traceBytes, err := json.Marshal(jsonTrace)
 	if err != nil {
-		return plugins.DataResponse{}, fmt.Errorf("failed to json.Marshal trace \"%s\" :%w", traceID, err)
+		return queryResult, fmt.Errorf("failed to json.Marshal trace \"%s\" :%w", traceID, err)
 	}
 
 	frames := []*data.Frame{
-		{Name: "Traces", RefID: refID, Fields: []*data.Field{data.NewField("trace", nil, []string{string(traceBytes)})}},
+		{Name: "Traces", RefID: query.RefID, Fields: []*data.Field{data.NewField("trace", nil, []string{string(traceBytes)})}},
 	}
 	queryResult.Dataframes = plugins.NewDecodedDataFrames(frames)
 
-	return plugins.DataResponse{
-		Results: map[string]plugins.DataQueryResult{
-			refID: queryResult,
-		},
-	}, nil
+	return queryResult, nil
 }
 
 func (e *tempoExecutor) createRequest(ctx context.Context, dsInfo *models.DataSource, traceID string) (*http.Request, error) {
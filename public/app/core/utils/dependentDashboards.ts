@@ -0,0 +1,33 @@
+import { getBackendSrv } from '@grafana/runtime';
+
+export interface DependentDashboard {
+  id: number;
+  uid: string;
+  title: string;
+  folderId: number;
+}
+
+/**
+ * Returns the dashboards that break if the entity of the dependents url is deleted or disabled.
+ * The check only adds a warning, so failing to get the dashboards doesn't block the user.
+ */
+export async function getDependentDashboards(url: string): Promise<DependentDashboard[]> {
+  try {
+    return await getBackendSrv().get(url);
+  } catch (err) {
+    return [];
+  }
+}
+
+/**
+ * Returns the warning shown in the confirm modal, or undefined if no dashboard depends on the entity.
+ */
+export function dependentDashboardsWarning(dependents: DependentDashboard[], what: string): string | undefined {
+  if (dependents.length === 0) {
+    return undefined;
+  }
+  const titles = dependents.map((dash) => dash.title).join(', ');
+  return `${dependents.length} dashboard${dependents.length === 1 ? '' : 's'} ${
+    dependents.length === 1 ? 'uses' : 'use'
+  } ${what} and will break: ${titles}`;
+}
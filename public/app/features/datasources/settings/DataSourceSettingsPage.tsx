@@ -31,6 +31,7 @@ import { GrafanaRouteComponentProps } from 'app/core/navigation/types';
 import { connect, ConnectedProps } from 'react-redux';
 import { cleanUpAction } from 'app/core/actions/cleanUp';
 import { ShowConfirmModalEvent } from '../../../types/events';
+import { dependentDashboardsWarning, getDependentDashboards } from 'app/core/utils/dependentDashboards';
 
 export interface OwnProps extends GrafanaRouteComponentProps<{ id: string }> {}
 
@@ -99,11 +100,13 @@ export class DataSourceSettingsPage extends PureComponent<Props> {
     this.testDataSource();
   };
 
-  onDelete = () => {
+  onDelete = async () => {
+    const dependents = await getDependentDashboards(`/api/datasources/uid/${this.props.dataSource.uid}/dependents`);
     appEvents.publish(
       new ShowConfirmModalEvent({
         title: 'Delete',
         text: 'Are you sure you want to delete this data source?',
+        text2: dependentDashboardsWarning(dependents, 'this data source'),
         yesText: 'Delete',
         icon: 'trash-alt',
         onConfirm: () => {
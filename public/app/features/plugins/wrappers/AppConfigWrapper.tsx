@@ -8,6 +8,9 @@ import { PluginMeta, AppPlugin, deprecationWarning } from '@grafana/data';
 import { AngularComponent, getAngularLoader, getBackendSrv } from '@grafana/runtime';
 
 import { css } from 'emotion';
+import appEvents from 'app/core/app_events';
+import { ShowConfirmModalEvent } from 'app/types/events';
+import { dependentDashboardsWarning, getDependentDashboards } from 'app/core/utils/dependentDashboards';
 
 interface Props {
   app: AppPlugin;
@@ -140,7 +143,27 @@ export class AppConfigCtrlWrapper extends PureComponent<Props, State> {
     this.update();
   };
 
-  disable = () => {
+  disable = async () => {
+    const dependents = await getDependentDashboards(`/api/plugins/${this.model.id}/dependents`);
+    const warning = dependentDashboardsWarning(dependents, 'this app');
+    if (!warning) {
+      this.disableConfirmed();
+      return;
+    }
+
+    appEvents.publish(
+      new ShowConfirmModalEvent({
+        title: 'Disable',
+        text: 'Are you sure you want to disable this app?',
+        text2: warning,
+        yesText: 'Disable',
+        icon: 'exclamation-triangle',
+        onConfirm: this.disableConfirmed,
+      })
+    );
+  };
+
+  disableConfirmed = () => {
     this.model.enabled = false;
     this.model.pinned = false;
     this.update();
@@ -2,7 +2,7 @@ import _ from 'lodash';
 import { Observable, of } from 'rxjs';
 import { catchError, map, mapTo } from 'rxjs/operators';
 import { getBackendSrv } from '@grafana/runtime';
-import { ScopedVars } from '@grafana/data';
+import { getTimeZoneInfo, ScopedVars } from '@grafana/data';
 
 import ResponseParser, { MssqlResponse } from './response_parser';
 import { getTemplateSrv, TemplateSrv } from 'app/features/templating/template_srv';
@@ -69,6 +69,8 @@ export class MssqlDatasource {
   }
 
   query(options: any): Observable<MssqlResponse> {
+    // the dashboard's time zone for the time zone aware macros
+    const timezone = getTimeZoneInfo(options.timezone, Date.now())?.ianaName;
     const queries = _.filter(options.targets, (item) => {
       return item.hide !== true;
     }).map((item) => {
@@ -79,6 +81,7 @@ export class MssqlDatasource {
         datasourceId: this.id,
         rawSql: this.templateSrv.replace(item.rawSql, options.scopedVars, this.interpolateVariable),
         format: item.format,
+        timezone,
       };
     });
 
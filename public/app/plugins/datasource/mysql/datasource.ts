@@ -2,7 +2,7 @@ import _ from 'lodash';
 import { Observable, of } from 'rxjs';
 import { catchError, map, mapTo } from 'rxjs/operators';
 import { getBackendSrv } from '@grafana/runtime';
-import { ScopedVars } from '@grafana/data';
+import { getTimeZoneInfo, ScopedVars } from '@grafana/data';
 import MysqlQuery from 'app/plugins/datasource/mysql/mysql_query';
 import ResponseParser, { MysqlResponse } from './response_parser';
 import { MysqlMetricFindValue, MysqlQueryForInterpolation } from './types';
@@ -69,6 +69,8 @@ export class MysqlDatasource {
   }
 
   query(options: any): Observable<MysqlResponse> {
+    // the dashboard's time zone for the time zone aware macros
+    const timezone = getTimeZoneInfo(options.timezone, Date.now())?.ianaName;
     const queries = _.filter(options.targets, (target) => {
       return target.hide !== true;
     }).map((target) => {
@@ -81,6 +83,7 @@ export class MysqlDatasource {
         datasourceId: this.id,
         rawSql: queryModel.render(this.interpolateVariable as any),
         format: target.format,
+        timezone,
       };
     });
 
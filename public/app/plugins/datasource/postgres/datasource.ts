@@ -2,7 +2,7 @@ import _ from 'lodash';
 import { Observable, of } from 'rxjs';
 import { map } from 'rxjs/operators';
 import { getBackendSrv } from '@grafana/runtime';
-import { DataQueryResponse, ScopedVars } from '@grafana/data';
+import { DataQueryResponse, getTimeZoneInfo, ScopedVars } from '@grafana/data';
 
 import ResponseParser from './response_parser';
 import PostgresQuery from 'app/plugins/datasource/postgres/postgres_query';
@@ -72,6 +72,8 @@ export class PostgresDatasource {
   }
 
   query(options: any): Observable<DataQueryResponse> {
+    // the dashboard's time zone for the time zone aware macros
+    const timezone = getTimeZoneInfo(options.timezone, Date.now())?.ianaName;
     const queries = _.filter(options.targets, (target) => {
       return target.hide !== true;
     }).map((target) => {
@@ -84,6 +86,7 @@ export class PostgresDatasource {
         datasourceId: this.id,
         rawSql: queryModel.render(this.interpolateVariable),
         format: target.format,
+        timezone,
       };
     });
 
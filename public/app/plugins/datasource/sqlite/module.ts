@@ -0,0 +1,36 @@
+import { SqliteDatasource } from './datasource';
+import { SqliteQueryCtrl } from './query_ctrl';
+
+class SqliteConfigCtrl {
+  static templateUrl = 'partials/config.html';
+  current: any;
+}
+
+const defaultQuery = `SELECT
+    $__time(<time_column>),
+    <text_column> as text,
+    <tags_column> as tags
+  FROM <table name>
+  WHERE $__timeFilter(<time_column>)
+  ORDER BY <time_column> ASC
+  LIMIT 100
+  `;
+
+class SqliteAnnotationsQueryCtrl {
+  static templateUrl = 'partials/annotations.editor.html';
+
+  annotation: any;
+
+  /** @ngInject */
+  constructor() {
+    this.annotation.rawQuery = this.annotation.rawQuery || defaultQuery;
+  }
+}
+
+export {
+  SqliteDatasource,
+  SqliteDatasource as Datasource,
+  SqliteQueryCtrl as QueryCtrl,
+  SqliteConfigCtrl as ConfigCtrl,
+  SqliteAnnotationsQueryCtrl as AnnotationsQueryCtrl,
+};
@@ -0,0 +1,56 @@
+import { auto } from 'angular';
+import { PanelEvents, QueryResultMeta } from '@grafana/data';
+import { QueryCtrl } from 'app/plugins/sdk';
+
+const defaultQuery = `SELECT
+  $__time(<time_column>),
+  <value column> as value,
+  <series name column> as metric
+FROM <table name>
+WHERE $__timeFilter(<time_column>)
+ORDER BY 1
+`;
+
+export class SqliteQueryCtrl extends QueryCtrl {
+  static templateUrl = 'partials/query.editor.html';
+
+  formats: any[];
+  lastQueryError?: string;
+  lastQueryMeta?: QueryResultMeta;
+  showHelp!: boolean;
+  showLastQuerySQL!: boolean;
+
+  /** @ngInject */
+  constructor($scope: any, $injector: auto.IInjectorService) {
+    super($scope, $injector);
+
+    this.formats = [
+      { text: 'Time series', value: 'time_series' },
+      { text: 'Table', value: 'table' },
+    ];
+
+    this.target.format = this.target.format || (this.panelCtrl.panel.type === 'table' ? 'table' : 'time_series');
+    if (!this.target.rawSql) {
+      this.target.rawSql = this.target.format === 'table' ? 'SELECT 1' : defaultQuery;
+    }
+    // SQLite queries are always written in SQL, there is no query builder
+    this.target.rawQuery = true;
+
+    this.panelCtrl.events.on(PanelEvents.dataReceived, this.onDataReceived.bind(this), $scope);
+    this.panelCtrl.events.on(PanelEvents.dataError, this.onDataError.bind(this), $scope);
+  }
+
+  onDataReceived(dataList: any) {
+    this.lastQueryError = undefined;
+    this.lastQueryMeta = dataList[0]?.meta;
+  }
+
+  onDataError(err: any) {
+    if (err.data && err.data.results) {
+      const queryRes = err.data.results[this.target.refId];
+      if (queryRes) {
+        this.lastQueryError = queryRes.error;
+      }
+    }
+  }
+}